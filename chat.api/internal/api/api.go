@@ -8,12 +8,16 @@ import (
 	http_server "chat-api/internal/server/http"
 	"chat-api/internal/service"
 	handler "chat-api/internal/transport/http"
+	"chat-api/pkg/audio"
 	"chat-api/pkg/auth"
 	"chat-api/pkg/broker"
 	"chat-api/pkg/crypto"
 	"chat-api/pkg/db/psql"
 	"chat-api/pkg/db/redis"
+	"chat-api/pkg/imaging"
+	"chat-api/pkg/linkpreview"
 	"chat-api/pkg/logger"
+	"chat-api/pkg/storage"
 	"context"
 	"net/http"
 	"os"
@@ -68,21 +72,50 @@ func Run() {
 	}
 
 	cache := cache.NewCashe(redisClient, cfg.Redis.TTL)
-	messangeCrypter, err := crypto.NewAESCipher(cfg.Auth.MessageSalt)
+	legacyCrypter, err := crypto.NewAESCipher(cfg.Auth.MessageSalt)
 	if err != nil {
 		logger.Fatal("Failed to initialize to message crypter",
 			zap.Error(err),
 		)
 	}
 
+	keyProvider, err := crypto.NewFileKeyProvider(cfg.Crypto.MasterKeysFile)
+	if err != nil {
+		logger.Fatal("Failed to load master keys",
+			zap.Error(err),
+		)
+	}
+
+	blindIndexer, err := crypto.NewBlindIndexer(cfg.Crypto.SearchKey)
+	if err != nil {
+		logger.Fatal("Failed to initialize search index",
+			zap.Error(err),
+		)
+	}
+
+	fileStorage, err := storage.NewStorage(cfg.Storage)
+	if err != nil {
+		logger.Fatal("Failed to initialize file storage",
+			zap.Error(err),
+		)
+	}
+
+	urlSigner, err := storage.NewURLSigner(cfg.Storage.URLSigningKey, cfg.Storage.URLTTL)
+	if err != nil {
+		logger.Fatal("Failed to initialize download url signer",
+			zap.Error(err),
+		)
+	}
+
 	repositories := repo.NewRepositories(db)
+	messangeCrypter := crypto.NewEnvelopeCipher(keyProvider, repositories.DataKeys, legacyCrypter)
 	tokenManager, err := auth.NewManager(cfg.Auth.JWT.SecretAccessKey)
 	if err != nil {
 		logger.Fatal("Failed to create tokenManager",
 			zap.Error(err),
 		)
 	}
-	deps := service.NewDeps(repositories, tokenManager, rabbitmq, messangeCrypter, cache)
+	deps := service.NewDeps(repositories, tokenManager, rabbitmq, messangeCrypter, keyProvider, blindIndexer, fileStorage, urlSigner, imaging.NewVideoProber(cfg.Media.FFprobePath, cfg.Media.FFmpegPath, cfg.Media.FFmpegTimeout), audio.NewAnalyzer(cfg.Media.FFmpegPath, cfg.Media.FFmpegTimeout), linkpreview.NewFetcher(cfg.LinkPreview), cache, cfg.Auth.ComplianceKey)
 
 	services := service.NewServices(deps)
 
@@ -98,6 +131,10 @@ func Run() {
 	httpHandler := handler.NewHandler(services, upgrader, profileServer.ProfileClient)
 	httpServer := http_server.NewServer(cfg.Http, httpHandler)
 
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	httpHandler.RunWorkers(workersCtx, cfg.Workers)
+
 	if err := httpServer.Run(); err != nil {
 		logger.Errorf("the server didn't start: %s\n", err)
 	}
@@ -109,6 +146,8 @@ func Run() {
 
 	<-quit
 
+	stopWorkers()
+
 	if err := httpServer.ShutDown(context.Background()); err != nil {
 		logger.Errorf("failed to shutdown http server: %v", err)
 	}
@@ -4,7 +4,9 @@ import (
 	"chat-api/pkg/broker"
 	"chat-api/pkg/db/psql"
 	"chat-api/pkg/db/redis"
+	"chat-api/pkg/linkpreview"
 	"chat-api/pkg/logger"
+	"chat-api/pkg/storage"
 	"os"
 	"time"
 
@@ -13,18 +15,66 @@ import (
 )
 
 type Config struct {
-	Redis     redis.RedisConfig
-	Psql      psql.PSQlConfig
-	RabbitMQ  broker.RabbitMQConfig
-	Http      HttpConfig
-	WebSocket WebSocketConfig
-	Grpc      GrpcConfig
-	Auth      AuthConfig
+	Redis       redis.RedisConfig
+	Psql        psql.PSQlConfig
+	RabbitMQ    broker.RabbitMQConfig
+	Http        HttpConfig
+	WebSocket   WebSocketConfig
+	Grpc        GrpcConfig
+	Auth        AuthConfig
+	Crypto      CryptoConfig
+	Storage     storage.StorageConfig
+	Media       MediaConfig
+	LinkPreview linkpreview.Config
+	Workers     WorkersConfig
+}
+
+type WorkersConfig struct {
+	SchedulerInterval time.Duration `envconfig:"SCHEDULER_INTERVAL" default:"5s"`
+	ReaperInterval    time.Duration `envconfig:"REAPER_INTERVAL" default:"10s"`
+	ReencryptInterval time.Duration `envconfig:"REENCRYPT_INTERVAL" default:"1m"`
+	DataKeyMaxAge     time.Duration `envconfig:"DATA_KEY_MAX_AGE" default:"2160h"`
+	// UploadsCleanupInterval is how often the abandoned resumable uploads are removed
+	UploadsCleanupInterval time.Duration `envconfig:"UPLOADS_CLEANUP_INTERVAL" default:"1h"`
+	// MediaSweepInterval is how often the media left pending by the lost jobs is queued again
+	MediaSweepInterval time.Duration `envconfig:"MEDIA_SWEEP_INTERVAL" default:"5m"`
+	// MediaRetryDelay is the pause before consuming the media, link preview, channel fan-out and export jobs again after the consumer failed
+	MediaRetryDelay time.Duration `envconfig:"MEDIA_RETRY_DELAY" default:"5s"`
+	// PollsCloseInterval is how often the polls whose close time has come are closed
+	PollsCloseInterval time.Duration `envconfig:"POLLS_CLOSE_INTERVAL" default:"10s"`
+	// LinkPreviewWorkers is how many link previews are fetched at once by the replica
+	LinkPreviewWorkers int `envconfig:"LINK_PREVIEW_WORKERS" default:"4"`
+	// ChannelFanoutWorkers is how many pages of the channel subscribers get the posts at once from the replica
+	ChannelFanoutWorkers int `envconfig:"CHANNEL_FANOUT_WORKERS" default:"4"`
+	// ExportWorkers is how many chat archives are built at once by the replica
+	ExportWorkers int `envconfig:"EXPORT_WORKERS" default:"1"`
+	// RetentionHour is the hour of the day in UTC the nightly retention job starts at
+	RetentionHour int `envconfig:"RETENTION_HOUR" default:"3"`
+	// PresenceRetryDelay is the pause before subscribing to the presence changes again after Redis failed
+	PresenceRetryDelay time.Duration `envconfig:"PRESENCE_RETRY_DELAY" default:"1s"`
+}
+
+// CryptoConfig points to the master keys, MESSAGE_SALT stays as the legacy key
+// until all messages encrypted by it are re-encrypted
+type CryptoConfig struct {
+	MasterKeysFile string `envconfig:"MASTER_KEYS_FILE"`
+	// SearchKey keys the blind index of the messages, changing it empties the search until the messages are indexed again
+	SearchKey string `envconfig:"SEARCH_KEY"`
+}
+
+// MediaConfig points to the binaries reading the uploaded videos and voice messages
+type MediaConfig struct {
+	FFprobePath string `envconfig:"FFPROBE_PATH" default:"ffprobe"`
+	FFmpegPath  string `envconfig:"FFMPEG_PATH" default:"ffmpeg"`
+	// FFmpegTimeout kills the run of ffprobe or ffmpeg stuck on the crafted file
+	FFmpegTimeout time.Duration `envconfig:"FFMPEG_TIMEOUT" default:"1m"`
 }
 
 type AuthConfig struct {
 	JWT         JWTConfig
 	MessageSalt string `envconfig:"MESSAGE_SALT"`
+	// ComplianceKey guards the retention policies and the legal holds, the compliance API is closed without it
+	ComplianceKey string `envconfig:"COMPLIANCE_KEY"`
 }
 
 type JWTConfig struct {
@@ -115,7 +165,53 @@ func loadFromEnv(cfg *Config) error {
 		return err
 	}
 
+	if err := envconfig.Process("WORKERS", &cfg.Workers); err != nil {
+		logger.Error("Failed to unmarshal environment file",
+			zap.String("prefix", "WORKERS"),
+			zap.String("file", "config-app/.env"),
+			zap.Error(err),
+		)
+		return err
+	}
+
+	if err := envconfig.Process("CRYPTO", &cfg.Crypto); err != nil {
+		logger.Error("Failed to unmarshal environment file",
+			zap.String("prefix", "CRYPTO"),
+			zap.String("file", "config-app/.env"),
+			zap.Error(err),
+		)
+		return err
+	}
+
+	if err := envconfig.Process("STORAGE", &cfg.Storage); err != nil {
+		logger.Error("Failed to unmarshal environment file",
+			zap.String("prefix", "STORAGE"),
+			zap.String("file", "config-app/.env"),
+			zap.Error(err),
+		)
+		return err
+	}
+
+	if err := envconfig.Process("MEDIA", &cfg.Media); err != nil {
+		logger.Error("Failed to unmarshal environment file",
+			zap.String("prefix", "MEDIA"),
+			zap.String("file", "config-app/.env"),
+			zap.Error(err),
+		)
+		return err
+	}
+
+	if err := envconfig.Process("LINK_PREVIEW", &cfg.LinkPreview); err != nil {
+		logger.Error("Failed to unmarshal environment file",
+			zap.String("prefix", "LINK_PREVIEW"),
+			zap.String("file", "config-app/.env"),
+			zap.Error(err),
+		)
+		return err
+	}
+
 	cfg.Auth.MessageSalt = os.Getenv("MESSAGE_SALT")
 	cfg.Auth.JWT.SecretAccessKey = os.Getenv("SECRET_ACCESS_KEY")
+	cfg.Auth.ComplianceKey = os.Getenv("COMPLIANCE_KEY")
 	return nil
 }
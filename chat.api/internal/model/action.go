@@ -20,6 +20,9 @@ const (
 	ACTION_LIMIT_REQUEST = 30
 )
 
+// CHAT_ACTIONS_OF_CHAT are the actions on the chat itself, the rest tell who joined and left it
+var CHAT_ACTIONS_OF_CHAT = []string{CHAT_ACTION_CREATE, CHAT_ACTION_DELETE, CHAT_ACTION_RENAME}
+
 type MessageAction struct {
 	MessageID int64     `db:"message_id"`
 	UserID    string    `db:"user_id"`
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	BOOKMARKS_LIMIT_REQUEST  = 50
+	BOOKMARK_TAGS_LIMIT      = 10
+	BOOKMARK_TAG_MAX_LENGTH  = 32
+	BOOKMARK_TAG_FILTER_SIZE = 5 // tags the list can be filtered by at once
+)
+
+type Bookmark struct {
+	BookmarkID int64          `json:"bookmark_id" db:"bookmark_id"`
+	UserID     string         `json:"-" db:"user_id"`
+	ChatID     int64          `json:"chat_id" db:"chat_id"`
+	MessageID  int64          `json:"message_id" db:"message_id"`
+	Tags       pq.StringArray `json:"tags" db:"tags"`
+	CreatedAt  time.Time      `json:"created_at" db:"created_at"`
+}
+
+// BookmarkedMessage is the saved message along with its attachments
+type BookmarkedMessage struct {
+	Bookmark Bookmark `json:"bookmark"`
+	Message  Message  `json:"message"`
+}
+
+type BookmarkRequest struct {
+	UserID    string   `json:"-"`
+	ChatID    int64    `json:"chat_id"`
+	MessageID int64    `json:"message_id"`
+	Tags      []string `json:"tags"`
+}
+
+type BookmarksRequest struct {
+	UserID           string
+	BeforeBookmarkID int64
+	Limit            int
+	Tags             []string // the bookmarks carrying all the tags
+}
+
+// NormalizeBookmarkTags lowercases the tags and drops the empty and repeated ones,
+// false if there are too many tags or one of them is too long
+func NormalizeBookmarkTags(tags []string, limit int) ([]string, bool) {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]struct{}, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" {
+			continue
+		}
+		if len([]rune(tag)) > BOOKMARK_TAG_MAX_LENGTH {
+			return nil, false
+		}
+		if _, ok := seen[tag]; ok {
+			continue
+		}
+		seen[tag] = struct{}{}
+		normalized = append(normalized, tag)
+	}
+	return normalized, len(normalized) <= limit
+}
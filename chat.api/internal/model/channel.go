@@ -0,0 +1,78 @@
+package model
+
+import (
+	"regexp"
+	"time"
+)
+
+const (
+	CHANNEL_FANOUT_BATCH_SIZE = 500 // subscribers a single fan-out job delivers the post to
+	CHANNEL_VIEWS_LIMIT       = 100 // posts viewed by a single request
+)
+
+// handle of the channel starts with a letter and has 5 to 32 letters, digits or underscores
+var channelHandleRegexp = regexp.MustCompile(`^[a-z][a-z0-9_]{4,31}$`)
+
+// ChannelPost is the message of a channel, Signature is set if the channel signs its posts
+type ChannelPost struct {
+	MessageID int64   `json:"message_id" db:"message_id"`
+	ChatID    int64   `json:"-" db:"chat_id"`
+	Signature *string `json:"signature,omitempty" db:"signature"`
+	Views     int     `json:"views" db:"views"`
+}
+
+// ChannelInfo is what anybody sees by the handle, the subscribers stay hidden
+type ChannelInfo struct {
+	Chat             ChatDB `json:"chat"`
+	SubscribersCount int    `json:"subscribers_count"`
+}
+
+// ChannelFanoutJob is the RabbitMQ job delivering the post, or the update of the post if Update is set,
+// to the subscribers following AfterUserID. The message keeps the ciphertext in the queue
+type ChannelFanoutJob struct {
+	Message     CreateMessageRequest `json:"message"`
+	Update      *ChannelPostUpdate   `json:"update,omitempty"`
+	AfterUserID string               `json:"after_user_id,omitempty"`
+}
+
+// ChannelPostUpdate is the reaction, the vote or the edit of the stored post. The reactions and the results
+// of the poll are read on the delivery and go out as the counts without the users, SkipUserID has got
+// its own view already. The edit keeps the ciphertext of the content and the entities in the queue
+type ChannelPostUpdate struct {
+	Type       string `json:"type"`
+	ChatID     int64  `json:"chat_id"`
+	MessageID  int64  `json:"message_id"`
+	SkipUserID string `json:"skip_user_id,omitempty"`
+
+	Content           *string    `json:"content,omitempty"`
+	EncryptedEntities *string    `json:"encrypted_entities,omitempty"`
+	Mentions          *[]Mention `json:"mentions,omitempty"`
+	EditedAt          time.Time  `json:"edited_at,omitempty"`
+}
+
+type ViewPostsRequest struct {
+	ChatID     int64   `json:"chat_id"`
+	UserID     string  `json:"-"`
+	MessageIDs []int64 `json:"message_ids"`
+}
+
+type PostViewsEvent struct {
+	Type   string        `json:"type"`
+	ChatID int64         `json:"chat_id"`
+	Posts  []ChannelPost `json:"posts"`
+}
+
+func (j ChannelFanoutJob) ChatID() int64 {
+	if j.Update != nil {
+		return j.Update.ChatID
+	}
+	return j.Message.ChatID
+}
+
+func IsValidChannelHandle(handle string) bool {
+	return channelHandleRegexp.MatchString(handle)
+}
+
+func (r ViewPostsRequest) IsValid() bool {
+	return len(r.MessageIDs) > 0 && len(r.MessageIDs) <= CHANNEL_VIEWS_LIMIT
+}
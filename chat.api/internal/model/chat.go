@@ -7,8 +7,11 @@ const (
 	CHAT_TYPE_GROUP   = "group"
 	CHAT_TYPE_CHANNEL = "channel"
 
-	CHAT_ROLE_USER  = "user"
-	CHAT_ROLE_ADMIN = "admin"
+	CHAT_ROLE_OWNER      = "owner" // the creator of the chat, never stored in chat_roles
+	CHAT_ROLE_ADMIN      = "admin"
+	CHAT_ROLE_MODERATOR  = "moderator"
+	CHAT_ROLE_MEMBER     = "member"
+	CHAT_ROLE_RESTRICTED = "restricted"
 
 	CHAT_LIMIT_REQUEST        = 10
 	PINNED_CHAT_LIMIT_REQUEST = 10
@@ -24,6 +27,12 @@ type ChatDB struct {
 	CreatedAt   time.Time `json:"created_at,omitempty" db:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at,omitempty" db:"updated_at"`
 	AvatarURL   *string   `json:"avatar_url,omitempty" db:"avatar_url"`
+	MessageTTL  *int      `json:"message_ttl,omitempty" db:"message_ttl"` // seconds, disappearing messages
+
+	DefaultPermissions int `json:"default_permissions" db:"default_permissions"` // of the members without a role
+
+	Handle       *string `json:"handle,omitempty" db:"handle"`               // public name of the channel
+	SignMessages bool    `json:"sign_messages,omitempty" db:"sign_messages"` // posts of the channel carry the name of the author
 }
 
 type ChatBriefInfo struct {
@@ -41,6 +50,8 @@ type ChatRole struct {
 	GranterID string `json:"granter_id" db:"granter_id"`
 	Nickname  string `json:"nickname" db:"nickname"`
 	Role      string `json:"role" db:"role"`
+
+	Permissions *int `json:"permissions,omitempty" db:"permissions"` // only of the restricted members
 }
 
 type PinnedChat struct {
@@ -65,6 +76,7 @@ type CreatePrivateChatResponse struct {
 	Chat        ChatDB
 	RecipientID string
 	Message     Message
+	Created     bool // false if the chat between the users already existed
 }
 
 type Chat struct {
@@ -73,6 +85,9 @@ type Chat struct {
 	ParticipantsIDs []string  // if chat active - last 75
 	ChatAction      *[]ChatAction
 	ChatRoles       *[]ChatRole
+
+	// SubscribersCount of the channel, its participants are shown to the admins only
+	SubscribersCount *int
 }
 
 type PinnedChatInit struct {
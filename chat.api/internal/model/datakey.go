@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+const (
+	DATA_KEY_BATCH_SIZE  = 100
+	REENCRYPT_BATCH_SIZE = 200
+
+	ENVELOPE_CIPHER_PREFIX         = "v2" // bound to the chat and the message
+	ENVELOPE_CIPHER_UNBOUND_PREFIX = "v1" // written before the binding, re-encrypted by the job
+)
+
+// DataKey is the key encrypting the messages of one chat, it's stored wrapped by the master key of MasterKeyVersion.
+// A chat has one active key, the inactive ones are kept to decrypt the messages until they are re-encrypted.
+type DataKey struct {
+	KeyID            int64     `db:"key_id"`
+	ChatID           int64     `db:"chat_id"`
+	WrappedKey       []byte    `db:"wrapped_key"`
+	MasterKeyVersion int       `db:"master_key_version"`
+	Active           bool      `db:"active"`
+	CreatedAt        time.Time `db:"created_at"`
+}
+
+// StoredContent is the stored ciphertext of a message waiting to be moved to the active data key of the chat
+// or to be bound to the message
+type StoredContent struct {
+	MessageID int64  `db:"message_id"`
+	ChatID    int64  `db:"chat_id"`
+	Content   string `db:"content"`
+}
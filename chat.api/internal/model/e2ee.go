@@ -0,0 +1,109 @@
+package model
+
+import "time"
+
+const (
+	ONE_TIME_PREKEYS_LIMIT = 100 // per upload
+	ONE_TIME_PREKEYS_LOW   = 10  // the device is asked to upload more once fewer are left
+
+	PREKEY_BUNDLES_FETCH_LIMIT  = 10 // per caller and user within the window
+	PREKEY_BUNDLES_FETCH_WINDOW = time.Hour
+)
+
+type PreKey struct {
+	KeyID     int    `json:"key_id" db:"prekey_id"`
+	PublicKey string `json:"public_key" db:"public_key"`
+}
+
+type SignedPreKey struct {
+	KeyID     int    `json:"key_id" db:"signed_prekey_id"`
+	PublicKey string `json:"public_key" db:"signed_prekey"`
+	Signature string `json:"signature" db:"signed_prekey_signature"`
+}
+
+// DeviceKeys is published by a device, the server keeps only public keys
+type DeviceKeys struct {
+	UserID         string       `json:"user_id"`
+	DeviceID       int          `json:"device_id"`
+	IdentityKey    string       `json:"identity_key"`
+	SignedPreKey   SignedPreKey `json:"signed_prekey"`
+	OneTimePreKeys []PreKey     `json:"one_time_prekeys"`
+}
+
+// PreKeyBundle is handed out to start a session with the device,
+// every one-time prekey is given out only once.
+type PreKeyBundle struct {
+	UserID             string       `json:"user_id"`
+	DeviceID           int          `json:"device_id"`
+	IdentityKey        string       `json:"identity_key"`
+	SignedPreKey       SignedPreKey `json:"signed_prekey"`
+	OneTimePreKey      *PreKey      `json:"one_time_prekey,omitempty"`
+	OneTimePreKeysLeft int          `json:"-"`
+	LowOnPreKeys       bool         `json:"low_on_prekeys,omitempty"` // the session may start from the signed prekey soon
+}
+
+// PreKeysLowEvent asks the device of the user to upload more one-time prekeys
+type PreKeysLowEvent struct {
+	Type     string `json:"type"`
+	DeviceID int    `json:"device_id"`
+	Count    int    `json:"count"`
+}
+
+// EncryptedEnvelope is the ciphertext of a message for one device of the recipient,
+// the server relays it as is.
+type EncryptedEnvelope struct {
+	RecipientID string `json:"recipient_id" db:"recipient_id"`
+	DeviceID    int    `json:"device_id" db:"device_id"`
+	Ciphertext  string `json:"ciphertext" db:"ciphertext"`
+}
+
+type EncryptedMessageRequest struct {
+	ChatID         int64               `json:"chat_id"`
+	SenderDeviceID int                 `json:"sender_device_id"`
+	Message        MessageDB           `json:"message"`
+	Envelopes      []EncryptedEnvelope `json:"envelopes"`
+}
+
+type EncryptedMessage struct {
+	Type           string              `json:"type,omitempty"`
+	ChatID         int64               `json:"chat_id"`
+	SenderDeviceID int                 `json:"sender_device_id"`
+	Message        MessageDB           `json:"message"`
+	Envelopes      []EncryptedEnvelope `json:"envelopes"`
+}
+
+// EncryptedMessageDB is the message together with the envelope for one device
+type EncryptedMessageDB struct {
+	MessageID      int64      `db:"message_id"`
+	SenderID       string     `db:"sender_id"`
+	Status         string     `db:"status"`
+	Type           string     `db:"type"`
+	CreatedAt      time.Time  `db:"created_at"`
+	UpdatedAt      time.Time  `db:"updated_at"`
+	ExpiresAt      *time.Time `db:"expires_at"`
+	SenderDeviceID int        `db:"sender_device_id"`
+	RecipientID    string     `db:"recipient_id"`
+	DeviceID       int        `db:"device_id"`
+	Ciphertext     string     `db:"ciphertext"`
+}
+
+func (m EncryptedMessageDB) ToEncryptedMessage(chatID int64) EncryptedMessage {
+	return EncryptedMessage{
+		ChatID:         chatID,
+		SenderDeviceID: m.SenderDeviceID,
+		Message: MessageDB{
+			MessageID: m.MessageID,
+			SenderID:  m.SenderID,
+			Status:    m.Status,
+			Type:      m.Type,
+			CreatedAt: m.CreatedAt,
+			UpdatedAt: m.UpdatedAt,
+			ExpiresAt: m.ExpiresAt,
+		},
+		Envelopes: []EncryptedEnvelope{{
+			RecipientID: m.RecipientID,
+			DeviceID:    m.DeviceID,
+			Ciphertext:  m.Ciphertext,
+		}},
+	}
+}
@@ -0,0 +1,145 @@
+package model
+
+import (
+	"encoding/json"
+	"net/url"
+	"sort"
+	"unicode/utf8"
+)
+
+const (
+	ENTITY_BOLD          = "bold"
+	ENTITY_ITALIC        = "italic"
+	ENTITY_STRIKETHROUGH = "strikethrough"
+	ENTITY_SPOILER       = "spoiler"
+	ENTITY_CODE          = "code"
+	ENTITY_PRE           = "pre"
+	ENTITY_TEXT_LINK     = "text_link"
+
+	ENTITY_MAX_COUNT           = 100
+	ENTITY_URL_MAX_LENGTH      = 2048
+	ENTITY_LANGUAGE_MAX_LENGTH = 32
+
+	PARSE_MODE_MARKDOWN = "markdown"
+)
+
+// MessageEntity formats the part of the plain content, offset and length are in characters like the ones of mentions
+type MessageEntity struct {
+	Type     string  `json:"type"`
+	Offset   int     `json:"offset"`
+	Length   int     `json:"length"`
+	URL      *string `json:"url,omitempty"`      // text_link only
+	Language *string `json:"language,omitempty"` // pre only
+}
+
+var entityURLSchemes = map[string]bool{
+	"http":   true,
+	"https":  true,
+	"mailto": true,
+}
+
+// IsValidEntities checks the entities against the plain content. Entities may be nested
+// but must not cross each other, nothing is formatted inside code and pre.
+func IsValidEntities(content *string, entities *[]MessageEntity) bool {
+	if entities == nil || len(*entities) == 0 {
+		return true
+	}
+	if content == nil || len(*entities) > ENTITY_MAX_COUNT {
+		return false
+	}
+
+	length := utf8.RuneCountInString(*content)
+	for _, entity := range *entities {
+		if entity.Offset < 0 || entity.Length <= 0 || entity.Offset+entity.Length > length || !isValidEntityExtra(entity) {
+			return false
+		}
+	}
+
+	sorted := SortEntities(*entities)
+	for i, outer := range sorted {
+		for _, inner := range sorted[i+1:] {
+			if inner.Offset >= outer.Offset+outer.Length {
+				break
+			}
+			// sorted by offset and the longest first, so the inner one starts within the outer one
+			if inner.Offset+inner.Length > outer.Offset+outer.Length || inner.Type == outer.Type ||
+				outer.Type == ENTITY_CODE || outer.Type == ENTITY_PRE || inner.Type == ENTITY_PRE {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func isValidEntityExtra(entity MessageEntity) bool {
+	switch entity.Type {
+	case ENTITY_BOLD, ENTITY_ITALIC, ENTITY_STRIKETHROUGH, ENTITY_SPOILER, ENTITY_CODE:
+		return entity.URL == nil && entity.Language == nil
+	case ENTITY_PRE:
+		return entity.URL == nil && (entity.Language == nil || isValidLanguage(*entity.Language))
+	case ENTITY_TEXT_LINK:
+		return entity.Language == nil && entity.URL != nil && isValidEntityURL(*entity.URL)
+	default:
+		return false
+	}
+}
+
+func isValidEntityURL(rawURL string) bool {
+	if len(rawURL) > ENTITY_URL_MAX_LENGTH {
+		return false
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil || !entityURLSchemes[parsed.Scheme] {
+		return false
+	}
+	return parsed.Scheme == "mailto" && parsed.Opaque != "" || parsed.Host != ""
+}
+
+func isValidLanguage(language string) bool {
+	if language == "" || len(language) > ENTITY_LANGUAGE_MAX_LENGTH {
+		return false
+	}
+	for _, r := range language {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '+' || r == '#' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// SortEntities returns the entities ordered by offset, the outer ones first
+func SortEntities(entities []MessageEntity) []MessageEntity {
+	sorted := append([]MessageEntity(nil), entities...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Offset != sorted[j].Offset {
+			return sorted[i].Offset < sorted[j].Offset
+		}
+		return sorted[i].Length > sorted[j].Length
+	})
+	return sorted
+}
+
+// MarshalEntities returns the JSON stored encrypted with the message, nil if there are no entities
+func MarshalEntities(entities *[]MessageEntity) (*string, error) {
+	if entities == nil || len(*entities) == 0 {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(SortEntities(*entities))
+	if err != nil {
+		return nil, err
+	}
+	marshaled := string(data)
+	return &marshaled, nil
+}
+
+func UnmarshalEntities(data string) (*[]MessageEntity, error) {
+	var entities []MessageEntity
+	if err := json.Unmarshal([]byte(data), &entities); err != nil {
+		return nil, err
+	}
+	if len(entities) == 0 {
+		return nil, nil
+	}
+	return &entities, nil
+}
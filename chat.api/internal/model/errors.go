@@ -17,6 +17,84 @@ var (
 	ErrMediaIsEmpty           = errors.New("media is empty or null")
 	ErrLocationIsEmpty        = errors.New("location is empty or null")
 	ErrFailedToEncryptMessage = errors.New("failed to encrypting message")
+	ErrInvalidReaction        = errors.New("reaction is empty or too long")
+	ErrNotParticipant         = errors.New("user isn't a participant of the chat")
+	ErrMessageNotFound        = errors.New("message not found in the chat")
+	ErrChatBlocked            = errors.New("chat is blocked for the user")
+	ErrInvalidSendTime        = errors.New("send time must be in the future")
+	ErrScheduledNotFound      = errors.New("pending scheduled message not found")
+	ErrInvalidMessageTTL      = errors.New("message ttl is out of range")
+	ErrChatEncrypted          = errors.New("chat is end-to-end encrypted, content must be encrypted by the client")
+	ErrChatNotEncrypted       = errors.New("chat isn't end-to-end encrypted")
+	ErrInvalidDeviceKeys      = errors.New("device keys are invalid")
+	ErrDeviceKeysNotFound     = errors.New("user has no published device keys")
+	ErrPreKeyFetchLimit       = errors.New("too many prekey bundles requested for the user, try later")
+	ErrInvalidMasterKey       = errors.New("master key must be 32 bytes long with a positive version")
+	ErrInvalidSearchKey       = errors.New("search key must be at least 32 bytes long")
+	ErrMasterKeyNotFound      = errors.New("master key of the version not found")
+	ErrInvalidCiphertext      = errors.New("ciphertext has an unknown format")
+	ErrInvalidUploadKind      = errors.New("upload kind must be media, file or voice")
+	ErrUnsupportedUploadType  = errors.New("content type isn't allowed for the upload kind")
+	ErrUploadTooLarge         = errors.New("upload exceeds the size limit of the type")
+	ErrUploadNotFound         = errors.New("upload not found or expired")
+	ErrUploadOffsetMismatch   = errors.New("upload offset doesn't match")
+	ErrAttachmentNotFound     = errors.New("attachment isn't uploaded by the user or isn't in the chat")
+	ErrMediaProcessing        = errors.New("media is still being processed")
+	ErrMediaRejected          = errors.New("media can't be served, its location data can't be removed")
+	ErrInvalidEnvelopes       = errors.New("envelopes are empty or address non-participants")
+	ErrVoiceTooLong           = errors.New("voice message exceeds the duration limit")
+	ErrInvalidVoiceMessage    = errors.New("voice message must have exactly one voice upload and nothing else attached")
+	ErrNotVoiceMessage        = errors.New("message isn't a voice message")
+	ErrInvalidPoll            = errors.New("poll is invalid")
+	ErrPollNotAllowed         = errors.New("polls are allowed only in group chats and channels")
+	ErrPollClosed             = errors.New("poll is closed")
+	ErrPollAlreadyVoted       = errors.New("quiz vote can't be changed")
+	ErrInvalidPollVote        = errors.New("chosen options don't match the poll")
+	ErrNotPollMessage         = errors.New("message isn't a poll")
+	ErrInvalidMentions        = errors.New("mentions are out of the content or address non-participants")
+	ErrInvalidEntities        = errors.New("entities are out of the content or malformed")
+	ErrMessageNotEditable     = errors.New("message can be edited only by its sender and only if it's not forwarded, a poll or a voice message")
+	ErrInvalidPresence        = errors.New("presence status must be online or away")
+	ErrInvalidChatActivity    = errors.New("chat activity must be typing or uploading")
+	ErrChatActivityTooOften   = errors.New("chat activity is sent too often")
+	ErrChatPermissionDenied   = errors.New("user doesn't have the permission in the chat")
+	ErrInvalidChatRole        = errors.New("chat role or permissions are invalid")
+	ErrInvalidInviteLink      = errors.New("invite link params are invalid")
+	ErrInviteLinkNotFound     = errors.New("invite link not found")
+	ErrInviteLinkExpired      = errors.New("invite link is revoked, expired or used up")
+	ErrAlreadyParticipant     = errors.New("user is already a participant of the chat")
+	ErrJoinRequestNotFound    = errors.New("pending join request not found")
+	ErrInvalidMembers         = errors.New("members to add are invalid")
+	ErrInvalidChatInfo        = errors.New("name, description, avatar or handle of the chat is invalid")
+	ErrUserBanned             = errors.New("user is banned in the chat")
+	ErrCannotKickMember       = errors.New("member can't be kicked by the user")
+	ErrChatOwnerCannotLeave   = errors.New("chat owner has to transfer the ownership before leaving")
+	ErrNotChatOwner           = errors.New("user isn't the owner of the chat")
+	ErrMemberNotFound         = errors.New("member not found in the chat")
+	ErrChannelNotFound        = errors.New("channel not found")
+	ErrChannelHandleTaken     = errors.New("handle is taken by another channel")
+	ErrInvalidPostViews       = errors.New("posts to view are empty or too many")
+	ErrInvalidChatState       = errors.New("chat state is empty or its position is negative")
+	ErrInvalidChatFolder      = errors.New("chat folder has an invalid name, no rules or too many chats")
+	ErrChatFolderNotFound     = errors.New("chat folder not found")
+	ErrChatFoldersLimit       = errors.New("too many chat folders")
+	ErrInvalidBookmarkTags    = errors.New("bookmark has too many tags or a tag is too long")
+	ErrBookmarkNotFound       = errors.New("bookmark not found")
+	ErrInvalidSearch          = errors.New("search query has no words or too many, or its filters are invalid")
+	ErrExportNotFound         = errors.New("export not found")
+	ErrExportInProgress       = errors.New("previous export of the user isn't finished yet")
+	ErrExportNotReady         = errors.New("export isn't built or has expired")
+	ErrExportTimeout          = errors.New("export wasn't finished in time")
+	ErrInvalidImport          = errors.New("archive to import is invalid, too large or has unmapped senders")
+	ErrInvalidComplianceKey   = errors.New("compliance key is missing or invalid")
+	ErrChatNotFound           = errors.New("chat not found")
+	ErrInvalidRetention       = errors.New("retention days are out of range or the chat type is unknown")
+	ErrRetentionNotFound      = errors.New("retention policy not found")
+	ErrRetentionRunning       = errors.New("retention run is in progress already")
+	ErrRetentionTimeout       = errors.New("retention run wasn't finished in time")
+	ErrRetentionRunNotFound   = errors.New("retention run not found")
+	ErrInvalidLegalHold       = errors.New("legal hold needs either a chat or a user and a reason")
+	ErrLegalHoldNotFound      = errors.New("legal hold not found or released already")
 
 	ErrWebSocketNotFound                 = errors.New("websocket not found for the specified user")
 	ErrConvertWebSocketCacheToRedisCache = errors.New("failed to convert interface web socket cache to type RedisCache")
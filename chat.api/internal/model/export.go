@@ -0,0 +1,253 @@
+package model
+
+import (
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	EXPORT_STATUS_PENDING = "pending"
+	EXPORT_STATUS_RUNNING = "running"
+	EXPORT_STATUS_READY   = "ready"
+	EXPORT_STATUS_FAILED  = "failed"
+
+	EXPORT_TTL             = 7 * 24 * time.Hour // the archive is deleted after
+	EXPORT_RUNNING_TIMEOUT = 6 * time.Hour      // the export still running after is treated as lost with its worker
+	EXPORTS_LIMIT_REQUEST  = 20
+	EXPORT_MESSAGES_BATCH  = 200
+	EXPORT_BATCH_SIZE      = 100 // expired exports removed at once
+
+	ARCHIVE_VERSION = 1
+
+	IMPORT_BODY_LIMIT     = 64 << 20
+	IMPORT_MESSAGES_LIMIT = 50000
+	IMPORT_SENDERS_LIMIT  = CHAT_MEMBERS_ADD_LIMIT
+	IMPORT_CONTENT_LIMIT  = 10000 // runes of the content of a message
+	IMPORT_SENDER_LIMIT   = 64    // runes of the name of a sender
+)
+
+// Export is the archive of a chat, or of all chats of the user when ChatID is nil
+type Export struct {
+	ExportID    int64      `json:"export_id" db:"export_id"`
+	UserID      string     `json:"-" db:"user_id"`
+	ChatID      *int64     `json:"chat_id,omitempty" db:"chat_id"`
+	Status      string     `json:"status" db:"status"`
+	StorageKey  *string    `json:"-" db:"storage_key"`
+	Size        *int64     `json:"size,omitempty" db:"size"`
+	Error       *string    `json:"error,omitempty" db:"error"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	StartedAt   *time.Time `json:"started_at,omitempty" db:"started_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty" db:"completed_at"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+}
+
+type CreateExportRequest struct {
+	UserID string `json:"-"`
+	ChatID *int64 `json:"chat_id"`
+}
+
+// ExportJob is the RabbitMQ job building the archive
+type ExportJob struct {
+	ExportID int64 `json:"export_id"`
+}
+
+// ExportEvent tells the user the archive is built, DownloadURL is empty if it failed
+type ExportEvent struct {
+	Type        string `json:"type"`
+	Export      Export `json:"export"`
+	DownloadURL string `json:"download_url,omitempty"`
+}
+
+// NotificationExport is sent to notification.api when the user isn't online to get the ExportEvent
+type NotificationExport struct {
+	ExportID    int64      `json:"export_id"`
+	ChatID      *int64     `json:"chat_id,omitempty"`
+	RecipientID string     `json:"recipient_id"`
+	Status      string     `json:"status"`
+	DownloadURL string     `json:"download_url,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+// Archive format, version 1.
+//
+// The zip has index.html listing the chats and for every chat the directory chats/<chat_id>/ with:
+//   - messages.json, the ArchiveChat
+//   - messages.html, the static page of the chat
+//   - attachments/<kind>-<id>.<ext>, the stored files and media the messages point to
+//
+// ArchiveChat is also what the import takes, messages of other messengers are brought in by converting them to it:
+//
+//	{
+//	  "version": 1,
+//	  "chat": {"name": "Team", "description": "", "type": "group", "participants": ["alice", "bob"]},
+//	  "messages": [
+//	    {"id": "1", "sender": "alice", "created_at": "2021-03-04T10:00:00Z", "content": "hi"},
+//	    {"id": "2", "sender": "bob", "created_at": "2021-03-04T10:01:00Z", "edited_at": "2021-03-04T10:02:00Z", "content": "hello", "reply_to": "1"}
+//	  ]
+//	}
+//
+// The ids are any strings unique in the chat, a reply points to an earlier message.
+// The senders are the names the other messenger used, the import maps them to the users.
+type ArchiveChat struct {
+	Version  int              `json:"version"`
+	Chat     ArchiveChatInfo  `json:"chat"`
+	Messages []ArchiveMessage `json:"messages"`
+}
+
+type ArchiveChatInfo struct {
+	ChatID       int64     `json:"chat_id,omitempty"`
+	Name         string    `json:"name"`
+	Description  string    `json:"description,omitempty"`
+	Type         string    `json:"type"`
+	CreatedAt    time.Time `json:"created_at"`
+	Participants []string  `json:"participants,omitempty"` // of a channel, listed to its admins only
+	// SubscribersCount is set for a channel
+	SubscribersCount *int `json:"subscribers_count,omitempty"`
+}
+
+type ArchiveMessage struct {
+	ID          string              `json:"id"`
+	Sender      string              `json:"sender"`
+	Type        string              `json:"type,omitempty"`
+	Content     string              `json:"content,omitempty"`
+	CreatedAt   time.Time           `json:"created_at"`
+	EditedAt    *time.Time          `json:"edited_at,omitempty"`
+	ReplyTo     string              `json:"reply_to,omitempty"`
+	ThreadID    string              `json:"thread_id,omitempty"`
+	Forwarded   bool                `json:"forwarded,omitempty"`
+	Attachments []ArchiveAttachment `json:"attachments,omitempty"`
+	Locations   []Location          `json:"locations,omitempty"`
+	Poll        *Poll               `json:"poll,omitempty"`
+}
+
+// ArchiveAttachment is a file or a media of the message, Path is empty if its content isn't stored by chat.api
+type ArchiveAttachment struct {
+	Kind string `json:"kind"`
+	ID   int64  `json:"id"`
+	Type string `json:"type"`
+	Size int64  `json:"size,omitempty"`
+	Path string `json:"path,omitempty"`
+}
+
+// ImportRequest recreates the chat of the archive as a group chat of the user,
+// Senders maps every sender of the archive to a user_id. Only the messages of the user keep their sender,
+// the others are stored as sent by the user under the name of the archive
+type ImportRequest struct {
+	UserID  string            `json:"-"`
+	Senders map[string]string `json:"senders"`
+	Archive ArchiveChat       `json:"archive"`
+}
+
+// ImportedMessage is the message to store, ReplyTo is the index of an earlier message or -1
+type ImportedMessage struct {
+	MessageDB MessageDB
+	ReplyTo   int
+}
+
+type ChatImport struct {
+	ChatID          int64
+	UserID          string
+	ParticipantsIDs []string
+	Messages        []ImportedMessage
+}
+
+type ImportResult struct {
+	Chat     ChatDB `json:"chat"`
+	Imported int    `json:"imported"`
+	Skipped  int    `json:"skipped"` // messages without a text, the attachments aren't imported
+}
+
+// ArchiveMessageID is the id of the stored message in the archive
+func ArchiveMessageID(messageID int64) string {
+	return strconv.FormatInt(messageID, 10)
+}
+
+// IsValid checks the archive as a whole, the messages without a content are skipped by the import
+func (r ImportRequest) IsValid() bool {
+	if r.Archive.Version != ARCHIVE_VERSION {
+		return false
+	}
+	if r.Archive.Chat.Name == "" || len([]rune(r.Archive.Chat.Name)) > CHAT_NAME_MAX_LENGTH ||
+		len([]rune(r.Archive.Chat.Description)) > CHAT_DESCRIPTION_MAX_LENGTH {
+		return false
+	}
+	if len(r.Archive.Messages) == 0 || len(r.Archive.Messages) > IMPORT_MESSAGES_LIMIT {
+		return false
+	}
+	if len(r.Senders) == 0 || len(r.Senders) > IMPORT_SENDERS_LIMIT {
+		return false
+	}
+
+	now := time.Now()
+	ids := make(map[string]struct{}, len(r.Archive.Messages))
+	for _, message := range r.Archive.Messages {
+		if message.ID == "" || message.CreatedAt.IsZero() || message.CreatedAt.After(now) {
+			return false
+		}
+		if message.EditedAt != nil && message.EditedAt.Before(message.CreatedAt) {
+			return false
+		}
+		if _, ok := r.Senders[message.Sender]; !ok || len([]rune(message.Sender)) > IMPORT_SENDER_LIMIT {
+			return false
+		}
+		if len([]rune(message.Content)) > IMPORT_CONTENT_LIMIT {
+			return false
+		}
+		if _, ok := ids[message.ID]; ok {
+			return false
+		}
+		ids[message.ID] = struct{}{}
+	}
+
+	return true
+}
+
+// ImportedMessages orders the messages of the archive by the time, drops those without a content
+// and maps the senders, the replies to the dropped or later messages become plain messages.
+// Nobody else is made the sender of what the user uploads, the messages of the others keep the name of the archive
+func (r ImportRequest) ImportedMessages() (messages []ImportedMessage, contents []string) {
+	archived := make([]ArchiveMessage, len(r.Archive.Messages))
+	copy(archived, r.Archive.Messages)
+	sort.SliceStable(archived, func(i, j int) bool {
+		return archived[i].CreatedAt.Before(archived[j].CreatedAt)
+	})
+
+	indexes := make(map[string]int, len(archived))
+	for _, message := range archived {
+		if message.Content == "" {
+			continue
+		}
+
+		replyTo := -1
+		if index, ok := indexes[message.ReplyTo]; ok && message.ReplyTo != "" {
+			replyTo = index
+		}
+
+		updatedAt := message.CreatedAt.UTC()
+		if message.EditedAt != nil {
+			updatedAt = message.EditedAt.UTC()
+		}
+
+		var senderName *string
+		if r.Senders[message.Sender] != r.UserID {
+			senderName = &message.Sender
+		}
+
+		indexes[message.ID] = len(messages)
+		messages = append(messages, ImportedMessage{
+			MessageDB: MessageDB{
+				SenderID:           r.UserID,
+				Status:             MESSAGE_READ,
+				Type:               MESSAGE_TEXT,
+				CreatedAt:          message.CreatedAt.UTC(),
+				UpdatedAt:          updatedAt,
+				ImportedSenderName: senderName,
+			},
+			ReplyTo: replyTo,
+		})
+		contents = append(contents, message.Content)
+	}
+
+	return messages, contents
+}
@@ -8,6 +8,10 @@ type File struct {
 	Type       string    `json:"type" db:"type"`
 	Size       int64     `json:"size,omitempty" db:"size"`
 	UploadedAt time.Time `json:"uploaded_at,omitempty" db:"uploaded_at"`
+
+	// set for the voice uploads only
+	Duration *float64 `json:"duration,omitempty" db:"duration"`
+	Waveform Waveform `json:"waveform,omitempty" db:"waveform"`
 }
 
 type MessageFile struct {
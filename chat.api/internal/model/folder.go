@@ -0,0 +1,145 @@
+package model
+
+import "time"
+
+const (
+	CHAT_FOLDERS_LIMIT          = 10
+	CHAT_FOLDER_NAME_MAX_LENGTH = 32
+	CHAT_FOLDER_CHATS_LIMIT     = 100 // chats a folder includes or excludes by hand
+	CHAT_LIST_LIMIT_REQUEST     = 50
+)
+
+// ChatState is how the chat looks in the list of the user
+type ChatState struct {
+	ChatID            int64  `json:"chat_id" db:"chat_id"`
+	UserID            string `json:"-" db:"user_id"`
+	Archived          bool   `json:"archived" db:"archived"`
+	MarkedUnread      bool   `json:"marked_unread" db:"marked_unread"`
+	Position          *int   `json:"position,omitempty" db:"position"` // manual place in the list, the lower comes first
+	LastReadMessageID int64  `json:"last_read_message_id" db:"last_read_message_id"`
+}
+
+type UpdateChatStateRequest struct {
+	ChatID       int64  `json:"-"`
+	UserID       string `json:"-"`
+	Archived     *bool  `json:"archived"`
+	MarkedUnread *bool  `json:"marked_unread"`
+	Position     *int   `json:"position"` // 0 returns the chat to the order by activity
+}
+
+type ReadChatRequest struct {
+	ChatID    int64  `json:"-"`
+	UserID    string `json:"-"`
+	MessageID int64  `json:"message_id"` // messages up to it are read
+}
+
+// ChatFolder shows the chats matching its rules along with the included ones, the excluded chats are never shown
+type ChatFolder struct {
+	FolderID        int64     `json:"folder_id" db:"folder_id"`
+	UserID          string    `json:"-" db:"user_id"`
+	Name            string    `json:"name" db:"name"`
+	Position        int       `json:"position" db:"position"`
+	IncludePrivate  bool      `json:"include_private" db:"include_private"`
+	IncludeGroups   bool      `json:"include_groups" db:"include_groups"`
+	IncludeChannels bool      `json:"include_channels" db:"include_channels"`
+	OnlyUnread      bool      `json:"only_unread" db:"only_unread"`
+	IncludeArchived bool      `json:"include_archived" db:"include_archived"`
+	IncludedChatIDs []int64   `json:"included_chat_ids" db:"-"`
+	ExcludedChatIDs []int64   `json:"excluded_chat_ids" db:"-"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+}
+
+// ChatFolderChat is the chat included or excluded by the folder by hand
+type ChatFolderChat struct {
+	FolderID int64 `db:"folder_id"`
+	ChatID   int64 `db:"chat_id"`
+	Excluded bool  `db:"excluded"`
+}
+
+// ChatListFilter selects the chats of the list, the included chats match whatever the rules are
+type ChatListFilter struct {
+	Types           []string
+	OnlyUnread      bool
+	Archived        *bool // nil matches both the archived and the other chats
+	IncludedChatIDs []int64
+	ExcludedChatIDs []int64
+}
+
+type ChatListRequest struct {
+	UserID   string
+	FolderID int64 // 0 is the whole list
+	Archived bool  // the archive instead of the main list, the folder decides it by itself
+	Offset   int
+	Limit    int
+}
+
+// ChatListItem is the chat along with its state and activity as seen by the user
+type ChatListItem struct {
+	ChatDB            `json:"chat"`
+	Archived          bool      `json:"archived" db:"archived"`
+	MarkedUnread      bool      `json:"marked_unread" db:"marked_unread"`
+	Position          *int      `json:"position,omitempty" db:"position"`
+	LastReadMessageID int64     `json:"last_read_message_id" db:"last_read_message_id"`
+	LastMessageID     *int64    `json:"last_message_id,omitempty" db:"last_message_id"`
+	LastActivityAt    time.Time `json:"last_activity_at" db:"last_activity_at"` // the last message or the creation of the chat
+	UnreadCount       int       `json:"unread_count" db:"unread_count"`
+}
+
+func (r UpdateChatStateRequest) IsValid() bool {
+	if r.Archived == nil && r.MarkedUnread == nil && r.Position == nil {
+		return false
+	}
+	return r.Position == nil || *r.Position >= 0
+}
+
+func (f ChatFolder) IsValid() bool {
+	if f.Name == "" || len([]rune(f.Name)) > CHAT_FOLDER_NAME_MAX_LENGTH || f.Position < 0 {
+		return false
+	}
+	if !f.IncludePrivate && !f.IncludeGroups && !f.IncludeChannels && len(f.IncludedChatIDs) == 0 {
+		return false
+	}
+	if len(f.IncludedChatIDs)+len(f.ExcludedChatIDs) > CHAT_FOLDER_CHATS_LIMIT {
+		return false
+	}
+
+	seen := make(map[int64]struct{}, len(f.IncludedChatIDs)+len(f.ExcludedChatIDs))
+	for _, chatID := range append(append([]int64{}, f.IncludedChatIDs...), f.ExcludedChatIDs...) {
+		if _, ok := seen[chatID]; ok {
+			return false
+		}
+		seen[chatID] = struct{}{}
+	}
+	return true
+}
+
+// Filter turns the rules of the folder into the filter of the chat list, archived chats match only if the folder includes them
+func (f ChatFolder) Filter() ChatListFilter {
+	filter := ChatListFilter{
+		OnlyUnread:      f.OnlyUnread,
+		IncludedChatIDs: f.IncludedChatIDs,
+		ExcludedChatIDs: f.ExcludedChatIDs,
+	}
+	if f.IncludePrivate {
+		filter.Types = append(filter.Types, CHAT_TYPE_PRIVATE)
+	}
+	if f.IncludeGroups {
+		filter.Types = append(filter.Types, CHAT_TYPE_GROUP)
+	}
+	if f.IncludeChannels {
+		filter.Types = append(filter.Types, CHAT_TYPE_CHANNEL)
+	}
+	if !f.IncludeArchived {
+		archived := false
+		filter.Archived = &archived
+	}
+	return filter
+}
+
+// NewChatListFilter selects all chats of the main list or of the archive
+func NewChatListFilter(archived bool) ChatListFilter {
+	return ChatListFilter{
+		Types:    []string{CHAT_TYPE_PRIVATE, CHAT_TYPE_GROUP, CHAT_TYPE_CHANNEL},
+		Archived: &archived,
+	}
+}
@@ -0,0 +1,98 @@
+package model
+
+import "time"
+
+const (
+	JOIN_REQUEST_PENDING  = "pending"
+	JOIN_REQUEST_APPROVED = "approved"
+	JOIN_REQUEST_REJECTED = "rejected"
+
+	INVITE_LINK_NAME_MAX_LENGTH = 32
+	INVITE_LINK_USAGE_LIMIT_MAX = 100000
+	INVITE_LINKS_LIMIT_REQUEST  = 100
+)
+
+type InviteLink struct {
+	InviteLinkID     int64      `json:"invite_link_id" db:"invite_link_id"`
+	ChatID           int64      `json:"chat_id" db:"chat_id"`
+	CreatorID        string     `json:"creator_id" db:"creator_id"`
+	Token            string     `json:"token" db:"token"`
+	Name             *string    `json:"name,omitempty" db:"name"`
+	ExpiresAt        *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	UsageLimit       *int       `json:"usage_limit,omitempty" db:"usage_limit"` // unlimited if nil
+	UsageCount       int        `json:"usage_count" db:"usage_count"`
+	RequiresApproval bool       `json:"requires_approval" db:"requires_approval"`
+	Revoked          bool       `json:"revoked" db:"revoked"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
+}
+
+type CreateInviteLinkRequest struct {
+	ChatID           int64      `json:"-"`
+	UserID           string     `json:"-"`
+	Name             *string    `json:"name"`
+	ExpiresAt        *time.Time `json:"expires_at"`
+	UsageLimit       *int       `json:"usage_limit"`
+	RequiresApproval bool       `json:"requires_approval"`
+}
+
+type JoinRequest struct {
+	ChatID       int64      `json:"chat_id" db:"chat_id"`
+	UserID       string     `json:"user_id" db:"user_id"`
+	InviteLinkID *int64     `json:"invite_link_id,omitempty" db:"invite_link_id"`
+	Status       string     `json:"status" db:"status"`
+	RequestedAt  time.Time  `json:"requested_at" db:"requested_at"`
+	DecidedBy    *string    `json:"decided_by,omitempty" db:"decided_by"`
+	DecidedAt    *time.Time `json:"decided_at,omitempty" db:"decided_at"`
+}
+
+type JoinChatResponse struct {
+	Chat   ChatDB `json:"chat"`
+	Joined bool   `json:"joined"` // false if the request waits for the approval of the admins
+	// AdminsIDs are the admins to notify about the pending request
+	AdminsIDs []string `json:"-"`
+}
+
+type DecideJoinRequest struct {
+	ChatID  int64
+	UserID  string // who asked to join
+	AdminID string
+	Approve bool
+}
+
+// ChatMemberEvent tells the participants who joined or left the chat
+type ChatMemberEvent struct {
+	Type    string `json:"type"`
+	ChatID  int64  `json:"chat_id"`
+	UserID  string `json:"user_id"`
+	Action  string `json:"action"`
+	ActorID string `json:"actor_id,omitempty"`
+}
+
+type JoinRequestEvent struct {
+	Type        string      `json:"type"`
+	JoinRequest JoinRequest `json:"join_request"`
+}
+
+func (r CreateInviteLinkRequest) IsValid() bool {
+	if r.Name != nil && (*r.Name == "" || len([]rune(*r.Name)) > INVITE_LINK_NAME_MAX_LENGTH) {
+		return false
+	}
+	if r.ExpiresAt != nil && !r.ExpiresAt.After(time.Now()) {
+		return false
+	}
+	if r.UsageLimit != nil && (*r.UsageLimit < 1 || *r.UsageLimit > INVITE_LINK_USAGE_LIMIT_MAX) {
+		return false
+	}
+	return true
+}
+
+// IsUsable reports whether the link can still let somebody in
+func (l InviteLink) IsUsable(now time.Time) bool {
+	if l.Revoked {
+		return false
+	}
+	if l.ExpiresAt != nil && !l.ExpiresAt.After(now) {
+		return false
+	}
+	return l.UsageLimit == nil || l.UsageCount < *l.UsageLimit
+}
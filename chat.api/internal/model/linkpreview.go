@@ -0,0 +1,92 @@
+package model
+
+import (
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	LINK_PREVIEW_CACHE_TTL        = 24 * time.Hour
+	LINK_PREVIEW_FAILED_CACHE_TTL = time.Hour // a page without metadata isn't fetched again for a while
+
+	LINK_PREVIEW_TITLE_MAX_LENGTH       = 300
+	LINK_PREVIEW_DESCRIPTION_MAX_LENGTH = 1000
+)
+
+// LinkPreview is the Open Graph or Twitter card metadata of the first link of the message
+type LinkPreview struct {
+	URL         string `json:"url"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	ImageURL    string `json:"image_url,omitempty"`
+	SiteName    string `json:"site_name,omitempty"`
+}
+
+// LinkPreviewJob is the RabbitMQ job fetching the preview of the link of the message
+type LinkPreviewJob struct {
+	MessageID int64  `json:"message_id"`
+	ChatID    int64  `json:"chat_id"`
+	URL       string `json:"url"`
+}
+
+type MessageUpdatedEvent struct {
+	Type        string       `json:"type"`
+	ChatID      int64        `json:"chat_id"`
+	MessageID   int64        `json:"message_id"`
+	LinkPreview *LinkPreview `json:"link_preview,omitempty"`
+}
+
+func (p LinkPreview) IsEmpty() bool {
+	return p.Title == "" && p.Description == "" && p.ImageURL == ""
+}
+
+var linkPattern = regexp.MustCompile(`(?i)\bhttps?://[^\s<>"]+`)
+
+// FirstLink returns the first http(s) link of the plain content, written out or behind a text link.
+// Links written inside code and pre aren't previewed.
+func FirstLink(content *string, entities *[]MessageEntity) string {
+	if content == nil {
+		return ""
+	}
+
+	var sorted []MessageEntity
+	if entities != nil {
+		sorted = SortEntities(*entities)
+	}
+
+	first, firstOffset := "", -1
+	for _, entity := range sorted {
+		if entity.Type == ENTITY_TEXT_LINK && entity.URL != nil && isPreviewable(*entity.URL) {
+			first, firstOffset = *entity.URL, entity.Offset
+			break
+		}
+	}
+
+	for _, match := range linkPattern.FindAllStringIndex(*content, -1) {
+		offset := utf8.RuneCountInString((*content)[:match[0]])
+		if firstOffset >= 0 && offset >= firstOffset {
+			break
+		}
+
+		link := strings.TrimRight((*content)[match[0]:match[1]], ".,;:!?)]}'")
+		if !isInsideCode(sorted, offset) && isPreviewable(link) {
+			return link
+		}
+	}
+	return first
+}
+
+func isInsideCode(entities []MessageEntity, offset int) bool {
+	for _, entity := range entities {
+		if (entity.Type == ENTITY_CODE || entity.Type == ENTITY_PRE) && offset >= entity.Offset && offset < entity.Offset+entity.Length {
+			return true
+		}
+	}
+	return false
+}
+
+func isPreviewable(link string) bool {
+	return isValidEntityURL(link) && !strings.HasPrefix(strings.ToLower(link), "mailto:")
+}
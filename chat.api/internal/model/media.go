@@ -9,14 +9,67 @@ const (
 	MEDIA_TYPE_MP4  = "video/mp4"
 	MEDIA_TYPE_WEBM = "video/webm"
 	MEDIA_TYPE_WEBP = "image/webp"
+
+	MEDIA_STATUS_PENDING  = "pending"
+	MEDIA_STATUS_READY    = "ready"
+	MEDIA_STATUS_FAILED   = "failed"   // served without the preview
+	MEDIA_STATUS_REJECTED = "rejected" // the GPS data can't be removed, never served
+
+	THUMBNAIL_MAX_SIZE = 320
+	THUMBNAIL_TYPE     = MEDIA_TYPE_JPEG
+
+	// the media pending longer than the timeout lost its job, it's queued again up to the max attempts and failed then
+	MEDIA_PROCESSING_TIMEOUT      = 10 * time.Minute
+	MEDIA_PROCESSING_MAX_ATTEMPTS = 3
+	MEDIA_SWEEP_BATCH_SIZE        = 100
 )
 
 type Media struct {
-	MediaID    int64
-	URL        string
-	Type       string
-	Size       int64
-	UploadedAt time.Time
+	MediaID    int64     `json:"media_id,omitempty" db:"media_id"`
+	URL        string    `json:"url" db:"url"`
+	Type       string    `json:"type" db:"type"`
+	Size       int64     `json:"size,omitempty" db:"size"`
+	UploadedAt time.Time `json:"uploaded_at,omitempty" db:"uploaded_at"`
+
+	// filled by the processing of the uploaded media, Status is pending until then
+	Width           *int     `json:"width,omitempty" db:"width"`
+	Height          *int     `json:"height,omitempty" db:"height"`
+	Duration        *float64 `json:"duration,omitempty" db:"duration"`
+	BlurHash        *string  `json:"blurhash,omitempty" db:"blurhash"`
+	ThumbnailSHA256 *string  `json:"thumbnail_sha256,omitempty" db:"thumbnail_sha256"`
+	Status          string   `json:"status,omitempty" db:"processing_status"`
+}
+
+// MediaJob is the RabbitMQ job processing the uploaded media
+type MediaJob struct {
+	MediaID int64 `json:"media_id"`
+}
+
+// MediaSource is the uploaded media with the blob to process
+type MediaSource struct {
+	Media
+	SHA256     string `db:"sha256"`
+	StorageKey string `db:"storage_key"`
+	UploadedBy string `db:"uploaded_by"`
+}
+
+// MediaLocation is the message of the chat the media is attached to
+type MediaLocation struct {
+	ChatID    int64 `db:"chat_id"`
+	MessageID int64 `db:"message_id"`
+}
+
+type ProcessedMedia struct {
+	Media      Media
+	UploadedBy string
+	Locations  []MediaLocation
+}
+
+type MediaProcessedEvent struct {
+	Type      string `json:"type"`
+	ChatID    int64  `json:"chat_id,omitempty"`
+	MessageID int64  `json:"message_id,omitempty"`
+	Media     Media  `json:"media"`
 }
 
 type MessageMedia struct {
@@ -0,0 +1,84 @@
+package model
+
+const (
+	CHAT_MEMBERS_ADD_LIMIT      = 50
+	CHAT_NAME_MAX_LENGTH        = 50
+	CHAT_DESCRIPTION_MAX_LENGTH = 1000
+)
+
+type AddMembersRequest struct {
+	ChatID   int64    `json:"-"`
+	UserID   string   `json:"-"` // who adds
+	UsersIDs []string `json:"user_ids"`
+}
+
+// AddMembersResponse holds the chat along with the users who weren't participants before
+type AddMembersResponse struct {
+	Chat     ChatDB   `json:"chat"`
+	AddedIDs []string `json:"added_ids"`
+}
+
+type KickMemberRequest struct {
+	ChatID   int64  `json:"-"`
+	UserID   string `json:"-"` // who kicks
+	MemberID string `json:"-"`
+	Ban      bool   `json:"-"` // the member can't come back by the invite links
+}
+
+type UpdateChatInfoRequest struct {
+	ChatID      int64   `json:"-"`
+	UserID      string  `json:"-"`
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	AvatarURL   *string `json:"avatar_url"`
+
+	// of a channel only, the empty handle makes the channel private
+	Handle       *string `json:"handle"`
+	SignMessages *bool   `json:"sign_messages"`
+}
+
+type TransferOwnershipRequest struct {
+	ChatID       int64  `json:"-"`
+	UserID       string `json:"-"`
+	NewCreatorID string `json:"user_id"`
+}
+
+// ChatInfoEvent carries the chat after a change of its name, description, avatar or creator
+type ChatInfoEvent struct {
+	Type    string `json:"type"`
+	Chat    ChatDB `json:"chat"`
+	ActorID string `json:"actor_id"`
+}
+
+func (r AddMembersRequest) IsValid() bool {
+	if len(r.UsersIDs) == 0 || len(r.UsersIDs) > CHAT_MEMBERS_ADD_LIMIT {
+		return false
+	}
+	for _, userID := range r.UsersIDs {
+		if userID == "" {
+			return false
+		}
+	}
+	return true
+}
+
+func (r UpdateChatInfoRequest) IsValid() bool {
+	if r.Name == nil && r.Description == nil && r.AvatarURL == nil && r.Handle == nil && r.SignMessages == nil {
+		return false
+	}
+	if r.Name != nil && (*r.Name == "" || len([]rune(*r.Name)) > CHAT_NAME_MAX_LENGTH) {
+		return false
+	}
+	if r.Description != nil && len([]rune(*r.Description)) > CHAT_DESCRIPTION_MAX_LENGTH {
+		return false
+	}
+	if r.Handle != nil && *r.Handle != "" && !IsValidChannelHandle(*r.Handle) {
+		return false
+	}
+	return true
+}
+
+// IsChannelOnly reports whether the request changes what only a channel has
+func (r UpdateChatInfoRequest) IsChannelOnly() bool {
+	return r.Handle != nil || r.SignMessages != nil
+}
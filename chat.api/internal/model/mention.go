@@ -0,0 +1,68 @@
+package model
+
+import (
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	MENTION_MAX_COUNT = 50
+)
+
+// Mention points to the part of the plain content addressing the participant, offset and length are in characters
+type Mention struct {
+	UserID string `json:"user_id" db:"user_id"`
+	Offset int    `json:"offset" db:"mention_offset"`
+	Length int    `json:"length" db:"mention_length"`
+}
+
+// UnreadMention is the message of the chat mentioning the user which they haven't read yet
+type UnreadMention struct {
+	MessageID int64     `json:"message_id" db:"message_id"`
+	SenderID  string    `json:"sender_id" db:"sender_id"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+type ReadMentionsRequest struct {
+	ChatID    int64  `json:"-"`
+	UserID    string `json:"-"`
+	MessageID int64  `json:"message_id"` // mentions up to the message are read
+}
+
+// IsValidMentions checks the mentions against the plain content, they must not overlap
+func IsValidMentions(content *string, mentions *[]Mention) bool {
+	if mentions == nil || len(*mentions) == 0 {
+		return true
+	}
+	if content == nil || len(*mentions) > MENTION_MAX_COUNT {
+		return false
+	}
+
+	length := utf8.RuneCountInString(*content)
+	taken := make([]bool, length)
+	for _, mention := range *mentions {
+		if mention.UserID == "" || mention.Offset < 0 || mention.Length <= 0 || mention.Offset+mention.Length > length {
+			return false
+		}
+		for i := mention.Offset; i < mention.Offset+mention.Length; i++ {
+			if taken[i] {
+				return false
+			}
+			taken[i] = true
+		}
+	}
+	return true
+}
+
+// IsMentioned tells whether the message mentions the user
+func IsMentioned(message SendMessage, userID string) bool {
+	if message.Mentions == nil {
+		return false
+	}
+	for _, mention := range *message.Mentions {
+		if mention.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
@@ -4,6 +4,9 @@ import "time"
 
 const (
 	MESSAGE_LIMIT_REQUEST = 75
+	FORWARD_LIMIT_REQUEST = 100
+	EXPIRED_BATCH_SIZE    = 500
+	MESSAGE_TTL_MAX       = 365 * 24 * 60 * 60 // seconds
 	MESSAGE_SENT          = "sent"
 	MESSAGE_DELIVERED     = "delivered"
 	MESSAGE_READ          = "read"
@@ -13,6 +16,8 @@ const (
 	MESSAGE_FILE     = "file"
 	MESSAGE_LOCATION = "location"
 	MESSAGE_MIXED    = "mixed"
+	MESSAGE_VOICE    = "voice"
+	MESSAGE_POLL     = "poll"
 
 	VERY_HIGH = iota + 1 // 1
 	HIGH                 // 2
@@ -21,14 +26,29 @@ const (
 	VERY_LOW             // 5
 )
 
+const PINNED_MESSAGE_PRIORITY_MAX = 5 // the check of pinned_messages.priority
+
 type MessageDB struct {
-	MessageID int64     `json:"message_id,omitempty" db:"message_id"`
-	SenderID  string    `json:"sender_id" db:"sender_id"`
-	Content   *string   `json:"content,omitempty" db:"content"`
-	Status    string    `json:"status" db:"status"`
-	Type      string    `json:"type" db:"type"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	MessageID        int64     `json:"message_id,omitempty" db:"message_id"`
+	SenderID         string    `json:"sender_id" db:"sender_id"`
+	Content          *string   `json:"content,omitempty" db:"content"`
+	Status           string    `json:"status" db:"status"`
+	Type             string    `json:"type" db:"type"`
+	CreatedAt        time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at" db:"updated_at"`
+	ReplyToMessageID *int64    `json:"reply_to_message_id,omitempty" db:"reply_to_message_id"`
+	ThreadID         *int64    `json:"thread_id,omitempty" db:"thread_id"` // root message of the thread
+
+	ForwardedFromSenderID *string `json:"forwarded_from_sender_id,omitempty" db:"forwarded_from_sender_id"`
+	ForwardedFromChatID   *int64  `json:"forwarded_from_chat_id,omitempty" db:"forwarded_from_chat_id"`
+
+	ExpiresAt *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+
+	// EncryptedEntities is the stored form of SendMessage.Entities, clients get the decrypted ones
+	EncryptedEntities *string `json:"encrypted_entities,omitempty" db:"entities"`
+
+	// ImportedSenderName is who sent the imported message in the other messenger, the importer is SenderID then
+	ImportedSenderName *string `json:"imported_sender_name,omitempty" db:"imported_sender_name"`
 }
 
 type MessageBriefInfo struct {
@@ -36,19 +56,52 @@ type MessageBriefInfo struct {
 	SenderID  string    `json:"sender_id" db:"sender_id"`
 	Type      string    `json:"type" db:"type"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	Duration  *float64  `json:"duration,omitempty" db:"-"` // seconds of a voice message
 }
 
 type Message struct {
 	MessageWithData SendMessage
 	PinnedMessage   *PinnedMessage
 	Action          *[]MessageAction
+	Reactions       *[]ReactionCount
+	Thread          *MessageThread
+	Listens         *[]VoiceListen // recipients who played the voice message
 }
 
 type SendMessage struct {
-	MessageDB MessageDB   `json:"message"`
-	Media     *[]Media    `json:"media"`
-	Locations *[]Location `json:"locations"`
-	Files     *[]File     `json:"files"`
+	MessageDB MessageDB      `json:"message"`
+	Media     *[]Media       `json:"media"`
+	Locations *[]Location    `json:"locations"`
+	Files     *[]File        `json:"files"`
+	ReplyTo   *QuotedMessage `json:"reply_to,omitempty"`
+	Poll      *Poll          `json:"poll,omitempty"`
+	Mentions  *[]Mention     `json:"mentions,omitempty"`
+
+	Entities  *[]MessageEntity `json:"entities,omitempty"`
+	ParseMode string           `json:"parse_mode,omitempty"` // markdown turns the content into the plain one with entities
+
+	LinkPreview          *LinkPreview `json:"link_preview,omitempty"`
+	EncryptedLinkPreview *string      `json:"encrypted_link_preview,omitempty"` // stored form of LinkPreview
+
+	Post *ChannelPost `json:"post,omitempty"` // views and the signature of the message of a channel
+}
+
+// QuotedMessage is the brief info of the message being answered
+type QuotedMessage struct {
+	MessageID int64     `json:"message_id"`
+	SenderID  string    `json:"sender_id"`
+	Content   *string   `json:"content,omitempty"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+
+	Entities          *[]MessageEntity `json:"entities,omitempty"`
+	EncryptedEntities *string          `json:"encrypted_entities,omitempty"`
+}
+
+type MessageThread struct {
+	MessageID   int64     `json:"message_id" db:"message_id"`
+	ReplyCount  int       `json:"reply_count" db:"reply_count"`
+	LastReplyAt time.Time `json:"last_reply_at" db:"last_reply_at"`
 }
 
 type PinnedMessage struct {
@@ -61,4 +114,101 @@ type PinnedMessage struct {
 type CreateMessageRequest struct {
 	ChatID          int64       `json:"chat_id,omitempty"`
 	MessageWithData SendMessage `json:"first_message"`
+	InThread        bool        `json:"in_thread,omitempty"` // reply goes to the thread of reply_to_message_id
+	TTL             *int        `json:"ttl,omitempty"`       // overrides message_ttl of the chat, 0 keeps the message
+	Signature       string      `json:"-"`                   // name of the author if the channel signs its posts
+}
+
+type DeletedMessage struct {
+	MessageID int64 `db:"message_id"`
+	ChatID    int64 `db:"chat_id"`
+}
+
+type PinnedMessageEvent struct {
+	Type          string        `json:"type"`
+	PinnedMessage PinnedMessage `json:"pinned_message"`
+	Pinned        bool          `json:"pinned"` // false if the message was unpinned
+}
+
+type DeleteMessagesEvent struct {
+	Type       string  `json:"type"`
+	ChatID     int64   `json:"chat_id"`
+	MessageIDs []int64 `json:"message_ids"`
+}
+
+type MessageTTLRequest struct {
+	ChatID     int64  `json:"chat_id"`
+	UserID     string `json:"user_id"`
+	MessageTTL *int   `json:"message_ttl"` // null turns disappearing messages off
+}
+
+// EditMessageRequest replaces the content of the message sent by the user,
+// the entities and mentions are replaced too and point into the new plain content
+type EditMessageRequest struct {
+	ChatID    int64            `json:"chat_id"`
+	MessageID int64            `json:"message_id"`
+	UserID    string           `json:"-"`
+	Content   *string          `json:"content"`
+	Entities  *[]MessageEntity `json:"entities,omitempty"`
+	Mentions  *[]Mention       `json:"mentions,omitempty"`
+	ParseMode string           `json:"parse_mode,omitempty"`
+
+	EncryptedEntities *string `json:"-"`
+}
+
+type MessageEditedEvent struct {
+	Type      string           `json:"type"`
+	ChatID    int64            `json:"chat_id"`
+	MessageID int64            `json:"message_id"`
+	Content   *string          `json:"content,omitempty"`
+	Entities  *[]MessageEntity `json:"entities,omitempty"`
+	Mentions  *[]Mention       `json:"mentions,omitempty"`
+	EditedAt  time.Time        `json:"edited_at"`
+}
+
+type ForwardMessagesRequest struct {
+	FromChatID int64   `json:"from_chat_id"`
+	ToChatID   int64   `json:"to_chat_id"`
+	MessageIDs []int64 `json:"message_ids"`
+	UserID     string  `json:"user_id"`
+}
+
+// ForwardedMessage is the copy of the original message stored by the forward
+type ForwardedMessage struct {
+	OriginalMessageID int64
+	Message           MessageDB
+	Post              *ChannelPost // the copy forwarded into a channel
+	Poll              *Poll        // the copy of the poll without the votes
+	LinkPreview       *string      // stored form of the preview of the copy
+}
+
+func NewQuotedMessage(message MessageDB) *QuotedMessage {
+	return &QuotedMessage{
+		MessageID: message.MessageID,
+		SenderID:  message.SenderID,
+		Content:   message.Content,
+		Type:      message.Type,
+		CreatedAt: message.CreatedAt,
+
+		EncryptedEntities: message.EncryptedEntities,
+	}
+}
+
+func IsValidMessageTTL(ttl *int) bool {
+	return ttl == nil || (*ttl >= 0 && *ttl <= MESSAGE_TTL_MAX)
+}
+
+// MessageExpiresAt picks the ttl of the message over the one of the chat,
+// nil means the message doesn't disappear.
+func MessageExpiresAt(messageTTL, chatTTL *int, sentAt time.Time) *time.Time {
+	ttl := chatTTL
+	if messageTTL != nil {
+		ttl = messageTTL
+	}
+	if ttl == nil || *ttl == 0 {
+		return nil
+	}
+
+	expiresAt := sentAt.Add(time.Duration(*ttl) * time.Second)
+	return &expiresAt
 }
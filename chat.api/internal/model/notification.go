@@ -17,3 +17,7 @@ type NotificationMessage struct {
 	Sender      UserBriefInfo    `json:"sender"`
 	RecipientID string           `json:"recipient_id"`
 }
+
+type NotificationDeletedMessages struct {
+	MessageIDs []int64 `json:"message_ids"`
+}
@@ -0,0 +1,139 @@
+package model
+
+const (
+	PERMISSION_SEND_MESSAGES = 1 << iota
+	PERMISSION_SEND_MEDIA    // media, files, voice, locations and polls
+	PERMISSION_PIN_MESSAGES
+	PERMISSION_INVITE_USERS // invite links, join requests and adding members
+	PERMISSION_CHANGE_INFO  // name, description, avatar and the message TTL
+	PERMISSION_DELETE_MESSAGES
+	PERMISSION_MANAGE_ROLES // roles, default permissions and kicking
+	PERMISSION_REACT        // reactions and poll votes, the channel subscribers keep it
+
+	PERMISSIONS_ALL       = PERMISSION_REACT<<1 - 1
+	PERMISSIONS_MODERATOR = PERMISSION_SEND_MESSAGES | PERMISSION_SEND_MEDIA | PERMISSION_PIN_MESSAGES | PERMISSION_INVITE_USERS | PERMISSION_DELETE_MESSAGES | PERMISSION_REACT
+	// PERMISSIONS_DEFAULT is given to the members of a new group, keep in sync with chats.default_permissions
+	PERMISSIONS_DEFAULT = PERMISSION_SEND_MESSAGES | PERMISSION_SEND_MEDIA | PERMISSION_INVITE_USERS | PERMISSION_REACT
+	// PERMISSIONS_PRIVATE both users of a private chat have, there is nobody to manage
+	PERMISSIONS_PRIVATE = PERMISSION_SEND_MESSAGES | PERMISSION_SEND_MEDIA | PERMISSION_PIN_MESSAGES | PERMISSION_CHANGE_INFO | PERMISSION_REACT
+	// PERMISSIONS_POSTING only the admins of a channel have
+	PERMISSIONS_POSTING = PERMISSION_SEND_MESSAGES | PERMISSION_SEND_MEDIA
+)
+
+// ChatMember is the participant along with everything the permissions depend on
+type ChatMember struct {
+	ChatID             int64   `db:"chat_id"`
+	UserID             string  `db:"user_id"`
+	ChatType           string  `db:"type"`
+	CreatorID          string  `db:"creator_id"`
+	DefaultPermissions int     `db:"default_permissions"`
+	StoredRole         *string `db:"role"`        // nil if the member has no row in chat_roles
+	StoredPermissions  *int    `db:"permissions"` // of the restricted member
+}
+
+type ChatRoleEvent struct {
+	Type     string   `json:"type"`
+	ChatRole ChatRole `json:"chat_role"`
+}
+
+type ChatPermissionsResponse struct {
+	Role               string `json:"role"`
+	Permissions        int    `json:"permissions"`
+	DefaultPermissions int    `json:"default_permissions"`
+}
+
+type DefaultPermissionsRequest struct {
+	ChatID      int64  `json:"-"`
+	UserID      string `json:"-"`
+	Permissions int    `json:"permissions"`
+}
+
+func (m ChatMember) Role() string {
+	if m.CreatorID == m.UserID {
+		return CHAT_ROLE_OWNER
+	}
+	if m.StoredRole == nil {
+		return CHAT_ROLE_MEMBER
+	}
+	return *m.StoredRole
+}
+
+func (m ChatMember) Permissions() int {
+	if m.ChatType == CHAT_TYPE_PRIVATE {
+		return PERMISSIONS_PRIVATE
+	}
+
+	permissions := m.rolePermissions()
+	if m.ChatType == CHAT_TYPE_CHANNEL && !m.IsAdmin() {
+		// the subscribers read the channel, whatever the defaults are
+		return permissions &^ PERMISSIONS_POSTING
+	}
+	return permissions
+}
+
+// IsAdmin reports whether the member is the owner or an admin of the chat
+func (m ChatMember) IsAdmin() bool {
+	return ChatRoleRank(m.Role()) >= ChatRoleRank(CHAT_ROLE_ADMIN)
+}
+
+func (m ChatMember) rolePermissions() int {
+	switch m.Role() {
+	case CHAT_ROLE_OWNER, CHAT_ROLE_ADMIN:
+		return PERMISSIONS_ALL
+	case CHAT_ROLE_MODERATOR:
+		return PERMISSIONS_MODERATOR
+	case CHAT_ROLE_RESTRICTED:
+		// a restriction never gives more than the other members have
+		if m.StoredPermissions == nil {
+			return 0
+		}
+		return *m.StoredPermissions & m.DefaultPermissions
+	default:
+		return m.DefaultPermissions
+	}
+}
+
+func (m ChatMember) Can(permissions int) bool {
+	return m.Permissions()&permissions == permissions
+}
+
+// Outranks reports whether the member can manage somebody with the role
+func (m ChatMember) Outranks(role string) bool {
+	return ChatRoleRank(m.Role()) > ChatRoleRank(role)
+}
+
+func ChatRoleRank(role string) int {
+	switch role {
+	case CHAT_ROLE_OWNER:
+		return 4
+	case CHAT_ROLE_ADMIN:
+		return 3
+	case CHAT_ROLE_MODERATOR:
+		return 2
+	case CHAT_ROLE_MEMBER:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// IsValidChatRole reports whether the role can be granted, the owner changes only by the transfer
+func IsValidChatRole(role string) bool {
+	switch role {
+	case CHAT_ROLE_ADMIN, CHAT_ROLE_MODERATOR, CHAT_ROLE_MEMBER, CHAT_ROLE_RESTRICTED:
+		return true
+	}
+	return false
+}
+
+func IsValidPermissions(permissions int) bool {
+	return permissions >= 0 && permissions&^PERMISSIONS_ALL == 0
+}
+
+// MessagePermissions returns what it takes to send the message of the type
+func MessagePermissions(messageType string) int {
+	if messageType == MESSAGE_TEXT {
+		return PERMISSION_SEND_MESSAGES
+	}
+	return PERMISSION_SEND_MESSAGES | PERMISSION_SEND_MEDIA
+}
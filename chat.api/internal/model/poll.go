@@ -0,0 +1,171 @@
+package model
+
+import (
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	POLL_MIN_OPTIONS         = 2
+	POLL_MAX_OPTIONS         = 10
+	POLL_QUESTION_MAX_LENGTH = 300
+	POLL_OPTION_MAX_LENGTH   = 100
+	POLL_CLOSE_BATCH_SIZE    = 100
+)
+
+// Poll of a poll message. Question and the texts of the options are encrypted like the content.
+type Poll struct {
+	MessageID      int64        `json:"message_id,omitempty" db:"message_id"`
+	Question       string       `json:"question" db:"question"`
+	Options        []PollOption `json:"options"`
+	MultipleChoice bool         `json:"multiple_choice" db:"multiple_choice"`
+	Anonymous      bool         `json:"anonymous" db:"anonymous"`
+	Quiz           bool         `json:"quiz" db:"quiz"`
+	CorrectOption  *int         `json:"correct_option,omitempty" db:"correct_option"` // quiz only
+	CloseAt        *time.Time   `json:"close_at,omitempty" db:"close_at"`
+	ClosedAt       *time.Time   `json:"closed_at,omitempty" db:"closed_at"`
+	Results        *PollResults `json:"results,omitempty" db:"-"`
+}
+
+type PollOption struct {
+	OptionID int    `json:"option_id" db:"option_id"`
+	Text     string `json:"text" db:"text"`
+}
+
+type PollVote struct {
+	MessageID int64     `db:"message_id"`
+	OptionID  int       `db:"option_id"`
+	UserID    string    `db:"user_id"`
+	VotedAt   time.Time `db:"voted_at"`
+}
+
+// PollResults are the results as they are seen by the viewer
+type PollResults struct {
+	TotalVoters int                `json:"total_voters"`
+	Options     []PollOptionResult `json:"options"`
+	Chosen      []int              `json:"chosen,omitempty"`
+}
+
+type PollOptionResult struct {
+	OptionID int      `json:"option_id"`
+	Votes    int      `json:"votes"`
+	Voters   []string `json:"voters,omitempty"` // public polls only
+}
+
+type PollVoteRequest struct {
+	ChatID    int64  `json:"chat_id"`
+	MessageID int64  `json:"message_id"`
+	UserID    string `json:"user_id"`
+	OptionIDs []int  `json:"option_ids"`
+}
+
+// PollState is the poll with all its votes, results are made from it per viewer
+type PollState struct {
+	ChatID int64
+	Poll   Poll
+	Votes  []PollVote
+}
+
+type ClosedPoll struct {
+	MessageID int64 `db:"message_id"`
+	ChatID    int64 `db:"chat_id"`
+}
+
+type PollEvent struct {
+	Type      string `json:"type"`
+	ChatID    int64  `json:"chat_id"`
+	MessageID int64  `json:"message_id"`
+	Poll      Poll   `json:"poll"`
+}
+
+// IsValidPoll checks the structure of a new poll, the texts are checked by IsValidPollText before the encryption
+func IsValidPoll(poll *Poll, now time.Time) bool {
+	if poll == nil || len(poll.Options) < POLL_MIN_OPTIONS || len(poll.Options) > POLL_MAX_OPTIONS {
+		return false
+	}
+	if poll.CloseAt != nil && !poll.CloseAt.After(now) {
+		return false
+	}
+	if poll.Quiz {
+		return !poll.MultipleChoice && poll.CorrectOption != nil && *poll.CorrectOption >= 0 && *poll.CorrectOption < len(poll.Options)
+	}
+	return poll.CorrectOption == nil
+}
+
+func IsValidPollText(poll *Poll) bool {
+	if poll == nil || !isValidText(poll.Question, POLL_QUESTION_MAX_LENGTH) {
+		return false
+	}
+	for _, option := range poll.Options {
+		if !isValidText(option.Text, POLL_OPTION_MAX_LENGTH) {
+			return false
+		}
+	}
+	return true
+}
+
+func isValidText(text string, maxLength int) bool {
+	return text != "" && utf8.ValidString(text) && utf8.RuneCountInString(text) <= maxLength
+}
+
+// IsValidPollVote checks the chosen options against the poll, every vote replaces the previous one
+func IsValidPollVote(poll Poll, optionIDs []int) bool {
+	if len(optionIDs) == 0 || (!poll.MultipleChoice && len(optionIDs) > 1) {
+		return false
+	}
+
+	chosen := make(map[int]bool, len(optionIDs))
+	for _, optionID := range optionIDs {
+		if optionID < 0 || optionID >= len(poll.Options) || chosen[optionID] {
+			return false
+		}
+		chosen[optionID] = true
+	}
+	return true
+}
+
+func (p Poll) IsClosed(now time.Time) bool {
+	return p.ClosedAt != nil || (p.CloseAt != nil && !p.CloseAt.After(now))
+}
+
+// ForViewer attaches the results seen by the viewer. Voters are listed only in public polls
+// and the correct answer of a quiz is revealed once the viewer has voted or the poll is closed.
+func (p Poll) ForViewer(votes []PollVote, viewerID string) Poll {
+	results := &PollResults{Options: make([]PollOptionResult, len(p.Options))}
+	for i, option := range p.Options {
+		results.Options[i].OptionID = option.OptionID
+	}
+
+	voters := make(map[string]bool)
+	for _, vote := range votes {
+		if vote.OptionID < 0 || vote.OptionID >= len(results.Options) {
+			continue
+		}
+		option := &results.Options[vote.OptionID]
+		option.Votes++
+		if !p.Anonymous {
+			option.Voters = append(option.Voters, vote.UserID)
+		}
+		if vote.UserID == viewerID {
+			results.Chosen = append(results.Chosen, vote.OptionID)
+		}
+		voters[vote.UserID] = true
+	}
+	results.TotalVoters = len(voters)
+
+	if p.Quiz && len(results.Chosen) == 0 && p.ClosedAt == nil {
+		p.CorrectOption = nil
+	}
+	p.Results = results
+	return p
+}
+
+// ForSubscriber returns the results as they are seen by the channel subscriber: the counts
+// and the choice of the subscriber, the other voters stay hidden even if the poll is public
+func (p Poll) ForSubscriber(votes []PollVote, viewerID string) Poll {
+	p = p.ForViewer(votes, viewerID)
+	for i := range p.Results.Options {
+		p.Results.Options[i].Voters = nil
+	}
+	return p
+}
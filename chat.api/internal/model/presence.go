@@ -0,0 +1,80 @@
+package model
+
+import "time"
+
+const (
+	PRESENCE_ONLINE  = "online"
+	PRESENCE_AWAY    = "away"
+	PRESENCE_OFFLINE = "offline"
+
+	// PRESENCE_TTL is how long the user stays online after the last frame or ping of the socket,
+	// so the users of the replica which went down without closing the sockets turn offline
+	PRESENCE_TTL = 90 * time.Second
+	// PRESENCE_SUBSCRIPTIONS_MAX is how many users can be watched over one socket
+	PRESENCE_SUBSCRIPTIONS_MAX = 200
+
+	CHAT_ACTIVITY_TYPING    = "typing"
+	CHAT_ACTIVITY_UPLOADING = "uploading"
+
+	// CHAT_ACTIVITY_INTERVAL is how often the same activity of the user is sent to the chat,
+	// clients show it a bit longer and repeat it while the user keeps typing
+	CHAT_ACTIVITY_INTERVAL = 3 * time.Second
+)
+
+// Presence is the status as the subscriber sees it, LastSeenAt is set only for the offline user
+// who doesn't hide it
+type Presence struct {
+	UserID     string     `json:"user_id"`
+	Status     string     `json:"status"`
+	LastSeenAt *time.Time `json:"last_seen_at,omitempty"`
+}
+
+// UserPresence is the stored part of the presence
+type UserPresence struct {
+	UserID       string     `db:"user_id"`
+	LastSeenAt   *time.Time `db:"last_seen_at"`
+	HideLastSeen bool       `db:"hide_last_seen"`
+}
+
+type PresenceSettings struct {
+	HideLastSeen bool `json:"hide_last_seen"`
+}
+
+type PresenceUpdateRequest struct {
+	UserID string `json:"-"`
+	Status string `json:"status"`
+}
+
+type PresenceSubscribeRequest struct {
+	UserID   string   `json:"-"`
+	UsersIDs []string `json:"user_ids"`
+	// Contacts are those of UsersIDs who have the user in their contacts
+	Contacts []string `json:"-"`
+}
+
+type PresenceEvent struct {
+	Type      string     `json:"type"`
+	Presences []Presence `json:"presences"`
+}
+
+type ChatActivityRequest struct {
+	ChatID int64  `json:"chat_id"`
+	UserID string `json:"-"`
+	Action string `json:"action"`
+}
+
+type ChatActivityEvent struct {
+	Type   string `json:"type"`
+	ChatID int64  `json:"chat_id"`
+	UserID string `json:"user_id"`
+	Action string `json:"action"`
+}
+
+// IsValidPresenceStatus accepts the statuses the client can set, offline comes only from closing the socket
+func IsValidPresenceStatus(status string) bool {
+	return status == PRESENCE_ONLINE || status == PRESENCE_AWAY
+}
+
+func IsValidChatActivity(action string) bool {
+	return action == CHAT_ACTIVITY_TYPING || action == CHAT_ACTIVITY_UPLOADING
+}
@@ -0,0 +1,77 @@
+package model
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	REACTION_MAX_LENGTH = 32
+)
+
+type Reaction struct {
+	MessageID int64     `json:"message_id" db:"message_id"`
+	UserID    string    `json:"user_id" db:"user_id"`
+	Emoji     string    `json:"emoji" db:"emoji"`
+	ReactedAt time.Time `json:"reacted_at,omitempty" db:"reacted_at"`
+}
+
+type ReactionCount struct {
+	Emoji       string `json:"emoji"`
+	Count       int    `json:"count"`
+	ReactedByMe bool   `json:"reacted_by_me"`
+}
+
+type ReactionRequest struct {
+	ChatID    int64  `json:"chat_id"`
+	MessageID int64  `json:"message_id"`
+	UserID    string `json:"user_id"`
+	Emoji     string `json:"emoji"`
+}
+
+type ReactionEvent struct {
+	Type      string          `json:"type"`
+	ChatID    int64           `json:"chat_id"`
+	MessageID int64           `json:"message_id"`
+	UserID    string          `json:"user_id,omitempty"` // not sent to the channel subscribers
+	Emoji     string          `json:"emoji,omitempty"`
+	Reactions []ReactionCount `json:"reactions"`
+}
+
+type NotificationReaction struct {
+	Chat        ChatBriefInfo    `json:"chat"`
+	Message     MessageBriefInfo `json:"message"`
+	Sender      UserBriefInfo    `json:"sender"`
+	RecipientID string           `json:"recipient_id"`
+	Emoji       string           `json:"emoji"`
+}
+
+// AggregateReactions groups reactions by emoji as they are seen by the viewer.
+func AggregateReactions(reactions []Reaction, viewerID string) []ReactionCount {
+	counts := make(map[string]*ReactionCount)
+	for _, reaction := range reactions {
+		count, ok := counts[reaction.Emoji]
+		if !ok {
+			count = &ReactionCount{Emoji: reaction.Emoji}
+			counts[reaction.Emoji] = count
+		}
+		count.Count++
+		if reaction.UserID == viewerID {
+			count.ReactedByMe = true
+		}
+	}
+
+	result := make([]ReactionCount, 0, len(counts))
+	for _, count := range counts {
+		result = append(result, *count)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Emoji < result[j].Emoji
+	})
+
+	return result
+}
@@ -0,0 +1,141 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	RETENTION_SCOPE_TYPE = "type" // the default of the chats of the type
+	RETENTION_SCOPE_CHAT = "chat" // overrides the default of its type
+
+	RETENTION_RUN_RUNNING  = "running"
+	RETENTION_RUN_FINISHED = "finished"
+	RETENTION_RUN_FAILED   = "failed"
+
+	RETENTION_DAYS_MIN         = 1
+	RETENTION_DAYS_MAX         = 36500
+	RETENTION_BATCH_SIZE       = 500 // messages purged and audited at once
+	RETENTION_CHATS_BATCH      = 100
+	RETENTION_RUN_TIMEOUT      = 12 * time.Hour // the run still going after is treated as lost with its replica
+	RETENTION_RUNS_LIMIT       = 20
+	RETENTION_PURGES_LIMIT     = 100
+	LEGAL_HOLD_REASON_MAX_SIZE = 1000
+)
+
+// RetentionPolicy deletes the messages of the chats of ChatType, or of the chat ChatID, older than RetentionDays
+type RetentionPolicy struct {
+	PolicyID      int64     `json:"policy_id" db:"policy_id"`
+	ChatType      *string   `json:"chat_type,omitempty" db:"chat_type"`
+	ChatID        *int64    `json:"chat_id,omitempty" db:"chat_id"`
+	RetentionDays int       `json:"retention_days" db:"retention_days"`
+	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type RetentionPolicyRequest struct {
+	ChatType      string `json:"-"`
+	ChatID        int64  `json:"-"`
+	RetentionDays int    `json:"retention_days"`
+}
+
+func (r RetentionPolicyRequest) IsValid() bool {
+	if r.RetentionDays < RETENTION_DAYS_MIN || r.RetentionDays > RETENTION_DAYS_MAX {
+		return false
+	}
+	if r.ChatID != 0 {
+		return r.ChatType == ""
+	}
+	return IsRetentionChatType(r.ChatType)
+}
+
+func IsRetentionChatType(chatType string) bool {
+	return chatType == CHAT_TYPE_PRIVATE || chatType == CHAT_TYPE_GROUP || chatType == CHAT_TYPE_CHANNEL
+}
+
+// ChatRetention is the policy in force for the chat, the one of the chat wins over the one of its type
+type ChatRetention struct {
+	ChatID        int64  `db:"chat_id"`
+	ChatType      string `db:"type"`
+	PolicyID      int64  `db:"policy_id"`
+	Scope         string `db:"scope"`
+	RetentionDays int    `db:"retention_days"`
+}
+
+// PurgedBefore is the time the messages sent before are deleted
+func (r ChatRetention) PurgedBefore(now time.Time) time.Time {
+	return now.AddDate(0, 0, -r.RetentionDays)
+}
+
+// LegalHold suspends every automatic deletion of the messages of the chat or sent by the user until released,
+// the released holds are kept for the audit
+type LegalHold struct {
+	HoldID     int64      `json:"hold_id" db:"hold_id"`
+	ChatID     *int64     `json:"chat_id,omitempty" db:"chat_id"`
+	UserID     *string    `json:"user_id,omitempty" db:"user_id"`
+	Reason     string     `json:"reason" db:"reason"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	ReleasedAt *time.Time `json:"released_at,omitempty" db:"released_at"`
+}
+
+func (h LegalHold) IsValid() bool {
+	if (h.ChatID == nil) == (h.UserID == nil) {
+		return false
+	}
+	if h.UserID != nil && strings.TrimSpace(*h.UserID) == "" {
+		return false
+	}
+	reason := strings.TrimSpace(h.Reason)
+	return reason != "" && len(reason) <= LEGAL_HOLD_REASON_MAX_SIZE
+}
+
+// RetentionRun is the progress of a run of the retention job, one runs at a time across the replicas
+type RetentionRun struct {
+	RunID          int64      `json:"run_id" db:"run_id"`
+	Status         string     `json:"status" db:"status"`
+	ChatsProcessed int        `json:"chats_processed" db:"chats_processed"`
+	MessagesPurged int        `json:"messages_purged" db:"messages_purged"`
+	Error          *string    `json:"error,omitempty" db:"error"`
+	StartedAt      time.Time  `json:"started_at" db:"started_at"`
+	FinishedAt     *time.Time `json:"finished_at,omitempty" db:"finished_at"`
+}
+
+// RetentionPurge is the audit record of a batch of the messages deleted by the policy,
+// the policy is copied as it was since it may change or go away later
+type RetentionPurge struct {
+	PurgeID       int64          `json:"purge_id" db:"purge_id"`
+	RunID         int64          `json:"run_id" db:"run_id"`
+	ChatID        int64          `json:"chat_id" db:"chat_id"`
+	PolicyID      int64          `json:"policy_id" db:"policy_id"`
+	Scope         string         `json:"scope" db:"scope"`
+	RetentionDays int            `json:"retention_days" db:"retention_days"`
+	PurgedBefore  time.Time      `json:"purged_before" db:"purged_before"`
+	MessageIDs    pq.Int64Array  `json:"message_ids" db:"message_ids"`
+	Blobs         pq.StringArray `json:"blobs" db:"blobs"`
+	PurgedAt      time.Time      `json:"purged_at" db:"purged_at"`
+}
+
+// RetentionBatch is the batch purged under PurgeID, BlobSHA256s are the blobs of the deleted attachments
+// which may have no attachments left
+type RetentionBatch struct {
+	PurgeID     int64
+	Deleted     []DeletedMessage
+	BlobSHA256s []string
+}
+
+type RetentionPurgesRequest struct {
+	RunID        int64
+	AfterPurgeID int64
+	Limit        int
+}
+
+// NextRetentionRun is the next time the nightly retention job starts at the hour of the day in UTC
+func NextRetentionRun(now time.Time, hour int) time.Time {
+	now = now.UTC()
+	next := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, time.UTC)
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
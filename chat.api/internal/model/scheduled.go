@@ -0,0 +1,59 @@
+package model
+
+import "time"
+
+const (
+	SCHEDULED_MESSAGE_PENDING = "pending"
+	SCHEDULED_MESSAGE_SENDING = "sending" // claimed by a replica, dispatched outside of the transaction
+	SCHEDULED_MESSAGE_SENT    = "sent"
+	SCHEDULED_MESSAGE_FAILED  = "failed"
+
+	SCHEDULED_MESSAGE_BATCH_SIZE    = 50
+	SCHEDULED_MESSAGE_MAX_ATTEMPTS  = 5
+	SCHEDULED_MESSAGE_RETRY_DELAY   = time.Minute
+	SCHEDULED_MESSAGE_CLAIM_TIMEOUT = 5 * time.Minute // the claim still sending after is treated as lost with its replica
+
+	// SCHEDULED_MESSAGE_CONTENT_ID binds the content of the waiting message, it gets its own id once it's sent
+	SCHEDULED_MESSAGE_CONTENT_ID = 0
+)
+
+// ScheduledMessage is a message waiting to be sent at SendAt,
+// Message keeps the content in the encrypted form bound to Message.MessageDB.MessageID.
+type ScheduledMessage struct {
+	ScheduledMessageID int64       `json:"scheduled_message_id"`
+	ChatID             int64       `json:"chat_id"`
+	SenderID           string      `json:"sender_id"`
+	Message            SendMessage `json:"message"`
+	InThread           bool        `json:"in_thread,omitempty"`
+	SendAt             time.Time   `json:"send_at"`
+	Status             string      `json:"status"`
+	SentMessageID      *int64      `json:"sent_message_id,omitempty"`
+	Attempts           int         `json:"attempts,omitempty"`
+	CreatedAt          time.Time   `json:"created_at"`
+	UpdatedAt          time.Time   `json:"updated_at"`
+}
+
+type ScheduledMessageRequest struct {
+	ScheduledMessageID int64       `json:"scheduled_message_id,omitempty"`
+	ChatID             int64       `json:"chat_id"`
+	UserID             string      `json:"user_id"`
+	Message            SendMessage `json:"message"`
+	InThread           bool        `json:"in_thread,omitempty"`
+	SendAt             time.Time   `json:"send_at"`
+}
+
+// CreateMessageRequest builds the request passed to the usual sending flow when the message is due
+func (m ScheduledMessage) CreateMessageRequest() CreateMessageRequest {
+	message := m.Message
+	message.MessageDB.SenderID = m.SenderID
+	message.MessageDB.Status = MESSAGE_SENT
+	// the times are set once the message is stored
+	message.MessageDB.CreatedAt = time.Time{}
+	message.MessageDB.UpdatedAt = time.Time{}
+
+	return CreateMessageRequest{
+		ChatID:          m.ChatID,
+		MessageWithData: message,
+		InThread:        m.InThread,
+	}
+}
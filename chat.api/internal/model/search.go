@@ -0,0 +1,71 @@
+package model
+
+import "time"
+
+const (
+	SEARCH_LIMIT_REQUEST       = 50
+	SEARCH_QUERY_WORDS_LIMIT   = 8
+	SEARCH_MESSAGE_WORDS_LIMIT = 512 // distinct words of the message put into the index
+)
+
+// type filter of the search, media, files and locations match the mixed messages too
+const (
+	SEARCH_TYPE_TEXT     = MESSAGE_TEXT
+	SEARCH_TYPE_MEDIA    = MESSAGE_MEDIA
+	SEARCH_TYPE_FILE     = MESSAGE_FILE
+	SEARCH_TYPE_LOCATION = MESSAGE_LOCATION
+	SEARCH_TYPE_VOICE    = MESSAGE_VOICE
+	SEARCH_TYPE_POLL     = MESSAGE_POLL
+)
+
+type SearchRequest struct {
+	UserID          string
+	Query           string
+	ChatID          int64  // 0 searches all chats of the user
+	SenderID        string // "" matches any sender
+	Since           *time.Time
+	Until           *time.Time
+	Type            string // "" matches any type
+	BeforeMessageID int64
+	Limit           int
+}
+
+// SearchFilter is the search by the tokens of the words, a message matches if it has the token of every word
+type SearchFilter struct {
+	UserID          string
+	ChatIDs         []int64 // chat of each token
+	Tokens          []string
+	Words           []int // word of each token
+	WordsCount      int
+	SenderID        string
+	Since           *time.Time
+	Until           *time.Time
+	Type            string
+	BeforeMessageID int64
+	Limit           int
+}
+
+type SearchHit struct {
+	ChatID    int64 `db:"chat_id"`
+	MessageID int64 `db:"message_id"`
+}
+
+type SearchResult struct {
+	ChatID  int64   `json:"chat_id"`
+	Message Message `json:"message"`
+}
+
+func IsValidSearchType(searchType string) bool {
+	switch searchType {
+	case "", SEARCH_TYPE_TEXT, SEARCH_TYPE_MEDIA, SEARCH_TYPE_FILE, SEARCH_TYPE_LOCATION, SEARCH_TYPE_VOICE, SEARCH_TYPE_POLL:
+		return true
+	}
+	return false
+}
+
+func (r SearchRequest) IsValid() bool {
+	if r.Since != nil && r.Until != nil && !r.Since.Before(*r.Until) {
+		return false
+	}
+	return IsValidSearchType(r.Type)
+}
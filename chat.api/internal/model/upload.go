@@ -0,0 +1,167 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	UPLOAD_KIND_FILE  = "file"
+	UPLOAD_KIND_MEDIA = "media"
+	UPLOAD_KIND_VOICE = "voice" // stored as a file with the duration and the waveform
+
+	// the thumbnail of a media is downloaded by the id of the media
+	DOWNLOAD_KIND_THUMBNAIL = "thumbnail"
+
+	FILE_TYPE_MP3  = "audio/mp3"
+	FILE_TYPE_OGG  = "audio/ogg"
+	FILE_TYPE_PDF  = "application/pdf"
+	FILE_TYPE_ZIP  = "application/zip"
+	FILE_TYPE_TEXT = "text/plain"
+
+	UPLOAD_SNIFF_SIZE     = 512
+	UPLOAD_CHUNK_MAX_SIZE = 32 << 20
+	UPLOAD_TTL            = 24 * time.Hour
+	UPLOAD_BATCH_SIZE     = 100
+
+	UPLOAD_SIZE_LIMIT    = 500 << 20 // the largest limit of uploadSizeLimits
+	UPLOAD_FORM_OVERHEAD = 1 << 20   // boundaries, headers and the other fields of the multipart form
+
+	TUS_RESUMABLE_VERSION = "1.0.0"
+)
+
+var (
+	mediaTypes = map[string]bool{
+		MEDIA_TYPE_JPEG: true,
+		MEDIA_TYPE_PNG:  true,
+		MEDIA_TYPE_GIF:  true,
+		MEDIA_TYPE_WEBP: true,
+		MEDIA_TYPE_MP4:  true,
+		MEDIA_TYPE_WEBM: true,
+	}
+
+	fileTypes = map[string]bool{
+		MEDIA_TYPE_JPEG: true,
+		MEDIA_TYPE_PNG:  true,
+		MEDIA_TYPE_GIF:  true,
+		MEDIA_TYPE_WEBP: true,
+		MEDIA_TYPE_MP4:  true,
+		MEDIA_TYPE_WEBM: true,
+		FILE_TYPE_MP3:   true,
+		FILE_TYPE_OGG:   true,
+		FILE_TYPE_PDF:   true,
+		FILE_TYPE_ZIP:   true,
+		FILE_TYPE_TEXT:  true,
+	}
+
+	voiceTypes = map[string]bool{
+		FILE_TYPE_MP3: true,
+		FILE_TYPE_OGG: true,
+	}
+
+	// uploadSizeLimits by the top-level type, the detected type decides, not the declared one
+	uploadSizeLimits = map[string]int64{
+		"image":       20 << 20,
+		"video":       500 << 20,
+		"audio":       100 << 20,
+		"application": 200 << 20,
+		"text":        10 << 20,
+	}
+)
+
+// Blob is the stored content, the uploads with the same SHA-256 share it
+type Blob struct {
+	SHA256     string    `json:"sha256" db:"sha256"`
+	StorageKey string    `json:"-" db:"storage_key"`
+	Type       string    `json:"type" db:"type"`
+	Size       int64     `json:"size" db:"size"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// Upload is a resumable upload, the bytes up to Offset are stored as parts
+type Upload struct {
+	UploadID  string    `json:"upload_id" db:"upload_id"`
+	UserID    string    `json:"-" db:"user_id"`
+	Kind      string    `json:"kind" db:"kind"`
+	Length    int64     `json:"length" db:"length"`
+	Offset    int64     `json:"offset" db:"upload_offset"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	ExpiresAt time.Time `json:"expires_at" db:"expires_at"`
+}
+
+type UploadPart struct {
+	UploadID   string `db:"upload_id"`
+	Offset     int64  `db:"part_offset"`
+	Size       int64  `db:"size"`
+	StorageKey string `db:"storage_key"`
+}
+
+type CreateUploadRequest struct {
+	Kind   string `json:"kind"`
+	Length int64  `json:"length"`
+}
+
+// UploadResult is the stored attachment, its id goes into the message
+type UploadResult struct {
+	Kind  string `json:"kind"`
+	File  *File  `json:"file,omitempty"`
+	Media *Media `json:"media,omitempty"`
+}
+
+type DownloadURL struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// UploadType converts the sniffed content type to the value of the media_type/file_type enums
+func UploadType(sniffed string) string {
+	mimeType, _, _ := strings.Cut(sniffed, ";")
+	mimeType = strings.TrimSpace(mimeType)
+	switch mimeType {
+	case "audio/mpeg":
+		return FILE_TYPE_MP3
+	case "application/ogg":
+		return FILE_TYPE_OGG
+	}
+	return mimeType
+}
+
+func IsAllowedUploadType(kind, mimeType string) bool {
+	switch kind {
+	case UPLOAD_KIND_MEDIA:
+		return mediaTypes[mimeType]
+	case UPLOAD_KIND_FILE:
+		return fileTypes[mimeType]
+	case UPLOAD_KIND_VOICE:
+		return voiceTypes[mimeType]
+	default:
+		return false
+	}
+}
+
+func IsValidUploadKind(kind string) bool {
+	return kind == UPLOAD_KIND_MEDIA || kind == UPLOAD_KIND_FILE || kind == UPLOAD_KIND_VOICE
+}
+
+// IsValidDownloadKind doesn't accept the voice kind, voice messages are downloaded as files
+func IsValidDownloadKind(kind string) bool {
+	return kind == UPLOAD_KIND_MEDIA || kind == UPLOAD_KIND_FILE || kind == DOWNLOAD_KIND_THUMBNAIL
+}
+
+// UploadSizeLimit is the limit for the type, zero mimeType gives the largest one
+func UploadSizeLimit(mimeType string) int64 {
+	if mimeType == "" {
+		var limit int64
+		for _, typeLimit := range uploadSizeLimits {
+			limit = max(limit, typeLimit)
+		}
+		return limit
+	}
+
+	topLevel, _, _ := strings.Cut(mimeType, "/")
+	return uploadSizeLimits[topLevel]
+}
+
+func BlobStorageKey(sha256 string) string {
+	return "blobs/" + sha256[:2] + "/" + sha256
+}
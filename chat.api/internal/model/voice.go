@@ -0,0 +1,96 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	VOICE_WAVEFORM_BARS = 64
+	VOICE_MAX_DURATION  = 30 * 60 // seconds
+)
+
+// Waveform is the loudness of equal slices of a voice message, 0..31.
+// Stored as bytea, sent as an array of numbers instead of base64.
+type Waveform []byte
+
+func (w Waveform) MarshalJSON() ([]byte, error) {
+	levels := make([]int, len(w))
+	for i, level := range w {
+		levels[i] = int(level)
+	}
+	return json.Marshal(levels)
+}
+
+func (w *Waveform) UnmarshalJSON(data []byte) error {
+	var levels []int
+	if err := json.Unmarshal(data, &levels); err != nil {
+		return err
+	}
+	if levels == nil {
+		*w = nil
+		return nil
+	}
+
+	*w = make(Waveform, len(levels))
+	for i, level := range levels {
+		(*w)[i] = byte(level)
+	}
+	return nil
+}
+
+func (w Waveform) Value() (driver.Value, error) {
+	if w == nil {
+		return nil, nil
+	}
+	return []byte(w), nil
+}
+
+func (w *Waveform) Scan(src any) error {
+	switch value := src.(type) {
+	case nil:
+		*w = nil
+	case []byte:
+		*w = append(Waveform(nil), value...)
+	default:
+		return fmt.Errorf("unsupported waveform type %T", src)
+	}
+	return nil
+}
+
+// VoiceListen is the recipient who played the voice message
+type VoiceListen struct {
+	MessageID  int64     `json:"message_id" db:"message_id"`
+	UserID     string    `json:"user_id" db:"user_id"`
+	ListenedAt time.Time `json:"listened_at" db:"listened_at"`
+}
+
+type VoiceListenRequest struct {
+	ChatID    int64  `json:"chat_id"`
+	MessageID int64  `json:"message_id"`
+	UserID    string `json:"user_id"`
+}
+
+type VoiceListenedEvent struct {
+	Type   string `json:"type"`
+	ChatID int64  `json:"chat_id"`
+	VoiceListen
+}
+
+// IsValidVoiceMessage checks the voice message has exactly one voice upload and nothing else attached
+func IsValidVoiceMessage(message SendMessage) bool {
+	if message.Media != nil && len(*message.Media) > 0 || message.Locations != nil && len(*message.Locations) > 0 {
+		return false
+	}
+	return message.Files != nil && len(*message.Files) == 1 && (*message.Files)[0].Duration != nil
+}
+
+// VoiceDuration is the duration of the voice message, nil for other types
+func VoiceDuration(message SendMessage) *float64 {
+	if message.MessageDB.Type != MESSAGE_VOICE || message.Files == nil || len(*message.Files) == 0 {
+		return nil
+	}
+	return (*message.Files)[0].Duration
+}
@@ -7,7 +7,10 @@ import (
 )
 
 type Cache struct {
-	WebSocketCache WebSocketCache
+	WebSocketCache   WebSocketCache
+	LinkPreviewCache LinkPreviewCache
+	PresenceCache    PresenceCache
+	KeysCache        KeysCache
 }
 
 type RedisCache struct {
@@ -16,7 +19,13 @@ type RedisCache struct {
 }
 
 func NewCashe(client *redis.Client, ttl time.Duration) *Cache {
-	return &Cache{WebSocketCache: NewRedisCache(client, ttl)}
+	redisCache := NewRedisCache(client, ttl)
+	return &Cache{
+		WebSocketCache:   redisCache,
+		LinkPreviewCache: redisCache,
+		PresenceCache:    redisCache,
+		KeysCache:        redisCache,
+	}
 }
 
 func NewRedisCache(client *redis.Client, ttl time.Duration) *RedisCache {
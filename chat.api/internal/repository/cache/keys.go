@@ -0,0 +1,26 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// KeysCache limits how often the prekey bundles of a user are handed out,
+// every fetch takes one-time prekeys the user can't get back
+type KeysCache interface {
+	// AllowPreKeyFetch returns false once the caller has fetched the bundles of the user limit times within the window
+	AllowPreKeyFetch(ctx context.Context, callerID, userID string, limit int, window time.Duration) (bool, error)
+}
+
+func (c *RedisCache) AllowPreKeyFetch(ctx context.Context, callerID, userID string, limit int, window time.Duration) (bool, error) {
+	key := fmt.Sprintf("prekey_fetch:%s:%s", callerID, userID)
+
+	pipe := c.client.TxPipeline()
+	count := pipe.Incr(ctx, key)
+	pipe.ExpireNX(ctx, key, window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, fmt.Errorf("error limiting prekey fetches in Redis: %w", err)
+	}
+	return count.Val() <= int64(limit), nil
+}
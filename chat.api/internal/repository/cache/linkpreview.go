@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"chat-api/internal/model"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// LinkPreviewCache keeps the previews by link, so a link sent to many chats is fetched once.
+// An empty preview stands for the page which has no metadata or couldn't be fetched.
+type LinkPreviewCache interface {
+	SetLinkPreview(ctx context.Context, link string, preview model.LinkPreview, ttl time.Duration) error
+	// GetLinkPreview returns false if the link isn't cached
+	GetLinkPreview(ctx context.Context, link string) (model.LinkPreview, bool, error)
+}
+
+func (c *RedisCache) SetLinkPreview(ctx context.Context, link string, preview model.LinkPreview, ttl time.Duration) error {
+	data, err := json.Marshal(preview)
+	if err != nil {
+		return err
+	}
+
+	if err := c.client.Set(ctx, linkPreviewKey(link), data, ttl).Err(); err != nil {
+		return fmt.Errorf("error saving link preview to Redis: %w", err)
+	}
+	return nil
+}
+
+func (c *RedisCache) GetLinkPreview(ctx context.Context, link string) (model.LinkPreview, bool, error) {
+	var preview model.LinkPreview
+
+	data, err := c.client.Get(ctx, linkPreviewKey(link)).Bytes()
+	if err == redis.Nil {
+		return preview, false, nil
+	}
+	if err != nil {
+		return preview, false, fmt.Errorf("error getting link preview from Redis: %w", err)
+	}
+
+	return preview, true, json.Unmarshal(data, &preview)
+}
+
+// links can be long, the key holds their hash
+func linkPreviewKey(link string) string {
+	sum := sha256.Sum256([]byte(link))
+	return "link_previews:" + hex.EncodeToString(sum[:])
+}
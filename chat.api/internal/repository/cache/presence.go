@@ -0,0 +1,139 @@
+package cache
+
+import (
+	"chat-api/internal/model"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const presenceChannel = "presence"
+
+// PresenceCache keeps the status of the connected users shared by the replicas. The status expires
+// unless refreshed by the socket, the last time it was refreshed stays as the last seen.
+type PresenceCache interface {
+	SetPresence(ctx context.Context, userID, status string, ttl time.Duration) error
+	// RefreshPresence returns false if the status has already expired
+	RefreshPresence(ctx context.Context, userID string, ttl time.Duration) (bool, error)
+	DeletePresence(ctx context.Context, userID string) error
+	// GetPresences returns the offline status for the users who aren't connected
+	GetPresences(ctx context.Context, usersIDs []string) ([]model.Presence, error)
+	// PublishPresence tells all replicas the status of the user has changed
+	PublishPresence(ctx context.Context, userID string) error
+	// SubscribePresence calls handle for every changed or expired status until ctx is done
+	SubscribePresence(ctx context.Context, handle func(userID string)) error
+	// AllowChatActivity returns false if the same activity was sent to the chat within the interval
+	AllowChatActivity(ctx context.Context, chatID int64, userID, action string, interval time.Duration) (bool, error)
+}
+
+func (c *RedisCache) SetPresence(ctx context.Context, userID, status string, ttl time.Duration) error {
+	pipe := c.client.TxPipeline()
+	pipe.Set(ctx, "presence:"+userID, status, ttl)
+	pipe.Set(ctx, "last_seen:"+userID, time.Now().Unix(), 0)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("error saving presence to Redis: %w", err)
+	}
+	return nil
+}
+
+func (c *RedisCache) RefreshPresence(ctx context.Context, userID string, ttl time.Duration) (bool, error) {
+	pipe := c.client.TxPipeline()
+	refreshed := pipe.Expire(ctx, "presence:"+userID, ttl)
+	pipe.Set(ctx, "last_seen:"+userID, time.Now().Unix(), 0)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, fmt.Errorf("error refreshing presence in Redis: %w", err)
+	}
+	return refreshed.Val(), nil
+}
+
+func (c *RedisCache) DeletePresence(ctx context.Context, userID string) error {
+	pipe := c.client.TxPipeline()
+	pipe.Del(ctx, "presence:"+userID)
+	pipe.Set(ctx, "last_seen:"+userID, time.Now().Unix(), 0)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("error deleting presence from Redis: %w", err)
+	}
+	return nil
+}
+
+func (c *RedisCache) GetPresences(ctx context.Context, usersIDs []string) ([]model.Presence, error) {
+	if len(usersIDs) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]string, 0, 2*len(usersIDs))
+	for _, userID := range usersIDs {
+		keys = append(keys, "presence:"+userID)
+	}
+	for _, userID := range usersIDs {
+		keys = append(keys, "last_seen:"+userID)
+	}
+
+	values, err := c.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("error getting presence from Redis: %w", err)
+	}
+
+	presences := make([]model.Presence, len(usersIDs))
+	for i, userID := range usersIDs {
+		presences[i] = model.Presence{UserID: userID, Status: model.PRESENCE_OFFLINE}
+		if status, ok := values[i].(string); ok {
+			presences[i].Status = status
+		}
+		if lastSeen, ok := values[len(usersIDs)+i].(string); ok {
+			if unix, err := strconv.ParseInt(lastSeen, 10, 64); err == nil {
+				lastSeenAt := time.Unix(unix, 0).UTC()
+				presences[i].LastSeenAt = &lastSeenAt
+			}
+		}
+	}
+	return presences, nil
+}
+
+func (c *RedisCache) PublishPresence(ctx context.Context, userID string) error {
+	if err := c.client.Publish(ctx, presenceChannel, userID).Err(); err != nil {
+		return fmt.Errorf("error publishing presence to Redis: %w", err)
+	}
+	return nil
+}
+
+// SubscribePresence relies on the expired keyspace events enabled for the sockets
+func (c *RedisCache) SubscribePresence(ctx context.Context, handle func(userID string)) error {
+	pubsub := c.client.Subscribe(ctx, presenceChannel, "__keyevent@0__:expired")
+	defer pubsub.Close()
+
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return fmt.Errorf("error subscribing to presence in Redis: %w", err)
+	}
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-ch:
+			if !ok {
+				return redis.ErrClosed
+			}
+			if msg.Channel == presenceChannel {
+				handle(msg.Payload)
+			} else if userID, found := strings.CutPrefix(msg.Payload, "presence:"); found {
+				handle(userID)
+			}
+		}
+	}
+}
+
+func (c *RedisCache) AllowChatActivity(ctx context.Context, chatID int64, userID, action string, interval time.Duration) (bool, error) {
+	key := fmt.Sprintf("chat_activity:%d:%s:%s", chatID, userID, action)
+
+	allowed, err := c.client.SetNX(ctx, key, 1, interval).Result()
+	if err != nil {
+		return false, fmt.Errorf("error limiting chat activity in Redis: %w", err)
+	}
+	return allowed, nil
+}
@@ -0,0 +1,98 @@
+package repo
+
+import (
+	"chat-api/internal/model"
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type BookmarksRepo struct {
+	db *sqlx.DB
+}
+
+func NewBookmarksRepo(db *sqlx.DB) *BookmarksRepo {
+	return &BookmarksRepo{db: db}
+}
+
+// SetBookmark saves the message for the user, the tags of the saved one are replaced
+func (r *BookmarksRepo) SetBookmark(ctx context.Context, bookmark model.Bookmark) (model.Bookmark, error) {
+	query := `
+		INSERT INTO message_bookmarks (user_id, chat_id, message_id, tags)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, message_id) DO UPDATE
+		SET tags = EXCLUDED.tags
+		RETURNING bookmark_id, user_id, chat_id, message_id, tags, created_at
+	`
+	err := r.db.GetContext(ctx, &bookmark, query, bookmark.UserID, bookmark.ChatID, bookmark.MessageID, bookmark.Tags)
+	return bookmark, err
+}
+
+// GetBookmarks pages back through the bookmarks of the user carrying all the tags,
+// the expired messages are skipped until they are deleted
+func (r *BookmarksRepo) GetBookmarks(ctx context.Context, userID string, tags []string, beforeBookmarkID int64, limit int) ([]model.Bookmark, error) {
+	var bookmarks []model.Bookmark
+
+	query := `
+		SELECT b.bookmark_id, b.user_id, b.chat_id, b.message_id, b.tags, b.created_at
+		FROM message_bookmarks b
+		JOIN messages m ON m.message_id = b.message_id
+		WHERE b.user_id = $1 AND b.tags @> $2 AND ($3 = 0 OR b.bookmark_id < $3)
+		  AND (m.expires_at IS NULL OR m.expires_at > CURRENT_TIMESTAMP)
+		ORDER BY b.bookmark_id DESC
+		LIMIT $4
+	`
+
+	err := r.db.SelectContext(ctx, &bookmarks, query, userID, pq.StringArray(tags), beforeBookmarkID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return bookmarks, nil
+}
+
+// DeleteBookmark returns sql.ErrNoRows if the user hasn't saved the message
+func (r *BookmarksRepo) DeleteBookmark(ctx context.Context, userID string, messageID int64) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM message_bookmarks WHERE user_id = $1 AND message_id = $2`, userID, messageID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// IsAttachmentBookmarked checks that the file or media is attached to a message of the chat saved by the user
+func (r *BookmarksRepo) IsAttachmentBookmarked(ctx context.Context, kind string, attachmentID, chatID int64, userID string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM messages_files mf
+			JOIN message_bookmarks b ON mf.message_id = b.message_id
+			WHERE mf.file_id = $1 AND b.chat_id = $2 AND b.user_id = $3
+		)
+	`
+	if kind == model.UPLOAD_KIND_MEDIA || kind == model.DOWNLOAD_KIND_THUMBNAIL {
+		query = `
+			SELECT EXISTS (
+				SELECT 1
+				FROM messages_media mm
+				JOIN message_bookmarks b ON mm.message_id = b.message_id
+				WHERE mm.media_id = $1 AND b.chat_id = $2 AND b.user_id = $3
+			)
+		`
+	}
+
+	var exists bool
+	err := r.db.GetContext(ctx, &exists, query, attachmentID, chatID, userID)
+	return exists, err
+}
@@ -0,0 +1,121 @@
+package repo
+
+import (
+	"chat-api/internal/model"
+	"context"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type ChannelsRepo struct {
+	db *sqlx.DB
+}
+
+func NewChannelsRepo(db *sqlx.DB) *ChannelsRepo {
+	return &ChannelsRepo{db: db}
+}
+
+func (r *ChannelsRepo) GetChannelByHandle(ctx context.Context, handle string) (model.ChatDB, error) {
+	var chat model.ChatDB
+	query := `SELECT * FROM chats WHERE handle = $1 AND type = 'channel'`
+	err := r.db.GetContext(ctx, &chat, query, handle)
+	return chat, err
+}
+
+// JoinChannel subscribes the user, false if the user is already a subscriber
+func (r *ChannelsRepo) JoinChannel(ctx context.Context, chatID int64, userID string) (bool, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	joined, err := joinChat(ctx, tx, chatID, userID, nil)
+	if err != nil || !joined {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+func (r *ChannelsRepo) CountSubscribers(ctx context.Context, chatID int64) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM chats_participants WHERE chat_id = $1`
+	err := r.db.GetContext(ctx, &count, query, chatID)
+	return count, err
+}
+
+// GetSubscribersAfter returns the page of the subscribers ordered by their IDs, the key of the primary index keeps it cheap at any depth
+func (r *ChannelsRepo) GetSubscribersAfter(ctx context.Context, chatID int64, afterUserID string, limit int) ([]string, error) {
+	var subscribersIDs []string
+
+	query := `
+		SELECT user_id
+		FROM chats_participants
+		WHERE chat_id = $1 AND user_id > $2
+		ORDER BY user_id
+		LIMIT $3
+	`
+
+	err := r.db.SelectContext(ctx, &subscribersIDs, query, chatID, afterUserID, limit)
+	return subscribersIDs, err
+}
+
+func (r *ChannelsRepo) SetChannelPost(ctx context.Context, post model.ChannelPost) error {
+	query := `
+		INSERT INTO channel_posts (message_id, chat_id, signature)
+		VALUES ($1, $2, $3)
+	`
+	_, err := r.db.ExecContext(ctx, query, post.MessageID, post.ChatID, post.Signature)
+	return err
+}
+
+func (r *ChannelsRepo) GetChannelPost(ctx context.Context, messageID int64) (model.ChannelPost, error) {
+	var post model.ChannelPost
+	query := `SELECT message_id, chat_id, signature, views FROM channel_posts WHERE message_id = $1`
+	err := r.db.GetContext(ctx, &post, query, messageID)
+	return post, err
+}
+
+// ViewChannelPosts counts the first view of each post of the channel by the user and
+// returns the counters of the posts, the messages of other chats are skipped
+func (r *ChannelsRepo) ViewChannelPosts(ctx context.Context, chatID int64, userID string, messageIDs []int64) ([]model.ChannelPost, error) {
+	var posts []model.ChannelPost
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		WITH viewed AS (
+			INSERT INTO channel_post_views (message_id, user_id)
+			SELECT message_id, $2
+			FROM channel_posts
+			WHERE chat_id = $1 AND message_id = ANY($3)
+			ON CONFLICT DO NOTHING
+			RETURNING message_id
+		)
+		UPDATE channel_posts cp
+		SET views = cp.views + 1
+		FROM viewed v
+		WHERE cp.message_id = v.message_id
+	`
+	if _, err := tx.ExecContext(ctx, query, chatID, userID, pq.Array(messageIDs)); err != nil {
+		return nil, err
+	}
+
+	query = `
+		SELECT message_id, chat_id, signature, views
+		FROM channel_posts
+		WHERE chat_id = $1 AND message_id = ANY($2)
+		ORDER BY message_id
+	`
+	if err := tx.SelectContext(ctx, &posts, query, chatID, pq.Array(messageIDs)); err != nil {
+		return nil, err
+	}
+
+	return posts, tx.Commit()
+}
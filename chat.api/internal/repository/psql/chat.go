@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 type ChatsRepo struct {
@@ -24,12 +25,12 @@ func (r *ChatsRepo) SetChat(ctx context.Context, chat model.ChatDB) (chatID int6
 	defer tx.Rollback()
 
 	query := `
-		INSERT INTO chats (creator_id, name, description, type, avatar_url, encrypted)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO chats (creator_id, name, description, type, avatar_url, encrypted, message_ttl)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 		RETURNING chat_id, created_at
 	`
 
-	err = tx.QueryRowContext(ctx, query, chat.CreatorID, chat.Name, chat.Description, chat.Type, chat.AvatarURL, chat.Encrypted).Scan(&chatID, &createdAt)
+	err = tx.QueryRowContext(ctx, query, chat.CreatorID, chat.Name, chat.Description, chat.Type, chat.AvatarURL, chat.Encrypted, chat.MessageTTL).Scan(&chatID, &createdAt)
 	if err != nil {
 		return 0, time.Now(), err
 	}
@@ -37,6 +38,68 @@ func (r *ChatsRepo) SetChat(ctx context.Context, chat model.ChatDB) (chatID int6
 	return chatID, time.Now(), tx.Commit()
 }
 
+// SetPrivateChat creates a private chat for the pair of users together with its
+// participants. If the pair already has a chat, the existing one is returned
+// and created is false.
+func (r *ChatsRepo) SetPrivateChat(ctx context.Context, chat model.ChatDB, firstUserID, secondUserID string) (chatID int64, createdAt time.Time, created bool, err error) {
+	firstUserID, secondUserID = orderParticipants(firstUserID, secondUserID)
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, time.Time{}, false, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO chats (creator_id, name, description, type, avatar_url, encrypted, message_ttl)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING chat_id, created_at
+	`
+
+	err = tx.QueryRowContext(ctx, query, chat.CreatorID, chat.Name, chat.Description, model.CHAT_TYPE_PRIVATE, chat.AvatarURL, chat.Encrypted, chat.MessageTTL).Scan(&chatID, &createdAt)
+	if err != nil {
+		return 0, time.Time{}, false, err
+	}
+
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO private_chats (chat_id, first_user_id, second_user_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (first_user_id, second_user_id) DO NOTHING
+	`, chatID, firstUserID, secondUserID)
+	if err != nil {
+		return 0, time.Time{}, false, err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return 0, time.Time{}, false, err
+	}
+
+	if rowsAffected == 0 {
+		// another request created the chat first, drop ours and reuse theirs
+		if err := tx.Rollback(); err != nil {
+			return 0, time.Time{}, false, err
+		}
+		existing, err := r.GetPrivateChatByParticipants(ctx, firstUserID, secondUserID)
+		if err != nil {
+			return 0, time.Time{}, false, err
+		}
+		return existing.ChatID, existing.CreatedAt, false, nil
+	}
+
+	for _, userID := range []string{firstUserID, secondUserID} {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO chats_participants (chat_id, user_id)
+			VALUES ($1, $2)
+			ON CONFLICT DO NOTHING
+		`, chatID, userID); err != nil {
+			return 0, time.Time{}, false, err
+		}
+	}
+
+	return chatID, createdAt, true, tx.Commit()
+}
+
 func (r *ChatsRepo) SetParticipant(ctx context.Context, chatID int64, userID string) error {
 	_, err := r.db.ExecContext(ctx, `
         INSERT INTO chats_participants (chat_id, user_id)
@@ -54,11 +117,14 @@ func (r *ChatsRepo) SetChatRole(ctx context.Context, chatRole model.ChatRole) er
 	defer tx.Rollback()
 
 	query := `
-		INSERT INTO chat_roles (chat_id, user_id, granter_id, nickname, role)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO chat_roles (chat_id, user_id, granter_id, nickname, role, permissions)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (chat_id, user_id) DO UPDATE
+		SET granter_id = EXCLUDED.granter_id, nickname = EXCLUDED.nickname,
+		    role = EXCLUDED.role, permissions = EXCLUDED.permissions
 	`
 
-	_, err = tx.ExecContext(ctx, query, chatRole.ChatID, chatRole.UserID, chatRole.GranterID, chatRole.Nickname, chatRole.Role)
+	_, err = tx.ExecContext(ctx, query, chatRole.ChatID, chatRole.UserID, chatRole.GranterID, chatRole.Nickname, chatRole.Role, chatRole.Permissions)
 	if err != nil {
 		return err
 	}
@@ -110,10 +176,10 @@ func (r *ChatsRepo) GetAllChatRoles(ctx context.Context, chatID int64) ([]model.
 	var chatRoles []model.ChatRole
 
 	query := `
-		SELECT chat_id, user_id, granter_id, nickname, role
+		SELECT chat_id, user_id, granter_id, nickname, role, permissions
 		FROM chat_roles
 		WHERE chat_id = $1
-		ORDER BY role DESC
+		ORDER BY role ASC
 	`
 
 	err := r.db.SelectContext(ctx, &chatRoles, query, chatID)
@@ -157,6 +223,20 @@ func (r *ChatsRepo) GetAllActionsWithLimit(ctx context.Context, chatID int64, li
 	return actions, err
 }
 
+// GetActionsByTypesWithLimit returns the latest actions of the given types only
+func (r *ChatsRepo) GetActionsByTypesWithLimit(ctx context.Context, chatID int64, actionTypes []string, limit int) ([]model.ChatAction, error) {
+	var actions []model.ChatAction
+	query := `
+		SELECT chat_id, user_id, action_type, action_timestamp
+		FROM chat_history
+		WHERE chat_id = $1 AND action_type::text = ANY($2)
+		ORDER BY action_timestamp DESC
+		LIMIT $3
+	`
+	err := r.db.SelectContext(ctx, &actions, query, chatID, pq.Array(actionTypes), limit)
+	return actions, err
+}
+
 func (r *ChatsRepo) GetChatByChatID(ctx context.Context, chatID int64) (model.ChatDB, error) {
 	var chat model.ChatDB
 	query := `SELECT * FROM chats WHERE chat_id = $1`
@@ -164,10 +244,25 @@ func (r *ChatsRepo) GetChatByChatID(ctx context.Context, chatID int64) (model.Ch
 	return chat, err
 }
 
+func (r *ChatsRepo) GetPrivateChatByParticipants(ctx context.Context, firstUserID, secondUserID string) (model.ChatDB, error) {
+	var chat model.ChatDB
+	firstUserID, secondUserID = orderParticipants(firstUserID, secondUserID)
+
+	query := `
+		SELECT c.*
+		FROM chats c
+		JOIN private_chats pc ON c.chat_id = pc.chat_id
+		WHERE pc.first_user_id = $1 AND pc.second_user_id = $2
+	`
+
+	err := r.db.GetContext(ctx, &chat, query, firstUserID, secondUserID)
+	return chat, err
+}
+
 func (r *ChatsRepo) GetAllChatsByUserID(ctx context.Context, userID string) ([]model.ChatDB, error) {
 	var chats []model.ChatDB
 	query := `
-		SELECT c.chat_id, c.creator_id, c.name, c.description, c.type, c.created_at, c.updated_at, c.encrypted
+		SELECT c.chat_id, c.creator_id, c.name, c.description, c.type, c.created_at, c.updated_at, c.encrypted, c.message_ttl
 		FROM chats c
 		JOIN chats_participants cp ON c.chat_id = cp.chat_id
 		WHERE cp.user_id = $1
@@ -181,7 +276,7 @@ func (r *ChatsRepo) GetAllChatsByUserIDWithLimit(ctx context.Context, userID str
 	var chats []model.ChatDB
 
 	query := `
-		SELECT c.chat_id, c.creator_id, c.name, c.description, c.type, c.created_at, c.updated_at, c.encrypted
+		SELECT c.chat_id, c.creator_id, c.name, c.description, c.type, c.created_at, c.updated_at, c.encrypted, c.message_ttl
 		FROM chats c
 		JOIN chats_participants cp ON c.chat_id = cp.chat_id
 		WHERE cp.user_id = $1
@@ -193,8 +288,29 @@ func (r *ChatsRepo) GetAllChatsByUserIDWithLimit(ctx context.Context, userID str
 	return chats, err
 }
 
+func (r *ChatsRepo) UpdateMessageTTL(ctx context.Context, chatID int64, messageTTL *int) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE chats
+		SET message_ttl = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE chat_id = $2
+	`
+
+	if _, err := tx.ExecContext(ctx, query, messageTTL, chatID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteBlockUser lifts the block the user set, the ban of an admin stays
 func (r *ChatsRepo) DeleteBlockUser(ctx context.Context, chatID int64, userID string) error {
-	query := `DELETE FROM chat_blocked_users WHERE chat_id = $1 AND user_id = $2`
+	query := `DELETE FROM chat_blocked_users WHERE chat_id = $1 AND user_id = $2 AND NOT banned`
 	_, err := r.db.ExecContext(ctx, query, chatID, userID)
 	return err
 }
@@ -205,6 +321,23 @@ func (r *ChatsRepo) DeleteChat(ctx context.Context, chatID int64) error {
 	return err
 }
 
+func (r *ChatsRepo) IsUserBanned(ctx context.Context, chatID int64, userID string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM chat_blocked_users
+			WHERE chat_id = $1 AND user_id = $2 AND banned
+		)
+	`
+
+	var banned bool
+	err := r.db.GetContext(ctx, &banned, query, chatID, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return banned, nil
+}
+
 func (r *ChatsRepo) IsBlockedChatExists(ctx context.Context, chatID int64, userID string) (bool, error) {
 	query := `
 		SELECT EXISTS (
@@ -221,3 +354,115 @@ func (r *ChatsRepo) IsBlockedChatExists(ctx context.Context, chatID int64, userI
 
 	return exists, nil
 }
+
+func (r *ChatsRepo) IsParticipantExists(ctx context.Context, chatID int64, userID string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM chats_participants
+			WHERE chat_id = $1 AND user_id = $2
+		)
+	`
+
+	var exists bool
+	err := r.db.GetContext(ctx, &exists, query, chatID, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+// IsSharedChatExists reports whether the users are both participants of a private or group chat,
+// the subscribers of a channel don't count
+func (r *ChatsRepo) IsSharedChatExists(ctx context.Context, userID, otherUserID string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM chats_participants a
+			JOIN chats_participants b ON b.chat_id = a.chat_id
+			JOIN chats c ON c.chat_id = a.chat_id
+			WHERE a.user_id = $1 AND b.user_id = $2 AND c.type <> $3
+		) OR EXISTS (
+			SELECT 1
+			FROM private_chats
+			WHERE (first_user_id = $1 AND second_user_id = $2)
+			   OR (first_user_id = $2 AND second_user_id = $1)
+		)
+	`
+
+	var exists bool
+	err := r.db.GetContext(ctx, &exists, query, userID, otherUserID, model.CHAT_TYPE_CHANNEL)
+	if err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+// GetPrivateChatPartners returns those of the users who have a private chat with the user
+func (r *ChatsRepo) GetPrivateChatPartners(ctx context.Context, userID string, usersIDs []string) ([]string, error) {
+	var partners []string
+
+	query := `
+		SELECT CASE WHEN first_user_id = $1 THEN second_user_id ELSE first_user_id END
+		FROM private_chats
+		WHERE (first_user_id = $1 AND second_user_id = ANY($2))
+		   OR (second_user_id = $1 AND first_user_id = ANY($2))
+	`
+
+	err := r.db.SelectContext(ctx, &partners, query, userID, pq.Array(usersIDs))
+	return partners, err
+}
+
+// IsChatAdmin reports whether the participant created the chat or was made its admin
+// GetChatMember returns the role of the participant and the defaults of the chat, sql.ErrNoRows if the user isn't a participant
+func (r *ChatsRepo) GetChatMember(ctx context.Context, chatID int64, userID string) (model.ChatMember, error) {
+	var member model.ChatMember
+
+	query := `
+		SELECT cp.chat_id, cp.user_id, c.type, c.creator_id, c.default_permissions, cr.role, cr.permissions
+		FROM chats_participants cp
+		JOIN chats c ON c.chat_id = cp.chat_id
+		LEFT JOIN chat_roles cr ON cr.chat_id = cp.chat_id AND cr.user_id = cp.user_id
+		WHERE cp.chat_id = $1 AND cp.user_id = $2
+	`
+
+	err := r.db.GetContext(ctx, &member, query, chatID, userID)
+	return member, err
+}
+
+func (r *ChatsRepo) UpdateDefaultPermissions(ctx context.Context, chatID int64, permissions int) error {
+	query := `
+		UPDATE chats
+		SET default_permissions = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE chat_id = $1
+	`
+	_, err := r.db.ExecContext(ctx, query, chatID, permissions)
+	return err
+}
+
+// GetChatAdmins returns the participants who created the chat or were made its admins
+func (r *ChatsRepo) GetChatAdmins(ctx context.Context, chatID int64) ([]string, error) {
+	var admins []string
+
+	query := `
+		SELECT cp.user_id
+		FROM chats_participants cp
+		JOIN chats c ON c.chat_id = cp.chat_id
+		LEFT JOIN chat_roles cr ON cr.chat_id = cp.chat_id AND cr.user_id = cp.user_id
+		WHERE cp.chat_id = $1
+		  AND (c.creator_id = cp.user_id OR cr.role = 'admin')
+	`
+
+	err := r.db.SelectContext(ctx, &admins, query, chatID)
+	return admins, err
+}
+
+// orderParticipants normalizes a pair of users so that (a, b) and (b, a)
+// map to the same private_chats row.
+func orderParticipants(firstUserID, secondUserID string) (string, string) {
+	if firstUserID > secondUserID {
+		return secondUserID, firstUserID
+	}
+	return firstUserID, secondUserID
+}
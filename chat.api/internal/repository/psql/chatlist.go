@@ -0,0 +1,341 @@
+package repo
+
+import (
+	"chat-api/internal/model"
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type ChatListRepo struct {
+	db *sqlx.DB
+}
+
+func NewChatListRepo(db *sqlx.DB) *ChatListRepo {
+	return &ChatListRepo{db: db}
+}
+
+// GetChatList returns the page of the chats of the user matching the filter, the chats placed by hand
+// come first and the rest follow the last activity. The unread messages are counted for the page only,
+// the filter just looks whether there is one
+func (r *ChatListRepo) GetChatList(ctx context.Context, userID string, filter model.ChatListFilter, offset, limit int) ([]model.ChatListItem, error) {
+	var chats []model.ChatListItem
+
+	query := `
+		WITH list AS (
+			SELECT c.*,
+			       COALESCE(s.archived, false) AS archived,
+			       COALESCE(s.marked_unread, false) AS marked_unread,
+			       s.position,
+			       COALESCE(s.last_read_message_id, 0) AS last_read_message_id,
+			       lm.message_id AS last_message_id,
+			       COALESCE(lm.created_at, c.created_at) AS last_activity_at
+			FROM chats_participants cp
+			JOIN chats c ON c.chat_id = cp.chat_id
+			LEFT JOIN chat_user_states s ON s.chat_id = cp.chat_id AND s.user_id = cp.user_id
+			LEFT JOIN LATERAL (
+				SELECT m.message_id, m.created_at
+				FROM chat_messages cm
+				JOIN messages m ON m.message_id = cm.message_id
+				WHERE cm.chat_id = c.chat_id AND m.thread_id IS NULL
+				  AND (m.expires_at IS NULL OR m.expires_at > CURRENT_TIMESTAMP)
+				ORDER BY cm.message_id DESC
+				LIMIT 1
+			) lm ON true
+			WHERE cp.user_id = $1
+		), page AS (
+			SELECT *
+			FROM list
+			WHERE NOT chat_id = ANY(COALESCE($5::bigint[], '{}'))
+			  AND (
+			      chat_id = ANY(COALESCE($4::bigint[], '{}'))
+			      OR (
+			          type::text = ANY($2)
+			          AND (NOT $3 OR marked_unread OR EXISTS (
+			              SELECT 1
+			              FROM chat_messages cm
+			              JOIN messages m ON m.message_id = cm.message_id
+			              WHERE cm.chat_id = list.chat_id AND cm.message_id > list.last_read_message_id
+			                AND m.sender_id <> $1 AND m.thread_id IS NULL
+			                AND (m.expires_at IS NULL OR m.expires_at > CURRENT_TIMESTAMP)
+			          ))
+			          AND ($6::boolean IS NULL OR archived = $6)
+			      )
+			  )
+			ORDER BY position ASC NULLS LAST, last_activity_at DESC, chat_id DESC
+			OFFSET $7
+			LIMIT $8
+		)
+		SELECT page.*, unread.unread_count
+		FROM page
+		CROSS JOIN LATERAL (
+			SELECT COUNT(*) AS unread_count
+			FROM chat_messages cm
+			JOIN messages m ON m.message_id = cm.message_id
+			WHERE cm.chat_id = page.chat_id AND cm.message_id > page.last_read_message_id
+			  AND m.sender_id <> $1 AND m.thread_id IS NULL
+			  AND (m.expires_at IS NULL OR m.expires_at > CURRENT_TIMESTAMP)
+		) unread
+		ORDER BY page.position ASC NULLS LAST, page.last_activity_at DESC, page.chat_id DESC
+	`
+
+	err := r.db.SelectContext(ctx, &chats, query,
+		userID,
+		pq.Array(filter.Types),
+		filter.OnlyUnread,
+		pq.Array(filter.IncludedChatIDs),
+		pq.Array(filter.ExcludedChatIDs),
+		filter.Archived,
+		offset,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return chats, nil
+}
+
+// UpsertChatState changes the given fields only, the zero position removes the manual place of the chat
+func (r *ChatListRepo) UpsertChatState(ctx context.Context, request model.UpdateChatStateRequest) (model.ChatState, error) {
+	var state model.ChatState
+
+	query := `
+		INSERT INTO chat_user_states (chat_id, user_id, archived, marked_unread, position)
+		VALUES ($1, $2, COALESCE($3, false), COALESCE($4, false), NULLIF($5, 0))
+		ON CONFLICT (chat_id, user_id) DO UPDATE
+		SET archived = COALESCE($3, chat_user_states.archived),
+		    marked_unread = COALESCE($4, chat_user_states.marked_unread),
+		    position = CASE WHEN $5::int IS NULL THEN chat_user_states.position ELSE NULLIF($5, 0) END
+		RETURNING chat_id, user_id, archived, marked_unread, position, last_read_message_id
+	`
+
+	err := r.db.GetContext(ctx, &state, query, request.ChatID, request.UserID, request.Archived, request.MarkedUnread, request.Position)
+	return state, err
+}
+
+// SetChatRead moves the read mark of the user forward and drops the unread mark set by hand
+func (r *ChatListRepo) SetChatRead(ctx context.Context, chatID int64, userID string, messageID int64) (model.ChatState, error) {
+	var state model.ChatState
+
+	query := `
+		INSERT INTO chat_user_states (chat_id, user_id, last_read_message_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (chat_id, user_id) DO UPDATE
+		SET last_read_message_id = GREATEST(chat_user_states.last_read_message_id, EXCLUDED.last_read_message_id),
+		    marked_unread = false
+		RETURNING chat_id, user_id, archived, marked_unread, position, last_read_message_id
+	`
+
+	err := r.db.GetContext(ctx, &state, query, chatID, userID, messageID)
+	return state, err
+}
+
+func (r *ChatListRepo) CountChatFolders(ctx context.Context, userID string) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM chat_folders WHERE user_id = $1`
+	err := r.db.GetContext(ctx, &count, query, userID)
+	return count, err
+}
+
+// SetChatFolder creates the folder along with its chats, the chats the user isn't a participant of are skipped
+func (r *ChatListRepo) SetChatFolder(ctx context.Context, folder model.ChatFolder) (model.ChatFolder, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return folder, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO chat_folders (user_id, name, position, include_private, include_groups, include_channels, only_unread, include_archived)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING folder_id, created_at
+	`
+
+	err = tx.QueryRowxContext(ctx, query,
+		folder.UserID,
+		folder.Name,
+		folder.Position,
+		folder.IncludePrivate,
+		folder.IncludeGroups,
+		folder.IncludeChannels,
+		folder.OnlyUnread,
+		folder.IncludeArchived,
+	).Scan(&folder.FolderID, &folder.CreatedAt)
+	if err != nil {
+		return folder, err
+	}
+
+	if err := setChatFolderChats(ctx, tx, &folder); err != nil {
+		return folder, err
+	}
+
+	return folder, tx.Commit()
+}
+
+// UpdateChatFolder replaces the rules and the chats of the folder, returns sql.ErrNoRows if the user has no such folder
+func (r *ChatListRepo) UpdateChatFolder(ctx context.Context, folder model.ChatFolder) (model.ChatFolder, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return folder, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE chat_folders
+		SET name = $3, position = $4, include_private = $5, include_groups = $6,
+		    include_channels = $7, only_unread = $8, include_archived = $9
+		WHERE folder_id = $1 AND user_id = $2
+		RETURNING created_at
+	`
+
+	err = tx.QueryRowxContext(ctx, query,
+		folder.FolderID,
+		folder.UserID,
+		folder.Name,
+		folder.Position,
+		folder.IncludePrivate,
+		folder.IncludeGroups,
+		folder.IncludeChannels,
+		folder.OnlyUnread,
+		folder.IncludeArchived,
+	).Scan(&folder.CreatedAt)
+	if err != nil {
+		return folder, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM chat_folder_chats WHERE folder_id = $1`, folder.FolderID); err != nil {
+		return folder, err
+	}
+
+	if err := setChatFolderChats(ctx, tx, &folder); err != nil {
+		return folder, err
+	}
+
+	return folder, tx.Commit()
+}
+
+func (r *ChatListRepo) GetChatFolder(ctx context.Context, folderID int64, userID string) (model.ChatFolder, error) {
+	var folder model.ChatFolder
+
+	query := `
+		SELECT folder_id, user_id, name, position, include_private, include_groups, include_channels, only_unread, include_archived, created_at
+		FROM chat_folders
+		WHERE folder_id = $1 AND user_id = $2
+	`
+	if err := r.db.GetContext(ctx, &folder, query, folderID, userID); err != nil {
+		return folder, err
+	}
+
+	folders := []model.ChatFolder{folder}
+	if err := r.loadChatFolderChats(ctx, folders); err != nil {
+		return folder, err
+	}
+
+	return folders[0], nil
+}
+
+func (r *ChatListRepo) GetChatFoldersByUserID(ctx context.Context, userID string) ([]model.ChatFolder, error) {
+	var folders []model.ChatFolder
+
+	query := `
+		SELECT folder_id, user_id, name, position, include_private, include_groups, include_channels, only_unread, include_archived, created_at
+		FROM chat_folders
+		WHERE user_id = $1
+		ORDER BY position ASC, folder_id ASC
+	`
+	if err := r.db.SelectContext(ctx, &folders, query, userID); err != nil {
+		return nil, err
+	}
+
+	if err := r.loadChatFolderChats(ctx, folders); err != nil {
+		return nil, err
+	}
+
+	return folders, nil
+}
+
+// DeleteChatFolder returns sql.ErrNoRows if the user has no such folder
+func (r *ChatListRepo) DeleteChatFolder(ctx context.Context, folderID int64, userID string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM chat_folders WHERE folder_id = $1 AND user_id = $2`, folderID, userID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// loadChatFolderChats fills the included and the excluded chats of the folders
+func (r *ChatListRepo) loadChatFolderChats(ctx context.Context, folders []model.ChatFolder) error {
+	if len(folders) == 0 {
+		return nil
+	}
+
+	foldersIDs := make([]int64, len(folders))
+	byID := make(map[int64]*model.ChatFolder, len(folders))
+	for i := range folders {
+		foldersIDs[i] = folders[i].FolderID
+		byID[folders[i].FolderID] = &folders[i]
+		folders[i].IncludedChatIDs = []int64{}
+		folders[i].ExcludedChatIDs = []int64{}
+	}
+
+	var chats []model.ChatFolderChat
+	query := `
+		SELECT folder_id, chat_id, excluded
+		FROM chat_folder_chats
+		WHERE folder_id = ANY($1)
+		ORDER BY chat_id
+	`
+	if err := r.db.SelectContext(ctx, &chats, query, pq.Array(foldersIDs)); err != nil {
+		return err
+	}
+
+	for _, chat := range chats {
+		folder := byID[chat.FolderID]
+		if chat.Excluded {
+			folder.ExcludedChatIDs = append(folder.ExcludedChatIDs, chat.ChatID)
+		} else {
+			folder.IncludedChatIDs = append(folder.IncludedChatIDs, chat.ChatID)
+		}
+	}
+
+	return nil
+}
+
+// setChatFolderChats binds the chats of the user to the folder and keeps only those which were bound
+func setChatFolderChats(ctx context.Context, tx *sqlx.Tx, folder *model.ChatFolder) error {
+	bind := func(chatsIDs []int64, excluded bool) ([]int64, error) {
+		bound := []int64{}
+		if len(chatsIDs) == 0 {
+			return bound, nil
+		}
+
+		query := `
+			INSERT INTO chat_folder_chats (folder_id, chat_id, excluded)
+			SELECT $1, chat_id, $4
+			FROM chats_participants
+			WHERE user_id = $2 AND chat_id = ANY($3)
+			RETURNING chat_id
+		`
+		err := tx.SelectContext(ctx, &bound, query, folder.FolderID, folder.UserID, pq.Array(chatsIDs), excluded)
+		return bound, err
+	}
+
+	var err error
+	if folder.IncludedChatIDs, err = bind(folder.IncludedChatIDs, false); err != nil {
+		return err
+	}
+	folder.ExcludedChatIDs, err = bind(folder.ExcludedChatIDs, true)
+	return err
+}
@@ -0,0 +1,134 @@
+package repo
+
+import (
+	"chat-api/internal/model"
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type DataKeysRepo struct {
+	db *sqlx.DB
+}
+
+func NewDataKeysRepo(db *sqlx.DB) *DataKeysRepo {
+	return &DataKeysRepo{db: db}
+}
+
+func (r *DataKeysRepo) GetActiveDataKey(ctx context.Context, chatID int64) (model.DataKey, error) {
+	var key model.DataKey
+
+	query := `
+		SELECT key_id, chat_id, wrapped_key, master_key_version, active, created_at
+		FROM chat_data_keys
+		WHERE chat_id = $1 AND active
+	`
+
+	err := r.db.GetContext(ctx, &key, query, chatID)
+	return key, err
+}
+
+func (r *DataKeysRepo) GetDataKey(ctx context.Context, keyID int64) (model.DataKey, error) {
+	var key model.DataKey
+
+	query := `
+		SELECT key_id, chat_id, wrapped_key, master_key_version, active, created_at
+		FROM chat_data_keys
+		WHERE key_id = $1
+	`
+
+	err := r.db.GetContext(ctx, &key, query, keyID)
+	return key, err
+}
+
+// SetDataKey keeps the first active key of the chat when several replicas generate it at once
+func (r *DataKeysRepo) SetDataKey(ctx context.Context, key model.DataKey) (model.DataKey, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return model.DataKey{}, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO chat_data_keys (chat_id, wrapped_key, master_key_version)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (chat_id) WHERE active DO NOTHING
+	`, key.ChatID, key.WrappedKey, key.MasterKeyVersion)
+	if err != nil {
+		return model.DataKey{}, err
+	}
+
+	var active model.DataKey
+	err = tx.GetContext(ctx, &active, `
+		SELECT key_id, chat_id, wrapped_key, master_key_version, active, created_at
+		FROM chat_data_keys
+		WHERE chat_id = $1 AND active
+	`, key.ChatID)
+	if err != nil {
+		return model.DataKey{}, err
+	}
+
+	return active, tx.Commit()
+}
+
+// GetDataKeysToRewrap returns the keys wrapped by any master key other than the current one
+func (r *DataKeysRepo) GetDataKeysToRewrap(ctx context.Context, masterKeyVersion, limit int) ([]model.DataKey, error) {
+	var keys []model.DataKey
+
+	query := `
+		SELECT key_id, chat_id, wrapped_key, master_key_version, active, created_at
+		FROM chat_data_keys
+		WHERE master_key_version <> $1
+		ORDER BY key_id
+		LIMIT $2
+	`
+
+	if err := r.db.SelectContext(ctx, &keys, query, masterKeyVersion, limit); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// UpdateWrappedKey replaces the wrapped key unless another replica has rewrapped it already
+func (r *DataKeysRepo) UpdateWrappedKey(ctx context.Context, key model.DataKey, previousVersion int) (bool, error) {
+	query := `
+		UPDATE chat_data_keys
+		SET wrapped_key = $1, master_key_version = $2
+		WHERE key_id = $3 AND master_key_version = $4
+	`
+
+	result, err := r.db.ExecContext(ctx, query, key.WrappedKey, key.MasterKeyVersion, key.KeyID, previousVersion)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// DeactivateExpiredDataKeys retires the active keys created before createdBefore,
+// the next message of the chat generates a new one.
+func (r *DataKeysRepo) DeactivateExpiredDataKeys(ctx context.Context, createdBefore time.Time, limit int) (int, error) {
+	query := `
+		UPDATE chat_data_keys
+		SET active = FALSE
+		WHERE key_id IN (
+			SELECT key_id
+			FROM chat_data_keys
+			WHERE active AND created_at < $1
+			ORDER BY key_id
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+	`
+
+	result, err := r.db.ExecContext(ctx, query, createdBefore, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := result.RowsAffected()
+	return int(rows), err
+}
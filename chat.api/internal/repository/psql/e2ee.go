@@ -0,0 +1,211 @@
+package repo
+
+import (
+	"chat-api/internal/model"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type KeysRepo struct {
+	db *sqlx.DB
+}
+
+func NewKeysRepo(db *sqlx.DB) *KeysRepo {
+	return &KeysRepo{db: db}
+}
+
+// SetDeviceKeys replaces the identity and signed prekey of the device and adds the one-time prekeys
+func (r *KeysRepo) SetDeviceKeys(ctx context.Context, keys model.DeviceKeys) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO device_keys (user_id, device_id, identity_key, signed_prekey_id, signed_prekey, signed_prekey_signature)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id, device_id) DO UPDATE
+		SET identity_key = EXCLUDED.identity_key,
+			signed_prekey_id = EXCLUDED.signed_prekey_id,
+			signed_prekey = EXCLUDED.signed_prekey,
+			signed_prekey_signature = EXCLUDED.signed_prekey_signature,
+			updated_at = CURRENT_TIMESTAMP
+	`
+
+	_, err = tx.ExecContext(ctx, query, keys.UserID, keys.DeviceID, keys.IdentityKey,
+		keys.SignedPreKey.KeyID, keys.SignedPreKey.PublicKey, keys.SignedPreKey.Signature)
+	if err != nil {
+		return err
+	}
+
+	for _, preKey := range keys.OneTimePreKeys {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO one_time_prekeys (user_id, device_id, prekey_id, public_key)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT DO NOTHING
+		`, keys.UserID, keys.DeviceID, preKey.KeyID, preKey.PublicKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetPreKeyBundles returns a bundle per device of the user with the number of one-time prekeys left,
+// the handed out one-time prekeys are removed
+func (r *KeysRepo) GetPreKeyBundles(ctx context.Context, userID string) ([]model.PreKeyBundle, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var devices []struct {
+		UserID      string `db:"user_id"`
+		DeviceID    int    `db:"device_id"`
+		IdentityKey string `db:"identity_key"`
+		model.SignedPreKey
+	}
+
+	query := `
+		SELECT user_id, device_id, identity_key, signed_prekey_id, signed_prekey, signed_prekey_signature
+		FROM device_keys
+		WHERE user_id = $1
+		ORDER BY device_id
+	`
+
+	if err := tx.SelectContext(ctx, &devices, query, userID); err != nil {
+		return nil, err
+	}
+
+	bundles := make([]model.PreKeyBundle, 0, len(devices))
+	for _, device := range devices {
+		bundle := model.PreKeyBundle{
+			UserID:       device.UserID,
+			DeviceID:     device.DeviceID,
+			IdentityKey:  device.IdentityKey,
+			SignedPreKey: device.SignedPreKey,
+		}
+
+		var preKey model.PreKey
+		err := tx.GetContext(ctx, &preKey, `
+			DELETE FROM one_time_prekeys
+			WHERE (user_id, device_id, prekey_id) = (
+				SELECT user_id, device_id, prekey_id
+				FROM one_time_prekeys
+				WHERE user_id = $1 AND device_id = $2
+				ORDER BY prekey_id
+				LIMIT 1
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING prekey_id, public_key
+		`, bundle.UserID, bundle.DeviceID)
+		switch {
+		case err == nil:
+			bundle.OneTimePreKey = &preKey
+		case errors.Is(err, sql.ErrNoRows):
+			// the device ran out of one-time prekeys, the session starts from the signed prekey
+		default:
+			return nil, err
+		}
+
+		err = tx.GetContext(ctx, &bundle.OneTimePreKeysLeft, `
+			SELECT COUNT(*)
+			FROM one_time_prekeys
+			WHERE user_id = $1 AND device_id = $2
+		`, bundle.UserID, bundle.DeviceID)
+		if err != nil {
+			return nil, err
+		}
+
+		bundles = append(bundles, bundle)
+	}
+
+	return bundles, tx.Commit()
+}
+
+func (r *KeysRepo) CountOneTimePreKeys(ctx context.Context, userID string, deviceID int) (int, error) {
+	var count int
+
+	query := `
+		SELECT COUNT(*)
+		FROM one_time_prekeys
+		WHERE user_id = $1 AND device_id = $2
+	`
+
+	err := r.db.GetContext(ctx, &count, query, userID, deviceID)
+	return count, err
+}
+
+// SetEncryptedMessage stores the message without content and the ciphertext for every recipient device
+func (r *KeysRepo) SetEncryptedMessage(ctx context.Context, message model.MessageDB, chatID int64, senderDeviceID int, envelopes []model.EncryptedEnvelope) (messageID int64, createdAt time.Time, err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO messages (sender_id, status, type, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING message_id, created_at
+	`
+
+	err = tx.QueryRowContext(ctx, query, message.SenderID, message.Status, message.Type, message.ExpiresAt).
+		Scan(&messageID, &createdAt)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO chat_messages (chat_id, message_id)
+		VALUES ($1, $2)
+	`, chatID, messageID)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	for _, envelope := range envelopes {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO encrypted_envelopes (message_id, sender_device_id, recipient_id, device_id, ciphertext)
+			VALUES ($1, $2, $3, $4, $5)
+		`, messageID, senderDeviceID, envelope.RecipientID, envelope.DeviceID, envelope.Ciphertext)
+		if err != nil {
+			return 0, time.Time{}, err
+		}
+	}
+
+	return messageID, createdAt, tx.Commit()
+}
+
+// GetEncryptedMessages pages back through the messages of the chat addressed to the device
+// starting right before beforeMessageID; zero starts from the latest message.
+func (r *KeysRepo) GetEncryptedMessages(ctx context.Context, chatID int64, userID string, deviceID int, beforeMessageID int64, limit int) ([]model.EncryptedMessageDB, error) {
+	var messages []model.EncryptedMessageDB
+
+	query := `
+		SELECT m.message_id, m.sender_id, m.status, m.type, m.created_at, m.updated_at, m.expires_at,
+			ee.sender_device_id, ee.recipient_id, ee.device_id, ee.ciphertext
+		FROM messages m
+		JOIN chat_messages cm ON m.message_id = cm.message_id
+		JOIN encrypted_envelopes ee ON m.message_id = ee.message_id
+		WHERE cm.chat_id = $1 AND ee.recipient_id = $2 AND ee.device_id = $3
+			AND (m.expires_at IS NULL OR m.expires_at > CURRENT_TIMESTAMP)
+			AND ($4 = 0 OR m.message_id < $4)
+		ORDER BY m.message_id DESC
+		LIMIT $5
+	`
+
+	err := r.db.SelectContext(ctx, &messages, query, chatID, userID, deviceID, beforeMessageID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
@@ -0,0 +1,147 @@
+package repo
+
+import (
+	"chat-api/internal/model"
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const exportColumns = `export_id, user_id, chat_id, status, storage_key, size, error, created_at, started_at, completed_at, expires_at`
+
+type ExportsRepo struct {
+	db *sqlx.DB
+}
+
+func NewExportsRepo(db *sqlx.DB) *ExportsRepo {
+	return &ExportsRepo{db: db}
+}
+
+func (r *ExportsRepo) SetExport(ctx context.Context, export model.Export) (model.Export, error) {
+	query := `
+		INSERT INTO chat_exports (user_id, chat_id, status)
+		VALUES ($1, $2, $3)
+		RETURNING export_id, created_at
+	`
+
+	err := r.db.QueryRowxContext(ctx, query, export.UserID, export.ChatID, export.Status).
+		Scan(&export.ExportID, &export.CreatedAt)
+	return export, err
+}
+
+// GetExport returns sql.ErrNoRows if the user has no such export
+func (r *ExportsRepo) GetExport(ctx context.Context, exportID int64, userID string) (model.Export, error) {
+	var export model.Export
+
+	query := `
+		SELECT ` + exportColumns + `
+		FROM chat_exports
+		WHERE export_id = $1 AND user_id = $2
+	`
+
+	err := r.db.GetContext(ctx, &export, query, exportID, userID)
+	return export, err
+}
+
+func (r *ExportsRepo) GetExportsByUserID(ctx context.Context, userID string, limit int) ([]model.Export, error) {
+	var exports []model.Export
+
+	query := `
+		SELECT ` + exportColumns + `
+		FROM chat_exports
+		WHERE user_id = $1
+		ORDER BY export_id DESC
+		LIMIT $2
+	`
+
+	err := r.db.SelectContext(ctx, &exports, query, userID, limit)
+	return exports, err
+}
+
+func (r *ExportsRepo) CountUnfinishedExports(ctx context.Context, userID string) (int, error) {
+	var count int
+
+	query := `
+		SELECT COUNT(*)
+		FROM chat_exports
+		WHERE user_id = $1 AND status IN ($2, $3)
+	`
+
+	err := r.db.GetContext(ctx, &count, query, userID, model.EXPORT_STATUS_PENDING, model.EXPORT_STATUS_RUNNING)
+	return count, err
+}
+
+// StartExport takes the pending export, sql.ErrNoRows if it's taken already or gone
+func (r *ExportsRepo) StartExport(ctx context.Context, exportID int64) (model.Export, error) {
+	var export model.Export
+
+	query := `
+		UPDATE chat_exports
+		SET status = $2, started_at = CURRENT_TIMESTAMP
+		WHERE export_id = $1 AND status = $3
+		RETURNING ` + exportColumns
+
+	err := r.db.GetContext(ctx, &export, query, exportID, model.EXPORT_STATUS_RUNNING, model.EXPORT_STATUS_PENDING)
+	return export, err
+}
+
+func (r *ExportsRepo) CompleteExport(ctx context.Context, exportID int64, storageKey string, size int64, expiresAt time.Time) (model.Export, error) {
+	var export model.Export
+
+	query := `
+		UPDATE chat_exports
+		SET status = $2, storage_key = $3, size = $4, expires_at = $5, completed_at = CURRENT_TIMESTAMP
+		WHERE export_id = $1
+		RETURNING ` + exportColumns
+
+	err := r.db.GetContext(ctx, &export, query, exportID, model.EXPORT_STATUS_READY, storageKey, size, expiresAt)
+	return export, err
+}
+
+func (r *ExportsRepo) FailExport(ctx context.Context, exportID int64, message string, expiresAt time.Time) (model.Export, error) {
+	var export model.Export
+
+	query := `
+		UPDATE chat_exports
+		SET status = $2, error = $3, expires_at = $4, completed_at = CURRENT_TIMESTAMP
+		WHERE export_id = $1
+		RETURNING ` + exportColumns
+
+	err := r.db.GetContext(ctx, &export, query, exportID, model.EXPORT_STATUS_FAILED, message, expiresAt)
+	return export, err
+}
+
+// FailStaleExports fails the exports whose worker has been gone since startedBefore, so the users can ask again
+func (r *ExportsRepo) FailStaleExports(ctx context.Context, startedBefore, expiresAt time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE chat_exports
+		SET status = $1, error = $2, expires_at = $3, completed_at = CURRENT_TIMESTAMP
+		WHERE status = $4 AND started_at < $5
+	`, model.EXPORT_STATUS_FAILED, model.ErrExportTimeout.Error(), expiresAt, model.EXPORT_STATUS_RUNNING, startedBefore)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// GetExpiredExports returns the finished exports past their expiration, the failed ones expire too
+func (r *ExportsRepo) GetExpiredExports(ctx context.Context, limit int) ([]model.Export, error) {
+	var exports []model.Export
+
+	query := `
+		SELECT ` + exportColumns + `
+		FROM chat_exports
+		WHERE status IN ($1, $2) AND expires_at <= CURRENT_TIMESTAMP
+		ORDER BY expires_at
+		LIMIT $3
+	`
+
+	err := r.db.SelectContext(ctx, &exports, query, model.EXPORT_STATUS_READY, model.EXPORT_STATUS_FAILED, limit)
+	return exports, err
+}
+
+func (r *ExportsRepo) DeleteExport(ctx context.Context, exportID int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM chat_exports WHERE export_id = $1`, exportID)
+	return err
+}
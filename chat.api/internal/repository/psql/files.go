@@ -37,16 +37,42 @@ func (r *FilesRepo) SetFile(ctx context.Context, file model.File) (fileID int64,
 	return fileID, tx.Commit()
 }
 
+// SetUploadedFile stores the file received by chat.api, the url is the storage key of the blob
+func (r *FilesRepo) SetUploadedFile(ctx context.Context, file model.File, sha256, userID string) (fileID int64, err error) {
+	query := `
+		INSERT INTO files (url, type, size, sha256, uploaded_by, duration, waveform)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING file_id
+	`
+
+	err = r.db.QueryRowContext(ctx, query, file.URL, file.Type, file.Size, sha256, userID, file.Duration, file.Waveform).Scan(&fileID)
+	return fileID, err
+}
+
+// GetUploadedFile returns sql.ErrNoRows unless the file was uploaded by the user
+func (r *FilesRepo) GetUploadedFile(ctx context.Context, fileID int64, userID string) (model.File, error) {
+	var file model.File
+
+	query := `
+		SELECT file_id, url, type, size, uploaded_at, duration, waveform
+		FROM files
+		WHERE file_id = $1 AND uploaded_by = $2
+	`
+
+	err := r.db.GetContext(ctx, &file, query, fileID, userID)
+	return file, err
+}
+
 func (r *FilesRepo) GetFileByFileID(ctx context.Context, fileID int64) (model.File, error) {
 	var file model.File
 
 	query := `
-		SELECT f.file_id, f.url, f.type, f.size, f.uploaded_at
+		SELECT f.file_id, f.url, f.type, f.size, f.uploaded_at, f.duration, f.waveform
 		FROM files f
 		WHERE f.file_id = $1
 	`
 
-	err := r.db.QueryRowContext(ctx, query, fileID).Scan(&file.FileID, &file.URL, &file.Type, &file.Size, &file.UploadedAt)
+	err := r.db.QueryRowContext(ctx, query, fileID).Scan(&file.FileID, &file.URL, &file.Type, &file.Size, &file.UploadedAt, &file.Duration, &file.Waveform)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return file, nil
@@ -61,7 +87,7 @@ func (r *FilesRepo) GetFilesByMessageID(ctx context.Context, messageID int64) ([
 	var files []model.File
 
 	query := `
-		SELECT f.file_id, f.url, f.type, f.size, f.uploaded_at
+		SELECT f.file_id, f.url, f.type, f.size, f.uploaded_at, f.duration, f.waveform
 		FROM files f
 		JOIN messages_files mf ON f.file_id = mf.file_id
 		WHERE mf.message_id = $1
@@ -80,7 +106,7 @@ func (r *FilesRepo) GetFilesByChatID(ctx context.Context, chatID int64) ([]model
 	var files []model.File
 
 	query := `
-		SELECT f.file_id, f.url, f.type, f.size, f.uploaded_at
+		SELECT f.file_id, f.url, f.type, f.size, f.uploaded_at, f.duration, f.waveform
 		FROM files f
 		JOIN messages_files mf ON f.file_id = mf.file_id
 		JOIN chat_messages cm ON mf.message_id = cm.message_id
@@ -0,0 +1,78 @@
+package repo
+
+import (
+	"chat-api/internal/model"
+	"context"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type ImportsRepo struct {
+	db *sqlx.DB
+}
+
+func NewImportsRepo(db *sqlx.DB) *ImportsRepo {
+	return &ImportsRepo{db: db}
+}
+
+// ImportChat fills the created chat with the participants and the messages keeping their times,
+// either all of them are stored or none. Returns the ids of the messages in their order
+func (r *ImportsRepo) ImportChat(ctx context.Context, chatImport model.ChatImport) ([]int64, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	for _, userID := range chatImport.ParticipantsIDs {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO chats_participants (chat_id, user_id)
+			VALUES ($1, $2)
+			ON CONFLICT DO NOTHING
+		`, chatImport.ChatID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO chat_history (chat_id, user_id, action_type)
+		VALUES ($1, $2, $3)
+	`, chatImport.ChatID, chatImport.UserID, model.CHAT_ACTION_CREATE); err != nil {
+		return nil, err
+	}
+
+	messageIDs := make([]int64, len(chatImport.Messages))
+	for i, imported := range chatImport.Messages {
+		message := imported.MessageDB
+		if imported.ReplyTo >= 0 {
+			message.ReplyToMessageID = &messageIDs[imported.ReplyTo]
+		}
+
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO messages (message_id, sender_id, content, status, type, reply_to_message_id, created_at, updated_at, imported_sender_name)
+			OVERRIDING SYSTEM VALUE
+			VALUES (COALESCE(NULLIF($1::BIGINT, 0), nextval(pg_get_serial_sequence('messages', 'message_id'))), $2, $3, $4, $5, $6, $7, $8, $9)
+			RETURNING message_id
+		`, message.MessageID, message.SenderID, message.Content, message.Status, message.Type, message.ReplyToMessageID, message.CreatedAt, message.UpdatedAt, message.ImportedSenderName).
+			Scan(&messageIDs[i])
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO chat_messages (chat_id, message_id)
+			VALUES ($1, $2)
+		`, chatImport.ChatID, messageIDs[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO chat_imports (chat_id, user_id, messages_count)
+		VALUES ($1, $2, $3)
+	`, chatImport.ChatID, chatImport.UserID, len(messageIDs)); err != nil {
+		return nil, err
+	}
+
+	return messageIDs, tx.Commit()
+}
@@ -0,0 +1,217 @@
+package repo
+
+import (
+	"chat-api/internal/model"
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type InvitesRepo struct {
+	db *sqlx.DB
+}
+
+func NewInvitesRepo(db *sqlx.DB) *InvitesRepo {
+	return &InvitesRepo{db: db}
+}
+
+func (r *InvitesRepo) SetInviteLink(ctx context.Context, link model.InviteLink) (model.InviteLink, error) {
+	query := `
+		INSERT INTO chat_invite_links (chat_id, creator_id, token, name, expires_at, usage_limit, requires_approval)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING invite_link_id, usage_count, revoked, created_at
+	`
+
+	err := r.db.QueryRowxContext(ctx, query, link.ChatID, link.CreatorID, link.Token, link.Name, link.ExpiresAt, link.UsageLimit, link.RequiresApproval).
+		Scan(&link.InviteLinkID, &link.UsageCount, &link.Revoked, &link.CreatedAt)
+	return link, err
+}
+
+func (r *InvitesRepo) GetInviteLinksByChatID(ctx context.Context, chatID int64, limit int) ([]model.InviteLink, error) {
+	var links []model.InviteLink
+
+	query := `
+		SELECT invite_link_id, chat_id, creator_id, token, name, expires_at, usage_limit, usage_count, requires_approval, revoked, created_at
+		FROM chat_invite_links
+		WHERE chat_id = $1
+		ORDER BY invite_link_id DESC
+		LIMIT $2
+	`
+
+	err := r.db.SelectContext(ctx, &links, query, chatID, limit)
+	return links, err
+}
+
+// RevokeInviteLink returns sql.ErrNoRows if the chat has no such link
+func (r *InvitesRepo) RevokeInviteLink(ctx context.Context, chatID, inviteLinkID int64) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE chat_invite_links
+		SET revoked = true
+		WHERE chat_id = $1 AND invite_link_id = $2
+	`, chatID, inviteLinkID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// JoinByInviteLink adds the user to the chat of the link, or queues the join request if the link requires
+// the approval of the admins. The link is locked while check decides whether it can be used, so its usage
+// limit holds under concurrent joins. Returns sql.ErrNoRows if there's no such link.
+func (r *InvitesRepo) JoinByInviteLink(ctx context.Context, token, userID string, check func(link model.InviteLink) error) (model.InviteLink, error) {
+	var link model.InviteLink
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return link, err
+	}
+	defer tx.Rollback()
+
+	err = tx.GetContext(ctx, &link, `
+		SELECT invite_link_id, chat_id, creator_id, token, name, expires_at, usage_limit, usage_count, requires_approval, revoked, created_at
+		FROM chat_invite_links
+		WHERE token = $1
+		FOR UPDATE
+	`, token)
+	if err != nil {
+		return link, err
+	}
+
+	if err := check(link); err != nil {
+		return link, err
+	}
+
+	if link.RequiresApproval {
+		// the request rejected before can be sent again
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO chat_join_requests (chat_id, user_id, invite_link_id)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (chat_id, user_id) DO UPDATE
+			SET invite_link_id = EXCLUDED.invite_link_id, status = 'pending', requested_at = CURRENT_TIMESTAMP,
+				decided_by = NULL, decided_at = NULL
+		`, link.ChatID, userID, link.InviteLinkID)
+		if err != nil {
+			return link, err
+		}
+		return link, tx.Commit()
+	}
+
+	if _, err := joinChat(ctx, tx, link.ChatID, userID, &link.InviteLinkID); err != nil {
+		return link, err
+	}
+	return link, tx.Commit()
+}
+
+func (r *InvitesRepo) GetPendingJoinRequests(ctx context.Context, chatID int64) ([]model.JoinRequest, error) {
+	var requests []model.JoinRequest
+
+	query := `
+		SELECT chat_id, user_id, invite_link_id, status, requested_at, decided_by, decided_at
+		FROM chat_join_requests
+		WHERE chat_id = $1 AND status = 'pending'
+		ORDER BY requested_at ASC
+	`
+
+	err := r.db.SelectContext(ctx, &requests, query, chatID)
+	return requests, err
+}
+
+// ApproveJoinRequest adds the user to the chat, the approval counts as a use of the link even if
+// its limit has been reached since the request. Returns sql.ErrNoRows if no request is pending.
+func (r *InvitesRepo) ApproveJoinRequest(ctx context.Context, chatID int64, userID, adminID string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var inviteLinkID *int64
+	err = tx.GetContext(ctx, &inviteLinkID, `
+		UPDATE chat_join_requests
+		SET status = 'approved', decided_by = $3, decided_at = CURRENT_TIMESTAMP
+		WHERE chat_id = $1 AND user_id = $2 AND status = 'pending'
+		RETURNING invite_link_id
+	`, chatID, userID, adminID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := joinChat(ctx, tx, chatID, userID, inviteLinkID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RejectJoinRequest returns sql.ErrNoRows if no request is pending
+func (r *InvitesRepo) RejectJoinRequest(ctx context.Context, chatID int64, userID, adminID string) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE chat_join_requests
+		SET status = 'rejected', decided_by = $3, decided_at = CURRENT_TIMESTAMP
+		WHERE chat_id = $1 AND user_id = $2 AND status = 'pending'
+	`, chatID, userID, adminID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// joinChat adds the participant and records the join in the history of the chat,
+// the link is used only if the user wasn't a participant yet
+func joinChat(ctx context.Context, tx *sqlx.Tx, chatID int64, userID string, inviteLinkID *int64) (joined bool, err error) {
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO chats_participants (chat_id, user_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`, chatID, userID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		return false, err
+	}
+
+	if err := setChatAction(ctx, tx, chatID, userID, model.CHAT_ACTION_JOIN); err != nil {
+		return false, err
+	}
+
+	// the history before the join isn't unread for the new participant
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO chat_user_states (chat_id, user_id, last_read_message_id)
+		SELECT $1, $2, COALESCE(MAX(message_id), 0)
+		FROM chat_messages
+		WHERE chat_id = $1
+		ON CONFLICT (chat_id, user_id) DO UPDATE
+		SET last_read_message_id = GREATEST(chat_user_states.last_read_message_id, EXCLUDED.last_read_message_id)
+	`, chatID, userID); err != nil {
+		return false, err
+	}
+
+	if inviteLinkID == nil {
+		return true, nil
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE chat_invite_links
+		SET usage_count = usage_count + 1
+		WHERE invite_link_id = $1
+	`, *inviteLinkID)
+	return true, err
+}
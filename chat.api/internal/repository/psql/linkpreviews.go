@@ -0,0 +1,47 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type LinkPreviewsRepo struct {
+	db *sqlx.DB
+}
+
+func NewLinkPreviewsRepo(db *sqlx.DB) *LinkPreviewsRepo {
+	return &LinkPreviewsRepo{db: db}
+}
+
+// SetLinkPreview stores the encrypted preview of the message replacing the previous one
+func (r *LinkPreviewsRepo) SetLinkPreview(ctx context.Context, messageID int64, preview string) error {
+	query := `
+		INSERT INTO message_link_previews (message_id, preview)
+		VALUES ($1, $2)
+		ON CONFLICT (message_id) DO UPDATE
+		SET preview = EXCLUDED.preview, created_at = CURRENT_TIMESTAMP
+	`
+
+	_, err := r.db.ExecContext(ctx, query, messageID, preview)
+	return err
+}
+
+// GetLinkPreview returns sql.ErrNoRows if the message has no preview
+func (r *LinkPreviewsRepo) GetLinkPreview(ctx context.Context, messageID int64) (string, error) {
+	var preview string
+
+	query := `
+		SELECT preview
+		FROM message_link_previews
+		WHERE message_id = $1
+	`
+
+	err := r.db.GetContext(ctx, &preview, query, messageID)
+	return preview, err
+}
+
+func (r *LinkPreviewsRepo) DeleteLinkPreview(ctx context.Context, messageID int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM message_link_previews WHERE message_id = $1`, messageID)
+	return err
+}
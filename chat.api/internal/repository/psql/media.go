@@ -4,6 +4,7 @@ import (
 	"chat-api/internal/model"
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 )
@@ -37,16 +38,42 @@ func (r *MediaRepo) SetMedia(ctx context.Context, media model.Media) (mediaID in
 	return mediaID, tx.Commit()
 }
 
+// SetUploadedMedia stores the media received by chat.api, the url is the storage key of the blob
+func (r *MediaRepo) SetUploadedMedia(ctx context.Context, media model.Media, sha256, userID string) (mediaID int64, err error) {
+	query := `
+		INSERT INTO media (url, type, size, sha256, uploaded_by, processing_status)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING media_id
+	`
+
+	err = r.db.QueryRowContext(ctx, query, media.URL, media.Type, media.Size, sha256, userID, model.MEDIA_STATUS_PENDING).Scan(&mediaID)
+	return mediaID, err
+}
+
+// GetUploadedMedia returns sql.ErrNoRows unless the media was uploaded by the user
+func (r *MediaRepo) GetUploadedMedia(ctx context.Context, mediaID int64, userID string) (model.Media, error) {
+	var media model.Media
+
+	query := `
+		SELECT media_id, url, type, size, uploaded_at, width, height, duration, blurhash, thumbnail_sha256, processing_status
+		FROM media
+		WHERE media_id = $1 AND uploaded_by = $2
+	`
+
+	err := r.db.GetContext(ctx, &media, query, mediaID, userID)
+	return media, err
+}
+
 func (r *MediaRepo) GetMediaByMediaID(ctx context.Context, mediaID int64) (model.Media, error) {
 	var media model.Media
 
 	query := `
-		SELECT media_id, url, type, size, uploaded_at
+		SELECT media_id, url, type, size, uploaded_at, width, height, duration, blurhash, thumbnail_sha256, processing_status
 		FROM media
 		WHERE media_id = $1
 	`
 
-	err := r.db.QueryRowContext(ctx, query, mediaID).Scan(&media.MediaID, &media.URL, &media.Type, &media.Size, &media.UploadedAt)
+	err := r.db.GetContext(ctx, &media, query, mediaID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return media, nil
@@ -61,7 +88,7 @@ func (r *MediaRepo) GetMediaFileByMessageID(ctx context.Context, messageID int64
 	var mediaFiles []model.Media
 
 	query := `
-		SELECT m.media_id, m.url, m.type, m.size, m.uploaded_at
+		SELECT m.media_id, m.url, m.type, m.size, m.uploaded_at, m.width, m.height, m.duration, m.blurhash, m.thumbnail_sha256, m.processing_status
 		FROM media m
 		JOIN messages_media mm ON m.media_id = mm.media_id
 		WHERE mm.message_id = $1
@@ -80,7 +107,7 @@ func (r *MediaRepo) GetMediaByChatID(ctx context.Context, chatID int64) ([]model
 	var mediaFiles []model.Media
 
 	query := `
-		SELECT m.media_id, m.url, m.type, m.size, m.uploaded_at
+		SELECT m.media_id, m.url, m.type, m.size, m.uploaded_at, m.width, m.height, m.duration, m.blurhash, m.thumbnail_sha256, m.processing_status
 		FROM media m
 		JOIN messages_media mm ON m.media_id = mm.media_id
 		JOIN chat_messages chm ON mm.message_id = chm.message_id
@@ -96,6 +123,107 @@ func (r *MediaRepo) GetMediaByChatID(ctx context.Context, chatID int64) ([]model
 	return mediaFiles, nil
 }
 
+func (r *MediaRepo) GetMediaSource(ctx context.Context, mediaID int64) (model.MediaSource, error) {
+	var source model.MediaSource
+
+	query := `
+		SELECT m.media_id, m.url, m.type, m.size, m.uploaded_at, m.width, m.height, m.duration, m.blurhash, m.thumbnail_sha256, m.processing_status,
+			b.sha256, b.storage_key, COALESCE(m.uploaded_by, '') AS uploaded_by
+		FROM media m
+		JOIN blobs b ON m.sha256 = b.sha256
+		WHERE m.media_id = $1
+	`
+
+	err := r.db.GetContext(ctx, &source, query, mediaID)
+	return source, err
+}
+
+// GetMediaLocations returns the messages the media is attached to
+func (r *MediaRepo) GetMediaLocations(ctx context.Context, mediaID int64) ([]model.MediaLocation, error) {
+	var locations []model.MediaLocation
+
+	query := `
+		SELECT chm.chat_id, mm.message_id
+		FROM messages_media mm
+		JOIN chat_messages chm ON mm.message_id = chm.message_id
+		WHERE mm.media_id = $1
+	`
+
+	if err := r.db.SelectContext(ctx, &locations, query, mediaID); err != nil {
+		return nil, err
+	}
+
+	return locations, nil
+}
+
+// UpdateProcessedMedia stores the result of the processing, sha256 points to the blob without the GPS data
+func (r *MediaRepo) UpdateProcessedMedia(ctx context.Context, media model.Media, sha256 string) error {
+	query := `
+		UPDATE media
+		SET url = $1, sha256 = $2, width = $3, height = $4, duration = $5,
+			blurhash = $6, thumbnail_sha256 = $7, processing_status = $8
+		WHERE media_id = $9
+	`
+
+	_, err := r.db.ExecContext(ctx, query, media.URL, sha256, media.Width, media.Height, media.Duration,
+		media.BlurHash, media.ThumbnailSHA256, media.Status, media.MediaID)
+	return err
+}
+
+// RequeueStaleMedia counts one more attempt for up to limit media pending since before staleBefore
+// and returns them to queue the jobs again, rows locked by another replica are skipped
+func (r *MediaRepo) RequeueStaleMedia(ctx context.Context, staleBefore time.Time, maxAttempts, limit int) ([]int64, error) {
+	var mediaIDs []int64
+
+	query := `
+		UPDATE media
+		SET processing_attempts = processing_attempts + 1, processing_requested_at = $1
+		WHERE media_id IN (
+			SELECT media_id
+			FROM media
+			WHERE processing_status = 'pending' AND COALESCE(processing_requested_at, uploaded_at) < $2
+				AND processing_attempts < $3
+			ORDER BY uploaded_at
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING media_id
+	`
+
+	if err := r.db.SelectContext(ctx, &mediaIDs, query, time.Now().UTC(), staleBefore, maxAttempts, limit); err != nil {
+		return nil, err
+	}
+
+	return mediaIDs, nil
+}
+
+// FailStaleMedia gives up on up to limit media still pending after the last attempt.
+// The JPEG may still carry the GPS data so it's rejected, the rest is served without the preview
+func (r *MediaRepo) FailStaleMedia(ctx context.Context, staleBefore time.Time, maxAttempts, limit int) ([]int64, error) {
+	var mediaIDs []int64
+
+	query := `
+		UPDATE media
+		SET processing_status = CASE WHEN type = 'image/jpeg' THEN 'rejected' ELSE 'failed' END
+		WHERE media_id IN (
+			SELECT media_id
+			FROM media
+			WHERE processing_status = 'pending' AND COALESCE(processing_requested_at, uploaded_at) < $1
+				AND processing_attempts >= $2
+			ORDER BY uploaded_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING media_id
+	`
+
+	if err := r.db.SelectContext(ctx, &mediaIDs, query, staleBefore, maxAttempts, limit); err != nil {
+		return nil, err
+	}
+
+	return mediaIDs, nil
+}
+
 func (r *MediaRepo) DeleteMedia(ctx context.Context, mediaID int64) error {
 	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
@@ -0,0 +1,190 @@
+package repo
+
+import (
+	"chat-api/internal/model"
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type MembersRepo struct {
+	db *sqlx.DB
+}
+
+func NewMembersRepo(db *sqlx.DB) *MembersRepo {
+	return &MembersRepo{db: db}
+}
+
+// AddParticipants adds the users lifting their bans and returns those who weren't participants before
+func (r *MembersRepo) AddParticipants(ctx context.Context, chatID int64, usersIDs []string) ([]string, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	addedIDs := make([]string, 0, len(usersIDs))
+	for _, userID := range usersIDs {
+		_, err := tx.ExecContext(ctx, `
+			DELETE FROM chat_blocked_users
+			WHERE chat_id = $1 AND user_id = $2
+		`, chatID, userID)
+		if err != nil {
+			return nil, err
+		}
+
+		joined, err := joinChat(ctx, tx, chatID, userID, nil)
+		if err != nil {
+			return nil, err
+		}
+		if joined {
+			addedIDs = append(addedIDs, userID)
+		}
+	}
+
+	return addedIDs, tx.Commit()
+}
+
+// RemoveParticipant removes the user along with the role, the pin, the state and the folder entries of the chat,
+// returns sql.ErrNoRows if the user isn't a participant
+func (r *MembersRepo) RemoveParticipant(ctx context.Context, chatID int64, userID, action string, ban bool) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		DELETE FROM chats_participants
+		WHERE chat_id = $1 AND user_id = $2
+	`, chatID, userID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	for _, query := range []string{
+		`DELETE FROM chat_roles WHERE chat_id = $1 AND user_id = $2`,
+		`DELETE FROM pinned_chats WHERE chat_id = $1 AND user_id = $2`,
+		`DELETE FROM chat_user_states WHERE chat_id = $1 AND user_id = $2`,
+		`DELETE FROM chat_folder_chats fc USING chat_folders f WHERE fc.folder_id = f.folder_id AND fc.chat_id = $1 AND f.user_id = $2`,
+	} {
+		if _, err := tx.ExecContext(ctx, query, chatID, userID); err != nil {
+			return err
+		}
+	}
+
+	if ban {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO chat_blocked_users (chat_id, user_id, banned)
+			VALUES ($1, $2, TRUE)
+			ON CONFLICT (chat_id, user_id) DO UPDATE
+			SET banned = TRUE
+		`, chatID, userID)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := setChatAction(ctx, tx, chatID, userID, action); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UpdateChatInfo changes the given fields only and records the rename of the chat,
+// the empty handle is removed
+func (r *MembersRepo) UpdateChatInfo(ctx context.Context, request model.UpdateChatInfoRequest) (model.ChatDB, error) {
+	var chat model.ChatDB
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return chat, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE chats
+		SET name = COALESCE($2, name),
+		    description = COALESCE($3, description),
+		    avatar_url = COALESCE($4, avatar_url),
+		    handle = CASE WHEN $5::text IS NULL THEN handle ELSE NULLIF($5, '') END,
+		    sign_messages = COALESCE($6, sign_messages),
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE chat_id = $1
+		RETURNING *
+	`
+
+	if err := tx.GetContext(ctx, &chat, query, request.ChatID, request.Name, request.Description, request.AvatarURL, request.Handle, request.SignMessages); err != nil {
+		return chat, err
+	}
+
+	if request.Name != nil {
+		if err := setChatAction(ctx, tx, request.ChatID, request.UserID, model.CHAT_ACTION_RENAME); err != nil {
+			return chat, err
+		}
+	}
+
+	return chat, tx.Commit()
+}
+
+// TransferOwnership hands the chat over to the participant and keeps the previous creator as an admin,
+// returns sql.ErrNoRows if the chat has another creator
+func (r *MembersRepo) TransferOwnership(ctx context.Context, chatID int64, creatorID, newCreatorID string) (model.ChatDB, error) {
+	var chat model.ChatDB
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return chat, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE chats
+		SET creator_id = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE chat_id = $1 AND creator_id = $2
+		RETURNING *
+	`
+
+	if err := tx.GetContext(ctx, &chat, query, chatID, creatorID, newCreatorID); err != nil {
+		return chat, err
+	}
+
+	// the role of the owner comes from the chat itself
+	_, err = tx.ExecContext(ctx, `DELETE FROM chat_roles WHERE chat_id = $1 AND user_id = $2`, chatID, newCreatorID)
+	if err != nil {
+		return chat, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO chat_roles (chat_id, user_id, granter_id, nickname, role)
+		VALUES ($1, $2, $3, '', $4)
+		ON CONFLICT (chat_id, user_id) DO UPDATE
+		SET granter_id = EXCLUDED.granter_id, role = EXCLUDED.role
+	`, chatID, creatorID, newCreatorID, model.CHAT_ROLE_ADMIN)
+	if err != nil {
+		return chat, err
+	}
+
+	return chat, tx.Commit()
+}
+
+// setChatAction keeps the last action of the user in the chat
+func setChatAction(ctx context.Context, tx *sqlx.Tx, chatID int64, userID, action string) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO chat_history (chat_id, user_id, action_type)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (chat_id, user_id) DO UPDATE
+		SET action_type = EXCLUDED.action_type, action_timestamp = CURRENT_TIMESTAMP
+	`, chatID, userID, action)
+	return err
+}
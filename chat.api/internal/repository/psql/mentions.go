@@ -0,0 +1,99 @@
+package repo
+
+import (
+	"chat-api/internal/model"
+	"context"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type MentionsRepo struct {
+	db *sqlx.DB
+}
+
+func NewMentionsRepo(db *sqlx.DB) *MentionsRepo {
+	return &MentionsRepo{db: db}
+}
+
+func (r *MentionsRepo) SetMentions(ctx context.Context, messageID int64, mentions []model.Mention) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO message_mentions (message_id, mention_offset, mention_length, user_id)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	for _, mention := range mentions {
+		if _, err := tx.ExecContext(ctx, query, messageID, mention.Offset, mention.Length, mention.UserID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *MentionsRepo) GetMentionsByMessageID(ctx context.Context, messageID int64) ([]model.Mention, error) {
+	var mentions []model.Mention
+
+	query := `
+		SELECT user_id, mention_offset, mention_length
+		FROM message_mentions
+		WHERE message_id = $1
+		ORDER BY mention_offset ASC
+	`
+
+	if err := r.db.SelectContext(ctx, &mentions, query, messageID); err != nil {
+		return nil, err
+	}
+
+	return mentions, nil
+}
+
+// GetUnreadMentions returns the messages of the chat mentioning the user which aren't read by them, oldest first
+func (r *MentionsRepo) GetUnreadMentions(ctx context.Context, chatID int64, userID string) ([]model.UnreadMention, error) {
+	var mentions []model.UnreadMention
+
+	query := `
+		SELECT m.message_id, m.sender_id, m.created_at
+		FROM messages m
+		JOIN chat_messages chm ON m.message_id = chm.message_id
+		WHERE chm.chat_id = $1
+		  AND EXISTS (
+		      SELECT 1 FROM message_mentions mm WHERE mm.message_id = m.message_id AND mm.user_id = $2
+		  )
+		  AND NOT EXISTS (
+		      SELECT 1 FROM mention_reads mr WHERE mr.message_id = m.message_id AND mr.user_id = $2
+		  )
+		ORDER BY m.message_id ASC
+	`
+
+	if err := r.db.SelectContext(ctx, &mentions, query, chatID, userID); err != nil {
+		return nil, err
+	}
+
+	return mentions, nil
+}
+
+// SetMentionsRead marks the mentions of the user in the chat up to the message as read, returns the number of new reads
+func (r *MentionsRepo) SetMentionsRead(ctx context.Context, chatID int64, userID string, upToMessageID int64) (int, error) {
+	query := `
+		INSERT INTO mention_reads (message_id, user_id)
+		SELECT DISTINCT mm.message_id, mm.user_id
+		FROM message_mentions mm
+		JOIN chat_messages chm ON mm.message_id = chm.message_id
+		WHERE chm.chat_id = $1 AND mm.user_id = $2 AND mm.message_id <= $3
+		ON CONFLICT DO NOTHING
+	`
+
+	result, err := r.db.ExecContext(ctx, query, chatID, userID, upToMessageID)
+	if err != nil {
+		return 0, err
+	}
+
+	read, err := result.RowsAffected()
+	return int(read), err
+}
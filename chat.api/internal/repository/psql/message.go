@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 type MessagesRepo struct {
@@ -17,6 +18,25 @@ func NewMessagesRepo(db *sqlx.DB) *MessagesRepo {
 	return &MessagesRepo{db: db}
 }
 
+// ReserveMessageIDs takes the ids of the messages before they are stored, so their content can be bound to them.
+// The id that is never stored is just skipped
+func (r *MessagesRepo) ReserveMessageIDs(ctx context.Context, count int) ([]int64, error) {
+	var messageIDs []int64
+
+	query := `
+		SELECT nextval(pg_get_serial_sequence('messages', 'message_id'))
+		FROM generate_series(1, $1)
+	`
+
+	err := r.db.SelectContext(ctx, &messageIDs, query, count)
+	if err != nil {
+		return nil, err
+	}
+
+	return messageIDs, nil
+}
+
+// SetMessage stores the message under the reserved MessageID, the message without one gets the next id
 func (r *MessagesRepo) SetMessage(ctx context.Context, message model.MessageDB) (messageID int64, createdAt time.Time, err error) {
 	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
@@ -25,20 +45,136 @@ func (r *MessagesRepo) SetMessage(ctx context.Context, message model.MessageDB)
 	defer tx.Rollback()
 
 	query := `
-		INSERT INTO messages (sender_id, content, status, type)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO messages (message_id, sender_id, content, status, type, reply_to_message_id, thread_id, expires_at, entities)
+		OVERRIDING SYSTEM VALUE
+		VALUES (COALESCE(NULLIF($1::BIGINT, 0), nextval(pg_get_serial_sequence('messages', 'message_id'))), $2, $3, $4, $5, $6, $7, $8, $9)
 		RETURNING message_id, created_at
 	`
 
-	err = tx.QueryRowContext(ctx, query, message.SenderID, message.Content, message.Status, message.Type).
+	err = tx.QueryRowContext(ctx, query, message.MessageID, message.SenderID, message.Content, message.Status, message.Type, message.ReplyToMessageID, message.ThreadID, message.ExpiresAt, message.EncryptedEntities).
 		Scan(&messageID, &createdAt)
 	if err != nil {
 		return 0, time.Time{}, err
 	}
 
+	// the summary of the thread goes with the reply, deleteMessages recomputes it
+	if message.ThreadID != nil {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO message_threads (message_id, reply_count, last_reply_at)
+			VALUES ($1, 1, $2)
+			ON CONFLICT (message_id) DO UPDATE
+			SET reply_count = message_threads.reply_count + 1,
+				last_reply_at = GREATEST(message_threads.last_reply_at, EXCLUDED.last_reply_at)
+		`, *message.ThreadID, createdAt)
+		if err != nil {
+			return 0, time.Time{}, err
+		}
+	}
+
 	return messageID, createdAt, tx.Commit()
 }
 
+// SetForwardedMessages stores the copies of the messages in the chat in one transaction under their reserved ids
+// and binds the already stored files, media and locations of each original message to its copy, the copy forwarded
+// into a channel gets its post. The poll and the link preview of the copy come re-encrypted along with it.
+// Returns the forwards with the ids and times of the stored copies.
+func (r *MessagesRepo) SetForwardedMessages(ctx context.Context, chatID int64, forwards []model.ForwardedMessage) ([]model.ForwardedMessage, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO messages (message_id, sender_id, content, status, type, forwarded_from_sender_id, forwarded_from_chat_id, expires_at, entities)
+		OVERRIDING SYSTEM VALUE
+		VALUES (COALESCE(NULLIF($1::BIGINT, 0), nextval(pg_get_serial_sequence('messages', 'message_id'))), $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING message_id, created_at
+	`
+
+	bindQueries := []string{
+		`INSERT INTO messages_files (message_id, file_id) SELECT $1, file_id FROM messages_files WHERE message_id = $2`,
+		`INSERT INTO messages_media (message_id, media_id) SELECT $1, media_id FROM messages_media WHERE message_id = $2`,
+		`INSERT INTO messages_locations (message_id, location_id) SELECT $1, location_id FROM messages_locations WHERE message_id = $2`,
+	}
+
+	stored := make([]model.ForwardedMessage, 0, len(forwards))
+	for _, forward := range forwards {
+		message := &forward.Message
+		err = tx.QueryRowContext(ctx, query, message.MessageID, message.SenderID, message.Content, message.Status, message.Type, message.ForwardedFromSenderID, message.ForwardedFromChatID, message.ExpiresAt, message.EncryptedEntities).
+			Scan(&message.MessageID, &message.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		message.UpdatedAt = message.CreatedAt
+
+		for _, bindQuery := range bindQueries {
+			if _, err = tx.ExecContext(ctx, bindQuery, message.MessageID, forward.OriginalMessageID); err != nil {
+				return nil, err
+			}
+		}
+
+		if forward.Poll != nil {
+			poll := *forward.Poll
+			poll.MessageID = message.MessageID
+			_, err = tx.ExecContext(ctx, `
+				INSERT INTO polls (message_id, question, multiple_choice, anonymous, quiz, correct_option, close_at, closed_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			`, poll.MessageID, poll.Question, poll.MultipleChoice, poll.Anonymous, poll.Quiz, poll.CorrectOption, poll.CloseAt, poll.ClosedAt)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, option := range poll.Options {
+				_, err = tx.ExecContext(ctx, `
+					INSERT INTO poll_options (message_id, option_id, text)
+					VALUES ($1, $2, $3)
+				`, poll.MessageID, option.OptionID, option.Text)
+				if err != nil {
+					return nil, err
+				}
+			}
+			forward.Poll = &poll
+		}
+
+		if forward.LinkPreview != nil {
+			_, err = tx.ExecContext(ctx, `
+				INSERT INTO message_link_previews (message_id, preview)
+				VALUES ($1, $2)
+			`, message.MessageID, *forward.LinkPreview)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO chat_messages (chat_id, message_id)
+			VALUES ($1, $2)
+		`, chatID, message.MessageID)
+		if err != nil {
+			return nil, err
+		}
+
+		if forward.Post != nil {
+			post := *forward.Post
+			post.MessageID = message.MessageID
+			_, err = tx.ExecContext(ctx, `
+				INSERT INTO channel_posts (message_id, chat_id, signature)
+				VALUES ($1, $2, $3)
+			`, post.MessageID, post.ChatID, post.Signature)
+			if err != nil {
+				return nil, err
+			}
+			forward.Post = &post
+		}
+
+		stored = append(stored, forward)
+	}
+
+	return stored, tx.Commit()
+}
+
+// SetAction keeps the latest action of the user on the message
 func (r *MessagesRepo) SetAction(ctx context.Context, messageAction model.MessageAction) error {
 	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
@@ -47,8 +183,10 @@ func (r *MessagesRepo) SetAction(ctx context.Context, messageAction model.Messag
 	defer tx.Rollback()
 
 	query := `
-		INSERT INTO message_audio_log (message_id, user_id, action_type)
+		INSERT INTO message_audit_log (message_id, user_id, action_type)
 		VALUES ($1, $2, $3)
+		ON CONFLICT (message_id, user_id) DO UPDATE
+		SET action_type = EXCLUDED.action_type, action_timestamp = CURRENT_TIMESTAMP
 	`
 
 	_, err = tx.ExecContext(ctx, query, messageAction.MessageID, messageAction.UserID, messageAction.Type)
@@ -63,13 +201,13 @@ func (r *MessagesRepo) GetMessageByMessageID(ctx context.Context, messageID int6
 	var message model.MessageDB
 
 	query := `
-		SELECT message_id, sender_id, content, status, type, created_at, updated_at
+		SELECT message_id, sender_id, content, status, type, created_at, updated_at, reply_to_message_id, thread_id, forwarded_from_sender_id, forwarded_from_chat_id, expires_at, entities, imported_sender_name
 		FROM messages
 		WHERE message_id = $1
 	`
 
 	err := r.db.QueryRowContext(ctx, query, messageID).
-		Scan(&message.MessageID, &message.SenderID, &message.Content, &message.Status, &message.Type, &message.CreatedAt)
+		Scan(&message.MessageID, &message.SenderID, &message.Content, &message.Status, &message.Type, &message.CreatedAt, &message.UpdatedAt, &message.ReplyToMessageID, &message.ThreadID, &message.ForwardedFromSenderID, &message.ForwardedFromChatID, &message.ExpiresAt, &message.EncryptedEntities, &message.ImportedSenderName)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return message, nil
@@ -84,7 +222,7 @@ func (r *MessagesRepo) GetMessagesByChatID(ctx context.Context, chatID int64) ([
 	var messages []model.MessageDB
 
 	query := `
-		SELECT m.message_id, m.sender_id, m.content, m.status, m.type, m.created_at, m.updated_at
+		SELECT m.message_id, m.sender_id, m.content, m.status, m.type, m.created_at, m.updated_at, m.reply_to_message_id, m.thread_id, m.forwarded_from_sender_id, m.forwarded_from_chat_id, m.expires_at, m.entities, m.imported_sender_name
 		FROM messages m
 		JOIN chat_messages cm ON m.message_id = cm.message_id
 		WHERE cm.chat_id = $1
@@ -103,10 +241,10 @@ func (r *MessagesRepo) GetMessagesByChatIDWithLimit(ctx context.Context, chatID
 	var messages []model.MessageDB
 
 	query := `
-		SELECT m.message_id, m.sender_id, m.content, m.status, m.type, m.created_at, m.updated_at
+		SELECT m.message_id, m.sender_id, m.content, m.status, m.type, m.created_at, m.updated_at, m.reply_to_message_id, m.thread_id, m.forwarded_from_sender_id, m.forwarded_from_chat_id, m.expires_at, m.entities, m.imported_sender_name
 		FROM messages m
 		JOIN chat_messages cm ON m.message_id = cm.message_id
-		WHERE cm.chat_id = $1
+		WHERE cm.chat_id = $1 AND m.thread_id IS NULL AND (m.expires_at IS NULL OR m.expires_at > CURRENT_TIMESTAMP)
 		ORDER BY m.created_at DESC
 		LIMIT $2
 	`
@@ -119,6 +257,119 @@ func (r *MessagesRepo) GetMessagesByChatIDWithLimit(ctx context.Context, chatID
 	return messages, nil
 }
 
+// GetMessagesByChatIDBefore pages back through the chat history starting
+// right before beforeMessageID; zero starts from the latest message.
+func (r *MessagesRepo) GetMessagesByChatIDBefore(ctx context.Context, chatID, beforeMessageID int64, limit int) ([]model.MessageDB, error) {
+	var messages []model.MessageDB
+
+	query := `
+		SELECT m.message_id, m.sender_id, m.content, m.status, m.type, m.created_at, m.updated_at, m.reply_to_message_id, m.thread_id, m.forwarded_from_sender_id, m.forwarded_from_chat_id, m.expires_at, m.entities, m.imported_sender_name
+		FROM messages m
+		JOIN chat_messages cm ON m.message_id = cm.message_id
+		WHERE cm.chat_id = $1 AND m.thread_id IS NULL AND (m.expires_at IS NULL OR m.expires_at > CURRENT_TIMESTAMP) AND ($2 = 0 OR m.message_id < $2)
+		ORDER BY m.message_id DESC
+		LIMIT $3
+	`
+
+	err := r.db.SelectContext(ctx, &messages, query, chatID, beforeMessageID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+// GetMessagesByChatIDAfter pages forward through all messages of the chat, the replies
+// in the threads included, starting right after afterMessageID
+func (r *MessagesRepo) GetMessagesByChatIDAfter(ctx context.Context, chatID, afterMessageID int64, limit int) ([]model.MessageDB, error) {
+	var messages []model.MessageDB
+
+	query := `
+		SELECT m.message_id, m.sender_id, m.content, m.status, m.type, m.created_at, m.updated_at, m.reply_to_message_id, m.thread_id, m.forwarded_from_sender_id, m.forwarded_from_chat_id, m.expires_at, m.entities, m.imported_sender_name
+		FROM messages m
+		JOIN chat_messages cm ON m.message_id = cm.message_id
+		WHERE cm.chat_id = $1 AND (m.expires_at IS NULL OR m.expires_at > CURRENT_TIMESTAMP) AND m.message_id > $2
+		ORDER BY m.message_id ASC
+		LIMIT $3
+	`
+
+	err := r.db.SelectContext(ctx, &messages, query, chatID, afterMessageID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+// GetMessagesByIDs returns the messages in no particular order, the missing ones are skipped
+func (r *MessagesRepo) GetMessagesByIDs(ctx context.Context, messageIDs []int64) ([]model.MessageDB, error) {
+	var messages []model.MessageDB
+
+	query := `
+		SELECT m.message_id, m.sender_id, m.content, m.status, m.type, m.created_at, m.updated_at, m.reply_to_message_id, m.thread_id, m.forwarded_from_sender_id, m.forwarded_from_chat_id, m.expires_at, m.entities, m.imported_sender_name
+		FROM messages m
+		WHERE m.message_id = ANY($1)
+	`
+
+	err := r.db.SelectContext(ctx, &messages, query, pq.Array(messageIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+// GetThreadMessages pages back through the replies of the thread
+// starting right before beforeMessageID; zero starts from the latest reply.
+func (r *MessagesRepo) GetThreadMessages(ctx context.Context, threadID, beforeMessageID int64, limit int) ([]model.MessageDB, error) {
+	var messages []model.MessageDB
+
+	query := `
+		SELECT message_id, sender_id, content, status, type, created_at, updated_at, reply_to_message_id, thread_id, forwarded_from_sender_id, forwarded_from_chat_id, expires_at, entities, imported_sender_name
+		FROM messages
+		WHERE thread_id = $1 AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP) AND ($2 = 0 OR message_id < $2)
+		ORDER BY message_id DESC
+		LIMIT $3
+	`
+
+	err := r.db.SelectContext(ctx, &messages, query, threadID, beforeMessageID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+func (r *MessagesRepo) GetThread(ctx context.Context, messageID int64) (model.MessageThread, error) {
+	var thread model.MessageThread
+
+	query := `
+		SELECT message_id, reply_count, last_reply_at
+		FROM message_threads
+		WHERE message_id = $1
+	`
+
+	err := r.db.GetContext(ctx, &thread, query, messageID)
+	return thread, err
+}
+
+func (r *MessagesRepo) IsMessageInChat(ctx context.Context, messageID, chatID int64) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM chat_messages
+			WHERE message_id = $1 AND chat_id = $2
+		)
+	`
+
+	var exists bool
+	err := r.db.GetContext(ctx, &exists, query, messageID, chatID)
+	if err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
 func (r *MessagesRepo) GetAllActions(ctx context.Context, messageID int64) ([]model.MessageAction, error) {
 	var actions []model.MessageAction
 
@@ -155,6 +406,83 @@ func (r *MessagesRepo) DeleteMessage(ctx context.Context, messageID int64) error
 	return tx.Commit()
 }
 
+// GetContentToReencrypt pages through the messages after afterMessageID whose content isn't encrypted
+// by the active data key of the chat bound to the message: legacy, unbound, rotated or forwarded from another chat before.
+func (r *MessagesRepo) GetContentToReencrypt(ctx context.Context, afterMessageID int64, limit int) ([]model.StoredContent, error) {
+	var contents []model.StoredContent
+
+	query := `
+		SELECT m.message_id, cm.chat_id, m.content
+		FROM messages m
+		JOIN chat_messages cm ON m.message_id = cm.message_id
+		LEFT JOIN chat_data_keys k ON k.chat_id = cm.chat_id AND k.active
+		WHERE m.message_id > $1 AND m.content IS NOT NULL
+			AND (k.key_id IS NULL OR m.content NOT LIKE $2 || k.key_id || ':%')
+		ORDER BY m.message_id
+		LIMIT $3
+	`
+
+	err := r.db.SelectContext(ctx, &contents, query, afterMessageID, model.ENVELOPE_CIPHER_PREFIX+":", limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return contents, nil
+}
+
+// UpdateMessageContent replaces the content only if it's still the same,
+// so an edit made meanwhile is never overwritten.
+func (r *MessagesRepo) UpdateMessageContent(ctx context.Context, messageID int64, previous, content string) (bool, error) {
+	query := `
+		UPDATE messages
+		SET content = $1
+		WHERE message_id = $2 AND content = $3
+	`
+
+	result, err := r.db.ExecContext(ctx, query, content, messageID, previous)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// UpdateMessage replaces the content and entities of the message together with its mentions
+func (r *MessagesRepo) UpdateMessage(ctx context.Context, messageID int64, content, entities *string, mentions []model.Mention) (updatedAt time.Time, err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE messages
+		SET content = $1, entities = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE message_id = $3
+		RETURNING updated_at
+	`
+
+	if err = tx.QueryRowContext(ctx, query, content, entities, messageID).Scan(&updatedAt); err != nil {
+		return time.Time{}, err
+	}
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM message_mentions WHERE message_id = $1`, messageID); err != nil {
+		return time.Time{}, err
+	}
+	for _, mention := range mentions {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO message_mentions (message_id, mention_offset, mention_length, user_id)
+			VALUES ($1, $2, $3, $4)
+		`, messageID, mention.Offset, mention.Length, mention.UserID)
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	return updatedAt, tx.Commit()
+}
+
 func (r *MessagesRepo) SetBindMessageMedia(ctx context.Context, messageID, mediaID int64) error {
 	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
@@ -234,3 +562,193 @@ func (r *MessagesRepo) SetBindMessageChat(ctx context.Context, messageID, chatID
 
 	return tx.Commit()
 }
+
+// DeleteExpiredMessages removes up to limit expired messages together with the replies of their threads
+// and the files, media and locations which are no longer bound to any message. Rows locked by another
+// replica are skipped, the held messages wait for the release of their legal hold.
+func (r *MessagesRepo) DeleteExpiredMessages(ctx context.Context, limit int) (deleted []model.DeletedMessage, blobSHA256s []string, err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	var expired []model.DeletedMessage
+
+	query := `
+		SELECT m.message_id, cm.chat_id
+		FROM messages m
+		JOIN chat_messages cm ON m.message_id = cm.message_id
+		WHERE m.expires_at <= CURRENT_TIMESTAMP
+		  AND ` + notHeldCondition + `
+		ORDER BY m.expires_at ASC
+		LIMIT $1
+		FOR UPDATE OF m SKIP LOCKED
+	`
+
+	if err := tx.SelectContext(ctx, &expired, query, limit); err != nil {
+		return nil, nil, err
+	}
+	if len(expired) == 0 {
+		return nil, nil, nil
+	}
+
+	deleted, blobSHA256s, err = deleteMessages(ctx, tx, expired)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return deleted, blobSHA256s, tx.Commit()
+}
+
+// DeleteChatMessage removes the message of the chat along with its thread, returns sql.ErrNoRows if the chat has no such message
+func (r *MessagesRepo) DeleteChatMessage(ctx context.Context, chatID, messageID int64) (deleted []model.DeletedMessage, blobSHA256s []string, err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	var message model.DeletedMessage
+
+	query := `
+		SELECT m.message_id, cm.chat_id
+		FROM messages m
+		JOIN chat_messages cm ON m.message_id = cm.message_id
+		WHERE m.message_id = $1 AND cm.chat_id = $2
+		FOR UPDATE OF m
+	`
+
+	if err := tx.GetContext(ctx, &message, query, messageID, chatID); err != nil {
+		return nil, nil, err
+	}
+
+	deleted, blobSHA256s, err = deleteMessages(ctx, tx, []model.DeletedMessage{message})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return deleted, blobSHA256s, tx.Commit()
+}
+
+// deleteMessages removes the messages with the replies to them and the attachments nothing else refers to,
+// returns every removed message and the blobs of the removed attachments to pass to deleteOrphanBlobs
+func deleteMessages(ctx context.Context, tx *sqlx.Tx, messages []model.DeletedMessage) ([]model.DeletedMessage, []string, error) {
+	roots := make([]int64, 0, len(messages))
+	for _, message := range messages {
+		roots = append(roots, message.MessageID)
+	}
+
+	// replies are removed by the cascade of the thread root, they're collected to notify the participants
+	var replies []model.DeletedMessage
+	err := tx.SelectContext(ctx, &replies, `
+		SELECT m.message_id, cm.chat_id
+		FROM messages m
+		JOIN chat_messages cm ON m.message_id = cm.message_id
+		WHERE m.thread_id = ANY($1) AND NOT m.message_id = ANY($1)
+	`, pq.Array(roots))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	deleted := append(messages, replies...)
+	messageIDs := make([]int64, 0, len(deleted))
+	for _, message := range deleted {
+		messageIDs = append(messageIDs, message.MessageID)
+	}
+
+	var fileIDs, mediaIDs, locationIDs []int64
+	attachments := []struct {
+		ids   *[]int64
+		query string
+	}{
+		{&fileIDs, `SELECT file_id FROM messages_files WHERE message_id = ANY($1)`},
+		{&mediaIDs, `SELECT media_id FROM messages_media WHERE message_id = ANY($1)`},
+		{&locationIDs, `SELECT location_id FROM messages_locations WHERE message_id = ANY($1)`},
+	}
+	for _, attachment := range attachments {
+		if err := tx.SelectContext(ctx, attachment.ids, attachment.query, pq.Array(messageIDs)); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// the blobs are collected before the attachments go, the ones still shared stay in deleteOrphanBlobs
+	var blobSHA256s []string
+	err = tx.SelectContext(ctx, &blobSHA256s, `
+		SELECT sha256 FROM files WHERE file_id = ANY($1) AND sha256 IS NOT NULL
+		UNION
+		SELECT sha256 FROM media WHERE media_id = ANY($2) AND sha256 IS NOT NULL
+		UNION
+		SELECT thumbnail_sha256 FROM media WHERE media_id = ANY($2) AND thumbnail_sha256 IS NOT NULL
+	`, pq.Array(fileIDs), pq.Array(mediaIDs))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the threads which lose replies without losing their root
+	var threadIDs []int64
+	err = tx.SelectContext(ctx, &threadIDs, `
+		SELECT DISTINCT thread_id
+		FROM messages
+		WHERE message_id = ANY($1) AND thread_id IS NOT NULL AND NOT thread_id = ANY($1)
+	`, pq.Array(messageIDs))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM messages WHERE message_id = ANY($1)`, pq.Array(messageIDs)); err != nil {
+		return nil, nil, err
+	}
+
+	if len(threadIDs) > 0 {
+		if err := recountThreads(ctx, tx, threadIDs); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// forwarded copies share attachments with the original, only orphaned rows are removed
+	orphans := []struct {
+		ids   []int64
+		query string
+	}{
+		{fileIDs, `DELETE FROM files f WHERE f.file_id = ANY($1) AND NOT EXISTS (SELECT 1 FROM messages_files mf WHERE mf.file_id = f.file_id)`},
+		{mediaIDs, `DELETE FROM media md WHERE md.media_id = ANY($1) AND NOT EXISTS (SELECT 1 FROM messages_media mm WHERE mm.media_id = md.media_id)`},
+		{locationIDs, `DELETE FROM locations l WHERE l.location_id = ANY($1) AND NOT EXISTS (SELECT 1 FROM messages_locations ml WHERE ml.location_id = l.location_id)`},
+	}
+	for _, orphan := range orphans {
+		if len(orphan.ids) == 0 {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, orphan.query, pq.Array(orphan.ids)); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return deleted, blobSHA256s, nil
+}
+
+// recountThreads recomputes the summaries of the threads from their remaining replies,
+// the thread left without replies loses its summary
+func recountThreads(ctx context.Context, tx *sqlx.Tx, threadIDs []int64) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE message_threads t
+		SET reply_count = s.reply_count, last_reply_at = s.last_reply_at
+		FROM (
+			SELECT thread_id, COUNT(*) AS reply_count, MAX(created_at) AS last_reply_at
+			FROM messages
+			WHERE thread_id = ANY($1)
+			GROUP BY thread_id
+		) s
+		WHERE t.message_id = s.thread_id
+	`, pq.Array(threadIDs))
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM message_threads t
+		WHERE t.message_id = ANY($1)
+		  AND NOT EXISTS (SELECT 1 FROM messages r WHERE r.thread_id = t.message_id)
+	`, pq.Array(threadIDs))
+	return err
+}
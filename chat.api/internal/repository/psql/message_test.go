@@ -0,0 +1,172 @@
+package repo
+
+import (
+	"chat-api/internal/model"
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func TestGetMessageByMessageID(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	messages := NewMessagesRepo(db)
+
+	content, entities := "hello", "entities"
+	messageID, createdAt, err := messages.SetMessage(ctx, model.MessageDB{
+		SenderID:          "user-1",
+		Content:           &content,
+		Status:            model.MESSAGE_SENT,
+		Type:              model.MESSAGE_TEXT,
+		EncryptedEntities: &entities,
+	})
+	if err != nil {
+		t.Fatalf("SetMessage: %v", err)
+	}
+
+	message, err := messages.GetMessageByMessageID(ctx, messageID)
+	if err != nil {
+		t.Fatalf("GetMessageByMessageID: %v", err)
+	}
+	if message.MessageID != messageID || message.SenderID != "user-1" || !message.CreatedAt.Equal(createdAt) {
+		t.Errorf("GetMessageByMessageID = %+v, want message %d of user-1 created at %v", message, messageID, createdAt)
+	}
+	if message.Content == nil || *message.Content != content || message.EncryptedEntities == nil || *message.EncryptedEntities != entities {
+		t.Errorf("GetMessageByMessageID content = %v, entities = %v", message.Content, message.EncryptedEntities)
+	}
+	if message.ImportedSenderName != nil {
+		t.Errorf("ImportedSenderName = %q, want nil", *message.ImportedSenderName)
+	}
+
+	if _, err := db.ExecContext(ctx, `UPDATE messages SET imported_sender_name = 'Alice' WHERE message_id = $1`, messageID); err != nil {
+		t.Fatalf("set imported_sender_name: %v", err)
+	}
+	message, err = messages.GetMessageByMessageID(ctx, messageID)
+	if err != nil {
+		t.Fatalf("GetMessageByMessageID: %v", err)
+	}
+	if message.ImportedSenderName == nil || *message.ImportedSenderName != "Alice" {
+		t.Errorf("ImportedSenderName = %v, want %q", message.ImportedSenderName, "Alice")
+	}
+}
+
+// setFileMessage stores a message of the chat with the file of the blob, the message expires at expiresAt if set
+func setFileMessage(t *testing.T, db *sqlx.DB, chatID int64, sha256 string, expiresAt *time.Time) int64 {
+	t.Helper()
+	ctx := context.Background()
+
+	uploads := NewUploadsRepo(db)
+	if err := uploads.SetBlob(ctx, model.Blob{SHA256: sha256, StorageKey: model.BlobStorageKey(sha256), Type: "image/png", Size: 1}); err != nil {
+		t.Fatalf("SetBlob: %v", err)
+	}
+	fileID, err := NewFilesRepo(db).SetUploadedFile(ctx, model.File{URL: model.BlobStorageKey(sha256), Type: "image/png", Size: 1}, sha256, "user-1")
+	if err != nil {
+		t.Fatalf("SetUploadedFile: %v", err)
+	}
+
+	messages := NewMessagesRepo(db)
+	messageID, _, err := messages.SetMessage(ctx, model.MessageDB{SenderID: "user-1", Status: model.MESSAGE_SENT, Type: model.MESSAGE_FILE, ExpiresAt: expiresAt})
+	if err != nil {
+		t.Fatalf("SetMessage: %v", err)
+	}
+	if err := messages.SetBindMessageChat(ctx, messageID, chatID); err != nil {
+		t.Fatalf("SetBindMessageChat: %v", err)
+	}
+	if err := messages.SetBindMessageFile(ctx, messageID, fileID); err != nil {
+		t.Fatalf("SetBindMessageFile: %v", err)
+	}
+	return messageID
+}
+
+func setTestChat(t *testing.T, db *sqlx.DB) int64 {
+	t.Helper()
+
+	var chatID int64
+	if err := db.Get(&chatID, `INSERT INTO chats (creator_id, name, type) VALUES ('user-1', 'test', 'group') RETURNING chat_id`); err != nil {
+		t.Fatalf("insert chat: %v", err)
+	}
+	return chatID
+}
+
+func TestDeleteChatMessageOrphanBlobs(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	messages, uploads := NewMessagesRepo(db), NewUploadsRepo(db)
+	chatID := setTestChat(t, db)
+
+	own, shared := strings.Repeat("a", 64), strings.Repeat("b", 64)
+	messageID := setFileMessage(t, db, chatID, own, nil)
+	// the same bytes uploaded once more are attached to the other message
+	setFileMessage(t, db, chatID, shared, nil)
+	fileID, err := NewFilesRepo(db).SetUploadedFile(ctx, model.File{URL: model.BlobStorageKey(shared), Type: "image/png", Size: 1}, shared, "user-1")
+	if err != nil {
+		t.Fatalf("SetUploadedFile: %v", err)
+	}
+	if err := messages.SetBindMessageFile(ctx, messageID, fileID); err != nil {
+		t.Fatalf("SetBindMessageFile: %v", err)
+	}
+
+	_, blobSHA256s, err := messages.DeleteChatMessage(ctx, chatID, messageID)
+	if err != nil {
+		t.Fatalf("DeleteChatMessage: %v", err)
+	}
+	sort.Strings(blobSHA256s)
+	if !reflect.DeepEqual(blobSHA256s, []string{own, shared}) {
+		t.Errorf("DeleteChatMessage blobs = %v, want %v", blobSHA256s, []string{own, shared})
+	}
+
+	blobs, err := uploads.DeleteOrphanBlobs(ctx, blobSHA256s)
+	if err != nil {
+		t.Fatalf("DeleteOrphanBlobs: %v", err)
+	}
+	if len(blobs) != 1 || blobs[0].SHA256 != own || blobs[0].StorageKey != model.BlobStorageKey(own) {
+		t.Errorf("DeleteOrphanBlobs = %+v, want the blob %s only", blobs, own)
+	}
+	if _, err := uploads.GetBlob(ctx, own); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetBlob of the deleted blob: error = %v, want %v", err, sql.ErrNoRows)
+	}
+	if _, err := uploads.GetBlob(ctx, shared); err != nil {
+		t.Errorf("GetBlob of the shared blob: %v", err)
+	}
+}
+
+func TestDeleteExpiredMessagesOrphanBlobs(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	messages, uploads := NewMessagesRepo(db), NewUploadsRepo(db)
+	chatID := setTestChat(t, db)
+
+	expired, kept := strings.Repeat("c", 64), strings.Repeat("d", 64)
+	expiresAt := time.Now().Add(-time.Minute)
+	expiredID := setFileMessage(t, db, chatID, expired, &expiresAt)
+	setFileMessage(t, db, chatID, kept, nil)
+
+	deleted, blobSHA256s, err := messages.DeleteExpiredMessages(ctx, 10)
+	if err != nil {
+		t.Fatalf("DeleteExpiredMessages: %v", err)
+	}
+	if len(deleted) != 1 || deleted[0].MessageID != expiredID {
+		t.Fatalf("DeleteExpiredMessages = %+v, want message %d", deleted, expiredID)
+	}
+
+	blobs, err := uploads.DeleteOrphanBlobs(ctx, blobSHA256s)
+	if err != nil {
+		t.Fatalf("DeleteOrphanBlobs: %v", err)
+	}
+	if len(blobs) != 1 || blobs[0].SHA256 != expired {
+		t.Errorf("DeleteOrphanBlobs = %+v, want the blob %s", blobs, expired)
+	}
+	if _, err := uploads.GetBlob(ctx, expired); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetBlob of the expired blob: error = %v, want %v", err, sql.ErrNoRows)
+	}
+	if _, err := uploads.GetBlob(ctx, kept); err != nil {
+		t.Errorf("GetBlob of the kept blob: %v", err)
+	}
+}
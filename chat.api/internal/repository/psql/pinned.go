@@ -26,6 +26,8 @@ func (r *PinnedRepo) SetPinnedMessage(ctx context.Context, pinMessage model.Pinn
 	query := `
 		INSERT INTO pinned_messages (chat_id, message_id, pinned_by_user_id, priority)
 		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (chat_id, message_id) DO UPDATE
+		SET pinned_by_user_id = EXCLUDED.pinned_by_user_id, priority = EXCLUDED.priority
 	`
 	_, err = tx.ExecContext(ctx, query,
 		pinMessage.ChatID,
@@ -0,0 +1,195 @@
+package repo
+
+import (
+	"chat-api/internal/model"
+	"context"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type PollsRepo struct {
+	db *sqlx.DB
+}
+
+func NewPollsRepo(db *sqlx.DB) *PollsRepo {
+	return &PollsRepo{db: db}
+}
+
+func (r *PollsRepo) SetPoll(ctx context.Context, poll model.Poll) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO polls (message_id, question, multiple_choice, anonymous, quiz, correct_option, close_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	_, err = tx.ExecContext(ctx, query, poll.MessageID, poll.Question, poll.MultipleChoice, poll.Anonymous, poll.Quiz, poll.CorrectOption, poll.CloseAt)
+	if err != nil {
+		return err
+	}
+
+	for _, option := range poll.Options {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO poll_options (message_id, option_id, text)
+			VALUES ($1, $2, $3)
+		`, poll.MessageID, option.OptionID, option.Text)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *PollsRepo) GetPoll(ctx context.Context, messageID int64) (model.Poll, error) {
+	return getPoll(ctx, r.db, messageID, "")
+}
+
+// getPoll reads the poll with its options, lock is appended to the query of the poll row
+func getPoll(ctx context.Context, q sqlx.QueryerContext, messageID int64, lock string) (model.Poll, error) {
+	var poll model.Poll
+
+	query := `
+		SELECT message_id, question, multiple_choice, anonymous, quiz, correct_option, close_at, closed_at
+		FROM polls
+		WHERE message_id = $1
+	` + lock
+
+	if err := sqlx.GetContext(ctx, q, &poll, query, messageID); err != nil {
+		return poll, err
+	}
+
+	query = `
+		SELECT option_id, text
+		FROM poll_options
+		WHERE message_id = $1
+		ORDER BY option_id ASC
+	`
+
+	if err := sqlx.SelectContext(ctx, q, &poll.Options, query, messageID); err != nil {
+		return poll, err
+	}
+
+	return poll, nil
+}
+
+func (r *PollsRepo) GetPollVotes(ctx context.Context, messageID int64) ([]model.PollVote, error) {
+	var votes []model.PollVote
+
+	query := `
+		SELECT message_id, option_id, user_id, voted_at
+		FROM poll_votes
+		WHERE message_id = $1
+		ORDER BY voted_at ASC, option_id ASC
+	`
+
+	if err := r.db.SelectContext(ctx, &votes, query, messageID); err != nil {
+		return nil, err
+	}
+
+	return votes, nil
+}
+
+// ReplacePollVotes replaces the choice of the user by optionIDs, empty optionIDs retracts the vote.
+// The poll row is locked while check decides on the poll and the previous choice, so the votes
+// can't be changed after the poll is closed. Returns false if the choice stays the same.
+func (r *PollsRepo) ReplacePollVotes(ctx context.Context, messageID int64, userID string, optionIDs []int, check func(poll model.Poll, chosen []int) error) (bool, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	poll, err := getPoll(ctx, tx, messageID, "FOR UPDATE")
+	if err != nil {
+		return false, err
+	}
+
+	var chosen []int
+	err = tx.SelectContext(ctx, &chosen, `
+		SELECT option_id
+		FROM poll_votes
+		WHERE message_id = $1 AND user_id = $2
+		ORDER BY option_id ASC
+	`, messageID, userID)
+	if err != nil {
+		return false, err
+	}
+
+	if err := check(poll, chosen); err != nil {
+		return false, err
+	}
+	if isSameChoice(chosen, optionIDs) {
+		return false, nil
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM poll_votes WHERE message_id = $1 AND user_id = $2`, messageID, userID)
+	if err != nil {
+		return false, err
+	}
+
+	if len(optionIDs) > 0 {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO poll_votes (message_id, option_id, user_id)
+			SELECT $1, UNNEST($2::SMALLINT[]), $3
+		`, messageID, pq.Array(optionIDs), userID)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return true, tx.Commit()
+}
+
+func isSameChoice(chosen, optionIDs []int) bool {
+	if len(chosen) != len(optionIDs) {
+		return false
+	}
+
+	set := make(map[int]bool, len(chosen))
+	for _, optionID := range chosen {
+		set[optionID] = true
+	}
+	for _, optionID := range optionIDs {
+		if !set[optionID] {
+			return false
+		}
+	}
+	return true
+}
+
+// CloseDuePolls closes up to limit polls whose close time has come, rows locked by another replica are skipped
+func (r *PollsRepo) CloseDuePolls(ctx context.Context, limit int) ([]model.ClosedPoll, error) {
+	var closed []model.ClosedPoll
+
+	query := `
+		WITH due AS (
+			SELECT message_id
+			FROM polls
+			WHERE closed_at IS NULL AND close_at <= CURRENT_TIMESTAMP
+			ORDER BY close_at ASC
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		), closed AS (
+			UPDATE polls p
+			SET closed_at = CURRENT_TIMESTAMP
+			FROM due
+			WHERE p.message_id = due.message_id
+			RETURNING p.message_id
+		)
+		SELECT closed.message_id, cm.chat_id
+		FROM closed
+		JOIN chat_messages cm ON closed.message_id = cm.message_id
+	`
+
+	if err := r.db.SelectContext(ctx, &closed, query, limit); err != nil {
+		return nil, err
+	}
+
+	return closed, nil
+}
@@ -0,0 +1,75 @@
+package repo
+
+import (
+	"chat-api/internal/model"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type PresenceRepo struct {
+	db *sqlx.DB
+}
+
+func NewPresenceRepo(db *sqlx.DB) *PresenceRepo {
+	return &PresenceRepo{db: db}
+}
+
+func (r *PresenceRepo) SetLastSeen(ctx context.Context, userID string, lastSeenAt time.Time) error {
+	query := `
+		INSERT INTO user_presence (user_id, last_seen_at)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET last_seen_at = GREATEST(user_presence.last_seen_at, EXCLUDED.last_seen_at)
+	`
+
+	_, err := r.db.ExecContext(ctx, query, userID, lastSeenAt)
+	return err
+}
+
+func (r *PresenceRepo) SetHideLastSeen(ctx context.Context, userID string, hide bool) error {
+	query := `
+		INSERT INTO user_presence (user_id, hide_last_seen)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET hide_last_seen = EXCLUDED.hide_last_seen
+	`
+
+	_, err := r.db.ExecContext(ctx, query, userID, hide)
+	return err
+}
+
+// GetUserPresence returns the defaults for the user who has never been online
+func (r *PresenceRepo) GetUserPresence(ctx context.Context, userID string) (model.UserPresence, error) {
+	presence := model.UserPresence{UserID: userID}
+
+	query := `
+		SELECT user_id, last_seen_at, hide_last_seen
+		FROM user_presence
+		WHERE user_id = $1
+	`
+
+	err := r.db.GetContext(ctx, &presence, query, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return presence, nil
+	}
+	return presence, err
+}
+
+// GetUsersPresence skips the users who have never been online
+func (r *PresenceRepo) GetUsersPresence(ctx context.Context, usersIDs []string) ([]model.UserPresence, error) {
+	var presences []model.UserPresence
+
+	query := `
+		SELECT user_id, last_seen_at, hide_last_seen
+		FROM user_presence
+		WHERE user_id = ANY($1)
+	`
+
+	err := r.db.SelectContext(ctx, &presences, query, pq.Array(usersIDs))
+	return presences, err
+}
@@ -0,0 +1,47 @@
+package repo
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// newTestDB connects to the database of TEST_POSTGRES_DSN and creates the schema of init.sql
+// in a schema of its own, dropped after the test. The test is skipped without the database
+func newTestDB(t *testing.T) *sqlx.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN is not set")
+	}
+
+	db, err := sqlx.Connect("postgres", dsn)
+	if err != nil {
+		t.Fatalf("sqlx.Connect: %v", err)
+	}
+	// the search path is set on the connection, so the test keeps the only one
+	db.SetMaxOpenConns(1)
+
+	schema := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	t.Cleanup(func() {
+		db.Exec(`DROP SCHEMA IF EXISTS ` + schema + ` CASCADE`)
+		db.Close()
+	})
+
+	initSQL, err := os.ReadFile("../../../pkg/db/psql/init.sql")
+	if err != nil {
+		t.Fatalf("os.ReadFile: %v", err)
+	}
+	if _, err := db.Exec(`CREATE SCHEMA ` + schema + `; SET search_path TO ` + schema); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	if _, err := db.Exec(string(initSQL)); err != nil {
+		t.Fatalf("init.sql: %v", err)
+	}
+
+	return db
+}
@@ -0,0 +1,50 @@
+package repo
+
+import (
+	"chat-api/internal/model"
+	"context"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type ReactionsRepo struct {
+	db *sqlx.DB
+}
+
+func NewReactionsRepo(db *sqlx.DB) *ReactionsRepo {
+	return &ReactionsRepo{db: db}
+}
+
+func (r *ReactionsRepo) SetReaction(ctx context.Context, reaction model.Reaction) error {
+	query := `
+		INSERT INTO message_reactions (message_id, user_id, emoji)
+		VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING
+	`
+	_, err := r.db.ExecContext(ctx, query, reaction.MessageID, reaction.UserID, reaction.Emoji)
+	return err
+}
+
+func (r *ReactionsRepo) GetReactionsByMessageID(ctx context.Context, messageID int64) ([]model.Reaction, error) {
+	var reactions []model.Reaction
+
+	query := `
+		SELECT message_id, user_id, emoji, reacted_at
+		FROM message_reactions
+		WHERE message_id = $1
+		ORDER BY reacted_at ASC
+	`
+
+	err := r.db.SelectContext(ctx, &reactions, query, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	return reactions, nil
+}
+
+func (r *ReactionsRepo) DeleteReaction(ctx context.Context, reaction model.Reaction) error {
+	query := `DELETE FROM message_reactions WHERE message_id = $1 AND user_id = $2 AND emoji = $3`
+	_, err := r.db.ExecContext(ctx, query, reaction.MessageID, reaction.UserID, reaction.Emoji)
+	return err
+}
@@ -15,6 +15,25 @@ type Repositories struct {
 	Files     Files
 	Messages  Messages
 	Chats     Chats
+	Reactions Reactions
+	Scheduled ScheduledMessages
+	Keys      Keys
+	DataKeys  DataKeys
+	Uploads   Uploads
+	Voice     Voice
+	Polls     Polls
+	Mentions  Mentions
+	Previews  LinkPreviews
+	Presence  Presence
+	Invites   Invites
+	Members   Members
+	Channels  Channels
+	ChatList  ChatList
+	Bookmarks Bookmarks
+	Search    Search
+	Exports   Exports
+	Imports   Imports
+	Retention Retention
 }
 
 func NewRepositories(db *sqlx.DB) *Repositories {
@@ -25,6 +44,25 @@ func NewRepositories(db *sqlx.DB) *Repositories {
 		Files:     NewFilesRepo(db),
 		Messages:  NewMessagesRepo(db),
 		Chats:     NewChatsRepo(db),
+		Reactions: NewReactionsRepo(db),
+		Scheduled: NewScheduledMessagesRepo(db),
+		Keys:      NewKeysRepo(db),
+		DataKeys:  NewDataKeysRepo(db),
+		Uploads:   NewUploadsRepo(db),
+		Voice:     NewVoiceRepo(db),
+		Polls:     NewPollsRepo(db),
+		Mentions:  NewMentionsRepo(db),
+		Previews:  NewLinkPreviewsRepo(db),
+		Presence:  NewPresenceRepo(db),
+		Invites:   NewInvitesRepo(db),
+		Members:   NewMembersRepo(db),
+		Channels:  NewChannelsRepo(db),
+		ChatList:  NewChatListRepo(db),
+		Bookmarks: NewBookmarksRepo(db),
+		Search:    NewSearchRepo(db),
+		Exports:   NewExportsRepo(db),
+		Imports:   NewImportsRepo(db),
+		Retention: NewRetentionRepo(db),
 	}
 }
 
@@ -44,6 +82,13 @@ type Pinned interface {
 
 type Media interface {
 	SetMedia(ctx context.Context, media model.Media) (mediaID int64, err error)
+	SetUploadedMedia(ctx context.Context, media model.Media, sha256, userID string) (mediaID int64, err error)
+	GetUploadedMedia(ctx context.Context, mediaID int64, userID string) (model.Media, error)
+	GetMediaSource(ctx context.Context, mediaID int64) (model.MediaSource, error)
+	GetMediaLocations(ctx context.Context, mediaID int64) ([]model.MediaLocation, error)
+	UpdateProcessedMedia(ctx context.Context, media model.Media, sha256 string) error
+	RequeueStaleMedia(ctx context.Context, staleBefore time.Time, maxAttempts, limit int) ([]int64, error)
+	FailStaleMedia(ctx context.Context, staleBefore time.Time, maxAttempts, limit int) ([]int64, error)
 	GetMediaByMediaID(ctx context.Context, mediaID int64) (model.Media, error)
 	GetMediaFileByMessageID(ctx context.Context, messageID int64) ([]model.Media, error)
 	GetMediaByChatID(ctx context.Context, chatID int64) ([]model.Media, error)
@@ -60,6 +105,8 @@ type Locations interface {
 
 type Files interface {
 	SetFile(ctx context.Context, file model.File) (fileID int64, err error)
+	SetUploadedFile(ctx context.Context, file model.File, sha256, userID string) (fileID int64, err error)
+	GetUploadedFile(ctx context.Context, fileID int64, userID string) (model.File, error)
 	GetFileByFileID(ctx context.Context, fileID int64) (model.File, error)
 	GetFilesByMessageID(ctx context.Context, messageID int64) ([]model.File, error)
 	GetFilesByChatID(ctx context.Context, chatID int64) ([]model.File, error)
@@ -67,14 +114,27 @@ type Files interface {
 }
 
 type Messages interface {
+	ReserveMessageIDs(ctx context.Context, count int) ([]int64, error)
 	SetMessage(ctx context.Context, message model.MessageDB) (messageID int64, createdAt time.Time, err error)
+	SetForwardedMessages(ctx context.Context, chatID int64, forwards []model.ForwardedMessage) ([]model.ForwardedMessage, error)
 	SetAction(ctx context.Context, messageAction model.MessageAction) error
 	GetAllActions(ctx context.Context, messageID int64) ([]model.MessageAction, error)
 	GetMessageByMessageID(ctx context.Context, messageID int64) (model.MessageDB, error)
 	GetMessagesByChatID(ctx context.Context, chatID int64) ([]model.MessageDB, error)
 	GetMessagesByChatIDWithLimit(ctx context.Context, chatID int64, limit int) ([]model.MessageDB, error)
+	GetMessagesByChatIDBefore(ctx context.Context, chatID, beforeMessageID int64, limit int) ([]model.MessageDB, error)
+	GetMessagesByChatIDAfter(ctx context.Context, chatID, afterMessageID int64, limit int) ([]model.MessageDB, error)
+	GetMessagesByIDs(ctx context.Context, messageIDs []int64) ([]model.MessageDB, error)
+	IsMessageInChat(ctx context.Context, messageID, chatID int64) (bool, error)
+	GetThreadMessages(ctx context.Context, threadID, beforeMessageID int64, limit int) ([]model.MessageDB, error)
+	GetThread(ctx context.Context, messageID int64) (model.MessageThread, error)
 	// UpdateChat(ctx context.Context, chat model.Chat, chatID int64) error // НУЖНО ПОДУМАТЬ НУЖНО ЛИ ВОЗРАЩАТЬ ЕЩЁ ЧТО-ТО + ДОЛЖНА БЫТЬ ЛОГИКА ДЕЙСТВИЙ
 	DeleteMessage(ctx context.Context, messageID int64) error
+	DeleteExpiredMessages(ctx context.Context, limit int) (deleted []model.DeletedMessage, blobSHA256s []string, err error)
+	DeleteChatMessage(ctx context.Context, chatID, messageID int64) (deleted []model.DeletedMessage, blobSHA256s []string, err error)
+	GetContentToReencrypt(ctx context.Context, afterMessageID int64, limit int) ([]model.StoredContent, error)
+	UpdateMessageContent(ctx context.Context, messageID int64, previous, content string) (bool, error)
+	UpdateMessage(ctx context.Context, messageID int64, content, entities *string, mentions []model.Mention) (updatedAt time.Time, err error)
 
 	SetBindMessageMedia(ctx context.Context, messageID, mediaID int64) error
 	SetBindMessageLocation(ctx context.Context, messageID, locationID int64) error
@@ -84,19 +144,199 @@ type Messages interface {
 
 type Chats interface {
 	SetChat(ctx context.Context, chat model.ChatDB) (chatID int64, createdAt time.Time, err error)
+	SetPrivateChat(ctx context.Context, chat model.ChatDB, firstUserID, secondUserID string) (chatID int64, createdAt time.Time, created bool, err error)
 	SetParticipant(ctx context.Context, chatID int64, userID string) error
 	SetChatRole(ctx context.Context, chatRole model.ChatRole) error
 	SetBlockChat(ctx context.Context, chatID int64, userID string) error
 	SetAction(ctx context.Context, chatAction model.ChatAction) error
 	GetAllActions(ctx context.Context, chatID int64) ([]model.ChatAction, error)
 	GetAllActionsWithLimit(ctx context.Context, chatID int64, limit int) ([]model.ChatAction, error)
+	GetActionsByTypesWithLimit(ctx context.Context, chatID int64, actionTypes []string, limit int) ([]model.ChatAction, error)
 	GetChatByChatID(ctx context.Context, chatID int64) (model.ChatDB, error)
+	GetPrivateChatByParticipants(ctx context.Context, firstUserID, secondUserID string) (model.ChatDB, error)
 	GetAllChatRoles(ctx context.Context, chatID int64) ([]model.ChatRole, error)
 	GetAllParticipantsByChatID(ctx context.Context, chatID int64) ([]string, error)
 	GetAllChatsByUserID(ctx context.Context, userID string) ([]model.ChatDB, error)
 	GetAllChatsByUserIDWithLimit(ctx context.Context, userID string, limit int) ([]model.ChatDB, error)
 	IsBlockedChatExists(ctx context.Context, chatID int64, userID string) (bool, error)
+	IsUserBanned(ctx context.Context, chatID int64, userID string) (bool, error)
+	IsParticipantExists(ctx context.Context, chatID int64, userID string) (bool, error)
+	IsSharedChatExists(ctx context.Context, userID, otherUserID string) (bool, error)
+	GetPrivateChatPartners(ctx context.Context, userID string, usersIDs []string) ([]string, error)
+	GetChatMember(ctx context.Context, chatID int64, userID string) (model.ChatMember, error)
+	GetChatAdmins(ctx context.Context, chatID int64) ([]string, error)
 	// UpdateChat(ctx context.Context, chat model.Chat, chatID int64) error // НУЖНО ПОДУМАТЬ НУЖНО ЛИ ВОЗРАЩАТЬ ЕЩЁ ЧТО-ТО + ДОЛЖНА БЫТЬ ЛОГИКА ДЕЙСТВИЙ
+	UpdateMessageTTL(ctx context.Context, chatID int64, messageTTL *int) error
+	UpdateDefaultPermissions(ctx context.Context, chatID int64, permissions int) error
 	DeleteBlockUser(ctx context.Context, chatID int64, userID string) error
 	DeleteChat(ctx context.Context, chatID int64) error
 }
+
+type Reactions interface {
+	SetReaction(ctx context.Context, reaction model.Reaction) error
+	GetReactionsByMessageID(ctx context.Context, messageID int64) ([]model.Reaction, error)
+	DeleteReaction(ctx context.Context, reaction model.Reaction) error
+}
+
+type ScheduledMessages interface {
+	SetScheduledMessage(ctx context.Context, scheduled model.ScheduledMessage) (scheduledMessageID int64, createdAt time.Time, err error)
+	GetPendingScheduledMessages(ctx context.Context, chatID int64, senderID string) ([]model.ScheduledMessage, error)
+	UpdateScheduledMessage(ctx context.Context, scheduled model.ScheduledMessage) (bool, error)
+	DeleteScheduledMessage(ctx context.Context, scheduledMessageID, chatID int64, senderID string) (bool, error)
+	ClaimDueScheduledMessages(ctx context.Context, limit int, retryDelay, claimTimeout time.Duration) ([]model.ScheduledMessage, error)
+	FinishScheduledMessage(ctx context.Context, scheduledMessageID int64, status string, sentMessageID *int64) (bool, error)
+}
+
+type Keys interface {
+	SetDeviceKeys(ctx context.Context, keys model.DeviceKeys) error
+	GetPreKeyBundles(ctx context.Context, userID string) ([]model.PreKeyBundle, error)
+	CountOneTimePreKeys(ctx context.Context, userID string, deviceID int) (int, error)
+	SetEncryptedMessage(ctx context.Context, message model.MessageDB, chatID int64, senderDeviceID int, envelopes []model.EncryptedEnvelope) (messageID int64, createdAt time.Time, err error)
+	GetEncryptedMessages(ctx context.Context, chatID int64, userID string, deviceID int, beforeMessageID int64, limit int) ([]model.EncryptedMessageDB, error)
+}
+
+type DataKeys interface {
+	GetActiveDataKey(ctx context.Context, chatID int64) (model.DataKey, error)
+	GetDataKey(ctx context.Context, keyID int64) (model.DataKey, error)
+	SetDataKey(ctx context.Context, key model.DataKey) (model.DataKey, error)
+	GetDataKeysToRewrap(ctx context.Context, masterKeyVersion, limit int) ([]model.DataKey, error)
+	UpdateWrappedKey(ctx context.Context, key model.DataKey, previousVersion int) (bool, error)
+	DeactivateExpiredDataKeys(ctx context.Context, createdBefore time.Time, limit int) (int, error)
+}
+
+type Uploads interface {
+	SetBlob(ctx context.Context, blob model.Blob) error
+	GetBlob(ctx context.Context, sha256 string) (model.Blob, error)
+	GetAttachmentBlob(ctx context.Context, kind string, attachmentID int64) (model.Blob, error)
+	DeleteOrphanBlobs(ctx context.Context, sha256s []string) ([]model.Blob, error)
+	IsAttachmentInChat(ctx context.Context, kind string, attachmentID, chatID int64) (bool, error)
+	SetUpload(ctx context.Context, upload model.Upload) (createdAt time.Time, err error)
+	GetUpload(ctx context.Context, uploadID, userID string) (model.Upload, error)
+	SetUploadPart(ctx context.Context, part model.UploadPart) (bool, error)
+	GetUploadParts(ctx context.Context, uploadID string) ([]model.UploadPart, error)
+	DeleteUpload(ctx context.Context, uploadID string) error
+	GetExpiredUploads(ctx context.Context, limit int) ([]model.Upload, error)
+}
+
+type Voice interface {
+	SetVoiceListen(ctx context.Context, messageID int64, userID string) (model.VoiceListen, bool, error)
+	GetVoiceListensByMessageID(ctx context.Context, messageID int64) ([]model.VoiceListen, error)
+	GetUnlistenedVoiceMessages(ctx context.Context, chatID int64, userID string) ([]int64, error)
+}
+
+type Polls interface {
+	SetPoll(ctx context.Context, poll model.Poll) error
+	GetPoll(ctx context.Context, messageID int64) (model.Poll, error)
+	GetPollVotes(ctx context.Context, messageID int64) ([]model.PollVote, error)
+	ReplacePollVotes(ctx context.Context, messageID int64, userID string, optionIDs []int, check func(poll model.Poll, chosen []int) error) (bool, error)
+	CloseDuePolls(ctx context.Context, limit int) ([]model.ClosedPoll, error)
+}
+
+type Mentions interface {
+	SetMentions(ctx context.Context, messageID int64, mentions []model.Mention) error
+	GetMentionsByMessageID(ctx context.Context, messageID int64) ([]model.Mention, error)
+	GetUnreadMentions(ctx context.Context, chatID int64, userID string) ([]model.UnreadMention, error)
+	SetMentionsRead(ctx context.Context, chatID int64, userID string, upToMessageID int64) (int, error)
+}
+
+type LinkPreviews interface {
+	SetLinkPreview(ctx context.Context, messageID int64, preview string) error
+	GetLinkPreview(ctx context.Context, messageID int64) (string, error)
+	DeleteLinkPreview(ctx context.Context, messageID int64) error
+}
+
+type Presence interface {
+	SetLastSeen(ctx context.Context, userID string, lastSeenAt time.Time) error
+	SetHideLastSeen(ctx context.Context, userID string, hide bool) error
+	GetUserPresence(ctx context.Context, userID string) (model.UserPresence, error)
+	GetUsersPresence(ctx context.Context, usersIDs []string) ([]model.UserPresence, error)
+}
+
+type Members interface {
+	AddParticipants(ctx context.Context, chatID int64, usersIDs []string) ([]string, error)
+	RemoveParticipant(ctx context.Context, chatID int64, userID, action string, ban bool) error
+	UpdateChatInfo(ctx context.Context, request model.UpdateChatInfoRequest) (model.ChatDB, error)
+	TransferOwnership(ctx context.Context, chatID int64, creatorID, newCreatorID string) (model.ChatDB, error)
+}
+
+type Channels interface {
+	GetChannelByHandle(ctx context.Context, handle string) (model.ChatDB, error)
+	JoinChannel(ctx context.Context, chatID int64, userID string) (bool, error)
+	CountSubscribers(ctx context.Context, chatID int64) (int, error)
+	GetSubscribersAfter(ctx context.Context, chatID int64, afterUserID string, limit int) ([]string, error)
+	SetChannelPost(ctx context.Context, post model.ChannelPost) error
+	GetChannelPost(ctx context.Context, messageID int64) (model.ChannelPost, error)
+	ViewChannelPosts(ctx context.Context, chatID int64, userID string, messageIDs []int64) ([]model.ChannelPost, error)
+}
+
+type ChatList interface {
+	GetChatList(ctx context.Context, userID string, filter model.ChatListFilter, offset, limit int) ([]model.ChatListItem, error)
+	UpsertChatState(ctx context.Context, request model.UpdateChatStateRequest) (model.ChatState, error)
+	SetChatRead(ctx context.Context, chatID int64, userID string, messageID int64) (model.ChatState, error)
+	CountChatFolders(ctx context.Context, userID string) (int, error)
+	SetChatFolder(ctx context.Context, folder model.ChatFolder) (model.ChatFolder, error)
+	UpdateChatFolder(ctx context.Context, folder model.ChatFolder) (model.ChatFolder, error)
+	GetChatFolder(ctx context.Context, folderID int64, userID string) (model.ChatFolder, error)
+	GetChatFoldersByUserID(ctx context.Context, userID string) ([]model.ChatFolder, error)
+	DeleteChatFolder(ctx context.Context, folderID int64, userID string) error
+}
+
+type Bookmarks interface {
+	SetBookmark(ctx context.Context, bookmark model.Bookmark) (model.Bookmark, error)
+	GetBookmarks(ctx context.Context, userID string, tags []string, beforeBookmarkID int64, limit int) ([]model.Bookmark, error)
+	DeleteBookmark(ctx context.Context, userID string, messageID int64) error
+	IsAttachmentBookmarked(ctx context.Context, kind string, attachmentID, chatID int64, userID string) (bool, error)
+}
+
+type Search interface {
+	SetMessageTokens(ctx context.Context, messageID, chatID int64, tokens []string) error
+	SearchMessages(ctx context.Context, filter model.SearchFilter) ([]model.SearchHit, error)
+}
+
+type Exports interface {
+	SetExport(ctx context.Context, export model.Export) (model.Export, error)
+	GetExport(ctx context.Context, exportID int64, userID string) (model.Export, error)
+	GetExportsByUserID(ctx context.Context, userID string, limit int) ([]model.Export, error)
+	CountUnfinishedExports(ctx context.Context, userID string) (int, error)
+	StartExport(ctx context.Context, exportID int64) (model.Export, error)
+	CompleteExport(ctx context.Context, exportID int64, storageKey string, size int64, expiresAt time.Time) (model.Export, error)
+	FailExport(ctx context.Context, exportID int64, message string, expiresAt time.Time) (model.Export, error)
+	FailStaleExports(ctx context.Context, startedBefore, expiresAt time.Time) (int64, error)
+	GetExpiredExports(ctx context.Context, limit int) ([]model.Export, error)
+	DeleteExport(ctx context.Context, exportID int64) error
+}
+
+type Retention interface {
+	GetRetentionPolicies(ctx context.Context) ([]model.RetentionPolicy, error)
+	SetTypeRetentionPolicy(ctx context.Context, chatType string, retentionDays int) (model.RetentionPolicy, error)
+	SetChatRetentionPolicy(ctx context.Context, chatID int64, retentionDays int) (model.RetentionPolicy, error)
+	DeleteTypeRetentionPolicy(ctx context.Context, chatType string) error
+	DeleteChatRetentionPolicy(ctx context.Context, chatID int64) error
+	GetLegalHolds(ctx context.Context) ([]model.LegalHold, error)
+	SetLegalHold(ctx context.Context, hold model.LegalHold) (model.LegalHold, error)
+	ReleaseLegalHold(ctx context.Context, holdID int64) (model.LegalHold, error)
+	StartRun(ctx context.Context) (model.RetentionRun, error)
+	FailStaleRuns(ctx context.Context, startedBefore time.Time) (int64, error)
+	SetRunChatsProcessed(ctx context.Context, runID int64, chatsProcessed int) error
+	FinishRun(ctx context.Context, runID int64, status string, message *string) (model.RetentionRun, error)
+	GetRun(ctx context.Context, runID int64) (model.RetentionRun, error)
+	GetRuns(ctx context.Context, limit int) ([]model.RetentionRun, error)
+	GetPurges(ctx context.Context, request model.RetentionPurgesRequest) ([]model.RetentionPurge, error)
+	GetChatRetentions(ctx context.Context, afterChatID int64, limit int) ([]model.ChatRetention, error)
+	PurgeChatMessages(ctx context.Context, runID int64, retention model.ChatRetention, purgedBefore time.Time, limit int) (model.RetentionBatch, error)
+	DeleteOrphanBlobs(ctx context.Context, purgeID int64, sha256s []string) ([]model.Blob, error)
+}
+
+type Imports interface {
+	ImportChat(ctx context.Context, chatImport model.ChatImport) ([]int64, error)
+}
+
+type Invites interface {
+	SetInviteLink(ctx context.Context, link model.InviteLink) (model.InviteLink, error)
+	GetInviteLinksByChatID(ctx context.Context, chatID int64, limit int) ([]model.InviteLink, error)
+	RevokeInviteLink(ctx context.Context, chatID, inviteLinkID int64) error
+	JoinByInviteLink(ctx context.Context, token, userID string, check func(link model.InviteLink) error) (model.InviteLink, error)
+	GetPendingJoinRequests(ctx context.Context, chatID int64) ([]model.JoinRequest, error)
+	ApproveJoinRequest(ctx context.Context, chatID int64, userID, adminID string) error
+	RejectJoinRequest(ctx context.Context, chatID int64, userID, adminID string) error
+}
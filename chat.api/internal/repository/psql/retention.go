@@ -0,0 +1,361 @@
+package repo
+
+import (
+	"chat-api/internal/model"
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+const (
+	retentionPolicyColumns = `policy_id, chat_type, chat_id, retention_days, updated_at`
+	legalHoldColumns       = `hold_id, chat_id, user_id, reason, created_at, released_at`
+	retentionRunColumns    = `run_id, status, chats_processed, messages_purged, error, started_at, finished_at`
+
+	// notHeldCondition keeps the message m of the chat cm and the thread of m out of any automatic deletion
+	// while the chat or one of the senders is under a legal hold
+	notHeldCondition = `
+		NOT EXISTS (
+			SELECT 1 FROM legal_holds h
+			WHERE h.released_at IS NULL AND (h.chat_id = cm.chat_id OR h.user_id = m.sender_id)
+		)
+		AND NOT EXISTS (
+			SELECT 1 FROM messages r
+			JOIN legal_holds h ON h.released_at IS NULL AND h.user_id = r.sender_id
+			WHERE r.thread_id = m.message_id AND r.message_id <> m.message_id
+		)`
+)
+
+type RetentionRepo struct {
+	db *sqlx.DB
+}
+
+func NewRetentionRepo(db *sqlx.DB) *RetentionRepo {
+	return &RetentionRepo{db: db}
+}
+
+func (r *RetentionRepo) GetRetentionPolicies(ctx context.Context) ([]model.RetentionPolicy, error) {
+	var policies []model.RetentionPolicy
+
+	query := `
+		SELECT ` + retentionPolicyColumns + `
+		FROM retention_policies
+		ORDER BY chat_id NULLS FIRST, chat_type
+	`
+
+	err := r.db.SelectContext(ctx, &policies, query)
+	return policies, err
+}
+
+func (r *RetentionRepo) SetTypeRetentionPolicy(ctx context.Context, chatType string, retentionDays int) (model.RetentionPolicy, error) {
+	var policy model.RetentionPolicy
+
+	query := `
+		INSERT INTO retention_policies (chat_type, retention_days)
+		VALUES ($1, $2)
+		ON CONFLICT (chat_type) DO UPDATE
+		SET retention_days = EXCLUDED.retention_days, updated_at = CURRENT_TIMESTAMP
+		RETURNING ` + retentionPolicyColumns
+
+	err := r.db.GetContext(ctx, &policy, query, chatType, retentionDays)
+	return policy, err
+}
+
+func (r *RetentionRepo) SetChatRetentionPolicy(ctx context.Context, chatID int64, retentionDays int) (model.RetentionPolicy, error) {
+	var policy model.RetentionPolicy
+
+	query := `
+		INSERT INTO retention_policies (chat_id, retention_days)
+		VALUES ($1, $2)
+		ON CONFLICT (chat_id) DO UPDATE
+		SET retention_days = EXCLUDED.retention_days, updated_at = CURRENT_TIMESTAMP
+		RETURNING ` + retentionPolicyColumns
+
+	err := r.db.GetContext(ctx, &policy, query, chatID, retentionDays)
+	return policy, err
+}
+
+// DeleteTypeRetentionPolicy returns sql.ErrNoRows if the type has no policy
+func (r *RetentionRepo) DeleteTypeRetentionPolicy(ctx context.Context, chatType string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM retention_policies WHERE chat_type = $1`, chatType)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteChatRetentionPolicy returns sql.ErrNoRows if the chat has no policy of its own
+func (r *RetentionRepo) DeleteChatRetentionPolicy(ctx context.Context, chatID int64) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM retention_policies WHERE chat_id = $1`, chatID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *RetentionRepo) GetLegalHolds(ctx context.Context) ([]model.LegalHold, error) {
+	var holds []model.LegalHold
+
+	query := `
+		SELECT ` + legalHoldColumns + `
+		FROM legal_holds
+		ORDER BY released_at IS NOT NULL, hold_id DESC
+	`
+
+	err := r.db.SelectContext(ctx, &holds, query)
+	return holds, err
+}
+
+func (r *RetentionRepo) SetLegalHold(ctx context.Context, hold model.LegalHold) (model.LegalHold, error) {
+	query := `
+		INSERT INTO legal_holds (chat_id, user_id, reason)
+		VALUES ($1, $2, $3)
+		RETURNING ` + legalHoldColumns
+
+	err := r.db.GetContext(ctx, &hold, query, hold.ChatID, hold.UserID, hold.Reason)
+	return hold, err
+}
+
+// ReleaseLegalHold returns sql.ErrNoRows if there's no such hold or it's released already
+func (r *RetentionRepo) ReleaseLegalHold(ctx context.Context, holdID int64) (model.LegalHold, error) {
+	var hold model.LegalHold
+
+	query := `
+		UPDATE legal_holds
+		SET released_at = CURRENT_TIMESTAMP
+		WHERE hold_id = $1 AND released_at IS NULL
+		RETURNING ` + legalHoldColumns
+
+	err := r.db.GetContext(ctx, &hold, query, holdID)
+	return hold, err
+}
+
+// StartRun returns sql.ErrNoRows if another run is in progress
+func (r *RetentionRepo) StartRun(ctx context.Context) (model.RetentionRun, error) {
+	var run model.RetentionRun
+
+	query := `
+		INSERT INTO retention_runs (status)
+		VALUES ($1)
+		ON CONFLICT (status) WHERE status = 'running' DO NOTHING
+		RETURNING ` + retentionRunColumns
+
+	err := r.db.GetContext(ctx, &run, query, model.RETENTION_RUN_RUNNING)
+	return run, err
+}
+
+// FailStaleRuns fails the runs whose replica has been gone since startedBefore, so the next run can start
+func (r *RetentionRepo) FailStaleRuns(ctx context.Context, startedBefore time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE retention_runs
+		SET status = $1, error = $2, finished_at = CURRENT_TIMESTAMP
+		WHERE status = $3 AND started_at < $4
+	`, model.RETENTION_RUN_FAILED, model.ErrRetentionTimeout.Error(), model.RETENTION_RUN_RUNNING, startedBefore)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (r *RetentionRepo) SetRunChatsProcessed(ctx context.Context, runID int64, chatsProcessed int) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE retention_runs SET chats_processed = $2 WHERE run_id = $1`, runID, chatsProcessed)
+	return err
+}
+
+func (r *RetentionRepo) FinishRun(ctx context.Context, runID int64, status string, message *string) (model.RetentionRun, error) {
+	var run model.RetentionRun
+
+	query := `
+		UPDATE retention_runs
+		SET status = $2, error = $3, finished_at = CURRENT_TIMESTAMP
+		WHERE run_id = $1
+		RETURNING ` + retentionRunColumns
+
+	err := r.db.GetContext(ctx, &run, query, runID, status, message)
+	return run, err
+}
+
+func (r *RetentionRepo) GetRun(ctx context.Context, runID int64) (model.RetentionRun, error) {
+	var run model.RetentionRun
+
+	query := `
+		SELECT ` + retentionRunColumns + `
+		FROM retention_runs
+		WHERE run_id = $1
+	`
+
+	err := r.db.GetContext(ctx, &run, query, runID)
+	return run, err
+}
+
+func (r *RetentionRepo) GetRuns(ctx context.Context, limit int) ([]model.RetentionRun, error) {
+	var runs []model.RetentionRun
+
+	query := `
+		SELECT ` + retentionRunColumns + `
+		FROM retention_runs
+		ORDER BY run_id DESC
+		LIMIT $1
+	`
+
+	err := r.db.SelectContext(ctx, &runs, query, limit)
+	return runs, err
+}
+
+func (r *RetentionRepo) GetPurges(ctx context.Context, request model.RetentionPurgesRequest) ([]model.RetentionPurge, error) {
+	var purges []model.RetentionPurge
+
+	query := `
+		SELECT purge_id, run_id, chat_id, policy_id, scope, retention_days, purged_before, message_ids, blobs, purged_at
+		FROM retention_purges
+		WHERE run_id = $1 AND purge_id > $2
+		ORDER BY purge_id
+		LIMIT $3
+	`
+
+	err := r.db.SelectContext(ctx, &purges, query, request.RunID, request.AfterPurgeID, request.Limit)
+	return purges, err
+}
+
+// GetChatRetentions pages through the chats with a policy in force which aren't under a legal hold
+func (r *RetentionRepo) GetChatRetentions(ctx context.Context, afterChatID int64, limit int) ([]model.ChatRetention, error) {
+	var retentions []model.ChatRetention
+
+	query := `
+		SELECT c.chat_id, c.type,
+			COALESCE(cp.policy_id, tp.policy_id) AS policy_id,
+			CASE WHEN cp.policy_id IS NOT NULL THEN $1 ELSE $2 END AS scope,
+			COALESCE(cp.retention_days, tp.retention_days) AS retention_days
+		FROM chats c
+		LEFT JOIN retention_policies cp ON cp.chat_id = c.chat_id
+		LEFT JOIN retention_policies tp ON tp.chat_type = c.type
+		WHERE c.chat_id > $3
+		  AND (cp.policy_id IS NOT NULL OR tp.policy_id IS NOT NULL)
+		  AND NOT EXISTS (
+			SELECT 1 FROM legal_holds h
+			WHERE h.chat_id = c.chat_id AND h.released_at IS NULL
+		  )
+		ORDER BY c.chat_id
+		LIMIT $4
+	`
+
+	err := r.db.SelectContext(ctx, &retentions, query, model.RETENTION_SCOPE_CHAT, model.RETENTION_SCOPE_TYPE, afterChatID, limit)
+	return retentions, err
+}
+
+// PurgeChatMessages deletes up to limit messages of the chat sent before purgedBefore and records them
+// in the audit of the run under the policy. A thread goes only once all its replies are past the policy too,
+// the held messages stay and the rows locked by another replica are skipped.
+func (r *RetentionRepo) PurgeChatMessages(ctx context.Context, runID int64, retention model.ChatRetention, purgedBefore time.Time, limit int) (model.RetentionBatch, error) {
+	var batch model.RetentionBatch
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return batch, err
+	}
+	defer tx.Rollback()
+
+	var expired []model.DeletedMessage
+
+	query := `
+		SELECT m.message_id, cm.chat_id
+		FROM messages m
+		JOIN chat_messages cm ON m.message_id = cm.message_id
+		WHERE cm.chat_id = $1 AND m.created_at < $2
+		  AND NOT EXISTS (
+			SELECT 1 FROM messages r
+			WHERE r.thread_id = m.message_id AND r.message_id <> m.message_id AND r.created_at >= $2
+		  )
+		  AND ` + notHeldCondition + `
+		ORDER BY m.message_id
+		LIMIT $3
+		FOR UPDATE OF m SKIP LOCKED
+	`
+
+	if err := tx.SelectContext(ctx, &expired, query, retention.ChatID, purgedBefore, limit); err != nil {
+		return batch, err
+	}
+	if len(expired) == 0 {
+		return batch, nil
+	}
+
+	batch.Deleted, batch.BlobSHA256s, err = deleteMessages(ctx, tx, expired)
+	if err != nil {
+		return batch, err
+	}
+
+	messageIDs := make([]int64, 0, len(batch.Deleted))
+	for _, message := range batch.Deleted {
+		messageIDs = append(messageIDs, message.MessageID)
+	}
+
+	if err := tx.GetContext(ctx, &batch.PurgeID, `
+		INSERT INTO retention_purges (run_id, chat_id, policy_id, scope, retention_days, purged_before, message_ids)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING purge_id
+	`, runID, retention.ChatID, retention.PolicyID, retention.Scope, retention.RetentionDays, purgedBefore, pq.Array(messageIDs)); err != nil {
+		return batch, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE retention_runs
+		SET messages_purged = messages_purged + $2
+		WHERE run_id = $1
+	`, runID, len(batch.Deleted)); err != nil {
+		return batch, err
+	}
+
+	return batch, tx.Commit()
+}
+
+// DeleteOrphanBlobs deletes the blobs of sha256s no file or media refers to any more and records them
+// in the audit of the purge, it returns the deleted blobs to remove from the storage
+func (r *RetentionRepo) DeleteOrphanBlobs(ctx context.Context, purgeID int64, sha256s []string) ([]model.Blob, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	blobs, err := deleteOrphanBlobs(ctx, tx, sha256s)
+	if err != nil {
+		return nil, err
+	}
+	if len(blobs) == 0 {
+		return nil, nil
+	}
+
+	deleted := make([]string, 0, len(blobs))
+	for _, blob := range blobs {
+		deleted = append(deleted, blob.SHA256)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE retention_purges
+		SET blobs = blobs || $2::CHAR(64)[]
+		WHERE purge_id = $1
+	`, purgeID, pq.Array(deleted)); err != nil {
+		return nil, err
+	}
+
+	return blobs, tx.Commit()
+}
@@ -0,0 +1,228 @@
+package repo
+
+import (
+	"chat-api/internal/model"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type ScheduledMessagesRepo struct {
+	db *sqlx.DB
+}
+
+func NewScheduledMessagesRepo(db *sqlx.DB) *ScheduledMessagesRepo {
+	return &ScheduledMessagesRepo{db: db}
+}
+
+type scheduledMessageRow struct {
+	ScheduledMessageID int64     `db:"scheduled_message_id"`
+	ChatID             int64     `db:"chat_id"`
+	SenderID           string    `db:"sender_id"`
+	Payload            []byte    `db:"payload"`
+	InThread           bool      `db:"in_thread"`
+	SendAt             time.Time `db:"send_at"`
+	Status             string    `db:"status"`
+	SentMessageID      *int64    `db:"sent_message_id"`
+	Attempts           int       `db:"attempts"`
+	CreatedAt          time.Time `db:"created_at"`
+	UpdatedAt          time.Time `db:"updated_at"`
+}
+
+func (row scheduledMessageRow) toModel() (model.ScheduledMessage, error) {
+	scheduled := model.ScheduledMessage{
+		ScheduledMessageID: row.ScheduledMessageID,
+		ChatID:             row.ChatID,
+		SenderID:           row.SenderID,
+		InThread:           row.InThread,
+		SendAt:             row.SendAt,
+		Status:             row.Status,
+		SentMessageID:      row.SentMessageID,
+		Attempts:           row.Attempts,
+		CreatedAt:          row.CreatedAt,
+		UpdatedAt:          row.UpdatedAt,
+	}
+
+	return scheduled, json.Unmarshal(row.Payload, &scheduled.Message)
+}
+
+const scheduledMessageColumns = `scheduled_message_id, chat_id, sender_id, payload, in_thread, send_at, status, sent_message_id, attempts, created_at, updated_at`
+
+func (r *ScheduledMessagesRepo) SetScheduledMessage(ctx context.Context, scheduled model.ScheduledMessage) (scheduledMessageID int64, createdAt time.Time, err error) {
+	payload, err := json.Marshal(scheduled.Message)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO scheduled_messages (chat_id, sender_id, payload, in_thread, send_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING scheduled_message_id, created_at
+	`
+
+	err = tx.QueryRowContext(ctx, query, scheduled.ChatID, scheduled.SenderID, payload, scheduled.InThread, scheduled.SendAt).
+		Scan(&scheduledMessageID, &createdAt)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	return scheduledMessageID, createdAt, tx.Commit()
+}
+
+func (r *ScheduledMessagesRepo) GetPendingScheduledMessages(ctx context.Context, chatID int64, senderID string) ([]model.ScheduledMessage, error) {
+	var rows []scheduledMessageRow
+
+	query := `
+		SELECT ` + scheduledMessageColumns + `
+		FROM scheduled_messages
+		WHERE chat_id = $1 AND sender_id = $2 AND status = 'pending'
+		ORDER BY send_at ASC
+	`
+
+	if err := r.db.SelectContext(ctx, &rows, query, chatID, senderID); err != nil {
+		return nil, err
+	}
+
+	scheduled := make([]model.ScheduledMessage, 0, len(rows))
+	for _, row := range rows {
+		message, err := row.toModel()
+		if err != nil {
+			return nil, err
+		}
+		scheduled = append(scheduled, message)
+	}
+
+	return scheduled, nil
+}
+
+// UpdateScheduledMessage changes a pending message of the sender,
+// false is returned if it has been already sent or doesn't exist.
+func (r *ScheduledMessagesRepo) UpdateScheduledMessage(ctx context.Context, scheduled model.ScheduledMessage) (bool, error) {
+	payload, err := json.Marshal(scheduled.Message)
+	if err != nil {
+		return false, err
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE scheduled_messages
+		SET payload = $1, in_thread = $2, send_at = $3, attempts = 0, claimed_at = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE scheduled_message_id = $4 AND chat_id = $5 AND sender_id = $6 AND status = 'pending'
+	`
+
+	result, err := tx.ExecContext(ctx, query, payload, scheduled.InThread, scheduled.SendAt, scheduled.ScheduledMessageID, scheduled.ChatID, scheduled.SenderID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, tx.Commit()
+}
+
+func (r *ScheduledMessagesRepo) DeleteScheduledMessage(ctx context.Context, scheduledMessageID, chatID int64, senderID string) (bool, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		DELETE FROM scheduled_messages
+		WHERE scheduled_message_id = $1 AND chat_id = $2 AND sender_id = $3 AND status = 'pending'
+	`
+
+	result, err := tx.ExecContext(ctx, query, scheduledMessageID, chatID, senderID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, tx.Commit()
+}
+
+// ClaimDueScheduledMessages takes up to limit due messages for sending and commits the claim, so the messages
+// are dispatched outside of any transaction. A message waits retryDelay after a failed attempt, the claim
+// of a replica gone for claimTimeout is taken over, so a crash right after sending may send the message again.
+// Rows locked by another replica are skipped.
+func (r *ScheduledMessagesRepo) ClaimDueScheduledMessages(ctx context.Context, limit int, retryDelay, claimTimeout time.Duration) ([]model.ScheduledMessage, error) {
+	var rows []scheduledMessageRow
+
+	query := `
+		UPDATE scheduled_messages
+		SET status = $1, attempts = attempts + 1, claimed_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE scheduled_message_id IN (
+			SELECT scheduled_message_id
+			FROM scheduled_messages
+			WHERE send_at <= CURRENT_TIMESTAMP AND (
+				(status = $2 AND (claimed_at IS NULL OR claimed_at <= CURRENT_TIMESTAMP - make_interval(secs => $3)))
+				OR (status = $1 AND claimed_at <= CURRENT_TIMESTAMP - make_interval(secs => $4))
+			)
+			ORDER BY send_at ASC
+			LIMIT $5
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + scheduledMessageColumns
+
+	err := r.db.SelectContext(ctx, &rows, query,
+		model.SCHEDULED_MESSAGE_SENDING, model.SCHEDULED_MESSAGE_PENDING, retryDelay.Seconds(), claimTimeout.Seconds(), limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	scheduled := make([]model.ScheduledMessage, 0, len(rows))
+	for _, row := range rows {
+		message, err := row.toModel()
+		if err != nil {
+			// the payload can't be read on any attempt
+			if _, failErr := r.FinishScheduledMessage(ctx, row.ScheduledMessageID, model.SCHEDULED_MESSAGE_FAILED, nil); failErr != nil {
+				return nil, failErr
+			}
+			continue
+		}
+		scheduled = append(scheduled, message)
+	}
+
+	return scheduled, nil
+}
+
+// FinishScheduledMessage sets the outcome of the claimed message: sent, failed or pending again to be retried.
+// false is returned if the claim has been taken over in the meantime.
+func (r *ScheduledMessagesRepo) FinishScheduledMessage(ctx context.Context, scheduledMessageID int64, status string, sentMessageID *int64) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE scheduled_messages
+		SET status = $1, sent_message_id = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE scheduled_message_id = $3 AND status = $4
+	`, status, sentMessageID, scheduledMessageID, model.SCHEDULED_MESSAGE_SENDING)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
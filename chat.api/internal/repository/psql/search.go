@@ -0,0 +1,100 @@
+package repo
+
+import (
+	"chat-api/internal/model"
+	"context"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type SearchRepo struct {
+	db *sqlx.DB
+}
+
+func NewSearchRepo(db *sqlx.DB) *SearchRepo {
+	return &SearchRepo{db: db}
+}
+
+// SetMessageTokens replaces the tokens of the message, the message without tokens drops out of the search
+func (r *SearchRepo) SetMessageTokens(ctx context.Context, messageID, chatID int64, tokens []string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM message_search_tokens WHERE message_id = $1`, messageID); err != nil {
+		return err
+	}
+
+	if len(tokens) > 0 {
+		query := `
+			INSERT INTO message_search_tokens (message_id, chat_id, token)
+			SELECT $1, $2, token
+			FROM unnest($3::text[]) AS token
+			ON CONFLICT DO NOTHING
+		`
+		if _, err := tx.ExecContext(ctx, query, messageID, chatID, pq.Array(tokens)); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// SearchMessages pages back through the messages having the tokens of all the words,
+// only the chats the user takes part in are searched
+func (r *SearchRepo) SearchMessages(ctx context.Context, filter model.SearchFilter) ([]model.SearchHit, error) {
+	var hits []model.SearchHit
+
+	query := `
+		WITH q AS (
+			SELECT *
+			FROM unnest($1::bigint[], $2::text[], $3::int[]) AS q(chat_id, token, word)
+		), hits AS (
+			SELECT t.chat_id, t.message_id
+			FROM message_search_tokens t
+			JOIN q ON q.chat_id = t.chat_id AND q.token = t.token
+			GROUP BY t.chat_id, t.message_id
+			HAVING COUNT(DISTINCT q.word) = $4
+		)
+		SELECT h.chat_id, h.message_id
+		FROM hits h
+		JOIN chats_participants cp ON cp.chat_id = h.chat_id AND cp.user_id = $5
+		JOIN messages m ON m.message_id = h.message_id
+		WHERE ($6 = '' OR m.sender_id = $6)
+		  AND ($7::timestamp IS NULL OR m.created_at >= $7)
+		  AND ($8::timestamp IS NULL OR m.created_at < $8)
+		  AND ($9 = 0 OR m.message_id < $9)
+		  AND (m.expires_at IS NULL OR m.expires_at > CURRENT_TIMESTAMP)
+		  AND (
+		      $10 = ''
+		      OR ($10 = 'media' AND EXISTS (SELECT 1 FROM messages_media mm WHERE mm.message_id = m.message_id))
+		      OR ($10 = 'file' AND m.type <> 'voice' AND EXISTS (SELECT 1 FROM messages_files mf WHERE mf.message_id = m.message_id))
+		      OR ($10 = 'location' AND EXISTS (SELECT 1 FROM messages_locations ml WHERE ml.message_id = m.message_id))
+		      OR ($10 IN ('text', 'voice', 'poll') AND m.type::text = $10)
+		  )
+		ORDER BY h.message_id DESC
+		LIMIT $11
+	`
+
+	err := r.db.SelectContext(ctx, &hits, query,
+		pq.Array(filter.ChatIDs),
+		pq.Array(filter.Tokens),
+		pq.Array(filter.Words),
+		filter.WordsCount,
+		filter.UserID,
+		filter.SenderID,
+		filter.Since,
+		filter.Until,
+		filter.BeforeMessageID,
+		filter.Type,
+		filter.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return hits, nil
+}
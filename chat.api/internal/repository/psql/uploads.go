@@ -0,0 +1,236 @@
+package repo
+
+import (
+	"chat-api/internal/model"
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type UploadsRepo struct {
+	db *sqlx.DB
+}
+
+func NewUploadsRepo(db *sqlx.DB) *UploadsRepo {
+	return &UploadsRepo{db: db}
+}
+
+// SetBlob keeps the first stored blob, the same content is always put under the same key
+func (r *UploadsRepo) SetBlob(ctx context.Context, blob model.Blob) error {
+	query := `
+		INSERT INTO blobs (sha256, storage_key, type, size)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (sha256) DO NOTHING
+	`
+
+	_, err := r.db.ExecContext(ctx, query, blob.SHA256, blob.StorageKey, blob.Type, blob.Size)
+	return err
+}
+
+func (r *UploadsRepo) GetBlob(ctx context.Context, sha256 string) (model.Blob, error) {
+	var blob model.Blob
+
+	query := `
+		SELECT sha256, storage_key, type, size, created_at
+		FROM blobs
+		WHERE sha256 = $1
+	`
+
+	err := r.db.GetContext(ctx, &blob, query, sha256)
+	return blob, err
+}
+
+// DeleteOrphanBlobs deletes the blobs of sha256s no file or media refers to any more,
+// it returns the deleted blobs to remove from the storage
+func (r *UploadsRepo) DeleteOrphanBlobs(ctx context.Context, sha256s []string) ([]model.Blob, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	blobs, err := deleteOrphanBlobs(ctx, tx, sha256s)
+	if err != nil {
+		return nil, err
+	}
+
+	return blobs, tx.Commit()
+}
+
+func deleteOrphanBlobs(ctx context.Context, tx *sqlx.Tx, sha256s []string) ([]model.Blob, error) {
+	var blobs []model.Blob
+
+	query := `
+		DELETE FROM blobs b
+		WHERE b.sha256 = ANY($1)
+		  AND NOT EXISTS (SELECT 1 FROM files f WHERE f.sha256 = b.sha256)
+		  AND NOT EXISTS (SELECT 1 FROM media m WHERE m.sha256 = b.sha256 OR m.thumbnail_sha256 = b.sha256)
+		RETURNING b.sha256, b.storage_key, b.type, b.size, b.created_at
+	`
+
+	err := tx.SelectContext(ctx, &blobs, query, pq.Array(sha256s))
+	return blobs, err
+}
+
+// GetAttachmentBlob returns the blob behind the uploaded file or media
+func (r *UploadsRepo) GetAttachmentBlob(ctx context.Context, kind string, attachmentID int64) (model.Blob, error) {
+	var blob model.Blob
+
+	query := `
+		SELECT b.sha256, b.storage_key, b.type, b.size, b.created_at
+		FROM blobs b
+		JOIN files f ON b.sha256 = f.sha256
+		WHERE f.file_id = $1
+	`
+	switch kind {
+	case model.UPLOAD_KIND_MEDIA:
+		query = `
+			SELECT b.sha256, b.storage_key, b.type, b.size, b.created_at
+			FROM blobs b
+			JOIN media m ON b.sha256 = m.sha256
+			WHERE m.media_id = $1
+		`
+	case model.DOWNLOAD_KIND_THUMBNAIL:
+		query = `
+			SELECT b.sha256, b.storage_key, b.type, b.size, b.created_at
+			FROM blobs b
+			JOIN media m ON b.sha256 = m.thumbnail_sha256
+			WHERE m.media_id = $1
+		`
+	}
+
+	err := r.db.GetContext(ctx, &blob, query, attachmentID)
+	return blob, err
+}
+
+// IsAttachmentInChat checks that the file or media is attached to a message of the chat
+func (r *UploadsRepo) IsAttachmentInChat(ctx context.Context, kind string, attachmentID, chatID int64) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM messages_files mf
+			JOIN chat_messages cm ON mf.message_id = cm.message_id
+			WHERE mf.file_id = $1 AND cm.chat_id = $2
+		)
+	`
+	if kind == model.UPLOAD_KIND_MEDIA || kind == model.DOWNLOAD_KIND_THUMBNAIL {
+		query = `
+			SELECT EXISTS (
+				SELECT 1
+				FROM messages_media mm
+				JOIN chat_messages cm ON mm.message_id = cm.message_id
+				WHERE mm.media_id = $1 AND cm.chat_id = $2
+			)
+		`
+	}
+
+	var exists bool
+	err := r.db.GetContext(ctx, &exists, query, attachmentID, chatID)
+	return exists, err
+}
+
+func (r *UploadsRepo) SetUpload(ctx context.Context, upload model.Upload) (createdAt time.Time, err error) {
+	query := `
+		INSERT INTO uploads (upload_id, user_id, kind, length, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at
+	`
+
+	err = r.db.QueryRowContext(ctx, query, upload.UploadID, upload.UserID, upload.Kind, upload.Length, upload.ExpiresAt).
+		Scan(&createdAt)
+	return createdAt, err
+}
+
+func (r *UploadsRepo) GetUpload(ctx context.Context, uploadID, userID string) (model.Upload, error) {
+	var upload model.Upload
+
+	query := `
+		SELECT upload_id, user_id, kind, length, upload_offset, created_at, expires_at
+		FROM uploads
+		WHERE upload_id = $1 AND user_id = $2 AND expires_at > CURRENT_TIMESTAMP
+	`
+
+	err := r.db.GetContext(ctx, &upload, query, uploadID, userID)
+	return upload, err
+}
+
+// SetUploadPart moves the offset past the part only if nobody has written at the same offset meanwhile
+func (r *UploadsRepo) SetUploadPart(ctx context.Context, part model.UploadPart) (bool, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE uploads
+		SET upload_offset = upload_offset + $1
+		WHERE upload_id = $2 AND upload_offset = $3
+	`, part.Size, part.UploadID, part.Offset)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rows == 0 {
+		return false, nil
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO upload_parts (upload_id, part_offset, size, storage_key)
+		VALUES ($1, $2, $3, $4)
+	`, part.UploadID, part.Offset, part.Size, part.StorageKey)
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+func (r *UploadsRepo) GetUploadParts(ctx context.Context, uploadID string) ([]model.UploadPart, error) {
+	var parts []model.UploadPart
+
+	query := `
+		SELECT upload_id, part_offset, size, storage_key
+		FROM upload_parts
+		WHERE upload_id = $1
+		ORDER BY part_offset
+	`
+
+	if err := r.db.SelectContext(ctx, &parts, query, uploadID); err != nil {
+		return nil, err
+	}
+
+	return parts, nil
+}
+
+func (r *UploadsRepo) DeleteUpload(ctx context.Context, uploadID string) error {
+	_, err := r.db.ExecContext(ctx, `
+		DELETE FROM uploads
+		WHERE upload_id = $1
+	`, uploadID)
+	return err
+}
+
+func (r *UploadsRepo) GetExpiredUploads(ctx context.Context, limit int) ([]model.Upload, error) {
+	var uploads []model.Upload
+
+	query := `
+		SELECT upload_id, user_id, kind, length, upload_offset, created_at, expires_at
+		FROM uploads
+		WHERE expires_at <= CURRENT_TIMESTAMP
+		ORDER BY expires_at
+		LIMIT $1
+	`
+
+	if err := r.db.SelectContext(ctx, &uploads, query, limit); err != nil {
+		return nil, err
+	}
+
+	return uploads, nil
+}
@@ -0,0 +1,79 @@
+package repo
+
+import (
+	"chat-api/internal/model"
+	"context"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type VoiceRepo struct {
+	db *sqlx.DB
+}
+
+func NewVoiceRepo(db *sqlx.DB) *VoiceRepo {
+	return &VoiceRepo{db: db}
+}
+
+// SetVoiceListen returns false if the recipient has listened to the message already
+func (r *VoiceRepo) SetVoiceListen(ctx context.Context, messageID int64, userID string) (model.VoiceListen, bool, error) {
+	listen := model.VoiceListen{MessageID: messageID, UserID: userID}
+
+	query := `
+		INSERT INTO voice_listens (message_id, user_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+		RETURNING listened_at
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, messageID, userID)
+	if err != nil {
+		return listen, false, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return listen, false, rows.Err()
+	}
+
+	return listen, true, rows.Scan(&listen.ListenedAt)
+}
+
+func (r *VoiceRepo) GetVoiceListensByMessageID(ctx context.Context, messageID int64) ([]model.VoiceListen, error) {
+	var listens []model.VoiceListen
+
+	query := `
+		SELECT message_id, user_id, listened_at
+		FROM voice_listens
+		WHERE message_id = $1
+		ORDER BY listened_at ASC
+	`
+
+	if err := r.db.SelectContext(ctx, &listens, query, messageID); err != nil {
+		return nil, err
+	}
+
+	return listens, nil
+}
+
+// GetUnlistenedVoiceMessages returns the voice messages of the chat sent to the user and not played by them
+func (r *VoiceRepo) GetUnlistenedVoiceMessages(ctx context.Context, chatID int64, userID string) ([]int64, error) {
+	var messageIDs []int64
+
+	query := `
+		SELECT m.message_id
+		FROM messages m
+		JOIN chat_messages chm ON m.message_id = chm.message_id
+		WHERE chm.chat_id = $1 AND m.type = 'voice' AND m.sender_id <> $2
+		  AND NOT EXISTS (
+		      SELECT 1 FROM voice_listens vl WHERE vl.message_id = m.message_id AND vl.user_id = $2
+		  )
+		ORDER BY m.message_id ASC
+	`
+
+	if err := r.db.SelectContext(ctx, &messageIDs, query, chatID, userID); err != nil {
+		return nil, err
+	}
+
+	return messageIDs, nil
+}
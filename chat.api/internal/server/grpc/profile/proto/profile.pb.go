@@ -449,6 +449,58 @@ func (x *UsersBriefInfoResponse) GetResponse() *Response {
 	return nil
 }
 
+type UserIDsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserIDs       []string               `protobuf:"bytes,1,rep,name=userIDs,proto3" json:"userIDs,omitempty"`
+	Response      *Response              `protobuf:"bytes,2,opt,name=response,proto3" json:"response,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserIDsResponse) Reset() {
+	*x = UserIDsResponse{}
+	mi := &file_internal_server_grpc_profile_proto_profile_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserIDsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserIDsResponse) ProtoMessage() {}
+
+func (x *UserIDsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_server_grpc_profile_proto_profile_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserIDsResponse.ProtoReflect.Descriptor instead.
+func (*UserIDsResponse) Descriptor() ([]byte, []int) {
+	return file_internal_server_grpc_profile_proto_profile_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *UserIDsResponse) GetUserIDs() []string {
+	if x != nil {
+		return x.UserIDs
+	}
+	return nil
+}
+
+func (x *UserIDsResponse) GetResponse() *Response {
+	if x != nil {
+		return x.Response
+	}
+	return nil
+}
+
 var File_internal_server_grpc_profile_proto_profile_proto protoreflect.FileDescriptor
 
 const file_internal_server_grpc_profile_proto_profile_proto_rawDesc = "" +
@@ -489,11 +541,15 @@ const file_internal_server_grpc_profile_proto_profile_proto_rawDesc = "" +
 	"_avatarURL\"\x9b\x01\n" +
 	"\x16UsersBriefInfoResponse\x12T\n" +
 	"\x16usersBriefInfoResponse\x18\x01 \x03(\v2\x1c.proto.UserBriefInfoResponseR\x16usersBriefInfoResponse\x12+\n" +
-	"\bresponse\x18\x02 \x01(\v2\x0f.proto.ResponseR\bresponse2\xe4\x01\n" +
+	"\bresponse\x18\x02 \x01(\v2\x0f.proto.ResponseR\bresponse\"X\n" +
+	"\x0fUserIDsResponse\x12\x18\n" +
+	"\auserIDs\x18\x01 \x03(\tR\auserIDs\x12+\n" +
+	"\bresponse\x18\x02 \x01(\v2\x0f.proto.ResponseR\bresponse2\xa8\x02\n" +
 	"\x0eProfileService\x12D\n" +
 	"\x10GetUserBriefInfo\x12\x12.proto.UserRequest\x1a\x1c.proto.UserBriefInfoResponse\x12G\n" +
 	"\x11GetUsersBriefInfo\x12\x13.proto.UsersRequest\x1a\x1d.proto.UsersBriefInfoResponse\x12C\n" +
-	"\x0fGetUsersProfile\x12\x13.proto.UsersRequest\x1a\x1b.proto.UsersProfileResponseB$Z\"./internal/server/grpc/proto;protob\x06proto3"
+	"\x0fGetUsersProfile\x12\x13.proto.UsersRequest\x1a\x1b.proto.UsersProfileResponse\x12B\n" +
+	"\x13GetUsersWithContact\x12\x13.proto.UsersRequest\x1a\x16.proto.UserIDsResponseB$Z\"./internal/server/grpc/proto;protob\x06proto3"
 
 var (
 	file_internal_server_grpc_profile_proto_profile_proto_rawDescOnce sync.Once
@@ -507,7 +563,7 @@ func file_internal_server_grpc_profile_proto_profile_proto_rawDescGZIP() []byte
 	return file_internal_server_grpc_profile_proto_profile_proto_rawDescData
 }
 
-var file_internal_server_grpc_profile_proto_profile_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_internal_server_grpc_profile_proto_profile_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
 var file_internal_server_grpc_profile_proto_profile_proto_goTypes = []any{
 	(*Response)(nil),               // 0: proto.Response
 	(*UserRequest)(nil),            // 1: proto.UserRequest
@@ -516,24 +572,28 @@ var file_internal_server_grpc_profile_proto_profile_proto_goTypes = []any{
 	(*UsersProfileResponse)(nil),   // 4: proto.UsersProfileResponse
 	(*UserBriefInfoResponse)(nil),  // 5: proto.UserBriefInfoResponse
 	(*UsersBriefInfoResponse)(nil), // 6: proto.UsersBriefInfoResponse
+	(*UserIDsResponse)(nil),        // 7: proto.UserIDsResponse
 }
 var file_internal_server_grpc_profile_proto_profile_proto_depIdxs = []int32{
-	3, // 0: proto.UsersProfileResponse.users:type_name -> proto.User
-	0, // 1: proto.UsersProfileResponse.response:type_name -> proto.Response
-	0, // 2: proto.UserBriefInfoResponse.response:type_name -> proto.Response
-	5, // 3: proto.UsersBriefInfoResponse.usersBriefInfoResponse:type_name -> proto.UserBriefInfoResponse
-	0, // 4: proto.UsersBriefInfoResponse.response:type_name -> proto.Response
-	1, // 5: proto.ProfileService.GetUserBriefInfo:input_type -> proto.UserRequest
-	2, // 6: proto.ProfileService.GetUsersBriefInfo:input_type -> proto.UsersRequest
-	2, // 7: proto.ProfileService.GetUsersProfile:input_type -> proto.UsersRequest
-	5, // 8: proto.ProfileService.GetUserBriefInfo:output_type -> proto.UserBriefInfoResponse
-	6, // 9: proto.ProfileService.GetUsersBriefInfo:output_type -> proto.UsersBriefInfoResponse
-	4, // 10: proto.ProfileService.GetUsersProfile:output_type -> proto.UsersProfileResponse
-	8, // [8:11] is the sub-list for method output_type
-	5, // [5:8] is the sub-list for method input_type
-	5, // [5:5] is the sub-list for extension type_name
-	5, // [5:5] is the sub-list for extension extendee
-	0, // [0:5] is the sub-list for field type_name
+	3,  // 0: proto.UsersProfileResponse.users:type_name -> proto.User
+	0,  // 1: proto.UsersProfileResponse.response:type_name -> proto.Response
+	0,  // 2: proto.UserBriefInfoResponse.response:type_name -> proto.Response
+	5,  // 3: proto.UsersBriefInfoResponse.usersBriefInfoResponse:type_name -> proto.UserBriefInfoResponse
+	0,  // 4: proto.UsersBriefInfoResponse.response:type_name -> proto.Response
+	0,  // 5: proto.UserIDsResponse.response:type_name -> proto.Response
+	1,  // 6: proto.ProfileService.GetUserBriefInfo:input_type -> proto.UserRequest
+	2,  // 7: proto.ProfileService.GetUsersBriefInfo:input_type -> proto.UsersRequest
+	2,  // 8: proto.ProfileService.GetUsersProfile:input_type -> proto.UsersRequest
+	2,  // 9: proto.ProfileService.GetUsersWithContact:input_type -> proto.UsersRequest
+	5,  // 10: proto.ProfileService.GetUserBriefInfo:output_type -> proto.UserBriefInfoResponse
+	6,  // 11: proto.ProfileService.GetUsersBriefInfo:output_type -> proto.UsersBriefInfoResponse
+	4,  // 12: proto.ProfileService.GetUsersProfile:output_type -> proto.UsersProfileResponse
+	7,  // 13: proto.ProfileService.GetUsersWithContact:output_type -> proto.UserIDsResponse
+	10, // [10:14] is the sub-list for method output_type
+	6,  // [6:10] is the sub-list for method input_type
+	6,  // [6:6] is the sub-list for extension type_name
+	6,  // [6:6] is the sub-list for extension extendee
+	0,  // [0:6] is the sub-list for field type_name
 }
 
 func init() { file_internal_server_grpc_profile_proto_profile_proto_init() }
@@ -549,7 +609,7 @@ func file_internal_server_grpc_profile_proto_profile_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_internal_server_grpc_profile_proto_profile_proto_rawDesc), len(file_internal_server_grpc_profile_proto_profile_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   7,
+			NumMessages:   8,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
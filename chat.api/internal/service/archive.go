@@ -0,0 +1,241 @@
+package service
+
+import (
+	"archive/zip"
+	"chat-api/internal/model"
+	"chat-api/pkg/storage"
+	"context"
+	"encoding/json"
+	"html/template"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const archiveStyle = `body{font-family:sans-serif;max-width:800px;margin:0 auto;padding:16px;color:#222}
+.message{border-bottom:1px solid #eee;padding:8px 0}
+.meta{color:#888;font-size:12px}
+.content{white-space:pre-wrap;margin:4px 0}
+.reply{font-size:12px}
+img{max-width:320px;display:block;margin:4px 0}`
+
+var archiveTemplates = template.Must(template.New("archive").Funcs(template.FuncMap{
+	"isImage": func(attachment model.ArchiveAttachment) bool {
+		return attachment.Kind == model.UPLOAD_KIND_MEDIA && strings.HasPrefix(attachment.Type, "image/")
+	},
+	"time": func(t time.Time) string {
+		return t.UTC().Format("2006-01-02 15:04 MST")
+	},
+}).Parse(`
+{{define "header"}}<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>{{.Name}}</title><style>` + archiveStyle + `</style></head>
+<body><a href="../../index.html">All chats</a><h1>{{.Name}}</h1>{{if .Description}}<p>{{.Description}}</p>{{end}}
+{{end}}
+
+{{define "message"}}<div class="message" id="m{{.ID}}">
+<div class="meta">{{.Sender}} · {{time .CreatedAt}}{{if .EditedAt}} · edited{{end}}{{if .Forwarded}} · forwarded{{end}}</div>
+{{if .ReplyTo}}<a class="reply" href="#m{{.ReplyTo}}">in reply to the message</a>{{end}}
+{{if .Content}}<div class="content">{{.Content}}</div>{{end}}
+{{range .Attachments}}{{if .Path}}{{if isImage .}}<a href="{{.Path}}"><img src="{{.Path}}" alt="{{.Type}}"></a>{{else}}<a href="{{.Path}}">{{.Kind}} ({{.Type}})</a>{{end}}{{else}}<div class="meta">{{.Kind}} ({{.Type}}) isn't in the archive</div>{{end}}
+{{end}}{{range .Locations}}<div class="meta">Location {{.Latitude}}, {{.Longitude}}</div>
+{{end}}{{if .Poll}}<div class="content">Poll: {{.Poll.Question}}</div><ul>{{range .Poll.Options}}<li>{{.Text}}</li>{{end}}</ul>
+{{end}}</div>
+{{end}}
+
+{{define "footer"}}</body></html>
+{{end}}
+
+{{define "index"}}<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Exported chats</title><style>` + archiveStyle + `</style></head>
+<body><h1>Exported chats</h1><ul>
+{{range .}}<li><a href="{{.Dir}}/messages.html">{{.Name}}</a> <span class="meta">{{.Messages}} messages · <a href="{{.Dir}}/messages.json">JSON</a></span></li>
+{{end}}</ul></body></html>
+{{end}}`))
+
+// archiveWriter builds the zip of the export. The entries of a zip are written one after another,
+// so the messages of a chat are staged in temporary files and copied in when the chat is done
+type archiveWriter struct {
+	zip     *zip.Writer
+	storage storage.Storage
+	chats   []archivedChat
+}
+
+type archivedChat struct {
+	Name     string
+	Dir      string
+	Messages int
+}
+
+// chatArchive is the chat being written, its attachments are copied from the storage at the end
+type chatArchive struct {
+	info        model.ArchiveChatInfo
+	dir         string
+	json        *os.File
+	html        *os.File
+	messages    int
+	attachments []archivedBlob
+	paths       map[string]bool
+}
+
+type archivedBlob struct {
+	path       string
+	storageKey string
+}
+
+func newArchiveWriter(w io.Writer, storage storage.Storage) *archiveWriter {
+	return &archiveWriter{zip: zip.NewWriter(w), storage: storage}
+}
+
+func (a *archiveWriter) startChat(info model.ArchiveChatInfo) (*chatArchive, error) {
+	if info.Name == "" {
+		info.Name = "Chat " + strconv.FormatInt(info.ChatID, 10)
+	}
+
+	chat := &chatArchive{
+		info:  info,
+		dir:   "chats/" + strconv.FormatInt(info.ChatID, 10),
+		paths: make(map[string]bool),
+	}
+
+	var err error
+	if chat.json, err = os.CreateTemp("", "chat-export-*.json"); err != nil {
+		return nil, err
+	}
+	if chat.html, err = os.CreateTemp("", "chat-export-*.html"); err != nil {
+		removeTempFile(chat.json)
+		return nil, err
+	}
+
+	header, err := json.Marshal(info)
+	if err == nil {
+		_, err = io.WriteString(chat.json, `{"version":`+strconv.Itoa(model.ARCHIVE_VERSION)+`,"chat":`+string(header)+`,"messages":[`)
+	}
+	if err == nil {
+		err = archiveTemplates.ExecuteTemplate(chat.html, "header", info)
+	}
+	if err != nil {
+		chat.close()
+		return nil, err
+	}
+
+	return chat, nil
+}
+
+// addAttachment returns the path of the attachment in the directory of the chat
+func (c *chatArchive) addAttachment(kind string, attachmentID int64, blob model.Blob) string {
+	path := "attachments/" + kind + "-" + strconv.FormatInt(attachmentID, 10) + archiveExtension(blob.Type)
+	if !c.paths[path] {
+		c.paths[path] = true
+		c.attachments = append(c.attachments, archivedBlob{path: path, storageKey: blob.StorageKey})
+	}
+	return path
+}
+
+func (c *chatArchive) addMessage(message model.ArchiveMessage) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	if c.messages > 0 {
+		data = append([]byte{','}, data...)
+	}
+	if _, err := c.json.Write(data); err != nil {
+		return err
+	}
+
+	c.messages++
+	return archiveTemplates.ExecuteTemplate(c.html, "message", message)
+}
+
+// finishChat copies the staged chat and its attachments into the zip
+func (a *archiveWriter) finishChat(ctx context.Context, chat *chatArchive) error {
+	defer chat.close()
+
+	if _, err := io.WriteString(chat.json, "]}"); err != nil {
+		return err
+	}
+	if err := archiveTemplates.ExecuteTemplate(chat.html, "footer", nil); err != nil {
+		return err
+	}
+
+	if err := a.copyFile(chat.dir+"/messages.json", chat.json); err != nil {
+		return err
+	}
+	if err := a.copyFile(chat.dir+"/messages.html", chat.html); err != nil {
+		return err
+	}
+
+	for _, attachment := range chat.attachments {
+		if err := a.copyBlob(ctx, chat.dir+"/"+attachment.path, attachment.storageKey); err != nil {
+			return err
+		}
+	}
+
+	a.chats = append(a.chats, archivedChat{Name: chat.info.Name, Dir: chat.dir, Messages: chat.messages})
+	return nil
+}
+
+// close writes the index of the chats and finishes the zip
+func (a *archiveWriter) close() error {
+	index, err := a.zip.Create("index.html")
+	if err != nil {
+		return err
+	}
+	if err := archiveTemplates.ExecuteTemplate(index, "index", a.chats); err != nil {
+		return err
+	}
+	return a.zip.Close()
+}
+
+func (a *archiveWriter) copyFile(name string, file *os.File) error {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	entry, err := a.zip.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(entry, file)
+	return err
+}
+
+func (a *archiveWriter) copyBlob(ctx context.Context, name, storageKey string) error {
+	content, err := a.storage.Get(ctx, storageKey)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	// the media and the files are compressed already
+	entry, err := a.zip.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(entry, content)
+	return err
+}
+
+func (c *chatArchive) close() {
+	removeTempFile(c.json)
+	removeTempFile(c.html)
+}
+
+func archiveExtension(mimeType string) string {
+	_, subtype, ok := strings.Cut(mimeType, "/")
+	if !ok || subtype == "" {
+		return ""
+	}
+
+	switch subtype {
+	case "jpeg":
+		return ".jpg"
+	case "plain":
+		return ".txt"
+	case "mpeg":
+		return ".mp3"
+	}
+	return "." + subtype
+}
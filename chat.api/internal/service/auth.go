@@ -5,6 +5,7 @@ import (
 	"chat-api/internal/repository/cache"
 	"chat-api/pkg/auth"
 	"context"
+	"crypto/subtle"
 	"math/rand"
 	"strings"
 	"time"
@@ -21,14 +22,16 @@ const (
 var src = rand.NewSource(time.Now().UnixNano())
 
 type AuthService struct {
-	tokenManeger auth.TokenManager
-	cache        *cache.Cache
+	tokenManeger  auth.TokenManager
+	cache         *cache.Cache
+	complianceKey string
 }
 
-func NewAuthService(tkManager auth.TokenManager, cache *cache.Cache) *AuthService {
+func NewAuthService(tkManager auth.TokenManager, cache *cache.Cache, complianceKey string) *AuthService {
 	return &AuthService{
-		tokenManeger: tkManager,
-		cache:        cache,
+		tokenManeger:  tkManager,
+		cache:         cache,
+		complianceKey: complianceKey,
 	}
 }
 
@@ -40,6 +43,15 @@ func (s *AuthService) ValidateToken(token string) (string, error) {
 	return claims.UserID, err
 }
 
+// ValidateComplianceKey lets the compliance team manage the retention and the legal holds,
+// the compliance API is closed while no key is configured
+func (s *AuthService) ValidateComplianceKey(key string) error {
+	if s.complianceKey == "" || subtle.ConstantTimeCompare([]byte(key), []byte(s.complianceKey)) != 1 {
+		return model.ErrInvalidComplianceKey
+	}
+	return nil
+}
+
 func (s *AuthService) SetWebSocket(ctx context.Context, ws model.WebSocketConnection) error {
 	socketID := randStringBytesMaskImprSrcSB(optimalLength)
 	socketManager := model.NewWebSocketManagerWithRedis(s.cache.WebSocketCache.GetClient())
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"chat-api/internal/model"
+	repo "chat-api/internal/repository/psql"
+	"context"
+	"database/sql"
+	"errors"
+)
+
+type BookmarkService struct {
+	repoBookmarks repo.Bookmarks
+	repoMessages  repo.Messages
+	repoChats     repo.Chats
+	chats         *ChatService
+}
+
+func NewBookmarkService(bookmarks repo.Bookmarks, messages repo.Messages, chats repo.Chats, chatService *ChatService) *BookmarkService {
+	return &BookmarkService{
+		repoBookmarks: bookmarks,
+		repoMessages:  messages,
+		repoChats:     chats,
+		chats:         chatService,
+	}
+}
+
+// AddBookmark saves the message of the chat the user takes part in, saving it again replaces the tags
+func (s *BookmarkService) AddBookmark(ctx context.Context, request model.BookmarkRequest) (model.Bookmark, error) {
+	tags, ok := model.NormalizeBookmarkTags(request.Tags, model.BOOKMARK_TAGS_LIMIT)
+	if !ok {
+		return model.Bookmark{}, model.ErrInvalidBookmarkTags
+	}
+
+	if _, err := getChatMember(ctx, s.repoChats, request.ChatID, request.UserID); err != nil {
+		return model.Bookmark{}, err
+	}
+
+	chat, err := s.repoChats.GetChatByChatID(ctx, request.ChatID)
+	if err != nil {
+		return model.Bookmark{}, err
+	}
+	// the server can't show the content of the end-to-end encrypted messages
+	if chat.Encrypted {
+		return model.Bookmark{}, model.ErrChatEncrypted
+	}
+
+	inChat, err := s.repoMessages.IsMessageInChat(ctx, request.MessageID, request.ChatID)
+	if err != nil {
+		return model.Bookmark{}, err
+	}
+	if !inChat {
+		return model.Bookmark{}, model.ErrMessageNotFound
+	}
+
+	return s.repoBookmarks.SetBookmark(ctx, model.Bookmark{
+		UserID:    request.UserID,
+		ChatID:    request.ChatID,
+		MessageID: request.MessageID,
+		Tags:      tags,
+	})
+}
+
+func (s *BookmarkService) RemoveBookmark(ctx context.Context, userID string, messageID int64) error {
+	err := s.repoBookmarks.DeleteBookmark(ctx, userID, messageID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.ErrBookmarkNotFound
+	}
+	return err
+}
+
+// GetBookmarks returns the page of the saved messages with their attachments, the user may have left their chats
+func (s *BookmarkService) GetBookmarks(ctx context.Context, request model.BookmarksRequest) ([]model.BookmarkedMessage, error) {
+	tags, ok := model.NormalizeBookmarkTags(request.Tags, model.BOOKMARK_TAG_FILTER_SIZE)
+	if !ok {
+		return nil, model.ErrInvalidBookmarkTags
+	}
+
+	if request.Limit <= 0 || request.Limit > model.BOOKMARKS_LIMIT_REQUEST {
+		request.Limit = model.BOOKMARKS_LIMIT_REQUEST
+	}
+
+	bookmarks, err := s.repoBookmarks.GetBookmarks(ctx, request.UserID, tags, request.BeforeBookmarkID, request.Limit)
+	if err != nil {
+		return nil, err
+	}
+	if len(bookmarks) == 0 {
+		return []model.BookmarkedMessage{}, nil
+	}
+
+	messageIDs := make([]int64, len(bookmarks))
+	for i, bookmark := range bookmarks {
+		messageIDs[i] = bookmark.MessageID
+	}
+
+	messagesDB, err := s.repoMessages.GetMessagesByIDs(ctx, messageIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int64]model.MessageDB, len(messagesDB))
+	for _, messageDB := range messagesDB {
+		byID[messageDB.MessageID] = messageDB
+	}
+
+	// the message may be deleted between the queries, its bookmark is gone too
+	found := bookmarks[:0]
+	ordered := make([]model.MessageDB, 0, len(bookmarks))
+	for _, bookmark := range bookmarks {
+		messageDB, ok := byID[bookmark.MessageID]
+		if !ok {
+			continue
+		}
+		found = append(found, bookmark)
+		ordered = append(ordered, messageDB)
+	}
+
+	messages := s.chats.loadMessages(ctx, ordered, request.UserID)
+
+	bookmarked := make([]model.BookmarkedMessage, len(found))
+	for i, bookmark := range found {
+		bookmarked[i] = model.BookmarkedMessage{Bookmark: bookmark, Message: messages[i]}
+	}
+	return bookmarked, nil
+}
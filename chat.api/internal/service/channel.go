@@ -0,0 +1,108 @@
+package service
+
+import (
+	"chat-api/internal/model"
+	repo "chat-api/internal/repository/psql"
+	"chat-api/pkg/broker"
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+)
+
+type ChannelService struct {
+	repoChannels  repo.Channels
+	repoChats     repo.Chats
+	notifications Notifications
+}
+
+func NewChannelService(channels repo.Channels, chats repo.Chats, notifications Notifications) *ChannelService {
+	return &ChannelService{
+		repoChannels:  channels,
+		repoChats:     chats,
+		notifications: notifications,
+	}
+}
+
+// GetChannel returns the public channel along with the number of its subscribers
+func (s *ChannelService) GetChannel(ctx context.Context, handle string) (model.ChannelInfo, error) {
+	chat, err := s.getChannelByHandle(ctx, handle)
+	if err != nil {
+		return model.ChannelInfo{}, err
+	}
+
+	count, err := s.repoChannels.CountSubscribers(ctx, chat.ChatID)
+	if err != nil {
+		return model.ChannelInfo{}, err
+	}
+
+	return model.ChannelInfo{Chat: chat, SubscribersCount: count}, nil
+}
+
+// JoinChannel subscribes the user to the public channel, the banned users can't come back
+func (s *ChannelService) JoinChannel(ctx context.Context, handle, userID string) (model.ChatDB, error) {
+	chat, err := s.getChannelByHandle(ctx, handle)
+	if err != nil {
+		return chat, err
+	}
+
+	banned, err := s.repoChats.IsBlockedChatExists(ctx, chat.ChatID, userID)
+	if err != nil {
+		return chat, err
+	}
+	if banned {
+		return chat, model.ErrUserBanned
+	}
+
+	joined, err := s.repoChannels.JoinChannel(ctx, chat.ChatID, userID)
+	if err != nil {
+		return chat, err
+	}
+	if !joined {
+		return chat, model.ErrAlreadyParticipant
+	}
+	return chat, nil
+}
+
+func (s *ChannelService) GetChannelAdmins(ctx context.Context, chatID int64) ([]string, error) {
+	return s.repoChats.GetChatAdmins(ctx, chatID)
+}
+
+// GetSubscribers returns the page of the subscribers following the user, the page is empty after the last one
+func (s *ChannelService) GetSubscribers(ctx context.Context, chatID int64, afterUserID string) ([]string, error) {
+	return s.repoChannels.GetSubscribersAfter(ctx, chatID, afterUserID, model.CHANNEL_FANOUT_BATCH_SIZE)
+}
+
+// RequestFanout queues the delivery of the post to the next page of the subscribers
+func (s *ChannelService) RequestFanout(ctx context.Context, job model.ChannelFanoutJob) error {
+	return s.notifications.SendNotification(ctx, model.NotificationRabbitMQ{
+		Exchange:   broker.EXCHANGE_MESSAGE,
+		RoutingKey: broker.ROUTING_KEY_CHANNEL_FANOUT,
+	}, job)
+}
+
+// ViewPosts counts the views of the posts by the subscriber and returns their counters
+func (s *ChannelService) ViewPosts(ctx context.Context, request model.ViewPostsRequest) ([]model.ChannelPost, error) {
+	if !request.IsValid() {
+		return nil, model.ErrInvalidPostViews
+	}
+
+	if _, err := getChatMember(ctx, s.repoChats, request.ChatID, request.UserID); err != nil {
+		return nil, err
+	}
+
+	return s.repoChannels.ViewChannelPosts(ctx, request.ChatID, request.UserID, request.MessageIDs)
+}
+
+func (s *ChannelService) getChannelByHandle(ctx context.Context, handle string) (model.ChatDB, error) {
+	handle = strings.ToLower(handle)
+	if !model.IsValidChannelHandle(handle) {
+		return model.ChatDB{}, model.ErrChannelNotFound
+	}
+
+	chat, err := s.repoChannels.GetChannelByHandle(ctx, handle)
+	if errors.Is(err, sql.ErrNoRows) {
+		return chat, model.ErrChannelNotFound
+	}
+	return chat, err
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"chat-api/internal/model"
 	repo "chat-api/internal/repository/psql"
+	"chat-api/pkg/crypto"
 	"chat-api/pkg/logger"
 	"context"
 	"database/sql"
@@ -18,9 +19,16 @@ type ChatService struct {
 	repoFiles     repo.Files
 	repoLocations repo.Locations
 	repoPinned    repo.Pinned
+	repoReactions repo.Reactions
+	repoVoice     repo.Voice
+	repoPolls     repo.Polls
+	repoMentions  repo.Mentions
+	repoPreviews  repo.LinkPreviews
+	repoChannels  repo.Channels
+	encrypter     crypto.MessageEncrypter
 }
 
-func NewChatService(ms repo.Messages, ct repo.Chats, md repo.Media, f repo.Files, l repo.Locations, pin repo.Pinned) *ChatService {
+func NewChatService(ms repo.Messages, ct repo.Chats, md repo.Media, f repo.Files, l repo.Locations, pin repo.Pinned, r repo.Reactions, v repo.Voice, p repo.Polls, mn repo.Mentions, lp repo.LinkPreviews, cn repo.Channels, e crypto.MessageEncrypter) *ChatService {
 	return &ChatService{
 		repoMessages:  ms,
 		repoChats:     ct,
@@ -28,6 +36,13 @@ func NewChatService(ms repo.Messages, ct repo.Chats, md repo.Media, f repo.Files
 		repoFiles:     f,
 		repoLocations: l,
 		repoPinned:    pin,
+		repoReactions: r,
+		repoVoice:     v,
+		repoPolls:     p,
+		repoMentions:  mn,
+		repoPreviews:  lp,
+		repoChannels:  cn,
+		encrypter:     e,
 	}
 }
 
@@ -39,21 +54,98 @@ func (s *ChatService) CreatePrivateChat(ctx context.Context, request model.Creat
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	if request.Chat.CreatorID == "" || request.Chat.Name == "" || request.Chat.Type == "" {
+	if request.Chat.CreatorID == "" || request.RecipientID == "" || request.Chat.CreatorID == request.RecipientID {
 		return response, model.ErrInvalidParamsOfChat
 	}
 
+	if !model.IsValidMessageTTL(request.Chat.MessageTTL) || !model.IsValidMessageTTL(request.InitialMessage.TTL) {
+		return response, model.ErrInvalidMessageTTL
+	}
+
 	if request.InitialMessage.MessageWithData.MessageDB.SenderID == "" || request.InitialMessage.MessageWithData.MessageDB.Status == "" || request.InitialMessage.MessageWithData.MessageDB.Type == "" {
 		return response, model.ErrInvalidParamsOfMessage
 	}
 
-	messageID, messageCreatedAt, err := s.repoMessages.SetMessage(ctx, request.InitialMessage.MessageWithData.MessageDB)
+	// The name of a private chat is derived per viewer from the other participant
+	request.Chat.Name = ""
+	request.Chat.Type = model.CHAT_TYPE_PRIVATE
+
+	// polls belong to group chats and channels
+	if request.InitialMessage.MessageWithData.MessageDB.Type == model.MESSAGE_POLL {
+		return response, model.ErrPollNotAllowed
+	}
+	request.InitialMessage.MessageWithData.Poll = nil
+	request.InitialMessage.MessageWithData.Mentions = nil
+
+	// the initial message can't answer anything yet
+	request.InitialMessage.MessageWithData.MessageDB.ReplyToMessageID = nil
+	request.InitialMessage.MessageWithData.MessageDB.ThreadID = nil
+
+	chatDB, err := s.repoChats.GetPrivateChatByParticipants(ctx, request.Chat.CreatorID, request.RecipientID)
+	switch {
+	case err == nil:
+		request.Chat = chatDB
+	case errors.Is(err, sql.ErrNoRows):
+		chatID, chatCreatedAt, created, err := s.repoChats.SetPrivateChat(ctx, request.Chat, request.Chat.CreatorID, request.RecipientID)
+		if err != nil {
+			return response, err
+		}
+		if !created {
+			if request.Chat, err = s.repoChats.GetChatByChatID(ctx, chatID); err != nil {
+				return response, err
+			}
+		} else {
+			request.Chat.ChatID = chatID
+			request.Chat.CreatedAt = chatCreatedAt
+			request.Chat.UpdatedAt = chatCreatedAt
+		}
+		response.Created = created
+	default:
+		return response, err
+	}
+	chatID := request.Chat.ChatID
+
+	if request.Chat.Encrypted && request.InitialMessage.MessageWithData.MessageDB.Content != nil {
+		return response, model.ErrChatEncrypted
+	}
+
+	// the data key belongs to the chat, so the content is encrypted only once the chat exists
+	messageIDs, err := s.repoMessages.ReserveMessageIDs(ctx, 1)
 	if err != nil {
 		return response, err
 	}
+	messageID := messageIDs[0]
+	request.InitialMessage.MessageWithData.MessageDB.MessageID = messageID
 
-	var chatID int64
-	var chatCreatedAt time.Time
+	if content := request.InitialMessage.MessageWithData.MessageDB.Content; content != nil {
+		encrypted, err := s.encrypter.Encrypt(ctx, chatID, messageID, *content)
+		if err != nil {
+			return response, err
+		}
+		request.InitialMessage.MessageWithData.MessageDB.Content = &encrypted
+	}
+	entities, err := model.MarshalEntities(request.InitialMessage.MessageWithData.Entities)
+	if err != nil {
+		return response, err
+	}
+	if entities != nil {
+		encrypted, err := s.encrypter.Encrypt(ctx, chatID, messageID, *entities)
+		if err != nil {
+			return response, err
+		}
+		request.InitialMessage.MessageWithData.MessageDB.EncryptedEntities = &encrypted
+	}
+
+	request.InitialMessage.MessageWithData.MessageDB.ExpiresAt = model.MessageExpiresAt(request.InitialMessage.TTL, request.Chat.MessageTTL, time.Now().UTC())
+
+	if err := resolveUploads(ctx, s.repoFiles, s.repoMedia, request.InitialMessage.MessageWithData.MessageDB.SenderID, &request.InitialMessage.MessageWithData); err != nil {
+		return response, err
+	}
+
+	messageID, messageCreatedAt, err := s.repoMessages.SetMessage(ctx, request.InitialMessage.MessageWithData.MessageDB)
+	if err != nil {
+		return response, err
+	}
 
 	runParallel := func(task func() error) {
 		wg.Add(1)
@@ -74,19 +166,15 @@ func (s *ChatService) CreatePrivateChat(ctx context.Context, request model.Creat
 	}
 
 	runParallel(func() error {
-		if request.InitialMessage.MessageWithData.MessageDB.Type == model.MESSAGE_FILE || request.InitialMessage.MessageWithData.MessageDB.Type == model.MESSAGE_MIXED {
+		if request.InitialMessage.MessageWithData.MessageDB.Type == model.MESSAGE_FILE || request.InitialMessage.MessageWithData.MessageDB.Type == model.MESSAGE_MIXED ||
+			request.InitialMessage.MessageWithData.MessageDB.Type == model.MESSAGE_VOICE {
 			if request.InitialMessage.MessageWithData.Files == nil {
 				return model.ErrFilesIsEmpty
 			}
 			for _, file := range *request.InitialMessage.MessageWithData.Files {
-				fileID, err := s.repoFiles.SetFile(ctx, file)
-				if err != nil {
-					return model.ErrUploadFile
-				}
-				if err = s.repoMessages.SetBindMessageFile(ctx, messageID, fileID); err != nil {
+				if err := s.repoMessages.SetBindMessageFile(ctx, messageID, file.FileID); err != nil {
 					return err
 				}
-				file.FileID = fileID
 
 				mu.Lock()
 				if response.Message.MessageWithData.Files == nil {
@@ -105,14 +193,9 @@ func (s *ChatService) CreatePrivateChat(ctx context.Context, request model.Creat
 				return model.ErrMediaIsEmpty
 			}
 			for _, media := range *request.InitialMessage.MessageWithData.Media {
-				mediaID, err := s.repoMedia.SetMedia(ctx, media)
-				if err != nil {
-					return model.ErrUploadFile
-				}
-				if err = s.repoMessages.SetBindMessageMedia(ctx, messageID, mediaID); err != nil {
+				if err := s.repoMessages.SetBindMessageMedia(ctx, messageID, media.MediaID); err != nil {
 					return err
 				}
-				media.MediaID = mediaID
 
 				mu.Lock()
 				if response.Message.MessageWithData.Media == nil {
@@ -152,65 +235,13 @@ func (s *ChatService) CreatePrivateChat(ctx context.Context, request model.Creat
 	})
 
 	runParallel(func() error {
-		tmpChatID, tmpCreatedAt, err := s.repoChats.SetChat(ctx, request.Chat)
-		if err == nil {
-			mu.Lock()
-			chatID = tmpChatID
-			chatCreatedAt = tmpCreatedAt
-			mu.Unlock()
-		}
-		return err
+		return s.repoMessages.SetBindMessageChat(ctx, messageID, chatID)
 	})
 
 	wg.Wait()
 	close(errChan)
 
-	var firstErr error
 	for err := range errChan {
-		if firstErr == nil {
-			firstErr = err
-		}
-	}
-	if firstErr != nil {
-		return response, firstErr
-	}
-
-	var postWg sync.WaitGroup
-	postErrChan := make(chan error, 2)
-
-	runPost := func(task func() error) {
-		postWg.Add(1)
-		// Run post-creation task in a goroutine
-		go func() {
-			defer postWg.Done()
-			if err := task(); err != nil {
-				select {
-				case postErrChan <- err:
-				default:
-				}
-				cancel()
-			}
-		}()
-	}
-
-	runPost(func() error {
-		return s.repoMessages.SetBindMessageChat(ctx, messageID, chatID)
-	})
-
-	runPost(func() error {
-		if err := s.repoChats.SetParticipant(ctx, chatID, request.Chat.CreatorID); err != nil {
-			return err
-		}
-		if err := s.repoChats.SetParticipant(ctx, chatID, request.RecipientID); err != nil {
-			return err
-		}
-		return nil
-	})
-
-	postWg.Wait()
-	close(postErrChan)
-
-	for err := range postErrChan {
 		if err != nil {
 			return response, err
 		}
@@ -218,8 +249,7 @@ func (s *ChatService) CreatePrivateChat(ctx context.Context, request model.Creat
 
 	request.InitialMessage.MessageWithData.MessageDB.MessageID = messageID
 	request.InitialMessage.MessageWithData.MessageDB.CreatedAt = messageCreatedAt
-	request.Chat.ChatID = chatID
-	request.Chat.CreatedAt = chatCreatedAt
+	request.InitialMessage.MessageWithData.MessageDB.UpdatedAt = messageCreatedAt
 
 	response.RecipientID = request.RecipientID
 	response.Message.MessageWithData.MessageDB = request.InitialMessage.MessageWithData.MessageDB
@@ -249,7 +279,16 @@ func (s *ChatService) CreateGroupChat(ctx context.Context, request *model.Create
 		return model.ErrInvalidParamsOfMessage
 	}
 
-	request.Chat.Type = model.CHAT_ACTION_CREATE
+	if !model.IsValidMessageTTL(request.Chat.MessageTTL) {
+		return model.ErrInvalidMessageTTL
+	}
+
+	// polls and the other group features rely on the type, a private chat is created only with its pair of users
+	if request.Chat.Type != model.CHAT_TYPE_GROUP && request.Chat.Type != model.CHAT_TYPE_CHANNEL {
+		return model.ErrInvalidParamsOfChat
+	}
+
+	request.ChatAction.Type = model.CHAT_ACTION_CREATE
 
 	// Save chat and capture generated ID and timestamp
 	chatID, chatCreatedAt, err := s.repoChats.SetChat(ctx, request.Chat)
@@ -303,29 +342,52 @@ func (s *ChatService) CreateGroupChat(ctx context.Context, request *model.Create
 	return nil
 }
 
-func (s *ChatService) loadChatDetails(ctx context.Context, chatDB model.ChatDB) (model.Chat, error) {
+func (s *ChatService) loadChatDetails(ctx context.Context, chatDB model.ChatDB, viewerID string) (model.Chat, error) {
 	var (
 		chat     model.Chat
 		messages []model.Message
-		muMsg    sync.Mutex
-		wgMsg    sync.WaitGroup
 
 		wgMeta  sync.WaitGroup
 		metaErr error
 		metaMu  sync.Mutex
 
-		participantsIDs []string
-		actions         *[]model.ChatAction
-		roles           *[]model.ChatRole
+		participantsIDs  []string
+		subscribersCount *int
+		actions          *[]model.ChatAction
+		roles            *[]model.ChatRole
 	)
 
 	chat.ChatDB = chatDB
 
+	// the subscribers of a channel are counted, and listed along with their joins and leaves to its admins only
+	var hideMembers bool
+	if chatDB.Type == model.CHAT_TYPE_CHANNEL {
+		member, err := s.repoChats.GetChatMember(ctx, chatDB.ChatID, viewerID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return chat, err
+		}
+		hideMembers = err != nil || !member.IsAdmin()
+	}
+
 	wgMeta.Add(3)
 
 	// 1. Get participants
 	go func() {
 		defer wgMeta.Done()
+		if chatDB.Type == model.CHAT_TYPE_CHANNEL {
+			count, err := s.repoChannels.CountSubscribers(ctx, chatDB.ChatID)
+			if err != nil {
+				metaMu.Lock()
+				metaErr = err
+				metaMu.Unlock()
+				return
+			}
+			subscribersCount = &count
+		}
+		if hideMembers {
+			return
+		}
+
 		result, err := s.repoChats.GetAllParticipantsByChatID(ctx, chatDB.ChatID)
 		if err != nil && !errors.Is(err, sql.ErrNoRows) {
 			metaMu.Lock()
@@ -341,7 +403,15 @@ func (s *ChatService) loadChatDetails(ctx context.Context, chatDB model.ChatDB)
 	// 2. Get chat actions
 	go func() {
 		defer wgMeta.Done()
-		result, err := s.repoChats.GetAllActionsWithLimit(ctx, chatDB.ChatID, model.ACTION_LIMIT_REQUEST)
+		var (
+			result []model.ChatAction
+			err    error
+		)
+		if hideMembers {
+			result, err = s.repoChats.GetActionsByTypesWithLimit(ctx, chatDB.ChatID, model.CHAT_ACTIONS_OF_CHAT, model.ACTION_LIMIT_REQUEST)
+		} else {
+			result, err = s.repoChats.GetAllActionsWithLimit(ctx, chatDB.ChatID, model.ACTION_LIMIT_REQUEST)
+		}
 		if err != nil && !errors.Is(err, sql.ErrNoRows) {
 			metaMu.Lock()
 			metaErr = err
@@ -373,90 +443,7 @@ func (s *ChatService) loadChatDetails(ctx context.Context, chatDB model.ChatDB)
 		return chat, err
 	}
 
-	for _, messageDB := range messagesDB {
-		wgMsg.Add(1)
-		go func(messageDB model.MessageDB) {
-			defer wgMsg.Done()
-			var message model.Message
-			message.MessageWithData.MessageDB = messageDB
-
-			var innerWg sync.WaitGroup
-			innerWg.Add(5)
-
-			// 1. Media
-			go func() {
-				defer innerWg.Done()
-				media, err := s.repoMedia.GetMediaFileByMessageID(ctx, messageDB.MessageID)
-				if err != nil && !errors.Is(err, sql.ErrNoRows) {
-					logger.Warnf("Failed to load media, err: %s", err)
-					return
-				}
-				if err == nil {
-					message.MessageWithData.Media = &media
-				}
-			}()
-
-			// 2. Location
-			go func() {
-				defer innerWg.Done()
-				loc, err := s.repoLocations.GetLocationsByMessageID(ctx, messageDB.MessageID)
-				if err != nil && !errors.Is(err, sql.ErrNoRows) {
-					logger.Warnf("Failed to load location, err: %s", err)
-					return
-				}
-				if err == nil {
-					message.MessageWithData.Locations = &loc
-				}
-			}()
-
-			// 3. Files
-			go func() {
-				defer innerWg.Done()
-				files, err := s.repoFiles.GetFilesByMessageID(ctx, messageDB.MessageID)
-				if err != nil && !errors.Is(err, sql.ErrNoRows) {
-					logger.Warnf("Failed to load files, err: %s", err)
-					return
-				}
-				if err == nil {
-					message.MessageWithData.Files = &files
-				}
-			}()
-
-			// 4. PinnedMessage
-			go func() {
-				defer innerWg.Done()
-				pinned, err := s.repoPinned.GetPinnedMessageByMessageID(ctx, messageDB.MessageID)
-				if err != nil && !errors.Is(err, sql.ErrNoRows) {
-					logger.Warnf("Failed to load pinned messages, err: %s", err)
-					return
-				}
-				if err == nil {
-					message.PinnedMessage = &pinned
-				}
-			}()
-
-			// 5. Actions
-			go func() {
-				defer innerWg.Done()
-				acts, err := s.repoMessages.GetAllActions(ctx, messageDB.MessageID)
-				if err != nil && !errors.Is(err, sql.ErrNoRows) {
-					logger.Warnf("Failed to load message action, err: %s", err)
-					return
-				}
-				if err == nil {
-					message.Action = &acts
-				}
-			}()
-
-			innerWg.Wait()
-
-			muMsg.Lock()
-			messages = append(messages, message)
-			muMsg.Unlock()
-		}(messageDB)
-	}
-
-	wgMsg.Wait()
+	messages = s.loadMessages(ctx, messagesDB, viewerID)
 
 	wgMeta.Wait()
 	if metaErr != nil {
@@ -465,12 +452,220 @@ func (s *ChatService) loadChatDetails(ctx context.Context, chatDB model.ChatDB)
 
 	chat.Messages = messages
 	chat.ParticipantsIDs = participantsIDs
+	chat.SubscribersCount = subscribersCount
 	chat.ChatAction = actions
 	chat.ChatRoles = roles
 
 	return chat, nil
 }
 
+// loadMessages loads attachments and metadata of the messages keeping their order.
+func (s *ChatService) loadMessages(ctx context.Context, messagesDB []model.MessageDB, viewerID string) []model.Message {
+	var wgMsg sync.WaitGroup
+	messages := make([]model.Message, len(messagesDB))
+
+	for i, messageDB := range messagesDB {
+		wgMsg.Add(1)
+		go func(i int, messageDB model.MessageDB) {
+			defer wgMsg.Done()
+			messages[i] = s.loadMessageDetails(ctx, messageDB, viewerID)
+		}(i, messageDB)
+	}
+
+	wgMsg.Wait()
+	return messages
+}
+
+func (s *ChatService) loadMessageDetails(ctx context.Context, messageDB model.MessageDB, viewerID string) model.Message {
+	var message model.Message
+	message.MessageWithData.MessageDB = messageDB
+
+	var innerWg sync.WaitGroup
+	innerWg.Add(13)
+
+	// 1. Media
+	go func() {
+		defer innerWg.Done()
+		media, err := s.repoMedia.GetMediaFileByMessageID(ctx, messageDB.MessageID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			logger.Warnf("Failed to load media, err: %s", err)
+			return
+		}
+		if err == nil {
+			message.MessageWithData.Media = &media
+		}
+	}()
+
+	// 2. Location
+	go func() {
+		defer innerWg.Done()
+		loc, err := s.repoLocations.GetLocationsByMessageID(ctx, messageDB.MessageID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			logger.Warnf("Failed to load location, err: %s", err)
+			return
+		}
+		if err == nil {
+			message.MessageWithData.Locations = &loc
+		}
+	}()
+
+	// 3. Files
+	go func() {
+		defer innerWg.Done()
+		files, err := s.repoFiles.GetFilesByMessageID(ctx, messageDB.MessageID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			logger.Warnf("Failed to load files, err: %s", err)
+			return
+		}
+		if err == nil {
+			message.MessageWithData.Files = &files
+		}
+	}()
+
+	// 4. PinnedMessage
+	go func() {
+		defer innerWg.Done()
+		pinned, err := s.repoPinned.GetPinnedMessageByMessageID(ctx, messageDB.MessageID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			logger.Warnf("Failed to load pinned messages, err: %s", err)
+			return
+		}
+		if err == nil {
+			message.PinnedMessage = &pinned
+		}
+	}()
+
+	// 5. Actions
+	go func() {
+		defer innerWg.Done()
+		acts, err := s.repoMessages.GetAllActions(ctx, messageDB.MessageID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			logger.Warnf("Failed to load message action, err: %s", err)
+			return
+		}
+		if err == nil {
+			message.Action = &acts
+		}
+	}()
+
+	// 6. Reactions
+	go func() {
+		defer innerWg.Done()
+		reactions, err := s.repoReactions.GetReactionsByMessageID(ctx, messageDB.MessageID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			logger.Warnf("Failed to load message reactions, err: %s", err)
+			return
+		}
+		if err == nil {
+			counts := model.AggregateReactions(reactions, viewerID)
+			message.Reactions = &counts
+		}
+	}()
+
+	// 7. Quoted message
+	go func() {
+		defer innerWg.Done()
+		if messageDB.ReplyToMessageID == nil {
+			return
+		}
+		parent, err := s.repoMessages.GetMessageByMessageID(ctx, *messageDB.ReplyToMessageID)
+		if err != nil {
+			logger.Warnf("Failed to load quoted message, err: %s", err)
+			return
+		}
+		message.MessageWithData.ReplyTo = model.NewQuotedMessage(parent)
+	}()
+
+	// 8. Thread
+	go func() {
+		defer innerWg.Done()
+		thread, err := s.repoMessages.GetThread(ctx, messageDB.MessageID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			logger.Warnf("Failed to load message thread, err: %s", err)
+			return
+		}
+		if err == nil {
+			message.Thread = &thread
+		}
+	}()
+
+	// 9. Listens of the voice message
+	go func() {
+		defer innerWg.Done()
+		if messageDB.Type != model.MESSAGE_VOICE {
+			return
+		}
+		listens, err := s.repoVoice.GetVoiceListensByMessageID(ctx, messageDB.MessageID)
+		if err != nil {
+			logger.Warnf("Failed to load voice listens, err: %s", err)
+			return
+		}
+		message.Listens = &listens
+	}()
+
+	// 10. Poll with the results seen by the viewer
+	go func() {
+		defer innerWg.Done()
+		if messageDB.Type != model.MESSAGE_POLL {
+			return
+		}
+		poll, err := s.repoPolls.GetPoll(ctx, messageDB.MessageID)
+		if err != nil {
+			logger.Warnf("Failed to load poll, err: %s", err)
+			return
+		}
+		votes, err := s.repoPolls.GetPollVotes(ctx, messageDB.MessageID)
+		if err != nil {
+			logger.Warnf("Failed to load poll votes, err: %s", err)
+			return
+		}
+		poll = poll.ForViewer(votes, viewerID)
+		message.MessageWithData.Poll = &poll
+	}()
+
+	// 11. Mentions
+	go func() {
+		defer innerWg.Done()
+		mentions, err := s.repoMentions.GetMentionsByMessageID(ctx, messageDB.MessageID)
+		if err != nil {
+			logger.Warnf("Failed to load mentions, err: %s", err)
+			return
+		}
+		if len(mentions) > 0 {
+			message.MessageWithData.Mentions = &mentions
+		}
+	}()
+
+	// 12. Link preview, decrypted by the caller like the content
+	go func() {
+		defer innerWg.Done()
+		preview, err := s.repoPreviews.GetLinkPreview(ctx, messageDB.MessageID)
+		if err != nil {
+			if !errors.Is(err, sql.ErrNoRows) {
+				logger.Warnf("Failed to load link preview, err: %s", err)
+			}
+			return
+		}
+		message.MessageWithData.EncryptedLinkPreview = &preview
+	}()
+
+	// 13. Views and the signature of the post of a channel
+	go func() {
+		defer innerWg.Done()
+		post, err := s.repoChannels.GetChannelPost(ctx, messageDB.MessageID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			logger.Warnf("Failed to load channel post, err: %s", err)
+			return
+		}
+		if err == nil {
+			message.MessageWithData.Post = &post
+		}
+	}()
+
+	innerWg.Wait()
+	return message
+}
+
 func (s *ChatService) InitializeChatsForMessenger(ctx context.Context, userID string) ([]model.Chat, error) {
 	var (
 		chats   []model.Chat
@@ -489,7 +684,7 @@ func (s *ChatService) InitializeChatsForMessenger(ctx context.Context, userID st
 		go func(chatDB model.ChatDB) {
 			defer wgChat.Done()
 
-			chat, err := s.loadChatDetails(ctx, chatDB)
+			chat, err := s.loadChatDetails(ctx, chatDB, userID)
 			if err != nil {
 				select {
 				case errChan <- err:
@@ -547,7 +742,7 @@ func (s *ChatService) InitializePinnedChatsForMessenger(ctx context.Context, use
 				return
 			}
 
-			chat, err := s.loadChatDetails(ctx, chatDB)
+			chat, err := s.loadChatDetails(ctx, chatDB, userID)
 			if err != nil {
 				select {
 				case errChan <- err:
@@ -583,6 +778,56 @@ func (s *ChatService) InitializePinnedChatsForMessenger(ctx context.Context, use
 	}
 }
 
+func (s *ChatService) GetChatHistory(ctx context.Context, chatID int64, userID string, beforeMessageID int64, limit int) ([]model.Message, error) {
+	isParticipant, err := s.repoChats.IsParticipantExists(ctx, chatID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !isParticipant {
+		return nil, model.ErrNotParticipant
+	}
+
+	if limit <= 0 || limit > model.MESSAGE_LIMIT_REQUEST {
+		limit = model.MESSAGE_LIMIT_REQUEST
+	}
+
+	messagesDB, err := s.repoMessages.GetMessagesByChatIDBefore(ctx, chatID, beforeMessageID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.loadMessages(ctx, messagesDB, userID), nil
+}
+
+func (s *ChatService) GetThreadHistory(ctx context.Context, chatID, threadID int64, userID string, beforeMessageID int64, limit int) ([]model.Message, error) {
+	isParticipant, err := s.repoChats.IsParticipantExists(ctx, chatID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !isParticipant {
+		return nil, model.ErrNotParticipant
+	}
+
+	inChat, err := s.repoMessages.IsMessageInChat(ctx, threadID, chatID)
+	if err != nil {
+		return nil, err
+	}
+	if !inChat {
+		return nil, model.ErrMessageNotFound
+	}
+
+	if limit <= 0 || limit > model.MESSAGE_LIMIT_REQUEST {
+		limit = model.MESSAGE_LIMIT_REQUEST
+	}
+
+	messagesDB, err := s.repoMessages.GetThreadMessages(ctx, threadID, beforeMessageID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.loadMessages(ctx, messagesDB, userID), nil
+}
+
 func (s *ChatService) GetParticipantsOfChat(ctx context.Context, chatID int64) ([]string, error) {
 	return s.repoChats.GetAllParticipantsByChatID(ctx, chatID)
 }
@@ -611,10 +856,121 @@ func (s *ChatService) UpdatePinnedChat(ctx context.Context, pinnedChatWithFlag m
 	return nil
 }
 
+// SetChatRole lets the granter give only the roles lower than their own to the members of a lower role
 func (s *ChatService) SetChatRole(ctx context.Context, chatRole model.ChatRole) error {
+	if !model.IsValidChatRole(chatRole.Role) {
+		return model.ErrInvalidChatRole
+	}
+	if chatRole.Role != model.CHAT_ROLE_RESTRICTED {
+		chatRole.Permissions = nil
+	} else if chatRole.Permissions != nil && !model.IsValidPermissions(*chatRole.Permissions) {
+		return model.ErrInvalidChatRole
+	}
+
+	if _, err := getGroupChat(ctx, s.repoChats, chatRole.ChatID); err != nil {
+		return err
+	}
+
+	granter, err := checkChatPermission(ctx, s.repoChats, chatRole.ChatID, chatRole.GranterID, model.PERMISSION_MANAGE_ROLES)
+	if err != nil {
+		return err
+	}
+
+	member, err := getChatMember(ctx, s.repoChats, chatRole.ChatID, chatRole.UserID)
+	if err != nil {
+		if errors.Is(err, model.ErrNotParticipant) {
+			return model.ErrMemberNotFound
+		}
+		return err
+	}
+	if !granter.Outranks(member.Role()) || !granter.Outranks(chatRole.Role) {
+		return model.ErrChatPermissionDenied
+	}
+
 	return s.repoChats.SetChatRole(ctx, chatRole)
 }
 
+func (s *ChatService) GetChatPermissions(ctx context.Context, chatID int64, userID string) (model.ChatPermissionsResponse, error) {
+	member, err := getChatMember(ctx, s.repoChats, chatID, userID)
+	if err != nil {
+		return model.ChatPermissionsResponse{}, err
+	}
+
+	return model.ChatPermissionsResponse{
+		Role:               member.Role(),
+		Permissions:        member.Permissions(),
+		DefaultPermissions: member.DefaultPermissions,
+	}, nil
+}
+
+// SetDefaultPermissions changes what the members without a role can do, returns the updated chat
+func (s *ChatService) SetDefaultPermissions(ctx context.Context, request model.DefaultPermissionsRequest) (model.ChatDB, error) {
+	if !model.IsValidPermissions(request.Permissions) {
+		return model.ChatDB{}, model.ErrInvalidChatRole
+	}
+
+	if _, err := getGroupChatWithPermission(ctx, s.repoChats, request.ChatID, request.UserID, model.PERMISSION_MANAGE_ROLES); err != nil {
+		return model.ChatDB{}, err
+	}
+
+	if err := s.repoChats.UpdateDefaultPermissions(ctx, request.ChatID, request.Permissions); err != nil {
+		return model.ChatDB{}, err
+	}
+	return s.repoChats.GetChatByChatID(ctx, request.ChatID)
+}
+
+func (s *ChatService) PinMessage(ctx context.Context, pinnedMessage model.PinnedMessage) error {
+	if pinnedMessage.Priority != nil && (*pinnedMessage.Priority < 1 || *pinnedMessage.Priority > model.PINNED_MESSAGE_PRIORITY_MAX) {
+		return model.ErrInvalidParamsOfMessage
+	}
+
+	if err := s.checkPin(ctx, pinnedMessage); err != nil {
+		return err
+	}
+
+	return s.repoPinned.SetPinnedMessage(ctx, pinnedMessage)
+}
+
+func (s *ChatService) UnpinMessage(ctx context.Context, pinnedMessage model.PinnedMessage) error {
+	if err := s.checkPin(ctx, pinnedMessage); err != nil {
+		return err
+	}
+
+	return s.repoPinned.DeletePinnedMessage(ctx, pinnedMessage)
+}
+
+func (s *ChatService) checkPin(ctx context.Context, pinnedMessage model.PinnedMessage) error {
+	if _, err := checkChatPermission(ctx, s.repoChats, pinnedMessage.ChatID, pinnedMessage.PinnedByUserID, model.PERMISSION_PIN_MESSAGES); err != nil {
+		return err
+	}
+
+	inChat, err := s.repoMessages.IsMessageInChat(ctx, pinnedMessage.MessageID, pinnedMessage.ChatID)
+	if err != nil {
+		return err
+	}
+	if !inChat {
+		return model.ErrMessageNotFound
+	}
+	return nil
+}
+
+func (s *ChatService) SetMessageTTL(ctx context.Context, request model.MessageTTLRequest) error {
+	if !model.IsValidMessageTTL(request.MessageTTL) {
+		return model.ErrInvalidMessageTTL
+	}
+
+	if _, err := checkChatPermission(ctx, s.repoChats, request.ChatID, request.UserID, model.PERMISSION_CHANGE_INFO); err != nil {
+		return err
+	}
+
+	// zero and null both mean the messages stay
+	if request.MessageTTL != nil && *request.MessageTTL == 0 {
+		request.MessageTTL = nil
+	}
+
+	return s.repoChats.UpdateMessageTTL(ctx, request.ChatID, request.MessageTTL)
+}
+
 func (s *ChatService) SetBlockChat(ctx context.Context, blockChat model.BlockChat) error {
 	exists, err := s.repoChats.IsBlockedChatExists(ctx, blockChat.ChatID, blockChat.UserID)
 	if err != nil {
@@ -627,6 +983,14 @@ func (s *ChatService) SetBlockChat(ctx context.Context, blockChat model.BlockCha
 		}
 	} else {
 		if exists {
+			// the banned user can't lift the ban to come back
+			banned, err := s.repoChats.IsUserBanned(ctx, blockChat.ChatID, blockChat.UserID)
+			if err != nil {
+				return err
+			}
+			if banned {
+				return model.ErrUserBanned
+			}
 			return s.repoChats.DeleteBlockUser(ctx, blockChat.ChatID, blockChat.UserID)
 		}
 	}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"chat-api/internal/model"
+	repo "chat-api/internal/repository/psql"
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+)
+
+type ChatListService struct {
+	repoChatList repo.ChatList
+	repoChats    repo.Chats
+	repoMessages repo.Messages
+}
+
+func NewChatListService(chatList repo.ChatList, chats repo.Chats, messages repo.Messages) *ChatListService {
+	return &ChatListService{
+		repoChatList: chatList,
+		repoChats:    chats,
+		repoMessages: messages,
+	}
+}
+
+// GetChatList returns the page of the main list, of the archive or of the folder of the user
+func (s *ChatListService) GetChatList(ctx context.Context, request model.ChatListRequest) ([]model.ChatListItem, error) {
+	filter := model.NewChatListFilter(request.Archived)
+	if request.FolderID != 0 {
+		folder, err := s.getChatFolder(ctx, request.FolderID, request.UserID)
+		if err != nil {
+			return nil, err
+		}
+		filter = folder.Filter()
+	}
+
+	if request.Limit <= 0 || request.Limit > model.CHAT_LIST_LIMIT_REQUEST {
+		request.Limit = model.CHAT_LIST_LIMIT_REQUEST
+	}
+	if request.Offset < 0 {
+		request.Offset = 0
+	}
+
+	chats, err := s.repoChatList.GetChatList(ctx, request.UserID, filter, request.Offset, request.Limit)
+	if err != nil {
+		return nil, err
+	}
+	if chats == nil {
+		chats = []model.ChatListItem{}
+	}
+	return chats, nil
+}
+
+func (s *ChatListService) UpdateChatState(ctx context.Context, request model.UpdateChatStateRequest) (model.ChatState, error) {
+	if !request.IsValid() {
+		return model.ChatState{}, model.ErrInvalidChatState
+	}
+
+	if _, err := getChatMember(ctx, s.repoChats, request.ChatID, request.UserID); err != nil {
+		return model.ChatState{}, err
+	}
+
+	return s.repoChatList.UpsertChatState(ctx, request)
+}
+
+// ReadChat marks the messages of the chat read up to the given one, the read mark never goes back
+func (s *ChatListService) ReadChat(ctx context.Context, request model.ReadChatRequest) (model.ChatState, error) {
+	if _, err := getChatMember(ctx, s.repoChats, request.ChatID, request.UserID); err != nil {
+		return model.ChatState{}, err
+	}
+
+	inChat, err := s.repoMessages.IsMessageInChat(ctx, request.MessageID, request.ChatID)
+	if err != nil {
+		return model.ChatState{}, err
+	}
+	if !inChat {
+		return model.ChatState{}, model.ErrMessageNotFound
+	}
+
+	return s.repoChatList.SetChatRead(ctx, request.ChatID, request.UserID, request.MessageID)
+}
+
+func (s *ChatListService) GetChatFolders(ctx context.Context, userID string) ([]model.ChatFolder, error) {
+	folders, err := s.repoChatList.GetChatFoldersByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if folders == nil {
+		folders = []model.ChatFolder{}
+	}
+	return folders, nil
+}
+
+func (s *ChatListService) CreateChatFolder(ctx context.Context, folder model.ChatFolder) (model.ChatFolder, error) {
+	folder.Name = strings.TrimSpace(folder.Name)
+	if !folder.IsValid() {
+		return folder, model.ErrInvalidChatFolder
+	}
+
+	count, err := s.repoChatList.CountChatFolders(ctx, folder.UserID)
+	if err != nil {
+		return folder, err
+	}
+	if count >= model.CHAT_FOLDERS_LIMIT {
+		return folder, model.ErrChatFoldersLimit
+	}
+
+	return s.repoChatList.SetChatFolder(ctx, folder)
+}
+
+func (s *ChatListService) UpdateChatFolder(ctx context.Context, folder model.ChatFolder) (model.ChatFolder, error) {
+	folder.Name = strings.TrimSpace(folder.Name)
+	if !folder.IsValid() {
+		return folder, model.ErrInvalidChatFolder
+	}
+
+	updated, err := s.repoChatList.UpdateChatFolder(ctx, folder)
+	if errors.Is(err, sql.ErrNoRows) {
+		return folder, model.ErrChatFolderNotFound
+	}
+	return updated, err
+}
+
+func (s *ChatListService) DeleteChatFolder(ctx context.Context, folderID int64, userID string) error {
+	err := s.repoChatList.DeleteChatFolder(ctx, folderID, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.ErrChatFolderNotFound
+	}
+	return err
+}
+
+func (s *ChatListService) getChatFolder(ctx context.Context, folderID int64, userID string) (model.ChatFolder, error) {
+	folder, err := s.repoChatList.GetChatFolder(ctx, folderID, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return folder, model.ErrChatFolderNotFound
+	}
+	return folder, err
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"chat-api/internal/model"
+	"chat-api/internal/repository/cache"
+	repo "chat-api/internal/repository/psql"
+	"context"
+	"time"
+)
+
+// E2EEService keeps public keys and relays ciphertext, it never sees the plain content
+type E2EEService struct {
+	repoKeys  repo.Keys
+	repoChats repo.Chats
+	cache     cache.KeysCache
+}
+
+func NewE2EEService(keys repo.Keys, chats repo.Chats, cache cache.KeysCache) *E2EEService {
+	return &E2EEService{
+		repoKeys:  keys,
+		repoChats: chats,
+		cache:     cache,
+	}
+}
+
+func (s *E2EEService) PublishDeviceKeys(ctx context.Context, keys model.DeviceKeys) error {
+	if keys.UserID == "" || keys.DeviceID <= 0 || keys.IdentityKey == "" ||
+		keys.SignedPreKey.PublicKey == "" || keys.SignedPreKey.Signature == "" ||
+		len(keys.OneTimePreKeys) > model.ONE_TIME_PREKEYS_LIMIT {
+		return model.ErrInvalidDeviceKeys
+	}
+
+	for _, preKey := range keys.OneTimePreKeys {
+		if preKey.PublicKey == "" {
+			return model.ErrInvalidDeviceKeys
+		}
+	}
+
+	return s.repoKeys.SetDeviceKeys(ctx, keys)
+}
+
+// GetPreKeyBundles hands out the bundles of the user to the caller sharing a private or group chat with them,
+// every fetch takes a one-time prekey of each device so the fetches are limited per caller and user.
+// The bundle of the device running out of one-time prekeys is marked as low on them.
+func (s *E2EEService) GetPreKeyBundles(ctx context.Context, callerID, userID string) ([]model.PreKeyBundle, error) {
+	// the other devices of the caller are always reachable
+	if callerID != userID {
+		shared, err := s.repoChats.IsSharedChatExists(ctx, callerID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if !shared {
+			return nil, model.ErrNotParticipant
+		}
+
+		allowed, err := s.cache.AllowPreKeyFetch(ctx, callerID, userID, model.PREKEY_BUNDLES_FETCH_LIMIT, model.PREKEY_BUNDLES_FETCH_WINDOW)
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			return nil, model.ErrPreKeyFetchLimit
+		}
+	}
+
+	bundles, err := s.repoKeys.GetPreKeyBundles(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(bundles) == 0 {
+		return nil, model.ErrDeviceKeysNotFound
+	}
+
+	for i := range bundles {
+		bundles[i].LowOnPreKeys = bundles[i].OneTimePreKeysLeft < model.ONE_TIME_PREKEYS_LOW
+	}
+
+	return bundles, nil
+}
+
+func (s *E2EEService) CountOneTimePreKeys(ctx context.Context, userID string, deviceID int) (int, error) {
+	return s.repoKeys.CountOneTimePreKeys(ctx, userID, deviceID)
+}
+
+// SendEncryptedMessage stores the envelopes of the message for an encrypted chat,
+// every envelope must be addressed to a participant of the chat.
+func (s *E2EEService) SendEncryptedMessage(ctx context.Context, request *model.EncryptedMessageRequest) error {
+	message := &request.Message
+	if message.SenderID == "" || message.Type == "" || request.SenderDeviceID <= 0 {
+		return model.ErrInvalidParamsOfMessage
+	}
+
+	chatDB, err := s.repoChats.GetChatByChatID(ctx, request.ChatID)
+	if err != nil {
+		return err
+	}
+	if !chatDB.Encrypted {
+		return model.ErrChatNotEncrypted
+	}
+
+	blocked, err := s.repoChats.IsBlockedChatExists(ctx, request.ChatID, message.SenderID)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return model.ErrChatBlocked
+	}
+
+	if _, err := checkChatPermission(ctx, s.repoChats, request.ChatID, message.SenderID, model.MessagePermissions(message.Type)); err != nil {
+		return err
+	}
+
+	participantsIDs, err := s.repoChats.GetAllParticipantsByChatID(ctx, request.ChatID)
+	if err != nil {
+		return err
+	}
+
+	participants := make(map[string]bool, len(participantsIDs))
+	for _, participantID := range participantsIDs {
+		participants[participantID] = true
+	}
+	if !participants[message.SenderID] {
+		return model.ErrNotParticipant
+	}
+
+	if len(request.Envelopes) == 0 {
+		return model.ErrInvalidEnvelopes
+	}
+	for _, envelope := range request.Envelopes {
+		if !participants[envelope.RecipientID] || envelope.DeviceID <= 0 || envelope.Ciphertext == "" {
+			return model.ErrInvalidEnvelopes
+		}
+	}
+
+	message.Content = nil
+	message.Status = model.MESSAGE_SENT
+	message.ExpiresAt = model.MessageExpiresAt(nil, chatDB.MessageTTL, time.Now().UTC())
+
+	message.MessageID, message.CreatedAt, err = s.repoKeys.SetEncryptedMessage(ctx, *message, request.ChatID, request.SenderDeviceID, request.Envelopes)
+	if err != nil {
+		return err
+	}
+	message.UpdatedAt = message.CreatedAt
+
+	return nil
+}
+
+func (s *E2EEService) GetEncryptedHistory(ctx context.Context, chatID int64, userID string, deviceID int, beforeMessageID int64, limit int) ([]model.EncryptedMessage, error) {
+	isParticipant, err := s.repoChats.IsParticipantExists(ctx, chatID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !isParticipant {
+		return nil, model.ErrNotParticipant
+	}
+
+	if limit <= 0 || limit > model.MESSAGE_LIMIT_REQUEST {
+		limit = model.MESSAGE_LIMIT_REQUEST
+	}
+
+	messagesDB, err := s.repoKeys.GetEncryptedMessages(ctx, chatID, userID, deviceID, beforeMessageID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]model.EncryptedMessage, 0, len(messagesDB))
+	for _, messageDB := range messagesDB {
+		messages = append(messages, messageDB.ToEncryptedMessage(chatID))
+	}
+
+	return messages, nil
+}
@@ -0,0 +1,404 @@
+package service
+
+import (
+	"chat-api/internal/model"
+	repo "chat-api/internal/repository/psql"
+	"chat-api/pkg/broker"
+	"chat-api/pkg/logger"
+	"chat-api/pkg/storage"
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type ExportService struct {
+	repoExports   repo.Exports
+	repoMessages  repo.Messages
+	repoChats     repo.Chats
+	repoChannels  repo.Channels
+	repoUploads   repo.Uploads
+	storage       storage.Storage
+	notifications Notifications
+	chats         *ChatService
+}
+
+func NewExportService(exports repo.Exports, messages repo.Messages, chats repo.Chats, channels repo.Channels, uploads repo.Uploads, storage storage.Storage, notifications Notifications, chatService *ChatService) *ExportService {
+	return &ExportService{
+		repoExports:   exports,
+		repoMessages:  messages,
+		repoChats:     chats,
+		repoChannels:  channels,
+		repoUploads:   uploads,
+		storage:       storage,
+		notifications: notifications,
+		chats:         chatService,
+	}
+}
+
+// CreateExport queues the archive of the chat or of all chats of the user, one export of the user runs at a time
+func (s *ExportService) CreateExport(ctx context.Context, request model.CreateExportRequest) (model.Export, error) {
+	if request.ChatID != nil {
+		if _, err := s.exportedChat(ctx, *request.ChatID, request.UserID); err != nil {
+			return model.Export{}, err
+		}
+	}
+
+	unfinished, err := s.repoExports.CountUnfinishedExports(ctx, request.UserID)
+	if err != nil {
+		return model.Export{}, err
+	}
+	if unfinished > 0 {
+		return model.Export{}, model.ErrExportInProgress
+	}
+
+	export, err := s.repoExports.SetExport(ctx, model.Export{
+		UserID: request.UserID,
+		ChatID: request.ChatID,
+		Status: model.EXPORT_STATUS_PENDING,
+	})
+	if err != nil {
+		return model.Export{}, err
+	}
+
+	if err := s.notifications.SendNotification(ctx, model.NotificationRabbitMQ{
+		Exchange:   broker.EXCHANGE_CHAT,
+		RoutingKey: broker.ROUTING_KEY_CHAT_EXPORT,
+	}, model.ExportJob{ExportID: export.ExportID}); err != nil {
+		return model.Export{}, err
+	}
+
+	return export, nil
+}
+
+func (s *ExportService) GetExport(ctx context.Context, exportID int64, userID string) (model.Export, error) {
+	export, err := s.repoExports.GetExport(ctx, exportID, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.Export{}, model.ErrExportNotFound
+	}
+	return export, err
+}
+
+func (s *ExportService) GetExports(ctx context.Context, userID string) ([]model.Export, error) {
+	exports, err := s.repoExports.GetExportsByUserID(ctx, userID, model.EXPORTS_LIMIT_REQUEST)
+	if err != nil {
+		return nil, err
+	}
+	if exports == nil {
+		exports = []model.Export{}
+	}
+	return exports, nil
+}
+
+// RunExport builds the archive of the pending export. The content is stored encrypted,
+// decrypt gives the plain messages. A failed export isn't retried, the user asks for another one
+func (s *ExportService) RunExport(ctx context.Context, exportID int64, decrypt func(ctx context.Context, chatID int64, message *model.SendMessage)) (model.Export, error) {
+	export, err := s.repoExports.StartExport(ctx, exportID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Export{}, model.ErrExportNotFound
+		}
+		return model.Export{}, err
+	}
+
+	expiresAt := time.Now().UTC().Add(model.EXPORT_TTL)
+
+	storageKey, size, err := s.buildArchive(ctx, export, decrypt)
+	if err != nil {
+		logger.Error("Failed to build export", zap.Int64("exportID", exportID), zap.Error(err))
+
+		message := "failed to build the archive"
+		if errors.Is(err, model.ErrNotParticipant) || errors.Is(err, model.ErrChatEncrypted) {
+			message = err.Error()
+		}
+		return s.repoExports.FailExport(ctx, exportID, message, expiresAt)
+	}
+
+	return s.repoExports.CompleteExport(ctx, exportID, storageKey, size, expiresAt)
+}
+
+// OpenExport opens the built archive of the user
+func (s *ExportService) OpenExport(ctx context.Context, exportID int64, userID string) (io.ReadCloser, model.Export, error) {
+	export, err := s.GetExport(ctx, exportID, userID)
+	if err != nil {
+		return nil, model.Export{}, err
+	}
+	if export.Status != model.EXPORT_STATUS_READY || export.StorageKey == nil || export.Size == nil {
+		return nil, model.Export{}, model.ErrExportNotReady
+	}
+	if export.ExpiresAt != nil && !export.ExpiresAt.After(time.Now().UTC()) {
+		return nil, model.Export{}, model.ErrExportNotReady
+	}
+
+	content, err := s.storage.Get(ctx, *export.StorageKey)
+	if err != nil {
+		return nil, model.Export{}, err
+	}
+
+	return content, export, nil
+}
+
+// DeleteExpiredExports removes a batch of the expired archives and fails the exports whose worker is gone
+func (s *ExportService) DeleteExpiredExports(ctx context.Context) (int, error) {
+	now := time.Now().UTC()
+	if _, err := s.repoExports.FailStaleExports(ctx, now.Add(-model.EXPORT_RUNNING_TIMEOUT), now.Add(model.EXPORT_TTL)); err != nil {
+		return 0, err
+	}
+
+	exports, err := s.repoExports.GetExpiredExports(ctx, model.EXPORT_BATCH_SIZE)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, export := range exports {
+		if export.StorageKey != nil {
+			if err := s.storage.Delete(ctx, *export.StorageKey); err != nil {
+				return 0, err
+			}
+		}
+		if err := s.repoExports.DeleteExport(ctx, export.ExportID); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(exports), nil
+}
+
+// buildArchive writes the zip into a temporary file and puts it into the storage
+func (s *ExportService) buildArchive(ctx context.Context, export model.Export, decrypt func(ctx context.Context, chatID int64, message *model.SendMessage)) (string, int64, error) {
+	chats, err := s.exportedChats(ctx, export)
+	if err != nil {
+		return "", 0, err
+	}
+
+	file, err := os.CreateTemp("", "chat-export-*.zip")
+	if err != nil {
+		return "", 0, err
+	}
+	defer removeTempFile(file)
+
+	archive := newArchiveWriter(file, s.storage)
+	for _, chat := range chats {
+		if err := s.exportChat(ctx, archive, chat, export.UserID, decrypt); err != nil {
+			return "", 0, err
+		}
+	}
+	if err := archive.close(); err != nil {
+		return "", 0, err
+	}
+
+	size, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return "", 0, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", 0, err
+	}
+
+	storageKey := "exports/" + strconv.FormatInt(export.ExportID, 10) + ".zip"
+	if err := s.storage.Put(ctx, storageKey, file, size, model.FILE_TYPE_ZIP); err != nil {
+		return "", 0, err
+	}
+
+	return storageKey, size, nil
+}
+
+// exportChat pages forward through the chat, the threads included, so the archive reads in the order of sending
+func (s *ExportService) exportChat(ctx context.Context, archive *archiveWriter, chatDB model.ChatDB, userID string, decrypt func(ctx context.Context, chatID int64, message *model.SendMessage)) error {
+	info, err := s.archiveChatInfo(ctx, chatDB, userID)
+	if err != nil {
+		return err
+	}
+
+	chat, err := archive.startChat(info)
+	if err != nil {
+		return err
+	}
+
+	var afterMessageID int64
+	for {
+		messagesDB, err := s.repoMessages.GetMessagesByChatIDAfter(ctx, chatDB.ChatID, afterMessageID, model.EXPORT_MESSAGES_BATCH)
+		if err != nil {
+			chat.close()
+			return err
+		}
+
+		for _, message := range s.chats.loadMessages(ctx, messagesDB, userID) {
+			decrypt(ctx, chatDB.ChatID, &message.MessageWithData)
+
+			archived, err := s.archiveMessage(ctx, chat, message)
+			if err == nil {
+				err = chat.addMessage(archived)
+			}
+			if err != nil {
+				chat.close()
+				return err
+			}
+		}
+
+		if len(messagesDB) < model.EXPORT_MESSAGES_BATCH {
+			break
+		}
+		afterMessageID = messagesDB[len(messagesDB)-1].MessageID
+	}
+
+	return archive.finishChat(ctx, chat)
+}
+
+// archiveChatInfo lists the participants of the chat, the subscribers of a channel are counted and listed to its admins only
+func (s *ExportService) archiveChatInfo(ctx context.Context, chatDB model.ChatDB, userID string) (model.ArchiveChatInfo, error) {
+	info := model.ArchiveChatInfo{
+		ChatID:      chatDB.ChatID,
+		Name:        chatDB.Name,
+		Description: chatDB.Description,
+		Type:        chatDB.Type,
+		CreatedAt:   chatDB.CreatedAt,
+	}
+
+	if chatDB.Type == model.CHAT_TYPE_CHANNEL {
+		count, err := s.repoChannels.CountSubscribers(ctx, chatDB.ChatID)
+		if err != nil {
+			return info, err
+		}
+		info.SubscribersCount = &count
+
+		member, err := getChatMember(ctx, s.repoChats, chatDB.ChatID, userID)
+		if err != nil {
+			return info, err
+		}
+		if !member.IsAdmin() {
+			return info, nil
+		}
+	}
+
+	participantsIDs, err := s.repoChats.GetAllParticipantsByChatID(ctx, chatDB.ChatID)
+	if err != nil {
+		return info, err
+	}
+	info.Participants = participantsIDs
+
+	return info, nil
+}
+
+func (s *ExportService) archiveMessage(ctx context.Context, chat *chatArchive, message model.Message) (model.ArchiveMessage, error) {
+	messageDB := message.MessageWithData.MessageDB
+
+	archived := model.ArchiveMessage{
+		ID:        model.ArchiveMessageID(messageDB.MessageID),
+		Sender:    messageDB.SenderID,
+		Type:      messageDB.Type,
+		CreatedAt: messageDB.CreatedAt,
+		Forwarded: messageDB.ForwardedFromSenderID != nil,
+	}
+	// the imported message goes back under the name it came with
+	if messageDB.ImportedSenderName != nil {
+		archived.Sender = *messageDB.ImportedSenderName
+	}
+	if messageDB.Content != nil {
+		archived.Content = *messageDB.Content
+	}
+	if messageDB.UpdatedAt.After(messageDB.CreatedAt) {
+		editedAt := messageDB.UpdatedAt
+		archived.EditedAt = &editedAt
+	}
+	if messageDB.ReplyToMessageID != nil {
+		archived.ReplyTo = model.ArchiveMessageID(*messageDB.ReplyToMessageID)
+	}
+	if messageDB.ThreadID != nil {
+		archived.ThreadID = model.ArchiveMessageID(*messageDB.ThreadID)
+	}
+	if message.MessageWithData.Locations != nil {
+		archived.Locations = *message.MessageWithData.Locations
+	}
+	if message.MessageWithData.Poll != nil {
+		archived.Poll = message.MessageWithData.Poll
+	}
+
+	if message.MessageWithData.Media != nil {
+		for _, media := range *message.MessageWithData.Media {
+			// the original may still carry the GPS data until the processing is done
+			archivedMedia, err := s.archiveAttachment(ctx, chat, model.UPLOAD_KIND_MEDIA, media.MediaID, media.Type, media.Status != model.MEDIA_STATUS_PENDING)
+			if err != nil {
+				return model.ArchiveMessage{}, err
+			}
+			archived.Attachments = append(archived.Attachments, archivedMedia)
+		}
+	}
+	if message.MessageWithData.Files != nil {
+		for _, file := range *message.MessageWithData.Files {
+			archivedFile, err := s.archiveAttachment(ctx, chat, model.UPLOAD_KIND_FILE, file.FileID, file.Type, true)
+			if err != nil {
+				return model.ArchiveMessage{}, err
+			}
+			archived.Attachments = append(archived.Attachments, archivedFile)
+		}
+	}
+
+	return archived, nil
+}
+
+// archiveAttachment puts the stored content of the attachment into the archive,
+// those stored before the uploads have only the url sent by the client
+func (s *ExportService) archiveAttachment(ctx context.Context, chat *chatArchive, kind string, attachmentID int64, mimeType string, withContent bool) (model.ArchiveAttachment, error) {
+	attachment := model.ArchiveAttachment{Kind: kind, ID: attachmentID, Type: mimeType}
+	if !withContent {
+		return attachment, nil
+	}
+
+	blob, err := s.repoUploads.GetAttachmentBlob(ctx, kind, attachmentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return attachment, nil
+		}
+		return attachment, err
+	}
+
+	attachment.Size = blob.Size
+	attachment.Path = chat.addAttachment(kind, attachmentID, blob)
+	return attachment, nil
+}
+
+// exportedChats returns the chat of the export, or all chats of the user with the content on the server
+func (s *ExportService) exportedChats(ctx context.Context, export model.Export) ([]model.ChatDB, error) {
+	if export.ChatID != nil {
+		chat, err := s.exportedChat(ctx, *export.ChatID, export.UserID)
+		if err != nil {
+			return nil, err
+		}
+		return []model.ChatDB{chat}, nil
+	}
+
+	chats, err := s.repoChats.GetAllChatsByUserID(ctx, export.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	exported := make([]model.ChatDB, 0, len(chats))
+	for _, chat := range chats {
+		if !chat.Encrypted {
+			exported = append(exported, chat)
+		}
+	}
+	return exported, nil
+}
+
+// exportedChat checks the user takes part in the chat, the end-to-end encrypted chats can't be exported by the server
+func (s *ExportService) exportedChat(ctx context.Context, chatID int64, userID string) (model.ChatDB, error) {
+	if _, err := getChatMember(ctx, s.repoChats, chatID, userID); err != nil {
+		return model.ChatDB{}, err
+	}
+
+	chat, err := s.repoChats.GetChatByChatID(ctx, chatID)
+	if err != nil {
+		return model.ChatDB{}, err
+	}
+	if chat.Encrypted {
+		return model.ChatDB{}, model.ErrChatEncrypted
+	}
+	return chat, nil
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"chat-api/internal/model"
+	repo "chat-api/internal/repository/psql"
+	"chat-api/pkg/crypto"
+	"chat-api/pkg/logger"
+	"context"
+
+	"go.uber.org/zap"
+)
+
+type ImportService struct {
+	repoImports  repo.Imports
+	repoMessages repo.Messages
+	repoChats    repo.Chats
+	encrypter    crypto.MessageEncrypter
+	search       *SearchService
+}
+
+func NewImportService(imports repo.Imports, messages repo.Messages, chats repo.Chats, encrypter crypto.MessageEncrypter, search *SearchService) *ImportService {
+	return &ImportService{
+		repoImports:  imports,
+		repoMessages: messages,
+		repoChats:    chats,
+		encrypter:    encrypter,
+		search:       search,
+	}
+}
+
+// ImportChat recreates the archived chat as a new group chat of the user. The senders are mapped
+// to the user and to those the user has a private chat with, so nobody is added by a stranger.
+// Every message is stored as sent by the user, the others are only named, so nobody is made to say what they didn't
+func (s *ImportService) ImportChat(ctx context.Context, request model.ImportRequest) (model.ImportResult, error) {
+	if !request.IsValid() {
+		return model.ImportResult{}, model.ErrInvalidImport
+	}
+
+	participantsIDs, err := s.importedParticipants(ctx, request)
+	if err != nil {
+		return model.ImportResult{}, err
+	}
+
+	messages, contents := request.ImportedMessages()
+
+	chatID, _, err := s.repoChats.SetChat(ctx, model.ChatDB{
+		CreatorID:   request.UserID,
+		Name:        request.Archive.Chat.Name,
+		Description: request.Archive.Chat.Description,
+		Type:        model.CHAT_TYPE_GROUP,
+	})
+	if err != nil {
+		return model.ImportResult{}, err
+	}
+
+	messageIDs, err := s.importMessages(ctx, model.ChatImport{
+		ChatID:          chatID,
+		UserID:          request.UserID,
+		ParticipantsIDs: participantsIDs,
+		Messages:        messages,
+	}, contents)
+	if err != nil {
+		if err := s.repoChats.DeleteChat(ctx, chatID); err != nil {
+			logger.Error("Failed to delete chat of failed import", zap.Int64("chatID", chatID), zap.Error(err))
+		}
+		return model.ImportResult{}, err
+	}
+
+	for i, messageID := range messageIDs {
+		if err := s.search.IndexMessage(ctx, chatID, messageID, &contents[i]); err != nil {
+			logger.Warnf("Failed to index imported message, err: %s", err)
+		}
+	}
+
+	chat, err := s.repoChats.GetChatByChatID(ctx, chatID)
+	if err != nil {
+		return model.ImportResult{}, err
+	}
+
+	return model.ImportResult{
+		Chat:     chat,
+		Imported: len(messageIDs),
+		Skipped:  len(request.Archive.Messages) - len(messageIDs),
+	}, nil
+}
+
+// importMessages encrypts the contents with the key of the created chat bound to the reserved ids and stores the messages
+func (s *ImportService) importMessages(ctx context.Context, chatImport model.ChatImport, contents []string) ([]int64, error) {
+	messageIDs, err := s.repoMessages.ReserveMessageIDs(ctx, len(chatImport.Messages))
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range chatImport.Messages {
+		chatImport.Messages[i].MessageDB.MessageID = messageIDs[i]
+		encrypted, err := s.encrypter.Encrypt(ctx, chatImport.ChatID, messageIDs[i], contents[i])
+		if err != nil {
+			return nil, err
+		}
+		chatImport.Messages[i].MessageDB.Content = &encrypted
+	}
+
+	return s.repoImports.ImportChat(ctx, chatImport)
+}
+
+func (s *ImportService) importedParticipants(ctx context.Context, request model.ImportRequest) ([]string, error) {
+	participantsIDs := []string{request.UserID}
+	seen := map[string]bool{request.UserID: true}
+
+	var others []string
+	for _, userID := range request.Senders {
+		if userID == "" {
+			return nil, model.ErrInvalidImport
+		}
+		if !seen[userID] {
+			seen[userID] = true
+			others = append(others, userID)
+		}
+	}
+	if len(others) == 0 {
+		return participantsIDs, nil
+	}
+
+	partners, err := s.repoChats.GetPrivateChatPartners(ctx, request.UserID, others)
+	if err != nil {
+		return nil, err
+	}
+	if len(partners) != len(others) {
+		return nil, model.ErrInvalidImport
+	}
+
+	return append(participantsIDs, others...), nil
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"chat-api/internal/model"
+	repo "chat-api/internal/repository/psql"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type InviteService struct {
+	repoInvites repo.Invites
+	repoChats   repo.Chats
+}
+
+func NewInviteService(invites repo.Invites, chats repo.Chats) *InviteService {
+	return &InviteService{
+		repoInvites: invites,
+		repoChats:   chats,
+	}
+}
+
+func (s *InviteService) CreateInviteLink(ctx context.Context, request model.CreateInviteLinkRequest) (model.InviteLink, error) {
+	if !request.IsValid() {
+		return model.InviteLink{}, model.ErrInvalidInviteLink
+	}
+
+	if _, err := getGroupChatWithPermission(ctx, s.repoChats, request.ChatID, request.UserID, model.PERMISSION_INVITE_USERS); err != nil {
+		return model.InviteLink{}, err
+	}
+
+	token, err := randomID()
+	if err != nil {
+		return model.InviteLink{}, err
+	}
+
+	return s.repoInvites.SetInviteLink(ctx, model.InviteLink{
+		ChatID:           request.ChatID,
+		CreatorID:        request.UserID,
+		Token:            token,
+		Name:             request.Name,
+		ExpiresAt:        request.ExpiresAt,
+		UsageLimit:       request.UsageLimit,
+		RequiresApproval: request.RequiresApproval,
+	})
+}
+
+func (s *InviteService) GetInviteLinks(ctx context.Context, chatID int64, userID string) ([]model.InviteLink, error) {
+	if err := s.checkInvite(ctx, chatID, userID); err != nil {
+		return nil, err
+	}
+	return s.repoInvites.GetInviteLinksByChatID(ctx, chatID, model.INVITE_LINKS_LIMIT_REQUEST)
+}
+
+func (s *InviteService) RevokeInviteLink(ctx context.Context, chatID, inviteLinkID int64, userID string) error {
+	if err := s.checkInvite(ctx, chatID, userID); err != nil {
+		return err
+	}
+
+	err := s.repoInvites.RevokeInviteLink(ctx, chatID, inviteLinkID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.ErrInviteLinkNotFound
+	}
+	return err
+}
+
+// JoinByInviteLink adds the user to the chat right away or, if the link requires the approval,
+// leaves the request to the admins returned along with the chat
+func (s *InviteService) JoinByInviteLink(ctx context.Context, token, userID string) (model.JoinChatResponse, error) {
+	link, err := s.repoInvites.JoinByInviteLink(ctx, token, userID, func(link model.InviteLink) error {
+		if !link.IsUsable(time.Now()) {
+			return model.ErrInviteLinkExpired
+		}
+
+		isParticipant, err := s.repoChats.IsParticipantExists(ctx, link.ChatID, userID)
+		if err != nil {
+			return err
+		}
+		if isParticipant {
+			return model.ErrAlreadyParticipant
+		}
+
+		banned, err := s.repoChats.IsBlockedChatExists(ctx, link.ChatID, userID)
+		if err != nil {
+			return err
+		}
+		if banned {
+			return model.ErrUserBanned
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.JoinChatResponse{}, model.ErrInviteLinkNotFound
+		}
+		return model.JoinChatResponse{}, err
+	}
+
+	chat, err := s.repoChats.GetChatByChatID(ctx, link.ChatID)
+	if err != nil {
+		return model.JoinChatResponse{}, err
+	}
+
+	response := model.JoinChatResponse{Chat: chat, Joined: !link.RequiresApproval}
+	if !response.Joined {
+		if response.AdminsIDs, err = s.repoChats.GetChatAdmins(ctx, link.ChatID); err != nil {
+			return model.JoinChatResponse{}, err
+		}
+	}
+	return response, nil
+}
+
+func (s *InviteService) GetJoinRequests(ctx context.Context, chatID int64, userID string) ([]model.JoinRequest, error) {
+	if err := s.checkInvite(ctx, chatID, userID); err != nil {
+		return nil, err
+	}
+	return s.repoInvites.GetPendingJoinRequests(ctx, chatID)
+}
+
+func (s *InviteService) DecideJoinRequest(ctx context.Context, request model.DecideJoinRequest) error {
+	if err := s.checkInvite(ctx, request.ChatID, request.AdminID); err != nil {
+		return err
+	}
+
+	var err error
+	if request.Approve {
+		err = s.repoInvites.ApproveJoinRequest(ctx, request.ChatID, request.UserID, request.AdminID)
+	} else {
+		err = s.repoInvites.RejectJoinRequest(ctx, request.ChatID, request.UserID, request.AdminID)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.ErrJoinRequestNotFound
+	}
+	return err
+}
+
+func (s *InviteService) checkInvite(ctx context.Context, chatID int64, userID string) error {
+	_, err := checkChatPermission(ctx, s.repoChats, chatID, userID, model.PERMISSION_INVITE_USERS)
+	return err
+}
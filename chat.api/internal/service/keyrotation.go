@@ -0,0 +1,99 @@
+package service
+
+import (
+	"chat-api/internal/model"
+	repo "chat-api/internal/repository/psql"
+	"chat-api/pkg/crypto"
+	"chat-api/pkg/logger"
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// KeyRotationService moves the stored content to the current keys while the messenger keeps working:
+// every row is updated on its own and only if nobody has changed it meanwhile.
+type KeyRotationService struct {
+	repoDataKeys     repo.DataKeys
+	repoMessages     repo.Messages
+	keyProvider      crypto.KeyProvider
+	messageEncrypter crypto.MessageEncrypter
+}
+
+func NewKeyRotationService(dataKeys repo.DataKeys, messages repo.Messages, keyProvider crypto.KeyProvider, messageEncrypter crypto.MessageEncrypter) *KeyRotationService {
+	return &KeyRotationService{
+		repoDataKeys:     dataKeys,
+		repoMessages:     messages,
+		keyProvider:      keyProvider,
+		messageEncrypter: messageEncrypter,
+	}
+}
+
+// RewrapDataKeys wraps a batch of data keys by the current master key, the messages stay untouched.
+// It returns the number of keys in the batch.
+func (s *KeyRotationService) RewrapDataKeys(ctx context.Context) (int, error) {
+	keys, err := s.repoDataKeys.GetDataKeysToRewrap(ctx, s.keyProvider.CurrentVersion(), model.DATA_KEY_BATCH_SIZE)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, key := range keys {
+		plain, err := s.keyProvider.UnwrapKey(ctx, key.WrappedKey, key.MasterKeyVersion)
+		if err != nil {
+			return 0, err
+		}
+
+		previousVersion := key.MasterKeyVersion
+		key.WrappedKey, key.MasterKeyVersion, err = s.keyProvider.WrapKey(ctx, plain)
+		if err != nil {
+			return 0, err
+		}
+
+		if _, err := s.repoDataKeys.UpdateWrappedKey(ctx, key, previousVersion); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(keys), nil
+}
+
+// RotateDataKeys retires the data keys older than maxAge, zero maxAge disables the rotation
+func (s *KeyRotationService) RotateDataKeys(ctx context.Context, maxAge time.Duration) (int, error) {
+	if maxAge <= 0 {
+		return 0, nil
+	}
+
+	return s.repoDataKeys.DeactivateExpiredDataKeys(ctx, time.Now().UTC().Add(-maxAge), model.DATA_KEY_BATCH_SIZE)
+}
+
+// ReencryptMessages re-encrypts a batch of messages after afterMessageID by the active data key of their chat.
+// It returns the last checked message to continue from and the number of messages in the batch,
+// content that can't be decrypted is skipped so it never blocks the job.
+func (s *KeyRotationService) ReencryptMessages(ctx context.Context, afterMessageID int64) (int64, int, error) {
+	contents, err := s.repoMessages.GetContentToReencrypt(ctx, afterMessageID, model.REENCRYPT_BATCH_SIZE)
+	if err != nil {
+		return afterMessageID, 0, err
+	}
+
+	lastMessageID := afterMessageID
+	for _, content := range contents {
+		plaintext, err := s.messageEncrypter.Decrypt(ctx, content.ChatID, content.MessageID, content.Content)
+		if err != nil {
+			logger.Warn("Failed to decrypt message for re-encryption", zap.Int64("messageID", content.MessageID), zap.Error(err))
+			lastMessageID = content.MessageID
+			continue
+		}
+
+		encrypted, err := s.messageEncrypter.Encrypt(ctx, content.ChatID, content.MessageID, plaintext)
+		if err != nil {
+			return afterMessageID, 0, err
+		}
+
+		if _, err := s.repoMessages.UpdateMessageContent(ctx, content.MessageID, content.Content, encrypted); err != nil {
+			return afterMessageID, 0, err
+		}
+		lastMessageID = content.MessageID
+	}
+
+	return lastMessageID, len(contents), nil
+}
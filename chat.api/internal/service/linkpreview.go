@@ -0,0 +1,138 @@
+package service
+
+import (
+	"chat-api/internal/model"
+	"chat-api/internal/repository/cache"
+	repo "chat-api/internal/repository/psql"
+	"chat-api/pkg/broker"
+	"chat-api/pkg/crypto"
+	"chat-api/pkg/linkpreview"
+	"chat-api/pkg/logger"
+	"context"
+	"encoding/json"
+
+	"go.uber.org/zap"
+)
+
+type LinkPreviewService struct {
+	repoPreviews  repo.LinkPreviews
+	repoMessages  repo.Messages
+	cache         cache.LinkPreviewCache
+	fetcher       *linkpreview.Fetcher
+	notifications Notifications
+	encrypter     crypto.MessageEncrypter
+}
+
+func NewLinkPreviewService(previews repo.LinkPreviews, messages repo.Messages, cache cache.LinkPreviewCache, fetcher *linkpreview.Fetcher, notifications Notifications, encrypter crypto.MessageEncrypter) *LinkPreviewService {
+	return &LinkPreviewService{
+		repoPreviews:  previews,
+		repoMessages:  messages,
+		cache:         cache,
+		fetcher:       fetcher,
+		notifications: notifications,
+		encrypter:     encrypter,
+	}
+}
+
+// RequestLinkPreview queues the fetching of the preview of the first link of the plain content.
+// Without a link the preview left from the previous content is removed.
+func (s *LinkPreviewService) RequestLinkPreview(ctx context.Context, chatID, messageID int64, content *string, entities *[]model.MessageEntity) error {
+	link := model.FirstLink(content, entities)
+	if link == "" {
+		return s.repoPreviews.DeleteLinkPreview(ctx, messageID)
+	}
+
+	return s.notifications.SendNotification(ctx, model.NotificationRabbitMQ{
+		Exchange:   broker.EXCHANGE_MEDIA,
+		RoutingKey: broker.ROUTING_KEY_LINK_PREVIEW,
+	}, model.LinkPreviewJob{MessageID: messageID, ChatID: chatID, URL: link})
+}
+
+// ProcessLinkPreview attaches the preview of the link to the message, nil means there's nothing to show:
+// the page has no metadata or the message is deleted or edited to another link meanwhile
+func (s *LinkPreviewService) ProcessLinkPreview(ctx context.Context, job model.LinkPreviewJob) (*model.LinkPreview, error) {
+	current, err := s.currentLink(ctx, job.ChatID, job.MessageID)
+	if err != nil {
+		return nil, err
+	}
+	if current != job.URL {
+		return nil, nil
+	}
+
+	preview, cached, err := s.cache.GetLinkPreview(ctx, job.URL)
+	if err != nil {
+		logger.Warn("Failed to get cached link preview", zap.Error(err))
+	}
+	if !cached {
+		preview, err = s.fetch(ctx, job.URL)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if preview.IsEmpty() {
+		return nil, nil
+	}
+	preview.URL = job.URL
+
+	data, err := json.Marshal(preview)
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := s.encrypter.Encrypt(ctx, job.ChatID, job.MessageID, string(data))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repoPreviews.SetLinkPreview(ctx, job.MessageID, encrypted); err != nil {
+		return nil, err
+	}
+
+	return &preview, nil
+}
+
+// fetch reads the page and caches the result, a page which can't be fetched is cached
+// as the empty preview, so the link shared to many chats doesn't hit it again and again
+func (s *LinkPreviewService) fetch(ctx context.Context, link string) (model.LinkPreview, error) {
+	preview, err := s.fetcher.Fetch(ctx, link)
+	if ctx.Err() != nil {
+		return model.LinkPreview{}, ctx.Err()
+	}
+
+	ttl := model.LINK_PREVIEW_CACHE_TTL
+	if err != nil || preview.IsEmpty() {
+		if err != nil {
+			logger.Info("Failed to fetch link preview", zap.String("url", link), zap.Error(err))
+		}
+		preview, ttl = model.LinkPreview{}, model.LINK_PREVIEW_FAILED_CACHE_TTL
+	}
+
+	if err := s.cache.SetLinkPreview(ctx, link, preview, ttl); err != nil {
+		logger.Warn("Failed to cache link preview", zap.Error(err))
+	}
+	return preview, nil
+}
+
+// currentLink returns the link the message points to now, empty if the message is deleted
+func (s *LinkPreviewService) currentLink(ctx context.Context, chatID, messageID int64) (string, error) {
+	message, err := s.repoMessages.GetMessageByMessageID(ctx, messageID)
+	if err != nil || message.MessageID == 0 || message.Content == nil {
+		return "", err
+	}
+
+	content, err := s.encrypter.Decrypt(ctx, chatID, messageID, *message.Content)
+	if err != nil {
+		return "", err
+	}
+
+	var entities *[]model.MessageEntity
+	if message.EncryptedEntities != nil {
+		data, err := s.encrypter.Decrypt(ctx, chatID, messageID, *message.EncryptedEntities)
+		if err != nil {
+			return "", err
+		}
+		if entities, err = model.UnmarshalEntities(data); err != nil {
+			return "", err
+		}
+	}
+
+	return model.FirstLink(&content, entities), nil
+}
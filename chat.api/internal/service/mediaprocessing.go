@@ -0,0 +1,238 @@
+package service
+
+import (
+	"bytes"
+	"chat-api/internal/model"
+	repo "chat-api/internal/repository/psql"
+	"chat-api/pkg/broker"
+	"chat-api/pkg/imaging"
+	"chat-api/pkg/logger"
+	"chat-api/pkg/storage"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// errMediaUnreadable marks the failures of the content itself, retrying them won't help
+	errMediaUnreadable = errors.New("media is unreadable")
+	// errMediaUnsafe marks the content whose GPS data can't be removed, it's never served
+	errMediaUnsafe = errors.New("media can't be stripped of the GPS data")
+)
+
+type MediaProcessingService struct {
+	repoMedia     repo.Media
+	repoUploads   repo.Uploads
+	storage       storage.Storage
+	prober        *imaging.VideoProber
+	notifications Notifications
+}
+
+func NewMediaProcessingService(media repo.Media, uploads repo.Uploads, storage storage.Storage, prober *imaging.VideoProber, notifications Notifications) *MediaProcessingService {
+	return &MediaProcessingService{
+		repoMedia:     media,
+		repoUploads:   uploads,
+		storage:       storage,
+		prober:        prober,
+		notifications: notifications,
+	}
+}
+
+// ProcessMedia makes the thumbnail of the uploaded media and reads its dimensions.
+// Returns nil when the media was processed already, so the redelivered jobs are no-ops.
+func (s *MediaProcessingService) ProcessMedia(ctx context.Context, mediaID int64) (*model.ProcessedMedia, error) {
+	source, err := s.repoMedia.GetMediaSource(ctx, mediaID)
+	if err != nil {
+		return nil, err
+	}
+
+	if source.Status != model.MEDIA_STATUS_PENDING {
+		return nil, nil
+	}
+
+	media := source.Media
+	sum := source.SHA256
+
+	var preview imaging.Preview
+
+	switch media.Type {
+	case model.MEDIA_TYPE_MP4, model.MEDIA_TYPE_WEBM:
+		var duration float64
+		preview, duration, err = s.processVideo(ctx, source)
+		media.Duration = &duration
+	default:
+		preview, sum, err = s.processImage(ctx, source)
+		media.URL = model.BlobStorageKey(sum)
+	}
+
+	switch {
+	case errors.Is(err, errMediaUnsafe):
+		logger.Warn("Failed to process media", zap.Int64("media_id", mediaID), zap.Error(err))
+		media = source.Media
+		sum = source.SHA256
+		media.Status = model.MEDIA_STATUS_REJECTED
+	case errors.Is(err, errMediaUnreadable):
+		// the content is kept without the preview, the image stays stripped of the GPS data
+		logger.Warn("Failed to process media", zap.Int64("media_id", mediaID), zap.Error(err))
+		media = source.Media
+		if sum != source.SHA256 {
+			media.URL = model.BlobStorageKey(sum)
+		}
+		media.Status = model.MEDIA_STATUS_FAILED
+	case err != nil:
+		return nil, err
+	default:
+		thumbnailSum := sha256.Sum256(preview.Thumbnail)
+		thumbnail, err := storeBlob(ctx, s.repoUploads, s.storage, hex.EncodeToString(thumbnailSum[:]),
+			bytes.NewReader(preview.Thumbnail), int64(len(preview.Thumbnail)), model.THUMBNAIL_TYPE)
+		if err != nil {
+			return nil, err
+		}
+
+		media.Width = &preview.Width
+		media.Height = &preview.Height
+		media.BlurHash = &preview.BlurHash
+		media.ThumbnailSHA256 = &thumbnail.SHA256
+		media.Status = model.MEDIA_STATUS_READY
+	}
+
+	if err := s.repoMedia.UpdateProcessedMedia(ctx, media, sum); err != nil {
+		return nil, err
+	}
+
+	locations, err := s.repoMedia.GetMediaLocations(ctx, mediaID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.ProcessedMedia{
+		Media:      media,
+		UploadedBy: source.UploadedBy,
+		Locations:  locations,
+	}, nil
+}
+
+// SweepStaleMedia queues again the jobs of the media pending for too long, the job may be lost with the broker
+// or killed with the replica. The media out of attempts is failed and returned to tell the chats about it
+func (s *MediaProcessingService) SweepStaleMedia(ctx context.Context) ([]model.ProcessedMedia, error) {
+	staleBefore := time.Now().UTC().Add(-model.MEDIA_PROCESSING_TIMEOUT)
+
+	requeued, err := s.repoMedia.RequeueStaleMedia(ctx, staleBefore, model.MEDIA_PROCESSING_MAX_ATTEMPTS, model.MEDIA_SWEEP_BATCH_SIZE)
+	if err != nil {
+		return nil, err
+	}
+	for _, mediaID := range requeued {
+		if err := s.notifications.SendNotification(ctx, model.NotificationRabbitMQ{
+			Exchange:   broker.EXCHANGE_MEDIA,
+			RoutingKey: broker.ROUTING_KEY_MEDIA_PROCESS,
+		}, model.MediaJob{MediaID: mediaID}); err != nil {
+			return nil, err
+		}
+	}
+
+	failed, err := s.repoMedia.FailStaleMedia(ctx, staleBefore, model.MEDIA_PROCESSING_MAX_ATTEMPTS, model.MEDIA_SWEEP_BATCH_SIZE)
+	if err != nil {
+		return nil, err
+	}
+
+	processed := make([]model.ProcessedMedia, 0, len(failed))
+	for _, mediaID := range failed {
+		logger.Warn("Media processing ran out of attempts", zap.Int64("media_id", mediaID))
+
+		source, err := s.repoMedia.GetMediaSource(ctx, mediaID)
+		if err != nil {
+			return nil, err
+		}
+		locations, err := s.repoMedia.GetMediaLocations(ctx, mediaID)
+		if err != nil {
+			return nil, err
+		}
+		processed = append(processed, model.ProcessedMedia{
+			Media:      source.Media,
+			UploadedBy: source.UploadedBy,
+			Locations:  locations,
+		})
+	}
+
+	return processed, nil
+}
+
+// processImage strips the GPS data of the image, the stripped copy is stored as a new blob
+// and its SHA-256 is returned in place of the original one, even if the preview fails then
+func (s *MediaProcessingService) processImage(ctx context.Context, source model.MediaSource) (imaging.Preview, string, error) {
+	content, err := s.storage.Get(ctx, source.StorageKey)
+	if err != nil {
+		return imaging.Preview{}, "", err
+	}
+	data, err := io.ReadAll(content)
+	content.Close()
+	if err != nil {
+		return imaging.Preview{}, "", err
+	}
+
+	sum := source.SHA256
+
+	if source.Type == model.MEDIA_TYPE_JPEG {
+		cleaned, stripped, err := imaging.StripJPEGGPS(data)
+		if err != nil {
+			return imaging.Preview{}, "", fmt.Errorf("%w: %v", errMediaUnsafe, err)
+		}
+		if stripped {
+			data = cleaned
+			strippedSum := sha256.Sum256(data)
+			blob, err := storeBlob(ctx, s.repoUploads, s.storage, hex.EncodeToString(strippedSum[:]),
+				bytes.NewReader(data), int64(len(data)), source.Type)
+			if err != nil {
+				return imaging.Preview{}, "", err
+			}
+			sum = blob.SHA256
+		}
+	}
+
+	preview, err := imaging.NewPreview(data, model.THUMBNAIL_MAX_SIZE)
+	if err != nil {
+		return imaging.Preview{}, sum, fmt.Errorf("%w: %v", errMediaUnreadable, err)
+	}
+
+	return preview, sum, nil
+}
+
+// processVideo reads the video from a temp file since ffprobe and ffmpeg need to seek
+func (s *MediaProcessingService) processVideo(ctx context.Context, source model.MediaSource) (imaging.Preview, float64, error) {
+	content, err := s.storage.Get(ctx, source.StorageKey)
+	if err != nil {
+		return imaging.Preview{}, 0, err
+	}
+	tmp, _, err := stageToTempFile(content, source.Size)
+	content.Close()
+	if err != nil {
+		return imaging.Preview{}, 0, err
+	}
+	defer removeTempFile(tmp)
+
+	info, err := s.prober.Probe(ctx, tmp.Name())
+	if err == nil {
+		var frame []byte
+		if frame, err = s.prober.Frame(ctx, tmp.Name()); err == nil {
+			var preview imaging.Preview
+			if preview, err = imaging.NewPreview(frame, model.THUMBNAIL_MAX_SIZE); err == nil {
+				// the frame may be scaled down, the dimensions are those of the video
+				preview.Width = info.Width
+				preview.Height = info.Height
+				return preview, info.Duration, nil
+			}
+		}
+	}
+
+	// a cancelled job is retried, the exit of ffmpeg or its timeout means it can't read the video
+	if ctx.Err() != nil {
+		return imaging.Preview{}, 0, ctx.Err()
+	}
+	return imaging.Preview{}, 0, fmt.Errorf("%w: %v", errMediaUnreadable, err)
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"chat-api/internal/model"
+	repo "chat-api/internal/repository/psql"
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+)
+
+type MemberService struct {
+	repoMembers  repo.Members
+	repoChats    repo.Chats
+	repoChannels repo.Channels
+}
+
+func NewMemberService(members repo.Members, chats repo.Chats, channels repo.Channels) *MemberService {
+	return &MemberService{
+		repoMembers:  members,
+		repoChats:    chats,
+		repoChannels: channels,
+	}
+}
+
+// AddMembers lets those who can invite add the users, the bans of the added users are lifted
+func (s *MemberService) AddMembers(ctx context.Context, request model.AddMembersRequest) (model.AddMembersResponse, error) {
+	if !request.IsValid() {
+		return model.AddMembersResponse{}, model.ErrInvalidMembers
+	}
+
+	chat, err := getGroupChatWithPermission(ctx, s.repoChats, request.ChatID, request.UserID, model.PERMISSION_INVITE_USERS)
+	if err != nil {
+		return model.AddMembersResponse{}, err
+	}
+
+	usersIDs := make([]string, 0, len(request.UsersIDs))
+	seen := make(map[string]struct{}, len(request.UsersIDs))
+	for _, userID := range request.UsersIDs {
+		if _, ok := seen[userID]; ok {
+			continue
+		}
+		seen[userID] = struct{}{}
+		usersIDs = append(usersIDs, userID)
+	}
+
+	addedIDs, err := s.repoMembers.AddParticipants(ctx, request.ChatID, usersIDs)
+	if err != nil {
+		return model.AddMembersResponse{}, err
+	}
+
+	return model.AddMembersResponse{Chat: chat, AddedIDs: addedIDs}, nil
+}
+
+// KickMember removes the member, only those of a lower role can be kicked
+func (s *MemberService) KickMember(ctx context.Context, request model.KickMemberRequest) (model.ChatDB, error) {
+	chat, err := getGroupChat(ctx, s.repoChats, request.ChatID)
+	if err != nil {
+		return chat, err
+	}
+
+	kicker, err := checkChatPermission(ctx, s.repoChats, request.ChatID, request.UserID, model.PERMISSION_MANAGE_ROLES)
+	if err != nil {
+		return chat, err
+	}
+
+	member, err := getChatMember(ctx, s.repoChats, request.ChatID, request.MemberID)
+	if err != nil {
+		if errors.Is(err, model.ErrNotParticipant) {
+			return chat, model.ErrMemberNotFound
+		}
+		return chat, err
+	}
+	if !kicker.Outranks(member.Role()) {
+		return chat, model.ErrCannotKickMember
+	}
+
+	err = s.repoMembers.RemoveParticipant(ctx, request.ChatID, request.MemberID, model.CHAT_ACTION_KICK, request.Ban)
+	if errors.Is(err, sql.ErrNoRows) {
+		return chat, model.ErrMemberNotFound
+	}
+	return chat, err
+}
+
+func (s *MemberService) LeaveChat(ctx context.Context, chatID int64, userID string) (model.ChatDB, error) {
+	chat, err := getGroupChat(ctx, s.repoChats, chatID)
+	if err != nil {
+		return chat, err
+	}
+
+	if chat.CreatorID == userID {
+		return chat, model.ErrChatOwnerCannotLeave
+	}
+
+	err = s.repoMembers.RemoveParticipant(ctx, chatID, userID, model.CHAT_ACTION_LEFT, false)
+	if errors.Is(err, sql.ErrNoRows) {
+		return chat, model.ErrNotParticipant
+	}
+	return chat, err
+}
+
+// UpdateChatInfo changes the chat, the handle and the signing of the posts belong to the channels only
+func (s *MemberService) UpdateChatInfo(ctx context.Context, request model.UpdateChatInfoRequest) (model.ChatDB, error) {
+	// handles are case insensitive
+	if request.Handle != nil {
+		handle := strings.ToLower(*request.Handle)
+		request.Handle = &handle
+	}
+	if !request.IsValid() {
+		return model.ChatDB{}, model.ErrInvalidChatInfo
+	}
+
+	chat, err := getGroupChatWithPermission(ctx, s.repoChats, request.ChatID, request.UserID, model.PERMISSION_CHANGE_INFO)
+	if err != nil {
+		return model.ChatDB{}, err
+	}
+	if request.IsChannelOnly() && chat.Type != model.CHAT_TYPE_CHANNEL {
+		return model.ChatDB{}, model.ErrInvalidChatInfo
+	}
+
+	if request.Handle != nil && *request.Handle != "" {
+		owner, err := s.repoChannels.GetChannelByHandle(ctx, *request.Handle)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return model.ChatDB{}, err
+		}
+		if err == nil && owner.ChatID != request.ChatID {
+			return model.ChatDB{}, model.ErrChannelHandleTaken
+		}
+	}
+
+	return s.repoMembers.UpdateChatInfo(ctx, request)
+}
+
+// TransferOwnership makes another participant the creator of the chat, the previous one stays an admin
+func (s *MemberService) TransferOwnership(ctx context.Context, request model.TransferOwnershipRequest) (model.ChatDB, error) {
+	chat, err := getGroupChat(ctx, s.repoChats, request.ChatID)
+	if err != nil {
+		return chat, err
+	}
+
+	if chat.CreatorID != request.UserID {
+		return chat, model.ErrNotChatOwner
+	}
+	if request.NewCreatorID == "" || request.NewCreatorID == request.UserID {
+		return chat, model.ErrInvalidParamsOfChat
+	}
+
+	isParticipant, err := s.repoChats.IsParticipantExists(ctx, request.ChatID, request.NewCreatorID)
+	if err != nil {
+		return chat, err
+	}
+	if !isParticipant {
+		return chat, model.ErrMemberNotFound
+	}
+
+	chat, err = s.repoMembers.TransferOwnership(ctx, request.ChatID, request.UserID, request.NewCreatorID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return chat, model.ErrNotChatOwner
+	}
+	return chat, err
+}
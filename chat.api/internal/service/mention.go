@@ -0,0 +1,77 @@
+package service
+
+import (
+	"chat-api/internal/model"
+	repo "chat-api/internal/repository/psql"
+	"context"
+)
+
+type MentionService struct {
+	repoMentions repo.Mentions
+	repoChats    repo.Chats
+}
+
+func NewMentionService(mentions repo.Mentions, chats repo.Chats) *MentionService {
+	return &MentionService{
+		repoMentions: mentions,
+		repoChats:    chats,
+	}
+}
+
+func (s *MentionService) GetUnreadMentions(ctx context.Context, chatID int64, userID string) ([]model.UnreadMention, error) {
+	if err := s.checkParticipant(ctx, chatID, userID); err != nil {
+		return nil, err
+	}
+
+	return s.repoMentions.GetUnreadMentions(ctx, chatID, userID)
+}
+
+// ReadMentions marks the mentions of the user up to the message as read, returns the number of the newly read ones
+func (s *MentionService) ReadMentions(ctx context.Context, request model.ReadMentionsRequest) (int, error) {
+	if request.MessageID <= 0 {
+		return 0, model.ErrInvalidParamsOfMessage
+	}
+
+	if err := s.checkParticipant(ctx, request.ChatID, request.UserID); err != nil {
+		return 0, err
+	}
+
+	return s.repoMentions.SetMentionsRead(ctx, request.ChatID, request.UserID, request.MessageID)
+}
+
+func (s *MentionService) checkParticipant(ctx context.Context, chatID int64, userID string) error {
+	isParticipant, err := s.repoChats.IsParticipantExists(ctx, chatID, userID)
+	if err != nil {
+		return err
+	}
+	if !isParticipant {
+		return model.ErrNotParticipant
+	}
+	return nil
+}
+
+// resolveMentions checks the mentioned users are participants of the chat,
+// the offsets are checked against the plain content before the encryption
+func resolveMentions(ctx context.Context, repoChats repo.Chats, chatID int64, message *model.SendMessage) error {
+	if message.Mentions == nil || len(*message.Mentions) == 0 {
+		message.Mentions = nil
+		return nil
+	}
+
+	participantsIDs, err := repoChats.GetAllParticipantsByChatID(ctx, chatID)
+	if err != nil {
+		return err
+	}
+
+	participants := make(map[string]bool, len(participantsIDs))
+	for _, participantID := range participantsIDs {
+		participants[participantID] = true
+	}
+	for _, mention := range *message.Mentions {
+		if !participants[mention.UserID] {
+			return model.ErrInvalidMentions
+		}
+	}
+
+	return nil
+}
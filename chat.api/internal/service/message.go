@@ -3,16 +3,31 @@ package service
 import (
 	"chat-api/internal/model"
 	repo "chat-api/internal/repository/psql"
+	"chat-api/pkg/crypto"
+	"chat-api/pkg/logger"
+	"chat-api/pkg/storage"
 	"context"
+	"database/sql"
 	"errors"
 	"sync"
+	"time"
+
+	"go.uber.org/zap"
 )
 
 type MessageService struct {
-	repoMessages  repo.Messages
-	repoMedia     repo.Media
-	repoFiles     repo.Files
-	repoLocations repo.Locations
+	repoMessages     repo.Messages
+	repoMedia        repo.Media
+	repoFiles        repo.Files
+	repoLocations    repo.Locations
+	repoChats        repo.Chats
+	repoPolls        repo.Polls
+	repoMentions     repo.Mentions
+	repoChannels     repo.Channels
+	repoLinkPreviews repo.LinkPreviews
+	repoUploads      repo.Uploads
+	storage          storage.Storage
+	encrypter        crypto.MessageEncrypter
 }
 
 func NewMessageService(
@@ -20,23 +35,83 @@ func NewMessageService(
 	repoFiles repo.Files,
 	repoMedia repo.Media,
 	repoLocations repo.Locations,
+	repoChats repo.Chats,
+	repoPolls repo.Polls,
+	repoMentions repo.Mentions,
+	repoChannels repo.Channels,
+	repoLinkPreviews repo.LinkPreviews,
+	repoUploads repo.Uploads,
+	storage storage.Storage,
+	encrypter crypto.MessageEncrypter,
 ) *MessageService {
 	return &MessageService{
-		repoMessages:  repoMessages,
-		repoFiles:     repoFiles,
-		repoMedia:     repoMedia,
-		repoLocations: repoLocations,
+		repoMessages:     repoMessages,
+		repoFiles:        repoFiles,
+		repoMedia:        repoMedia,
+		repoLocations:    repoLocations,
+		repoChats:        repoChats,
+		repoPolls:        repoPolls,
+		repoMentions:     repoMentions,
+		repoChannels:     repoChannels,
+		repoLinkPreviews: repoLinkPreviews,
+		repoUploads:      repoUploads,
+		storage:          storage,
+		encrypter:        encrypter,
 	}
 }
 
+// ReserveMessageID gives the new message its id before it's stored, the content is encrypted bound to it
+func (s *MessageService) ReserveMessageID(ctx context.Context) (int64, error) {
+	messageIDs, err := s.repoMessages.ReserveMessageIDs(ctx, 1)
+	if err != nil {
+		return 0, err
+	}
+	return messageIDs[0], nil
+}
+
 func (s *MessageService) SendMessage(ctx context.Context, createMessageRequest *model.CreateMessageRequest) error {
 	var err error
 	var wg sync.WaitGroup
 	var mu sync.Mutex
-	errChan := make(chan error, 4)
+	errChan := make(chan error, 6)
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
+	if err := s.resolveReply(ctx, createMessageRequest); err != nil {
+		return err
+	}
+
+	if !model.IsValidMessageTTL(createMessageRequest.TTL) {
+		return model.ErrInvalidMessageTTL
+	}
+
+	chatDB, err := s.repoChats.GetChatByChatID(ctx, createMessageRequest.ChatID)
+	if err != nil {
+		return err
+	}
+	// content of an E2EE chat goes only through SendEncryptedMessage
+	if chatDB.Encrypted {
+		return model.ErrChatEncrypted
+	}
+
+	messageDB := createMessageRequest.MessageWithData.MessageDB
+	if _, err := checkChatPermission(ctx, s.repoChats, createMessageRequest.ChatID, messageDB.SenderID, model.MessagePermissions(messageDB.Type)); err != nil {
+		return err
+	}
+	createMessageRequest.MessageWithData.MessageDB.ExpiresAt = model.MessageExpiresAt(createMessageRequest.TTL, chatDB.MessageTTL, time.Now().UTC())
+
+	if err := resolvePoll(chatDB, &createMessageRequest.MessageWithData); err != nil {
+		return err
+	}
+
+	if err := resolveMentions(ctx, s.repoChats, createMessageRequest.ChatID, &createMessageRequest.MessageWithData); err != nil {
+		return err
+	}
+
+	if err := resolveUploads(ctx, s.repoFiles, s.repoMedia, createMessageRequest.MessageWithData.MessageDB.SenderID, &createMessageRequest.MessageWithData); err != nil {
+		return err
+	}
+
 	messageID, createMessageTime, err := s.repoMessages.SetMessage(ctx, createMessageRequest.MessageWithData.MessageDB)
 	if err != nil {
 		return err
@@ -46,6 +121,9 @@ func (s *MessageService) SendMessage(ctx context.Context, createMessageRequest *
 	createMessageRequest.MessageWithData.MessageDB.UpdatedAt = createMessageTime
 	createMessageRequest.MessageWithData.MessageDB.MessageID = messageID
 
+	// the counter and the signature are up to the server
+	createMessageRequest.MessageWithData.Post = channelPost(chatDB, messageID, createMessageRequest.Signature)
+
 	runParallel := func(task func() error) {
 		wg.Add(1)
 		go func() {
@@ -65,22 +143,16 @@ func (s *MessageService) SendMessage(ctx context.Context, createMessageRequest *
 
 	runParallel(func() error {
 		if createMessageRequest.MessageWithData.MessageDB.Type == model.MESSAGE_FILE ||
-			createMessageRequest.MessageWithData.MessageDB.Type == model.MESSAGE_MIXED {
+			createMessageRequest.MessageWithData.MessageDB.Type == model.MESSAGE_MIXED ||
+			createMessageRequest.MessageWithData.MessageDB.Type == model.MESSAGE_VOICE {
 			if createMessageRequest.MessageWithData.Files == nil {
 				return model.ErrFilesIsEmpty
 			}
 			for i := range *createMessageRequest.MessageWithData.Files {
-				file := &(*createMessageRequest.MessageWithData.Files)[i]
-				fileID, err := s.repoFiles.SetFile(ctx, *file)
-				if err != nil {
-					return model.ErrUploadFile
-				}
-				if err = s.repoMessages.SetBindMessageFile(ctx, messageID, fileID); err != nil {
+				file := (*createMessageRequest.MessageWithData.Files)[i]
+				if err := s.repoMessages.SetBindMessageFile(ctx, messageID, file.FileID); err != nil {
 					return err
 				}
-				mu.Lock()
-				file.FileID = fileID
-				mu.Unlock()
 			}
 		}
 		return nil
@@ -93,17 +165,10 @@ func (s *MessageService) SendMessage(ctx context.Context, createMessageRequest *
 				return model.ErrMediaIsEmpty
 			}
 			for i := range *createMessageRequest.MessageWithData.Media {
-				media := &(*createMessageRequest.MessageWithData.Media)[i]
-				mediaID, err := s.repoMedia.SetMedia(ctx, *media)
-				if err != nil {
-					return model.ErrUploadFile
-				}
-				if err = s.repoMessages.SetBindMessageMedia(ctx, messageID, mediaID); err != nil {
+				media := (*createMessageRequest.MessageWithData.Media)[i]
+				if err := s.repoMessages.SetBindMessageMedia(ctx, messageID, media.MediaID); err != nil {
 					return err
 				}
-				mu.Lock()
-				media.MediaID = mediaID
-				mu.Unlock()
 			}
 		}
 		return nil
@@ -132,10 +197,32 @@ func (s *MessageService) SendMessage(ctx context.Context, createMessageRequest *
 		return nil
 	})
 
+	runParallel(func() error {
+		if createMessageRequest.MessageWithData.Poll == nil {
+			return nil
+		}
+		createMessageRequest.MessageWithData.Poll.MessageID = messageID
+		return s.repoPolls.SetPoll(ctx, *createMessageRequest.MessageWithData.Poll)
+	})
+
+	runParallel(func() error {
+		if createMessageRequest.MessageWithData.Mentions == nil {
+			return nil
+		}
+		return s.repoMentions.SetMentions(ctx, messageID, *createMessageRequest.MessageWithData.Mentions)
+	})
+
 	runParallel(func() error {
 		return s.repoMessages.SetBindMessageChat(ctx, messageID, createMessageRequest.ChatID)
 	})
 
+	runParallel(func() error {
+		if createMessageRequest.MessageWithData.Post == nil {
+			return nil
+		}
+		return s.repoChannels.SetChannelPost(ctx, *createMessageRequest.MessageWithData.Post)
+	})
+
 	wg.Wait()
 	close(errChan)
 
@@ -147,3 +234,370 @@ func (s *MessageService) SendMessage(ctx context.Context, createMessageRequest *
 
 	return nil
 }
+
+// channelPost returns the post of the message sent to the channel, nil for other chats
+func channelPost(chatDB model.ChatDB, messageID int64, signature string) *model.ChannelPost {
+	if chatDB.Type != model.CHAT_TYPE_CHANNEL {
+		return nil
+	}
+
+	post := model.ChannelPost{MessageID: messageID, ChatID: chatDB.ChatID}
+	if chatDB.SignMessages && signature != "" {
+		post.Signature = &signature
+	}
+	return &post
+}
+
+// resolveReply checks that the answered message belongs to the same chat,
+// attaches its brief info and places the reply into the thread if requested.
+func (s *MessageService) resolveReply(ctx context.Context, createMessageRequest *model.CreateMessageRequest) error {
+	messageDB := &createMessageRequest.MessageWithData.MessageDB
+	messageDB.ThreadID = nil
+	createMessageRequest.MessageWithData.ReplyTo = nil
+
+	if messageDB.ReplyToMessageID == nil {
+		if createMessageRequest.InThread {
+			return model.ErrInvalidParamsOfMessage
+		}
+		return nil
+	}
+
+	inChat, err := s.repoMessages.IsMessageInChat(ctx, *messageDB.ReplyToMessageID, createMessageRequest.ChatID)
+	if err != nil {
+		return err
+	}
+	if !inChat {
+		return model.ErrMessageNotFound
+	}
+
+	parent, err := s.repoMessages.GetMessageByMessageID(ctx, *messageDB.ReplyToMessageID)
+	if err != nil {
+		return err
+	}
+	createMessageRequest.MessageWithData.ReplyTo = model.NewQuotedMessage(parent)
+
+	// replies inside a thread always belong to the thread of its root message
+	switch {
+	case parent.ThreadID != nil:
+		messageDB.ThreadID = parent.ThreadID
+	case createMessageRequest.InThread:
+		messageDB.ThreadID = &parent.MessageID
+	}
+
+	return nil
+}
+
+// ForwardMessages copies the messages into the destination chat keeping the
+// original sender and chat as the forward origin. Attachments aren't duplicated,
+// the copies are bound to the same files, media and locations. The content of every copy
+// is re-encrypted by the key of the destination chat. Either all the copies are stored or none.
+func (s *MessageService) ForwardMessages(ctx context.Context, request model.ForwardMessagesRequest) ([]model.SendMessage, error) {
+	if request.UserID == "" || len(request.MessageIDs) == 0 || len(request.MessageIDs) > model.FORWARD_LIMIT_REQUEST {
+		return nil, model.ErrInvalidParamsOfMessage
+	}
+
+	isParticipant, err := s.repoChats.IsParticipantExists(ctx, request.FromChatID, request.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !isParticipant {
+		return nil, model.ErrNotParticipant
+	}
+
+	sender, err := checkChatPermission(ctx, s.repoChats, request.ToChatID, request.UserID, model.PERMISSION_SEND_MESSAGES)
+	if err != nil {
+		return nil, err
+	}
+
+	blocked, err := s.repoChats.IsBlockedChatExists(ctx, request.ToChatID, request.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if blocked {
+		return nil, model.ErrChatBlocked
+	}
+
+	chatDB, err := s.repoChats.GetChatByChatID(ctx, request.ToChatID)
+	if err != nil {
+		return nil, err
+	}
+
+	// ciphertext is bound to the devices of the chat, it can't be copied anywhere
+	fromChatDB, err := s.repoChats.GetChatByChatID(ctx, request.FromChatID)
+	if err != nil {
+		return nil, err
+	}
+	if chatDB.Encrypted || fromChatDB.Encrypted {
+		return nil, model.ErrChatEncrypted
+	}
+
+	copyIDs, err := s.repoMessages.ReserveMessageIDs(ctx, len(request.MessageIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	// every message is checked before anything is stored, the copies are stored all at once
+	forwards := make([]model.ForwardedMessage, 0, len(request.MessageIDs))
+	for i, messageID := range request.MessageIDs {
+		inChat, err := s.repoMessages.IsMessageInChat(ctx, messageID, request.FromChatID)
+		if err != nil {
+			return nil, err
+		}
+		if !inChat {
+			return nil, model.ErrMessageNotFound
+		}
+
+		original, err := s.repoMessages.GetMessageByMessageID(ctx, messageID)
+		if err != nil {
+			return nil, err
+		}
+		if original.Type == model.MESSAGE_POLL && chatDB.Type == model.CHAT_TYPE_PRIVATE {
+			return nil, model.ErrPollNotAllowed
+		}
+		if !sender.Can(model.MessagePermissions(original.Type)) {
+			return nil, model.ErrChatPermissionDenied
+		}
+
+		message := model.MessageDB{
+			MessageID:             copyIDs[i],
+			SenderID:              request.UserID,
+			Content:               original.Content,
+			Status:                model.MESSAGE_SENT,
+			Type:                  original.Type,
+			ForwardedFromSenderID: original.ForwardedFromSenderID,
+			ForwardedFromChatID:   original.ForwardedFromChatID,
+			ExpiresAt:             model.MessageExpiresAt(nil, chatDB.MessageTTL, time.Now().UTC()),
+			EncryptedEntities:     original.EncryptedEntities,
+		}
+		// forwarding a forward keeps the very first origin
+		if message.ForwardedFromSenderID == nil {
+			message.ForwardedFromSenderID = &original.SenderID
+			message.ForwardedFromChatID = &request.FromChatID
+		}
+
+		forward := model.ForwardedMessage{
+			OriginalMessageID: messageID,
+			Message:           message,
+			// the forward carries its origin instead of the signature
+			Post: channelPost(chatDB, 0, ""),
+		}
+		if err := s.reencryptForward(ctx, request.FromChatID, request.ToChatID, &forward); err != nil {
+			return nil, err
+		}
+		forwards = append(forwards, forward)
+	}
+
+	forwards, err = s.repoMessages.SetForwardedMessages(ctx, request.ToChatID, forwards)
+	if err != nil {
+		return nil, err
+	}
+
+	forwarded := make([]model.SendMessage, 0, len(forwards))
+	for _, forward := range forwards {
+		sent := s.loadAttachments(ctx, forward.Message)
+		sent.Post = forward.Post
+		sent.EncryptedLinkPreview = forward.LinkPreview
+		forwarded = append(forwarded, sent)
+	}
+
+	return forwarded, nil
+}
+
+// reencryptForward moves the content, entities, poll and link preview of the original message
+// from the key of its chat to the key of the destination chat bound to the copy
+func (s *MessageService) reencryptForward(ctx context.Context, fromChatID, toChatID int64, forward *model.ForwardedMessage) error {
+	reencrypt := func(ciphertext string) (string, error) {
+		plaintext, err := s.encrypter.Decrypt(ctx, fromChatID, forward.OriginalMessageID, ciphertext)
+		if err != nil {
+			return "", err
+		}
+		return s.encrypter.Encrypt(ctx, toChatID, forward.Message.MessageID, plaintext)
+	}
+
+	for _, field := range []*string{forward.Message.Content, forward.Message.EncryptedEntities} {
+		if field == nil {
+			continue
+		}
+		encrypted, err := reencrypt(*field)
+		if err != nil {
+			return err
+		}
+		*field = encrypted
+	}
+
+	if forward.Message.Type == model.MESSAGE_POLL {
+		poll, err := s.repoPolls.GetPoll(ctx, forward.OriginalMessageID)
+		if err != nil {
+			return err
+		}
+		if poll.Question, err = reencrypt(poll.Question); err != nil {
+			return err
+		}
+		for i := range poll.Options {
+			if poll.Options[i].Text, err = reencrypt(poll.Options[i].Text); err != nil {
+				return err
+			}
+		}
+		forward.Poll = &poll
+	}
+
+	preview, err := s.repoLinkPreviews.GetLinkPreview(ctx, forward.OriginalMessageID)
+	switch {
+	case err == nil:
+		encrypted, err := reencrypt(preview)
+		if err != nil {
+			return err
+		}
+		forward.LinkPreview = &encrypted
+	case !errors.Is(err, sql.ErrNoRows):
+		return err
+	}
+
+	return nil
+}
+
+// EditMessage replaces the content, entities and mentions of the message sent by the user,
+// the content and entities come already encrypted. Returns the edited message.
+func (s *MessageService) EditMessage(ctx context.Context, request model.EditMessageRequest) (model.MessageDB, error) {
+	chatDB, err := s.repoChats.GetChatByChatID(ctx, request.ChatID)
+	if err != nil {
+		return model.MessageDB{}, err
+	}
+	if chatDB.Encrypted {
+		return model.MessageDB{}, model.ErrChatEncrypted
+	}
+
+	inChat, err := s.repoMessages.IsMessageInChat(ctx, request.MessageID, request.ChatID)
+	if err != nil {
+		return model.MessageDB{}, err
+	}
+	if !inChat {
+		return model.MessageDB{}, model.ErrMessageNotFound
+	}
+
+	message, err := s.repoMessages.GetMessageByMessageID(ctx, request.MessageID)
+	if err != nil {
+		return model.MessageDB{}, err
+	}
+	if message.SenderID != request.UserID || message.ForwardedFromSenderID != nil ||
+		message.Type == model.MESSAGE_POLL || message.Type == model.MESSAGE_VOICE {
+		return model.MessageDB{}, model.ErrMessageNotEditable
+	}
+	// the restricted members can't change what they have sent before
+	if _, err := checkChatPermission(ctx, s.repoChats, request.ChatID, request.UserID, model.PERMISSION_SEND_MESSAGES); err != nil {
+		return model.MessageDB{}, err
+	}
+	if message.Type == model.MESSAGE_TEXT && request.Content == nil {
+		return model.MessageDB{}, model.ErrInvalidParamsOfMessage
+	}
+
+	edited := model.SendMessage{MessageDB: model.MessageDB{Content: request.Content}, Mentions: request.Mentions}
+	if err := resolveMentions(ctx, s.repoChats, request.ChatID, &edited); err != nil {
+		return model.MessageDB{}, err
+	}
+	var mentions []model.Mention
+	if edited.Mentions != nil {
+		mentions = *edited.Mentions
+	}
+
+	message.UpdatedAt, err = s.repoMessages.UpdateMessage(ctx, request.MessageID, request.Content, request.EncryptedEntities, mentions)
+	if err != nil {
+		return model.MessageDB{}, err
+	}
+	message.Content = request.Content
+	message.EncryptedEntities = request.EncryptedEntities
+
+	if err := s.repoMessages.SetAction(ctx, model.MessageAction{
+		MessageID: request.MessageID,
+		UserID:    request.UserID,
+		Type:      model.MESSAGE_ACTION_EDITED,
+	}); err != nil {
+		return model.MessageDB{}, err
+	}
+
+	return message, nil
+}
+
+func (s *MessageService) loadAttachments(ctx context.Context, messageDB model.MessageDB) model.SendMessage {
+	message := model.SendMessage{MessageDB: messageDB}
+
+	if files, err := s.repoFiles.GetFilesByMessageID(ctx, messageDB.MessageID); err == nil && len(files) > 0 {
+		message.Files = &files
+	}
+	if media, err := s.repoMedia.GetMediaFileByMessageID(ctx, messageDB.MessageID); err == nil && len(media) > 0 {
+		message.Media = &media
+	}
+	if locations, err := s.repoLocations.GetLocationsByMessageID(ctx, messageDB.MessageID); err == nil && len(locations) > 0 {
+		message.Locations = &locations
+	}
+	if messageDB.Type == model.MESSAGE_POLL {
+		if poll, err := s.repoPolls.GetPoll(ctx, messageDB.MessageID); err == nil {
+			poll = poll.ForViewer(nil, "")
+			message.Poll = &poll
+		}
+	}
+
+	return message
+}
+
+// DeleteMessage removes the message of the user or, with the permission, of anybody else in the chat.
+// Returns the message along with the replies of its thread.
+func (s *MessageService) DeleteMessage(ctx context.Context, chatID, messageID int64, userID string) ([]model.DeletedMessage, error) {
+	member, err := getChatMember(ctx, s.repoChats, chatID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	inChat, err := s.repoMessages.IsMessageInChat(ctx, messageID, chatID)
+	if err != nil {
+		return nil, err
+	}
+	if !inChat {
+		return nil, model.ErrMessageNotFound
+	}
+
+	message, err := s.repoMessages.GetMessageByMessageID(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if message.SenderID != userID && !member.Can(model.PERMISSION_DELETE_MESSAGES) {
+		return nil, model.ErrChatPermissionDenied
+	}
+
+	deleted, blobSHA256s, err := s.repoMessages.DeleteChatMessage(ctx, chatID, messageID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.ErrMessageNotFound
+		}
+		return nil, err
+	}
+
+	s.deleteOrphanBlobs(ctx, blobSHA256s)
+	return deleted, nil
+}
+
+// DeleteExpiredMessages removes a batch of disappearing messages whose time has come
+func (s *MessageService) DeleteExpiredMessages(ctx context.Context) ([]model.DeletedMessage, error) {
+	deleted, blobSHA256s, err := s.repoMessages.DeleteExpiredMessages(ctx, model.EXPIRED_BATCH_SIZE)
+	if err != nil {
+		return nil, err
+	}
+
+	s.deleteOrphanBlobs(ctx, blobSHA256s)
+	return deleted, nil
+}
+
+// deleteOrphanBlobs removes the blobs the deleted attachments left unused, the messages are gone already
+// so a failure leaves the content in the storage only
+func (s *MessageService) deleteOrphanBlobs(ctx context.Context, sha256s []string) {
+	if len(sha256s) == 0 {
+		return
+	}
+
+	blobs, err := s.repoUploads.DeleteOrphanBlobs(ctx, sha256s)
+	if err != nil {
+		logger.Error("Failed to delete orphan blobs", zap.Strings("sha256s", sha256s), zap.Error(err))
+		return
+	}
+	deleteBlobObjects(ctx, s.storage, blobs)
+}
@@ -59,6 +59,23 @@ func (s *NotificationService) SendNotification(ctx context.Context, notRMQ model
 			return err
 		}
 
+	case broker.EXCHANGE_MEDIA:
+		err = s.rabbitMQ.Channels[broker.EXCHANGE_MEDIA].Publish(
+			notRMQ.Exchange,
+			notRMQ.RoutingKey,
+			false,
+			false,
+			amqp.Publishing{
+				ContentType:  "application/json",
+				DeliveryMode: amqp.Persistent,
+				Body:         bytes,
+			},
+		)
+		if err != nil {
+			logger.Errorf("failed to publish to media exchange: %s", err.Error())
+			return err
+		}
+
 	default:
 		err := fmt.Errorf("unknown exchange: %s", notRMQ.Exchange)
 		logger.Errorf(err.Error())
@@ -68,3 +85,92 @@ func (s *NotificationService) SendNotification(ctx context.Context, notRMQ model
 	logger.Infof("Notification sent to %s with routing key %s", notRMQ.Exchange, notRMQ.RoutingKey)
 	return nil
 }
+
+// SendNotifications publishes the batch under one routing key in a single pass over the channel of the exchange,
+// the notifications published before a failure stay sent
+func (s *NotificationService) SendNotifications(ctx context.Context, notRMQ model.NotificationRabbitMQ, data []any) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	ch, ok := s.rabbitMQ.Channels[notRMQ.Exchange]
+	if !ok {
+		err := fmt.Errorf("unknown exchange: %s", notRMQ.Exchange)
+		logger.Errorf(err.Error())
+		return err
+	}
+
+	deliveryMode := amqp.Transient
+	if notRMQ.Exchange == broker.EXCHANGE_MEDIA {
+		deliveryMode = amqp.Persistent
+	}
+
+	for _, notification := range data {
+		bytes, err := json.Marshal(notification)
+		if err != nil {
+			logger.Errorf("failed to marshal data: %s", err.Error())
+			return err
+		}
+
+		err = ch.Publish(
+			notRMQ.Exchange,
+			notRMQ.RoutingKey,
+			false,
+			false,
+			amqp.Publishing{
+				ContentType:  "application/json",
+				DeliveryMode: deliveryMode,
+				Body:         bytes,
+			},
+		)
+		if err != nil {
+			logger.Errorf("failed to publish to %s exchange: %s", notRMQ.Exchange, err.Error())
+			return err
+		}
+	}
+
+	logger.Infof("%d notifications sent to %s with routing key %s", len(data), notRMQ.Exchange, notRMQ.RoutingKey)
+	return nil
+}
+
+// Consume handles the deliveries of the queue one at a time until the context is done.
+// Failed deliveries are requeued once and dropped after the second failure.
+func (s *NotificationService) Consume(ctx context.Context, queue string, handle func(ctx context.Context, body []byte) error) error {
+	ch, err := s.rabbitMQ.NewChannel()
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+
+	if err := ch.Qos(1, 0, false); err != nil {
+		return err
+	}
+
+	deliveries, err := ch.ConsumeWithContext(ctx, queue, "", false, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case d, ok := <-deliveries:
+			if !ok {
+				return fmt.Errorf("consumer of %s closed", queue)
+			}
+
+			if err := handle(ctx, d.Body); err != nil {
+				logger.Errorf("failed to handle delivery from %s: %s", queue, err.Error())
+				if err := d.Nack(false, !d.Redelivered); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if err := d.Ack(false); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"chat-api/internal/model"
+	repo "chat-api/internal/repository/psql"
+	"context"
+	"database/sql"
+	"errors"
+)
+
+func getChatMember(ctx context.Context, repoChats repo.Chats, chatID int64, userID string) (model.ChatMember, error) {
+	member, err := repoChats.GetChatMember(ctx, chatID, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return member, model.ErrNotParticipant
+	}
+	return member, err
+}
+
+// checkChatPermission returns the participant if the role or the defaults of the chat give all the permissions
+func checkChatPermission(ctx context.Context, repoChats repo.Chats, chatID int64, userID string, permissions int) (model.ChatMember, error) {
+	member, err := getChatMember(ctx, repoChats, chatID, userID)
+	if err != nil {
+		return member, err
+	}
+	if !member.Can(permissions) {
+		return member, model.ErrChatPermissionDenied
+	}
+	return member, nil
+}
+
+// getGroupChat returns the group or the channel, private chats always stay between their pair of users
+func getGroupChat(ctx context.Context, repoChats repo.Chats, chatID int64) (model.ChatDB, error) {
+	chat, err := repoChats.GetChatByChatID(ctx, chatID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return chat, model.ErrNotParticipant
+		}
+		return chat, err
+	}
+	if chat.Type == model.CHAT_TYPE_PRIVATE {
+		return chat, model.ErrInvalidParamsOfChat
+	}
+	return chat, nil
+}
+
+func getGroupChatWithPermission(ctx context.Context, repoChats repo.Chats, chatID int64, userID string, permissions int) (model.ChatDB, error) {
+	chat, err := getGroupChat(ctx, repoChats, chatID)
+	if err != nil {
+		return chat, err
+	}
+	_, err = checkChatPermission(ctx, repoChats, chatID, userID, permissions)
+	return chat, err
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"chat-api/internal/model"
+	repo "chat-api/internal/repository/psql"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type PollService struct {
+	repoPolls    repo.Polls
+	repoMessages repo.Messages
+	repoChats    repo.Chats
+}
+
+func NewPollService(polls repo.Polls, messages repo.Messages, chats repo.Chats) *PollService {
+	return &PollService{
+		repoPolls:    polls,
+		repoMessages: messages,
+		repoChats:    chats,
+	}
+}
+
+// Vote replaces the choice of the user. The state of the poll is returned only if the choice has changed.
+func (s *PollService) Vote(ctx context.Context, request model.PollVoteRequest) (*model.PollState, error) {
+	return s.replaceVotes(ctx, request, func(poll model.Poll, chosen []int) error {
+		if poll.IsClosed(time.Now().UTC()) {
+			return model.ErrPollClosed
+		}
+		// the answer of a quiz is final
+		if poll.Quiz && len(chosen) > 0 {
+			return model.ErrPollAlreadyVoted
+		}
+		if !model.IsValidPollVote(poll, request.OptionIDs) {
+			return model.ErrInvalidPollVote
+		}
+		return nil
+	})
+}
+
+// RetractVote removes the choice of the user, quiz answers can't be retracted
+func (s *PollService) RetractVote(ctx context.Context, request model.PollVoteRequest) (*model.PollState, error) {
+	request.OptionIDs = nil
+
+	return s.replaceVotes(ctx, request, func(poll model.Poll, chosen []int) error {
+		if poll.IsClosed(time.Now().UTC()) {
+			return model.ErrPollClosed
+		}
+		if poll.Quiz && len(chosen) > 0 {
+			return model.ErrPollAlreadyVoted
+		}
+		return nil
+	})
+}
+
+func (s *PollService) replaceVotes(ctx context.Context, request model.PollVoteRequest, check func(poll model.Poll, chosen []int) error) (*model.PollState, error) {
+	if err := s.validate(ctx, request); err != nil {
+		return nil, err
+	}
+
+	changed, err := s.repoPolls.ReplacePollVotes(ctx, request.MessageID, request.UserID, request.OptionIDs, check)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.ErrNotPollMessage
+		}
+		return nil, err
+	}
+	if !changed {
+		return nil, nil
+	}
+
+	return s.GetPollState(ctx, request.ChatID, request.MessageID)
+}
+
+// CloseDuePolls closes a batch of polls whose close time has come and returns them with the final votes
+func (s *PollService) CloseDuePolls(ctx context.Context) ([]model.PollState, error) {
+	closed, err := s.repoPolls.CloseDuePolls(ctx, model.POLL_CLOSE_BATCH_SIZE)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]model.PollState, 0, len(closed))
+	for _, poll := range closed {
+		state, err := s.GetPollState(ctx, poll.ChatID, poll.MessageID)
+		if err != nil {
+			return states, err
+		}
+		states = append(states, *state)
+	}
+
+	return states, nil
+}
+
+// GetPollState returns the poll with all its votes, model.ErrNotPollMessage if the poll is gone
+func (s *PollService) GetPollState(ctx context.Context, chatID, messageID int64) (*model.PollState, error) {
+	poll, err := s.repoPolls.GetPoll(ctx, messageID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.ErrNotPollMessage
+		}
+		return nil, err
+	}
+
+	votes, err := s.repoPolls.GetPollVotes(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.PollState{ChatID: chatID, Poll: poll, Votes: votes}, nil
+}
+
+func (s *PollService) validate(ctx context.Context, request model.PollVoteRequest) error {
+	// the restricted members can't vote, the channel subscribers can
+	if _, err := checkChatPermission(ctx, s.repoChats, request.ChatID, request.UserID, model.PERMISSION_REACT); err != nil {
+		return err
+	}
+
+	inChat, err := s.repoMessages.IsMessageInChat(ctx, request.MessageID, request.ChatID)
+	if err != nil {
+		return err
+	}
+	if !inChat {
+		return model.ErrMessageNotFound
+	}
+
+	return nil
+}
+
+// resolvePoll checks the poll of a new message and numbers its options,
+// other types of messages can't carry a poll
+func resolvePoll(chatDB model.ChatDB, message *model.SendMessage) error {
+	if message.MessageDB.Type != model.MESSAGE_POLL {
+		message.Poll = nil
+		return nil
+	}
+
+	if chatDB.Type != model.CHAT_TYPE_GROUP && chatDB.Type != model.CHAT_TYPE_CHANNEL {
+		return model.ErrPollNotAllowed
+	}
+	if !model.IsValidPoll(message.Poll, time.Now().UTC()) {
+		return model.ErrInvalidPoll
+	}
+
+	message.Poll.ClosedAt = nil
+	message.Poll.Results = nil
+	for i := range message.Poll.Options {
+		message.Poll.Options[i].OptionID = i
+	}
+
+	return nil
+}
@@ -0,0 +1,262 @@
+package service
+
+import (
+	"chat-api/internal/model"
+	"chat-api/internal/repository/cache"
+	repo "chat-api/internal/repository/psql"
+	"chat-api/pkg/logger"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// PresenceService tracks the status of the users by their sockets. The status is shared by the replicas
+// through the cache, while the subscriptions are kept by the replica holding the socket of the subscriber.
+type PresenceService struct {
+	repoPresence repo.Presence
+	repoChats    repo.Chats
+	cache        cache.PresenceCache
+
+	mu            sync.RWMutex
+	subscribers   map[string]map[string]struct{} // watched user -> subscribers
+	subscriptions map[string][]string            // subscriber -> watched users
+}
+
+func NewPresenceService(presence repo.Presence, chats repo.Chats, cache cache.PresenceCache) *PresenceService {
+	return &PresenceService{
+		repoPresence:  presence,
+		repoChats:     chats,
+		cache:         cache,
+		subscribers:   make(map[string]map[string]struct{}),
+		subscriptions: make(map[string][]string),
+	}
+}
+
+// Connect marks the user online once the socket is opened
+func (s *PresenceService) Connect(ctx context.Context, userID string) error {
+	if err := s.cache.SetPresence(ctx, userID, model.PRESENCE_ONLINE, model.PRESENCE_TTL); err != nil {
+		return err
+	}
+	return s.cache.PublishPresence(ctx, userID)
+}
+
+// Refresh keeps the user online while the socket is alive
+func (s *PresenceService) Refresh(ctx context.Context, userID string) error {
+	refreshed, err := s.cache.RefreshPresence(ctx, userID, model.PRESENCE_TTL)
+	if err != nil || refreshed {
+		return err
+	}
+
+	// the socket was silent longer than the ttl, the subscribers have already seen the user offline
+	return s.Connect(ctx, userID)
+}
+
+func (s *PresenceService) SetStatus(ctx context.Context, request model.PresenceUpdateRequest) error {
+	if !model.IsValidPresenceStatus(request.Status) {
+		return model.ErrInvalidPresence
+	}
+
+	if err := s.cache.SetPresence(ctx, request.UserID, request.Status, model.PRESENCE_TTL); err != nil {
+		return err
+	}
+	return s.cache.PublishPresence(ctx, request.UserID)
+}
+
+// Disconnect marks the user offline and drops the subscriptions of the closed socket
+func (s *PresenceService) Disconnect(ctx context.Context, userID string) error {
+	s.unsubscribe(userID)
+
+	if err := s.cache.DeletePresence(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.repoPresence.SetLastSeen(ctx, userID, time.Now().UTC()); err != nil {
+		return err
+	}
+	return s.cache.PublishPresence(ctx, userID)
+}
+
+// Subscribe replaces the users watched by the subscriber and returns their presence. Only the users
+// having the subscriber in their contacts or a private chat with the subscriber are watched, the rest are skipped.
+func (s *PresenceService) Subscribe(ctx context.Context, request model.PresenceSubscribeRequest) ([]model.Presence, error) {
+	if len(request.UsersIDs) > model.PRESENCE_SUBSCRIPTIONS_MAX {
+		return nil, model.ErrInvalidUserData
+	}
+
+	candidates := make([]string, 0, len(request.UsersIDs))
+	for _, userID := range request.UsersIDs {
+		if userID != "" && userID != request.UserID && !slices.Contains(candidates, userID) {
+			candidates = append(candidates, userID)
+		}
+	}
+
+	allowed := make([]string, 0, len(candidates))
+	for _, userID := range request.Contacts {
+		if slices.Contains(candidates, userID) && !slices.Contains(allowed, userID) {
+			allowed = append(allowed, userID)
+		}
+	}
+
+	if len(allowed) < len(candidates) {
+		partners, err := s.repoChats.GetPrivateChatPartners(ctx, request.UserID, candidates)
+		if err != nil {
+			return nil, err
+		}
+		for _, userID := range partners {
+			if !slices.Contains(allowed, userID) {
+				allowed = append(allowed, userID)
+			}
+		}
+	}
+
+	s.mu.Lock()
+	s.unsubscribeLocked(request.UserID)
+	for _, userID := range allowed {
+		if s.subscribers[userID] == nil {
+			s.subscribers[userID] = make(map[string]struct{})
+		}
+		s.subscribers[userID][request.UserID] = struct{}{}
+	}
+	if len(allowed) > 0 {
+		s.subscriptions[request.UserID] = allowed
+	}
+	s.mu.Unlock()
+
+	return s.getPresences(ctx, allowed)
+}
+
+// WatchPresence calls notify for every local subscriber of the user whose status changed on any replica
+func (s *PresenceService) WatchPresence(ctx context.Context, notify func(subscriberID string, presence model.Presence)) error {
+	return s.cache.SubscribePresence(ctx, func(userID string) {
+		subscribers := s.getSubscribers(userID)
+		if len(subscribers) == 0 {
+			return
+		}
+
+		presences, err := s.getPresences(ctx, []string{userID})
+		if err != nil {
+			logger.Error("Failed to get presence", zap.String("userID", userID), zap.Error(err))
+			return
+		}
+
+		for _, subscriberID := range subscribers {
+			notify(subscriberID, presences[0])
+		}
+	})
+}
+
+func (s *PresenceService) GetPresenceSettings(ctx context.Context, userID string) (model.PresenceSettings, error) {
+	presence, err := s.repoPresence.GetUserPresence(ctx, userID)
+	if err != nil {
+		return model.PresenceSettings{}, err
+	}
+	return model.PresenceSettings{HideLastSeen: presence.HideLastSeen}, nil
+}
+
+// UpdatePresenceSettings changes what the subscribers see from the next change of the status
+func (s *PresenceService) UpdatePresenceSettings(ctx context.Context, userID string, settings model.PresenceSettings) error {
+	if err := s.repoPresence.SetHideLastSeen(ctx, userID, settings.HideLastSeen); err != nil {
+		return err
+	}
+	return s.cache.PublishPresence(ctx, userID)
+}
+
+// SendChatActivity checks the typing or uploading of the user and returns the participants to show it to,
+// ErrChatActivityTooOften means the same activity was shown shortly before
+func (s *PresenceService) SendChatActivity(ctx context.Context, request model.ChatActivityRequest) ([]string, error) {
+	if !model.IsValidChatActivity(request.Action) {
+		return nil, model.ErrInvalidChatActivity
+	}
+
+	// nobody waits for a message the user isn't allowed to send
+	if _, err := checkChatPermission(ctx, s.repoChats, request.ChatID, request.UserID, model.PERMISSION_SEND_MESSAGES); err != nil {
+		return nil, err
+	}
+
+	blocked, err := s.repoChats.IsBlockedChatExists(ctx, request.ChatID, request.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if blocked {
+		return nil, model.ErrChatBlocked
+	}
+
+	allowed, err := s.cache.AllowChatActivity(ctx, request.ChatID, request.UserID, request.Action, model.CHAT_ACTIVITY_INTERVAL)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, model.ErrChatActivityTooOften
+	}
+
+	participantsIDs, err := s.repoChats.GetAllParticipantsByChatID(ctx, request.ChatID)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(participantsIDs, func(userID string) bool {
+		return userID == request.UserID
+	}), nil
+}
+
+// getPresences combines the status from the cache with the stored last seen, which is
+// hidden for the online users and for the users who chose to hide it
+func (s *PresenceService) getPresences(ctx context.Context, usersIDs []string) ([]model.Presence, error) {
+	if len(usersIDs) == 0 {
+		return []model.Presence{}, nil
+	}
+
+	presences, err := s.cache.GetPresences(ctx, usersIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := s.repoPresence.GetUsersPresence(ctx, usersIDs)
+	if err != nil {
+		return nil, err
+	}
+	byUserID := make(map[string]model.UserPresence, len(stored))
+	for _, presence := range stored {
+		byUserID[presence.UserID] = presence
+	}
+
+	for i := range presences {
+		stored := byUserID[presences[i].UserID]
+		if presences[i].Status != model.PRESENCE_OFFLINE || stored.HideLastSeen {
+			presences[i].LastSeenAt = nil
+			continue
+		}
+		if presences[i].LastSeenAt == nil || (stored.LastSeenAt != nil && stored.LastSeenAt.After(*presences[i].LastSeenAt)) {
+			presences[i].LastSeenAt = stored.LastSeenAt
+		}
+	}
+	return presences, nil
+}
+
+func (s *PresenceService) getSubscribers(userID string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	subscribers := make([]string, 0, len(s.subscribers[userID]))
+	for subscriberID := range s.subscribers[userID] {
+		subscribers = append(subscribers, subscriberID)
+	}
+	return subscribers
+}
+
+func (s *PresenceService) unsubscribe(subscriberID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unsubscribeLocked(subscriberID)
+}
+
+func (s *PresenceService) unsubscribeLocked(subscriberID string) {
+	for _, userID := range s.subscriptions[subscriberID] {
+		delete(s.subscribers[userID], subscriberID)
+		if len(s.subscribers[userID]) == 0 {
+			delete(s.subscribers, userID)
+		}
+	}
+	delete(s.subscriptions, subscriberID)
+}
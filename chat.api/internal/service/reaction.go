@@ -0,0 +1,76 @@
+package service
+
+import (
+	"chat-api/internal/model"
+	repo "chat-api/internal/repository/psql"
+	"context"
+	"unicode/utf8"
+)
+
+type ReactionService struct {
+	repoReactions repo.Reactions
+	repoMessages  repo.Messages
+	repoChats     repo.Chats
+}
+
+func NewReactionService(reactions repo.Reactions, messages repo.Messages, chats repo.Chats) *ReactionService {
+	return &ReactionService{
+		repoReactions: reactions,
+		repoMessages:  messages,
+		repoChats:     chats,
+	}
+}
+
+func (s *ReactionService) AddReaction(ctx context.Context, request model.ReactionRequest) (model.MessageDB, error) {
+	if err := s.validate(ctx, request); err != nil {
+		return model.MessageDB{}, err
+	}
+
+	if err := s.repoReactions.SetReaction(ctx, model.Reaction{
+		MessageID: request.MessageID,
+		UserID:    request.UserID,
+		Emoji:     request.Emoji,
+	}); err != nil {
+		return model.MessageDB{}, err
+	}
+
+	// the author of the message is notified about the reaction
+	return s.repoMessages.GetMessageByMessageID(ctx, request.MessageID)
+}
+
+func (s *ReactionService) RemoveReaction(ctx context.Context, request model.ReactionRequest) error {
+	if err := s.validate(ctx, request); err != nil {
+		return err
+	}
+
+	return s.repoReactions.DeleteReaction(ctx, model.Reaction{
+		MessageID: request.MessageID,
+		UserID:    request.UserID,
+		Emoji:     request.Emoji,
+	})
+}
+
+func (s *ReactionService) GetReactions(ctx context.Context, messageID int64) ([]model.Reaction, error) {
+	return s.repoReactions.GetReactionsByMessageID(ctx, messageID)
+}
+
+func (s *ReactionService) validate(ctx context.Context, request model.ReactionRequest) error {
+	if request.Emoji == "" || len(request.Emoji) > model.REACTION_MAX_LENGTH || !utf8.ValidString(request.Emoji) {
+		return model.ErrInvalidReaction
+	}
+
+	// the restricted members can't react, the channel subscribers can
+	if _, err := checkChatPermission(ctx, s.repoChats, request.ChatID, request.UserID, model.PERMISSION_REACT); err != nil {
+		return err
+	}
+
+	inChat, err := s.repoMessages.IsMessageInChat(ctx, request.MessageID, request.ChatID)
+	if err != nil {
+		return err
+	}
+	if !inChat {
+		return model.ErrMessageNotFound
+	}
+
+	return nil
+}
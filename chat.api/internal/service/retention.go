@@ -0,0 +1,242 @@
+package service
+
+import (
+	"chat-api/internal/model"
+	repo "chat-api/internal/repository/psql"
+	"chat-api/pkg/logger"
+	"chat-api/pkg/storage"
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type RetentionService struct {
+	repoRetention repo.Retention
+	repoChats     repo.Chats
+	storage       storage.Storage
+}
+
+func NewRetentionService(retention repo.Retention, chats repo.Chats, storage storage.Storage) *RetentionService {
+	return &RetentionService{
+		repoRetention: retention,
+		repoChats:     chats,
+		storage:       storage,
+	}
+}
+
+func (s *RetentionService) GetRetentionPolicies(ctx context.Context) ([]model.RetentionPolicy, error) {
+	policies, err := s.repoRetention.GetRetentionPolicies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if policies == nil {
+		policies = []model.RetentionPolicy{}
+	}
+	return policies, nil
+}
+
+// SetRetentionPolicy sets the policy of the chat type or, if ChatID is set, of the chat
+func (s *RetentionService) SetRetentionPolicy(ctx context.Context, request model.RetentionPolicyRequest) (model.RetentionPolicy, error) {
+	if !request.IsValid() {
+		return model.RetentionPolicy{}, model.ErrInvalidRetention
+	}
+
+	if request.ChatID == 0 {
+		return s.repoRetention.SetTypeRetentionPolicy(ctx, request.ChatType, request.RetentionDays)
+	}
+
+	if err := s.checkChatExists(ctx, request.ChatID); err != nil {
+		return model.RetentionPolicy{}, err
+	}
+	return s.repoRetention.SetChatRetentionPolicy(ctx, request.ChatID, request.RetentionDays)
+}
+
+// DeleteRetentionPolicy keeps the messages of the chat type forever, or brings the chat back to the policy of its type
+func (s *RetentionService) DeleteRetentionPolicy(ctx context.Context, request model.RetentionPolicyRequest) error {
+	var err error
+	switch {
+	case request.ChatID != 0:
+		err = s.repoRetention.DeleteChatRetentionPolicy(ctx, request.ChatID)
+	case model.IsRetentionChatType(request.ChatType):
+		err = s.repoRetention.DeleteTypeRetentionPolicy(ctx, request.ChatType)
+	default:
+		return model.ErrInvalidRetention
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.ErrRetentionNotFound
+	}
+	return err
+}
+
+func (s *RetentionService) GetLegalHolds(ctx context.Context) ([]model.LegalHold, error) {
+	holds, err := s.repoRetention.GetLegalHolds(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if holds == nil {
+		holds = []model.LegalHold{}
+	}
+	return holds, nil
+}
+
+// SetLegalHold puts the chat or the messages of the user on hold, a target may have several holds for different matters
+func (s *RetentionService) SetLegalHold(ctx context.Context, hold model.LegalHold) (model.LegalHold, error) {
+	if !hold.IsValid() {
+		return model.LegalHold{}, model.ErrInvalidLegalHold
+	}
+	hold.Reason = strings.TrimSpace(hold.Reason)
+
+	if hold.ChatID != nil {
+		if err := s.checkChatExists(ctx, *hold.ChatID); err != nil {
+			return model.LegalHold{}, err
+		}
+	}
+
+	return s.repoRetention.SetLegalHold(ctx, hold)
+}
+
+// ReleaseLegalHold resumes the deletion unless another hold covers the messages, the deletion catches up on the next run
+func (s *RetentionService) ReleaseLegalHold(ctx context.Context, holdID int64) (model.LegalHold, error) {
+	hold, err := s.repoRetention.ReleaseLegalHold(ctx, holdID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.LegalHold{}, model.ErrLegalHoldNotFound
+	}
+	return hold, err
+}
+
+func (s *RetentionService) GetRetentionRuns(ctx context.Context) ([]model.RetentionRun, error) {
+	runs, err := s.repoRetention.GetRuns(ctx, model.RETENTION_RUNS_LIMIT)
+	if err != nil {
+		return nil, err
+	}
+	if runs == nil {
+		runs = []model.RetentionRun{}
+	}
+	return runs, nil
+}
+
+func (s *RetentionService) GetRetentionRun(ctx context.Context, runID int64) (model.RetentionRun, error) {
+	run, err := s.repoRetention.GetRun(ctx, runID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.RetentionRun{}, model.ErrRetentionRunNotFound
+	}
+	return run, err
+}
+
+// GetRetentionPurges returns the audit of the run, paging by the "after" purge_id
+func (s *RetentionService) GetRetentionPurges(ctx context.Context, request model.RetentionPurgesRequest) ([]model.RetentionPurge, error) {
+	if _, err := s.GetRetentionRun(ctx, request.RunID); err != nil {
+		return nil, err
+	}
+
+	if request.Limit <= 0 || request.Limit > model.RETENTION_PURGES_LIMIT {
+		request.Limit = model.RETENTION_PURGES_LIMIT
+	}
+
+	purges, err := s.repoRetention.GetPurges(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	if purges == nil {
+		purges = []model.RetentionPurge{}
+	}
+	return purges, nil
+}
+
+// RunRetention deletes the messages past the policies of their chats in batches, progress gets every purged batch.
+// Only one run goes at a time across the replicas, model.ErrRetentionRunning if another one is in progress
+func (s *RetentionService) RunRetention(ctx context.Context, progress func(ctx context.Context, run model.RetentionRun, deleted []model.DeletedMessage)) (model.RetentionRun, error) {
+	now := time.Now().UTC()
+	if _, err := s.repoRetention.FailStaleRuns(ctx, now.Add(-model.RETENTION_RUN_TIMEOUT)); err != nil {
+		return model.RetentionRun{}, err
+	}
+
+	run, err := s.repoRetention.StartRun(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.RetentionRun{}, model.ErrRetentionRunning
+		}
+		return model.RetentionRun{}, err
+	}
+
+	if err := s.purge(ctx, &run, now, progress); err != nil {
+		logger.Error("Failed to purge messages by retention policies", zap.Int64("runID", run.RunID), zap.Error(err))
+
+		message := err.Error()
+		if _, finishErr := s.repoRetention.FinishRun(ctx, run.RunID, model.RETENTION_RUN_FAILED, &message); finishErr != nil {
+			logger.Error("Failed to finish retention run", zap.Int64("runID", run.RunID), zap.Error(finishErr))
+		}
+		return run, err
+	}
+
+	return s.repoRetention.FinishRun(ctx, run.RunID, model.RETENTION_RUN_FINISHED, nil)
+}
+
+func (s *RetentionService) purge(ctx context.Context, run *model.RetentionRun, now time.Time, progress func(ctx context.Context, run model.RetentionRun, deleted []model.DeletedMessage)) error {
+	var afterChatID int64
+	for {
+		retentions, err := s.repoRetention.GetChatRetentions(ctx, afterChatID, model.RETENTION_CHATS_BATCH)
+		if err != nil {
+			return err
+		}
+
+		for _, retention := range retentions {
+			purgedBefore := retention.PurgedBefore(now)
+			for {
+				batch, err := s.repoRetention.PurgeChatMessages(ctx, run.RunID, retention, purgedBefore, model.RETENTION_BATCH_SIZE)
+				if err != nil {
+					return err
+				}
+				if len(batch.Deleted) == 0 {
+					break
+				}
+
+				run.MessagesPurged += len(batch.Deleted)
+				progress(ctx, *run, batch.Deleted)
+
+				if err := s.deleteOrphanBlobs(ctx, batch); err != nil {
+					return err
+				}
+			}
+
+			run.ChatsProcessed++
+			if err := s.repoRetention.SetRunChatsProcessed(ctx, run.RunID, run.ChatsProcessed); err != nil {
+				return err
+			}
+		}
+
+		if len(retentions) < model.RETENTION_CHATS_BATCH {
+			return nil
+		}
+		afterChatID = retentions[len(retentions)-1].ChatID
+	}
+}
+
+// deleteOrphanBlobs removes the blobs the purged attachments left unused, a blob still shared
+// by a forwarded copy or another upload of the same bytes stays
+func (s *RetentionService) deleteOrphanBlobs(ctx context.Context, batch model.RetentionBatch) error {
+	if len(batch.BlobSHA256s) == 0 {
+		return nil
+	}
+
+	blobs, err := s.repoRetention.DeleteOrphanBlobs(ctx, batch.PurgeID, batch.BlobSHA256s)
+	if err != nil {
+		return err
+	}
+
+	deleteBlobObjects(ctx, s.storage, blobs)
+	return nil
+}
+
+func (s *RetentionService) checkChatExists(ctx context.Context, chatID int64) error {
+	_, err := s.repoChats.GetChatByChatID(ctx, chatID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.ErrChatNotFound
+	}
+	return err
+}
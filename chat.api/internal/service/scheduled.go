@@ -0,0 +1,181 @@
+package service
+
+import (
+	"chat-api/internal/model"
+	repo "chat-api/internal/repository/psql"
+	"chat-api/pkg/logger"
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type ScheduledMessageService struct {
+	repoScheduled repo.ScheduledMessages
+	repoChats     repo.Chats
+}
+
+func NewScheduledMessageService(scheduled repo.ScheduledMessages, chats repo.Chats) *ScheduledMessageService {
+	return &ScheduledMessageService{
+		repoScheduled: scheduled,
+		repoChats:     chats,
+	}
+}
+
+func (s *ScheduledMessageService) ScheduleMessage(ctx context.Context, request model.ScheduledMessageRequest) (model.ScheduledMessage, error) {
+	if err := s.validate(ctx, request); err != nil {
+		return model.ScheduledMessage{}, err
+	}
+
+	scheduled := newScheduledMessage(request)
+
+	var err error
+	scheduled.ScheduledMessageID, scheduled.CreatedAt, err = s.repoScheduled.SetScheduledMessage(ctx, scheduled)
+	if err != nil {
+		return model.ScheduledMessage{}, err
+	}
+	scheduled.UpdatedAt = scheduled.CreatedAt
+
+	return scheduled, nil
+}
+
+func (s *ScheduledMessageService) GetScheduledMessages(ctx context.Context, chatID int64, userID string) ([]model.ScheduledMessage, error) {
+	return s.repoScheduled.GetPendingScheduledMessages(ctx, chatID, userID)
+}
+
+func (s *ScheduledMessageService) EditScheduledMessage(ctx context.Context, request model.ScheduledMessageRequest) error {
+	if err := s.validate(ctx, request); err != nil {
+		return err
+	}
+
+	updated, err := s.repoScheduled.UpdateScheduledMessage(ctx, newScheduledMessage(request))
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return model.ErrScheduledNotFound
+	}
+
+	return nil
+}
+
+func (s *ScheduledMessageService) CancelScheduledMessage(ctx context.Context, scheduledMessageID, chatID int64, userID string) error {
+	deleted, err := s.repoScheduled.DeleteScheduledMessage(ctx, scheduledMessageID, chatID, userID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return model.ErrScheduledNotFound
+	}
+
+	return nil
+}
+
+// SendDueScheduledMessages claims the due messages and passes each of them to send, the sender must still be
+// able to write into the chat. A failed message is retried up to model.SCHEDULED_MESSAGE_MAX_ATTEMPTS times.
+// It returns the number of claimed messages.
+func (s *ScheduledMessageService) SendDueScheduledMessages(ctx context.Context, send func(ctx context.Context, request *model.CreateMessageRequest) error) (int, error) {
+	claimed, err := s.repoScheduled.ClaimDueScheduledMessages(ctx, model.SCHEDULED_MESSAGE_BATCH_SIZE, model.SCHEDULED_MESSAGE_RETRY_DELAY, model.SCHEDULED_MESSAGE_CLAIM_TIMEOUT)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, scheduled := range claimed {
+		status, sentMessageID := s.sendScheduledMessage(ctx, scheduled, send)
+		if _, err := s.repoScheduled.FinishScheduledMessage(ctx, scheduled.ScheduledMessageID, status, sentMessageID); err != nil {
+			return len(claimed), err
+		}
+	}
+
+	return len(claimed), nil
+}
+
+// sendScheduledMessage returns the status the claimed message ends up with
+func (s *ScheduledMessageService) sendScheduledMessage(ctx context.Context, scheduled model.ScheduledMessage, send func(ctx context.Context, request *model.CreateMessageRequest) error) (string, *int64) {
+	// the sender lost the right to write, no retry brings it back
+	if err := s.checkSender(ctx, scheduled.ChatID, scheduled.SenderID, model.MessagePermissions(scheduled.Message.MessageDB.Type)); err != nil {
+		logger.Warn("Scheduled message can't be sent", zap.Int64("scheduledMessageID", scheduled.ScheduledMessageID), zap.Error(err))
+		return model.SCHEDULED_MESSAGE_FAILED, nil
+	}
+
+	request := scheduled.CreateMessageRequest()
+	err := send(ctx, &request)
+
+	// send reserves the id before it stores the message, the message is stored once it has the time
+	messageID := request.MessageWithData.MessageDB.MessageID
+	switch {
+	case err == nil:
+		return model.SCHEDULED_MESSAGE_SENT, &messageID
+	case !request.MessageWithData.MessageDB.CreatedAt.IsZero():
+		// the message stored before the failure isn't sent twice
+		logger.Warn("Scheduled message is stored but not delivered", zap.Int64("scheduledMessageID", scheduled.ScheduledMessageID), zap.Error(err))
+		return model.SCHEDULED_MESSAGE_SENT, &messageID
+	}
+
+	if scheduled.Attempts >= model.SCHEDULED_MESSAGE_MAX_ATTEMPTS {
+		logger.Error("Failed to send scheduled message", zap.Int64("scheduledMessageID", scheduled.ScheduledMessageID), zap.Int("attempts", scheduled.Attempts), zap.Error(err))
+		return model.SCHEDULED_MESSAGE_FAILED, nil
+	}
+
+	logger.Warn("Failed to send scheduled message, retrying", zap.Int64("scheduledMessageID", scheduled.ScheduledMessageID), zap.Int("attempts", scheduled.Attempts), zap.Error(err))
+	return model.SCHEDULED_MESSAGE_PENDING, nil
+}
+
+func (s *ScheduledMessageService) validate(ctx context.Context, request model.ScheduledMessageRequest) error {
+	if request.UserID == "" || request.Message.MessageDB.Type == "" {
+		return model.ErrInvalidParamsOfMessage
+	}
+
+	if !request.SendAt.After(time.Now()) {
+		return model.ErrInvalidSendTime
+	}
+
+	chatDB, err := s.repoChats.GetChatByChatID(ctx, request.ChatID)
+	if err != nil {
+		return err
+	}
+	if chatDB.Encrypted {
+		return model.ErrChatEncrypted
+	}
+
+	// the poll is checked again once it's sent, its close time must come after that
+	if err := resolvePoll(chatDB, &request.Message); err != nil {
+		return err
+	}
+	if poll := request.Message.Poll; poll != nil && poll.CloseAt != nil && !poll.CloseAt.After(request.SendAt) {
+		return model.ErrInvalidPoll
+	}
+	if err := resolveMentions(ctx, s.repoChats, request.ChatID, &request.Message); err != nil {
+		return err
+	}
+
+	return s.checkSender(ctx, request.ChatID, request.UserID, model.MessagePermissions(request.Message.MessageDB.Type))
+}
+
+func (s *ScheduledMessageService) checkSender(ctx context.Context, chatID int64, userID string, permissions int) error {
+	if _, err := checkChatPermission(ctx, s.repoChats, chatID, userID, permissions); err != nil {
+		return err
+	}
+
+	blocked, err := s.repoChats.IsBlockedChatExists(ctx, chatID, userID)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return model.ErrChatBlocked
+	}
+
+	return nil
+}
+
+func newScheduledMessage(request model.ScheduledMessageRequest) model.ScheduledMessage {
+	return model.ScheduledMessage{
+		ScheduledMessageID: request.ScheduledMessageID,
+		ChatID:             request.ChatID,
+		SenderID:           request.UserID,
+		Message:            request.Message,
+		InThread:           request.InThread,
+		SendAt:             request.SendAt.UTC(),
+		Status:             model.SCHEDULED_MESSAGE_PENDING,
+	}
+}
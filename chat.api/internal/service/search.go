@@ -0,0 +1,149 @@
+package service
+
+import (
+	"chat-api/internal/model"
+	repo "chat-api/internal/repository/psql"
+	"chat-api/pkg/crypto"
+	"context"
+)
+
+type SearchService struct {
+	repoSearch   repo.Search
+	repoMessages repo.Messages
+	repoChats    repo.Chats
+	indexer      *crypto.BlindIndexer
+	chats        *ChatService
+}
+
+func NewSearchService(search repo.Search, messages repo.Messages, chats repo.Chats, indexer *crypto.BlindIndexer, chatService *ChatService) *SearchService {
+	return &SearchService{
+		repoSearch:   search,
+		repoMessages: messages,
+		repoChats:    chats,
+		indexer:      indexer,
+		chats:        chatService,
+	}
+}
+
+// IndexMessage replaces the tokens of the message by those of its plain content, nil content removes them
+func (s *SearchService) IndexMessage(ctx context.Context, chatID, messageID int64, content *string) error {
+	var tokens []string
+	if content != nil {
+		tokens = s.indexer.Tokens(chatID, *content, model.SEARCH_MESSAGE_WORDS_LIMIT)
+	}
+	return s.repoSearch.SetMessageTokens(ctx, messageID, chatID, tokens)
+}
+
+// Search finds the messages containing all the words of the query in the chat or in all chats of the user,
+// the end-to-end encrypted chats are never indexed
+func (s *SearchService) Search(ctx context.Context, request model.SearchRequest) ([]model.SearchResult, error) {
+	words := crypto.SearchWords(request.Query)
+	if len(words) == 0 || len(words) > model.SEARCH_QUERY_WORDS_LIMIT || !request.IsValid() {
+		return nil, model.ErrInvalidSearch
+	}
+
+	if request.Limit <= 0 || request.Limit > model.SEARCH_LIMIT_REQUEST {
+		request.Limit = model.SEARCH_LIMIT_REQUEST
+	}
+
+	chatIDs, err := s.searchedChats(ctx, request.ChatID, request.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if len(chatIDs) == 0 {
+		return []model.SearchResult{}, nil
+	}
+
+	filter := model.SearchFilter{
+		UserID:          request.UserID,
+		WordsCount:      len(words),
+		SenderID:        request.SenderID,
+		Since:           request.Since,
+		Until:           request.Until,
+		Type:            request.Type,
+		BeforeMessageID: request.BeforeMessageID,
+		Limit:           request.Limit,
+	}
+	// the token of the word differs in every chat
+	for _, chatID := range chatIDs {
+		for i, word := range words {
+			filter.ChatIDs = append(filter.ChatIDs, chatID)
+			filter.Tokens = append(filter.Tokens, s.indexer.Token(chatID, word))
+			filter.Words = append(filter.Words, i)
+		}
+	}
+
+	hits, err := s.repoSearch.SearchMessages(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(hits) == 0 {
+		return []model.SearchResult{}, nil
+	}
+
+	messageIDs := make([]int64, len(hits))
+	for i, hit := range hits {
+		messageIDs[i] = hit.MessageID
+	}
+
+	messagesDB, err := s.repoMessages.GetMessagesByIDs(ctx, messageIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int64]model.MessageDB, len(messagesDB))
+	for _, messageDB := range messagesDB {
+		byID[messageDB.MessageID] = messageDB
+	}
+
+	// the message may be deleted between the queries
+	found := hits[:0]
+	ordered := make([]model.MessageDB, 0, len(hits))
+	for _, hit := range hits {
+		messageDB, ok := byID[hit.MessageID]
+		if !ok {
+			continue
+		}
+		found = append(found, hit)
+		ordered = append(ordered, messageDB)
+	}
+
+	messages := s.chats.loadMessages(ctx, ordered, request.UserID)
+
+	results := make([]model.SearchResult, len(found))
+	for i, hit := range found {
+		results[i] = model.SearchResult{ChatID: hit.ChatID, Message: messages[i]}
+	}
+	return results, nil
+}
+
+// searchedChats returns the chat if the user takes part in it, or all chats of the user with the content on the server
+func (s *SearchService) searchedChats(ctx context.Context, chatID int64, userID string) ([]int64, error) {
+	if chatID != 0 {
+		if _, err := getChatMember(ctx, s.repoChats, chatID, userID); err != nil {
+			return nil, err
+		}
+
+		chat, err := s.repoChats.GetChatByChatID(ctx, chatID)
+		if err != nil {
+			return nil, err
+		}
+		if chat.Encrypted {
+			return nil, model.ErrChatEncrypted
+		}
+		return []int64{chatID}, nil
+	}
+
+	chats, err := s.repoChats.GetAllChatsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	chatIDs := make([]int64, 0, len(chats))
+	for _, chat := range chats {
+		if !chat.Encrypted {
+			chatIDs = append(chatIDs, chat.ChatID)
+		}
+	}
+	return chatIDs, nil
+}
@@ -4,10 +4,17 @@ import (
 	"chat-api/internal/model"
 	"chat-api/internal/repository/cache"
 	repo "chat-api/internal/repository/psql"
+	"chat-api/pkg/audio"
 	"chat-api/pkg/auth"
 	"chat-api/pkg/broker"
 	"chat-api/pkg/crypto"
+	"chat-api/pkg/imaging"
+	"chat-api/pkg/linkpreview"
+	"chat-api/pkg/storage"
 	"context"
+	"io"
+	"net/url"
+	"time"
 )
 
 type Services struct {
@@ -15,6 +22,26 @@ type Services struct {
 	Messages         Messages
 	Auth             Auth
 	Notifications    Notifications
+	Reactions        Reactions
+	Scheduled        ScheduledMessages
+	E2EE             E2EE
+	KeyRotation      KeyRotation
+	Uploads          Uploads
+	MediaProcessing  MediaProcessing
+	Voice            Voice
+	Polls            Polls
+	Mentions         Mentions
+	LinkPreviews     LinkPreviews
+	Presence         Presence
+	Invites          Invites
+	Members          Members
+	Channels         Channels
+	ChatList         ChatList
+	Bookmarks        Bookmarks
+	Search           Search
+	Exports          Exports
+	Imports          Imports
+	Retention        Retention
 	MessageEncrypter crypto.MessageEncrypter
 }
 
@@ -23,31 +50,71 @@ type Deps struct {
 	tokenManager     auth.TokenManager
 	rabbitMQ         *broker.RabbitMQ
 	messageEncrypter crypto.MessageEncrypter
+	keyProvider      crypto.KeyProvider
+	blindIndexer     *crypto.BlindIndexer
+	storage          storage.Storage
+	urlSigner        *storage.URLSigner
+	videoProber      *imaging.VideoProber
+	audioAnalyzer    *audio.Analyzer
+	linkFetcher      *linkpreview.Fetcher
 	cache            *cache.Cache
+	complianceKey    string
 }
 
 func NewServices(deps *Deps) *Services {
+	chats := NewChatService(deps.repositories.Messages, deps.repositories.Chats, deps.repositories.Media, deps.repositories.Files, deps.repositories.Locations, deps.repositories.Pinned, deps.repositories.Reactions, deps.repositories.Voice, deps.repositories.Polls, deps.repositories.Mentions, deps.repositories.Previews, deps.repositories.Channels, deps.messageEncrypter)
+	search := NewSearchService(deps.repositories.Search, deps.repositories.Messages, deps.repositories.Chats, deps.blindIndexer, chats)
+
 	return &Services{
-		Chats:            NewChatService(deps.repositories.Messages, deps.repositories.Chats, deps.repositories.Media, deps.repositories.Files, deps.repositories.Locations, deps.repositories.Pinned),
-		Messages:         NewMessageService(deps.repositories.Messages, deps.repositories.Files, deps.repositories.Media, deps.repositories.Locations),
-		Auth:             NewAuthService(deps.tokenManager, deps.cache),
+		Chats:            chats,
+		Messages:         NewMessageService(deps.repositories.Messages, deps.repositories.Files, deps.repositories.Media, deps.repositories.Locations, deps.repositories.Chats, deps.repositories.Polls, deps.repositories.Mentions, deps.repositories.Channels, deps.repositories.Previews, deps.repositories.Uploads, deps.storage, deps.messageEncrypter),
+		Auth:             NewAuthService(deps.tokenManager, deps.cache, deps.complianceKey),
 		Notifications:    NewNotificationService(deps.rabbitMQ),
+		Reactions:        NewReactionService(deps.repositories.Reactions, deps.repositories.Messages, deps.repositories.Chats),
+		Scheduled:        NewScheduledMessageService(deps.repositories.Scheduled, deps.repositories.Chats),
+		E2EE:             NewE2EEService(deps.repositories.Keys, deps.repositories.Chats, deps.cache.KeysCache),
+		KeyRotation:      NewKeyRotationService(deps.repositories.DataKeys, deps.repositories.Messages, deps.keyProvider, deps.messageEncrypter),
+		Uploads:          NewUploadService(deps.repositories.Uploads, deps.repositories.Files, deps.repositories.Media, deps.repositories.Chats, deps.repositories.Bookmarks, deps.storage, deps.urlSigner, NewNotificationService(deps.rabbitMQ), deps.audioAnalyzer),
+		MediaProcessing:  NewMediaProcessingService(deps.repositories.Media, deps.repositories.Uploads, deps.storage, deps.videoProber, NewNotificationService(deps.rabbitMQ)),
+		Voice:            NewVoiceService(deps.repositories.Voice, deps.repositories.Messages, deps.repositories.Chats),
+		Polls:            NewPollService(deps.repositories.Polls, deps.repositories.Messages, deps.repositories.Chats),
+		Mentions:         NewMentionService(deps.repositories.Mentions, deps.repositories.Chats),
+		LinkPreviews:     NewLinkPreviewService(deps.repositories.Previews, deps.repositories.Messages, deps.cache.LinkPreviewCache, deps.linkFetcher, NewNotificationService(deps.rabbitMQ), deps.messageEncrypter),
+		Presence:         NewPresenceService(deps.repositories.Presence, deps.repositories.Chats, deps.cache.PresenceCache),
+		Invites:          NewInviteService(deps.repositories.Invites, deps.repositories.Chats),
+		Members:          NewMemberService(deps.repositories.Members, deps.repositories.Chats, deps.repositories.Channels),
+		Channels:         NewChannelService(deps.repositories.Channels, deps.repositories.Chats, NewNotificationService(deps.rabbitMQ)),
+		ChatList:         NewChatListService(deps.repositories.ChatList, deps.repositories.Chats, deps.repositories.Messages),
+		Bookmarks:        NewBookmarkService(deps.repositories.Bookmarks, deps.repositories.Messages, deps.repositories.Chats, chats),
+		Search:           search,
+		Exports:          NewExportService(deps.repositories.Exports, deps.repositories.Messages, deps.repositories.Chats, deps.repositories.Channels, deps.repositories.Uploads, deps.storage, NewNotificationService(deps.rabbitMQ), chats),
+		Imports:          NewImportService(deps.repositories.Imports, deps.repositories.Messages, deps.repositories.Chats, deps.messageEncrypter, search),
+		Retention:        NewRetentionService(deps.repositories.Retention, deps.repositories.Chats, deps.storage),
 		MessageEncrypter: deps.messageEncrypter,
 	}
 }
 
-func NewDeps(repo *repo.Repositories, tkManager auth.TokenManager, rabbit *broker.RabbitMQ, messageEncrypter crypto.MessageEncrypter, cache *cache.Cache) *Deps {
+func NewDeps(repo *repo.Repositories, tkManager auth.TokenManager, rabbit *broker.RabbitMQ, messageEncrypter crypto.MessageEncrypter, keyProvider crypto.KeyProvider, blindIndexer *crypto.BlindIndexer, storage storage.Storage, urlSigner *storage.URLSigner, videoProber *imaging.VideoProber, audioAnalyzer *audio.Analyzer, linkFetcher *linkpreview.Fetcher, cache *cache.Cache, complianceKey string) *Deps {
 	return &Deps{
 		repositories:     repo,
 		tokenManager:     tkManager,
 		rabbitMQ:         rabbit,
 		messageEncrypter: messageEncrypter,
+		keyProvider:      keyProvider,
+		blindIndexer:     blindIndexer,
+		storage:          storage,
+		urlSigner:        urlSigner,
+		videoProber:      videoProber,
+		audioAnalyzer:    audioAnalyzer,
+		linkFetcher:      linkFetcher,
 		cache:            cache,
+		complianceKey:    complianceKey,
 	}
 }
 
 type Auth interface {
 	ValidateToken(token string) (string, error)
+	ValidateComplianceKey(key string) error
 	SetWebSocket(ctx context.Context, ws model.WebSocketConnection) error
 	GetWebSocket(ctx context.Context, userID string) (*model.WebSocketConnection, error)
 	UpdateWebSocket(ctx context.Context, userID string) error
@@ -56,10 +123,17 @@ type Auth interface {
 
 type Chats interface {
 	SetChatRole(ctx context.Context, chatRole model.ChatRole) error
+	GetChatPermissions(ctx context.Context, chatID int64, userID string) (model.ChatPermissionsResponse, error)
+	SetDefaultPermissions(ctx context.Context, request model.DefaultPermissionsRequest) (model.ChatDB, error)
+	PinMessage(ctx context.Context, pinnedMessage model.PinnedMessage) error
+	UnpinMessage(ctx context.Context, pinnedMessage model.PinnedMessage) error
 	SetBlockChat(ctx context.Context, blockChat model.BlockChat) error
+	SetMessageTTL(ctx context.Context, request model.MessageTTLRequest) error
 	CreatePrivateChat(ctx context.Context, request model.CreatePrivateChatRequest) (model.CreatePrivateChatResponse, error)
 	CreateGroupChat(ctx context.Context, request *model.CreateGroupChatRequest) error
 	GetParticipantsOfChat(ctx context.Context, chatID int64) ([]string, error)
+	GetChatHistory(ctx context.Context, chatID int64, userID string, beforeMessageID int64, limit int) ([]model.Message, error)
+	GetThreadHistory(ctx context.Context, chatID, threadID int64, userID string, beforeMessageID int64, limit int) ([]model.Message, error)
 	GetChatByChatID(ctx context.Context, chatID int64) (model.ChatDB, error)
 	UpdatePinnedChat(ctx context.Context, pinnedChatWithFlag model.PinnedChatWithFlag) error
 	InitializeChatsForMessenger(ctx context.Context, userID string) ([]model.Chat, error)
@@ -67,9 +141,167 @@ type Chats interface {
 }
 
 type Messages interface {
+	ReserveMessageID(ctx context.Context) (int64, error)
 	SendMessage(ctx context.Context, createMessageRequest *model.CreateMessageRequest) error
+	ForwardMessages(ctx context.Context, request model.ForwardMessagesRequest) ([]model.SendMessage, error)
+	EditMessage(ctx context.Context, request model.EditMessageRequest) (model.MessageDB, error)
+	DeleteMessage(ctx context.Context, chatID, messageID int64, userID string) ([]model.DeletedMessage, error)
+	DeleteExpiredMessages(ctx context.Context) ([]model.DeletedMessage, error)
 }
 
 type Notifications interface {
 	SendNotification(ctx context.Context, notRMQ model.NotificationRabbitMQ, data any) error
+	SendNotifications(ctx context.Context, notRMQ model.NotificationRabbitMQ, data []any) error
+	Consume(ctx context.Context, queue string, handle func(ctx context.Context, body []byte) error) error
+}
+
+type Reactions interface {
+	AddReaction(ctx context.Context, request model.ReactionRequest) (model.MessageDB, error)
+	RemoveReaction(ctx context.Context, request model.ReactionRequest) error
+	GetReactions(ctx context.Context, messageID int64) ([]model.Reaction, error)
+}
+
+type ScheduledMessages interface {
+	ScheduleMessage(ctx context.Context, request model.ScheduledMessageRequest) (model.ScheduledMessage, error)
+	GetScheduledMessages(ctx context.Context, chatID int64, userID string) ([]model.ScheduledMessage, error)
+	EditScheduledMessage(ctx context.Context, request model.ScheduledMessageRequest) error
+	CancelScheduledMessage(ctx context.Context, scheduledMessageID, chatID int64, userID string) error
+	SendDueScheduledMessages(ctx context.Context, send func(ctx context.Context, request *model.CreateMessageRequest) error) (int, error)
+}
+
+type E2EE interface {
+	PublishDeviceKeys(ctx context.Context, keys model.DeviceKeys) error
+	GetPreKeyBundles(ctx context.Context, callerID, userID string) ([]model.PreKeyBundle, error)
+	CountOneTimePreKeys(ctx context.Context, userID string, deviceID int) (int, error)
+	SendEncryptedMessage(ctx context.Context, request *model.EncryptedMessageRequest) error
+	GetEncryptedHistory(ctx context.Context, chatID int64, userID string, deviceID int, beforeMessageID int64, limit int) ([]model.EncryptedMessage, error)
+}
+
+type KeyRotation interface {
+	RewrapDataKeys(ctx context.Context) (int, error)
+	RotateDataKeys(ctx context.Context, maxAge time.Duration) (int, error)
+	ReencryptMessages(ctx context.Context, afterMessageID int64) (lastMessageID int64, processed int, err error)
+}
+
+type Uploads interface {
+	Upload(ctx context.Context, userID, kind string, body io.Reader) (model.UploadResult, error)
+	CreateUpload(ctx context.Context, userID string, request model.CreateUploadRequest) (model.Upload, error)
+	GetUpload(ctx context.Context, uploadID, userID string) (model.Upload, error)
+	AppendUpload(ctx context.Context, uploadID, userID string, offset int64, body io.Reader) (model.Upload, *model.UploadResult, error)
+	CancelUpload(ctx context.Context, uploadID, userID string) error
+	DeleteExpiredUploads(ctx context.Context) (int, error)
+	SignDownload(ctx context.Context, userID string, chatID int64, kind string, attachmentID int64) (url.Values, time.Time, error)
+	OpenDownload(ctx context.Context, kind string, attachmentID int64, query url.Values) (io.ReadCloser, model.Blob, error)
+}
+
+type MediaProcessing interface {
+	ProcessMedia(ctx context.Context, mediaID int64) (*model.ProcessedMedia, error)
+	SweepStaleMedia(ctx context.Context) ([]model.ProcessedMedia, error)
+}
+
+type Voice interface {
+	ListenVoice(ctx context.Context, request model.VoiceListenRequest) (model.MessageDB, *model.VoiceListen, error)
+	GetUnlistenedVoiceMessages(ctx context.Context, chatID int64, userID string) ([]int64, error)
+}
+
+type Polls interface {
+	Vote(ctx context.Context, request model.PollVoteRequest) (*model.PollState, error)
+	RetractVote(ctx context.Context, request model.PollVoteRequest) (*model.PollState, error)
+	CloseDuePolls(ctx context.Context) ([]model.PollState, error)
+	GetPollState(ctx context.Context, chatID, messageID int64) (*model.PollState, error)
+}
+
+type Mentions interface {
+	GetUnreadMentions(ctx context.Context, chatID int64, userID string) ([]model.UnreadMention, error)
+	ReadMentions(ctx context.Context, request model.ReadMentionsRequest) (int, error)
+}
+
+type LinkPreviews interface {
+	RequestLinkPreview(ctx context.Context, chatID, messageID int64, content *string, entities *[]model.MessageEntity) error
+	ProcessLinkPreview(ctx context.Context, job model.LinkPreviewJob) (*model.LinkPreview, error)
+}
+
+type Presence interface {
+	Connect(ctx context.Context, userID string) error
+	Refresh(ctx context.Context, userID string) error
+	SetStatus(ctx context.Context, request model.PresenceUpdateRequest) error
+	Disconnect(ctx context.Context, userID string) error
+	Subscribe(ctx context.Context, request model.PresenceSubscribeRequest) ([]model.Presence, error)
+	WatchPresence(ctx context.Context, notify func(subscriberID string, presence model.Presence)) error
+	GetPresenceSettings(ctx context.Context, userID string) (model.PresenceSettings, error)
+	UpdatePresenceSettings(ctx context.Context, userID string, settings model.PresenceSettings) error
+	SendChatActivity(ctx context.Context, request model.ChatActivityRequest) ([]string, error)
+}
+
+type Members interface {
+	AddMembers(ctx context.Context, request model.AddMembersRequest) (model.AddMembersResponse, error)
+	KickMember(ctx context.Context, request model.KickMemberRequest) (model.ChatDB, error)
+	LeaveChat(ctx context.Context, chatID int64, userID string) (model.ChatDB, error)
+	UpdateChatInfo(ctx context.Context, request model.UpdateChatInfoRequest) (model.ChatDB, error)
+	TransferOwnership(ctx context.Context, request model.TransferOwnershipRequest) (model.ChatDB, error)
+}
+
+type Channels interface {
+	GetChannel(ctx context.Context, handle string) (model.ChannelInfo, error)
+	JoinChannel(ctx context.Context, handle, userID string) (model.ChatDB, error)
+	GetChannelAdmins(ctx context.Context, chatID int64) ([]string, error)
+	GetSubscribers(ctx context.Context, chatID int64, afterUserID string) ([]string, error)
+	RequestFanout(ctx context.Context, job model.ChannelFanoutJob) error
+	ViewPosts(ctx context.Context, request model.ViewPostsRequest) ([]model.ChannelPost, error)
+}
+
+type ChatList interface {
+	GetChatList(ctx context.Context, request model.ChatListRequest) ([]model.ChatListItem, error)
+	UpdateChatState(ctx context.Context, request model.UpdateChatStateRequest) (model.ChatState, error)
+	ReadChat(ctx context.Context, request model.ReadChatRequest) (model.ChatState, error)
+	GetChatFolders(ctx context.Context, userID string) ([]model.ChatFolder, error)
+	CreateChatFolder(ctx context.Context, folder model.ChatFolder) (model.ChatFolder, error)
+	UpdateChatFolder(ctx context.Context, folder model.ChatFolder) (model.ChatFolder, error)
+	DeleteChatFolder(ctx context.Context, folderID int64, userID string) error
+}
+
+type Bookmarks interface {
+	AddBookmark(ctx context.Context, request model.BookmarkRequest) (model.Bookmark, error)
+	RemoveBookmark(ctx context.Context, userID string, messageID int64) error
+	GetBookmarks(ctx context.Context, request model.BookmarksRequest) ([]model.BookmarkedMessage, error)
+}
+
+type Search interface {
+	IndexMessage(ctx context.Context, chatID, messageID int64, content *string) error
+	Search(ctx context.Context, request model.SearchRequest) ([]model.SearchResult, error)
+}
+
+type Exports interface {
+	CreateExport(ctx context.Context, request model.CreateExportRequest) (model.Export, error)
+	GetExport(ctx context.Context, exportID int64, userID string) (model.Export, error)
+	GetExports(ctx context.Context, userID string) ([]model.Export, error)
+	RunExport(ctx context.Context, exportID int64, decrypt func(ctx context.Context, chatID int64, message *model.SendMessage)) (model.Export, error)
+	OpenExport(ctx context.Context, exportID int64, userID string) (io.ReadCloser, model.Export, error)
+	DeleteExpiredExports(ctx context.Context) (int, error)
+}
+
+type Imports interface {
+	ImportChat(ctx context.Context, request model.ImportRequest) (model.ImportResult, error)
+}
+
+type Retention interface {
+	GetRetentionPolicies(ctx context.Context) ([]model.RetentionPolicy, error)
+	SetRetentionPolicy(ctx context.Context, request model.RetentionPolicyRequest) (model.RetentionPolicy, error)
+	DeleteRetentionPolicy(ctx context.Context, request model.RetentionPolicyRequest) error
+	GetLegalHolds(ctx context.Context) ([]model.LegalHold, error)
+	SetLegalHold(ctx context.Context, hold model.LegalHold) (model.LegalHold, error)
+	ReleaseLegalHold(ctx context.Context, holdID int64) (model.LegalHold, error)
+	GetRetentionRuns(ctx context.Context) ([]model.RetentionRun, error)
+	GetRetentionRun(ctx context.Context, runID int64) (model.RetentionRun, error)
+	GetRetentionPurges(ctx context.Context, request model.RetentionPurgesRequest) ([]model.RetentionPurge, error)
+	RunRetention(ctx context.Context, progress func(ctx context.Context, run model.RetentionRun, deleted []model.DeletedMessage)) (model.RetentionRun, error)
+}
+
+type Invites interface {
+	CreateInviteLink(ctx context.Context, request model.CreateInviteLinkRequest) (model.InviteLink, error)
+	GetInviteLinks(ctx context.Context, chatID int64, userID string) ([]model.InviteLink, error)
+	RevokeInviteLink(ctx context.Context, chatID, inviteLinkID int64, userID string) error
+	JoinByInviteLink(ctx context.Context, token, userID string) (model.JoinChatResponse, error)
+	GetJoinRequests(ctx context.Context, chatID int64, userID string) ([]model.JoinRequest, error)
+	DecideJoinRequest(ctx context.Context, request model.DecideJoinRequest) error
 }
@@ -0,0 +1,539 @@
+package service
+
+import (
+	"chat-api/internal/model"
+	repo "chat-api/internal/repository/psql"
+	"chat-api/pkg/audio"
+	"chat-api/pkg/broker"
+	"chat-api/pkg/logger"
+	"chat-api/pkg/storage"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type UploadService struct {
+	repoUploads   repo.Uploads
+	repoFiles     repo.Files
+	repoMedia     repo.Media
+	repoChats     repo.Chats
+	repoBookmarks repo.Bookmarks
+	storage       storage.Storage
+	signer        *storage.URLSigner
+	notifications Notifications
+	analyzer      *audio.Analyzer
+}
+
+func NewUploadService(uploads repo.Uploads, files repo.Files, media repo.Media, chats repo.Chats, bookmarks repo.Bookmarks, storage storage.Storage, signer *storage.URLSigner, notifications Notifications, analyzer *audio.Analyzer) *UploadService {
+	return &UploadService{
+		repoUploads:   uploads,
+		repoFiles:     files,
+		repoMedia:     media,
+		repoChats:     chats,
+		repoBookmarks: bookmarks,
+		storage:       storage,
+		signer:        signer,
+		notifications: notifications,
+		analyzer:      analyzer,
+	}
+}
+
+// Upload stores the whole content received in one request
+func (s *UploadService) Upload(ctx context.Context, userID, kind string, body io.Reader) (model.UploadResult, error) {
+	if !model.IsValidUploadKind(kind) {
+		return model.UploadResult{}, model.ErrInvalidUploadKind
+	}
+
+	return s.store(ctx, userID, kind, body, model.UploadSizeLimit(""))
+}
+
+func (s *UploadService) CreateUpload(ctx context.Context, userID string, request model.CreateUploadRequest) (model.Upload, error) {
+	if !model.IsValidUploadKind(request.Kind) {
+		return model.Upload{}, model.ErrInvalidUploadKind
+	}
+	if request.Length <= 0 || request.Length > model.UploadSizeLimit("") {
+		return model.Upload{}, model.ErrUploadTooLarge
+	}
+
+	uploadID, err := randomID()
+	if err != nil {
+		return model.Upload{}, err
+	}
+
+	upload := model.Upload{
+		UploadID:  uploadID,
+		UserID:    userID,
+		Kind:      request.Kind,
+		Length:    request.Length,
+		ExpiresAt: time.Now().UTC().Add(model.UPLOAD_TTL),
+	}
+
+	upload.CreatedAt, err = s.repoUploads.SetUpload(ctx, upload)
+	if err != nil {
+		return model.Upload{}, err
+	}
+
+	return upload, nil
+}
+
+func (s *UploadService) GetUpload(ctx context.Context, uploadID, userID string) (model.Upload, error) {
+	upload, err := s.repoUploads.GetUpload(ctx, uploadID, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.Upload{}, model.ErrUploadNotFound
+	}
+	return upload, err
+}
+
+// AppendUpload stores the chunk starting at offset, the last chunk completes the upload
+// and the result is returned together with the upload.
+func (s *UploadService) AppendUpload(ctx context.Context, uploadID, userID string, offset int64, body io.Reader) (model.Upload, *model.UploadResult, error) {
+	upload, err := s.GetUpload(ctx, uploadID, userID)
+	if err != nil {
+		return model.Upload{}, nil, err
+	}
+	if offset != upload.Offset {
+		return upload, nil, model.ErrUploadOffsetMismatch
+	}
+
+	limit := min(upload.Length-upload.Offset, model.UPLOAD_CHUNK_MAX_SIZE)
+
+	chunk, size, err := stageToTempFile(body, limit+1)
+	if err != nil {
+		return upload, nil, err
+	}
+	defer removeTempFile(chunk)
+
+	if size > limit {
+		return upload, nil, model.ErrUploadTooLarge
+	}
+
+	if size > 0 {
+		if err := s.appendPart(ctx, upload, chunk, size); err != nil {
+			return upload, nil, err
+		}
+		upload.Offset += size
+	}
+
+	// an empty chunk at the end retries completing the upload after a failure
+	if upload.Offset < upload.Length {
+		return upload, nil, nil
+	}
+
+	result, err := s.complete(ctx, upload)
+	if err != nil {
+		return upload, nil, err
+	}
+
+	return upload, &result, nil
+}
+
+func (s *UploadService) appendPart(ctx context.Context, upload model.Upload, chunk io.Reader, size int64) error {
+	suffix, err := randomID()
+	if err != nil {
+		return err
+	}
+
+	part := model.UploadPart{
+		UploadID:   upload.UploadID,
+		Offset:     upload.Offset,
+		Size:       size,
+		StorageKey: "uploads/" + upload.UploadID + "/" + suffix,
+	}
+
+	if err := s.storage.Put(ctx, part.StorageKey, chunk, size, "application/octet-stream"); err != nil {
+		return err
+	}
+
+	stored, err := s.repoUploads.SetUploadPart(ctx, part)
+	if err == nil && stored {
+		return nil
+	}
+
+	// another request has written at the same offset, its part wins
+	if deleteErr := s.storage.Delete(ctx, part.StorageKey); deleteErr != nil {
+		logger.Warn("Failed to delete upload part", zap.String("key", part.StorageKey), zap.Error(deleteErr))
+	}
+	if err != nil {
+		return err
+	}
+	return model.ErrUploadOffsetMismatch
+}
+
+// complete stores the content of all parts, the upload is removed once it's either stored or rejected for good
+func (s *UploadService) complete(ctx context.Context, upload model.Upload) (model.UploadResult, error) {
+	parts, err := s.repoUploads.GetUploadParts(ctx, upload.UploadID)
+	if err != nil {
+		return model.UploadResult{}, err
+	}
+
+	result, err := s.store(ctx, upload.UserID, upload.Kind, &partsReader{ctx: ctx, storage: s.storage, parts: parts}, upload.Length)
+	if err != nil && !isRejectedUpload(err) {
+		return model.UploadResult{}, err
+	}
+
+	if deleteErr := s.deleteUpload(ctx, upload.UploadID, parts); deleteErr != nil {
+		logger.Warn("Failed to delete completed upload", zap.String("uploadID", upload.UploadID), zap.Error(deleteErr))
+	}
+
+	return result, err
+}
+
+func (s *UploadService) CancelUpload(ctx context.Context, uploadID, userID string) error {
+	if _, err := s.GetUpload(ctx, uploadID, userID); err != nil {
+		return err
+	}
+
+	parts, err := s.repoUploads.GetUploadParts(ctx, uploadID)
+	if err != nil {
+		return err
+	}
+
+	return s.deleteUpload(ctx, uploadID, parts)
+}
+
+// DeleteExpiredUploads removes a batch of abandoned uploads and returns its size
+func (s *UploadService) DeleteExpiredUploads(ctx context.Context) (int, error) {
+	uploads, err := s.repoUploads.GetExpiredUploads(ctx, model.UPLOAD_BATCH_SIZE)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, upload := range uploads {
+		parts, err := s.repoUploads.GetUploadParts(ctx, upload.UploadID)
+		if err != nil {
+			return 0, err
+		}
+		if err := s.deleteUpload(ctx, upload.UploadID, parts); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(uploads), nil
+}
+
+// SignDownload issues a download link for a participant of the chat the attachment was sent to,
+// the user who saved the message keeps the access after leaving the chat
+func (s *UploadService) SignDownload(ctx context.Context, userID string, chatID int64, kind string, attachmentID int64) (url.Values, time.Time, error) {
+	if !model.IsValidDownloadKind(kind) {
+		return nil, time.Time{}, model.ErrInvalidUploadKind
+	}
+
+	isParticipant, err := s.repoChats.IsParticipantExists(ctx, chatID, userID)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if !isParticipant {
+		bookmarked, err := s.repoBookmarks.IsAttachmentBookmarked(ctx, kind, attachmentID, chatID, userID)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		if !bookmarked {
+			return nil, time.Time{}, model.ErrNotParticipant
+		}
+	}
+
+	inChat, err := s.repoUploads.IsAttachmentInChat(ctx, kind, attachmentID, chatID)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if !inChat {
+		return nil, time.Time{}, model.ErrAttachmentNotFound
+	}
+
+	// the original may still carry the GPS data until the processing is done
+	if kind == model.UPLOAD_KIND_MEDIA {
+		media, err := s.repoMedia.GetMediaByMediaID(ctx, attachmentID)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		switch media.Status {
+		case model.MEDIA_STATUS_PENDING:
+			return nil, time.Time{}, model.ErrMediaProcessing
+		case model.MEDIA_STATUS_REJECTED:
+			return nil, time.Time{}, model.ErrMediaRejected
+		}
+	}
+
+	// attachments stored before the uploads have only the url sent by the client
+	if _, err := s.repoUploads.GetAttachmentBlob(ctx, kind, attachmentID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, time.Time{}, model.ErrAttachmentNotFound
+		}
+		return nil, time.Time{}, err
+	}
+
+	query, expiresAt := s.signer.Sign(kind, attachmentID)
+	return query, expiresAt, nil
+}
+
+// OpenDownload checks the signed link and opens the content of the attachment
+func (s *UploadService) OpenDownload(ctx context.Context, kind string, attachmentID int64, query url.Values) (io.ReadCloser, model.Blob, error) {
+	if err := s.signer.Verify(kind, attachmentID, query); err != nil {
+		return nil, model.Blob{}, err
+	}
+
+	blob, err := s.repoUploads.GetAttachmentBlob(ctx, kind, attachmentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.Blob{}, model.ErrAttachmentNotFound
+		}
+		return nil, model.Blob{}, err
+	}
+
+	content, err := s.storage.Get(ctx, blob.StorageKey)
+	if err != nil {
+		return nil, model.Blob{}, err
+	}
+
+	return content, blob, nil
+}
+
+// store sniffs, hashes and validates the content, the bytes are put into the storage only once per SHA-256
+func (s *UploadService) store(ctx context.Context, userID, kind string, body io.Reader, limit int64) (model.UploadResult, error) {
+	hash := sha256.New()
+	sniff := &sniffBuffer{}
+
+	content, size, err := stageToTempFile(io.TeeReader(body, io.MultiWriter(hash, sniff)), limit+1)
+	if err != nil {
+		return model.UploadResult{}, err
+	}
+	defer removeTempFile(content)
+
+	if size == 0 {
+		return model.UploadResult{}, model.ErrUnsupportedUploadType
+	}
+
+	mimeType := model.UploadType(http.DetectContentType(sniff.data))
+	if !model.IsAllowedUploadType(kind, mimeType) {
+		return model.UploadResult{}, model.ErrUnsupportedUploadType
+	}
+	if size > limit || size > model.UploadSizeLimit(mimeType) {
+		return model.UploadResult{}, model.ErrUploadTooLarge
+	}
+
+	// the waveform is small enough to compute before the upload is answered
+	var voice audio.Info
+	if kind == model.UPLOAD_KIND_VOICE {
+		if voice, err = s.analyzer.Analyze(ctx, content.Name(), model.VOICE_WAVEFORM_BARS, model.VOICE_MAX_DURATION); err != nil {
+			if ctx.Err() != nil {
+				return model.UploadResult{}, ctx.Err()
+			}
+			logger.Warn("Failed to analyze voice upload", zap.Error(err))
+			return model.UploadResult{}, model.ErrUnsupportedUploadType
+		}
+		if voice.Duration > model.VOICE_MAX_DURATION {
+			return model.UploadResult{}, model.ErrVoiceTooLong
+		}
+	}
+
+	blob, err := storeBlob(ctx, s.repoUploads, s.storage, hex.EncodeToString(hash.Sum(nil)), content, size, mimeType)
+	if err != nil {
+		return model.UploadResult{}, err
+	}
+
+	result := model.UploadResult{Kind: kind}
+	uploadedAt := time.Now().UTC()
+
+	if kind == model.UPLOAD_KIND_MEDIA {
+		media := model.Media{URL: blob.StorageKey, Type: blob.Type, Size: blob.Size, UploadedAt: uploadedAt, Status: model.MEDIA_STATUS_PENDING}
+		if media.MediaID, err = s.repoMedia.SetUploadedMedia(ctx, media, blob.SHA256, userID); err != nil {
+			return model.UploadResult{}, err
+		}
+
+		// thumbnails, dimensions and the GPS stripping are done by the media processing job
+		if err := s.notifications.SendNotification(ctx, model.NotificationRabbitMQ{
+			Exchange:   broker.EXCHANGE_MEDIA,
+			RoutingKey: broker.ROUTING_KEY_MEDIA_PROCESS,
+		}, model.MediaJob{MediaID: media.MediaID}); err != nil {
+			return model.UploadResult{}, err
+		}
+
+		result.Media = &media
+		return result, nil
+	}
+
+	file := model.File{URL: blob.StorageKey, Type: blob.Type, Size: blob.Size, UploadedAt: uploadedAt}
+	if kind == model.UPLOAD_KIND_VOICE {
+		file.Duration = &voice.Duration
+		file.Waveform = voice.Waveform
+	}
+	if file.FileID, err = s.repoFiles.SetUploadedFile(ctx, file, blob.SHA256, userID); err != nil {
+		return model.UploadResult{}, err
+	}
+	result.File = &file
+	return result, nil
+}
+
+// storeBlob puts the content into the storage unless a blob with the same SHA-256 is there already
+func storeBlob(ctx context.Context, repoUploads repo.Uploads, fileStorage storage.Storage, sum string, content io.Reader, size int64, mimeType string) (model.Blob, error) {
+	blob, err := repoUploads.GetBlob(ctx, sum)
+	if err == nil {
+		return blob, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return model.Blob{}, err
+	}
+
+	blob = model.Blob{
+		SHA256:     sum,
+		StorageKey: model.BlobStorageKey(sum),
+		Type:       mimeType,
+		Size:       size,
+	}
+
+	if err := fileStorage.Put(ctx, blob.StorageKey, content, size, mimeType); err != nil {
+		return model.Blob{}, err
+	}
+
+	return blob, repoUploads.SetBlob(ctx, blob)
+}
+
+func (s *UploadService) deleteUpload(ctx context.Context, uploadID string, parts []model.UploadPart) error {
+	for _, part := range parts {
+		if err := s.storage.Delete(ctx, part.StorageKey); err != nil {
+			return err
+		}
+	}
+
+	return s.repoUploads.DeleteUpload(ctx, uploadID)
+}
+
+// resolveUploads replaces the attachments of the message by the uploads of the sender they point to,
+// the client can't attach bytes chat.api hasn't checked or somebody else's upload.
+func resolveUploads(ctx context.Context, repoFiles repo.Files, repoMedia repo.Media, senderID string, message *model.SendMessage) error {
+	if message.Files != nil {
+		for i := range *message.Files {
+			file, err := repoFiles.GetUploadedFile(ctx, (*message.Files)[i].FileID, senderID)
+			if err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					return model.ErrAttachmentNotFound
+				}
+				return err
+			}
+			(*message.Files)[i] = file
+		}
+	}
+
+	if message.Media != nil {
+		for i := range *message.Media {
+			media, err := repoMedia.GetUploadedMedia(ctx, (*message.Media)[i].MediaID, senderID)
+			if err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					return model.ErrAttachmentNotFound
+				}
+				return err
+			}
+			(*message.Media)[i] = media
+		}
+	}
+
+	if message.MessageDB.Type == model.MESSAGE_VOICE && !model.IsValidVoiceMessage(*message) {
+		return model.ErrInvalidVoiceMessage
+	}
+
+	return nil
+}
+
+func isRejectedUpload(err error) bool {
+	return errors.Is(err, model.ErrUnsupportedUploadType) || errors.Is(err, model.ErrUploadTooLarge) || errors.Is(err, model.ErrVoiceTooLong)
+}
+
+// stageToTempFile copies at most limit bytes into a temporary file rewound to the start
+func stageToTempFile(body io.Reader, limit int64) (*os.File, int64, error) {
+	tmp, err := os.CreateTemp("", "chat-upload-*")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	size, err := io.Copy(tmp, io.LimitReader(body, limit))
+	if err == nil {
+		_, err = tmp.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		removeTempFile(tmp)
+		return nil, 0, err
+	}
+
+	return tmp, size, nil
+}
+
+func removeTempFile(file *os.File) {
+	file.Close()
+	os.Remove(file.Name())
+}
+
+func randomID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// sniffBuffer keeps the beginning of the content for the type detection
+type sniffBuffer struct {
+	data []byte
+}
+
+func (b *sniffBuffer) Write(p []byte) (int, error) {
+	if rest := model.UPLOAD_SNIFF_SIZE - len(b.data); rest > 0 {
+		b.data = append(b.data, p[:min(rest, len(p))]...)
+	}
+	return len(p), nil
+}
+
+// partsReader reads the parts of a resumable upload one after another
+type partsReader struct {
+	ctx     context.Context
+	storage storage.Storage
+	parts   []model.UploadPart
+	current io.ReadCloser
+}
+
+func (r *partsReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if len(r.parts) == 0 {
+				return 0, io.EOF
+			}
+
+			part, err := r.storage.Get(r.ctx, r.parts[0].StorageKey)
+			if err != nil {
+				return 0, err
+			}
+			r.current = part
+			r.parts = r.parts[1:]
+		}
+
+		n, err := r.current.Read(p)
+		if errors.Is(err, io.EOF) {
+			r.current.Close()
+			r.current = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+// deleteBlobObjects removes the objects of the blobs deleted from the database,
+// the object left by a failure is only logged since nothing refers to it any more
+func deleteBlobObjects(ctx context.Context, storage storage.Storage, blobs []model.Blob) {
+	for _, blob := range blobs {
+		if err := storage.Delete(ctx, blob.StorageKey); err != nil {
+			logger.Error("Failed to delete blob from storage", zap.String("storageKey", blob.StorageKey), zap.Error(err))
+		}
+	}
+}
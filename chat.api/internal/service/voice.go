@@ -0,0 +1,71 @@
+package service
+
+import (
+	"chat-api/internal/model"
+	repo "chat-api/internal/repository/psql"
+	"context"
+)
+
+type VoiceService struct {
+	repoVoice    repo.Voice
+	repoMessages repo.Messages
+	repoChats    repo.Chats
+}
+
+func NewVoiceService(voice repo.Voice, messages repo.Messages, chats repo.Chats) *VoiceService {
+	return &VoiceService{
+		repoVoice:    voice,
+		repoMessages: messages,
+		repoChats:    chats,
+	}
+}
+
+// ListenVoice marks the voice message as played by the recipient. The listen is returned
+// together with the message only the first time, the sender playing their own message isn't tracked.
+func (s *VoiceService) ListenVoice(ctx context.Context, request model.VoiceListenRequest) (model.MessageDB, *model.VoiceListen, error) {
+	isParticipant, err := s.repoChats.IsParticipantExists(ctx, request.ChatID, request.UserID)
+	if err != nil {
+		return model.MessageDB{}, nil, err
+	}
+	if !isParticipant {
+		return model.MessageDB{}, nil, model.ErrNotParticipant
+	}
+
+	inChat, err := s.repoMessages.IsMessageInChat(ctx, request.MessageID, request.ChatID)
+	if err != nil {
+		return model.MessageDB{}, nil, err
+	}
+	if !inChat {
+		return model.MessageDB{}, nil, model.ErrMessageNotFound
+	}
+
+	messageDB, err := s.repoMessages.GetMessageByMessageID(ctx, request.MessageID)
+	if err != nil {
+		return model.MessageDB{}, nil, err
+	}
+	if messageDB.Type != model.MESSAGE_VOICE {
+		return model.MessageDB{}, nil, model.ErrNotVoiceMessage
+	}
+	if messageDB.SenderID == request.UserID {
+		return messageDB, nil, nil
+	}
+
+	listen, created, err := s.repoVoice.SetVoiceListen(ctx, request.MessageID, request.UserID)
+	if err != nil || !created {
+		return messageDB, nil, err
+	}
+
+	return messageDB, &listen, nil
+}
+
+func (s *VoiceService) GetUnlistenedVoiceMessages(ctx context.Context, chatID int64, userID string) ([]int64, error) {
+	isParticipant, err := s.repoChats.IsParticipantExists(ctx, chatID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !isParticipant {
+		return nil, model.ErrNotParticipant
+	}
+
+	return s.repoVoice.GetUnlistenedVoiceMessages(ctx, chatID, userID)
+}
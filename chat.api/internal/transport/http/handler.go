@@ -1,25 +1,23 @@
 package handler
 
 import (
+	"chat-api/internal/config"
 	profile "chat-api/internal/server/grpc/profile/proto"
 	"chat-api/internal/service"
 	v1 "chat-api/internal/transport/http/v1"
+	"context"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 )
 
 type Handler struct {
-	services      *service.Services
-	upgrader      websocket.Upgrader
-	profileClient profile.ProfileServiceClient
+	handlerV1 *v1.Handler
 }
 
 func NewHandler(services *service.Services, upgrader websocket.Upgrader, profileClient profile.ProfileServiceClient) *Handler {
 	return &Handler{
-		services:      services,
-		upgrader:      upgrader,
-		profileClient: profileClient,
+		handlerV1: v1.NewHandler(services, upgrader, profileClient),
 	}
 }
 
@@ -34,9 +32,31 @@ func (h *Handler) Init() *gin.Engine {
 }
 
 func (h *Handler) initAPI(router *gin.Engine) {
-	handlerV1 := v1.NewHandler(h.services, h.upgrader, h.profileClient)
 	api := router.Group("/api")
 	{
-		handlerV1.Init(api)
+		h.handlerV1.Init(api)
 	}
 }
+
+// RunWorkers starts the background jobs sharing the delivery flow of the handlers
+func (h *Handler) RunWorkers(ctx context.Context, cfg config.WorkersConfig) {
+	go h.handlerV1.RunScheduler(ctx, cfg.SchedulerInterval)
+	go h.handlerV1.RunReaper(ctx, cfg.ReaperInterval)
+	go h.handlerV1.RunReencryption(ctx, cfg.ReencryptInterval, cfg.DataKeyMaxAge)
+	go h.handlerV1.RunUploadsJanitor(ctx, cfg.UploadsCleanupInterval)
+	go h.handlerV1.RunMediaProcessor(ctx, cfg.MediaRetryDelay)
+	go h.handlerV1.RunMediaSweeper(ctx, cfg.MediaSweepInterval)
+	go h.handlerV1.RunPollCloser(ctx, cfg.PollsCloseInterval)
+	go h.handlerV1.RunPresenceWatcher(ctx, cfg.PresenceRetryDelay)
+	for i := 0; i < cfg.LinkPreviewWorkers; i++ {
+		go h.handlerV1.RunLinkPreviewer(ctx, cfg.MediaRetryDelay)
+	}
+	for i := 0; i < cfg.ChannelFanoutWorkers; i++ {
+		go h.handlerV1.RunChannelFanout(ctx, cfg.MediaRetryDelay)
+	}
+	for i := 0; i < cfg.ExportWorkers; i++ {
+		go h.handlerV1.RunExporter(ctx, cfg.MediaRetryDelay)
+	}
+	go h.handlerV1.RunExportsJanitor(ctx, cfg.UploadsCleanupInterval)
+	go h.handlerV1.RunRetention(ctx, cfg.RetentionHour)
+}
@@ -0,0 +1,111 @@
+package v1
+
+import (
+	"chat-api/internal/model"
+	"chat-api/pkg/logger"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func (h *Handler) initBookmarkRoutes(router *gin.RouterGroup) {
+	bookmarks := router.Group("/bookmarks")
+	{
+		bookmarks.GET("", h.getBookmarks)
+		bookmarks.POST("", h.addBookmark)
+		bookmarks.DELETE("/:message_id", h.removeBookmark)
+	}
+}
+
+// getBookmarks pages back through the saved messages by the "before" bookmark_id, every "tag" must match
+func (h *Handler) getBookmarks(c *gin.Context) {
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	request := model.BookmarksRequest{UserID: userID, Tags: c.QueryArray("tag")}
+
+	var err error
+	if request.BeforeBookmarkID, err = strconv.ParseInt(c.DefaultQuery("before", "0"), 10, 64); err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid before")
+		return
+	}
+	if request.Limit, err = strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(model.BOOKMARKS_LIMIT_REQUEST))); err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid limit")
+		return
+	}
+
+	bookmarks, err := h.services.Bookmarks.GetBookmarks(c.Request.Context(), request)
+	if err != nil {
+		h.newBookmarkErrorResponse(c, err, "failed to get bookmarks")
+		return
+	}
+
+	for i := range bookmarks {
+		h.decryptMessage(c.Request.Context(), bookmarks[i].Bookmark.ChatID, &bookmarks[i].Message.MessageWithData)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"bookmarks": bookmarks})
+}
+
+func (h *Handler) addBookmark(c *gin.Context) {
+	var request model.BookmarkRequest
+
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Error("Failed to bind request", zap.Error(err))
+		newResponse(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	request.UserID = userID
+
+	bookmark, err := h.services.Bookmarks.AddBookmark(c.Request.Context(), request)
+	if err != nil {
+		h.newBookmarkErrorResponse(c, err, "failed to add bookmark")
+		return
+	}
+
+	c.JSON(http.StatusOK, bookmark)
+}
+
+func (h *Handler) removeBookmark(c *gin.Context) {
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	messageID, err := strconv.ParseInt(c.Param("message_id"), 10, 64)
+	if err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid message_id")
+		return
+	}
+
+	if err := h.services.Bookmarks.RemoveBookmark(c.Request.Context(), userID, messageID); err != nil {
+		h.newBookmarkErrorResponse(c, err, "failed to remove bookmark")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) newBookmarkErrorResponse(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, model.ErrInvalidBookmarkTags), errors.Is(err, model.ErrChatEncrypted):
+		newResponse(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, model.ErrNotParticipant):
+		newResponse(c, http.StatusForbidden, err.Error())
+	case errors.Is(err, model.ErrBookmarkNotFound), errors.Is(err, model.ErrMessageNotFound):
+		newResponse(c, http.StatusNotFound, err.Error())
+	default:
+		logger.Error(message, zap.Error(err))
+		newResponse(c, http.StatusInternalServerError, message)
+	}
+}
@@ -0,0 +1,302 @@
+package v1
+
+import (
+	"chat-api/internal/model"
+	"chat-api/internal/server/grpc/profile/proto"
+	"chat-api/pkg/broker"
+	"chat-api/pkg/logger"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func (h *Handler) getChannel(c *gin.Context) {
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	channel, err := h.services.Channels.GetChannel(c.Request.Context(), c.Param("handle"))
+	if err != nil {
+		h.newMemberErrorResponse(c, err, "failed to get channel")
+		return
+	}
+
+	c.JSON(http.StatusOK, channel)
+}
+
+func (h *Handler) joinChannel(c *gin.Context) {
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	chat, err := h.services.Channels.JoinChannel(c.Request.Context(), c.Param("handle"), userID)
+	if err != nil {
+		h.newMemberErrorResponse(c, err, "failed to join channel")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	h.broadcastChatMember(ctx, chat, userID, model.CHAT_ACTION_JOIN, "", "")
+
+	c.JSON(http.StatusOK, chat)
+}
+
+// viewPosts counts the posts seen by the subscriber, the counters go back to the subscriber only
+func (h *Handler) viewPosts(request model.ViewPostsRequest) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	posts, err := h.services.Channels.ViewPosts(ctx, request)
+	if err != nil {
+		logger.Error("Failed to view channel posts", zap.Error(err))
+		return
+	}
+
+	event := model.PostViewsEvent{
+		Type:   WEBSOCKET_TYPE_POST_VIEWS,
+		ChatID: request.ChatID,
+		Posts:  posts,
+	}
+	if err := h.writeToUser(ctx, request.UserID, event); err != nil && !errors.Is(err, model.ErrWebSocketNotFound) {
+		logger.Warn("Failed to send WebSocket message", zap.String("userID", request.UserID), zap.Error(err))
+	}
+}
+
+// RunChannelFanout consumes the fan-out jobs until ctx is done, each job delivers the post to a page of the subscribers
+func (h *Handler) RunChannelFanout(ctx context.Context, retryDelay time.Duration) {
+	for {
+		err := h.services.Notifications.Consume(ctx, broker.QUEUE_CHANNEL_FANOUT, h.processChannelFanout)
+		if ctx.Err() != nil {
+			return
+		}
+		logger.Error("Failed to consume channel fan-out jobs", zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryDelay):
+		}
+	}
+}
+
+func (h *Handler) processChannelFanout(ctx context.Context, body []byte) error {
+	var job model.ChannelFanoutJob
+	if err := json.Unmarshal(body, &job); err != nil {
+		// the malformed job can't succeed on retry
+		logger.Error("Failed to unmarshal channel fan-out job", zap.Error(err))
+		return nil
+	}
+
+	chatDB, err := h.services.Chats.GetChatByChatID(ctx, job.ChatID())
+	if err != nil {
+		return err
+	}
+
+	subscribersIDs, err := h.services.Channels.GetSubscribers(ctx, job.ChatID(), job.AfterUserID)
+	if err != nil {
+		return err
+	}
+	if len(subscribersIDs) == 0 {
+		return nil
+	}
+
+	// the next page is queued before this one is delivered, so the replicas share the subscribers of a large channel
+	if len(subscribersIDs) == model.CHANNEL_FANOUT_BATCH_SIZE {
+		next := job
+		next.AfterUserID = subscribersIDs[len(subscribersIDs)-1]
+		if err := h.services.Channels.RequestFanout(ctx, next); err != nil {
+			return err
+		}
+	}
+
+	if job.Update != nil {
+		return h.deliverChannelUpdate(ctx, *job.Update, subscribersIDs)
+	}
+
+	// the queued message keeps the ciphertext, subscribers get a copy with the plain content
+	delivered := job.Message
+	h.decryptMessage(ctx, delivered.ChatID, &delivered.MessageWithData)
+	if delivered.MessageWithData.Poll != nil {
+		poll := delivered.MessageWithData.Poll.ForViewer(nil, "")
+		delivered.MessageWithData.Poll = &poll
+	}
+
+	h.deliverChannelPost(ctx, chatDB, subscribersIDs, delivered)
+	return nil
+}
+
+// deliverChannelPost sends the post to the connected subscribers of the page, the rest get the notifications
+// published in one batch with the profile of the author fetched once
+func (h *Handler) deliverChannelPost(ctx context.Context, chatDB model.ChatDB, subscribersIDs []string, request model.CreateMessageRequest) {
+	messageDB := request.MessageWithData.MessageDB
+
+	var offline []string
+	for _, recipientID := range subscribersIDs {
+		err := h.writeToUser(ctx, recipientID, request)
+		if errors.Is(err, model.ErrWebSocketNotFound) {
+			offline = append(offline, recipientID)
+			continue
+		}
+		if err != nil {
+			logger.Warn("Failed to send WebSocket message", zap.String("userID", recipientID), zap.Error(err))
+		}
+	}
+	if len(offline) == 0 {
+		return
+	}
+
+	sender := model.UserBriefInfo{UserID: messageDB.SenderID}
+	responseProfile, err := h.profileClient.GetUserBriefInfo(ctx, &proto.UserRequest{
+		SenderID:    messageDB.SenderID,
+		RecipientID: messageDB.SenderID,
+	})
+	if err != nil {
+		// the subscribers still get the notifications, without the name of the author
+		logger.Warn("Failed to get profile for notification", zap.String("userID", messageDB.SenderID), zap.Error(err))
+	} else {
+		sender = model.UserBriefInfo{
+			UserID:    responseProfile.UserID,
+			Name:      responseProfile.Name,
+			AvatarURL: responseProfile.AvatarURL,
+		}
+	}
+
+	notification := model.NotificationMessage{
+		Message: model.MessageBriefInfo{
+			MessageID: messageDB.MessageID,
+			SenderID:  messageDB.SenderID,
+			Type:      messageDB.Type,
+			UpdatedAt: messageDB.CreatedAt,
+			Duration:  model.VoiceDuration(request.MessageWithData),
+		},
+		Chat: model.ChatBriefInfo{
+			ChatID:    chatDB.ChatID,
+			CreatorID: chatDB.CreatorID,
+			Name:      chatDB.Name,
+			Encrypted: chatDB.Encrypted,
+			UpdatedAt: chatDB.UpdatedAt,
+		},
+		Sender: sender,
+	}
+
+	var sent, mentioned []any
+	for _, recipientID := range offline {
+		notification.RecipientID = recipientID
+		if model.IsMentioned(request.MessageWithData, recipientID) && recipientID != messageDB.SenderID {
+			mentioned = append(mentioned, notification)
+		} else {
+			sent = append(sent, notification)
+		}
+	}
+
+	for routingKey, notifications := range map[string][]any{
+		broker.ROUTING_KEY_MESSAGE_SEND:    sent,
+		broker.ROUTING_KEY_MESSAGE_MENTION: mentioned,
+	} {
+		if err := h.services.Notifications.SendNotifications(ctx, model.NotificationRabbitMQ{
+			Exchange:   broker.EXCHANGE_MESSAGE,
+			RoutingKey: routingKey,
+		}, notifications); err != nil {
+			logger.Error("Failed to send notifications about new channel post", zap.Int64("messageID", messageDB.MessageID), zap.Error(err))
+		}
+	}
+}
+
+// requestChannelFanout queues the delivery of the stored post to the first page of the subscribers
+func (h *Handler) requestChannelFanout(ctx context.Context, request model.CreateMessageRequest) {
+	if err := h.services.Channels.RequestFanout(ctx, model.ChannelFanoutJob{Message: request}); err != nil {
+		logger.Error("Failed to request channel fan-out", zap.Int64("messageID", request.MessageWithData.MessageDB.MessageID), zap.Error(err))
+	}
+}
+
+// requestChannelUpdate queues the delivery of the update of the post to the first page of the subscribers
+func (h *Handler) requestChannelUpdate(ctx context.Context, update model.ChannelPostUpdate) {
+	if err := h.services.Channels.RequestFanout(ctx, model.ChannelFanoutJob{Update: &update}); err != nil {
+		logger.Error("Failed to request channel fan-out", zap.Int64("messageID", update.MessageID), zap.Error(err))
+	}
+}
+
+// deliverChannelUpdate makes the event once for the page of the subscribers, nobody sees who reacted or voted
+func (h *Handler) deliverChannelUpdate(ctx context.Context, update model.ChannelPostUpdate, subscribersIDs []string) error {
+	var event any
+
+	switch update.Type {
+	case WEBSOCKET_TYPE_ADD_REACTION, WEBSOCKET_TYPE_REMOVE_REACTION:
+		reactions, err := h.services.Reactions.GetReactions(ctx, update.MessageID)
+		if err != nil {
+			return err
+		}
+		event = model.ReactionEvent{
+			Type:      update.Type,
+			ChatID:    update.ChatID,
+			MessageID: update.MessageID,
+			Reactions: model.AggregateReactions(reactions, ""),
+		}
+	case WEBSOCKET_TYPE_POLL_UPDATED, WEBSOCKET_TYPE_POLL_CLOSED:
+		state, err := h.services.Polls.GetPollState(ctx, update.ChatID, update.MessageID)
+		if errors.Is(err, model.ErrNotPollMessage) {
+			// the post was deleted meanwhile
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		poll := h.decryptPoll(ctx, update.ChatID, state.Poll)
+		event = model.PollEvent{
+			Type:      update.Type,
+			ChatID:    update.ChatID,
+			MessageID: update.MessageID,
+			Poll:      poll.ForSubscriber(state.Votes, ""),
+		}
+	case WEBSOCKET_TYPE_MESSAGE_EDITED:
+		event = model.MessageEditedEvent{
+			Type:      update.Type,
+			ChatID:    update.ChatID,
+			MessageID: update.MessageID,
+			Content:   h.decryptContent(ctx, update.ChatID, update.MessageID, update.Content),
+			Entities:  h.decryptEntities(ctx, update.ChatID, update.MessageID, update.EncryptedEntities),
+			Mentions:  update.Mentions,
+			EditedAt:  update.EditedAt,
+		}
+	default:
+		// the unknown update can't succeed on retry
+		logger.Error("Unknown channel post update", zap.String("type", update.Type))
+		return nil
+	}
+
+	for _, recipientID := range subscribersIDs {
+		if recipientID == update.SkipUserID {
+			continue
+		}
+		if err := h.writeToUser(ctx, recipientID, event); err != nil && !errors.Is(err, model.ErrWebSocketNotFound) {
+			logger.Warn("Failed to send WebSocket message", zap.String("userID", recipientID), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// postSignature returns the name of the author if the channel signs its posts, the post stays unsigned if the profile isn't available
+func (h *Handler) postSignature(ctx context.Context, chatDB model.ChatDB, senderID string) string {
+	if !chatDB.SignMessages {
+		return ""
+	}
+
+	responseProfile, err := h.profileClient.GetUserBriefInfo(ctx, &proto.UserRequest{
+		SenderID:    senderID,
+		RecipientID: senderID,
+	})
+	if err != nil {
+		logger.Warn("Failed to get profile for signature", zap.String("userID", senderID), zap.Error(err))
+		return ""
+	}
+	return responseProfile.Name
+}
@@ -8,6 +8,7 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -22,9 +23,143 @@ func (h *Handler) initChatRoutes(router *gin.RouterGroup) {
 		chat.PATCH("/pinned", h.pinnedChat)
 		chat.POST("/role", h.addRole)
 		chat.POST("/block", h.blockChat)
+		chat.GET("/:chat_id/messages", h.getChatHistory)
+		chat.GET("/:chat_id/threads/:message_id", h.getThreadHistory)
+		chat.GET("/:chat_id/encrypted", h.getEncryptedHistory)
+		chat.PUT("/:chat_id/ttl", h.setMessageTTL)
+		chat.POST("/:chat_id/scheduled", h.scheduleMessage)
+		chat.GET("/:chat_id/scheduled", h.getScheduledMessages)
+		chat.PUT("/:chat_id/scheduled/:scheduled_id", h.editScheduledMessage)
+		chat.DELETE("/:chat_id/scheduled/:scheduled_id", h.cancelScheduledMessage)
+		chat.GET("/:chat_id/attachments/:kind/:attachment_id/url", h.getDownloadURL)
+		chat.GET("/:chat_id/voice/unlistened", h.getUnlistenedVoiceMessages)
+		chat.GET("/:chat_id/mentions/unread", h.getUnreadMentions)
+		chat.POST("/:chat_id/mentions/read", h.readMentions)
+		chat.POST("/:chat_id/invites", h.createInviteLink)
+		chat.GET("/:chat_id/invites", h.getInviteLinks)
+		chat.DELETE("/:chat_id/invites/:invite_link_id", h.revokeInviteLink)
+		chat.POST("/join/:token", h.joinChat)
+		chat.GET("/:chat_id/join_requests", h.getJoinRequests)
+		chat.POST("/:chat_id/join_requests/:user_id/approve", h.approveJoinRequest)
+		chat.POST("/:chat_id/join_requests/:user_id/reject", h.rejectJoinRequest)
+		chat.PATCH("/:chat_id", h.updateChatInfo)
+		chat.PUT("/:chat_id/owner", h.transferOwnership)
+		chat.POST("/:chat_id/members", h.addMembers)
+		chat.DELETE("/:chat_id/members/:user_id", h.kickMember)
+		chat.POST("/:chat_id/leave", h.leaveChat)
+		chat.GET("/:chat_id/permissions", h.getChatPermissions)
+		chat.PUT("/:chat_id/permissions", h.setDefaultPermissions)
+		chat.DELETE("/:chat_id/messages/:message_id", h.deleteMessage)
+		chat.POST("/:chat_id/messages/:message_id/pin", h.pinMessage)
+		chat.DELETE("/:chat_id/messages/:message_id/pin", h.unpinMessage)
+		chat.GET("/channels/:handle", h.getChannel)
+		chat.POST("/channels/:handle/join", h.joinChannel)
+		chat.GET("/list", h.getChatList)
+		chat.PATCH("/:chat_id/state", h.updateChatState)
+		chat.POST("/:chat_id/read", h.readChat)
+		chat.GET("/folders", h.getChatFolders)
+		chat.POST("/folders", h.createChatFolder)
+		chat.PUT("/folders/:folder_id", h.updateChatFolder)
+		chat.DELETE("/folders/:folder_id", h.deleteChatFolder)
 	}
 }
 
+func (h *Handler) getChatHistory(c *gin.Context) {
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	chatID, err := strconv.ParseInt(c.Param("chat_id"), 10, 64)
+	if err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid chat_id")
+		return
+	}
+
+	beforeMessageID, limit, ok := parsePage(c)
+	if !ok {
+		return
+	}
+
+	messages, err := h.services.Chats.GetChatHistory(c.Request.Context(), chatID, userID, beforeMessageID, limit)
+	if err != nil {
+		if errors.Is(err, model.ErrNotParticipant) {
+			newResponse(c, http.StatusForbidden, err.Error())
+			return
+		}
+		logger.Error("Failed to get chat history", zap.Error(err))
+		newResponse(c, http.StatusInternalServerError, "failed to get chat history")
+		return
+	}
+
+	for i := range messages {
+		h.decryptMessage(c.Request.Context(), chatID, &messages[i].MessageWithData)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"messages": messages})
+}
+
+func (h *Handler) getThreadHistory(c *gin.Context) {
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	chatID, err := strconv.ParseInt(c.Param("chat_id"), 10, 64)
+	if err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid chat_id")
+		return
+	}
+
+	threadID, err := strconv.ParseInt(c.Param("message_id"), 10, 64)
+	if err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid message_id")
+		return
+	}
+
+	beforeMessageID, limit, ok := parsePage(c)
+	if !ok {
+		return
+	}
+
+	messages, err := h.services.Chats.GetThreadHistory(c.Request.Context(), chatID, threadID, userID, beforeMessageID, limit)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrNotParticipant):
+			newResponse(c, http.StatusForbidden, err.Error())
+		case errors.Is(err, model.ErrMessageNotFound):
+			newResponse(c, http.StatusNotFound, err.Error())
+		default:
+			logger.Error("Failed to get thread history", zap.Error(err))
+			newResponse(c, http.StatusInternalServerError, "failed to get thread history")
+		}
+		return
+	}
+
+	for i := range messages {
+		h.decryptMessage(c.Request.Context(), chatID, &messages[i].MessageWithData)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"messages": messages})
+}
+
+// parsePage reads the "before" message cursor and the page size from the query
+func parsePage(c *gin.Context) (beforeMessageID int64, limit int, ok bool) {
+	beforeMessageID, err := strconv.ParseInt(c.DefaultQuery("before", "0"), 10, 64)
+	if err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid before")
+		return 0, 0, false
+	}
+
+	limit, err = strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(model.MESSAGE_LIMIT_REQUEST)))
+	if err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid limit")
+		return 0, 0, false
+	}
+
+	return beforeMessageID, limit, true
+}
+
 func (h *Handler) blockChat(c *gin.Context) {
 	var blockChat model.BlockChat
 	userID := h.extractUserIDFromToken(c)
@@ -47,6 +182,10 @@ func (h *Handler) blockChat(c *gin.Context) {
 	}
 
 	if err := h.services.Chats.SetBlockChat(c.Request.Context(), blockChat); err != nil {
+		if errors.Is(err, model.ErrUserBanned) {
+			newResponse(c, http.StatusForbidden, err.Error())
+			return
+		}
 		logger.Error("Failed to block/unlock chat", zap.Error(err))
 		newResponse(c, http.StatusInternalServerError, "failed to set block/unblock chat")
 		return
@@ -75,11 +214,15 @@ func (h *Handler) addRole(c *gin.Context) {
 	}
 
 	if err := h.services.Chats.SetChatRole(c.Request.Context(), chatRole); err != nil {
-		logger.Error("Failed to set role", zap.Error(err))
-		newResponse(c, http.StatusInternalServerError, "failed to set role")
+		h.newMemberErrorResponse(c, err, "failed to set role")
 		return
 	}
 
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	h.broadcastChatRole(ctx, chatRole)
+
 	c.JSON(http.StatusOK, gin.H{"message": "successfully set chat role"})
 }
 
@@ -115,29 +258,38 @@ func (h *Handler) createPrivateChat(ws *websocket.Conn, request model.CreatePriv
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	if request.InitialMessage.MessageWithData.MessageDB.Content != nil {
-		encrypted, err := h.services.MessageEncrypter.Encrypt(*request.InitialMessage.MessageWithData.MessageDB.Content)
-		if err != nil {
-			logger.Error("Failed to encrypted message", zap.Error(err))
-			return
-		}
-		request.InitialMessage.MessageWithData.MessageDB.Content = &encrypted
+	// the content is encrypted by the service once the chat and its data key exist
+	if err := formatMessage(&request.InitialMessage.MessageWithData); err != nil {
+		logger.Error("Failed to create private chat", zap.Error(err))
+		return
 	}
 
-	// Attempt to create a private chat
+	// Look up the existing private chat or create a new one
 	response, err := h.services.Chats.CreatePrivateChat(ctx, request)
 	if err != nil {
 		logger.Error("Failed to create private chat", zap.Error(err))
 		return
 	}
 
-	if response.Message.MessageWithData.MessageDB.Content != nil {
-		decrypted, err := h.services.MessageEncrypter.Decrypt(*response.Message.MessageWithData.MessageDB.Content)
-		if err != nil {
-			logger.Error("Failed to decrypted message", zap.Error(err))
-			return
-		}
-		response.Message.MessageWithData.MessageDB.Content = &decrypted
+	h.decryptMessage(ctx, response.Chat.ChatID, &response.Message.MessageWithData)
+	h.requestLinkPreview(ctx, response.Chat.ChatID, response.Message.MessageWithData.MessageDB.MessageID, response.Message.MessageWithData.MessageDB.Content, response.Message.MessageWithData.Entities)
+
+	creatorID := request.Chat.CreatorID
+
+	// Each participant sees the private chat under the name of the other one
+	creatorResponse := response
+	creatorResponse.Chat, _, err = h.privateChatForViewer(ctx, response.Chat, creatorID, request.RecipientID)
+	if err != nil {
+		logger.Warn("Failed to get profile for private chat name", zap.String("userID", request.RecipientID), zap.Error(err))
+	}
+
+	recipientResponse := response
+	var sender model.UserBriefInfo
+	recipientResponse.Chat, sender, err = h.privateChatForViewer(ctx, response.Chat, request.RecipientID, creatorID)
+	if err != nil {
+		// the recipient still gets the chat, unnamed like the creator's one above
+		logger.Warn("Failed to get profile for private chat name", zap.String("userID", creatorID), zap.Error(err))
+		sender = model.UserBriefInfo{UserID: creatorID}
 	}
 
 	// Try to get the recipient's WebSocket connection
@@ -146,45 +298,30 @@ func (h *Handler) createPrivateChat(ws *websocket.Conn, request model.CreatePriv
 		if errors.Is(err, model.ErrWebSocketNotFound) {
 			// WebSocket not found — fallback to sending a notification instead
 
-			// Get recipient's user brief info for the notification
-			responseProfile, err := h.profileClient.GetUserBriefInfo(ctx, &proto.UserRequest{
-				SenderID:    request.Chat.CreatorID,
-				RecipientID: request.RecipientID,
-			})
-			if err != nil {
-				logger.Error("Failed to get profile for notification", zap.Error(err))
-				return
-			}
-
-			sender := model.UserBriefInfo{
-				UserID:    responseProfile.UserID,
-				Username:  responseProfile.Username,
-				Name:      responseProfile.Name,
-				AvatarURL: responseProfile.AvatarURL,
-			}
-
-			// Send notification about chat creation
-			if err := h.services.Notifications.SendNotification(
-				ctx,
-				model.NotificationRabbitMQ{
-					Exchange:   broker.EXCHANGE_CHAT,
-					RoutingKey: broker.ROUTING_KEY_CHAT_CREATED,
-				},
-				model.NotificationChat{
-					Chat: model.ChatBriefInfo{
-						ChatID:    response.Chat.ChatID,
-						CreatorID: response.Chat.CreatorID,
-						Name:      response.Chat.Name,
-						Encrypted: response.Chat.Encrypted,
-						AvatarURL: response.Chat.AvatarURL,
-						UpdatedAt: response.Chat.CreatedAt,
+			// The recipient already knows about an existing chat, notify only about a new one
+			if response.Created {
+				if err := h.services.Notifications.SendNotification(
+					ctx,
+					model.NotificationRabbitMQ{
+						Exchange:   broker.EXCHANGE_CHAT,
+						RoutingKey: broker.ROUTING_KEY_CHAT_CREATED,
 					},
-					Sender:      sender,
-					RecipientID: request.RecipientID,
-				},
-			); err != nil {
-				logger.Error("Failed to send notification about creating chat", zap.Error(err))
-				return
+					model.NotificationChat{
+						Chat: model.ChatBriefInfo{
+							ChatID:    recipientResponse.Chat.ChatID,
+							CreatorID: recipientResponse.Chat.CreatorID,
+							Name:      recipientResponse.Chat.Name,
+							Encrypted: recipientResponse.Chat.Encrypted,
+							AvatarURL: recipientResponse.Chat.AvatarURL,
+							UpdatedAt: recipientResponse.Chat.CreatedAt,
+						},
+						Sender:      sender,
+						RecipientID: request.RecipientID,
+					},
+				); err != nil {
+					logger.Error("Failed to send notification about creating chat", zap.Error(err))
+					return
+				}
 			}
 
 			// Send notification about the first message
@@ -200,13 +337,14 @@ func (h *Handler) createPrivateChat(ws *websocket.Conn, request model.CreatePriv
 						SenderID:  response.Message.MessageWithData.MessageDB.SenderID,
 						Type:      response.Message.MessageWithData.MessageDB.Type,
 						UpdatedAt: response.Message.MessageWithData.MessageDB.CreatedAt,
+						Duration:  model.VoiceDuration(response.Message.MessageWithData),
 					},
 					Chat: model.ChatBriefInfo{
-						ChatID:    response.Chat.ChatID,
-						CreatorID: response.Chat.CreatorID,
-						Name:      response.Chat.Name,
-						Encrypted: response.Chat.Encrypted,
-						UpdatedAt: response.Chat.CreatedAt,
+						ChatID:    recipientResponse.Chat.ChatID,
+						CreatorID: recipientResponse.Chat.CreatorID,
+						Name:      recipientResponse.Chat.Name,
+						Encrypted: recipientResponse.Chat.Encrypted,
+						UpdatedAt: recipientResponse.Chat.CreatedAt,
 					},
 					Sender:      sender,
 					RecipientID: request.RecipientID,
@@ -215,7 +353,7 @@ func (h *Handler) createPrivateChat(ws *websocket.Conn, request model.CreatePriv
 				logger.Error("Failed to send notification about new message", zap.Error(err))
 				return
 			}
-			ws.WriteJSON(response)
+			ws.WriteJSON(creatorResponse)
 			return
 		}
 
@@ -231,12 +369,37 @@ func (h *Handler) createPrivateChat(ws *websocket.Conn, request model.CreatePriv
 	}
 
 	// Send the created chat response over WebSocket
-	if err := webSocketConnection.Conn.WriteJSON(response); err != nil {
+	if err := webSocketConnection.Conn.WriteJSON(recipientResponse); err != nil {
 		logger.Warn("Failed to send WebSocket message", zap.String("userID", request.RecipientID), zap.Error(err))
 	}
 
 	// response for creator
-	ws.WriteJSON(response)
+	ws.WriteJSON(creatorResponse)
+}
+
+// privateChatForViewer returns the private chat named after the partner as the
+// viewer sees them (contact alias or profile name) along with the partner's brief info.
+func (h *Handler) privateChatForViewer(ctx context.Context, chat model.ChatDB, viewerID, partnerID string) (model.ChatDB, model.UserBriefInfo, error) {
+	responseProfile, err := h.profileClient.GetUserBriefInfo(ctx, &proto.UserRequest{
+		SenderID:    partnerID,
+		RecipientID: viewerID,
+	})
+	if err != nil {
+		return chat, model.UserBriefInfo{}, err
+	}
+
+	partner := model.UserBriefInfo{
+		UserID:    responseProfile.UserID,
+		Username:  responseProfile.Username,
+		Name:      responseProfile.Name,
+		AvatarURL: responseProfile.AvatarURL,
+	}
+
+	chat.Name = partner.Name
+	if chat.AvatarURL == nil {
+		chat.AvatarURL = partner.AvatarURL
+	}
+	return chat, partner, nil
 }
 
 func (h *Handler) createGroupChat(ws *websocket.Conn, request model.CreateGroupChatRequest) {
@@ -353,6 +516,13 @@ func (h *Handler) initializationOfChats(c *gin.Context) {
 		return
 	}
 
+	for i := range res.chats {
+		h.namePrivateChat(c.Request.Context(), userID, &res.chats[i])
+	}
+	for i := range res.pinnedChats {
+		h.namePrivateChat(c.Request.Context(), userID, &res.pinnedChats[i].Chat)
+	}
+
 	var userProfilesIDs []string
 	for _, chat := range res.chats {
 		limit := model.USER_LIMIT_REQUEST
@@ -408,3 +578,24 @@ func (h *Handler) initializationOfChats(c *gin.Context) {
 
 	c.JSON(http.StatusOK, initialization)
 }
+
+// namePrivateChat replaces the stored name of a private chat with the
+// name of the other participant as the viewer sees them.
+func (h *Handler) namePrivateChat(ctx context.Context, viewerID string, chat *model.Chat) {
+	if chat.ChatDB.Type != model.CHAT_TYPE_PRIVATE {
+		return
+	}
+
+	for _, participantID := range chat.ParticipantsIDs {
+		if participantID == viewerID {
+			continue
+		}
+		chatDB, _, err := h.privateChatForViewer(ctx, chat.ChatDB, viewerID, participantID)
+		if err != nil {
+			logger.Warn("Failed to get profile for private chat name", zap.String("userID", participantID), zap.Error(err))
+			return
+		}
+		chat.ChatDB = chatDB
+		return
+	}
+}
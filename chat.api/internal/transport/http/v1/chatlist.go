@@ -0,0 +1,215 @@
+package v1
+
+import (
+	"chat-api/internal/model"
+	"chat-api/pkg/logger"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func (h *Handler) getChatList(c *gin.Context) {
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	request := model.ChatListRequest{UserID: userID}
+
+	var err error
+	if request.FolderID, err = strconv.ParseInt(c.DefaultQuery("folder_id", "0"), 10, 64); err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid folder_id")
+		return
+	}
+	if request.Archived, err = strconv.ParseBool(c.DefaultQuery("archived", "false")); err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid archived")
+		return
+	}
+	if request.Offset, err = strconv.Atoi(c.DefaultQuery("offset", "0")); err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid offset")
+		return
+	}
+	if request.Limit, err = strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(model.CHAT_LIST_LIMIT_REQUEST))); err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid limit")
+		return
+	}
+
+	chats, err := h.services.ChatList.GetChatList(c.Request.Context(), request)
+	if err != nil {
+		h.newChatListErrorResponse(c, err, "failed to get chat list")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"chats": chats})
+}
+
+func (h *Handler) updateChatState(c *gin.Context) {
+	var request model.UpdateChatStateRequest
+
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	chatID, err := strconv.ParseInt(c.Param("chat_id"), 10, 64)
+	if err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid chat_id")
+		return
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Error("Failed to bind request", zap.Error(err))
+		newResponse(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	request.ChatID = chatID
+	request.UserID = userID
+
+	state, err := h.services.ChatList.UpdateChatState(c.Request.Context(), request)
+	if err != nil {
+		h.newChatListErrorResponse(c, err, "failed to update chat state")
+		return
+	}
+
+	c.JSON(http.StatusOK, state)
+}
+
+func (h *Handler) readChat(c *gin.Context) {
+	var request model.ReadChatRequest
+
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	chatID, err := strconv.ParseInt(c.Param("chat_id"), 10, 64)
+	if err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid chat_id")
+		return
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Error("Failed to bind request", zap.Error(err))
+		newResponse(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	request.ChatID = chatID
+	request.UserID = userID
+
+	state, err := h.services.ChatList.ReadChat(c.Request.Context(), request)
+	if err != nil {
+		h.newChatListErrorResponse(c, err, "failed to read chat")
+		return
+	}
+
+	c.JSON(http.StatusOK, state)
+}
+
+func (h *Handler) getChatFolders(c *gin.Context) {
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	folders, err := h.services.ChatList.GetChatFolders(c.Request.Context(), userID)
+	if err != nil {
+		h.newChatListErrorResponse(c, err, "failed to get chat folders")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"folders": folders})
+}
+
+func (h *Handler) createChatFolder(c *gin.Context) {
+	var folder model.ChatFolder
+
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	if err := c.ShouldBindJSON(&folder); err != nil {
+		logger.Error("Failed to bind request", zap.Error(err))
+		newResponse(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	folder.UserID = userID
+
+	folder, err := h.services.ChatList.CreateChatFolder(c.Request.Context(), folder)
+	if err != nil {
+		h.newChatListErrorResponse(c, err, "failed to create chat folder")
+		return
+	}
+
+	c.JSON(http.StatusCreated, folder)
+}
+
+func (h *Handler) updateChatFolder(c *gin.Context) {
+	var folder model.ChatFolder
+
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	folderID, err := strconv.ParseInt(c.Param("folder_id"), 10, 64)
+	if err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid folder_id")
+		return
+	}
+
+	if err := c.ShouldBindJSON(&folder); err != nil {
+		logger.Error("Failed to bind request", zap.Error(err))
+		newResponse(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	folder.FolderID = folderID
+	folder.UserID = userID
+
+	folder, err = h.services.ChatList.UpdateChatFolder(c.Request.Context(), folder)
+	if err != nil {
+		h.newChatListErrorResponse(c, err, "failed to update chat folder")
+		return
+	}
+
+	c.JSON(http.StatusOK, folder)
+}
+
+func (h *Handler) deleteChatFolder(c *gin.Context) {
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	folderID, err := strconv.ParseInt(c.Param("folder_id"), 10, 64)
+	if err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid folder_id")
+		return
+	}
+
+	if err := h.services.ChatList.DeleteChatFolder(c.Request.Context(), folderID, userID); err != nil {
+		h.newChatListErrorResponse(c, err, "failed to delete chat folder")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) newChatListErrorResponse(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, model.ErrInvalidChatState), errors.Is(err, model.ErrInvalidChatFolder):
+		newResponse(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, model.ErrNotParticipant):
+		newResponse(c, http.StatusForbidden, err.Error())
+	case errors.Is(err, model.ErrChatFolderNotFound), errors.Is(err, model.ErrMessageNotFound):
+		newResponse(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, model.ErrChatFoldersLimit):
+		newResponse(c, http.StatusConflict, err.Error())
+	default:
+		logger.Error(message, zap.Error(err))
+		newResponse(c, http.StatusInternalServerError, message)
+	}
+}
@@ -0,0 +1,119 @@
+package v1
+
+import (
+	"chat-api/internal/model"
+	"chat-api/pkg/broker"
+	"chat-api/pkg/logger"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func (h *Handler) setMessageTTL(c *gin.Context) {
+	var request model.MessageTTLRequest
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	chatID, err := strconv.ParseInt(c.Param("chat_id"), 10, 64)
+	if err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid chat_id")
+		return
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Error("Failed to bind request", zap.Error(err))
+		newResponse(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	request.ChatID = chatID
+	request.UserID = userID
+
+	if err := h.services.Chats.SetMessageTTL(c.Request.Context(), request); err != nil {
+		switch {
+		case errors.Is(err, model.ErrInvalidMessageTTL):
+			newResponse(c, http.StatusBadRequest, err.Error())
+		case errors.Is(err, model.ErrNotParticipant):
+			newResponse(c, http.StatusForbidden, err.Error())
+		default:
+			logger.Error("Failed to set message ttl", zap.Error(err))
+			newResponse(c, http.StatusInternalServerError, "failed to set message ttl")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "successfully set message ttl"})
+}
+
+// RunReaper deletes expired messages until ctx is done, connected participants get a delete event
+// and notification.api drops the mirrored rows.
+func (h *Handler) RunReaper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for {
+			deleted, err := h.services.Messages.DeleteExpiredMessages(ctx)
+			if err != nil {
+				logger.Error("Failed to delete expired messages", zap.Error(err))
+				break
+			}
+			if len(deleted) == 0 {
+				break
+			}
+
+			h.broadcastDeletedMessages(ctx, deleted)
+		}
+	}
+}
+
+func (h *Handler) broadcastDeletedMessages(ctx context.Context, deleted []model.DeletedMessage) {
+	messageIDsByChat := make(map[int64][]int64)
+	messageIDs := make([]int64, 0, len(deleted))
+	for _, message := range deleted {
+		messageIDsByChat[message.ChatID] = append(messageIDsByChat[message.ChatID], message.MessageID)
+		messageIDs = append(messageIDs, message.MessageID)
+	}
+
+	for chatID, ids := range messageIDsByChat {
+		participantsIDs, err := h.services.Chats.GetParticipantsOfChat(ctx, chatID)
+		if err != nil {
+			logger.Error("Failed to get participants of chat", zap.Int64("chatID", chatID), zap.Error(err))
+			continue
+		}
+
+		event := model.DeleteMessagesEvent{
+			Type:       WEBSOCKET_TYPE_DELETE_MESSAGES,
+			ChatID:     chatID,
+			MessageIDs: ids,
+		}
+		for _, recipientID := range participantsIDs {
+			if err := h.writeToUser(ctx, recipientID, event); err != nil && !errors.Is(err, model.ErrWebSocketNotFound) {
+				logger.Warn("Failed to send WebSocket message", zap.String("userID", recipientID), zap.Error(err))
+			}
+		}
+	}
+
+	if err := h.services.Notifications.SendNotification(
+		ctx,
+		model.NotificationRabbitMQ{
+			Exchange:   broker.EXCHANGE_MESSAGE,
+			RoutingKey: broker.ROUTING_KEY_MESSAGE_DELETED,
+		},
+		model.NotificationDeletedMessages{MessageIDs: messageIDs},
+	); err != nil {
+		logger.Error("Failed to send notification about deleted messages", zap.Error(err))
+	}
+}
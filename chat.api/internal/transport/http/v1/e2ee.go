@@ -0,0 +1,236 @@
+package v1
+
+import (
+	"chat-api/internal/model"
+	"chat-api/internal/server/grpc/profile/proto"
+	"chat-api/pkg/broker"
+	"chat-api/pkg/logger"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func (h *Handler) initKeysRoutes(router *gin.RouterGroup) {
+	keys := router.Group("/keys")
+	{
+		keys.PUT("", h.publishDeviceKeys)
+		keys.GET("/users/:user_id", h.getPreKeyBundles)
+		keys.GET("/devices/:device_id/count", h.countOneTimePreKeys)
+	}
+}
+
+func (h *Handler) publishDeviceKeys(c *gin.Context) {
+	var keys model.DeviceKeys
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	if err := c.ShouldBindJSON(&keys); err != nil {
+		logger.Error("Failed to bind request", zap.Error(err))
+		newResponse(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	keys.UserID = userID
+
+	if err := h.services.E2EE.PublishDeviceKeys(c.Request.Context(), keys); err != nil {
+		if errors.Is(err, model.ErrInvalidDeviceKeys) {
+			newResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		logger.Error("Failed to publish device keys", zap.Error(err))
+		newResponse(c, http.StatusInternalServerError, "failed to publish device keys")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "successfully published device keys"})
+}
+
+func (h *Handler) getPreKeyBundles(c *gin.Context) {
+	callerID := h.extractUserIDFromToken(c)
+	if callerID == "" {
+		return
+	}
+
+	userID := c.Param("user_id")
+	bundles, err := h.services.E2EE.GetPreKeyBundles(c.Request.Context(), callerID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrDeviceKeysNotFound):
+			newResponse(c, http.StatusNotFound, err.Error())
+		case errors.Is(err, model.ErrNotParticipant):
+			newResponse(c, http.StatusForbidden, err.Error())
+		case errors.Is(err, model.ErrPreKeyFetchLimit):
+			newResponse(c, http.StatusTooManyRequests, err.Error())
+		default:
+			logger.Error("Failed to get prekey bundles", zap.Error(err))
+			newResponse(c, http.StatusInternalServerError, "failed to get prekey bundles")
+		}
+		return
+	}
+
+	for _, bundle := range bundles {
+		if !bundle.LowOnPreKeys {
+			continue
+		}
+		event := model.PreKeysLowEvent{
+			Type:     WEBSOCKET_TYPE_PREKEYS_LOW,
+			DeviceID: bundle.DeviceID,
+			Count:    bundle.OneTimePreKeysLeft,
+		}
+		if err := h.writeToUser(c.Request.Context(), userID, event); err != nil && !errors.Is(err, model.ErrWebSocketNotFound) {
+			logger.Warn("Failed to send WebSocket message", zap.String("userID", userID), zap.Error(err))
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"bundles": bundles})
+}
+
+func (h *Handler) countOneTimePreKeys(c *gin.Context) {
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	deviceID, err := strconv.Atoi(c.Param("device_id"))
+	if err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid device_id")
+		return
+	}
+
+	count, err := h.services.E2EE.CountOneTimePreKeys(c.Request.Context(), userID, deviceID)
+	if err != nil {
+		logger.Error("Failed to count one-time prekeys", zap.Error(err))
+		newResponse(c, http.StatusInternalServerError, "failed to count one-time prekeys")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"count": count})
+}
+
+func (h *Handler) getEncryptedHistory(c *gin.Context) {
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	chatID, err := strconv.ParseInt(c.Param("chat_id"), 10, 64)
+	if err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid chat_id")
+		return
+	}
+
+	deviceID, err := strconv.Atoi(c.Query("device_id"))
+	if err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid device_id")
+		return
+	}
+
+	beforeMessageID, limit, ok := parsePage(c)
+	if !ok {
+		return
+	}
+
+	messages, err := h.services.E2EE.GetEncryptedHistory(c.Request.Context(), chatID, userID, deviceID, beforeMessageID, limit)
+	if err != nil {
+		if errors.Is(err, model.ErrNotParticipant) {
+			newResponse(c, http.StatusForbidden, err.Error())
+			return
+		}
+		logger.Error("Failed to get encrypted history", zap.Error(err))
+		newResponse(c, http.StatusInternalServerError, "failed to get encrypted history")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"messages": messages})
+}
+
+// sendEncryptedMessage relays the ciphertext, every participant gets only the envelopes of own devices.
+// Offline participants are notified through message_send_encrypted without any content.
+func (h *Handler) sendEncryptedMessage(request model.EncryptedMessageRequest) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := h.services.E2EE.SendEncryptedMessage(ctx, &request); err != nil {
+		logger.Error("Failed to send encrypted message", zap.Error(err))
+		return
+	}
+
+	chatDB, err := h.services.Chats.GetChatByChatID(ctx, request.ChatID)
+	if err != nil {
+		logger.Error("Failed to get chat", zap.Error(err))
+		return
+	}
+
+	envelopesByRecipient := make(map[string][]model.EncryptedEnvelope)
+	for _, envelope := range request.Envelopes {
+		envelopesByRecipient[envelope.RecipientID] = append(envelopesByRecipient[envelope.RecipientID], envelope)
+	}
+
+	for recipientID, envelopes := range envelopesByRecipient {
+		event := model.EncryptedMessage{
+			Type:           WEBSOCKET_TYPE_SEND_ENCRYPTED_MESSAGE,
+			ChatID:         request.ChatID,
+			SenderDeviceID: request.SenderDeviceID,
+			Message:        request.Message,
+			Envelopes:      envelopes,
+		}
+
+		err := h.writeToUser(ctx, recipientID, event)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, model.ErrWebSocketNotFound) {
+			logger.Warn("Failed to send WebSocket message", zap.String("userID", recipientID), zap.Error(err))
+			continue
+		}
+		if recipientID == request.Message.SenderID {
+			continue
+		}
+
+		responseProfile, err := h.profileClient.GetUserBriefInfo(ctx, &proto.UserRequest{
+			SenderID:    request.Message.SenderID,
+			RecipientID: recipientID,
+		})
+		if err != nil {
+			logger.Error("Failed to get profile for notification", zap.Error(err))
+			continue
+		}
+
+		if err := h.services.Notifications.SendNotification(
+			ctx,
+			model.NotificationRabbitMQ{
+				Exchange:   broker.EXCHANGE_MESSAGE,
+				RoutingKey: broker.ROUTING_KEY_MESSAGE_SEND_ENCRYPTED,
+			},
+			model.NotificationMessage{
+				Message: model.MessageBriefInfo{
+					MessageID: request.Message.MessageID,
+					SenderID:  request.Message.SenderID,
+					Type:      request.Message.Type,
+					UpdatedAt: request.Message.CreatedAt,
+				},
+				Chat: model.ChatBriefInfo{
+					ChatID:    chatDB.ChatID,
+					CreatorID: chatDB.CreatorID,
+					Name:      chatDB.Name,
+					Encrypted: chatDB.Encrypted,
+					UpdatedAt: chatDB.UpdatedAt,
+				},
+				Sender: model.UserBriefInfo{
+					UserID:    responseProfile.UserID,
+					Name:      responseProfile.Name,
+					AvatarURL: responseProfile.AvatarURL,
+				},
+				RecipientID: recipientID,
+			},
+		); err != nil {
+			logger.Error("Failed to send notification about new encrypted message", zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package v1
+
+import (
+	"chat-api/internal/model"
+	"chat-api/pkg/logger"
+	"chat-api/pkg/markdown"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// formatMessage turns the markdown content into the plain one with entities and checks the entities
+// and mentions against the plain content. Offsets of the mentions sent along with markdown point
+// into the plain content too.
+func formatMessage(message *model.SendMessage) error {
+	// the stored form comes only from encryptMessage
+	message.MessageDB.EncryptedEntities = nil
+
+	switch message.ParseMode {
+	case "":
+	case model.PARSE_MODE_MARKDOWN:
+		if message.Entities != nil {
+			return model.ErrInvalidEntities
+		}
+		if content := message.MessageDB.Content; content != nil {
+			plain, entities, err := markdown.Parse(*content)
+			if err != nil {
+				return fmt.Errorf("%w: %v", model.ErrInvalidEntities, err)
+			}
+			message.MessageDB.Content = &plain
+			if len(entities) > 0 {
+				message.Entities = &entities
+			}
+		}
+	default:
+		return model.ErrInvalidEntities
+	}
+	message.ParseMode = ""
+
+	if !model.IsValidEntities(message.MessageDB.Content, message.Entities) {
+		return model.ErrInvalidEntities
+	}
+	if !model.IsValidMentions(message.MessageDB.Content, message.Mentions) {
+		return model.ErrInvalidMentions
+	}
+	return nil
+}
+
+// reserveMessageID gives the new message its id before the content is encrypted, the id sent by the client is ignored
+func (h *Handler) reserveMessageID(ctx context.Context, message *model.SendMessage) error {
+	messageID, err := h.services.Messages.ReserveMessageID(ctx)
+	if err != nil {
+		return err
+	}
+	message.MessageDB.MessageID = messageID
+	return nil
+}
+
+// encryptMessage encrypts the content and the entities of the message by the key of the chat bound to the message,
+// only the stored form of the entities is kept
+func (h *Handler) encryptMessage(ctx context.Context, chatID int64, message *model.SendMessage) error {
+	messageID := message.MessageDB.MessageID
+	if content := message.MessageDB.Content; content != nil {
+		encrypted, err := h.services.MessageEncrypter.Encrypt(ctx, chatID, messageID, *content)
+		if err != nil {
+			return err
+		}
+		message.MessageDB.Content = &encrypted
+	}
+
+	entities, err := model.MarshalEntities(message.Entities)
+	if err != nil {
+		return err
+	}
+	if entities != nil {
+		encrypted, err := h.services.MessageEncrypter.Encrypt(ctx, chatID, messageID, *entities)
+		if err != nil {
+			return err
+		}
+		message.MessageDB.EncryptedEntities = &encrypted
+	}
+	message.Entities = nil
+
+	return nil
+}
+
+func (h *Handler) decryptEntities(ctx context.Context, chatID, messageID int64, encrypted *string) *[]model.MessageEntity {
+	data := h.decryptContent(ctx, chatID, messageID, encrypted)
+	if data == nil {
+		return nil
+	}
+
+	entities, err := model.UnmarshalEntities(*data)
+	if err != nil {
+		logger.Warn("Failed to unmarshal entities", zap.Int64("messageID", messageID), zap.Error(err))
+		return nil
+	}
+	return entities
+}
+
+// editMessage replaces the content of the message and sends the edited one to the connected participants
+func (h *Handler) editMessage(request model.EditMessageRequest) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	edited := model.SendMessage{
+		MessageDB: model.MessageDB{MessageID: request.MessageID, Content: request.Content},
+		Entities:  request.Entities,
+		Mentions:  request.Mentions,
+		ParseMode: request.ParseMode,
+	}
+	if err := formatMessage(&edited); err != nil {
+		logger.Error("Failed to edit message", zap.Error(err))
+		return
+	}
+
+	event := model.MessageEditedEvent{
+		Type:      WEBSOCKET_TYPE_MESSAGE_EDITED,
+		ChatID:    request.ChatID,
+		MessageID: request.MessageID,
+		Content:   edited.MessageDB.Content,
+		Entities:  edited.Entities,
+		Mentions:  edited.Mentions,
+	}
+
+	if err := h.encryptMessage(ctx, request.ChatID, &edited); err != nil {
+		logger.Error("Failed to encrypted message", zap.Error(err))
+		return
+	}
+	request.Content = edited.MessageDB.Content
+	request.EncryptedEntities = edited.MessageDB.EncryptedEntities
+	request.Mentions = edited.Mentions
+
+	message, err := h.services.Messages.EditMessage(ctx, request)
+	if err != nil {
+		logger.Error("Failed to edit message", zap.Error(err))
+		return
+	}
+	event.EditedAt = message.UpdatedAt
+	h.indexMessage(ctx, request.ChatID, request.MessageID, event.Content)
+	h.requestLinkPreview(ctx, request.ChatID, request.MessageID, event.Content, event.Entities)
+
+	chatDB, err := h.services.Chats.GetChatByChatID(ctx, request.ChatID)
+	if err != nil {
+		logger.Error("Failed to get chat", zap.Error(err))
+		return
+	}
+
+	// the subscribers of a channel get the edited post from the fan-out workers
+	if chatDB.Type == model.CHAT_TYPE_CHANNEL {
+		h.requestChannelUpdate(ctx, model.ChannelPostUpdate{
+			Type:              WEBSOCKET_TYPE_MESSAGE_EDITED,
+			ChatID:            request.ChatID,
+			MessageID:         request.MessageID,
+			Content:           request.Content,
+			EncryptedEntities: request.EncryptedEntities,
+			Mentions:          request.Mentions,
+			EditedAt:          event.EditedAt,
+		})
+		return
+	}
+
+	participantsIDs, err := h.services.Chats.GetParticipantsOfChat(ctx, request.ChatID)
+	if err != nil {
+		logger.Error("Failed to get participants of chat", zap.Error(err))
+		return
+	}
+
+	for _, recipientID := range participantsIDs {
+		if err := h.writeToUser(ctx, recipientID, event); err != nil && !errors.Is(err, model.ErrWebSocketNotFound) {
+			logger.Warn("Failed to send WebSocket message", zap.String("userID", recipientID), zap.Error(err))
+		}
+	}
+}
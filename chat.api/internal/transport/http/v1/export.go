@@ -0,0 +1,265 @@
+package v1
+
+import (
+	"chat-api/internal/model"
+	"chat-api/pkg/broker"
+	"chat-api/pkg/logger"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func (h *Handler) initExportRoutes(router *gin.RouterGroup) {
+	exports := router.Group("/exports")
+	{
+		exports.POST("", h.createExport)
+		exports.GET("", h.getExports)
+		exports.GET("/:export_id", h.getExport)
+		exports.GET("/:export_id/download", h.downloadExport)
+	}
+	router.POST("/imports", h.importChat)
+}
+
+// createExport queues the archive of "chat_id" or of all chats of the user, the user gets the export finished event when it's built
+func (h *Handler) createExport(c *gin.Context) {
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	// without a body the whole account is exported
+	var request model.CreateExportRequest
+	if err := c.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+		newResponse(c, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	request.UserID = userID
+
+	export, err := h.services.Exports.CreateExport(c.Request.Context(), request)
+	if err != nil {
+		h.exportErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, export)
+}
+
+func (h *Handler) getExports(c *gin.Context) {
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	exports, err := h.services.Exports.GetExports(c.Request.Context(), userID)
+	if err != nil {
+		h.exportErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"exports": exports})
+}
+
+func (h *Handler) getExport(c *gin.Context) {
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	exportID, err := strconv.ParseInt(c.Param("export_id"), 10, 64)
+	if err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid export_id")
+		return
+	}
+
+	export, err := h.services.Exports.GetExport(c.Request.Context(), exportID, userID)
+	if err != nil {
+		h.exportErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, export)
+}
+
+// downloadExport streams the archive to its owner, the link stays valid until the archive expires
+func (h *Handler) downloadExport(c *gin.Context) {
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	exportID, err := strconv.ParseInt(c.Param("export_id"), 10, 64)
+	if err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid export_id")
+		return
+	}
+
+	content, export, err := h.services.Exports.OpenExport(c.Request.Context(), exportID, userID)
+	if err != nil {
+		h.exportErrorResponse(c, err)
+		return
+	}
+	defer content.Close()
+
+	c.Header("Cache-Control", "private, no-store")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.DataFromReader(http.StatusOK, *export.Size, model.FILE_TYPE_ZIP, content, map[string]string{
+		"Content-Disposition": `attachment; filename="export-` + strconv.FormatInt(export.ExportID, 10) + `.zip"`,
+	})
+}
+
+// importChat recreates the chat of the archive JSON as a new group chat of the user
+func (h *Handler) importChat(c *gin.Context) {
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, model.IMPORT_BODY_LIMIT)
+
+	var request model.ImportRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			newResponse(c, http.StatusRequestEntityTooLarge, model.ErrInvalidImport.Error())
+			return
+		}
+		newResponse(c, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	request.UserID = userID
+
+	result, err := h.services.Imports.ImportChat(c.Request.Context(), request)
+	if err != nil {
+		h.exportErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, result)
+}
+
+func (h *Handler) exportErrorResponse(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, model.ErrInvalidImport), errors.Is(err, model.ErrChatEncrypted):
+		newResponse(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, model.ErrNotParticipant):
+		newResponse(c, http.StatusForbidden, err.Error())
+	case errors.Is(err, model.ErrExportNotFound):
+		newResponse(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, model.ErrExportInProgress), errors.Is(err, model.ErrExportNotReady):
+		newResponse(c, http.StatusConflict, err.Error())
+	default:
+		logger.Error("Failed to handle export", zap.Error(err))
+		newResponse(c, http.StatusInternalServerError, "failed to handle export")
+	}
+}
+
+// RunExporter builds the queued archives, an archive of a large account takes a while so the replica builds one at a time per worker
+func (h *Handler) RunExporter(ctx context.Context, retryDelay time.Duration) {
+	for {
+		err := h.services.Notifications.Consume(ctx, broker.QUEUE_CHAT_EXPORT, h.processExport)
+		if ctx.Err() != nil {
+			return
+		}
+		logger.Error("Failed to consume export jobs", zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryDelay):
+		}
+	}
+}
+
+func (h *Handler) processExport(ctx context.Context, body []byte) error {
+	var job model.ExportJob
+	if err := json.Unmarshal(body, &job); err != nil {
+		// the malformed job can't succeed on retry
+		logger.Error("Failed to unmarshal export job", zap.Error(err))
+		return nil
+	}
+
+	export, err := h.services.Exports.RunExport(ctx, job.ExportID, h.decryptMessage)
+	if err != nil {
+		// taken by another worker or deleted with its chat
+		if errors.Is(err, model.ErrExportNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	h.notifyExport(ctx, export)
+	return nil
+}
+
+// notifyExport tells the owner the export is finished, through notification.api if the owner is offline
+func (h *Handler) notifyExport(ctx context.Context, export model.Export) {
+	var downloadURL string
+	if export.Status == model.EXPORT_STATUS_READY {
+		downloadURL = "/api/v1/exports/" + strconv.FormatInt(export.ExportID, 10) + "/download"
+	}
+
+	event := model.ExportEvent{
+		Type:        WEBSOCKET_TYPE_EXPORT_FINISHED,
+		Export:      export,
+		DownloadURL: downloadURL,
+	}
+
+	err := h.writeToUser(ctx, export.UserID, event)
+	if err == nil {
+		return
+	}
+	if !errors.Is(err, model.ErrWebSocketNotFound) {
+		logger.Warn("Failed to send WebSocket message", zap.String("userID", export.UserID), zap.Error(err))
+		return
+	}
+
+	if err := h.services.Notifications.SendNotification(
+		ctx,
+		model.NotificationRabbitMQ{
+			Exchange:   broker.EXCHANGE_CHAT,
+			RoutingKey: broker.ROUTING_KEY_CHAT_EXPORT_READY,
+		},
+		model.NotificationExport{
+			ExportID:    export.ExportID,
+			ChatID:      export.ChatID,
+			RecipientID: export.UserID,
+			Status:      export.Status,
+			DownloadURL: downloadURL,
+			ExpiresAt:   export.ExpiresAt,
+		},
+	); err != nil {
+		logger.Error("Failed to send export notification", zap.Int64("exportID", export.ExportID), zap.Error(err))
+	}
+}
+
+// RunExportsJanitor removes the expired archives
+func (h *Handler) RunExportsJanitor(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for {
+			deleted, err := h.services.Exports.DeleteExpiredExports(ctx)
+			if err != nil {
+				logger.Error("Failed to delete expired exports", zap.Error(err))
+				break
+			}
+			if deleted < model.EXPORT_BATCH_SIZE {
+				break
+			}
+		}
+	}
+}
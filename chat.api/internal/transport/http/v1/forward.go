@@ -0,0 +1,57 @@
+package v1
+
+import (
+	"chat-api/internal/model"
+	"chat-api/pkg/logger"
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func (h *Handler) forwardMessages(request model.ForwardMessagesRequest) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	messages, err := h.services.Messages.ForwardMessages(ctx, request)
+	if err != nil {
+		logger.Error("Failed to forward messages", zap.Error(err))
+		return
+	}
+
+	chatDB, err := h.services.Chats.GetChatByChatID(ctx, request.ToChatID)
+	if err != nil {
+		logger.Error("Failed to get chat", zap.Error(err))
+		return
+	}
+
+	// the stored forwards go to the subscribers of a channel through the fan-out workers
+	if chatDB.Type == model.CHAT_TYPE_CHANNEL {
+		for _, message := range messages {
+			h.requestChannelFanout(ctx, model.CreateMessageRequest{
+				ChatID:          request.ToChatID,
+				MessageWithData: message,
+			})
+
+			plain := message
+			h.decryptMessage(ctx, request.ToChatID, &plain)
+			h.indexMessage(ctx, request.ToChatID, plain.MessageDB.MessageID, plain.MessageDB.Content)
+		}
+		return
+	}
+
+	participantsIDs, err := h.services.Chats.GetParticipantsOfChat(ctx, request.ToChatID)
+	if err != nil {
+		logger.Error("Failed to get participants of chat", zap.Error(err))
+		return
+	}
+
+	for _, message := range messages {
+		h.decryptMessage(ctx, request.ToChatID, &message)
+		h.deliverMessage(ctx, chatDB, participantsIDs, model.CreateMessageRequest{
+			ChatID:          request.ToChatID,
+			MessageWithData: message,
+		})
+		h.indexMessage(ctx, request.ToChatID, message.MessageDB.MessageID, message.MessageDB.Content)
+	}
+}
@@ -28,5 +28,12 @@ func (h *Handler) Init(router *gin.RouterGroup) {
 	{
 		h.initWebSocket(v1)
 		h.initChatRoutes(v1)
+		h.initKeysRoutes(v1)
+		h.initUploadRoutes(v1)
+		h.initPresenceRoutes(v1)
+		h.initBookmarkRoutes(v1)
+		h.initSearchRoutes(v1)
+		h.initExportRoutes(v1)
+		h.initComplianceRoutes(v1)
 	}
 }
@@ -0,0 +1,242 @@
+package v1
+
+import (
+	"chat-api/internal/model"
+	"chat-api/pkg/broker"
+	"chat-api/pkg/logger"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func (h *Handler) createInviteLink(c *gin.Context) {
+	var request model.CreateInviteLinkRequest
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	chatID, err := strconv.ParseInt(c.Param("chat_id"), 10, 64)
+	if err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid chat_id")
+		return
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Error("Failed to bind request", zap.Error(err))
+		newResponse(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	request.ChatID = chatID
+	request.UserID = userID
+
+	link, err := h.services.Invites.CreateInviteLink(c.Request.Context(), request)
+	if err != nil {
+		h.newInviteErrorResponse(c, err, "failed to create invite link")
+		return
+	}
+
+	c.JSON(http.StatusCreated, link)
+}
+
+func (h *Handler) getInviteLinks(c *gin.Context) {
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	chatID, err := strconv.ParseInt(c.Param("chat_id"), 10, 64)
+	if err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid chat_id")
+		return
+	}
+
+	links, err := h.services.Invites.GetInviteLinks(c.Request.Context(), chatID, userID)
+	if err != nil {
+		h.newInviteErrorResponse(c, err, "failed to get invite links")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"invite_links": links})
+}
+
+func (h *Handler) revokeInviteLink(c *gin.Context) {
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	chatID, err := strconv.ParseInt(c.Param("chat_id"), 10, 64)
+	if err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid chat_id")
+		return
+	}
+
+	inviteLinkID, err := strconv.ParseInt(c.Param("invite_link_id"), 10, 64)
+	if err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid invite_link_id")
+		return
+	}
+
+	if err := h.services.Invites.RevokeInviteLink(c.Request.Context(), chatID, inviteLinkID, userID); err != nil {
+		h.newInviteErrorResponse(c, err, "failed to revoke invite link")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) joinChat(c *gin.Context) {
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	response, err := h.services.Invites.JoinByInviteLink(c.Request.Context(), c.Param("token"), userID)
+	if err != nil {
+		h.newInviteErrorResponse(c, err, "failed to join chat")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if response.Joined {
+		h.broadcastChatMember(ctx, response.Chat, userID, model.CHAT_ACTION_JOIN, "", "")
+	} else {
+		h.notifyJoinRequest(ctx, response, userID)
+	}
+
+	status := http.StatusOK
+	if !response.Joined {
+		status = http.StatusAccepted
+	}
+	c.JSON(status, response)
+}
+
+func (h *Handler) getJoinRequests(c *gin.Context) {
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	chatID, err := strconv.ParseInt(c.Param("chat_id"), 10, 64)
+	if err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid chat_id")
+		return
+	}
+
+	requests, err := h.services.Invites.GetJoinRequests(c.Request.Context(), chatID, userID)
+	if err != nil {
+		h.newInviteErrorResponse(c, err, "failed to get join requests")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"join_requests": requests})
+}
+
+func (h *Handler) approveJoinRequest(c *gin.Context) {
+	h.decideJoinRequest(c, true)
+}
+
+func (h *Handler) rejectJoinRequest(c *gin.Context) {
+	h.decideJoinRequest(c, false)
+}
+
+func (h *Handler) decideJoinRequest(c *gin.Context, approve bool) {
+	adminID := h.extractUserIDFromToken(c)
+	if adminID == "" {
+		return
+	}
+
+	chatID, err := strconv.ParseInt(c.Param("chat_id"), 10, 64)
+	if err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid chat_id")
+		return
+	}
+
+	request := model.DecideJoinRequest{
+		ChatID:  chatID,
+		UserID:  c.Param("user_id"),
+		AdminID: adminID,
+		Approve: approve,
+	}
+
+	if err := h.services.Invites.DecideJoinRequest(c.Request.Context(), request); err != nil {
+		h.newInviteErrorResponse(c, err, "failed to decide join request")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if approve {
+		chat, err := h.services.Chats.GetChatByChatID(ctx, chatID)
+		if err != nil {
+			logger.Error("Failed to get chat", zap.Error(err))
+		} else {
+			h.broadcastChatMember(ctx, chat, request.UserID, model.CHAT_ACTION_JOIN, adminID, broker.ROUTING_KEY_CHAT_ADDED_USER)
+		}
+	} else {
+		now := time.Now()
+		event := model.JoinRequestEvent{
+			Type: WEBSOCKET_TYPE_JOIN_REQUEST,
+			JoinRequest: model.JoinRequest{
+				ChatID:    chatID,
+				UserID:    request.UserID,
+				Status:    model.JOIN_REQUEST_REJECTED,
+				DecidedBy: &adminID,
+				DecidedAt: &now,
+			},
+		}
+		if err := h.writeToUser(ctx, request.UserID, event); err != nil && !errors.Is(err, model.ErrWebSocketNotFound) {
+			logger.Warn("Failed to send WebSocket message", zap.String("userID", request.UserID), zap.Error(err))
+		}
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// notifyJoinRequest lets the admins know about the pending request over the socket and the notifications
+func (h *Handler) notifyJoinRequest(ctx context.Context, response model.JoinChatResponse, userID string) {
+	event := model.JoinRequestEvent{
+		Type: WEBSOCKET_TYPE_JOIN_REQUEST,
+		JoinRequest: model.JoinRequest{
+			ChatID:      response.Chat.ChatID,
+			UserID:      userID,
+			Status:      model.JOIN_REQUEST_PENDING,
+			RequestedAt: time.Now(),
+		},
+	}
+
+	for _, adminID := range response.AdminsIDs {
+		if err := h.writeToUser(ctx, adminID, event); err != nil && !errors.Is(err, model.ErrWebSocketNotFound) {
+			logger.Warn("Failed to send WebSocket message", zap.String("userID", adminID), zap.Error(err))
+		}
+
+		h.notifyChat(ctx, response.Chat, userID, adminID, broker.ROUTING_KEY_CHAT_JOIN_REQUEST)
+	}
+}
+
+func (h *Handler) newInviteErrorResponse(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, model.ErrInvalidInviteLink), errors.Is(err, model.ErrInvalidParamsOfChat):
+		newResponse(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, model.ErrChatPermissionDenied), errors.Is(err, model.ErrNotParticipant), errors.Is(err, model.ErrUserBanned):
+		newResponse(c, http.StatusForbidden, err.Error())
+	case errors.Is(err, model.ErrInviteLinkNotFound), errors.Is(err, model.ErrJoinRequestNotFound):
+		newResponse(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, model.ErrAlreadyParticipant):
+		newResponse(c, http.StatusConflict, err.Error())
+	case errors.Is(err, model.ErrInviteLinkExpired):
+		newResponse(c, http.StatusGone, err.Error())
+	default:
+		logger.Error(message, zap.Error(err))
+		newResponse(c, http.StatusInternalServerError, message)
+	}
+}
@@ -0,0 +1,88 @@
+package v1
+
+import (
+	"chat-api/internal/model"
+	"chat-api/pkg/broker"
+	"chat-api/pkg/logger"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// RunLinkPreviewer consumes the link preview jobs until ctx is done and sends the participants
+// the preview once it's attached to the message
+func (h *Handler) RunLinkPreviewer(ctx context.Context, retryDelay time.Duration) {
+	for {
+		err := h.services.Notifications.Consume(ctx, broker.QUEUE_LINK_PREVIEW, h.processLinkPreview)
+		if ctx.Err() != nil {
+			return
+		}
+		logger.Error("Failed to consume link preview jobs", zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryDelay):
+		}
+	}
+}
+
+func (h *Handler) processLinkPreview(ctx context.Context, body []byte) error {
+	var job model.LinkPreviewJob
+	if err := json.Unmarshal(body, &job); err != nil {
+		// the malformed job can't succeed on retry
+		logger.Error("Failed to unmarshal link preview job", zap.Error(err))
+		return nil
+	}
+
+	preview, err := h.services.LinkPreviews.ProcessLinkPreview(ctx, job)
+	if err != nil {
+		return err
+	}
+	if preview == nil {
+		return nil
+	}
+
+	participantsIDs, err := h.services.Chats.GetParticipantsOfChat(ctx, job.ChatID)
+	if err != nil {
+		logger.Error("Failed to get participants of chat", zap.Int64("chatID", job.ChatID), zap.Error(err))
+		return nil
+	}
+
+	event := model.MessageUpdatedEvent{
+		Type:        WEBSOCKET_TYPE_MESSAGE_UPDATED,
+		ChatID:      job.ChatID,
+		MessageID:   job.MessageID,
+		LinkPreview: preview,
+	}
+	for _, recipientID := range participantsIDs {
+		if err := h.writeToUser(ctx, recipientID, event); err != nil && !errors.Is(err, model.ErrWebSocketNotFound) {
+			logger.Warn("Failed to send WebSocket message", zap.String("userID", recipientID), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// requestLinkPreview queues the preview of the plain content of the stored message
+func (h *Handler) requestLinkPreview(ctx context.Context, chatID, messageID int64, content *string, entities *[]model.MessageEntity) {
+	if err := h.services.LinkPreviews.RequestLinkPreview(ctx, chatID, messageID, content, entities); err != nil {
+		logger.Warn("Failed to request link preview", zap.Int64("messageID", messageID), zap.Error(err))
+	}
+}
+
+func (h *Handler) decryptLinkPreview(ctx context.Context, chatID, messageID int64, encrypted *string) *model.LinkPreview {
+	data := h.decryptContent(ctx, chatID, messageID, encrypted)
+	if data == nil {
+		return nil
+	}
+
+	var preview model.LinkPreview
+	if err := json.Unmarshal([]byte(*data), &preview); err != nil {
+		logger.Warn("Failed to unmarshal link preview", zap.Int64("messageID", messageID), zap.Error(err))
+		return nil
+	}
+	return &preview
+}
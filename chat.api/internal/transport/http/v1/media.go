@@ -0,0 +1,108 @@
+package v1
+
+import (
+	"chat-api/internal/model"
+	"chat-api/pkg/broker"
+	"chat-api/pkg/logger"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// RunMediaProcessor consumes the media jobs until ctx is done and tells the chats
+// the media is attached to once its thumbnail is ready. Several replicas may run it at once.
+func (h *Handler) RunMediaProcessor(ctx context.Context, retryDelay time.Duration) {
+	for {
+		err := h.services.Notifications.Consume(ctx, broker.QUEUE_MEDIA_PROCESS, h.processMedia)
+		if ctx.Err() != nil {
+			return
+		}
+		logger.Error("Failed to consume media jobs", zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryDelay):
+		}
+	}
+}
+
+// RunMediaSweeper queues again the media jobs lost on the way and tells the chats about the media given up on
+func (h *Handler) RunMediaSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		failed, err := h.services.MediaProcessing.SweepStaleMedia(ctx)
+		if err != nil {
+			logger.Error("Failed to sweep stale media", zap.Error(err))
+			continue
+		}
+		for _, processed := range failed {
+			h.broadcastProcessedMedia(ctx, processed)
+		}
+	}
+}
+
+func (h *Handler) processMedia(ctx context.Context, body []byte) error {
+	var job model.MediaJob
+	if err := json.Unmarshal(body, &job); err != nil {
+		// the malformed job can't succeed on retry
+		logger.Error("Failed to unmarshal media job", zap.Error(err))
+		return nil
+	}
+
+	processed, err := h.services.MediaProcessing.ProcessMedia(ctx, job.MediaID)
+	if err != nil {
+		return err
+	}
+	if processed == nil {
+		return nil
+	}
+
+	h.broadcastProcessedMedia(ctx, *processed)
+	return nil
+}
+
+func (h *Handler) broadcastProcessedMedia(ctx context.Context, processed model.ProcessedMedia) {
+	// the media not attached to a message yet is only known to the uploader
+	if len(processed.Locations) == 0 {
+		event := model.MediaProcessedEvent{
+			Type:  WEBSOCKET_TYPE_MEDIA_PROCESSED,
+			Media: processed.Media,
+		}
+		if err := h.writeToUser(ctx, processed.UploadedBy, event); err != nil && !errors.Is(err, model.ErrWebSocketNotFound) {
+			logger.Warn("Failed to send WebSocket message", zap.String("userID", processed.UploadedBy), zap.Error(err))
+		}
+		return
+	}
+
+	for _, location := range processed.Locations {
+		participantsIDs, err := h.services.Chats.GetParticipantsOfChat(ctx, location.ChatID)
+		if err != nil {
+			logger.Error("Failed to get participants of chat", zap.Int64("chatID", location.ChatID), zap.Error(err))
+			continue
+		}
+
+		event := model.MediaProcessedEvent{
+			Type:      WEBSOCKET_TYPE_MEDIA_PROCESSED,
+			ChatID:    location.ChatID,
+			MessageID: location.MessageID,
+			Media:     processed.Media,
+		}
+		for _, recipientID := range participantsIDs {
+			if err := h.writeToUser(ctx, recipientID, event); err != nil && !errors.Is(err, model.ErrWebSocketNotFound) {
+				logger.Warn("Failed to send WebSocket message", zap.String("userID", recipientID), zap.Error(err))
+			}
+		}
+	}
+}
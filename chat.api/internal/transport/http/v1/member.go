@@ -0,0 +1,332 @@
+package v1
+
+import (
+	"chat-api/internal/model"
+	"chat-api/internal/server/grpc/profile/proto"
+	"chat-api/pkg/broker"
+	"chat-api/pkg/logger"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func (h *Handler) addMembers(c *gin.Context) {
+	var request model.AddMembersRequest
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	chatID, err := strconv.ParseInt(c.Param("chat_id"), 10, 64)
+	if err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid chat_id")
+		return
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Error("Failed to bind request", zap.Error(err))
+		newResponse(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	request.ChatID = chatID
+	request.UserID = userID
+
+	response, err := h.services.Members.AddMembers(c.Request.Context(), request)
+	if err != nil {
+		h.newMemberErrorResponse(c, err, "failed to add members")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	for _, addedID := range response.AddedIDs {
+		h.broadcastChatMember(ctx, response.Chat, addedID, model.CHAT_ACTION_JOIN, userID, broker.ROUTING_KEY_CHAT_ADDED_USER)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *Handler) kickMember(c *gin.Context) {
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	chatID, err := strconv.ParseInt(c.Param("chat_id"), 10, 64)
+	if err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid chat_id")
+		return
+	}
+
+	request := model.KickMemberRequest{
+		ChatID:   chatID,
+		UserID:   userID,
+		MemberID: c.Param("user_id"),
+	}
+	if ban := c.Query("ban"); ban != "" {
+		if request.Ban, err = strconv.ParseBool(ban); err != nil {
+			newResponse(c, http.StatusBadRequest, "invalid ban")
+			return
+		}
+	}
+
+	chat, err := h.services.Members.KickMember(c.Request.Context(), request)
+	if err != nil {
+		h.newMemberErrorResponse(c, err, "failed to kick member")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	h.broadcastChatMember(ctx, chat, request.MemberID, model.CHAT_ACTION_KICK, userID, broker.ROUTING_KEY_CHAT_KICKED_USER)
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) leaveChat(c *gin.Context) {
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	chatID, err := strconv.ParseInt(c.Param("chat_id"), 10, 64)
+	if err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid chat_id")
+		return
+	}
+
+	chat, err := h.services.Members.LeaveChat(c.Request.Context(), chatID, userID)
+	if err != nil {
+		h.newMemberErrorResponse(c, err, "failed to leave chat")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	h.broadcastChatMember(ctx, chat, userID, model.CHAT_ACTION_LEFT, "", broker.ROUTING_KEY_CHAT_LEFT_USER)
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) updateChatInfo(c *gin.Context) {
+	var request model.UpdateChatInfoRequest
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	chatID, err := strconv.ParseInt(c.Param("chat_id"), 10, 64)
+	if err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid chat_id")
+		return
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Error("Failed to bind request", zap.Error(err))
+		newResponse(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	request.ChatID = chatID
+	request.UserID = userID
+
+	chat, err := h.services.Members.UpdateChatInfo(c.Request.Context(), request)
+	if err != nil {
+		h.newMemberErrorResponse(c, err, "failed to update chat")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// only the rename is worth a notification
+	routingKey := ""
+	if request.Name != nil {
+		routingKey = broker.ROUTING_KEY_CHAT_RENAME
+	}
+	h.broadcastChatInfo(ctx, chat, userID, routingKey)
+
+	c.JSON(http.StatusOK, chat)
+}
+
+func (h *Handler) transferOwnership(c *gin.Context) {
+	var request model.TransferOwnershipRequest
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	chatID, err := strconv.ParseInt(c.Param("chat_id"), 10, 64)
+	if err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid chat_id")
+		return
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Error("Failed to bind request", zap.Error(err))
+		newResponse(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	request.ChatID = chatID
+	request.UserID = userID
+
+	chat, err := h.services.Members.TransferOwnership(c.Request.Context(), request)
+	if err != nil {
+		h.newMemberErrorResponse(c, err, "failed to transfer ownership")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	h.broadcastChatInfo(ctx, chat, userID, "")
+
+	c.JSON(http.StatusOK, chat)
+}
+
+// broadcastChatMember tells the participants and the member about the change of the members,
+// only the admins of a channel hear about its subscribers.
+// With the routing key the offline member, or the creator when the member left, gets a notification
+func (h *Handler) broadcastChatMember(ctx context.Context, chat model.ChatDB, userID, action, actorID, routingKey string) {
+	var (
+		participantsIDs []string
+		err             error
+	)
+	if chat.Type == model.CHAT_TYPE_CHANNEL {
+		participantsIDs, err = h.services.Channels.GetChannelAdmins(ctx, chat.ChatID)
+	} else {
+		participantsIDs, err = h.services.Chats.GetParticipantsOfChat(ctx, chat.ChatID)
+	}
+	if err != nil {
+		logger.Error("Failed to get participants of chat", zap.Error(err))
+		return
+	}
+
+	// the kicked or the left user isn't a participant anymore
+	recipientsIDs := participantsIDs
+	if action == model.CHAT_ACTION_KICK || action == model.CHAT_ACTION_LEFT {
+		recipientsIDs = append(recipientsIDs, userID)
+	}
+
+	notifyID, senderID := userID, actorID
+	if action == model.CHAT_ACTION_LEFT {
+		notifyID, senderID = chat.CreatorID, userID
+		// the owner of a channel isn't notified about every unsubscription
+		if chat.Type == model.CHAT_TYPE_CHANNEL {
+			routingKey = ""
+		}
+	}
+
+	event := model.ChatMemberEvent{
+		Type:    WEBSOCKET_TYPE_CHAT_MEMBER,
+		ChatID:  chat.ChatID,
+		UserID:  userID,
+		Action:  action,
+		ActorID: actorID,
+	}
+
+	for _, recipientID := range recipientsIDs {
+		err := h.writeToUser(ctx, recipientID, event)
+		if errors.Is(err, model.ErrWebSocketNotFound) {
+			if routingKey != "" && recipientID == notifyID {
+				h.notifyChat(ctx, chat, senderID, recipientID, routingKey)
+			}
+			continue
+		}
+		if err != nil {
+			logger.Warn("Failed to send WebSocket message", zap.String("userID", recipientID), zap.Error(err))
+		}
+	}
+}
+
+// broadcastChatInfo sends the updated chat to the participants, with the routing key the offline ones get a notification
+func (h *Handler) broadcastChatInfo(ctx context.Context, chat model.ChatDB, actorID, routingKey string) {
+	participantsIDs, err := h.services.Chats.GetParticipantsOfChat(ctx, chat.ChatID)
+	if err != nil {
+		logger.Error("Failed to get participants of chat", zap.Error(err))
+		return
+	}
+
+	event := model.ChatInfoEvent{
+		Type:    WEBSOCKET_TYPE_CHAT_UPDATED,
+		Chat:    chat,
+		ActorID: actorID,
+	}
+
+	for _, recipientID := range participantsIDs {
+		err := h.writeToUser(ctx, recipientID, event)
+		if errors.Is(err, model.ErrWebSocketNotFound) {
+			if routingKey != "" && recipientID != actorID {
+				h.notifyChat(ctx, chat, actorID, recipientID, routingKey)
+			}
+			continue
+		}
+		if err != nil {
+			logger.Warn("Failed to send WebSocket message", zap.String("userID", recipientID), zap.Error(err))
+		}
+	}
+}
+
+// notifyChat publishes the chat event from the sender to the notifications of the recipient
+func (h *Handler) notifyChat(ctx context.Context, chat model.ChatDB, senderID, recipientID, routingKey string) {
+	responseProfile, err := h.profileClient.GetUserBriefInfo(ctx, &proto.UserRequest{
+		SenderID:    senderID,
+		RecipientID: recipientID,
+	})
+	if err != nil {
+		logger.Error("Failed to get profile for notification", zap.Error(err))
+		return
+	}
+
+	if err := h.services.Notifications.SendNotification(
+		ctx,
+		model.NotificationRabbitMQ{
+			Exchange:   broker.EXCHANGE_CHAT,
+			RoutingKey: routingKey,
+		},
+		model.NotificationChat{
+			Chat: model.ChatBriefInfo{
+				ChatID:    chat.ChatID,
+				CreatorID: chat.CreatorID,
+				Name:      chat.Name,
+				Encrypted: chat.Encrypted,
+				AvatarURL: chat.AvatarURL,
+				UpdatedAt: chat.UpdatedAt,
+			},
+			Sender: model.UserBriefInfo{
+				UserID:    responseProfile.UserID,
+				Username:  responseProfile.Username,
+				Name:      responseProfile.Name,
+				AvatarURL: responseProfile.AvatarURL,
+			},
+			RecipientID: recipientID,
+		},
+	); err != nil {
+		logger.Error("Failed to send chat notification", zap.String("routingKey", routingKey), zap.Error(err))
+	}
+}
+
+func (h *Handler) newMemberErrorResponse(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, model.ErrInvalidMembers), errors.Is(err, model.ErrInvalidChatInfo), errors.Is(err, model.ErrInvalidParamsOfChat),
+		errors.Is(err, model.ErrInvalidChatRole), errors.Is(err, model.ErrInvalidParamsOfMessage):
+		newResponse(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, model.ErrChatPermissionDenied), errors.Is(err, model.ErrNotChatOwner), errors.Is(err, model.ErrNotParticipant),
+		errors.Is(err, model.ErrCannotKickMember), errors.Is(err, model.ErrUserBanned):
+		newResponse(c, http.StatusForbidden, err.Error())
+	case errors.Is(err, model.ErrMemberNotFound), errors.Is(err, model.ErrMessageNotFound), errors.Is(err, model.ErrChannelNotFound):
+		newResponse(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, model.ErrChatOwnerCannotLeave), errors.Is(err, model.ErrChannelHandleTaken), errors.Is(err, model.ErrAlreadyParticipant):
+		newResponse(c, http.StatusConflict, err.Error())
+	default:
+		logger.Error(message, zap.Error(err))
+		newResponse(c, http.StatusInternalServerError, message)
+	}
+}
@@ -0,0 +1,76 @@
+package v1
+
+import (
+	"chat-api/internal/model"
+	"chat-api/pkg/logger"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func (h *Handler) getUnreadMentions(c *gin.Context) {
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	chatID, err := strconv.ParseInt(c.Param("chat_id"), 10, 64)
+	if err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid chat_id")
+		return
+	}
+
+	mentions, err := h.services.Mentions.GetUnreadMentions(c.Request.Context(), chatID, userID)
+	if err != nil {
+		if errors.Is(err, model.ErrNotParticipant) {
+			newResponse(c, http.StatusForbidden, err.Error())
+			return
+		}
+		logger.Error("Failed to get unread mentions", zap.Error(err))
+		newResponse(c, http.StatusInternalServerError, "failed to get unread mentions")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"mentions": mentions, "count": len(mentions)})
+}
+
+func (h *Handler) readMentions(c *gin.Context) {
+	var request model.ReadMentionsRequest
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	chatID, err := strconv.ParseInt(c.Param("chat_id"), 10, 64)
+	if err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid chat_id")
+		return
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Error("Failed to bind request", zap.Error(err))
+		newResponse(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	request.ChatID = chatID
+	request.UserID = userID
+
+	read, err := h.services.Mentions.ReadMentions(c.Request.Context(), request)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrInvalidParamsOfMessage):
+			newResponse(c, http.StatusBadRequest, err.Error())
+		case errors.Is(err, model.ErrNotParticipant):
+			newResponse(c, http.StatusForbidden, err.Error())
+		default:
+			logger.Error("Failed to read mentions", zap.Error(err))
+			newResponse(c, http.StatusInternalServerError, "failed to read mentions")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"read": read})
+}
@@ -18,28 +18,71 @@ func (h *Handler) sendMessage(ws *websocket.Conn, request model.CreateMessageReq
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	// offsets of the entities and mentions point into the plain content
+	if err := formatMessage(&request.MessageWithData); err != nil {
+		logger.Error("Failed to send message", zap.Error(err))
+		return
+	}
+
+	if err := h.reserveMessageID(ctx, &request.MessageWithData); err != nil {
+		logger.Error("Failed to reserve message id", zap.Error(err))
+		return
+	}
+
+	if err := h.encryptMessage(ctx, request.ChatID, &request.MessageWithData); err != nil {
+		logger.Error("Failed to encrypted message", zap.Error(err))
+		return
+	}
+
+	if err := h.encryptPoll(ctx, request.ChatID, request.MessageWithData.MessageDB.MessageID, request.MessageWithData.Poll); err != nil {
+		logger.Error("Failed to encrypted poll", zap.Error(err))
+		return
+	}
+
+	if err := h.dispatchMessage(ctx, &request); err != nil {
+		logger.Error("Failed during sendMessage flow", zap.Error(err))
+	}
+}
+
+// dispatchMessage stores the message with the encrypted content and delivers it to the participants of the chat,
+// the subscribers of a channel get the post from the fan-out workers
+func (h *Handler) dispatchMessage(ctx context.Context, request *model.CreateMessageRequest) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	chatDB, err := h.services.Chats.GetChatByChatID(ctx, request.ChatID)
+	if err != nil {
+		return err
+	}
+
+	if chatDB.Type == model.CHAT_TYPE_CHANNEL {
+		request.Signature = h.postSignature(ctx, chatDB, request.MessageWithData.MessageDB.SenderID)
+		if err := h.services.Messages.SendMessage(ctx, request); err != nil {
+			return err
+		}
+
+		h.requestChannelFanout(ctx, *request)
+
+		// the link is looked for in the plain content
+		plain := request.MessageWithData
+		h.decryptMessage(ctx, request.ChatID, &plain)
+		h.indexMessage(ctx, request.ChatID, plain.MessageDB.MessageID, plain.MessageDB.Content)
+		h.requestLinkPreview(ctx, request.ChatID, plain.MessageDB.MessageID, plain.MessageDB.Content, plain.Entities)
+		return nil
+	}
+
 	var (
 		wg              sync.WaitGroup
 		mu              sync.Mutex
 		sendErr         error
 		participantsIDs []string
-		chatDB          model.ChatDB
 	)
 
-	if request.MessageWithData.MessageDB.Content != nil {
-		encrypted, err := h.services.MessageEncrypter.Encrypt(*request.MessageWithData.MessageDB.Content)
-		if err != nil {
-			logger.Error("Failed to encrypted message", zap.Error(err))
-			return
-		}
-		request.MessageWithData.MessageDB.Content = &encrypted
-	}
-
-	wg.Add(3)
+	wg.Add(2)
 
 	go func() {
 		defer wg.Done()
-		if err := h.services.Messages.SendMessage(ctx, &request); err != nil {
+		if err := h.services.Messages.SendMessage(ctx, request); err != nil {
 			mu.Lock()
 			sendErr = err
 			mu.Unlock()
@@ -59,34 +102,30 @@ func (h *Handler) sendMessage(ws *websocket.Conn, request model.CreateMessageReq
 		mu.Unlock()
 	}()
 
-	go func() {
-		defer wg.Done()
-		chat, err := h.services.Chats.GetChatByChatID(ctx, request.ChatID)
-		mu.Lock()
-		if err != nil && sendErr == nil {
-			sendErr = err
-			cancel()
-		}
-		chatDB = chat
-		mu.Unlock()
-	}()
-
 	wg.Wait()
 
 	if sendErr != nil {
-		logger.Error("Failed during sendMessage flow", zap.Error(sendErr))
-		return
+		return sendErr
 	}
 
-	if request.MessageWithData.MessageDB.Content != nil {
-		decrypted, err := h.services.MessageEncrypter.Decrypt(*request.MessageWithData.MessageDB.Content)
-		if err != nil {
-			logger.Error("Failed to decrypted message", zap.Error(err))
-			return
-		}
-		request.MessageWithData.MessageDB.Content = &decrypted
+	// the stored message keeps the ciphertext, participants get a copy with the plain content
+	delivered := *request
+	h.decryptMessage(ctx, delivered.ChatID, &delivered.MessageWithData)
+	if delivered.MessageWithData.Poll != nil {
+		poll := delivered.MessageWithData.Poll.ForViewer(nil, "")
+		delivered.MessageWithData.Poll = &poll
 	}
 
+	h.deliverMessage(ctx, chatDB, participantsIDs, delivered)
+	h.indexMessage(ctx, request.ChatID, delivered.MessageWithData.MessageDB.MessageID, delivered.MessageWithData.MessageDB.Content)
+	h.requestLinkPreview(ctx, request.ChatID, delivered.MessageWithData.MessageDB.MessageID, delivered.MessageWithData.MessageDB.Content, delivered.MessageWithData.Entities)
+	return nil
+}
+
+// deliverMessage pushes the message to the participants over WebSocket,
+// offline participants get the message.send notification instead, or message.mention
+// if they are mentioned, which is shown even in a muted chat
+func (h *Handler) deliverMessage(ctx context.Context, chatDB model.ChatDB, participantsIDs []string, request model.CreateMessageRequest) {
 	for _, recipientID := range participantsIDs {
 		// Try to get the recipient's WebSocket connection
 		webSocketConnection, err := h.services.Auth.GetWebSocket(ctx, recipientID)
@@ -111,12 +150,16 @@ func (h *Handler) sendMessage(ws *websocket.Conn, request model.CreateMessageReq
 				}
 
 				// Send notification about message creation
+				routingKey := broker.ROUTING_KEY_MESSAGE_SEND
+				if model.IsMentioned(request.MessageWithData, recipientID) && recipientID != request.MessageWithData.MessageDB.SenderID {
+					routingKey = broker.ROUTING_KEY_MESSAGE_MENTION
+				}
 
 				if err := h.services.Notifications.SendNotification(
 					ctx,
 					model.NotificationRabbitMQ{
 						Exchange:   broker.EXCHANGE_MESSAGE,
-						RoutingKey: broker.ROUTING_KEY_MESSAGE_SEND,
+						RoutingKey: routingKey,
 					},
 					model.NotificationMessage{
 						Message: model.MessageBriefInfo{
@@ -124,6 +167,7 @@ func (h *Handler) sendMessage(ws *websocket.Conn, request model.CreateMessageReq
 							SenderID:  request.MessageWithData.MessageDB.SenderID,
 							Type:      request.MessageWithData.MessageDB.Type,
 							UpdatedAt: request.MessageWithData.MessageDB.CreatedAt,
+							Duration:  model.VoiceDuration(request.MessageWithData),
 						},
 						Chat: model.ChatBriefInfo{
 							ChatID:    chatDB.ChatID,
@@ -160,4 +204,40 @@ func (h *Handler) sendMessage(ws *websocket.Conn, request model.CreateMessageReq
 	}
 }
 
-// TODO sendMessageE2EE
+// decryptMessage replaces the stored ciphertext of the message of the chat, its entities, link preview, poll
+// and the quoted message with the plain content
+func (h *Handler) decryptMessage(ctx context.Context, chatID int64, message *model.SendMessage) {
+	messageID := message.MessageDB.MessageID
+	message.MessageDB.Content = h.decryptContent(ctx, chatID, messageID, message.MessageDB.Content)
+	message.Entities = h.decryptEntities(ctx, chatID, messageID, message.MessageDB.EncryptedEntities)
+	message.MessageDB.EncryptedEntities = nil
+	if message.EncryptedLinkPreview != nil {
+		message.LinkPreview = h.decryptLinkPreview(ctx, chatID, messageID, message.EncryptedLinkPreview)
+		message.EncryptedLinkPreview = nil
+	}
+	if message.ReplyTo != nil {
+		// the quote is a copy, so the stored message keeps its ciphertext
+		quoted := *message.ReplyTo
+		quoted.Content = h.decryptContent(ctx, chatID, quoted.MessageID, quoted.Content)
+		quoted.Entities = h.decryptEntities(ctx, chatID, quoted.MessageID, quoted.EncryptedEntities)
+		quoted.EncryptedEntities = nil
+		message.ReplyTo = &quoted
+	}
+	if message.Poll != nil {
+		poll := h.decryptPoll(ctx, chatID, *message.Poll)
+		message.Poll = &poll
+	}
+}
+
+func (h *Handler) decryptContent(ctx context.Context, chatID, messageID int64, content *string) *string {
+	if content == nil {
+		return nil
+	}
+
+	decrypted, err := h.services.MessageEncrypter.Decrypt(ctx, chatID, messageID, *content)
+	if err != nil {
+		logger.Warn("Failed to decrypted message", zap.Int64("messageID", messageID), zap.Error(err))
+		return nil
+	}
+	return &decrypted
+}
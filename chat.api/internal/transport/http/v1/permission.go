@@ -0,0 +1,212 @@
+package v1
+
+import (
+	"chat-api/internal/model"
+	"chat-api/pkg/logger"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func (h *Handler) getChatPermissions(c *gin.Context) {
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	chatID, err := strconv.ParseInt(c.Param("chat_id"), 10, 64)
+	if err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid chat_id")
+		return
+	}
+
+	permissions, err := h.services.Chats.GetChatPermissions(c.Request.Context(), chatID, userID)
+	if err != nil {
+		h.newMemberErrorResponse(c, err, "failed to get chat permissions")
+		return
+	}
+
+	c.JSON(http.StatusOK, permissions)
+}
+
+func (h *Handler) setDefaultPermissions(c *gin.Context) {
+	var request model.DefaultPermissionsRequest
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	chatID, err := strconv.ParseInt(c.Param("chat_id"), 10, 64)
+	if err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid chat_id")
+		return
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Error("Failed to bind request", zap.Error(err))
+		newResponse(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	request.ChatID = chatID
+	request.UserID = userID
+
+	chat, err := h.services.Chats.SetDefaultPermissions(c.Request.Context(), request)
+	if err != nil {
+		h.newMemberErrorResponse(c, err, "failed to set default permissions")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	h.broadcastChatInfo(ctx, chat, userID, "")
+
+	c.JSON(http.StatusOK, chat)
+}
+
+func (h *Handler) deleteMessage(c *gin.Context) {
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	chatID, messageID, ok := parseChatMessage(c)
+	if !ok {
+		return
+	}
+
+	deleted, err := h.services.Messages.DeleteMessage(c.Request.Context(), chatID, messageID, userID)
+	if err != nil {
+		h.newMemberErrorResponse(c, err, "failed to delete message")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	h.broadcastDeletedMessages(ctx, deleted)
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) pinMessage(c *gin.Context) {
+	var pinnedMessage model.PinnedMessage
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	chatID, messageID, ok := parseChatMessage(c)
+	if !ok {
+		return
+	}
+
+	// the priority is optional
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&pinnedMessage); err != nil {
+			logger.Error("Failed to bind request", zap.Error(err))
+			newResponse(c, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+	pinnedMessage.ChatID = chatID
+	pinnedMessage.MessageID = messageID
+	pinnedMessage.PinnedByUserID = userID
+
+	if err := h.services.Chats.PinMessage(c.Request.Context(), pinnedMessage); err != nil {
+		h.newMemberErrorResponse(c, err, "failed to pin message")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	h.broadcastPinnedMessage(ctx, pinnedMessage, true)
+
+	c.JSON(http.StatusOK, pinnedMessage)
+}
+
+func (h *Handler) unpinMessage(c *gin.Context) {
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	chatID, messageID, ok := parseChatMessage(c)
+	if !ok {
+		return
+	}
+
+	pinnedMessage := model.PinnedMessage{
+		ChatID:         chatID,
+		MessageID:      messageID,
+		PinnedByUserID: userID,
+	}
+
+	if err := h.services.Chats.UnpinMessage(c.Request.Context(), pinnedMessage); err != nil {
+		h.newMemberErrorResponse(c, err, "failed to unpin message")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	h.broadcastPinnedMessage(ctx, pinnedMessage, false)
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) broadcastChatRole(ctx context.Context, chatRole model.ChatRole) {
+	participantsIDs, err := h.services.Chats.GetParticipantsOfChat(ctx, chatRole.ChatID)
+	if err != nil {
+		logger.Error("Failed to get participants of chat", zap.Error(err))
+		return
+	}
+
+	event := model.ChatRoleEvent{Type: WEBSOCKET_TYPE_CHAT_ROLE, ChatRole: chatRole}
+	for _, recipientID := range participantsIDs {
+		if err := h.writeToUser(ctx, recipientID, event); err != nil && !errors.Is(err, model.ErrWebSocketNotFound) {
+			logger.Warn("Failed to send WebSocket message", zap.String("userID", recipientID), zap.Error(err))
+		}
+	}
+}
+
+func (h *Handler) broadcastPinnedMessage(ctx context.Context, pinnedMessage model.PinnedMessage, pinned bool) {
+	participantsIDs, err := h.services.Chats.GetParticipantsOfChat(ctx, pinnedMessage.ChatID)
+	if err != nil {
+		logger.Error("Failed to get participants of chat", zap.Error(err))
+		return
+	}
+
+	event := model.PinnedMessageEvent{
+		Type:          WEBSOCKET_TYPE_MESSAGE_PINNED,
+		PinnedMessage: pinnedMessage,
+		Pinned:        pinned,
+	}
+	for _, recipientID := range participantsIDs {
+		if err := h.writeToUser(ctx, recipientID, event); err != nil && !errors.Is(err, model.ErrWebSocketNotFound) {
+			logger.Warn("Failed to send WebSocket message", zap.String("userID", recipientID), zap.Error(err))
+		}
+	}
+}
+
+func parseChatMessage(c *gin.Context) (chatID, messageID int64, ok bool) {
+	chatID, err := strconv.ParseInt(c.Param("chat_id"), 10, 64)
+	if err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid chat_id")
+		return 0, 0, false
+	}
+
+	messageID, err = strconv.ParseInt(c.Param("message_id"), 10, 64)
+	if err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid message_id")
+		return 0, 0, false
+	}
+
+	return chatID, messageID, true
+}
@@ -0,0 +1,167 @@
+package v1
+
+import (
+	"chat-api/internal/model"
+	"chat-api/pkg/logger"
+	"context"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func (h *Handler) votePoll(request model.PollVoteRequest) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	state, err := h.services.Polls.Vote(ctx, request)
+	if err != nil {
+		logger.Error("Failed to vote in poll", zap.Error(err))
+		return
+	}
+	if state != nil {
+		h.broadcastPoll(ctx, WEBSOCKET_TYPE_POLL_UPDATED, *state, request.UserID)
+	}
+}
+
+func (h *Handler) retractPollVote(request model.PollVoteRequest) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	state, err := h.services.Polls.RetractVote(ctx, request)
+	if err != nil {
+		logger.Error("Failed to retract poll vote", zap.Error(err))
+		return
+	}
+	if state != nil {
+		h.broadcastPoll(ctx, WEBSOCKET_TYPE_POLL_UPDATED, *state, request.UserID)
+	}
+}
+
+// broadcastPoll sends every connected participant the results of the poll as they are seen by them.
+// The subscribers of a channel get the counts without the voters from the fan-out workers, the voter gets its own view at once
+func (h *Handler) broadcastPoll(ctx context.Context, eventType string, state model.PollState, voterID string) {
+	chatDB, err := h.services.Chats.GetChatByChatID(ctx, state.ChatID)
+	if err != nil {
+		logger.Error("Failed to get chat", zap.Error(err))
+		return
+	}
+
+	poll := h.decryptPoll(ctx, state.ChatID, state.Poll)
+
+	if chatDB.Type == model.CHAT_TYPE_CHANNEL {
+		if voterID != "" {
+			event := model.PollEvent{
+				Type:      eventType,
+				ChatID:    state.ChatID,
+				MessageID: state.Poll.MessageID,
+				Poll:      poll.ForSubscriber(state.Votes, voterID),
+			}
+			if err := h.writeToUser(ctx, voterID, event); err != nil && !errors.Is(err, model.ErrWebSocketNotFound) {
+				logger.Warn("Failed to send WebSocket message", zap.String("userID", voterID), zap.Error(err))
+			}
+		}
+
+		h.requestChannelUpdate(ctx, model.ChannelPostUpdate{
+			Type:       eventType,
+			ChatID:     state.ChatID,
+			MessageID:  state.Poll.MessageID,
+			SkipUserID: voterID,
+		})
+		return
+	}
+
+	participantsIDs, err := h.services.Chats.GetParticipantsOfChat(ctx, state.ChatID)
+	if err != nil {
+		logger.Error("Failed to get participants of chat", zap.Error(err))
+		return
+	}
+	for _, recipientID := range participantsIDs {
+		event := model.PollEvent{
+			Type:      eventType,
+			ChatID:    state.ChatID,
+			MessageID: state.Poll.MessageID,
+			Poll:      poll.ForViewer(state.Votes, recipientID),
+		}
+
+		if err := h.writeToUser(ctx, recipientID, event); err != nil && !errors.Is(err, model.ErrWebSocketNotFound) {
+			logger.Warn("Failed to send WebSocket message", zap.String("userID", recipientID), zap.Error(err))
+		}
+	}
+}
+
+// RunPollCloser closes the polls whose close time has come until ctx is done,
+// connected participants get the final results
+func (h *Handler) RunPollCloser(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// a full batch means there may be more due polls
+		for {
+			closed, err := h.services.Polls.CloseDuePolls(ctx)
+			for _, state := range closed {
+				h.broadcastPoll(ctx, WEBSOCKET_TYPE_POLL_CLOSED, state, "")
+			}
+			if err != nil {
+				logger.Error("Failed to close polls", zap.Error(err))
+				break
+			}
+			if len(closed) < model.POLL_CLOSE_BATCH_SIZE {
+				break
+			}
+		}
+	}
+}
+
+// encryptPoll checks the plain texts of the poll and encrypts them by the key of the chat bound to the message like the content
+func (h *Handler) encryptPoll(ctx context.Context, chatID, messageID int64, poll *model.Poll) error {
+	if poll == nil {
+		return nil
+	}
+	if !model.IsValidPollText(poll) {
+		return model.ErrInvalidPoll
+	}
+
+	question, err := h.services.MessageEncrypter.Encrypt(ctx, chatID, messageID, poll.Question)
+	if err != nil {
+		return err
+	}
+	poll.Question = question
+
+	for i := range poll.Options {
+		text, err := h.services.MessageEncrypter.Encrypt(ctx, chatID, messageID, poll.Options[i].Text)
+		if err != nil {
+			return err
+		}
+		poll.Options[i].Text = text
+	}
+
+	return nil
+}
+
+// decryptPoll returns a copy of the poll with the plain texts, the stored one keeps the ciphertext
+func (h *Handler) decryptPoll(ctx context.Context, chatID int64, poll model.Poll) model.Poll {
+	if question := h.decryptContent(ctx, chatID, poll.MessageID, &poll.Question); question != nil {
+		poll.Question = *question
+	} else {
+		poll.Question = ""
+	}
+
+	options := make([]model.PollOption, len(poll.Options))
+	for i, option := range poll.Options {
+		options[i] = model.PollOption{OptionID: option.OptionID}
+		if text := h.decryptContent(ctx, chatID, poll.MessageID, &option.Text); text != nil {
+			options[i].Text = *text
+		}
+	}
+	poll.Options = options
+
+	return poll
+}
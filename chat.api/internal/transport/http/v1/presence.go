@@ -0,0 +1,157 @@
+package v1
+
+import (
+	"chat-api/internal/model"
+	profile "chat-api/internal/server/grpc/profile/proto"
+	"chat-api/pkg/logger"
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+func (h *Handler) initPresenceRoutes(router *gin.RouterGroup) {
+	presence := router.Group("/presence")
+	{
+		presence.GET("/settings", h.getPresenceSettings)
+		presence.PUT("/settings", h.updatePresenceSettings)
+	}
+}
+
+func (h *Handler) getPresenceSettings(c *gin.Context) {
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	settings, err := h.services.Presence.GetPresenceSettings(c.Request.Context(), userID)
+	if err != nil {
+		logger.Error("Failed to get presence settings", zap.Error(err))
+		newResponse(c, http.StatusInternalServerError, "failed to get presence settings")
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+func (h *Handler) updatePresenceSettings(c *gin.Context) {
+	var settings model.PresenceSettings
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	if err := c.ShouldBindJSON(&settings); err != nil {
+		logger.Error("Failed to bind request", zap.Error(err))
+		newResponse(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.services.Presence.UpdatePresenceSettings(c.Request.Context(), userID, settings); err != nil {
+		logger.Error("Failed to update presence settings", zap.Error(err))
+		newResponse(c, http.StatusInternalServerError, "failed to update presence settings")
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// RunPresenceWatcher sends the changes of the presence to the subscribers connected to the replica until ctx is done
+func (h *Handler) RunPresenceWatcher(ctx context.Context, retryDelay time.Duration) {
+	for {
+		err := h.services.Presence.WatchPresence(ctx, func(subscriberID string, presence model.Presence) {
+			event := model.PresenceEvent{
+				Type:      WEBSOCKET_TYPE_PRESENCE,
+				Presences: []model.Presence{presence},
+			}
+			if err := h.writeToUser(ctx, subscriberID, event); err != nil && !errors.Is(err, model.ErrWebSocketNotFound) {
+				logger.Warn("Failed to send WebSocket message", zap.String("userID", subscriberID), zap.Error(err))
+			}
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		logger.Error("Failed to watch presence", zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryDelay):
+		}
+	}
+}
+
+// subscribePresence replaces the users watched over the socket and sends their current presence
+func (h *Handler) subscribePresence(ws *websocket.Conn, request model.PresenceSubscribeRequest) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if len(request.UsersIDs) > 0 {
+		response, err := h.profileClient.GetUsersWithContact(ctx, &profile.UsersRequest{
+			SenderID:     request.UserID,
+			RecipientIDs: request.UsersIDs,
+		})
+		if err != nil {
+			// the chat partners are still watched
+			logger.Warn("Failed to get users with contact", zap.String("userID", request.UserID), zap.Error(err))
+		} else {
+			request.Contacts = response.UserIDs
+		}
+	}
+
+	presences, err := h.services.Presence.Subscribe(ctx, request)
+	if err != nil {
+		logger.Error("Failed to subscribe to presence", zap.Error(err))
+		return
+	}
+
+	if err := ws.WriteJSON(model.PresenceEvent{Type: WEBSOCKET_TYPE_PRESENCE, Presences: presences}); err != nil {
+		logger.Warn("Failed to send WebSocket message", zap.String("userID", request.UserID), zap.Error(err))
+	}
+}
+
+func (h *Handler) updatePresence(request model.PresenceUpdateRequest) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := h.services.Presence.SetStatus(ctx, request); err != nil {
+		logger.Error("Failed to update presence", zap.Error(err))
+	}
+}
+
+// sendChatActivity shows the typing or uploading of the user to the other participants of the chat
+func (h *Handler) sendChatActivity(request model.ChatActivityRequest) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	recipientsIDs, err := h.services.Presence.SendChatActivity(ctx, request)
+	if err != nil {
+		if !errors.Is(err, model.ErrChatActivityTooOften) {
+			logger.Error("Failed to send chat activity", zap.Error(err))
+		}
+		return
+	}
+
+	event := model.ChatActivityEvent{
+		Type:   WEBSOCKET_TYPE_CHAT_ACTIVITY,
+		ChatID: request.ChatID,
+		UserID: request.UserID,
+		Action: request.Action,
+	}
+	for _, recipientID := range recipientsIDs {
+		if err := h.writeToUser(ctx, recipientID, event); err != nil && !errors.Is(err, model.ErrWebSocketNotFound) {
+			logger.Warn("Failed to send WebSocket message", zap.String("userID", recipientID), zap.Error(err))
+		}
+	}
+}
+
+// refreshPresence keeps the user online on every frame and ping of the socket
+func (h *Handler) refreshPresence(ctx context.Context, userID string) {
+	if err := h.services.Presence.Refresh(ctx, userID); err != nil {
+		logger.Warn("Failed to refresh presence", zap.String("userID", userID), zap.Error(err))
+	}
+}
@@ -0,0 +1,160 @@
+package v1
+
+import (
+	"chat-api/internal/model"
+	"chat-api/internal/server/grpc/profile/proto"
+	"chat-api/pkg/broker"
+	"chat-api/pkg/logger"
+	"context"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func (h *Handler) addReaction(request model.ReactionRequest) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	messageDB, err := h.services.Reactions.AddReaction(ctx, request)
+	if err != nil {
+		logger.Error("Failed to add reaction", zap.Error(err))
+		return
+	}
+
+	h.broadcastReaction(ctx, WEBSOCKET_TYPE_ADD_REACTION, request)
+
+	if messageDB.SenderID != "" && messageDB.SenderID != request.UserID {
+		h.notifyReactionAuthor(ctx, request, messageDB)
+	}
+}
+
+func (h *Handler) removeReaction(request model.ReactionRequest) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := h.services.Reactions.RemoveReaction(ctx, request); err != nil {
+		logger.Error("Failed to remove reaction", zap.Error(err))
+		return
+	}
+
+	h.broadcastReaction(ctx, WEBSOCKET_TYPE_REMOVE_REACTION, request)
+}
+
+// broadcastReaction sends the updated reaction counts to every connected participant of the chat,
+// the subscribers of a channel get the counts without the users from the fan-out workers
+func (h *Handler) broadcastReaction(ctx context.Context, eventType string, request model.ReactionRequest) {
+	chatDB, err := h.services.Chats.GetChatByChatID(ctx, request.ChatID)
+	if err != nil {
+		logger.Error("Failed to get chat", zap.Error(err))
+		return
+	}
+
+	reactions, err := h.services.Reactions.GetReactions(ctx, request.MessageID)
+	if err != nil {
+		logger.Error("Failed to get reactions of message", zap.Error(err))
+		return
+	}
+
+	if chatDB.Type == model.CHAT_TYPE_CHANNEL {
+		event := model.ReactionEvent{
+			Type:      eventType,
+			ChatID:    request.ChatID,
+			MessageID: request.MessageID,
+			Reactions: model.AggregateReactions(reactions, request.UserID),
+		}
+		if err := h.writeToUser(ctx, request.UserID, event); err != nil && !errors.Is(err, model.ErrWebSocketNotFound) {
+			logger.Warn("Failed to send WebSocket message", zap.String("userID", request.UserID), zap.Error(err))
+		}
+
+		h.requestChannelUpdate(ctx, model.ChannelPostUpdate{
+			Type:       eventType,
+			ChatID:     request.ChatID,
+			MessageID:  request.MessageID,
+			SkipUserID: request.UserID,
+		})
+		return
+	}
+
+	participantsIDs, err := h.services.Chats.GetParticipantsOfChat(ctx, request.ChatID)
+	if err != nil {
+		logger.Error("Failed to get participants of chat", zap.Error(err))
+		return
+	}
+
+	for _, recipientID := range participantsIDs {
+		event := model.ReactionEvent{
+			Type:      eventType,
+			ChatID:    request.ChatID,
+			MessageID: request.MessageID,
+			UserID:    request.UserID,
+			Emoji:     request.Emoji,
+			Reactions: model.AggregateReactions(reactions, recipientID),
+		}
+
+		if err := h.writeToUser(ctx, recipientID, event); err != nil && !errors.Is(err, model.ErrWebSocketNotFound) {
+			logger.Warn("Failed to send WebSocket message", zap.String("userID", recipientID), zap.Error(err))
+		}
+	}
+}
+
+// notifyReactionAuthor falls back to a notification when the author of the message is offline
+func (h *Handler) notifyReactionAuthor(ctx context.Context, request model.ReactionRequest, messageDB model.MessageDB) {
+	if _, err := h.services.Auth.GetWebSocket(ctx, messageDB.SenderID); !errors.Is(err, model.ErrWebSocketNotFound) {
+		return
+	}
+
+	responseProfile, err := h.profileClient.GetUserBriefInfo(ctx, &proto.UserRequest{
+		SenderID:    request.UserID,
+		RecipientID: messageDB.SenderID,
+	})
+	if err != nil {
+		logger.Error("Failed to get profile for notification", zap.Error(err))
+		return
+	}
+
+	sender := model.UserBriefInfo{
+		UserID:    responseProfile.UserID,
+		Username:  responseProfile.Username,
+		Name:      responseProfile.Name,
+		AvatarURL: responseProfile.AvatarURL,
+	}
+
+	chatDB, err := h.services.Chats.GetChatByChatID(ctx, request.ChatID)
+	if err != nil {
+		logger.Error("Failed to get chat for notification", zap.Error(err))
+		return
+	}
+
+	if chatDB.Type == model.CHAT_TYPE_PRIVATE {
+		chatDB.Name = sender.Name
+	}
+
+	if err := h.services.Notifications.SendNotification(
+		ctx,
+		model.NotificationRabbitMQ{
+			Exchange:   broker.EXCHANGE_MESSAGE,
+			RoutingKey: broker.ROUTING_KEY_MESSAGE_REACTION,
+		},
+		model.NotificationReaction{
+			Message: model.MessageBriefInfo{
+				MessageID: messageDB.MessageID,
+				SenderID:  messageDB.SenderID,
+				Type:      messageDB.Type,
+				UpdatedAt: messageDB.UpdatedAt,
+			},
+			Chat: model.ChatBriefInfo{
+				ChatID:    chatDB.ChatID,
+				CreatorID: chatDB.CreatorID,
+				Name:      chatDB.Name,
+				Encrypted: chatDB.Encrypted,
+				UpdatedAt: chatDB.UpdatedAt,
+			},
+			Sender:      sender,
+			RecipientID: messageDB.SenderID,
+			Emoji:       request.Emoji,
+		},
+	); err != nil {
+		logger.Error("Failed to send notification about reaction", zap.Error(err))
+	}
+}
@@ -0,0 +1,64 @@
+package v1
+
+import (
+	"chat-api/internal/model"
+	"chat-api/pkg/logger"
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// RunReencryption keeps the stored content on the current keys until ctx is done:
+// it rewraps the data keys after a master key rotation, retires the data keys older than maxAge
+// and re-encrypts the messages left on a retired or legacy key. Several replicas may run it at once.
+func (h *Handler) RunReencryption(ctx context.Context, interval, maxAge time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// the pass through the messages is continued on the next tick and starts over once finished
+	var afterMessageID int64
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for {
+			rewrapped, err := h.services.KeyRotation.RewrapDataKeys(ctx)
+			if err != nil {
+				logger.Error("Failed to rewrap data keys", zap.Error(err))
+				break
+			}
+			if rewrapped < model.DATA_KEY_BATCH_SIZE {
+				break
+			}
+		}
+
+		for {
+			rotated, err := h.services.KeyRotation.RotateDataKeys(ctx, maxAge)
+			if err != nil {
+				logger.Error("Failed to rotate data keys", zap.Error(err))
+				break
+			}
+			if rotated < model.DATA_KEY_BATCH_SIZE {
+				break
+			}
+		}
+
+		for {
+			lastMessageID, processed, err := h.services.KeyRotation.ReencryptMessages(ctx, afterMessageID)
+			if err != nil {
+				logger.Error("Failed to re-encrypt messages", zap.Error(err))
+				break
+			}
+			if processed < model.REENCRYPT_BATCH_SIZE {
+				afterMessageID = 0
+				break
+			}
+			afterMessageID = lastMessageID
+		}
+	}
+}
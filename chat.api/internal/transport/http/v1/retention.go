@@ -0,0 +1,286 @@
+package v1
+
+import (
+	"chat-api/internal/model"
+	"chat-api/pkg/logger"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const COMPLIANCE_KEY_HEADER = "X-Compliance-Key"
+
+// initComplianceRoutes serves the compliance team, not the users, every request carries the compliance key
+func (h *Handler) initComplianceRoutes(router *gin.RouterGroup) {
+	compliance := router.Group("/compliance")
+	{
+		compliance.GET("/retention/policies", h.getRetentionPolicies)
+		compliance.PUT("/retention/policies/types/:chat_type", h.setRetentionPolicy)
+		compliance.DELETE("/retention/policies/types/:chat_type", h.deleteRetentionPolicy)
+		compliance.PUT("/retention/policies/chats/:chat_id", h.setRetentionPolicy)
+		compliance.DELETE("/retention/policies/chats/:chat_id", h.deleteRetentionPolicy)
+
+		compliance.GET("/retention/runs", h.getRetentionRuns)
+		compliance.GET("/retention/runs/:run_id", h.getRetentionRun)
+		compliance.GET("/retention/runs/:run_id/purges", h.getRetentionPurges)
+
+		compliance.GET("/legal-holds", h.getLegalHolds)
+		compliance.POST("/legal-holds", h.setLegalHold)
+		compliance.DELETE("/legal-holds/:hold_id", h.releaseLegalHold)
+	}
+}
+
+func (h *Handler) checkComplianceKey(c *gin.Context) bool {
+	if err := h.services.Auth.ValidateComplianceKey(c.GetHeader(COMPLIANCE_KEY_HEADER)); err != nil {
+		newResponse(c, http.StatusUnauthorized, err.Error())
+		return false
+	}
+	return true
+}
+
+func (h *Handler) getRetentionPolicies(c *gin.Context) {
+	if !h.checkComplianceKey(c) {
+		return
+	}
+
+	policies, err := h.services.Retention.GetRetentionPolicies(c.Request.Context())
+	if err != nil {
+		h.retentionErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"policies": policies})
+}
+
+func (h *Handler) setRetentionPolicy(c *gin.Context) {
+	if !h.checkComplianceKey(c) {
+		return
+	}
+
+	var request model.RetentionPolicyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if !h.bindRetentionTarget(c, &request) {
+		return
+	}
+
+	policy, err := h.services.Retention.SetRetentionPolicy(c.Request.Context(), request)
+	if err != nil {
+		h.retentionErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+func (h *Handler) deleteRetentionPolicy(c *gin.Context) {
+	if !h.checkComplianceKey(c) {
+		return
+	}
+
+	var request model.RetentionPolicyRequest
+	if !h.bindRetentionTarget(c, &request) {
+		return
+	}
+
+	if err := h.services.Retention.DeleteRetentionPolicy(c.Request.Context(), request); err != nil {
+		h.retentionErrorResponse(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// bindRetentionTarget takes the chat type or the chat of the policy from the path
+func (h *Handler) bindRetentionTarget(c *gin.Context, request *model.RetentionPolicyRequest) bool {
+	if chatID := c.Param("chat_id"); chatID != "" {
+		var err error
+		if request.ChatID, err = strconv.ParseInt(chatID, 10, 64); err != nil || request.ChatID <= 0 {
+			newResponse(c, http.StatusBadRequest, "invalid chat_id")
+			return false
+		}
+		return true
+	}
+
+	request.ChatType = c.Param("chat_type")
+	return true
+}
+
+func (h *Handler) getRetentionRuns(c *gin.Context) {
+	if !h.checkComplianceKey(c) {
+		return
+	}
+
+	runs, err := h.services.Retention.GetRetentionRuns(c.Request.Context())
+	if err != nil {
+		h.retentionErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"runs": runs})
+}
+
+func (h *Handler) getRetentionRun(c *gin.Context) {
+	if !h.checkComplianceKey(c) {
+		return
+	}
+
+	runID, err := strconv.ParseInt(c.Param("run_id"), 10, 64)
+	if err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid run_id")
+		return
+	}
+
+	run, err := h.services.Retention.GetRetentionRun(c.Request.Context(), runID)
+	if err != nil {
+		h.retentionErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
+
+// getRetentionPurges lists what the run deleted and under which policy, paging by the "after" purge_id
+func (h *Handler) getRetentionPurges(c *gin.Context) {
+	if !h.checkComplianceKey(c) {
+		return
+	}
+
+	var (
+		request model.RetentionPurgesRequest
+		err     error
+	)
+	if request.RunID, err = strconv.ParseInt(c.Param("run_id"), 10, 64); err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid run_id")
+		return
+	}
+	if request.AfterPurgeID, err = strconv.ParseInt(c.DefaultQuery("after", "0"), 10, 64); err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid after")
+		return
+	}
+	if request.Limit, err = strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(model.RETENTION_PURGES_LIMIT))); err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid limit")
+		return
+	}
+
+	purges, err := h.services.Retention.GetRetentionPurges(c.Request.Context(), request)
+	if err != nil {
+		h.retentionErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"purges": purges})
+}
+
+func (h *Handler) getLegalHolds(c *gin.Context) {
+	if !h.checkComplianceKey(c) {
+		return
+	}
+
+	holds, err := h.services.Retention.GetLegalHolds(c.Request.Context())
+	if err != nil {
+		h.retentionErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"legal_holds": holds})
+}
+
+func (h *Handler) setLegalHold(c *gin.Context) {
+	if !h.checkComplianceKey(c) {
+		return
+	}
+
+	var hold model.LegalHold
+	if err := c.ShouldBindJSON(&hold); err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	hold, err := h.services.Retention.SetLegalHold(c.Request.Context(), hold)
+	if err != nil {
+		h.retentionErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, hold)
+}
+
+func (h *Handler) releaseLegalHold(c *gin.Context) {
+	if !h.checkComplianceKey(c) {
+		return
+	}
+
+	holdID, err := strconv.ParseInt(c.Param("hold_id"), 10, 64)
+	if err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid hold_id")
+		return
+	}
+
+	hold, err := h.services.Retention.ReleaseLegalHold(c.Request.Context(), holdID)
+	if err != nil {
+		h.retentionErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, hold)
+}
+
+func (h *Handler) retentionErrorResponse(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, model.ErrInvalidRetention), errors.Is(err, model.ErrInvalidLegalHold):
+		newResponse(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, model.ErrChatNotFound), errors.Is(err, model.ErrRetentionNotFound),
+		errors.Is(err, model.ErrRetentionRunNotFound), errors.Is(err, model.ErrLegalHoldNotFound):
+		newResponse(c, http.StatusNotFound, err.Error())
+	default:
+		logger.Error("Failed to handle retention", zap.Error(err))
+		newResponse(c, http.StatusInternalServerError, "failed to handle retention")
+	}
+}
+
+// RunRetention purges the messages past their retention policies every night at the hour in UTC,
+// the replica finding another run in progress skips the night
+func (h *Handler) RunRetention(ctx context.Context, hour int) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(model.NextRetentionRun(time.Now(), hour))):
+		}
+
+		run, err := h.services.Retention.RunRetention(ctx, h.reportRetentionProgress)
+		switch {
+		case errors.Is(err, model.ErrRetentionRunning):
+			logger.Info("Retention run is in progress on another replica")
+		case err != nil:
+			logger.Error("Failed to run retention", zap.Int64("runID", run.RunID), zap.Error(err))
+		default:
+			logger.Info("Retention run finished",
+				zap.Int64("runID", run.RunID),
+				zap.Int("chatsProcessed", run.ChatsProcessed),
+				zap.Int("messagesPurged", run.MessagesPurged),
+			)
+		}
+	}
+}
+
+// reportRetentionProgress removes the purged messages from the open chats like the disappearing ones
+func (h *Handler) reportRetentionProgress(ctx context.Context, run model.RetentionRun, deleted []model.DeletedMessage) {
+	logger.Info("Retention batch purged",
+		zap.Int64("runID", run.RunID),
+		zap.Int64("chatID", deleted[0].ChatID),
+		zap.Int("batch", len(deleted)),
+		zap.Int("chatsProcessed", run.ChatsProcessed),
+		zap.Int("messagesPurged", run.MessagesPurged),
+	)
+
+	h.broadcastDeletedMessages(ctx, deleted)
+}
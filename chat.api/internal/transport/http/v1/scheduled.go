@@ -0,0 +1,263 @@
+package v1
+
+import (
+	"chat-api/internal/model"
+	"chat-api/pkg/logger"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func (h *Handler) scheduleMessage(c *gin.Context) {
+	request, ok := h.bindScheduledMessage(c)
+	if !ok {
+		return
+	}
+
+	scheduled, err := h.services.Scheduled.ScheduleMessage(c.Request.Context(), request)
+	if err != nil {
+		h.scheduledErrorResponse(c, err, "failed to schedule message")
+		return
+	}
+
+	h.decryptMessage(c.Request.Context(), scheduled.ChatID, &scheduled.Message)
+	c.JSON(http.StatusCreated, gin.H{"scheduled_message": scheduled})
+}
+
+func (h *Handler) getScheduledMessages(c *gin.Context) {
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	chatID, err := strconv.ParseInt(c.Param("chat_id"), 10, 64)
+	if err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid chat_id")
+		return
+	}
+
+	scheduled, err := h.services.Scheduled.GetScheduledMessages(c.Request.Context(), chatID, userID)
+	if err != nil {
+		logger.Error("Failed to get scheduled messages", zap.Error(err))
+		newResponse(c, http.StatusInternalServerError, "failed to get scheduled messages")
+		return
+	}
+
+	for i := range scheduled {
+		h.decryptMessage(c.Request.Context(), scheduled[i].ChatID, &scheduled[i].Message)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"scheduled_messages": scheduled})
+}
+
+func (h *Handler) editScheduledMessage(c *gin.Context) {
+	request, ok := h.bindScheduledMessage(c)
+	if !ok {
+		return
+	}
+
+	scheduledMessageID, err := strconv.ParseInt(c.Param("scheduled_id"), 10, 64)
+	if err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid scheduled_id")
+		return
+	}
+	request.ScheduledMessageID = scheduledMessageID
+
+	if err := h.services.Scheduled.EditScheduledMessage(c.Request.Context(), request); err != nil {
+		h.scheduledErrorResponse(c, err, "failed to edit scheduled message")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "successfully edited scheduled message"})
+}
+
+func (h *Handler) cancelScheduledMessage(c *gin.Context) {
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	chatID, err := strconv.ParseInt(c.Param("chat_id"), 10, 64)
+	if err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid chat_id")
+		return
+	}
+
+	scheduledMessageID, err := strconv.ParseInt(c.Param("scheduled_id"), 10, 64)
+	if err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid scheduled_id")
+		return
+	}
+
+	if err := h.services.Scheduled.CancelScheduledMessage(c.Request.Context(), scheduledMessageID, chatID, userID); err != nil {
+		h.scheduledErrorResponse(c, err, "failed to cancel scheduled message")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "successfully canceled scheduled message"})
+}
+
+// bindScheduledMessage reads the request body and encrypts the content the same way sendMessage does,
+// the id of the message is reserved once it's sent so it takes its place in the chat at that time
+func (h *Handler) bindScheduledMessage(c *gin.Context) (model.ScheduledMessageRequest, bool) {
+	var request model.ScheduledMessageRequest
+
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return request, false
+	}
+
+	chatID, err := strconv.ParseInt(c.Param("chat_id"), 10, 64)
+	if err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid chat_id")
+		return request, false
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Error("Failed to bind request", zap.Error(err))
+		newResponse(c, http.StatusBadRequest, "Invalid request body")
+		return request, false
+	}
+
+	request.ChatID = chatID
+	request.UserID = userID
+
+	if err := formatMessage(&request.Message); err != nil {
+		newResponse(c, http.StatusBadRequest, err.Error())
+		return request, false
+	}
+
+	request.Message.MessageDB.MessageID = model.SCHEDULED_MESSAGE_CONTENT_ID
+	if request.Message.Poll != nil {
+		request.Message.Poll.MessageID = model.SCHEDULED_MESSAGE_CONTENT_ID
+	}
+
+	if err := h.encryptMessage(c.Request.Context(), chatID, &request.Message); err != nil {
+		logger.Error("Failed to encrypted message", zap.Error(err))
+		newResponse(c, http.StatusInternalServerError, "failed to encrypt message")
+		return request, false
+	}
+
+	if err := h.encryptPoll(c.Request.Context(), chatID, request.Message.MessageDB.MessageID, request.Message.Poll); err != nil {
+		if errors.Is(err, model.ErrInvalidPoll) {
+			newResponse(c, http.StatusBadRequest, err.Error())
+			return request, false
+		}
+		logger.Error("Failed to encrypted poll", zap.Error(err))
+		newResponse(c, http.StatusInternalServerError, "failed to encrypt message")
+		return request, false
+	}
+
+	return request, true
+}
+
+func (h *Handler) scheduledErrorResponse(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, model.ErrInvalidParamsOfMessage), errors.Is(err, model.ErrInvalidSendTime), errors.Is(err, model.ErrChatEncrypted),
+		errors.Is(err, model.ErrInvalidPoll), errors.Is(err, model.ErrPollNotAllowed), errors.Is(err, model.ErrInvalidMentions):
+		newResponse(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, model.ErrNotParticipant), errors.Is(err, model.ErrChatBlocked):
+		newResponse(c, http.StatusForbidden, err.Error())
+	case errors.Is(err, model.ErrScheduledNotFound):
+		newResponse(c, http.StatusNotFound, err.Error())
+	default:
+		logger.Error("Failed to process scheduled message", zap.Error(err))
+		newResponse(c, http.StatusInternalServerError, message)
+	}
+}
+
+// RunScheduler sends due scheduled messages through the usual sending flow until ctx is done.
+// Pending messages live in postgres, so the ones due during a downtime are sent after restart.
+func (h *Handler) RunScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// a full batch means there may be more due messages
+		for {
+			processed, err := h.services.Scheduled.SendDueScheduledMessages(ctx, h.sendScheduledMessage)
+			if err != nil {
+				logger.Error("Failed to send scheduled messages", zap.Error(err))
+				break
+			}
+			if processed < model.SCHEDULED_MESSAGE_BATCH_SIZE {
+				break
+			}
+		}
+	}
+}
+
+// sendScheduledMessage reserves the id of the due message and binds the content to it,
+// the content kept under the scheduled message is decrypted by the id it was bound to
+func (h *Handler) sendScheduledMessage(ctx context.Context, request *model.CreateMessageRequest) error {
+	message := &request.MessageWithData
+	boundID := message.MessageDB.MessageID
+
+	decrypt := func(ciphertext string) (string, error) {
+		return h.services.MessageEncrypter.Decrypt(ctx, request.ChatID, boundID, ciphertext)
+	}
+
+	if content := message.MessageDB.Content; content != nil {
+		plain, err := decrypt(*content)
+		if err != nil {
+			return err
+		}
+		message.MessageDB.Content = &plain
+	}
+
+	if encrypted := message.MessageDB.EncryptedEntities; encrypted != nil {
+		plain, err := decrypt(*encrypted)
+		if err != nil {
+			return err
+		}
+		entities, err := model.UnmarshalEntities(plain)
+		if err != nil {
+			return err
+		}
+		message.Entities = entities
+		message.MessageDB.EncryptedEntities = nil
+	}
+
+	if message.Poll != nil {
+		poll := *message.Poll
+		question, err := decrypt(poll.Question)
+		if err != nil {
+			return err
+		}
+		poll.Question = question
+
+		poll.Options = make([]model.PollOption, len(message.Poll.Options))
+		for i, option := range message.Poll.Options {
+			text, err := decrypt(option.Text)
+			if err != nil {
+				return err
+			}
+			option.Text = text
+			poll.Options[i] = option
+		}
+		message.Poll = &poll
+	}
+
+	if err := h.reserveMessageID(ctx, message); err != nil {
+		return err
+	}
+	if err := h.encryptMessage(ctx, request.ChatID, message); err != nil {
+		return err
+	}
+	if err := h.encryptPoll(ctx, request.ChatID, message.MessageDB.MessageID, message.Poll); err != nil {
+		return err
+	}
+
+	return h.dispatchMessage(ctx, request)
+}
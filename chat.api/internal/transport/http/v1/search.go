@@ -0,0 +1,99 @@
+package v1
+
+import (
+	"chat-api/internal/model"
+	"chat-api/pkg/logger"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func (h *Handler) initSearchRoutes(router *gin.RouterGroup) {
+	router.GET("/search", h.search)
+}
+
+// search looks for the words of "q" in "chat_id" or in all chats of the user, optionally sent "from" the user,
+// "since" and "until" the RFC 3339 times, with the attachment "type", paging back by the "before" message_id
+func (h *Handler) search(c *gin.Context) {
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	request := model.SearchRequest{
+		UserID:   userID,
+		Query:    c.Query("q"),
+		SenderID: c.Query("from"),
+		Type:     c.Query("type"),
+	}
+
+	var err error
+	if request.ChatID, err = strconv.ParseInt(c.DefaultQuery("chat_id", "0"), 10, 64); err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid chat_id")
+		return
+	}
+	if request.Since, err = parseSearchTime(c.Query("since")); err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid since")
+		return
+	}
+	if request.Until, err = parseSearchTime(c.Query("until")); err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid until")
+		return
+	}
+
+	if request.BeforeMessageID, err = strconv.ParseInt(c.DefaultQuery("before", "0"), 10, 64); err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid before")
+		return
+	}
+	if request.Limit, err = strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(model.SEARCH_LIMIT_REQUEST))); err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid limit")
+		return
+	}
+
+	results, err := h.services.Search.Search(c.Request.Context(), request)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrInvalidSearch), errors.Is(err, model.ErrChatEncrypted):
+			newResponse(c, http.StatusBadRequest, err.Error())
+		case errors.Is(err, model.ErrNotParticipant):
+			newResponse(c, http.StatusForbidden, err.Error())
+		default:
+			logger.Error("Failed to search messages", zap.Error(err))
+			newResponse(c, http.StatusInternalServerError, "failed to search messages")
+		}
+		return
+	}
+
+	for i := range results {
+		h.decryptMessage(c.Request.Context(), results[i].ChatID, &results[i].Message.MessageWithData)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
+
+// indexMessage puts the plain content of the stored message into the search, the message is still delivered if it fails
+func (h *Handler) indexMessage(ctx context.Context, chatID, messageID int64, content *string) {
+	if err := h.services.Search.IndexMessage(ctx, chatID, messageID, content); err != nil {
+		logger.Error("Failed to index message", zap.Int64("messageID", messageID), zap.Error(err))
+	}
+}
+
+func parseSearchTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+
+	// created_at is stored in UTC without the zone
+	parsed = parsed.UTC()
+	return &parsed, nil
+}
@@ -0,0 +1,268 @@
+package v1
+
+import (
+	"chat-api/internal/model"
+	"chat-api/pkg/logger"
+	"chat-api/pkg/storage"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func (h *Handler) initUploadRoutes(router *gin.RouterGroup) {
+	uploads := router.Group("/uploads")
+	{
+		uploads.POST("", h.upload)
+		uploads.POST("/resumable", h.createUpload)
+		uploads.HEAD("/resumable/:upload_id", h.getUploadOffset)
+		uploads.PATCH("/resumable/:upload_id", h.appendUpload)
+		uploads.DELETE("/resumable/:upload_id", h.cancelUpload)
+	}
+
+	// the link is signed, so it works without the access token (e.g. in <img src>)
+	router.GET("/downloads/:kind/:attachment_id", h.download)
+}
+
+// upload stores the multipart field "file" as the attachment of the "kind" form field
+func (h *Handler) upload(c *gin.Context) {
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	// the type limit is checked by the service, the body is cut before it's spooled to the disk
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, model.UPLOAD_SIZE_LIMIT+model.UPLOAD_FORM_OVERHEAD)
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			newResponse(c, http.StatusRequestEntityTooLarge, model.ErrUploadTooLarge.Error())
+			return
+		}
+		newResponse(c, http.StatusBadRequest, "file is missing")
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		logger.Error("Failed to open uploaded file", zap.Error(err))
+		newResponse(c, http.StatusInternalServerError, "failed to upload file")
+		return
+	}
+	defer file.Close()
+
+	result, err := h.services.Uploads.Upload(c.Request.Context(), userID, c.PostForm("kind"), file)
+	if err != nil {
+		h.uploadErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, result)
+}
+
+func (h *Handler) createUpload(c *gin.Context) {
+	var request model.CreateUploadRequest
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Error("Failed to bind request", zap.Error(err))
+		newResponse(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if length := c.GetHeader("Upload-Length"); length != "" {
+		var err error
+		if request.Length, err = strconv.ParseInt(length, 10, 64); err != nil {
+			newResponse(c, http.StatusBadRequest, "invalid Upload-Length")
+			return
+		}
+	}
+
+	upload, err := h.services.Uploads.CreateUpload(c.Request.Context(), userID, request)
+	if err != nil {
+		h.uploadErrorResponse(c, err)
+		return
+	}
+
+	setUploadHeaders(c, upload)
+	c.Header("Location", c.Request.URL.Path+"/"+upload.UploadID)
+	c.JSON(http.StatusCreated, upload)
+}
+
+func (h *Handler) getUploadOffset(c *gin.Context) {
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	upload, err := h.services.Uploads.GetUpload(c.Request.Context(), c.Param("upload_id"), userID)
+	if err != nil {
+		h.uploadErrorResponse(c, err)
+		return
+	}
+
+	setUploadHeaders(c, upload)
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+}
+
+// appendUpload takes the next chunk in the tus way: the body starts at Upload-Offset,
+// the response to the last chunk carries the stored attachment.
+func (h *Handler) appendUpload(c *gin.Context) {
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	if c.ContentType() != "application/offset+octet-stream" {
+		newResponse(c, http.StatusUnsupportedMediaType, "content type must be application/offset+octet-stream")
+		return
+	}
+
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		newResponse(c, http.StatusBadRequest, "invalid Upload-Offset")
+		return
+	}
+
+	upload, result, err := h.services.Uploads.AppendUpload(c.Request.Context(), c.Param("upload_id"), userID, offset, c.Request.Body)
+	if err != nil {
+		if errors.Is(err, model.ErrUploadOffsetMismatch) {
+			setUploadHeaders(c, upload)
+		}
+		h.uploadErrorResponse(c, err)
+		return
+	}
+
+	setUploadHeaders(c, upload)
+	if result == nil {
+		c.Status(http.StatusNoContent)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *Handler) cancelUpload(c *gin.Context) {
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	if err := h.services.Uploads.CancelUpload(c.Request.Context(), c.Param("upload_id"), userID); err != nil {
+		h.uploadErrorResponse(c, err)
+		return
+	}
+
+	c.Header("Tus-Resumable", model.TUS_RESUMABLE_VERSION)
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) getDownloadURL(c *gin.Context) {
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	chatID, err := strconv.ParseInt(c.Param("chat_id"), 10, 64)
+	if err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid chat_id")
+		return
+	}
+
+	attachmentID, err := strconv.ParseInt(c.Param("attachment_id"), 10, 64)
+	if err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid attachment_id")
+		return
+	}
+
+	kind := c.Param("kind")
+	query, expiresAt, err := h.services.Uploads.SignDownload(c.Request.Context(), userID, chatID, kind, attachmentID)
+	if err != nil {
+		h.uploadErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, model.DownloadURL{
+		URL:       "/api/v1/downloads/" + kind + "/" + strconv.FormatInt(attachmentID, 10) + "?" + query.Encode(),
+		ExpiresAt: expiresAt,
+	})
+}
+
+func (h *Handler) download(c *gin.Context) {
+	attachmentID, err := strconv.ParseInt(c.Param("attachment_id"), 10, 64)
+	if err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid attachment_id")
+		return
+	}
+
+	content, blob, err := h.services.Uploads.OpenDownload(c.Request.Context(), c.Param("kind"), attachmentID, c.Request.URL.Query())
+	if err != nil {
+		h.uploadErrorResponse(c, err)
+		return
+	}
+	defer content.Close()
+
+	c.Header("Cache-Control", "private, max-age=300")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.DataFromReader(http.StatusOK, blob.Size, blob.Type, content, nil)
+}
+
+func (h *Handler) uploadErrorResponse(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, model.ErrInvalidUploadKind), errors.Is(err, model.ErrUnsupportedUploadType):
+		newResponse(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, model.ErrUploadTooLarge), errors.Is(err, model.ErrVoiceTooLong):
+		newResponse(c, http.StatusRequestEntityTooLarge, err.Error())
+	case errors.Is(err, model.ErrUploadOffsetMismatch), errors.Is(err, model.ErrMediaProcessing):
+		newResponse(c, http.StatusConflict, err.Error())
+	case errors.Is(err, model.ErrNotParticipant), errors.Is(err, storage.ErrInvalidSignature), errors.Is(err, model.ErrMediaRejected):
+		newResponse(c, http.StatusForbidden, err.Error())
+	case errors.Is(err, model.ErrUploadNotFound), errors.Is(err, model.ErrAttachmentNotFound), errors.Is(err, storage.ErrObjectNotFound):
+		newResponse(c, http.StatusNotFound, err.Error())
+	default:
+		logger.Error("Failed to process upload", zap.Error(err))
+		newResponse(c, http.StatusInternalServerError, "failed to process upload")
+	}
+}
+
+func setUploadHeaders(c *gin.Context, upload model.Upload) {
+	c.Header("Tus-Resumable", model.TUS_RESUMABLE_VERSION)
+	c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	if !upload.ExpiresAt.IsZero() {
+		c.Header("Upload-Expires", upload.ExpiresAt.Format(http.TimeFormat))
+	}
+}
+
+// RunUploadsJanitor removes the resumable uploads abandoned before completion until ctx is done
+func (h *Handler) RunUploadsJanitor(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for {
+			deleted, err := h.services.Uploads.DeleteExpiredUploads(ctx)
+			if err != nil {
+				logger.Error("Failed to delete expired uploads", zap.Error(err))
+				break
+			}
+			if deleted < model.UPLOAD_BATCH_SIZE {
+				break
+			}
+		}
+	}
+}
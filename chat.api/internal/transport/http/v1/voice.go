@@ -0,0 +1,64 @@
+package v1
+
+import (
+	"chat-api/internal/model"
+	"chat-api/pkg/logger"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// listenVoice marks the voice message as played, the sender gets the receipt like a read receipt
+func (h *Handler) listenVoice(request model.VoiceListenRequest) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	messageDB, listen, err := h.services.Voice.ListenVoice(ctx, request)
+	if err != nil {
+		logger.Error("Failed to mark voice message as listened", zap.Error(err))
+		return
+	}
+	if listen == nil {
+		return
+	}
+
+	event := model.VoiceListenedEvent{
+		Type:        WEBSOCKET_TYPE_VOICE_LISTENED,
+		ChatID:      request.ChatID,
+		VoiceListen: *listen,
+	}
+	if err := h.writeToUser(ctx, messageDB.SenderID, event); err != nil && !errors.Is(err, model.ErrWebSocketNotFound) {
+		logger.Warn("Failed to send WebSocket message", zap.String("userID", messageDB.SenderID), zap.Error(err))
+	}
+}
+
+func (h *Handler) getUnlistenedVoiceMessages(c *gin.Context) {
+	userID := h.extractUserIDFromToken(c)
+	if userID == "" {
+		return
+	}
+
+	chatID, err := strconv.ParseInt(c.Param("chat_id"), 10, 64)
+	if err != nil {
+		newResponse(c, http.StatusBadRequest, "invalid chat_id")
+		return
+	}
+
+	messageIDs, err := h.services.Voice.GetUnlistenedVoiceMessages(c.Request.Context(), chatID, userID)
+	if err != nil {
+		if errors.Is(err, model.ErrNotParticipant) {
+			newResponse(c, http.StatusForbidden, err.Error())
+			return
+		}
+		logger.Error("Failed to get unlistened voice messages", zap.Error(err))
+		newResponse(c, http.StatusInternalServerError, "failed to get unlistened voice messages")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message_ids": messageIDs})
+}
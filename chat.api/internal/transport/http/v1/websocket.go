@@ -3,20 +3,55 @@ package v1
 import (
 	"chat-api/internal/model"
 	"chat-api/pkg/logger"
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
 	"go.uber.org/zap"
 )
 
 const (
-	WEBSOCKET_TYPE_CREATE_PRIVATE_CHAT = "create private chat"
-	WEBSOCKET_TYPE_CREATE_GROUP_CHAT   = "create group chat"
-	WEBSOCKET_TYPE_SEND_MESSAGE        = "send message"
+	WEBSOCKET_TYPE_CREATE_PRIVATE_CHAT    = "create private chat"
+	WEBSOCKET_TYPE_CREATE_GROUP_CHAT      = "create group chat"
+	WEBSOCKET_TYPE_SEND_MESSAGE           = "send message"
+	WEBSOCKET_TYPE_EDIT_MESSAGE           = "edit message"
+	WEBSOCKET_TYPE_FORWARD_MESSAGES       = "forward messages"
+	WEBSOCKET_TYPE_SEND_ENCRYPTED_MESSAGE = "send encrypted message"
+	WEBSOCKET_TYPE_ADD_REACTION           = "add reaction"
+	WEBSOCKET_TYPE_REMOVE_REACTION        = "remove reaction"
+	WEBSOCKET_TYPE_LISTEN_VOICE           = "listen voice"
+	WEBSOCKET_TYPE_VOTE_POLL              = "vote poll"
+	WEBSOCKET_TYPE_RETRACT_POLL_VOTE      = "retract poll vote"
+	WEBSOCKET_TYPE_UPDATE_PRESENCE        = "update presence"
+	WEBSOCKET_TYPE_SUBSCRIBE_PRESENCE     = "subscribe presence"
+	WEBSOCKET_TYPE_SEND_CHAT_ACTIVITY     = "send chat activity"
+	WEBSOCKET_TYPE_VIEW_POSTS             = "view posts"
 
 	WEBSOCKET_TYPE_MESSAGE_ACTION = "action on message"
 	WEBSOCKET_TYPE_CHAT_ACTION    = "action on chat"
+
+	// events pushed by the server
+	WEBSOCKET_TYPE_DELETE_MESSAGES = "delete messages"
+	WEBSOCKET_TYPE_MEDIA_PROCESSED = "media processed"
+	WEBSOCKET_TYPE_VOICE_LISTENED  = "voice listened"
+	WEBSOCKET_TYPE_POLL_UPDATED    = "poll updated"
+	WEBSOCKET_TYPE_POLL_CLOSED     = "poll closed"
+	WEBSOCKET_TYPE_MESSAGE_EDITED  = "message edited"
+	WEBSOCKET_TYPE_MESSAGE_UPDATED = "message updated"
+	WEBSOCKET_TYPE_PRESENCE        = "presence"
+	WEBSOCKET_TYPE_CHAT_ACTIVITY   = "chat activity"
+	WEBSOCKET_TYPE_CHAT_MEMBER     = "chat member"
+	WEBSOCKET_TYPE_JOIN_REQUEST    = "join request"
+	WEBSOCKET_TYPE_CHAT_UPDATED    = "chat updated"
+	WEBSOCKET_TYPE_CHAT_ROLE       = "chat role"
+	WEBSOCKET_TYPE_MESSAGE_PINNED  = "message pinned"
+	WEBSOCKET_TYPE_POST_VIEWS      = "post views"
+	WEBSOCKET_TYPE_EXPORT_FINISHED = "export finished"
+	WEBSOCKET_TYPE_PREKEYS_LOW     = "prekeys low"
 )
 
 type WSMessage struct {
@@ -57,6 +92,19 @@ func (h *Handler) InitializeWebSocket(c *gin.Context) {
 		return
 	}
 
+	if err := h.services.Presence.Connect(c.Request.Context(), userID); err != nil {
+		logger.Warn("Failed to set presence", zap.Error(err))
+	}
+	// clients ping the socket while idle to stay online
+	ws.SetPingHandler(func(appData string) error {
+		h.refreshPresence(c.Request.Context(), userID)
+		err := ws.WriteControl(websocket.PongMessage, []byte(appData), time.Now().Add(time.Second))
+		if errors.Is(err, websocket.ErrCloseSent) {
+			return nil
+		}
+		return err
+	})
+
 	for {
 		_, msg, err := ws.ReadMessage()
 		if err != nil {
@@ -64,8 +112,12 @@ func (h *Handler) InitializeWebSocket(c *gin.Context) {
 			if authErr := h.services.Auth.DeleteWebSocket(c.Request.Context(), userID); authErr != nil {
 				logger.Warn("Failed to remove websocket connection", zap.Error(authErr))
 			}
+			if presenceErr := h.services.Presence.Disconnect(c.Request.Context(), userID); presenceErr != nil {
+				logger.Warn("Failed to remove presence", zap.Error(presenceErr))
+			}
 			break
 		}
+		h.refreshPresence(c.Request.Context(), userID)
 
 		var wsMsg WSMessage
 		if err := json.Unmarshal(msg, &wsMsg); err != nil {
@@ -80,13 +132,40 @@ func (h *Handler) InitializeWebSocket(c *gin.Context) {
 				logger.Warn("Failed to parse JSON", zap.Error(err))
 				continue
 			}
+			request.MessageWithData.MessageDB.SenderID = userID
 			h.sendMessage(ws, request)
+		case WEBSOCKET_TYPE_EDIT_MESSAGE:
+			var request model.EditMessageRequest
+			if err := json.Unmarshal(msg, &request); err != nil {
+				logger.Warn("Failed to parse JSON", zap.Error(err))
+				continue
+			}
+			request.UserID = userID
+			h.editMessage(request)
+		case WEBSOCKET_TYPE_SEND_ENCRYPTED_MESSAGE:
+			var request model.EncryptedMessageRequest
+			if err := json.Unmarshal(msg, &request); err != nil {
+				logger.Warn("Failed to parse JSON", zap.Error(err))
+				continue
+			}
+			request.Message.SenderID = userID
+			h.sendEncryptedMessage(request)
+		case WEBSOCKET_TYPE_FORWARD_MESSAGES:
+			var request model.ForwardMessagesRequest
+			if err := json.Unmarshal(msg, &request); err != nil {
+				logger.Warn("Failed to parse JSON", zap.Error(err))
+				continue
+			}
+			request.UserID = userID
+			h.forwardMessages(request)
 		case WEBSOCKET_TYPE_CREATE_PRIVATE_CHAT:
 			var request model.CreatePrivateChatRequest
 			if err := json.Unmarshal(msg, &request); err != nil {
 				logger.Warn("Failed to parse JSON", zap.Error(err))
 				continue
 			}
+			request.Chat.CreatorID = userID
+			request.InitialMessage.MessageWithData.MessageDB.SenderID = userID
 			h.createPrivateChat(ws, request)
 		case WEBSOCKET_TYPE_CREATE_GROUP_CHAT:
 			var request model.CreateGroupChatRequest
@@ -94,7 +173,73 @@ func (h *Handler) InitializeWebSocket(c *gin.Context) {
 				logger.Warn("Failed to parse JSON", zap.Error(err))
 				continue
 			}
+			request.Chat.CreatorID = userID
+			request.ChatAction.UserID = userID
 			h.createGroupChat(ws, request)
+		case WEBSOCKET_TYPE_ADD_REACTION, WEBSOCKET_TYPE_REMOVE_REACTION:
+			var request model.ReactionRequest
+			if err := json.Unmarshal(msg, &request); err != nil {
+				logger.Warn("Failed to parse JSON", zap.Error(err))
+				continue
+			}
+			request.UserID = userID
+			if wsMsg.Type == WEBSOCKET_TYPE_ADD_REACTION {
+				h.addReaction(request)
+			} else {
+				h.removeReaction(request)
+			}
+		case WEBSOCKET_TYPE_LISTEN_VOICE:
+			var request model.VoiceListenRequest
+			if err := json.Unmarshal(msg, &request); err != nil {
+				logger.Warn("Failed to parse JSON", zap.Error(err))
+				continue
+			}
+			request.UserID = userID
+			h.listenVoice(request)
+		case WEBSOCKET_TYPE_VOTE_POLL, WEBSOCKET_TYPE_RETRACT_POLL_VOTE:
+			var request model.PollVoteRequest
+			if err := json.Unmarshal(msg, &request); err != nil {
+				logger.Warn("Failed to parse JSON", zap.Error(err))
+				continue
+			}
+			request.UserID = userID
+			if wsMsg.Type == WEBSOCKET_TYPE_VOTE_POLL {
+				h.votePoll(request)
+			} else {
+				h.retractPollVote(request)
+			}
+		case WEBSOCKET_TYPE_UPDATE_PRESENCE:
+			var request model.PresenceUpdateRequest
+			if err := json.Unmarshal(msg, &request); err != nil {
+				logger.Warn("Failed to parse JSON", zap.Error(err))
+				continue
+			}
+			request.UserID = userID
+			h.updatePresence(request)
+		case WEBSOCKET_TYPE_SUBSCRIBE_PRESENCE:
+			var request model.PresenceSubscribeRequest
+			if err := json.Unmarshal(msg, &request); err != nil {
+				logger.Warn("Failed to parse JSON", zap.Error(err))
+				continue
+			}
+			request.UserID = userID
+			h.subscribePresence(ws, request)
+		case WEBSOCKET_TYPE_SEND_CHAT_ACTIVITY:
+			var request model.ChatActivityRequest
+			if err := json.Unmarshal(msg, &request); err != nil {
+				logger.Warn("Failed to parse JSON", zap.Error(err))
+				continue
+			}
+			request.UserID = userID
+			h.sendChatActivity(request)
+		case WEBSOCKET_TYPE_VIEW_POSTS:
+			var request model.ViewPostsRequest
+			if err := json.Unmarshal(msg, &request); err != nil {
+				logger.Warn("Failed to parse JSON", zap.Error(err))
+				continue
+			}
+			request.UserID = userID
+			h.viewPosts(request)
 		// case WEBSOCKET_TYPE_MESSAGE_ACTION:
 		// 	var
 		// case WEBSOCKET_TYPE_CHAT_ACTION:
@@ -104,6 +249,21 @@ func (h *Handler) InitializeWebSocket(c *gin.Context) {
 	}
 }
 
+// writeToUser sends the payload over the user's WebSocket connection,
+// model.ErrWebSocketNotFound means the user is offline.
+func (h *Handler) writeToUser(ctx context.Context, userID string, payload any) error {
+	webSocketConnection, err := h.services.Auth.GetWebSocket(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if webSocketConnection == nil || webSocketConnection.Conn == nil {
+		return model.ErrWebSocketNotFound
+	}
+
+	return webSocketConnection.Conn.WriteJSON(payload)
+}
+
 func (h *Handler) extractUserIDFromToken(c *gin.Context) string {
 	token := c.Query("token")
 
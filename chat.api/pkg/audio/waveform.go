@@ -0,0 +1,138 @@
+package audio
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+const (
+	sampleRate = 8000 // enough for the loudness of speech
+	blockSize  = 80   // samples per 10ms
+	maxLevel   = 31   // waveform values fit into 5 bits
+)
+
+var ErrNoAudio = errors.New("no audio samples")
+
+type Info struct {
+	Duration float64 // seconds
+	Waveform []byte  // loudness of equal slices of the audio, 0..31
+}
+
+// Analyzer reads audio with the ffmpeg binary, a run taking longer than timeout is killed
+type Analyzer struct {
+	ffmpegPath string
+	timeout    time.Duration
+}
+
+func NewAnalyzer(ffmpegPath string, timeout time.Duration) *Analyzer {
+	return &Analyzer{
+		ffmpegPath: ffmpegPath,
+		timeout:    timeout,
+	}
+}
+
+// Analyze decodes at most maxDuration seconds (and a bit more to tell the longer audio)
+// into mono PCM. The duration is counted by the samples since the headers of recorded
+// voice are often missing or wrong.
+func (a *Analyzer) Analyze(ctx context.Context, path string, bars int, maxDuration float64) (Info, error) {
+	ctx, cancel := context.WithTimeout(ctx, a.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, a.ffmpegPath,
+		"-v", "error",
+		"-i", path,
+		"-t", strconv.FormatFloat(maxDuration+1, 'f', -1, 64),
+		"-vn",
+		"-ac", "1",
+		"-ar", strconv.Itoa(sampleRate),
+		"-f", "s16le",
+		"-",
+	)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return Info{}, err
+	}
+	if err := cmd.Start(); err != nil {
+		return Info{}, fmt.Errorf("ffmpeg: %w", err)
+	}
+
+	peaks, samples, readErr := readPeaks(bufio.NewReader(stdout))
+	if err := cmd.Wait(); err != nil {
+		return Info{}, fmt.Errorf("ffmpeg: %w", err)
+	}
+	if readErr != nil {
+		return Info{}, readErr
+	}
+	if samples == 0 {
+		return Info{}, ErrNoAudio
+	}
+
+	return Info{
+		Duration: float64(samples) / sampleRate,
+		Waveform: waveform(peaks, bars),
+	}, nil
+}
+
+// readPeaks returns the peak of every block of the samples
+func readPeaks(r io.Reader) ([]int, int, error) {
+	var peaks []int
+	var samples, peak int
+	buf := make([]byte, 2)
+
+	for {
+		if _, err := io.ReadFull(r, buf); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				break
+			}
+			return nil, 0, err
+		}
+
+		sample := int(int16(binary.LittleEndian.Uint16(buf)))
+		peak = max(peak, sample, -sample)
+		samples++
+
+		if samples%blockSize == 0 {
+			peaks = append(peaks, peak)
+			peak = 0
+		}
+	}
+	if samples%blockSize != 0 {
+		peaks = append(peaks, peak)
+	}
+
+	return peaks, samples, nil
+}
+
+// waveform averages the peaks into bars scaled to the loudest one
+func waveform(peaks []int, bars int) []byte {
+	bars = min(bars, len(peaks))
+	levels := make([]float64, bars)
+	var loudest float64
+
+	for i := range levels {
+		from, to := i*len(peaks)/bars, (i+1)*len(peaks)/bars
+		var sum int
+		for _, peak := range peaks[from:to] {
+			sum += peak
+		}
+		levels[i] = float64(sum) / float64(to-from)
+		loudest = max(loudest, levels[i])
+	}
+
+	result := make([]byte, bars)
+	if loudest == 0 {
+		return result
+	}
+	for i, level := range levels {
+		result[i] = byte(level / loudest * maxLevel)
+	}
+	return result
+}
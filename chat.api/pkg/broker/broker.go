@@ -10,28 +10,50 @@ import (
 const (
 	EXCHANGE_CHAT    = "chat_exchange"
 	EXCHANGE_MESSAGE = "message_exchange"
+	EXCHANGE_MEDIA   = "media_exchange"
 
-	QUEUE_CHAT_CREATED     = "chat_created"
-	QUEUE_CHAT_DELETED     = "chat_deleted"
-	QUEUE_CHAT_ADDED_USER  = "chat_added_user"
-	QUEUE_CHAT_LEFT_USER   = "chat_left_user"
-	QUEUE_CHAT_RENAME      = "chat_rename"
-	QUEUE_CHAT_KICKED_USER = "chat_kicked_user"
+	QUEUE_CHAT_CREATED      = "chat_created"
+	QUEUE_CHAT_DELETED      = "chat_deleted"
+	QUEUE_CHAT_ADDED_USER   = "chat_added_user"
+	QUEUE_CHAT_LEFT_USER    = "chat_left_user"
+	QUEUE_CHAT_RENAME       = "chat_rename"
+	QUEUE_CHAT_KICKED_USER  = "chat_kicked_user"
+	QUEUE_CHAT_JOIN_REQUEST = "chat_join_request"
+	QUEUE_CHAT_EXPORT       = "chat_export"
+	QUEUE_CHAT_EXPORT_READY = "chat_export_ready"
 
 	QUEUE_MESSAGE_SEND           = "message_send"
 	QUEUE_MESSAGE_SEND_ENCRYPTED = "message_send_encrypted"
+	QUEUE_MESSAGE_MENTION        = "message_mention"
+	QUEUE_MESSAGE_REACTION       = "message_reaction"
+	QUEUE_MESSAGE_DELETED        = "message_deleted"
+	QUEUE_CHANNEL_FANOUT         = "channel_fanout"
+
+	QUEUE_MEDIA_PROCESS = "media_process"
+	QUEUE_LINK_PREVIEW  = "link_preview"
 
 	// Routing Keys for chat events
-	ROUTING_KEY_CHAT_CREATED     = "chat.created"
-	ROUTING_KEY_CHAT_DELETED     = "chat.deleted"
-	ROUTING_KEY_CHAT_ADDED_USER  = "chat.user.added"
-	ROUTING_KEY_CHAT_LEFT_USER   = "chat.user.left"
-	ROUTING_KEY_CHAT_RENAME      = "chat.renamed"
-	ROUTING_KEY_CHAT_KICKED_USER = "chat.user.kicked"
+	ROUTING_KEY_CHAT_CREATED      = "chat.created"
+	ROUTING_KEY_CHAT_DELETED      = "chat.deleted"
+	ROUTING_KEY_CHAT_ADDED_USER   = "chat.user.added"
+	ROUTING_KEY_CHAT_LEFT_USER    = "chat.user.left"
+	ROUTING_KEY_CHAT_RENAME       = "chat.renamed"
+	ROUTING_KEY_CHAT_KICKED_USER  = "chat.user.kicked"
+	ROUTING_KEY_CHAT_JOIN_REQUEST = "chat.join.requested"
+	ROUTING_KEY_CHAT_EXPORT       = "chat.export"
+	ROUTING_KEY_CHAT_EXPORT_READY = "chat.export.ready"
 
 	// Routing Keys for message events
 	ROUTING_KEY_MESSAGE_SEND           = "message.send"
 	ROUTING_KEY_MESSAGE_SEND_ENCRYPTED = "message.send.encrypted"
+	ROUTING_KEY_MESSAGE_MENTION        = "message.mention"
+	ROUTING_KEY_MESSAGE_REACTION       = "message.reaction"
+	ROUTING_KEY_MESSAGE_DELETED        = "message.deleted"
+	ROUTING_KEY_CHANNEL_FANOUT         = "message.channel.fanout"
+
+	// Routing Keys for media jobs
+	ROUTING_KEY_MEDIA_PROCESS = "media.process"
+	ROUTING_KEY_LINK_PREVIEW  = "media.link_preview"
 )
 
 type RabbitMQConfig struct {
@@ -79,6 +101,11 @@ func (r *RabbitMQ) InitializationOfChannels() error {
 		logger.Errorf("Failed to initialize message channel: %s", err.Error())
 		return err
 	}
+
+	if err = r.initializationOfMediaChannel(); err != nil {
+		logger.Errorf("Failed to initialize media channel: %s", err.Error())
+		return err
+	}
 	return nil
 }
 
@@ -108,12 +135,15 @@ func (r *RabbitMQ) initializationOfChatChannel() error {
 
 	// Declare and bind queues with specific routing keys
 	queueBindings := map[string]string{
-		QUEUE_CHAT_CREATED:     ROUTING_KEY_CHAT_CREATED,
-		QUEUE_CHAT_DELETED:     ROUTING_KEY_CHAT_DELETED,
-		QUEUE_CHAT_ADDED_USER:  ROUTING_KEY_CHAT_ADDED_USER,
-		QUEUE_CHAT_LEFT_USER:   ROUTING_KEY_CHAT_LEFT_USER,
-		QUEUE_CHAT_RENAME:      ROUTING_KEY_CHAT_RENAME,
-		QUEUE_CHAT_KICKED_USER: ROUTING_KEY_CHAT_KICKED_USER,
+		QUEUE_CHAT_CREATED:      ROUTING_KEY_CHAT_CREATED,
+		QUEUE_CHAT_DELETED:      ROUTING_KEY_CHAT_DELETED,
+		QUEUE_CHAT_ADDED_USER:   ROUTING_KEY_CHAT_ADDED_USER,
+		QUEUE_CHAT_LEFT_USER:    ROUTING_KEY_CHAT_LEFT_USER,
+		QUEUE_CHAT_RENAME:       ROUTING_KEY_CHAT_RENAME,
+		QUEUE_CHAT_KICKED_USER:  ROUTING_KEY_CHAT_KICKED_USER,
+		QUEUE_CHAT_JOIN_REQUEST: ROUTING_KEY_CHAT_JOIN_REQUEST,
+		QUEUE_CHAT_EXPORT:       ROUTING_KEY_CHAT_EXPORT,
+		QUEUE_CHAT_EXPORT_READY: ROUTING_KEY_CHAT_EXPORT_READY,
 	}
 
 	for queueName, routingKey := range queueBindings {
@@ -174,6 +204,10 @@ func (r *RabbitMQ) initializationOfMessageChannel() error {
 	queueBindings := map[string]string{
 		QUEUE_MESSAGE_SEND:           ROUTING_KEY_MESSAGE_SEND,
 		QUEUE_MESSAGE_SEND_ENCRYPTED: ROUTING_KEY_MESSAGE_SEND_ENCRYPTED,
+		QUEUE_MESSAGE_MENTION:        ROUTING_KEY_MESSAGE_MENTION,
+		QUEUE_MESSAGE_REACTION:       ROUTING_KEY_MESSAGE_REACTION,
+		QUEUE_MESSAGE_DELETED:        ROUTING_KEY_MESSAGE_DELETED,
+		QUEUE_CHANNEL_FANOUT:         ROUTING_KEY_CHANNEL_FANOUT,
 	}
 
 	for queueName, routingKey := range queueBindings {
@@ -206,3 +240,64 @@ func (r *RabbitMQ) initializationOfMessageChannel() error {
 	r.Channels[EXCHANGE_MESSAGE] = ch2
 	return nil
 }
+
+func (r *RabbitMQ) initializationOfMediaChannel() error {
+	var ch3 *amqp.Channel
+	var err error
+
+	ch3, err = r.conn.Channel()
+	if err != nil {
+		logger.Errorf("Failed to open media channel: %s", err.Error())
+		return err
+	}
+
+	err = ch3.ExchangeDeclare(
+		EXCHANGE_MEDIA, // name
+		"topic",        // type
+		true,           // durable
+		false,          // auto-deleted
+		false,          // internal
+		false,          // no-wait
+		nil,            // arguments
+	)
+	if err != nil {
+		logger.Errorf("Failed to create %s: %s", EXCHANGE_MEDIA, err.Error())
+		return err
+	}
+
+	// Declare and bind queues with specific routing keys
+	queueBindings := map[string]string{
+		QUEUE_MEDIA_PROCESS: ROUTING_KEY_MEDIA_PROCESS,
+		QUEUE_LINK_PREVIEW:  ROUTING_KEY_LINK_PREVIEW,
+	}
+
+	for queueName, routingKey := range queueBindings {
+		q, err := ch3.QueueDeclare(
+			queueName,
+			true,  // durable
+			false, // delete when unused
+			false, // exclusive
+			false, // no-wait
+			nil,   // arguments
+		)
+		if err != nil {
+			logger.Errorf("Failed to declare queue %s: %s", queueName, err.Error())
+			return err
+		}
+
+		err = ch3.QueueBind(
+			q.Name,         // queue name
+			routingKey,     // routing key
+			EXCHANGE_MEDIA, // exchange
+			false,          // no-wait
+			nil,            // arguments
+		)
+		if err != nil {
+			logger.Errorf("Failed to bind queue %s to exchange with routing key %s: %s", queueName, routingKey, err.Error())
+			return err
+		}
+	}
+
+	r.Channels[EXCHANGE_MEDIA] = ch3
+	return nil
+}
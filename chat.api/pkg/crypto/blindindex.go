@@ -0,0 +1,78 @@
+package crypto
+
+import (
+	"chat-api/internal/model"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+	"unicode"
+)
+
+const (
+	blindTokenSize     = 16 // bytes of the HMAC kept in the token
+	searchWordMinRunes = 2
+	searchWordMaxRunes = 64
+)
+
+// BlindIndexer turns the words of the messages into tokens the database can match without knowing the words.
+// The tokens are keyed by the chat, so the same word gives different tokens in different chats.
+type BlindIndexer struct {
+	key []byte
+}
+
+func NewBlindIndexer(key string) (*BlindIndexer, error) {
+	if len(key) < 32 {
+		return nil, model.ErrInvalidSearchKey
+	}
+	return &BlindIndexer{key: []byte(key)}, nil
+}
+
+// Token returns the token of the normalized word in the chat
+func (b *BlindIndexer) Token(chatID int64, word string) string {
+	mac := hmac.New(sha256.New, b.key)
+
+	var chat [8]byte
+	binary.BigEndian.PutUint64(chat[:], uint64(chatID))
+	mac.Write(chat[:])
+	mac.Write([]byte(word))
+
+	return hex.EncodeToString(mac.Sum(nil)[:blindTokenSize])
+}
+
+// Tokens returns the tokens of the distinct words of the text, up to limit
+func (b *BlindIndexer) Tokens(chatID int64, text string, limit int) []string {
+	words := SearchWords(text)
+	if len(words) > limit {
+		words = words[:limit]
+	}
+
+	tokens := make([]string, len(words))
+	for i, word := range words {
+		tokens[i] = b.Token(chatID, word)
+	}
+	return tokens
+}
+
+// SearchWords splits the text into the distinct lowercase words, the too short and too long ones are skipped
+func SearchWords(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	words := make([]string, 0, len(fields))
+	seen := make(map[string]struct{}, len(fields))
+	for _, field := range fields {
+		runes := len([]rune(field))
+		if runes < searchWordMinRunes || runes > searchWordMaxRunes {
+			continue
+		}
+		if _, ok := seen[field]; ok {
+			continue
+		}
+		seen[field] = struct{}{}
+		words = append(words, field)
+	}
+	return words
+}
@@ -0,0 +1,108 @@
+package crypto
+
+import (
+	"chat-api/internal/model"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newTestBlindIndexer(t *testing.T, key string) *BlindIndexer {
+	t.Helper()
+
+	indexer, err := NewBlindIndexer(key)
+	if err != nil {
+		t.Fatalf("NewBlindIndexer: %v", err)
+	}
+	return indexer
+}
+
+func TestNewBlindIndexerShortKey(t *testing.T) {
+	if _, err := NewBlindIndexer(strings.Repeat("k", 31)); !errors.Is(err, model.ErrInvalidSearchKey) {
+		t.Errorf("NewBlindIndexer error = %v, want %v", err, model.ErrInvalidSearchKey)
+	}
+}
+
+func TestBlindIndexerToken(t *testing.T) {
+	indexer := newTestBlindIndexer(t, strings.Repeat("a", 32))
+
+	token := indexer.Token(1, "hello")
+	if len(token) != blindTokenSize*2 {
+		t.Errorf("token is %d characters, want %d", len(token), blindTokenSize*2)
+	}
+	if strings.Contains(token, "hello") {
+		t.Error("token contains the word")
+	}
+	if indexer.Token(1, "hello") != token {
+		t.Error("the same word in the same chat gives different tokens")
+	}
+
+	tests := []struct {
+		name   string
+		chatID int64
+		word   string
+		other  *BlindIndexer
+	}{
+		{name: "another chat", chatID: 2, word: "hello"},
+		{name: "negative chat", chatID: -1, word: "hello"},
+		{name: "another word", chatID: 1, word: "hellp"},
+		{name: "another key", chatID: 1, word: "hello", other: newTestBlindIndexer(t, strings.Repeat("b", 32))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			other := indexer
+			if tt.other != nil {
+				other = tt.other
+			}
+			if other.Token(tt.chatID, tt.word) == token {
+				t.Errorf("token of %q in chat %d matches the one of %q in chat 1", tt.word, tt.chatID, "hello")
+			}
+		})
+	}
+}
+
+func TestBlindIndexerTokens(t *testing.T) {
+	indexer := newTestBlindIndexer(t, strings.Repeat("a", 32))
+
+	tokens := indexer.Tokens(1, "Hello, hello world! Meet the world", 10)
+	want := []string{indexer.Token(1, "hello"), indexer.Token(1, "world"), indexer.Token(1, "meet"), indexer.Token(1, "the")}
+	if !reflect.DeepEqual(tokens, want) {
+		t.Errorf("Tokens = %v, want %v", tokens, want)
+	}
+
+	if tokens := indexer.Tokens(1, "one two three four", 2); len(tokens) != 2 {
+		t.Errorf("Tokens returned %d tokens over the limit of 2", len(tokens))
+	}
+
+	for i, token := range indexer.Tokens(2, "Hello, hello world! Meet the world", 10) {
+		if token == want[i] {
+			t.Errorf("token %d is the same in chats 1 and 2", i)
+		}
+	}
+}
+
+func TestSearchWords(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{name: "punctuation and case", text: "Hello, WORLD! hello-world", want: []string{"hello", "world"}},
+		{name: "short words skipped", text: "a I go to it", want: []string{"go", "to", "it"}},
+		{name: "long words skipped", text: "ok " + strings.Repeat("x", searchWordMaxRunes+1), want: []string{"ok"}},
+		{name: "longest word kept", text: strings.Repeat("x", searchWordMaxRunes), want: []string{strings.Repeat("x", searchWordMaxRunes)}},
+		{name: "digits", text: "order 42 shipped", want: []string{"order", "42", "shipped"}},
+		{name: "non-latin", text: "Привет, мир", want: []string{"привет", "мир"}},
+		{name: "empty", text: " ,. ", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SearchWords(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SearchWords(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package crypto
 
 import (
 	"chat-api/internal/model"
+	"context"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
@@ -10,11 +11,14 @@ import (
 	"io"
 )
 
+// MessageEncrypter encrypts the content of the message bound to the chat and the message,
+// the ciphertext moved to another message or chat doesn't decrypt
 type MessageEncrypter interface {
-	Encrypt(plaintext string) (string, error)
-	Decrypt(ciphertext string) (string, error)
+	Encrypt(ctx context.Context, chatID, messageID int64, plaintext string) (string, error)
+	Decrypt(ctx context.Context, chatID, messageID int64, ciphertext string) (string, error)
 }
 
+// AESCipher encrypts with one static key, the content stored before the envelope encryption is readable only by it
 type AESCipher struct {
 	key []byte
 }
@@ -29,52 +33,65 @@ func NewAESCipher(key string) (*AESCipher, error) {
 }
 
 func (a *AESCipher) Encrypt(plaintext string) (string, error) {
-	block, err := aes.NewCipher(a.key)
+	ciphertext, err := seal(a.key, []byte(plaintext), nil)
 	if err != nil {
 		return "", err
 	}
 
-	gcm, err := cipher.NewGCM(block)
+	return base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+func (a *AESCipher) Decrypt(ciphertext string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
 	if err != nil {
 		return "", err
 	}
 
-	nonce := make([]byte, gcm.NonceSize())
-	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+	plaintext, err := open(a.key, data, nil)
+	if err != nil {
 		return "", err
 	}
 
-	ciphertext := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
-	return base64.StdEncoding.EncodeToString(ciphertext), nil
+	return string(plaintext), nil
 }
 
-func (a *AESCipher) Decrypt(ciphertext string) (string, error) {
-	data, err := base64.StdEncoding.DecodeString(ciphertext)
+// seal encrypts plaintext with AES-GCM authenticating additionalData along with it, the nonce is prepended to the result
+func seal(key, plaintext, additionalData []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	block, err := aes.NewCipher(a.key)
+	gcm, err := cipher.NewGCM(block)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return gcm.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func open(key, data, additionalData []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
 	}
 
 	gcm, err := cipher.NewGCM(block)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	if len(data) < gcm.NonceSize() {
-		return "", errors.New("ciphertext too short")
+		return nil, errors.New("ciphertext too short")
 	}
 
 	nonce := data[:gcm.NonceSize()]
 	ciphertextData := data[gcm.NonceSize():]
 
-	plaintext, err := gcm.Open(nil, nonce, ciphertextData, nil)
-	if err != nil {
-		return "", err
-	}
-
-	return string(plaintext), nil
+	return gcm.Open(nil, nonce, ciphertextData, additionalData)
 }
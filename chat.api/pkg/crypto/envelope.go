@@ -0,0 +1,187 @@
+package crypto
+
+import (
+	"chat-api/internal/model"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DataKeyStore keeps the wrapped data keys of the chats
+type DataKeyStore interface {
+	// GetActiveDataKey returns sql.ErrNoRows when the chat has no active key
+	GetActiveDataKey(ctx context.Context, chatID int64) (model.DataKey, error)
+	GetDataKey(ctx context.Context, keyID int64) (model.DataKey, error)
+	// SetDataKey stores the key as the active one unless the chat already has it and returns the active key
+	SetDataKey(ctx context.Context, key model.DataKey) (model.DataKey, error)
+}
+
+// EnvelopeCipher encrypts the messages of every chat with its own data key wrapped by the master key.
+// The ciphertext is stored as "v2:<key id>:<base64 of nonce and sealed content>" and is bound to the chat
+// and the message. The "v1" content was written before the binding and is decrypted without it,
+// content without the prefix was written before that and is decrypted by the legacy cipher.
+type EnvelopeCipher struct {
+	provider KeyProvider
+	store    DataKeyStore
+	legacy   *AESCipher
+
+	// unwrapped keys by key_id, a key never changes once stored
+	keys sync.Map
+}
+
+// NewEnvelopeCipher creates the cipher, legacy may be nil when there's no content of the static key left
+func NewEnvelopeCipher(provider KeyProvider, store DataKeyStore, legacy *AESCipher) *EnvelopeCipher {
+	return &EnvelopeCipher{
+		provider: provider,
+		store:    store,
+		legacy:   legacy,
+	}
+}
+
+func (e *EnvelopeCipher) Encrypt(ctx context.Context, chatID, messageID int64, plaintext string) (string, error) {
+	keyID, key, err := e.activeKey(ctx, chatID)
+	if err != nil {
+		return "", err
+	}
+
+	ciphertext, err := seal(key, []byte(plaintext), messageAAD(chatID, messageID))
+	if err != nil {
+		return "", err
+	}
+
+	return KeyPrefix(keyID) + base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+func (e *EnvelopeCipher) Decrypt(ctx context.Context, chatID, messageID int64, ciphertext string) (string, error) {
+	version, rest, found := strings.Cut(ciphertext, ":")
+	if !found {
+		if e.legacy == nil {
+			return "", model.ErrInvalidCiphertext
+		}
+		return e.legacy.Decrypt(ciphertext)
+	}
+	var additionalData []byte
+	switch version {
+	case model.ENVELOPE_CIPHER_PREFIX:
+		additionalData = messageAAD(chatID, messageID)
+	case model.ENVELOPE_CIPHER_UNBOUND_PREFIX:
+	default:
+		return "", model.ErrInvalidCiphertext
+	}
+
+	keyIDText, encoded, found := strings.Cut(rest, ":")
+	if !found {
+		return "", model.ErrInvalidCiphertext
+	}
+
+	keyID, err := strconv.ParseInt(keyIDText, 10, 64)
+	if err != nil {
+		return "", model.ErrInvalidCiphertext
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", err
+	}
+
+	key, err := e.key(ctx, keyID)
+	if err != nil {
+		return "", err
+	}
+
+	plaintext, err := open(key, data, additionalData)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
+
+// KeyPrefix is the beginning of every ciphertext encrypted by the data key
+func KeyPrefix(keyID int64) string {
+	return model.ENVELOPE_CIPHER_PREFIX + ":" + strconv.FormatInt(keyID, 10) + ":"
+}
+
+// messageAAD binds the ciphertext to the chat and the message it's stored for
+func messageAAD(chatID, messageID int64) []byte {
+	data := make([]byte, 16)
+	binary.BigEndian.PutUint64(data[:8], uint64(chatID))
+	binary.BigEndian.PutUint64(data[8:], uint64(messageID))
+	return data
+}
+
+// activeKey returns the active data key of the chat, the first message of the chat generates it
+func (e *EnvelopeCipher) activeKey(ctx context.Context, chatID int64) (int64, []byte, error) {
+	dataKey, err := e.store.GetActiveDataKey(ctx, chatID)
+	switch {
+	case err == nil:
+	case errors.Is(err, sql.ErrNoRows):
+		dataKey, err = e.generateKey(ctx, chatID)
+		if err != nil {
+			return 0, nil, err
+		}
+	default:
+		return 0, nil, err
+	}
+
+	key, err := e.unwrap(ctx, dataKey)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return dataKey.KeyID, key, nil
+}
+
+func (e *EnvelopeCipher) generateKey(ctx context.Context, chatID int64) (model.DataKey, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return model.DataKey{}, err
+	}
+
+	wrapped, version, err := e.provider.WrapKey(ctx, key)
+	if err != nil {
+		return model.DataKey{}, err
+	}
+
+	// another replica may have generated the key at the same time, the stored one wins
+	return e.store.SetDataKey(ctx, model.DataKey{
+		ChatID:           chatID,
+		WrappedKey:       wrapped,
+		MasterKeyVersion: version,
+		Active:           true,
+	})
+}
+
+func (e *EnvelopeCipher) key(ctx context.Context, keyID int64) ([]byte, error) {
+	if key, ok := e.keys.Load(keyID); ok {
+		return key.([]byte), nil
+	}
+
+	dataKey, err := e.store.GetDataKey(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.unwrap(ctx, dataKey)
+}
+
+func (e *EnvelopeCipher) unwrap(ctx context.Context, dataKey model.DataKey) ([]byte, error) {
+	if key, ok := e.keys.Load(dataKey.KeyID); ok {
+		return key.([]byte), nil
+	}
+
+	key, err := e.provider.UnwrapKey(ctx, dataKey.WrappedKey, dataKey.MasterKeyVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	e.keys.Store(dataKey.KeyID, key)
+	return key, nil
+}
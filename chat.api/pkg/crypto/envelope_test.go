@@ -0,0 +1,250 @@
+package crypto
+
+import (
+	"chat-api/internal/model"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// memoryKeyStore keeps the data keys in memory like the data keys repository
+type memoryKeyStore struct {
+	mu     sync.Mutex
+	keys   map[int64]model.DataKey
+	nextID int64
+}
+
+func newMemoryKeyStore() *memoryKeyStore {
+	return &memoryKeyStore{keys: make(map[int64]model.DataKey)}
+}
+
+func (s *memoryKeyStore) GetActiveDataKey(ctx context.Context, chatID int64) (model.DataKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range s.keys {
+		if key.ChatID == chatID && key.Active {
+			return key, nil
+		}
+	}
+	return model.DataKey{}, sql.ErrNoRows
+}
+
+func (s *memoryKeyStore) GetDataKey(ctx context.Context, keyID int64) (model.DataKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[keyID]
+	if !ok {
+		return model.DataKey{}, sql.ErrNoRows
+	}
+	return key, nil
+}
+
+func (s *memoryKeyStore) SetDataKey(ctx context.Context, key model.DataKey) (model.DataKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, stored := range s.keys {
+		if stored.ChatID == key.ChatID && stored.Active {
+			return stored, nil
+		}
+	}
+	s.nextID++
+	key.KeyID = s.nextID
+	s.keys[key.KeyID] = key
+	return key, nil
+}
+
+func newTestKeyProvider(t *testing.T) *FileKeyProvider {
+	t.Helper()
+
+	masterKey := make([]byte, 32)
+	if _, err := rand.Read(masterKey); err != nil {
+		t.Fatalf("rand.Read: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "master.keys")
+	if err := os.WriteFile(path, []byte("1:"+base64.StdEncoding.EncodeToString(masterKey)+"\n"), 0o600); err != nil {
+		t.Fatalf("os.WriteFile: %v", err)
+	}
+
+	provider, err := NewFileKeyProvider(path)
+	if err != nil {
+		t.Fatalf("NewFileKeyProvider: %v", err)
+	}
+	return provider
+}
+
+func newTestEnvelopeCipher(t *testing.T, legacy *AESCipher) (*EnvelopeCipher, *memoryKeyStore) {
+	t.Helper()
+
+	store := newMemoryKeyStore()
+	return NewEnvelopeCipher(newTestKeyProvider(t), store, legacy), store
+}
+
+func TestEnvelopeCipherRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	cipher, store := newTestEnvelopeCipher(t, nil)
+
+	for _, plaintext := range []string{"hello", "", "привет 👋", strings.Repeat("long message ", 1000)} {
+		ciphertext, err := cipher.Encrypt(ctx, 1, 100, plaintext)
+		if err != nil {
+			t.Fatalf("Encrypt: %v", err)
+		}
+		if !strings.HasPrefix(ciphertext, model.ENVELOPE_CIPHER_PREFIX+":") {
+			t.Errorf("ciphertext %q doesn't start with the %q prefix", ciphertext[:8], model.ENVELOPE_CIPHER_PREFIX)
+		}
+		if plaintext != "" && strings.Contains(ciphertext, plaintext) {
+			t.Error("ciphertext contains the plaintext")
+		}
+
+		decrypted, err := cipher.Decrypt(ctx, 1, 100, ciphertext)
+		if err != nil {
+			t.Fatalf("Decrypt: %v", err)
+		}
+		if decrypted != plaintext {
+			t.Errorf("Decrypt = %q, want %q", decrypted, plaintext)
+		}
+	}
+
+	// the same plaintext encrypts differently every time
+	first, _ := cipher.Encrypt(ctx, 1, 100, "hello")
+	second, _ := cipher.Encrypt(ctx, 1, 100, "hello")
+	if first == second {
+		t.Error("two encryptions of the same plaintext are equal")
+	}
+
+	if _, err := cipher.Encrypt(ctx, 2, 200, "hello"); err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if len(store.keys) != 2 {
+		t.Errorf("%d data keys stored for two chats, want 2", len(store.keys))
+	}
+}
+
+func TestEnvelopeCipherDecryptWithoutCache(t *testing.T) {
+	ctx := context.Background()
+	provider := newTestKeyProvider(t)
+	store := newMemoryKeyStore()
+
+	ciphertext, err := NewEnvelopeCipher(provider, store, nil).Encrypt(ctx, 1, 100, "hello")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	// another replica reads the wrapped key from the store
+	decrypted, err := NewEnvelopeCipher(provider, store, nil).Decrypt(ctx, 1, 100, ciphertext)
+	if err != nil {
+		t.Fatalf("Decrypt: %v", err)
+	}
+	if decrypted != "hello" {
+		t.Errorf("Decrypt = %q, want %q", decrypted, "hello")
+	}
+}
+
+func TestEnvelopeCipherTamper(t *testing.T) {
+	ctx := context.Background()
+	cipher, _ := newTestEnvelopeCipher(t, nil)
+
+	ciphertext, err := cipher.Encrypt(ctx, 1, 100, "hello")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	prefix, encoded := ciphertext[:strings.LastIndex(ciphertext, ":")+1], ciphertext[strings.LastIndex(ciphertext, ":")+1:]
+
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		t.Fatalf("base64: %v", err)
+	}
+	flipped := make([]byte, len(data))
+	copy(flipped, data)
+	flipped[len(flipped)-1] ^= 0x01
+
+	otherCiphertext, err := cipher.Encrypt(ctx, 2, 100, "hello")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		chatID     int64
+		messageID  int64
+		ciphertext string
+	}{
+		{name: "flipped bit", chatID: 1, messageID: 100, ciphertext: prefix + base64.StdEncoding.EncodeToString(flipped)},
+		{name: "truncated", chatID: 1, messageID: 100, ciphertext: prefix + base64.StdEncoding.EncodeToString(data[:len(data)-4])},
+		{name: "moved to another message", chatID: 1, messageID: 101, ciphertext: ciphertext},
+		{name: "moved to another chat", chatID: 2, messageID: 100, ciphertext: ciphertext},
+		{name: "another chat key", chatID: 1, messageID: 100, ciphertext: prefix + otherCiphertext[strings.LastIndex(otherCiphertext, ":")+1:]},
+		{name: "downgraded to unbound", chatID: 1, messageID: 100, ciphertext: model.ENVELOPE_CIPHER_UNBOUND_PREFIX + strings.TrimPrefix(ciphertext, model.ENVELOPE_CIPHER_PREFIX)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if plaintext, err := cipher.Decrypt(ctx, tt.chatID, tt.messageID, tt.ciphertext); err == nil {
+				t.Errorf("Decrypt = %q, want an error", plaintext)
+			}
+		})
+	}
+}
+
+func TestEnvelopeCipherMalformed(t *testing.T) {
+	ctx := context.Background()
+	cipher, _ := newTestEnvelopeCipher(t, nil)
+
+	for _, ciphertext := range []string{
+		"v3:1:AAAA",
+		model.ENVELOPE_CIPHER_PREFIX + ":1",
+		model.ENVELOPE_CIPHER_PREFIX + ":key:AAAA",
+		"bm8gcHJlZml4", // no prefix and no legacy cipher
+	} {
+		if _, err := cipher.Decrypt(ctx, 1, 100, ciphertext); !errors.Is(err, model.ErrInvalidCiphertext) {
+			t.Errorf("Decrypt(%q) error = %v, want %v", ciphertext, err, model.ErrInvalidCiphertext)
+		}
+	}
+}
+
+func TestEnvelopeCipherPreviousFormats(t *testing.T) {
+	ctx := context.Background()
+
+	legacy, err := NewAESCipher(strings.Repeat("k", 32))
+	if err != nil {
+		t.Fatalf("NewAESCipher: %v", err)
+	}
+	cipher, store := newTestEnvelopeCipher(t, legacy)
+
+	legacyCiphertext, err := legacy.Encrypt("legacy")
+	if err != nil {
+		t.Fatalf("legacy Encrypt: %v", err)
+	}
+	if decrypted, err := cipher.Decrypt(ctx, 1, 100, legacyCiphertext); err != nil || decrypted != "legacy" {
+		t.Errorf("Decrypt of the legacy content = %q, %v", decrypted, err)
+	}
+
+	// the unbound content was sealed by the data key without the chat and the message
+	if _, err := cipher.Encrypt(ctx, 1, 100, "generates the key"); err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	dataKey, _ := store.GetActiveDataKey(ctx, 1)
+	key, err := cipher.key(ctx, dataKey.KeyID)
+	if err != nil {
+		t.Fatalf("key: %v", err)
+	}
+	sealed, err := seal(key, []byte("unbound"), nil)
+	if err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+	unbound := model.ENVELOPE_CIPHER_UNBOUND_PREFIX + ":" + strconv.FormatInt(dataKey.KeyID, 10) + ":" + base64.StdEncoding.EncodeToString(sealed)
+
+	if decrypted, err := cipher.Decrypt(ctx, 1, 100, unbound); err != nil || decrypted != "unbound" {
+		t.Errorf("Decrypt of the unbound content = %q, %v", decrypted, err)
+	}
+}
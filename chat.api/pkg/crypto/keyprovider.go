@@ -0,0 +1,97 @@
+package crypto
+
+import (
+	"bufio"
+	"chat-api/internal/model"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// KeyProvider wraps the data keys with the master key and never hands the master key out,
+// so a KMS client can implement it the same way as the local file.
+type KeyProvider interface {
+	// CurrentVersion is the version of the master key used to wrap new data keys
+	CurrentVersion() int
+	WrapKey(ctx context.Context, dataKey []byte) (wrapped []byte, version int, err error)
+	UnwrapKey(ctx context.Context, wrapped []byte, version int) ([]byte, error)
+}
+
+// FileKeyProvider keeps the master keys in a local file, one "<version>:<base64 key>" per line.
+// The highest version is the current one, the older ones stay in the file until the data keys are rewrapped.
+type FileKeyProvider struct {
+	keys    map[int][]byte
+	current int
+}
+
+func NewFileKeyProvider(path string) (*FileKeyProvider, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	provider := &FileKeyProvider{keys: make(map[int][]byte)}
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		versionText, keyText, found := strings.Cut(text, ":")
+		if !found {
+			return nil, fmt.Errorf("line %d: %w", line, model.ErrInvalidMasterKey)
+		}
+
+		version, err := strconv.Atoi(versionText)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("line %d: %w", line, model.ErrInvalidMasterKey)
+		}
+
+		key, err := base64.StdEncoding.DecodeString(keyText)
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("line %d: %w", line, model.ErrInvalidMasterKey)
+		}
+
+		provider.keys[version] = key
+		if version > provider.current {
+			provider.current = version
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if provider.current == 0 {
+		return nil, model.ErrMasterKeyNotFound
+	}
+
+	return provider, nil
+}
+
+func (p *FileKeyProvider) CurrentVersion() int {
+	return p.current
+}
+
+func (p *FileKeyProvider) WrapKey(ctx context.Context, dataKey []byte) ([]byte, int, error) {
+	wrapped, err := seal(p.keys[p.current], dataKey, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return wrapped, p.current, nil
+}
+
+func (p *FileKeyProvider) UnwrapKey(ctx context.Context, wrapped []byte, version int) ([]byte, error) {
+	key, ok := p.keys[version]
+	if !ok {
+		return nil, model.ErrMasterKeyNotFound
+	}
+
+	return open(key, wrapped, nil)
+}
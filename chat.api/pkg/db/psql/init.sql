@@ -14,6 +14,40 @@ DROP TABLE IF EXISTS chat_roles;
 DROP TABLE IF EXISTS chat_history;
 DROP TABLE IF EXISTS chat_blocked_users;
 DROP TABLE IF EXISTS chat_messages;
+DROP TABLE IF EXISTS private_chats;
+DROP TABLE IF EXISTS message_reactions;
+DROP TABLE IF EXISTS message_threads;
+DROP TABLE IF EXISTS scheduled_messages;
+DROP TABLE IF EXISTS device_keys;
+DROP TABLE IF EXISTS one_time_prekeys;
+DROP TABLE IF EXISTS encrypted_envelopes;
+DROP TABLE IF EXISTS chat_data_keys;
+DROP TABLE IF EXISTS blobs;
+DROP TABLE IF EXISTS uploads;
+DROP TABLE IF EXISTS upload_parts;
+DROP TABLE IF EXISTS voice_listens;
+DROP TABLE IF EXISTS polls;
+DROP TABLE IF EXISTS poll_options;
+DROP TABLE IF EXISTS poll_votes;
+DROP TABLE IF EXISTS message_mentions;
+DROP TABLE IF EXISTS mention_reads;
+DROP TABLE IF EXISTS message_link_previews;
+DROP TABLE IF EXISTS user_presence;
+DROP TABLE IF EXISTS chat_join_requests;
+DROP TABLE IF EXISTS chat_invite_links;
+DROP TABLE IF EXISTS channel_post_views;
+DROP TABLE IF EXISTS channel_posts;
+DROP TABLE IF EXISTS chat_user_states;
+DROP TABLE IF EXISTS chat_folder_chats;
+DROP TABLE IF EXISTS chat_folders;
+DROP TABLE IF EXISTS message_bookmarks;
+DROP TABLE IF EXISTS message_search_tokens;
+DROP TABLE IF EXISTS chat_exports;
+DROP TABLE IF EXISTS chat_imports;
+DROP TABLE IF EXISTS retention_purges;
+DROP TABLE IF EXISTS retention_runs;
+DROP TABLE IF EXISTS retention_policies;
+DROP TABLE IF EXISTS legal_holds;
 
 DROP TYPE IF EXISTS message_status;
 DROP TYPE IF EXISTS message_type;
@@ -23,12 +57,14 @@ DROP TYPE IF EXISTS chat_action;
 DROP TYPE IF EXISTS chat_type;
 DROP TYPE IF EXISTS media_type;
 DROP TYPE IF EXISTS file_type;
+DROP TYPE IF EXISTS join_request_status;
 
 CREATE TYPE message_status AS ENUM ('sent', 'delivered', 'read');
-CREATE TYPE message_type AS ENUM ('text', 'media', 'file', 'location', 'mixed');
+CREATE TYPE message_type AS ENUM ('text', 'media', 'file', 'location', 'mixed', 'voice', 'poll');
 CREATE TYPE message_action AS ENUM ('edited', 'blurred', 'deleted', 'password', 'replied', 'pinned');
-CREATE TYPE chat_role AS ENUM ('user', 'admin');
+CREATE TYPE chat_role AS ENUM ('admin', 'moderator', 'member', 'restricted');
 CREATE TYPE chat_action AS ENUM ('chat was created', 'chat was deleted', 'added to chat by', 'left chat', 'changed the chat name to', 'was kicked by');
+CREATE TYPE join_request_status AS ENUM ('pending', 'approved', 'rejected');
 
 CREATE TYPE chat_type AS ENUM (
     'private',   
@@ -53,6 +89,7 @@ CREATE TYPE file_type AS ENUM(
     'video/mp4',
     'video/webm',
     'audio/mp3',
+    'audio/ogg',
     'application/pdf',
     'application/zip',
     'text/plain'
@@ -66,33 +103,98 @@ CREATE TABLE locations (
     CONSTRAINT pk_locations PRIMARY KEY(location_id)
 );
 
+-- uploaded content addressed by SHA-256, shared by every upload of the same bytes
+CREATE TABLE blobs (
+    sha256 CHAR(64) NOT NULL,
+    storage_key VARCHAR(255) NOT NULL,
+    type VARCHAR(100) NOT NULL,
+    size BIGINT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT pk_blobs PRIMARY KEY(sha256)
+);
+
 CREATE TABLE media (
     media_id BIGINT GENERATED ALWAYS AS IDENTITY,
-    url VARCHAR(255) NOT NULL,
+    url VARCHAR(255) NOT NULL, -- storage key of the blob
     type media_type NOT NULL,
     size BIGINT,
+    sha256 CHAR(64),
+    uploaded_by VARCHAR(255),
     uploaded_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    CONSTRAINT pk_media PRIMARY KEY (media_id)
+    width INTEGER,
+    height INTEGER,
+    duration DOUBLE PRECISION, -- seconds of a video
+    blurhash VARCHAR(64),
+    thumbnail_sha256 CHAR(64),
+    processing_status VARCHAR(16) NOT NULL DEFAULT 'ready', -- uploaded media is pending until processed
+    processing_attempts INTEGER NOT NULL DEFAULT 0, -- times the job was queued again by the sweeper
+    processing_requested_at TIMESTAMP, -- last time the sweeper queued the job, the upload time until then
+    CONSTRAINT pk_media PRIMARY KEY (media_id),
+    CONSTRAINT fk_media_sha256 FOREIGN KEY(sha256) REFERENCES blobs(sha256),
+    CONSTRAINT fk_media_thumbnail_sha256 FOREIGN KEY(thumbnail_sha256) REFERENCES blobs(sha256)
 );
 
 CREATE TABLE files (
     file_id BIGINT GENERATED ALWAYS AS IDENTITY,
-    url VARCHAR(255) NOT NULL,
+    url VARCHAR(255) NOT NULL, -- storage key of the blob
     type file_type NOT NULL,
     size BIGINT,
+    sha256 CHAR(64),
+    uploaded_by VARCHAR(255),
     uploaded_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    CONSTRAINT pk_files PRIMARY KEY (file_id)
+    duration DOUBLE PRECISION, -- seconds of a voice upload
+    waveform BYTEA, -- loudness of the voice upload, 0..31 per bar
+    CONSTRAINT pk_files PRIMARY KEY (file_id),
+    CONSTRAINT fk_files_sha256 FOREIGN KEY(sha256) REFERENCES blobs(sha256)
+);
+
+-- resumable upload, the received bytes are kept as parts until the upload is complete
+CREATE TABLE uploads (
+    upload_id VARCHAR(32) NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    kind VARCHAR(16) NOT NULL,
+    length BIGINT NOT NULL,
+    upload_offset BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    CONSTRAINT pk_uploads PRIMARY KEY(upload_id)
+);
+
+CREATE TABLE upload_parts (
+    upload_id VARCHAR(32) NOT NULL,
+    part_offset BIGINT NOT NULL,
+    size BIGINT NOT NULL,
+    storage_key VARCHAR(255) NOT NULL,
+    CONSTRAINT pk_upload_parts PRIMARY KEY(upload_id, part_offset),
+    CONSTRAINT fk_upload_parts_upload_id FOREIGN KEY(upload_id) REFERENCES uploads(upload_id) ON DELETE CASCADE
 );
 
 CREATE TABLE messages (
     message_id BIGINT GENERATED ALWAYS AS IDENTITY,
     sender_id VARCHAR(255) NOT NULL,
     content TEXT,
+    entities TEXT, -- encrypted JSON of the formatting entities
     status message_status DEFAULT 'sent',
     type message_type DEFAULT 'text',
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    CONSTRAINT pk_messages PRIMARY KEY (message_id)
+    reply_to_message_id BIGINT,
+    thread_id BIGINT, -- root message if the reply belongs to a thread
+    forwarded_from_sender_id VARCHAR(255),
+    forwarded_from_chat_id BIGINT,
+    expires_at TIMESTAMP, -- disappearing message
+    imported_sender_name VARCHAR(64), -- sender named by the imported archive, the importer is sender_id
+    CONSTRAINT pk_messages PRIMARY KEY (message_id),
+    CONSTRAINT fk_messages_reply_to_message_id FOREIGN KEY(reply_to_message_id) REFERENCES messages(message_id) ON DELETE SET NULL,
+    CONSTRAINT fk_messages_thread_id FOREIGN KEY(thread_id) REFERENCES messages(message_id) ON DELETE CASCADE
+);
+
+CREATE TABLE message_threads (
+    message_id BIGINT NOT NULL,
+    reply_count INT NOT NULL DEFAULT 0,
+    last_reply_at TIMESTAMP NOT NULL,
+    CONSTRAINT pk_message_threads PRIMARY KEY(message_id),
+    CONSTRAINT fk_message_threads_message_id FOREIGN KEY(message_id) REFERENCES messages(message_id) ON DELETE CASCADE
 );
 
 CREATE TABLE message_audit_log (
@@ -104,6 +206,81 @@ CREATE TABLE message_audit_log (
     CONSTRAINT fk_message_audit_log_message_id FOREIGN KEY(message_id) REFERENCES messages(message_id) ON DELETE CASCADE
 );
 
+-- recipients who played the voice message
+CREATE TABLE voice_listens (
+    message_id BIGINT NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    listened_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT pk_voice_listens PRIMARY KEY(message_id, user_id),
+    CONSTRAINT fk_voice_listens_message_id FOREIGN KEY(message_id) REFERENCES messages(message_id) ON DELETE CASCADE
+);
+
+-- question and texts of the options are encrypted like messages.content
+CREATE TABLE polls (
+    message_id BIGINT NOT NULL,
+    question TEXT NOT NULL,
+    multiple_choice BOOLEAN NOT NULL DEFAULT FALSE,
+    anonymous BOOLEAN NOT NULL DEFAULT TRUE,
+    quiz BOOLEAN NOT NULL DEFAULT FALSE,
+    correct_option SMALLINT, -- quiz only
+    close_at TIMESTAMP,
+    closed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT pk_polls PRIMARY KEY(message_id),
+    CONSTRAINT fk_polls_message_id FOREIGN KEY(message_id) REFERENCES messages(message_id) ON DELETE CASCADE
+);
+
+CREATE TABLE poll_options (
+    message_id BIGINT NOT NULL,
+    option_id SMALLINT NOT NULL, -- position of the option
+    text TEXT NOT NULL,
+    CONSTRAINT pk_poll_options PRIMARY KEY(message_id, option_id),
+    CONSTRAINT fk_poll_options_message_id FOREIGN KEY(message_id) REFERENCES polls(message_id) ON DELETE CASCADE
+);
+
+CREATE TABLE poll_votes (
+    message_id BIGINT NOT NULL,
+    option_id SMALLINT NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    voted_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT pk_poll_votes PRIMARY KEY(message_id, option_id, user_id),
+    CONSTRAINT fk_poll_votes_option FOREIGN KEY(message_id, option_id) REFERENCES poll_options(message_id, option_id) ON DELETE CASCADE
+);
+
+-- participants addressed by the message, offset and length are in characters of the plain content
+CREATE TABLE message_mentions (
+    message_id BIGINT NOT NULL,
+    mention_offset INTEGER NOT NULL,
+    mention_length INTEGER NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    CONSTRAINT pk_message_mentions PRIMARY KEY(message_id, mention_offset),
+    CONSTRAINT fk_message_mentions_message_id FOREIGN KEY(message_id) REFERENCES messages(message_id) ON DELETE CASCADE
+);
+
+-- mentioned users who have read the message
+CREATE TABLE mention_reads (
+    message_id BIGINT NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    read_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT pk_mention_reads PRIMARY KEY(message_id, user_id),
+    CONSTRAINT fk_mention_reads_message_id FOREIGN KEY(message_id) REFERENCES messages(message_id) ON DELETE CASCADE
+);
+
+CREATE TABLE message_link_previews (
+    message_id BIGINT NOT NULL,
+    preview TEXT NOT NULL, -- encrypted JSON of the preview of the first link
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT pk_message_link_previews PRIMARY KEY(message_id),
+    CONSTRAINT fk_message_link_previews_message_id FOREIGN KEY(message_id) REFERENCES messages(message_id) ON DELETE CASCADE
+);
+
+CREATE TABLE user_presence (
+    user_id VARCHAR(255) NOT NULL,
+    last_seen_at TIMESTAMP, -- NULL until the first socket is closed
+    hide_last_seen BOOLEAN NOT NULL DEFAULT false,
+    CONSTRAINT pk_user_presence PRIMARY KEY(user_id)
+);
+
 CREATE TABLE messages_files (
     message_id BIGINT NOT NULL,
     file_id BIGINT NOT NULL,
@@ -136,9 +313,34 @@ CREATE TABLE chats (
     type chat_type DEFAULT 'private',
     avatar_url TEXT,
     encrypted BOOLEAN DEFAULT false, -- E2EE
+    message_ttl INTEGER, -- seconds, messages don't disappear if NULL
+    default_permissions INTEGER NOT NULL DEFAULT 139, -- bitmask, send messages | send media | invite users | react
+    handle VARCHAR(32), -- lowercase public name of the channel, anybody can join by it
+    sign_messages BOOLEAN NOT NULL DEFAULT false, -- posts of the channel carry the name of the author
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    CONSTRAINT pk_chats PRIMARY KEY(chat_id)
+    CONSTRAINT pk_chats PRIMARY KEY(chat_id),
+    CONSTRAINT uq_chats_handle UNIQUE(handle)
+);
+
+-- messages of the channels with their view counters
+CREATE TABLE channel_posts (
+    message_id BIGINT NOT NULL,
+    chat_id BIGINT NOT NULL,
+    signature VARCHAR(255),
+    views INTEGER NOT NULL DEFAULT 0,
+    CONSTRAINT pk_channel_posts PRIMARY KEY(message_id),
+    CONSTRAINT fk_channel_posts_message_id FOREIGN KEY(message_id) REFERENCES messages(message_id) ON DELETE CASCADE,
+    CONSTRAINT fk_channel_posts_chat_id FOREIGN KEY(chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE
+);
+
+-- subscribers who saw the post, each one is counted once
+CREATE TABLE channel_post_views (
+    message_id BIGINT NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    viewed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT pk_channel_post_views PRIMARY KEY(message_id, user_id),
+    CONSTRAINT fk_channel_post_views_message_id FOREIGN KEY(message_id) REFERENCES channel_posts(message_id) ON DELETE CASCADE
 );
 
 CREATE TABLE chats_participants (
@@ -156,6 +358,42 @@ CREATE TABLE pinned_chats (
     CONSTRAINT fk_pinned_chats_chat_id FOREIGN KEY(chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE
 );
 
+-- per-user state of the chat, the chat without a row is in the main list with nothing read
+CREATE TABLE chat_user_states (
+    chat_id BIGINT NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    archived BOOLEAN NOT NULL DEFAULT false,
+    marked_unread BOOLEAN NOT NULL DEFAULT false,
+    position INTEGER, -- manual place in the chat list, ordered by the activity if NULL
+    last_read_message_id BIGINT NOT NULL DEFAULT 0,
+    CONSTRAINT pk_chat_user_states PRIMARY KEY(chat_id, user_id),
+    CONSTRAINT fk_chat_user_states_chat_id FOREIGN KEY(chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE
+);
+
+CREATE TABLE chat_folders (
+    folder_id BIGINT GENERATED ALWAYS AS IDENTITY,
+    user_id VARCHAR(255) NOT NULL,
+    name VARCHAR(32) NOT NULL,
+    position INTEGER NOT NULL DEFAULT 0,
+    include_private BOOLEAN NOT NULL DEFAULT false,
+    include_groups BOOLEAN NOT NULL DEFAULT false,
+    include_channels BOOLEAN NOT NULL DEFAULT false,
+    only_unread BOOLEAN NOT NULL DEFAULT false,
+    include_archived BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT pk_chat_folders PRIMARY KEY(folder_id)
+);
+
+-- chats the folder shows or hides regardless of its rules
+CREATE TABLE chat_folder_chats (
+    folder_id BIGINT NOT NULL,
+    chat_id BIGINT NOT NULL,
+    excluded BOOLEAN NOT NULL DEFAULT false,
+    CONSTRAINT pk_chat_folder_chats PRIMARY KEY(folder_id, chat_id),
+    CONSTRAINT fk_chat_folder_chats_folder_id FOREIGN KEY(folder_id) REFERENCES chat_folders(folder_id) ON DELETE CASCADE,
+    CONSTRAINT fk_chat_folder_chats_chat_id FOREIGN KEY(chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE
+);
+
 CREATE TABLE pinned_messages (
     chat_id BIGINT NOT NULL,
     message_id BIGINT NOT NULL,
@@ -171,7 +409,8 @@ CREATE TABLE chat_roles (
     user_id VARCHAR(255) NOT NULL,
     granter_id VARCHAR(255) NOT NULL,
     nickname VARCHAR(50) NOT NULL,
-    role chat_role DEFAULT 'user',
+    role chat_role DEFAULT 'member',
+    permissions INTEGER, -- bitmask of the restricted member, read only if NULL
     CONSTRAINT pk_chat_roles PRIMARY KEY(chat_id, user_id),
     CONSTRAINT fk_chat_roles_chat_id FOREIGN KEY(chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE
 );
@@ -189,10 +428,41 @@ CREATE TABLE chat_blocked_users (
     chat_id BIGINT NOT NULL,
     user_id VARCHAR(255) NOT NULL,
     blocked_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    banned BOOLEAN NOT NULL DEFAULT FALSE, -- kicked with a ban by an admin, only an admin lifts it
     CONSTRAINT pk_chat_blocked_users PRIMARY KEY (chat_id, user_id),
     CONSTRAINT fk_chat_blocked_users_chat_id FOREIGN KEY(chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE
 );
 
+CREATE TABLE chat_invite_links (
+    invite_link_id BIGINT GENERATED ALWAYS AS IDENTITY,
+    chat_id BIGINT NOT NULL,
+    creator_id VARCHAR(255) NOT NULL,
+    token VARCHAR(32) NOT NULL,
+    name VARCHAR(32),
+    expires_at TIMESTAMP, -- the link doesn't expire if NULL
+    usage_limit INTEGER, -- unlimited if NULL
+    usage_count INTEGER NOT NULL DEFAULT 0,
+    requires_approval BOOLEAN NOT NULL DEFAULT false,
+    revoked BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT pk_chat_invite_links PRIMARY KEY(invite_link_id),
+    CONSTRAINT uq_chat_invite_links_token UNIQUE(token),
+    CONSTRAINT fk_chat_invite_links_chat_id FOREIGN KEY(chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE
+);
+
+CREATE TABLE chat_join_requests (
+    chat_id BIGINT NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    invite_link_id BIGINT,
+    status join_request_status NOT NULL DEFAULT 'pending',
+    requested_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    decided_by VARCHAR(255),
+    decided_at TIMESTAMP,
+    CONSTRAINT pk_chat_join_requests PRIMARY KEY(chat_id, user_id),
+    CONSTRAINT fk_chat_join_requests_chat_id FOREIGN KEY(chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE,
+    CONSTRAINT fk_chat_join_requests_invite_link_id FOREIGN KEY(invite_link_id) REFERENCES chat_invite_links(invite_link_id) ON DELETE SET NULL
+);
+
 CREATE TABLE chat_messages (
     chat_id BIGINT NOT NULL,
     message_id BIGINT NOT NULL,
@@ -201,6 +471,191 @@ CREATE TABLE chat_messages (
     CONSTRAINT fk_chat_messages_message_id FOREIGN KEY(message_id) REFERENCES messages(message_id) ON DELETE CASCADE
 );
 
+CREATE TABLE private_chats (
+    chat_id BIGINT NOT NULL,
+    first_user_id VARCHAR(255) NOT NULL,
+    second_user_id VARCHAR(255) NOT NULL,
+    CONSTRAINT pk_private_chats PRIMARY KEY(first_user_id, second_user_id),
+    CONSTRAINT uq_private_chats_chat_id UNIQUE(chat_id),
+    CONSTRAINT chk_private_chats_order CHECK (first_user_id < second_user_id), -- unordered pair
+    CONSTRAINT fk_private_chats_chat_id FOREIGN KEY(chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE
+);
+
+-- messages saved by the user, they stay after the user leaves the chat and go away with the message
+CREATE TABLE message_bookmarks (
+    bookmark_id BIGINT GENERATED ALWAYS AS IDENTITY,
+    user_id VARCHAR(255) NOT NULL,
+    chat_id BIGINT NOT NULL,
+    message_id BIGINT NOT NULL,
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT pk_message_bookmarks PRIMARY KEY(bookmark_id),
+    CONSTRAINT uq_message_bookmarks_user_message UNIQUE(user_id, message_id),
+    CONSTRAINT fk_message_bookmarks_chat_message FOREIGN KEY(chat_id, message_id) REFERENCES chat_messages(chat_id, message_id) ON DELETE CASCADE
+);
+
+-- blind index of the words of the messages, the tokens are keyed by the chat so the same word doesn't match across chats
+CREATE TABLE message_search_tokens (
+    message_id BIGINT NOT NULL,
+    chat_id BIGINT NOT NULL,
+    token CHAR(32) NOT NULL,
+    CONSTRAINT pk_message_search_tokens PRIMARY KEY(message_id, token),
+    CONSTRAINT fk_message_search_tokens_message_id FOREIGN KEY(message_id) REFERENCES messages(message_id) ON DELETE CASCADE,
+    CONSTRAINT fk_message_search_tokens_chat_id FOREIGN KEY(chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE
+);
+
+-- archives of a chat or of all chats of the user (chat_id is NULL), built by the export workers
+CREATE TABLE chat_exports (
+    export_id BIGINT GENERATED ALWAYS AS IDENTITY,
+    user_id VARCHAR(255) NOT NULL,
+    chat_id BIGINT,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    storage_key TEXT,
+    size BIGINT,
+    error TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    started_at TIMESTAMP,
+    completed_at TIMESTAMP,
+    expires_at TIMESTAMP,
+    CONSTRAINT pk_chat_exports PRIMARY KEY(export_id),
+    CONSTRAINT fk_chat_exports_chat_id FOREIGN KEY(chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE
+);
+
+-- who brought the history of the chat in from an archive
+CREATE TABLE chat_imports (
+    import_id BIGINT GENERATED ALWAYS AS IDENTITY,
+    chat_id BIGINT NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    messages_count INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT pk_chat_imports PRIMARY KEY(import_id),
+    CONSTRAINT fk_chat_imports_chat_id FOREIGN KEY(chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE
+);
+
+-- the messages older than retention_days are deleted, the policy of the chat overrides the one of its type
+CREATE TABLE retention_policies (
+    policy_id BIGINT GENERATED ALWAYS AS IDENTITY,
+    chat_type chat_type,
+    chat_id BIGINT,
+    retention_days INTEGER NOT NULL CHECK (retention_days > 0),
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT pk_retention_policies PRIMARY KEY(policy_id),
+    CONSTRAINT uq_retention_policies_chat_type UNIQUE(chat_type),
+    CONSTRAINT uq_retention_policies_chat_id UNIQUE(chat_id),
+    CONSTRAINT ck_retention_policies_scope CHECK ((chat_type IS NULL) <> (chat_id IS NULL)),
+    CONSTRAINT fk_retention_policies_chat_id FOREIGN KEY(chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE
+);
+
+-- suspends the automatic deletion of the messages of the chat or sent by the user, released holds are kept
+CREATE TABLE legal_holds (
+    hold_id BIGINT GENERATED ALWAYS AS IDENTITY,
+    chat_id BIGINT,
+    user_id VARCHAR(255),
+    reason TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    released_at TIMESTAMP,
+    CONSTRAINT pk_legal_holds PRIMARY KEY(hold_id),
+    CONSTRAINT ck_legal_holds_target CHECK ((chat_id IS NULL) <> (user_id IS NULL)),
+    CONSTRAINT fk_legal_holds_chat_id FOREIGN KEY(chat_id) REFERENCES chats(chat_id)
+);
+
+CREATE TABLE retention_runs (
+    run_id BIGINT GENERATED ALWAYS AS IDENTITY,
+    status VARCHAR(16) NOT NULL DEFAULT 'running',
+    chats_processed INTEGER NOT NULL DEFAULT 0,
+    messages_purged INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    started_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    finished_at TIMESTAMP,
+    CONSTRAINT pk_retention_runs PRIMARY KEY(run_id)
+);
+
+-- the audit of the purged messages outlives the chats, so chat_id isn't a foreign key
+CREATE TABLE retention_purges (
+    purge_id BIGINT GENERATED ALWAYS AS IDENTITY,
+    run_id BIGINT NOT NULL,
+    chat_id BIGINT NOT NULL,
+    policy_id BIGINT NOT NULL,
+    scope VARCHAR(8) NOT NULL,
+    retention_days INTEGER NOT NULL,
+    purged_before TIMESTAMP NOT NULL,
+    message_ids BIGINT[] NOT NULL,
+    blobs CHAR(64)[] NOT NULL DEFAULT '{}', -- the blobs left without attachments and removed from the storage
+    purged_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT pk_retention_purges PRIMARY KEY(purge_id),
+    CONSTRAINT fk_retention_purges_run_id FOREIGN KEY(run_id) REFERENCES retention_runs(run_id)
+);
+
+CREATE TABLE message_reactions (
+    message_id BIGINT NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    emoji VARCHAR(32) NOT NULL,
+    reacted_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT pk_message_reactions PRIMARY KEY(message_id, user_id, emoji),
+    CONSTRAINT fk_message_reactions_message_id FOREIGN KEY(message_id) REFERENCES messages(message_id) ON DELETE CASCADE
+);
+
+CREATE TABLE scheduled_messages (
+    scheduled_message_id BIGSERIAL,
+    chat_id BIGINT NOT NULL,
+    sender_id VARCHAR(255) NOT NULL,
+    payload JSONB NOT NULL, -- message with attachments, content is encrypted
+    in_thread BOOLEAN NOT NULL DEFAULT FALSE,
+    send_at TIMESTAMP NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    sent_message_id BIGINT,
+    attempts INT NOT NULL DEFAULT 0,
+    claimed_at TIMESTAMP, -- the last time a replica took the message for sending
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT pk_scheduled_messages PRIMARY KEY (scheduled_message_id),
+    CONSTRAINT fk_scheduled_messages_chat_id FOREIGN KEY(chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE,
+    CONSTRAINT fk_scheduled_messages_sent_message_id FOREIGN KEY(sent_message_id) REFERENCES messages(message_id) ON DELETE SET NULL
+);
+
+CREATE TABLE device_keys (
+    user_id VARCHAR(255) NOT NULL,
+    device_id INTEGER NOT NULL,
+    identity_key TEXT NOT NULL,
+    signed_prekey_id INTEGER NOT NULL,
+    signed_prekey TEXT NOT NULL,
+    signed_prekey_signature TEXT NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT pk_device_keys PRIMARY KEY(user_id, device_id)
+);
+
+CREATE TABLE one_time_prekeys (
+    user_id VARCHAR(255) NOT NULL,
+    device_id INTEGER NOT NULL,
+    prekey_id INTEGER NOT NULL,
+    public_key TEXT NOT NULL,
+    CONSTRAINT pk_one_time_prekeys PRIMARY KEY(user_id, device_id, prekey_id),
+    CONSTRAINT fk_one_time_prekeys_device FOREIGN KEY(user_id, device_id) REFERENCES device_keys(user_id, device_id) ON DELETE CASCADE
+);
+
+-- ciphertext of an E2EE message per recipient device, messages.content stays NULL
+CREATE TABLE encrypted_envelopes (
+    message_id BIGINT NOT NULL,
+    sender_device_id INTEGER NOT NULL,
+    recipient_id VARCHAR(255) NOT NULL,
+    device_id INTEGER NOT NULL,
+    ciphertext TEXT NOT NULL,
+    CONSTRAINT pk_encrypted_envelopes PRIMARY KEY(message_id, recipient_id, device_id),
+    CONSTRAINT fk_encrypted_envelopes_message_id FOREIGN KEY(message_id) REFERENCES messages(message_id) ON DELETE CASCADE
+);
+
+-- data key of a chat wrapped by the master key, messages.content starts with "v1:<key_id>:"
+CREATE TABLE chat_data_keys (
+    key_id BIGINT GENERATED ALWAYS AS IDENTITY,
+    chat_id BIGINT NOT NULL,
+    wrapped_key BYTEA NOT NULL,
+    master_key_version INTEGER NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT pk_chat_data_keys PRIMARY KEY(key_id),
+    CONSTRAINT fk_chat_data_keys_chat_id FOREIGN KEY(chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE
+);
+
 CREATE INDEX idx_messages_sender_id ON messages(sender_id);
 CREATE INDEX idx_messages_created_at ON messages(created_at);
 CREATE INDEX idx_chats_participants_user_id ON chats_participants(user_id);
@@ -209,3 +664,33 @@ CREATE INDEX idx_chat_roles_user_id ON chat_roles(user_id);
 CREATE INDEX idx_chat_history_user_id ON chat_history(user_id);
 CREATE INDEX idx_message_audit_log_user_id ON message_audit_log(user_id);
 CREATE INDEX idx_chat_blocked_users_user_id ON chat_blocked_users(user_id);
+CREATE INDEX idx_private_chats_second_user_id ON private_chats(second_user_id);
+CREATE INDEX idx_message_reactions_user_id ON message_reactions(user_id);
+CREATE INDEX idx_messages_thread_id ON messages(thread_id);
+CREATE INDEX idx_scheduled_messages_pending ON scheduled_messages(send_at) WHERE status IN ('pending', 'sending');
+CREATE INDEX idx_scheduled_messages_chat_id_sender_id ON scheduled_messages(chat_id, sender_id);
+CREATE INDEX idx_messages_expires_at ON messages(expires_at) WHERE expires_at IS NOT NULL;
+CREATE INDEX idx_encrypted_envelopes_recipient ON encrypted_envelopes(recipient_id, device_id);
+CREATE UNIQUE INDEX uq_chat_data_keys_active ON chat_data_keys(chat_id) WHERE active;
+CREATE INDEX idx_chat_data_keys_master_key_version ON chat_data_keys(master_key_version);
+CREATE INDEX idx_uploads_expires_at ON uploads(expires_at);
+CREATE INDEX idx_files_uploaded_by ON files(uploaded_by);
+CREATE INDEX idx_media_uploaded_by ON media(uploaded_by);
+CREATE INDEX idx_voice_listens_user_id ON voice_listens(user_id);
+CREATE INDEX idx_polls_close_at ON polls(close_at) WHERE closed_at IS NULL AND close_at IS NOT NULL;
+CREATE INDEX idx_poll_votes_user_id ON poll_votes(user_id);
+CREATE INDEX idx_message_mentions_user_id ON message_mentions(user_id, message_id);
+CREATE INDEX idx_chat_invite_links_chat_id ON chat_invite_links(chat_id);
+CREATE INDEX idx_chat_join_requests_pending ON chat_join_requests(chat_id, requested_at) WHERE status = 'pending';
+CREATE INDEX idx_media_pending ON media(uploaded_at) WHERE processing_status = 'pending';
+CREATE INDEX idx_chat_folders_user_id ON chat_folders(user_id, position);
+CREATE INDEX idx_chat_folder_chats_chat_id ON chat_folder_chats(chat_id);
+CREATE INDEX idx_message_bookmarks_user_id ON message_bookmarks(user_id, bookmark_id);
+CREATE INDEX idx_message_bookmarks_tags ON message_bookmarks USING GIN(tags);
+CREATE INDEX idx_message_search_tokens_chat_token ON message_search_tokens(chat_id, token);
+CREATE INDEX idx_chat_exports_user_id ON chat_exports(user_id, export_id);
+CREATE INDEX idx_chat_exports_status ON chat_exports(status, expires_at);
+CREATE UNIQUE INDEX idx_retention_runs_running ON retention_runs(status) WHERE status = 'running';
+CREATE INDEX idx_retention_purges_run_id ON retention_purges(run_id, purge_id);
+CREATE INDEX idx_legal_holds_chat_id ON legal_holds(chat_id) WHERE released_at IS NULL;
+CREATE INDEX idx_legal_holds_user_id ON legal_holds(user_id) WHERE released_at IS NULL;
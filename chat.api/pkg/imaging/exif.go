@@ -0,0 +1,135 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	jpegMarkerAPP1 = 0xE1
+	jpegMarkerSOS  = 0xDA
+	tiffTagGPSIFD  = 0x8825
+)
+
+// ErrMalformedEXIF means the GPS data can't be found for sure, it may be left in the content
+var ErrMalformedEXIF = errors.New("malformed EXIF")
+
+var (
+	exifHeader = []byte("Exif\x00\x00")
+
+	// byte size of the TIFF field types
+	tiffTypeSizes = map[uint16]uint32{1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8}
+)
+
+// StripJPEGGPS removes the GPS data from the EXIF of a JPEG without re-encoding it: the entries
+// of the GPS IFD and the values they point to are zeroed and the IFD is left empty.
+// The other EXIF fields (e.g. the orientation) stay, so does any content that isn't a JPEG.
+// It reports whether anything was removed, data itself is never modified. ErrMalformedEXIF is returned
+// when the segments before the image data or the EXIF can't be read, the GPS data may be left then.
+func StripJPEGGPS(data []byte) ([]byte, bool, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return data, false, nil
+	}
+
+	out := bytes.Clone(data)
+	stripped := false
+
+	for pos := 2; ; {
+		if pos+4 > len(out) || out[pos] != 0xFF {
+			return data, false, ErrMalformedEXIF
+		}
+		marker := out[pos+1]
+		if marker == jpegMarkerSOS {
+			break
+		}
+
+		length := int(binary.BigEndian.Uint16(out[pos+2:]))
+		end := pos + 2 + length
+		if length < 2 || end > len(out) {
+			return data, false, ErrMalformedEXIF
+		}
+
+		segment := out[pos+4 : end]
+		if marker == jpegMarkerAPP1 && bytes.HasPrefix(segment, exifHeader) {
+			found, err := stripTIFFGPS(segment[len(exifHeader):])
+			if err != nil {
+				return data, false, err
+			}
+			stripped = stripped || found
+		}
+
+		pos = end
+	}
+
+	if !stripped {
+		return data, false, nil
+	}
+	return out, true, nil
+}
+
+// stripTIFFGPS reports whether the TIFF had the GPS IFD
+func stripTIFFGPS(tiff []byte) (bool, error) {
+	if len(tiff) < 8 {
+		return false, ErrMalformedEXIF
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return false, ErrMalformedEXIF
+	}
+
+	ifd0 := order.Uint32(tiff[4:])
+	gpsIFD, ok, err := findIFDEntry(tiff, order, ifd0, tiffTagGPSIFD)
+	if err != nil || !ok {
+		return false, err
+	}
+	if uint64(gpsIFD)+2 > uint64(len(tiff)) {
+		return false, ErrMalformedEXIF
+	}
+
+	count := uint32(order.Uint16(tiff[gpsIFD:]))
+	for i := uint32(0); i < count; i++ {
+		entry := uint64(gpsIFD) + 2 + uint64(i)*12
+		if entry+12 > uint64(len(tiff)) {
+			return false, ErrMalformedEXIF
+		}
+
+		fieldType := order.Uint16(tiff[entry+2:])
+		size := uint64(tiffTypeSizes[fieldType]) * uint64(order.Uint32(tiff[entry+4:]))
+		if size > 4 {
+			offset := uint64(order.Uint32(tiff[entry+8:]))
+			if offset+size <= uint64(len(tiff)) {
+				clear(tiff[offset : offset+size])
+			}
+		}
+		clear(tiff[entry : entry+12])
+	}
+	order.PutUint16(tiff[gpsIFD:], 0)
+
+	return true, nil
+}
+
+func findIFDEntry(tiff []byte, order binary.ByteOrder, ifd uint32, tag uint16) (uint32, bool, error) {
+	if uint64(ifd)+2 > uint64(len(tiff)) {
+		return 0, false, ErrMalformedEXIF
+	}
+
+	count := uint32(order.Uint16(tiff[ifd:]))
+	for i := uint32(0); i < count; i++ {
+		entry := uint64(ifd) + 2 + uint64(i)*12
+		if entry+12 > uint64(len(tiff)) {
+			return 0, false, ErrMalformedEXIF
+		}
+		if order.Uint16(tiff[entry:]) == tag {
+			return order.Uint32(tiff[entry+8:]), true, nil
+		}
+	}
+
+	return 0, false, nil
+}
@@ -0,0 +1,207 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+const (
+	tiffTagOrientation  = 0x0112
+	tiffTagGPSLatRef    = 0x0001
+	tiffTagGPSLatitude  = 0x0002
+	testGPSIFDOffset    = 38
+	testGPSValuesOffset = 68
+)
+
+// testTIFF is a little-endian TIFF with the orientation and the GPS IFD holding the latitude
+func testTIFF() []byte {
+	order := binary.LittleEndian
+	tiff := make([]byte, testGPSValuesOffset+24)
+
+	copy(tiff, "II")
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8)
+
+	// IFD0: the orientation and the pointer to the GPS IFD
+	order.PutUint16(tiff[8:], 2)
+	putIFDEntry(tiff[10:], tiffTagOrientation, 3, 1, 6)
+	putIFDEntry(tiff[22:], tiffTagGPSIFD, 4, 1, testGPSIFDOffset)
+
+	// GPS IFD: the latitude reference fits the entry, the latitude itself is stored after the IFD
+	order.PutUint16(tiff[testGPSIFDOffset:], 2)
+	putIFDEntry(tiff[testGPSIFDOffset+2:], tiffTagGPSLatRef, 2, 2, 'N')
+	putIFDEntry(tiff[testGPSIFDOffset+14:], tiffTagGPSLatitude, 5, 3, testGPSValuesOffset)
+	copy(tiff[testGPSValuesOffset:], testLatitude())
+
+	return tiff
+}
+
+func putIFDEntry(entry []byte, tag, fieldType uint16, count, value uint32) {
+	binary.LittleEndian.PutUint16(entry, tag)
+	binary.LittleEndian.PutUint16(entry[2:], fieldType)
+	binary.LittleEndian.PutUint32(entry[4:], count)
+	binary.LittleEndian.PutUint32(entry[8:], value)
+}
+
+// testLatitude is 55°45'12.34" as three rationals
+func testLatitude() []byte {
+	latitude := make([]byte, 24)
+	for i, v := range []uint32{55, 1, 45, 1, 1234, 100} {
+		binary.LittleEndian.PutUint32(latitude[i*4:], v)
+	}
+	return latitude
+}
+
+// testJPEG encodes a small image and puts the EXIF segment with tiff right after SOI
+func testJPEG(t *testing.T, tiff []byte) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, 16, 8))
+	for x := 0; x < 16; x++ {
+		for y := 0; y < 8; y++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 16), G: uint8(y * 32), B: 128, A: 255})
+		}
+	}
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, img, nil); err != nil {
+		t.Fatalf("jpeg.Encode: %v", err)
+	}
+	if tiff == nil {
+		return encoded.Bytes()
+	}
+
+	segment := []byte{0xFF, jpegMarkerAPP1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(2+len(exifHeader)+len(tiff)))
+	segment = append(segment, exifHeader...)
+	segment = append(segment, tiff...)
+
+	data := append([]byte{}, encoded.Bytes()[:2]...)
+	data = append(data, segment...)
+	return append(data, encoded.Bytes()[2:]...)
+}
+
+// exifTIFF returns the TIFF of the EXIF segment testJPEG put after SOI
+func exifTIFF(data []byte) []byte {
+	start := 4 + len(exifHeader)
+	return data[2+start : 2+start+testGPSValuesOffset+24]
+}
+
+func TestStripJPEGGPS(t *testing.T) {
+	data := testJPEG(t, testTIFF())
+	original := bytes.Clone(data)
+
+	out, stripped, err := StripJPEGGPS(data)
+	if err != nil {
+		t.Fatalf("StripJPEGGPS: %v", err)
+	}
+	if !stripped {
+		t.Fatal("StripJPEGGPS reported nothing stripped from the JPEG with the GPS IFD")
+	}
+	if !bytes.Equal(data, original) {
+		t.Error("StripJPEGGPS modified its input")
+	}
+	if len(out) != len(data) {
+		t.Errorf("stripped JPEG is %d bytes, want %d", len(out), len(data))
+	}
+
+	tiff := exifTIFF(out)
+	order := binary.LittleEndian
+
+	gpsIFD, ok, err := findIFDEntry(tiff, order, 8, tiffTagGPSIFD)
+	if err != nil || !ok {
+		t.Fatalf("GPS IFD pointer: ok = %v, err = %v", ok, err)
+	}
+	for _, tag := range []uint16{tiffTagGPSLatRef, tiffTagGPSLatitude} {
+		if _, ok, err := findIFDEntry(tiff, order, gpsIFD, tag); err != nil || ok {
+			t.Errorf("GPS tag %#04x: found = %v, err = %v, want it gone", tag, ok, err)
+		}
+	}
+	if bytes.Contains(out, testLatitude()) {
+		t.Error("latitude value is left in the stripped JPEG")
+	}
+
+	orientation, ok, err := findIFDEntry(tiff, order, 8, tiffTagOrientation)
+	if err != nil || !ok || orientation&0xFFFF != 6 {
+		t.Errorf("orientation = %d, ok = %v, err = %v, want it kept", orientation, ok, err)
+	}
+
+	img, err := jpeg.Decode(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("stripped JPEG doesn't decode: %v", err)
+	}
+	if bounds := img.Bounds(); bounds.Dx() != 16 || bounds.Dy() != 8 {
+		t.Errorf("stripped JPEG is %dx%d, want 16x8", bounds.Dx(), bounds.Dy())
+	}
+}
+
+func TestStripJPEGGPSUntouched(t *testing.T) {
+	withoutGPS := testTIFF()
+	// the pointer to the GPS IFD becomes an unrelated tag
+	binary.LittleEndian.PutUint16(withoutGPS[22:], 0x0131)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "JPEG without EXIF", data: testJPEG(t, nil)},
+		{name: "JPEG without GPS IFD", data: testJPEG(t, withoutGPS)},
+		{name: "PNG", data: []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR")},
+		{name: "empty", data: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, stripped, err := StripJPEGGPS(tt.data)
+			if err != nil {
+				t.Fatalf("StripJPEGGPS: %v", err)
+			}
+			if stripped || !bytes.Equal(out, tt.data) {
+				t.Errorf("StripJPEGGPS changed the content without the GPS data, stripped = %v", stripped)
+			}
+		})
+	}
+}
+
+func TestStripJPEGGPSMalformed(t *testing.T) {
+	badOrder := testTIFF()
+	copy(badOrder, "XX")
+
+	gpsOutOfRange := testTIFF()
+	binary.LittleEndian.PutUint32(gpsOutOfRange[30:], 0xFFFFFFF0)
+
+	gpsEntriesOutOfRange := testTIFF()
+	binary.LittleEndian.PutUint16(gpsEntriesOutOfRange[testGPSIFDOffset:], 0xFFFF)
+
+	valid := testJPEG(t, testTIFF())
+	badLength := bytes.Clone(valid)
+	binary.BigEndian.PutUint16(badLength[4:], 0xFFFF)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "truncated segments", data: valid[:20]},
+		{name: "segment past the end", data: badLength},
+		{name: "garbage instead of a marker", data: append([]byte{0xFF, 0xD8}, bytes.Repeat([]byte{0x42}, 16)...)},
+		{name: "unknown byte order", data: testJPEG(t, badOrder)},
+		{name: "GPS IFD out of range", data: testJPEG(t, gpsOutOfRange)},
+		{name: "GPS entries out of range", data: testJPEG(t, gpsEntriesOutOfRange)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, stripped, err := StripJPEGGPS(tt.data)
+			if !errors.Is(err, ErrMalformedEXIF) {
+				t.Errorf("StripJPEGGPS error = %v, want %v", err, ErrMalformedEXIF)
+			}
+			if stripped || !bytes.Equal(out, tt.data) {
+				t.Errorf("StripJPEGGPS returned the changed content with the error, stripped = %v", stripped)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	thumbnailQuality = 80
+	blurHashSize     = 32
+	blurHashX        = 4
+	blurHashY        = 3
+)
+
+// Preview is what a client needs to render the media before downloading it
+type Preview struct {
+	Width     int
+	Height    int
+	Thumbnail []byte // JPEG
+	BlurHash  string
+}
+
+// NewPreview decodes the image (the first frame of a GIF) and makes a JPEG thumbnail
+// fitting into maxSize x maxSize together with the blurhash placeholder.
+func NewPreview(data []byte, maxSize int) (Preview, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return Preview{}, err
+	}
+
+	bounds := img.Bounds()
+	preview := Preview{
+		Width:  bounds.Dx(),
+		Height: bounds.Dy(),
+	}
+
+	var thumbnail bytes.Buffer
+	if err := jpeg.Encode(&thumbnail, fit(img, maxSize), &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+		return Preview{}, err
+	}
+	preview.Thumbnail = thumbnail.Bytes()
+	preview.BlurHash = BlurHash(fit(img, blurHashSize), blurHashX, blurHashY)
+
+	return preview, nil
+}
+
+// fit scales the image down to fit into maxSize x maxSize keeping the aspect ratio
+func fit(img image.Image, maxSize int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= maxSize && height <= maxSize {
+		return img
+	}
+
+	if width > height {
+		height = max(1, height*maxSize/width)
+		width = maxSize
+	} else {
+		width = max(1, width*maxSize/height)
+		height = maxSize
+	}
+
+	scaled := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(scaled, scaled.Bounds(), img, bounds, draw.Src, nil)
+	return scaled
+}
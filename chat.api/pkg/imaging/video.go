@@ -0,0 +1,101 @@
+package imaging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+type VideoInfo struct {
+	Width    int
+	Height   int
+	Duration float64 // seconds
+}
+
+// VideoProber reads videos with the ffprobe and ffmpeg binaries, a run taking longer than timeout is killed
+type VideoProber struct {
+	ffprobePath string
+	ffmpegPath  string
+	timeout     time.Duration
+}
+
+func NewVideoProber(ffprobePath, ffmpegPath string, timeout time.Duration) *VideoProber {
+	return &VideoProber{
+		ffprobePath: ffprobePath,
+		ffmpegPath:  ffmpegPath,
+		timeout:     timeout,
+	}
+}
+
+func (p *VideoProber) Probe(ctx context.Context, path string) (VideoInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, p.ffprobePath,
+		"-v", "error",
+		"-select_streams", "v:0",
+		"-show_entries", "stream=width,height:format=duration",
+		"-of", "json",
+		path,
+	).Output()
+	if err != nil {
+		return VideoInfo{}, fmt.Errorf("ffprobe: %w", err)
+	}
+
+	var probe struct {
+		Streams []struct {
+			Width  int `json:"width"`
+			Height int `json:"height"`
+		} `json:"streams"`
+		Format struct {
+			Duration string `json:"duration"`
+		} `json:"format"`
+	}
+	if err := json.Unmarshal(output, &probe); err != nil {
+		return VideoInfo{}, err
+	}
+	if len(probe.Streams) == 0 {
+		return VideoInfo{}, fmt.Errorf("ffprobe: no video stream")
+	}
+
+	info := VideoInfo{
+		Width:  probe.Streams[0].Width,
+		Height: probe.Streams[0].Height,
+	}
+	if probe.Format.Duration != "" {
+		if info.Duration, err = strconv.ParseFloat(probe.Format.Duration, 64); err != nil {
+			return VideoInfo{}, err
+		}
+	}
+
+	return info, nil
+}
+
+// Frame returns a representative frame of the first seconds as a JPEG
+func (p *VideoProber) Frame(ctx context.Context, path string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	var stdout bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, p.ffmpegPath,
+		"-v", "error",
+		"-i", path,
+		"-vf", "thumbnail",
+		"-frames:v", "1",
+		"-f", "image2pipe",
+		"-vcodec", "mjpeg",
+		"-",
+	)
+	cmd.Stdout = &stdout
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ffmpeg: %w", err)
+	}
+
+	return stdout.Bytes(), nil
+}
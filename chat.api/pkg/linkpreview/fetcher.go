@@ -0,0 +1,239 @@
+package linkpreview
+
+import (
+	"chat-api/internal/model"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+var (
+	ErrForbiddenAddress = errors.New("link points to a private or reserved address")
+	ErrUnsupportedLink  = errors.New("only http and https links on the standard ports are previewed")
+	ErrNotHTML          = errors.New("link doesn't point to an HTML page")
+	ErrTooManyRedirects = errors.New("link redirects too many times")
+)
+
+type Config struct {
+	Timeout      time.Duration `envconfig:"TIMEOUT" default:"5s"`
+	MaxBodySize  int64         `envconfig:"MAX_BODY_SIZE" default:"524288"` // only the head of the page is read
+	MaxRedirects int           `envconfig:"MAX_REDIRECTS" default:"3"`
+	UserAgent    string        `envconfig:"USER_AGENT" default:"Mozilla/5.0 (compatible; ChatLinkPreview/1.0)"`
+}
+
+// Fetcher reads the metadata of public web pages. Every address the connection goes to is checked
+// when it's dialed, so neither a redirect nor a DNS answer changed after the check leads into the
+// private network.
+type Fetcher struct {
+	client      *http.Client
+	maxBodySize int64
+	userAgent   string
+}
+
+// reserved are the ranges not covered by netip.Addr predicates
+var reserved = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"), // NAT64 reaches IPv4 addresses
+	netip.MustParsePrefix("2001:db8::/32"),
+}
+
+func NewFetcher(cfg Config) *Fetcher {
+	dialer := &net.Dialer{
+		Timeout: cfg.Timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil || !isPublic(addrPort.Addr()) {
+				return ErrForbiddenAddress
+			}
+			return nil
+		},
+	}
+
+	transport := &http.Transport{
+		Proxy:                 nil, // a proxy would be dialed instead of the checked address
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   cfg.Timeout,
+		ResponseHeaderTimeout: cfg.Timeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       30 * time.Second,
+	}
+
+	return &Fetcher{
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   cfg.Timeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) > cfg.MaxRedirects {
+					return ErrTooManyRedirects
+				}
+				return checkLink(req.URL)
+			},
+		},
+		maxBodySize: cfg.MaxBodySize,
+		userAgent:   cfg.UserAgent,
+	}
+}
+
+// Fetch returns the preview of the page, it's empty if the page has no metadata
+func (f *Fetcher) Fetch(ctx context.Context, link string) (model.LinkPreview, error) {
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return model.LinkPreview{}, err
+	}
+	if err := checkLink(parsed); err != nil {
+		return model.LinkPreview{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, parsed.String(), nil)
+	if err != nil {
+		return model.LinkPreview{}, err
+	}
+	req.Header.Set("User-Agent", f.userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return model.LinkPreview{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return model.LinkPreview{}, fmt.Errorf("link preview: unexpected status %d", resp.StatusCode)
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return model.LinkPreview{}, ErrNotHTML
+	}
+
+	preview := parseHead(io.LimitReader(resp.Body, f.maxBodySize), resp.Request.URL)
+	preview.URL = link
+	return preview, nil
+}
+
+func checkLink(link *url.URL) error {
+	if link.Scheme != "http" && link.Scheme != "https" || link.Hostname() == "" || link.User != nil {
+		return ErrUnsupportedLink
+	}
+	if port := link.Port(); port != "" && port != "80" && port != "443" {
+		return ErrUnsupportedLink
+	}
+	return nil
+}
+
+func isPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || !addr.IsGlobalUnicast() || addr.IsPrivate() || addr.IsLoopback() ||
+		addr.IsLinkLocalUnicast() || addr.IsMulticast() || addr.IsUnspecified() {
+		return false
+	}
+	for _, prefix := range reserved {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseHead reads the meta tags of the head, Open Graph goes first, then Twitter cards and the plain title
+func parseHead(body io.Reader, base *url.URL) model.LinkPreview {
+	meta := make(map[string]string)
+	var title string
+
+	tokenizer := html.NewTokenizer(body)
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			break
+		}
+
+		name, hasAttr := tokenizer.TagName()
+		tag := string(name)
+		if tokenType == html.EndTagToken && tag == "head" || tokenType == html.StartTagToken && tag == "body" {
+			break
+		}
+		if tokenType == html.StartTagToken && tag == "title" && title == "" {
+			if tokenizer.Next() == html.TextToken {
+				title = string(tokenizer.Text())
+			}
+			continue
+		}
+		if tag != "meta" || !hasAttr {
+			continue
+		}
+
+		var key, content string
+		for {
+			attrName, attrValue, more := tokenizer.TagAttr()
+			switch strings.ToLower(string(attrName)) {
+			case "property", "name":
+				key = strings.ToLower(string(attrValue))
+			case "content":
+				content = string(attrValue)
+			}
+			if !more {
+				break
+			}
+		}
+		if _, ok := meta[key]; key != "" && !ok {
+			meta[key] = content
+		}
+	}
+
+	first := func(keys ...string) string {
+		for _, key := range keys {
+			if value := strings.TrimSpace(meta[key]); value != "" {
+				return value
+			}
+		}
+		return ""
+	}
+
+	return model.LinkPreview{
+		Title:       truncate(first("og:title", "twitter:title"), title, model.LINK_PREVIEW_TITLE_MAX_LENGTH),
+		Description: truncate(first("og:description", "twitter:description", "description"), "", model.LINK_PREVIEW_DESCRIPTION_MAX_LENGTH),
+		ImageURL:    resolveImage(base, first("og:image:secure_url", "og:image", "twitter:image", "twitter:image:src")),
+		SiteName:    truncate(first("og:site_name"), "", model.LINK_PREVIEW_TITLE_MAX_LENGTH),
+	}
+}
+
+func truncate(value, fallback string, maxLength int) string {
+	if value == "" {
+		value = fallback
+	}
+	value = strings.TrimSpace(strings.ToValidUTF8(value, ""))
+	if utf8.RuneCountInString(value) <= maxLength {
+		return value
+	}
+	return string([]rune(value)[:maxLength])
+}
+
+// resolveImage makes the image link absolute, only http(s) images are kept
+func resolveImage(base *url.URL, image string) string {
+	if image == "" {
+		return ""
+	}
+	resolved, err := base.Parse(image)
+	if err != nil || resolved.Scheme != "http" && resolved.Scheme != "https" || len(resolved.String()) > model.ENTITY_URL_MAX_LENGTH {
+		return ""
+	}
+	return resolved.String()
+}
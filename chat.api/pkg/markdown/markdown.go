@@ -0,0 +1,230 @@
+// Package markdown converts the markdown subset of message content into the plain text and entities:
+//
+//	**bold**  __italic__  ~~strikethrough~~  ||spoiler||  `code`  ```lang
+//	pre```  [text](https://example.com)
+//
+// Markers may be nested except inside code and pre. A backslash escapes any of the characters
+// \ * _ ~ | ` [ ] ( ), so "[" meant literally has to be written as "\[". Single *, _, ~ and |
+// are kept as they are.
+package markdown
+
+import (
+	"chat-api/internal/model"
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+const maxDepth = 8
+
+var (
+	ErrUnclosedMarker = errors.New("markdown marker isn't closed")
+	ErrEmptyEntity    = errors.New("markdown marker encloses nothing")
+	ErrInvalidLink    = errors.New("markdown link must look like [text](url)")
+	ErrTooDeep        = errors.New("markdown markers are nested too deep")
+	ErrInvalidText    = errors.New("markdown text isn't valid UTF-8")
+)
+
+var markers = []struct {
+	marker     string
+	entityType string
+}{
+	{"**", model.ENTITY_BOLD},
+	{"__", model.ENTITY_ITALIC},
+	{"~~", model.ENTITY_STRIKETHROUGH},
+	{"||", model.ENTITY_SPOILER},
+}
+
+const escapable = "\\*_~|`[]()"
+
+type parser struct {
+	src      string
+	pos      int // byte offset in src
+	out      strings.Builder
+	length   int // characters written to out
+	entities []model.MessageEntity
+}
+
+// Parse returns the plain text and its entities, offsets are in characters of the plain text
+func Parse(text string) (string, []model.MessageEntity, error) {
+	if !utf8.ValidString(text) {
+		return "", nil, ErrInvalidText
+	}
+
+	p := &parser{src: text}
+	if err := p.parse("", 0); err != nil {
+		return "", nil, err
+	}
+
+	return p.out.String(), model.SortEntities(p.entities), nil
+}
+
+// parse writes the text until the closer, an empty closer reads up to the end
+func (p *parser) parse(closer string, depth int) error {
+	if depth > maxDepth {
+		return ErrTooDeep
+	}
+
+	for p.pos < len(p.src) {
+		rest := p.src[p.pos:]
+
+		if closer != "" && strings.HasPrefix(rest, closer) {
+			p.pos += len(closer)
+			return nil
+		}
+
+		switch {
+		case rest[0] == '\\' && len(rest) > 1 && strings.IndexByte(escapable, rest[1]) >= 0:
+			p.pos++
+			p.writeRune()
+			continue
+		case strings.HasPrefix(rest, "```"):
+			if err := p.parsePre(); err != nil {
+				return err
+			}
+			continue
+		case rest[0] == '`':
+			if err := p.parseCode(); err != nil {
+				return err
+			}
+			continue
+		case rest[0] == '[':
+			if err := p.parseLink(depth); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if marked, err := p.parseMarked(rest, depth); marked || err != nil {
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		p.writeRune()
+	}
+
+	if closer != "" {
+		return ErrUnclosedMarker
+	}
+	return nil
+}
+
+func (p *parser) parseMarked(rest string, depth int) (bool, error) {
+	for _, m := range markers {
+		if !strings.HasPrefix(rest, m.marker) {
+			continue
+		}
+
+		p.pos += len(m.marker)
+		offset := p.length
+		if err := p.parse(m.marker, depth+1); err != nil {
+			return true, err
+		}
+		return true, p.addEntity(model.MessageEntity{Type: m.entityType, Offset: offset})
+	}
+	return false, nil
+}
+
+// parseLink reads [text](url), the text may be formatted
+func (p *parser) parseLink(depth int) error {
+	p.pos++
+	offset := p.length
+	if err := p.parse("]", depth+1); err != nil {
+		if errors.Is(err, ErrUnclosedMarker) {
+			return ErrInvalidLink
+		}
+		return err
+	}
+
+	if !strings.HasPrefix(p.src[p.pos:], "(") {
+		return ErrInvalidLink
+	}
+	p.pos++
+
+	var url strings.Builder
+	for {
+		if p.pos >= len(p.src) {
+			return ErrInvalidLink
+		}
+		c := p.src[p.pos]
+		if c == ')' {
+			p.pos++
+			break
+		}
+		if c == '\\' && p.pos+1 < len(p.src) && strings.IndexByte(escapable, p.src[p.pos+1]) >= 0 {
+			p.pos++
+		}
+		r, size := utf8.DecodeRuneInString(p.src[p.pos:])
+		url.WriteRune(r)
+		p.pos += size
+	}
+
+	link := strings.TrimSpace(url.String())
+	if link == "" {
+		return ErrInvalidLink
+	}
+	return p.addEntity(model.MessageEntity{Type: model.ENTITY_TEXT_LINK, Offset: offset, URL: &link})
+}
+
+// parseCode reads `code`, nothing is formatted or escaped inside
+func (p *parser) parseCode() error {
+	p.pos++
+	end := strings.IndexByte(p.src[p.pos:], '`')
+	if end < 0 {
+		return ErrUnclosedMarker
+	}
+
+	offset := p.length
+	p.writeString(p.src[p.pos : p.pos+end])
+	p.pos += end + 1
+	return p.addEntity(model.MessageEntity{Type: model.ENTITY_CODE, Offset: offset})
+}
+
+// parsePre reads the fenced block, a single word on the line of the opening fence is the language
+func (p *parser) parsePre() error {
+	p.pos += len("```")
+	end := strings.Index(p.src[p.pos:], "```")
+	if end < 0 {
+		return ErrUnclosedMarker
+	}
+	block := p.src[p.pos : p.pos+end]
+	p.pos += end + len("```")
+
+	entity := model.MessageEntity{Type: model.ENTITY_PRE}
+	if newline := strings.IndexByte(block, '\n'); newline >= 0 {
+		if language := strings.TrimSpace(block[:newline]); language == "" || !strings.ContainsAny(language, " \t") {
+			if language != "" {
+				entity.Language = &language
+			}
+			block = block[newline+1:]
+		}
+	}
+	block = strings.TrimSuffix(block, "\n")
+
+	entity.Offset = p.length
+	p.writeString(block)
+	return p.addEntity(entity)
+}
+
+func (p *parser) addEntity(entity model.MessageEntity) error {
+	entity.Length = p.length - entity.Offset
+	if entity.Length == 0 {
+		return ErrEmptyEntity
+	}
+	p.entities = append(p.entities, entity)
+	return nil
+}
+
+func (p *parser) writeRune() {
+	r, size := utf8.DecodeRuneInString(p.src[p.pos:])
+	p.out.WriteRune(r)
+	p.length++
+	p.pos += size
+}
+
+func (p *parser) writeString(s string) {
+	p.out.WriteString(s)
+	p.length += utf8.RuneCountInString(s)
+}
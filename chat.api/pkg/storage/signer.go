@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+var ErrInvalidSignature = errors.New("download link is invalid or expired")
+
+// URLSigner issues the download links, a link is valid only until it expires
+// and can't be changed to point to another object.
+type URLSigner struct {
+	key []byte
+	ttl time.Duration
+}
+
+func NewURLSigner(key string, ttl time.Duration) (*URLSigner, error) {
+	if len(key) < 32 {
+		return nil, errors.New("url signing key must be at least 32 bytes long")
+	}
+	return &URLSigner{key: []byte(key), ttl: ttl}, nil
+}
+
+// Sign returns the query of the download link for the object of the kind
+func (s *URLSigner) Sign(kind string, objectID int64) (url.Values, time.Time) {
+	expiresAt := time.Now().Add(s.ttl).UTC()
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+
+	query := url.Values{}
+	query.Set("expires", expires)
+	query.Set("signature", s.signature(kind, objectID, expires))
+
+	return query, expiresAt
+}
+
+func (s *URLSigner) Verify(kind string, objectID int64, query url.Values) error {
+	expires := query.Get("expires")
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return ErrInvalidSignature
+	}
+
+	signature, err := hex.DecodeString(query.Get("signature"))
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	expected, _ := hex.DecodeString(s.signature(kind, objectID, expires))
+	if !hmac.Equal(signature, expected) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+func (s *URLSigner) signature(kind string, objectID int64, expires string) string {
+	mac := hmac.New(sha256.New, s.key)
+	fmt.Fprintf(mac, "%s\n%d\n%s", kind, objectID, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+const (
+	BACKEND_LOCAL = "local"
+	BACKEND_S3    = "s3"
+)
+
+var (
+	ErrObjectNotFound = errors.New("object not found in the storage")
+	ErrInvalidKey     = errors.New("object key is invalid")
+	ErrUnknownBackend = errors.New("unknown storage backend")
+)
+
+type StorageConfig struct {
+	Backend       string        `envconfig:"BACKEND" default:"local"`
+	LocalPath     string        `envconfig:"LOCAL_PATH" default:"storage"`
+	S3Endpoint    string        `envconfig:"S3_ENDPOINT"`
+	S3Region      string        `envconfig:"S3_REGION" default:"us-east-1"`
+	S3Bucket      string        `envconfig:"S3_BUCKET"`
+	S3AccessKey   string        `envconfig:"S3_ACCESS_KEY"`
+	S3SecretKey   string        `envconfig:"S3_SECRET_KEY"`
+	URLSigningKey string        `envconfig:"URL_SIGNING_KEY"`
+	URLTTL        time.Duration `envconfig:"URL_TTL" default:"5m"`
+}
+
+// Storage keeps the uploaded bytes under a key, the keys are chosen by the caller
+type Storage interface {
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	// Get returns ErrObjectNotFound when there's no object under the key
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+func NewStorage(cfg StorageConfig) (Storage, error) {
+	switch cfg.Backend {
+	case BACKEND_LOCAL:
+		return NewLocalStorage(cfg.LocalPath)
+	case BACKEND_S3:
+		return NewS3Storage(cfg.S3Endpoint, cfg.S3Region, cfg.S3Bucket, cfg.S3AccessKey, cfg.S3SecretKey)
+	default:
+		return nil, ErrUnknownBackend
+	}
+}
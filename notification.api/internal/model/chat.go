@@ -9,6 +9,8 @@ const (
 	CHAT_ACTION_LEFT   = "left chat"
 	CHAT_ACTION_RENAME = "changed the chat name to"
 	CHAT_ACTION_KICK   = "was kicked by"
+
+	CHAT_ACTION_JOIN_REQUEST = "requested to join"
 )
 
 type ChatBriefInfo struct {
@@ -10,6 +10,14 @@ const (
 	MESSAGE_ACTION_REPLIED    = "replied"
 	MESSAGE_ACTION_DELETED    = "deleted"
 	MESSAGE_ACTION_PINNED     = "pinned"
+
+	MESSAGE_TYPE_TEXT     = "text"
+	MESSAGE_TYPE_MEDIA    = "media"
+	MESSAGE_TYPE_FILE     = "file"
+	MESSAGE_TYPE_LOCATION = "location"
+	MESSAGE_TYPE_MIXED    = "mixed"
+	MESSAGE_TYPE_VOICE    = "voice"
+	MESSAGE_TYPE_POLL     = "poll"
 )
 
 type MessageBriefInfo struct {
@@ -17,4 +25,5 @@ type MessageBriefInfo struct {
 	SenderID  string    `json:"sender_id" db:"sender_id"`
 	Type      string    `json:"type" db:"type"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	Duration  *float64  `json:"duration,omitempty" db:"duration"` // seconds of a voice message
 }
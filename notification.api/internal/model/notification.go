@@ -1,10 +1,31 @@
 package model
 
+import (
+	"fmt"
+	"time"
+)
+
 const (
 	EMAIL = "email"
 	SMS   = "sms"
 )
 
+// notificationTexts is shown instead of the content, which never reaches notification.api
+var notificationTexts = map[string]string{
+	MESSAGE_TYPE_TEXT:     "New message",
+	MESSAGE_TYPE_MEDIA:    "Photo or video",
+	MESSAGE_TYPE_FILE:     "File",
+	MESSAGE_TYPE_LOCATION: "Location",
+	MESSAGE_TYPE_MIXED:    "Attachments",
+	MESSAGE_TYPE_VOICE:    "Voice message",
+	MESSAGE_TYPE_POLL:     "Poll",
+}
+
+const (
+	mentionText  = "Mentioned you"
+	reactionText = "Reacted %s to your message"
+)
+
 type NotificationRabbitMQ struct {
 	Exchange   string
 	RoutingKey string
@@ -23,6 +44,33 @@ type NotificationMessage struct {
 	Sender        UserBriefInfo    `json:"sender"`
 	RecipientID   string           `json:"recipient_id"`
 	MessageAction string
+	Mention       bool   `json:"mention"` // shown even if the chat is muted
+	Text          string `json:"text"`
+}
+
+// NotificationReaction tells the offline author of the message somebody reacted to it
+type NotificationReaction struct {
+	Chat        ChatBriefInfo    `json:"chat"`
+	Message     MessageBriefInfo `json:"message"`
+	Sender      UserBriefInfo    `json:"sender"`
+	RecipientID string           `json:"recipient_id"`
+	Emoji       string           `json:"emoji"`
+	Text        string           `json:"text"`
+}
+
+type NotificationDeletedMessages struct {
+	MessageIDs []int64 `json:"message_ids"`
+}
+
+// NotificationExport tells the offline user the archive of chat.api is built or failed,
+// the download url needs the token of the user
+type NotificationExport struct {
+	ExportID    int64      `json:"export_id"`
+	ChatID      *int64     `json:"chat_id,omitempty"`
+	RecipientID string     `json:"recipient_id"`
+	Status      string     `json:"status"`
+	DownloadURL string     `json:"download_url,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
 }
 
 type VerifyCodeInput struct {
@@ -31,7 +79,33 @@ type VerifyCodeInput struct {
 }
 
 type NotificationResponse struct {
-	NotificationMessages []NotificationMessage `json:"new_messages"`
-	NotificationChat     []NotificationChat    `json:"new_chats"`
-	MutedChat            []ChatBriefInfo       `json:"muted_chat"`
+	NotificationMessages []NotificationMessage  `json:"new_messages"`
+	NotificationChat     []NotificationChat     `json:"new_chats"`
+	MutedChat            []ChatBriefInfo        `json:"muted_chat"`
+	Exports              []NotificationExport   `json:"exports"`
+	Reactions            []NotificationReaction `json:"reactions"`
+}
+
+// NotificationText describes the message by its type, voice messages get their duration
+// and mentions are told apart from the other messages
+func NotificationText(message MessageBriefInfo, mention bool) string {
+	if mention {
+		return mentionText
+	}
+
+	text, ok := notificationTexts[message.Type]
+	if !ok {
+		text = notificationTexts[MESSAGE_TYPE_TEXT]
+	}
+
+	if message.Type == MESSAGE_TYPE_VOICE && message.Duration != nil {
+		seconds := int(*message.Duration + 0.5)
+		text = fmt.Sprintf("%s (%d:%02d)", text, seconds/60, seconds%60)
+	}
+
+	return text
+}
+
+func ReactionText(emoji string) string {
+	return fmt.Sprintf(reactionText, emoji)
 }
@@ -19,10 +19,10 @@ func (r *MessagesRepository) SetMessage(ctx context.Context, m model.MessageBrie
 	formated := m.UpdatedAt.Format("2006-01-02 15:04:05")
 
 	query := `
-		INSERT INTO messages (message_external_id, sender_id, type, updated_at, action)
-		VALUES (?, ?, ?, ?, ?)
+		INSERT INTO messages (message_external_id, sender_id, type, updated_at, action, duration)
+		VALUES (?, ?, ?, ?, ?, ?)
 	`
-	res, err := r.db.ExecContext(ctx, query, m.MessageID, m.SenderID, m.Type, formated, action)
+	res, err := r.db.ExecContext(ctx, query, m.MessageID, m.SenderID, m.Type, formated, action, m.Duration)
 	if err != nil {
 		return 0, err
 	}
@@ -33,7 +33,7 @@ func (r *MessagesRepository) GetMessage(ctx context.Context, externalMessageID i
 	var m model.MessageBriefInfo
 	var action string
 	query := `
-		SELECT message_external_id, sender_id, type, updated_at, action
+		SELECT message_external_id, sender_id, type, updated_at, action, duration
 		FROM messages
 		WHERE message_external_id = ?
 	`
@@ -43,6 +43,7 @@ func (r *MessagesRepository) GetMessage(ctx context.Context, externalMessageID i
 		&m.Type,
 		&m.UpdatedAt,
 		&action,
+		&m.Duration,
 	)
 	return m, action, err
 }
@@ -50,10 +51,10 @@ func (r *MessagesRepository) GetMessage(ctx context.Context, externalMessageID i
 func (r *MessagesRepository) UpdateMessage(ctx context.Context, m model.MessageBriefInfo, action string) error {
 	query := `
 		UPDATE messages
-		SET sender_id = ?, updated_at = ?, action = ?, type = ?
+		SET sender_id = ?, updated_at = ?, action = ?, type = ?, duration = ?
 		WHERE message_external_id = ?
 	`
-	_, err := r.db.ExecContext(ctx, query, m.SenderID, m.UpdatedAt, action, m.Type, m.MessageID)
+	_, err := r.db.ExecContext(ctx, query, m.SenderID, m.UpdatedAt, action, m.Type, m.Duration, m.MessageID)
 	return err
 }
 
@@ -62,3 +63,36 @@ func (r *MessagesRepository) DeleteMessage(ctx context.Context, externalMessageI
 	_, err := r.db.ExecContext(ctx, query, externalMessageID)
 	return err
 }
+
+// DeleteMessages removes the mirrored messages with their notifications and chat bindings
+func (r *MessagesRepository) DeleteMessages(ctx context.Context, externalMessageIDs []int64) error {
+	if len(externalMessageIDs) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	queries := []string{
+		`DELETE mn FROM message_notifications mn JOIN messages m ON mn.message_id = m.id WHERE m.message_external_id IN (?)`,
+		`DELETE FROM reaction_notifications WHERE message_external_id IN (?)`,
+		`DELETE cm FROM chat_messages cm JOIN messages m ON cm.message_id = m.id WHERE m.message_external_id IN (?)`,
+		`DELETE FROM messages WHERE message_external_id IN (?)`,
+	}
+
+	for _, query := range queries {
+		queryWithArgs, args, err := sqlx.In(query, externalMessageIDs)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, tx.Rebind(queryWithArgs), args...); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,108 @@
+package repo
+
+import (
+	"context"
+	"notification-api/internal/model"
+	"notification-api/pkg/logger"
+	"time"
+)
+
+// DB struct for notification exports
+type notificationExportDB struct {
+	ExportID    int64   `db:"export_external_id"`
+	RecipientID string  `db:"recipient_id"`
+	ChatID      *int64  `db:"chat_external_id"`
+	Status      string  `db:"status"`
+	DownloadURL *string `db:"download_url"`
+	ExpiresAt   *string `db:"expires_at"`
+}
+
+func toNotificationExport(dbRow notificationExportDB) model.NotificationExport {
+	export := model.NotificationExport{
+		ExportID:    dbRow.ExportID,
+		ChatID:      dbRow.ChatID,
+		RecipientID: dbRow.RecipientID,
+		Status:      dbRow.Status,
+	}
+	if dbRow.DownloadURL != nil {
+		export.DownloadURL = *dbRow.DownloadURL
+	}
+	if dbRow.ExpiresAt != nil {
+		t, err := time.Parse("2006-01-02 15:04:05", *dbRow.ExpiresAt)
+		if err != nil {
+			logger.Warn("Error with converting time db to time.Time")
+		} else {
+			export.ExpiresAt = &t
+		}
+	}
+	return export
+}
+
+func (r *NotificationsRepository) SetExport(ctx context.Context, export model.NotificationExport) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	var downloadURL *string
+	if export.DownloadURL != "" {
+		downloadURL = &export.DownloadURL
+	}
+
+	var expiresAt *string
+	if export.ExpiresAt != nil {
+		t := export.ExpiresAt.UTC().Format("2006-01-02 15:04:05")
+		expiresAt = &t
+	}
+
+	query := `
+		INSERT INTO export_notifications (export_external_id, recipient_id, chat_external_id, status, download_url, expires_at, is_read)
+		VALUES (?, ?, ?, ?, ?, ?, false)
+		ON DUPLICATE KEY UPDATE export_external_id = export_external_id
+	`
+	_, err := r.db.ExecContext(ctx, query, export.ExportID, export.RecipientID, export.ChatID, export.Status, downloadURL, expiresAt)
+	return err
+}
+
+// GetExportsForRecipient fetches the unread export notifications whose archives haven't expired yet and marks them read
+func (r *NotificationsRepository) GetExportsForRecipient(ctx context.Context, recipientID string) ([]model.NotificationExport, error) {
+	query := `
+	SELECT export_external_id, recipient_id, chat_external_id, status, download_url, expires_at
+	FROM export_notifications
+	WHERE recipient_id = ? AND is_read = FALSE
+	  AND (expires_at IS NULL OR expires_at > NOW())
+	ORDER BY export_external_id DESC
+	`
+
+	var dbRows []notificationExportDB
+	if err := r.db.SelectContext(ctx, &dbRows, query, recipientID); err != nil {
+		return nil, err
+	}
+
+	if len(dbRows) == 0 {
+		return nil, nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	updateQuery := `
+		UPDATE export_notifications
+		SET is_read = TRUE
+		WHERE recipient_id = ? AND is_read = FALSE
+	`
+	if _, err := r.db.ExecContext(ctx, updateQuery, recipientID); err != nil {
+		return nil, err
+	}
+
+	results := make([]model.NotificationExport, 0, len(dbRows))
+	for _, dbRow := range dbRows {
+		results = append(results, toNotificationExport(dbRow))
+	}
+
+	return results, nil
+}
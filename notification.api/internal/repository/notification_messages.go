@@ -26,11 +26,12 @@ type notificationMessageDB struct {
 	ChatAvatarURL *string `db:"chat_avatar_url"`
 	ChatUpdatedAt string  `db:"chat_updated_at"`
 
-	MessageID      int64  `db:"message_message_id"`
-	MessageSender  string `db:"message_sender_id"`
-	MessageType    string `db:"message_type"`
-	MessageUpdated string `db:"message_updated_at"`
-	MessageAction  string `db:"message_action"`
+	MessageID       int64    `db:"message_message_id"`
+	MessageSender   string   `db:"message_sender_id"`
+	MessageType     string   `db:"message_type"`
+	MessageUpdated  string   `db:"message_updated_at"`
+	MessageAction   string   `db:"message_action"`
+	MessageDuration *float64 `db:"message_duration"`
 
 	SenderUserID   string  `db:"sender_user_id"`
 	SenderUsername string  `db:"sender_username"`
@@ -39,6 +40,7 @@ type notificationMessageDB struct {
 
 	RecipientID string `db:"recipient_id"`
 	IsRead      bool   `db:"is_read"`
+	Mention     bool   `db:"mention"`
 }
 
 func toNotificationMessage(dbRow notificationMessageDB) model.NotificationMessage {
@@ -64,6 +66,7 @@ func toNotificationMessage(dbRow notificationMessageDB) model.NotificationMessag
 			SenderID:  dbRow.MessageSender,
 			Type:      dbRow.MessageType,
 			UpdatedAt: t_message,
+			Duration:  dbRow.MessageDuration,
 		},
 		Sender: model.UserBriefInfo{
 			UserID:    dbRow.SenderUserID,
@@ -73,10 +76,12 @@ func toNotificationMessage(dbRow notificationMessageDB) model.NotificationMessag
 		},
 		RecipientID:   dbRow.RecipientID,
 		MessageAction: dbRow.MessageAction,
+		Mention:       dbRow.Mention,
 	}
 }
 
-func (r *NotificationsRepository) SetMessage(ctx context.Context, internalMessageID int64, recipientID string) error {
+// SetMessage stores the notification for the recipient, a mention stays a mention if the message is sent again
+func (r *NotificationsRepository) SetMessage(ctx context.Context, internalMessageID int64, recipientID string, mention bool) error {
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
@@ -84,11 +89,11 @@ func (r *NotificationsRepository) SetMessage(ctx context.Context, internalMessag
 	}
 
 	query := `
-		INSERT INTO message_notifications (message_id, recipient_id, is_read)
-		VALUES (?, ?, false)
-		ON DUPLICATE KEY UPDATE message_id = message_id
+		INSERT INTO message_notifications (message_id, recipient_id, is_read, mention)
+		VALUES (?, ?, false, ?)
+		ON DUPLICATE KEY UPDATE mention = mention OR VALUES(mention)
 	`
-	_, err := r.db.ExecContext(ctx, query, internalMessageID, recipientID)
+	_, err := r.db.ExecContext(ctx, query, internalMessageID, recipientID, mention)
 	return err
 }
 
@@ -108,6 +113,7 @@ func (r *NotificationsRepository) GetMessage(ctx context.Context, internalMessag
 		m.type         AS message_type,
 		m.updated_at   AS message_updated_at,
 		m.action       AS message_action,
+		m.duration     AS message_duration,
 
 		u.user_id      AS sender_user_id,
 		u.username     AS sender_username,
@@ -116,6 +122,7 @@ func (r *NotificationsRepository) GetMessage(ctx context.Context, internalMessag
 
 		mn.recipient_id AS recipient_id,
 		mn.is_read      AS is_read,
+		mn.mention      AS mention
 
 	FROM message_notifications mn
 	JOIN messages m ON mn.message_id = m.id
@@ -126,7 +133,8 @@ func (r *NotificationsRepository) GetMessage(ctx context.Context, internalMessag
 	LEFT JOIN user_notifications un ON un.user_id = mn.recipient_id AND un.chat_id = c.id
 	WHERE mn.message_id = ? AND mn.recipient_id = ? AND mn.is_read = FALSE
 	  AND (
-	      un.mute = FALSE
+	      mn.mention = TRUE -- mentions bypass the mute
+	      OR un.mute = FALSE
 	      OR un.mute IS NULL
 	      OR (un.term IS NOT NULL AND un.term <= NOW()) -- ADD: expired mute = not muted
 	  )
@@ -173,6 +181,7 @@ func (r *NotificationsRepository) GetMessagesForRecipient(ctx context.Context, r
 		m.type         AS message_type,
 		m.updated_at   AS message_updated_at,
 		m.action       AS message_action,
+		m.duration     AS message_duration,
 
 		u.user_id      AS sender_user_id,
 		u.username     AS sender_username,
@@ -180,7 +189,8 @@ func (r *NotificationsRepository) GetMessagesForRecipient(ctx context.Context, r
 		u.avatar_url   AS sender_avatar_url,
 
 		mn.recipient_id AS recipient_id,
-		mn.is_read      AS is_read
+		mn.is_read      AS is_read,
+		mn.mention      AS mention
 
 	FROM message_notifications mn
 	JOIN messages m ON mn.message_id = m.id
@@ -191,7 +201,8 @@ func (r *NotificationsRepository) GetMessagesForRecipient(ctx context.Context, r
 	LEFT JOIN user_notifications un ON un.user_id = mn.recipient_id AND un.chat_id = c.id
 	WHERE mn.recipient_id = ? AND mn.is_read = FALSE
 	  AND (
-	      un.mute = FALSE
+	      mn.mention = TRUE -- mentions bypass the mute
+	      OR un.mute = FALSE
 	      OR un.mute IS NULL
 	      OR (un.term IS NOT NULL AND un.term <= NOW()) -- ADD: expired mute = not muted
 	  )
@@ -259,6 +270,7 @@ func (r *NotificationsRepository) GetMessages(ctx context.Context, internalMessa
 		m.type         AS message_type,
 		m.updated_at   AS message_updated_at,
 		m.action       AS message_action,
+		m.duration     AS message_duration,
 
 		u.user_id      AS sender_user_id,
 		u.username     AS sender_username,
@@ -266,7 +278,8 @@ func (r *NotificationsRepository) GetMessages(ctx context.Context, internalMessa
 		u.avatar_url   AS sender_avatar_url,
 
 		mn.recipient_id   AS recipient_id,
-		mn.is_read        AS is_read
+		mn.is_read        AS is_read,
+		mn.mention        AS mention
 
 	FROM message_notifications mn
 	JOIN messages m ON mn.message_id = m.id
@@ -0,0 +1,164 @@
+package repo
+
+import (
+	"context"
+	"notification-api/internal/model"
+	"notification-api/pkg/logger"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// DB struct for notification reactions
+type notificationReactionDB struct {
+	ChatID        int64   `db:"chat_chat_id"`
+	ChatCreatorID string  `db:"chat_creator_id"`
+	ChatName      string  `db:"chat_name"`
+	ChatEncrypted bool    `db:"chat_encrypted"`
+	ChatAvatarURL *string `db:"chat_avatar_url"`
+	ChatUpdatedAt string  `db:"chat_updated_at"`
+
+	MessageID   int64  `db:"message_message_id"`
+	MessageType string `db:"message_type"`
+	ReactedAt   string `db:"reacted_at"`
+
+	SenderUserID   string  `db:"sender_user_id"`
+	SenderUsername string  `db:"sender_username"`
+	SenderName     string  `db:"sender_name"`
+	SenderAvatar   *string `db:"sender_avatar_url"`
+
+	RecipientID string `db:"recipient_id"`
+	Emoji       string `db:"emoji"`
+}
+
+func toNotificationReaction(dbRow notificationReactionDB) model.NotificationReaction {
+	t_chat, err := time.Parse("2006-01-02 15:04:05", dbRow.ChatUpdatedAt)
+	if err != nil {
+		logger.Warn("Error with converting time db to time.Time")
+	}
+	t_reacted, err := time.Parse("2006-01-02 15:04:05", dbRow.ReactedAt)
+	if err != nil {
+		logger.Warn("Error with converting time db to time.Time")
+	}
+	return model.NotificationReaction{
+		Chat: model.ChatBriefInfo{
+			ChatID:    dbRow.ChatID,
+			CreatorID: dbRow.ChatCreatorID,
+			Name:      dbRow.ChatName,
+			Encrypted: dbRow.ChatEncrypted,
+			AvatarURL: dbRow.ChatAvatarURL,
+			UpdatedAt: t_chat,
+		},
+		Message: model.MessageBriefInfo{
+			MessageID: dbRow.MessageID,
+			SenderID:  dbRow.RecipientID,
+			Type:      dbRow.MessageType,
+			UpdatedAt: t_reacted,
+		},
+		Sender: model.UserBriefInfo{
+			UserID:    dbRow.SenderUserID,
+			Username:  dbRow.SenderUsername,
+			Name:      dbRow.SenderName,
+			AvatarURL: dbRow.SenderAvatar,
+		},
+		RecipientID: dbRow.RecipientID,
+		Emoji:       dbRow.Emoji,
+	}
+}
+
+// SetReaction stores the reaction for the author of the message, reacting again with the same emoji makes it unread again
+func (r *NotificationsRepository) SetReaction(ctx context.Context, internalChatID int64, reaction model.NotificationReaction) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	query := `
+		INSERT INTO reaction_notifications (message_external_id, chat_id, message_type, sender_id, recipient_id, emoji, reacted_at, is_read)
+		VALUES (?, ?, ?, ?, ?, ?, UTC_TIMESTAMP(), false)
+		ON DUPLICATE KEY UPDATE reacted_at = VALUES(reacted_at), is_read = false
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		reaction.Message.MessageID, internalChatID, reaction.Message.Type, reaction.Sender.UserID, reaction.RecipientID, reaction.Emoji,
+	)
+	return err
+}
+
+// GetReactionsForRecipient fetches the unread reactions to the messages of the recipient outside of the muted chats and marks them read
+func (r *NotificationsRepository) GetReactionsForRecipient(ctx context.Context, recipientID string) ([]model.NotificationReaction, error) {
+	query := `
+	SELECT
+		c.chat_external_id  AS chat_chat_id,
+		c.creator_id   AS chat_creator_id,
+		c.name         AS chat_name,
+		c.encrypted    AS chat_encrypted,
+		c.avatar_url   AS chat_avatar_url,
+		c.updated_at   AS chat_updated_at,
+
+		rn.message_external_id AS message_message_id,
+		rn.message_type AS message_type,
+		rn.reacted_at   AS reacted_at,
+
+		u.user_id      AS sender_user_id,
+		u.username     AS sender_username,
+		u.name         AS sender_name,
+		u.avatar_url   AS sender_avatar_url,
+
+		rn.recipient_id AS recipient_id,
+		rn.emoji        AS emoji
+
+	FROM reaction_notifications rn
+	JOIN chats c ON rn.chat_id = c.id
+	JOIN users u ON rn.sender_id = u.user_id
+	LEFT JOIN user_notifications un ON un.user_id = rn.recipient_id AND un.chat_id = c.id
+	WHERE rn.recipient_id = ? AND rn.is_read = FALSE
+	  AND (
+	      un.mute = FALSE
+	      OR un.mute IS NULL
+	      OR (un.term IS NOT NULL AND un.term <= NOW()) -- expired mute = not muted
+	  )
+	ORDER BY rn.reacted_at DESC
+	`
+
+	var dbRows []notificationReactionDB
+	if err := r.db.SelectContext(ctx, &dbRows, query, recipientID); err != nil {
+		return nil, err
+	}
+
+	if len(dbRows) == 0 {
+		return nil, nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	messageIDs := make([]int64, 0, len(dbRows))
+	for _, row := range dbRows {
+		messageIDs = append(messageIDs, row.MessageID)
+	}
+
+	updateQuery := `
+		UPDATE reaction_notifications
+		SET is_read = TRUE
+		WHERE recipient_id = ? AND message_external_id IN (?)
+	`
+	queryWithArgs, args, err := sqlx.In(updateQuery, recipientID, messageIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := r.db.ExecContext(ctx, r.db.Rebind(queryWithArgs), args...); err != nil {
+		return nil, err
+	}
+
+	results := make([]model.NotificationReaction, 0, len(dbRows))
+	for _, dbRow := range dbRows {
+		results = append(results, toNotificationReaction(dbRow))
+	}
+
+	return results, nil
+}
@@ -41,6 +41,7 @@ type Messages interface {
 	GetMessage(ctx context.Context, externalMessageID int64) (model.MessageBriefInfo, string, error)
 	UpdateMessage(ctx context.Context, mBriefInfo model.MessageBriefInfo, action string) error
 	DeleteMessage(ctx context.Context, externalMessageID int64) error
+	DeleteMessages(ctx context.Context, externalMessageIDs []int64) error
 }
 
 type Chats interface {
@@ -52,7 +53,7 @@ type Chats interface {
 }
 
 type Notifications interface {
-	SetMessage(ctx context.Context, internalMessageID int64, recipientID string) error
+	SetMessage(ctx context.Context, internalMessageID int64, recipientID string, mention bool) error
 	GetMessages(ctx context.Context, internalMessageID int64) ([]model.NotificationMessage, error)
 	GetMessage(ctx context.Context, internalMessageID int64, recipientID string) (model.NotificationMessage, error)
 	GetMessagesForRecipient(ctx context.Context, recipientID string) ([]model.NotificationMessage, error)
@@ -61,6 +62,12 @@ type Notifications interface {
 	GetChats(ctx context.Context, internalChatID int64) ([]model.NotificationChat, error)
 	GetChat(ctx context.Context, internalChatID int64, recipientID string) (model.NotificationChat, error)
 	GetChatsForRecipient(ctx context.Context, recipientID string) ([]model.NotificationChat, error)
+
+	SetExport(ctx context.Context, export model.NotificationExport) error
+	GetExportsForRecipient(ctx context.Context, recipientID string) ([]model.NotificationExport, error)
+
+	SetReaction(ctx context.Context, internalChatID int64, reaction model.NotificationReaction) error
+	GetReactionsForRecipient(ctx context.Context, recipientID string) ([]model.NotificationReaction, error)
 }
 
 type Verification interface {
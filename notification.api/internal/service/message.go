@@ -30,7 +30,7 @@ func (s *MessagesService) SaveNotificationMessage(ctx context.Context, notificat
 	if err != nil {
 		return err
 	}
-	if err := s.notificationRepo.SetMessage(ctx, internalMessageID, notificationMessage.RecipientID); err != nil {
+	if err := s.notificationRepo.SetMessage(ctx, internalMessageID, notificationMessage.RecipientID, notificationMessage.Mention); err != nil {
 		return err
 	}
 	internalChatID, err := s.chatsRepo.SetChat(ctx, notificationMessage.Chat, nil)
@@ -42,3 +42,19 @@ func (s *MessagesService) SaveNotificationMessage(ctx context.Context, notificat
 	}
 	return nil
 }
+
+// SaveNotificationReaction stores the reaction for the offline author, the content of the message never reaches notification.api
+func (s *MessagesService) SaveNotificationReaction(ctx context.Context, notificationReaction model.NotificationReaction) error {
+	if err := s.usersRepo.SetUser(ctx, notificationReaction.Sender); err != nil {
+		return err
+	}
+	internalChatID, err := s.chatsRepo.SetChat(ctx, notificationReaction.Chat, nil)
+	if err != nil {
+		return err
+	}
+	return s.notificationRepo.SetReaction(ctx, internalChatID, notificationReaction)
+}
+
+func (s *MessagesService) DeleteNotificationMessages(ctx context.Context, deletedMessages model.NotificationDeletedMessages) error {
+	return s.messagesRepo.DeleteMessages(ctx, deletedMessages.MessageIDs)
+}
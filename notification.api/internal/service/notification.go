@@ -25,6 +25,9 @@ func (s *NotificationService) GetNotifications(ctx context.Context, userID strin
 	if notificationResponse.NotificationMessages, err = s.notificationRepo.GetMessagesForRecipient(ctx, userID); err != nil {
 		return model.NotificationResponse{}, err
 	}
+	for i := range notificationResponse.NotificationMessages {
+		notificationResponse.NotificationMessages[i].Text = model.NotificationText(notificationResponse.NotificationMessages[i].Message, notificationResponse.NotificationMessages[i].Mention)
+	}
 
 	if notificationResponse.NotificationChat, err = s.notificationRepo.GetChatsForRecipient(ctx, userID); err != nil {
 		return model.NotificationResponse{}, err
@@ -34,9 +37,24 @@ func (s *NotificationService) GetNotifications(ctx context.Context, userID strin
 		return model.NotificationResponse{}, err
 	}
 
+	if notificationResponse.Exports, err = s.notificationRepo.GetExportsForRecipient(ctx, userID); err != nil {
+		return model.NotificationResponse{}, err
+	}
+
+	if notificationResponse.Reactions, err = s.notificationRepo.GetReactionsForRecipient(ctx, userID); err != nil {
+		return model.NotificationResponse{}, err
+	}
+	for i := range notificationResponse.Reactions {
+		notificationResponse.Reactions[i].Text = model.ReactionText(notificationResponse.Reactions[i].Emoji)
+	}
+
 	return notificationResponse, nil
 }
 
+func (s *NotificationService) SaveNotificationExport(ctx context.Context, export model.NotificationExport) error {
+	return s.notificationRepo.SetExport(ctx, export)
+}
+
 func (s *NotificationService) SetUserChatNotificationStatus(ctx context.Context, userNotification model.UserNotification) error {
 	return s.userRepo.SetUserNotification(ctx, userNotification)
 }
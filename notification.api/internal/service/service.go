@@ -51,6 +51,7 @@ func NewServices(deps *Deps) *Services {
 
 type Notifications interface {
 	GetNotifications(ctx context.Context, userID string) (model.NotificationResponse, error)
+	SaveNotificationExport(ctx context.Context, export model.NotificationExport) error
 
 	SetUserChatNotificationStatus(ctx context.Context, userNotification model.UserNotification) error
 	GetUserMutedChat(ctx context.Context, userID string) ([]model.UserNotification, error)
@@ -70,6 +71,8 @@ type Phones interface {
 
 type Messages interface {
 	SaveNotificationMessage(ctx context.Context, notificationMessage model.NotificationMessage) error
+	SaveNotificationReaction(ctx context.Context, notificationReaction model.NotificationReaction) error
+	DeleteNotificationMessages(ctx context.Context, deletedMessages model.NotificationDeletedMessages) error
 }
 
 type Chats interface {
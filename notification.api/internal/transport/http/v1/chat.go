@@ -87,4 +87,99 @@ func (h *Handler) processCreateChat(ctx context.Context, msg amqp.Delivery, cons
 	msg.Ack(false)
 }
 
-// TODO consumeMessageSendEncrypted
+// consumeJoinRequest stores notifications for the admins of the chat somebody asked to join
+func (h *Handler) consumeJoinRequest(ctx context.Context) {
+	h.consumeChatAction(ctx, "JoinRequest", broker.QUEUE_CHAT_JOIN_REQUEST, model.CHAT_ACTION_JOIN_REQUEST)
+}
+
+func (h *Handler) consumeAddedUser(ctx context.Context) {
+	h.consumeChatAction(ctx, "AddedUser", broker.QUEUE_CHAT_ADDED_USER, model.CHAT_ACTION_JOIN)
+}
+
+func (h *Handler) consumeLeftUser(ctx context.Context) {
+	h.consumeChatAction(ctx, "LeftUser", broker.QUEUE_CHAT_LEFT_USER, model.CHAT_ACTION_LEFT)
+}
+
+func (h *Handler) consumeKickedUser(ctx context.Context) {
+	h.consumeChatAction(ctx, "KickedUser", broker.QUEUE_CHAT_KICKED_USER, model.CHAT_ACTION_KICK)
+}
+
+func (h *Handler) consumeRenameChat(ctx context.Context) {
+	h.consumeChatAction(ctx, "RenameChat", broker.QUEUE_CHAT_RENAME, model.CHAT_ACTION_RENAME)
+}
+
+// consumeChatAction stores the chat notifications of the queue marked with the action
+func (h *Handler) consumeChatAction(ctx context.Context, consumerName, queue, action string) {
+	ch := h.services.RabbitMQ.Channels[broker.EXCHANGE_CHAT]
+	if ch == nil {
+		logger.Errorf("[%s] Channel is not initialized", consumerName)
+		return
+	}
+
+	msgs, err := ch.Consume(
+		queue,
+		"",
+		false, // manual ack
+		false,
+		false,
+		false,
+		nil,
+	)
+	if err != nil {
+		logger.Errorf("[%s] Failed to register consumer: %v", consumerName, err)
+		return
+	}
+
+	logger.Infof("[%s] Consumer registered, waiting for messages...", consumerName)
+
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Errorf("[%s] Panic recovered: %v", consumerName, r)
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Infof("[%s] Context cancelled, stopping consumer", consumerName)
+			return
+
+		case msg, ok := <-msgs:
+			if !ok {
+				logger.Warnf("[%s] Message channel closed, stopping consumer", consumerName)
+				return
+			}
+
+			h.processChatAction(ctx, msg, consumerName, action)
+		}
+	}
+}
+
+func (h *Handler) processChatAction(ctx context.Context, msg amqp.Delivery, consumerName, action string) {
+	logger.Debugf("[%s] Received message, size: %d bytes", consumerName, len(msg.Body))
+
+	var chat model.NotificationChat
+	if err := json.Unmarshal(msg.Body, &chat); err != nil {
+		logger.Errorf("[%s] Failed to unmarshal message: %v", consumerName, err)
+		msg.Nack(false, false)
+		return
+	}
+
+	chat.ChatAction = action
+
+	if err := h.services.Chats.SaveNotificationChat(ctx, chat); err != nil {
+		logger.Errorf("[%s] Failed to save notification chat: %v", consumerName, err)
+
+		if h.shouldRequeue(err) {
+			logger.Infof("[%s] Requeuing message for retry", consumerName)
+			msg.Nack(false, true)
+		} else {
+			logger.Infof("[%s] Discarding message (permanent error)", consumerName)
+			msg.Nack(false, false)
+		}
+		return
+	}
+
+	logger.Infof("[%s] Successfully processed %q for chat_id: %d", consumerName, action, chat.Chat.ChatID)
+	msg.Ack(false)
+}
@@ -0,0 +1,87 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"notification-api/internal/model"
+	"notification-api/pkg/broker"
+	"notification-api/pkg/logger"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// consumeExportReady stores notifications for the users who were offline when their chat archive was finished
+func (h *Handler) consumeExportReady(ctx context.Context) {
+	const consumerName = "ExportReady"
+
+	ch := h.services.RabbitMQ.Channels[broker.EXCHANGE_CHAT]
+	if ch == nil {
+		logger.Errorf("[%s] Channel is not initialized", consumerName)
+		return
+	}
+
+	msgs, err := ch.Consume(
+		broker.QUEUE_CHAT_EXPORT_READY,
+		"",
+		false, // manual ack
+		false,
+		false,
+		false,
+		nil,
+	)
+	if err != nil {
+		logger.Errorf("[%s] Failed to register consumer: %v", consumerName, err)
+		return
+	}
+
+	logger.Infof("[%s] Consumer registered, waiting for messages...", consumerName)
+
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Errorf("[%s] Panic recovered: %v", consumerName, r)
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Infof("[%s] Context cancelled, stopping consumer", consumerName)
+			return
+
+		case msg, ok := <-msgs:
+			if !ok {
+				logger.Warnf("[%s] Message channel closed, stopping consumer", consumerName)
+				return
+			}
+
+			h.processExportReady(ctx, msg, consumerName)
+		}
+	}
+}
+
+func (h *Handler) processExportReady(ctx context.Context, msg amqp.Delivery, consumerName string) {
+	logger.Debugf("[%s] Received message, size: %d bytes", consumerName, len(msg.Body))
+
+	var export model.NotificationExport
+	if err := json.Unmarshal(msg.Body, &export); err != nil {
+		logger.Errorf("[%s] Failed to unmarshal message: %v", consumerName, err)
+		msg.Nack(false, false)
+		return
+	}
+
+	if err := h.services.Notifications.SaveNotificationExport(ctx, export); err != nil {
+		logger.Errorf("[%s] Failed to save notification export: %v", consumerName, err)
+
+		if h.shouldRequeue(err) {
+			logger.Infof("[%s] Requeuing message for retry", consumerName)
+			msg.Nack(false, true)
+		} else {
+			logger.Infof("[%s] Discarding message (permanent error)", consumerName)
+			msg.Nack(false, false)
+		}
+		return
+	}
+
+	logger.Infof("[%s] Successfully processed export_id: %d", consumerName, export.ExportID)
+	msg.Ack(false)
+}
@@ -48,7 +48,17 @@ func (h *Handler) runConsumers(ctx context.Context, wg *sync.WaitGroup) {
 		{"VerifyCodeEmail", h.consumeVerifyCodeEmail},
 		{"VerifyCodePhone", h.consumeVerifyCodePhone},
 		{"SendMessage", h.consumeSendMessage},
+		{"SendEncryptedMessage", h.consumeMessageSendEncrypted},
+		{"MentionMessage", h.consumeMentionMessage},
+		{"DeleteMessages", h.consumeDeleteMessages},
+		{"MessageReaction", h.consumeMessageReaction},
 		{"CreateChat", h.consumeCreateChat},
+		{"JoinRequest", h.consumeJoinRequest},
+		{"AddedUser", h.consumeAddedUser},
+		{"LeftUser", h.consumeLeftUser},
+		{"KickedUser", h.consumeKickedUser},
+		{"RenameChat", h.consumeRenameChat},
+		{"ExportReady", h.consumeExportReady},
 	}
 
 	for _, consumer := range consumers {
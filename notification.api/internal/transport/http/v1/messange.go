@@ -69,6 +69,7 @@ func (h *Handler) processSendMessage(ctx context.Context, msg amqp.Delivery, con
 	}
 
 	nm.MessageAction = model.MESSAGE_ACTION_SEND
+	nm.Mention = msg.RoutingKey == broker.ROUTING_KEY_MESSAGE_MENTION
 
 	if err := h.services.Messages.SaveNotificationMessage(ctx, nm); err != nil {
 		logger.Errorf("[%s] Failed to save notification message: %v", consumerName, err)
@@ -87,4 +88,175 @@ func (h *Handler) processSendMessage(ctx context.Context, msg amqp.Delivery, con
 	msg.Ack(false)
 }
 
-// TODO consumeMessageSendEncrypted
+func (h *Handler) consumeDeleteMessages(ctx context.Context) {
+	const consumerName = "DeleteMessages"
+
+	ch := h.services.RabbitMQ.Channels[broker.EXCHANGE_MESSAGE]
+	if ch == nil {
+		logger.Errorf("[%s] Channel is not initialized", consumerName)
+		return
+	}
+
+	msgs, err := ch.Consume(
+		broker.QUEUE_MESSAGE_DELETED,
+		"",
+		false, // manual ack
+		false,
+		false,
+		false,
+		nil,
+	)
+	if err != nil {
+		logger.Errorf("[%s] Failed to register consumer: %v", consumerName, err)
+		return
+	}
+
+	logger.Infof("[%s] Consumer registered, waiting for messages...", consumerName)
+
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Errorf("[%s] Panic recovered: %v", consumerName, r)
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Infof("[%s] Context cancelled, stopping consumer", consumerName)
+			return
+
+		case msg, ok := <-msgs:
+			if !ok {
+				logger.Warnf("[%s] Message channel closed, stopping consumer", consumerName)
+				return
+			}
+
+			h.processDeleteMessages(ctx, msg, consumerName)
+		}
+	}
+}
+
+func (h *Handler) processDeleteMessages(ctx context.Context, msg amqp.Delivery, consumerName string) {
+	logger.Debugf("[%s] Received message, size: %d bytes", consumerName, len(msg.Body))
+
+	var deleted model.NotificationDeletedMessages
+	if err := json.Unmarshal(msg.Body, &deleted); err != nil {
+		logger.Errorf("[%s] Failed to unmarshal message: %v", consumerName, err)
+		msg.Nack(false, false)
+		return
+	}
+
+	if err := h.services.Messages.DeleteNotificationMessages(ctx, deleted); err != nil {
+		logger.Errorf("[%s] Failed to delete notification messages: %v", consumerName, err)
+
+		if h.shouldRequeue(err) {
+			logger.Infof("[%s] Requeuing message for retry", consumerName)
+			msg.Nack(false, true)
+		} else {
+			logger.Infof("[%s] Discarding message (permanent error)", consumerName)
+			msg.Nack(false, false)
+		}
+		return
+	}
+
+	logger.Infof("[%s] Successfully deleted %d messages", consumerName, len(deleted.MessageIDs))
+	msg.Ack(false)
+}
+
+// consumeMessageSendEncrypted stores notifications about E2EE messages, the payload never carries content
+func (h *Handler) consumeMessageSendEncrypted(ctx context.Context) {
+	const consumerName = "SendEncryptedMessage"
+
+	ch := h.services.RabbitMQ.Channels[broker.EXCHANGE_MESSAGE]
+	if ch == nil {
+		logger.Errorf("[%s] Channel is not initialized", consumerName)
+		return
+	}
+
+	msgs, err := ch.Consume(
+		broker.QUEUE_MESSAGE_SEND_ENCRYPTED,
+		"",
+		false, // manual ack
+		false,
+		false,
+		false,
+		nil,
+	)
+	if err != nil {
+		logger.Errorf("[%s] Failed to register consumer: %v", consumerName, err)
+		return
+	}
+
+	logger.Infof("[%s] Consumer registered, waiting for messages...", consumerName)
+
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Errorf("[%s] Panic recovered: %v", consumerName, r)
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Infof("[%s] Context cancelled, stopping consumer", consumerName)
+			return
+
+		case msg, ok := <-msgs:
+			if !ok {
+				logger.Warnf("[%s] Message channel closed, stopping consumer", consumerName)
+				return
+			}
+
+			h.processSendMessage(ctx, msg, consumerName)
+		}
+	}
+}
+
+// consumeMentionMessage stores notifications for the mentioned recipients, they are shown even in muted chats
+func (h *Handler) consumeMentionMessage(ctx context.Context) {
+	const consumerName = "MentionMessage"
+
+	ch := h.services.RabbitMQ.Channels[broker.EXCHANGE_MESSAGE]
+	if ch == nil {
+		logger.Errorf("[%s] Channel is not initialized", consumerName)
+		return
+	}
+
+	msgs, err := ch.Consume(
+		broker.QUEUE_MESSAGE_MENTION,
+		"",
+		false, // manual ack
+		false,
+		false,
+		false,
+		nil,
+	)
+	if err != nil {
+		logger.Errorf("[%s] Failed to register consumer: %v", consumerName, err)
+		return
+	}
+
+	logger.Infof("[%s] Consumer registered, waiting for messages...", consumerName)
+
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Errorf("[%s] Panic recovered: %v", consumerName, r)
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Infof("[%s] Context cancelled, stopping consumer", consumerName)
+			return
+
+		case msg, ok := <-msgs:
+			if !ok {
+				logger.Warnf("[%s] Message channel closed, stopping consumer", consumerName)
+				return
+			}
+
+			h.processSendMessage(ctx, msg, consumerName)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"notification-api/internal/model"
+	"notification-api/pkg/broker"
+	"notification-api/pkg/logger"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// consumeMessageReaction stores notifications for the authors who were offline when somebody reacted to their messages
+func (h *Handler) consumeMessageReaction(ctx context.Context) {
+	const consumerName = "MessageReaction"
+
+	ch := h.services.RabbitMQ.Channels[broker.EXCHANGE_MESSAGE]
+	if ch == nil {
+		logger.Errorf("[%s] Channel is not initialized", consumerName)
+		return
+	}
+
+	msgs, err := ch.Consume(
+		broker.QUEUE_MESSAGE_REACTION,
+		"",
+		false, // manual ack
+		false,
+		false,
+		false,
+		nil,
+	)
+	if err != nil {
+		logger.Errorf("[%s] Failed to register consumer: %v", consumerName, err)
+		return
+	}
+
+	logger.Infof("[%s] Consumer registered, waiting for messages...", consumerName)
+
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Errorf("[%s] Panic recovered: %v", consumerName, r)
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Infof("[%s] Context cancelled, stopping consumer", consumerName)
+			return
+
+		case msg, ok := <-msgs:
+			if !ok {
+				logger.Warnf("[%s] Message channel closed, stopping consumer", consumerName)
+				return
+			}
+
+			h.processMessageReaction(ctx, msg, consumerName)
+		}
+	}
+}
+
+func (h *Handler) processMessageReaction(ctx context.Context, msg amqp.Delivery, consumerName string) {
+	logger.Debugf("[%s] Received message, size: %d bytes", consumerName, len(msg.Body))
+
+	var reaction model.NotificationReaction
+	if err := json.Unmarshal(msg.Body, &reaction); err != nil {
+		logger.Errorf("[%s] Failed to unmarshal message: %v", consumerName, err)
+		msg.Nack(false, false)
+		return
+	}
+
+	if err := h.services.Messages.SaveNotificationReaction(ctx, reaction); err != nil {
+		logger.Errorf("[%s] Failed to save notification reaction: %v", consumerName, err)
+
+		if h.shouldRequeue(err) {
+			logger.Infof("[%s] Requeuing message for retry", consumerName)
+			msg.Nack(false, true)
+		} else {
+			logger.Infof("[%s] Discarding message (permanent error)", consumerName)
+			msg.Nack(false, false)
+		}
+		return
+	}
+
+	logger.Infof("[%s] Successfully processed reaction to message_id: %d", consumerName, reaction.Message.MessageID)
+	msg.Ack(false)
+}
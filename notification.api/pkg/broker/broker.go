@@ -12,15 +12,20 @@ const (
 	EXCHANGE_MESSAGE     = "message_exchange"
 	EXCHANGE_VERIFY_CODE = "verify_code_exchange"
 
-	QUEUE_CHAT_CREATED     = "chat_created"
-	QUEUE_CHAT_DELETED     = "chat_deleted"
-	QUEUE_CHAT_ADDED_USER  = "chat_added_user"
-	QUEUE_CHAT_LEFT_USER   = "chat_left_user"
-	QUEUE_CHAT_RENAME      = "chat_rename"
-	QUEUE_CHAT_KICKED_USER = "chat_kicked_user"
+	QUEUE_CHAT_CREATED      = "chat_created"
+	QUEUE_CHAT_DELETED      = "chat_deleted"
+	QUEUE_CHAT_ADDED_USER   = "chat_added_user"
+	QUEUE_CHAT_LEFT_USER    = "chat_left_user"
+	QUEUE_CHAT_RENAME       = "chat_rename"
+	QUEUE_CHAT_KICKED_USER  = "chat_kicked_user"
+	QUEUE_CHAT_JOIN_REQUEST = "chat_join_request"
+	QUEUE_CHAT_EXPORT_READY = "chat_export_ready"
 
 	QUEUE_MESSAGE_SEND           = "message_send"
 	QUEUE_MESSAGE_SEND_ENCRYPTED = "message_send_encrypted"
+	QUEUE_MESSAGE_MENTION        = "message_mention"
+	QUEUE_MESSAGE_DELETED        = "message_deleted"
+	QUEUE_MESSAGE_REACTION       = "message_reaction"
 
 	QUEUE_VERIFY_CODE_SEND_TO_PHONE = "verify_code_send_to_phone_queue"
 	QUEUE_VERIFY_CODE_SEND_TO_EMAIL = "verify_code_send_to_email_queue"
@@ -28,15 +33,20 @@ const (
 	ROUTING_KEY_VERIFY_CODE_EMAIL = "verify_code.email"
 	ROUTING_KEY_VERIFY_CODE_PHONE = "verify_code.phone"
 
-	ROUTING_KEY_CHAT_CREATED     = "chat.created"
-	ROUTING_KEY_CHAT_DELETED     = "chat.deleted"
-	ROUTING_KEY_CHAT_ADDED_USER  = "chat.user.added"
-	ROUTING_KEY_CHAT_LEFT_USER   = "chat.user.left"
-	ROUTING_KEY_CHAT_RENAME      = "chat.renamed"
-	ROUTING_KEY_CHAT_KICKED_USER = "chat.user.kicked"
+	ROUTING_KEY_CHAT_CREATED      = "chat.created"
+	ROUTING_KEY_CHAT_DELETED      = "chat.deleted"
+	ROUTING_KEY_CHAT_ADDED_USER   = "chat.user.added"
+	ROUTING_KEY_CHAT_LEFT_USER    = "chat.user.left"
+	ROUTING_KEY_CHAT_RENAME       = "chat.renamed"
+	ROUTING_KEY_CHAT_KICKED_USER  = "chat.user.kicked"
+	ROUTING_KEY_CHAT_JOIN_REQUEST = "chat.join.requested"
+	ROUTING_KEY_CHAT_EXPORT_READY = "chat.export.ready"
 
 	ROUTING_KEY_MESSAGE_SEND           = "message.send"
 	ROUTING_KEY_MESSAGE_SEND_ENCRYPTED = "message.send.encrypted"
+	ROUTING_KEY_MESSAGE_MENTION        = "message.mention"
+	ROUTING_KEY_MESSAGE_DELETED        = "message.deleted"
+	ROUTING_KEY_MESSAGE_REACTION       = "message.reaction"
 )
 
 type RabbitMQConfig struct {
@@ -118,12 +128,14 @@ func (r *RabbitMQ) initializationOfChatChannel() error {
 
 	// Declare and bind queues with specific routing keys
 	queueBindings := map[string]string{
-		QUEUE_CHAT_CREATED:     ROUTING_KEY_CHAT_CREATED,
-		QUEUE_CHAT_DELETED:     ROUTING_KEY_CHAT_DELETED,
-		QUEUE_CHAT_ADDED_USER:  ROUTING_KEY_CHAT_ADDED_USER,
-		QUEUE_CHAT_LEFT_USER:   ROUTING_KEY_CHAT_LEFT_USER,
-		QUEUE_CHAT_RENAME:      ROUTING_KEY_CHAT_RENAME,
-		QUEUE_CHAT_KICKED_USER: ROUTING_KEY_CHAT_KICKED_USER,
+		QUEUE_CHAT_CREATED:      ROUTING_KEY_CHAT_CREATED,
+		QUEUE_CHAT_DELETED:      ROUTING_KEY_CHAT_DELETED,
+		QUEUE_CHAT_ADDED_USER:   ROUTING_KEY_CHAT_ADDED_USER,
+		QUEUE_CHAT_LEFT_USER:    ROUTING_KEY_CHAT_LEFT_USER,
+		QUEUE_CHAT_RENAME:       ROUTING_KEY_CHAT_RENAME,
+		QUEUE_CHAT_KICKED_USER:  ROUTING_KEY_CHAT_KICKED_USER,
+		QUEUE_CHAT_JOIN_REQUEST: ROUTING_KEY_CHAT_JOIN_REQUEST,
+		QUEUE_CHAT_EXPORT_READY: ROUTING_KEY_CHAT_EXPORT_READY,
 	}
 
 	for queueName, routingKey := range queueBindings {
@@ -184,6 +196,9 @@ func (r *RabbitMQ) initializationOfMessageChannel() error {
 	queueBindings := map[string]string{
 		QUEUE_MESSAGE_SEND:           ROUTING_KEY_MESSAGE_SEND,
 		QUEUE_MESSAGE_SEND_ENCRYPTED: ROUTING_KEY_MESSAGE_SEND_ENCRYPTED,
+		QUEUE_MESSAGE_MENTION:        ROUTING_KEY_MESSAGE_MENTION,
+		QUEUE_MESSAGE_DELETED:        ROUTING_KEY_MESSAGE_DELETED,
+		QUEUE_MESSAGE_REACTION:       ROUTING_KEY_MESSAGE_REACTION,
 	}
 
 	for queueName, routingKey := range queueBindings {
@@ -27,7 +27,8 @@ CREATE TABLE IF NOT EXISTS chats (
         'added to chat by',
         'left chat',
         'changed the chat name to',
-        'was kicked by'
+        'was kicked by',
+        'requested to join'
     ) NULL,
     FOREIGN KEY (creator_id) REFERENCES users(user_id)
 ) ENGINE=InnoDB;
@@ -36,9 +37,10 @@ CREATE TABLE IF NOT EXISTS messages (
     id BIGINT AUTO_INCREMENT PRIMARY KEY,
     message_external_id BIGINT NOT NULL UNIQUE,
     sender_id VARCHAR(255) NOT NULL,
-    type ENUM('text', 'media', 'file', 'location', 'mixed') NOT NULL,
+    type ENUM('text', 'media', 'file', 'location', 'mixed', 'voice', 'poll') NOT NULL,
     updated_at TIMESTAMP NOT NULL,
     action ENUM('send', 'edited', 'blurred', 'password', 'replied', 'pinned') DEFAULT 'send',
+    duration DOUBLE NULL, -- seconds of a voice message
     FOREIGN KEY (sender_id) REFERENCES users(user_id)
 ) ENGINE=InnoDB;
 
@@ -54,6 +56,7 @@ CREATE TABLE IF NOT EXISTS message_notifications (
     message_id BIGINT NOT NULL,
     recipient_id VARCHAR(255) NOT NULL,
     is_read BOOLEAN DEFAULT false,
+    mention BOOLEAN DEFAULT false, -- shown even if the chat is muted
     PRIMARY KEY (message_id, recipient_id),
     FOREIGN KEY (message_id) REFERENCES messages(id)
 ) ENGINE=InnoDB;
@@ -72,4 +75,29 @@ CREATE TABLE IF NOT EXISTS user_notifications (
     mute BOOLEAN DEFAULT true,
     term TIMESTAMP NULL,
     PRIMARY KEY (user_id, chat_id)
-) ENGINE=InnoDB;
\ No newline at end of file
+) ENGINE=InnoDB;
+
+CREATE TABLE IF NOT EXISTS export_notifications (
+    export_external_id BIGINT NOT NULL,
+    recipient_id VARCHAR(255) NOT NULL,
+    chat_external_id BIGINT NULL, -- NULL for the archive of all chats
+    status ENUM('ready', 'failed') NOT NULL,
+    download_url VARCHAR(255) NULL,
+    expires_at TIMESTAMP NULL,
+    is_read BOOLEAN DEFAULT false,
+    PRIMARY KEY (export_external_id, recipient_id)
+) ENGINE=InnoDB;
+
+CREATE TABLE IF NOT EXISTS reaction_notifications (
+    message_external_id BIGINT NOT NULL,
+    chat_id BIGINT NOT NULL,
+    message_type ENUM('text', 'media', 'file', 'location', 'mixed', 'voice', 'poll') NOT NULL,
+    sender_id VARCHAR(255) NOT NULL, -- who reacted
+    recipient_id VARCHAR(255) NOT NULL, -- the author of the message
+    emoji VARCHAR(32) NOT NULL,
+    reacted_at TIMESTAMP NOT NULL,
+    is_read BOOLEAN DEFAULT false,
+    PRIMARY KEY (message_external_id, sender_id, recipient_id, emoji),
+    FOREIGN KEY (chat_id) REFERENCES chats(id),
+    FOREIGN KEY (sender_id) REFERENCES users(user_id)
+) ENGINE=InnoDB;
@@ -182,3 +182,37 @@ func (r *ContactsRepository) DeleteAlias(ctx context.Context, request model.User
 	}
 	return nil
 }
+
+// GetUsersWithContact returns those of the users who have the contact saved
+func (r *ContactsRepository) GetUsersWithContact(ctx context.Context, contactID string, userIDs []string) ([]string, error) {
+	filter := bson.M{
+		"sender":    bson.M{"$in": userIDs},
+		"recipient": contactID,
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetProjection(bson.M{"sender": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var usersIDs []string
+
+	for cursor.Next(ctx) {
+		var c struct {
+			Sender string `bson:"sender"`
+		}
+
+		if err := cursor.Decode(&c); err != nil {
+			return nil, err
+		}
+
+		usersIDs = append(usersIDs, c.Sender)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return usersIDs, nil
+}
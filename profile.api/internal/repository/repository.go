@@ -39,6 +39,7 @@ type Contacts interface {
 	GetContact(ctx context.Context, request model.UserRequest) (model.Contact, error)
 	GetContacts(ctx context.Context, senderID string) ([]model.Contact, error)
 	DeleteContact(ctx context.Context, request model.UserRequest) error
+	GetUsersWithContact(ctx context.Context, contactID string, userIDs []string) ([]string, error)
 
 	GetAlias(ctx context.Context, request model.UserRequest) (string, error)
 	UpdateAlias(ctx context.Context, contactRequest model.Contact) error
@@ -19,9 +19,10 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	ProfileService_GetUserBriefInfo_FullMethodName  = "/proto.ProfileService/GetUserBriefInfo"
-	ProfileService_GetUsersBriefInfo_FullMethodName = "/proto.ProfileService/GetUsersBriefInfo"
-	ProfileService_GetUsersProfile_FullMethodName   = "/proto.ProfileService/GetUsersProfile"
+	ProfileService_GetUserBriefInfo_FullMethodName    = "/proto.ProfileService/GetUserBriefInfo"
+	ProfileService_GetUsersBriefInfo_FullMethodName   = "/proto.ProfileService/GetUsersBriefInfo"
+	ProfileService_GetUsersProfile_FullMethodName     = "/proto.ProfileService/GetUsersProfile"
+	ProfileService_GetUsersWithContact_FullMethodName = "/proto.ProfileService/GetUsersWithContact"
 )
 
 // ProfileServiceClient is the client API for ProfileService service.
@@ -31,6 +32,8 @@ type ProfileServiceClient interface {
 	GetUserBriefInfo(ctx context.Context, in *UserRequest, opts ...grpc.CallOption) (*UserBriefInfoResponse, error)
 	GetUsersBriefInfo(ctx context.Context, in *UsersRequest, opts ...grpc.CallOption) (*UsersBriefInfoResponse, error)
 	GetUsersProfile(ctx context.Context, in *UsersRequest, opts ...grpc.CallOption) (*UsersProfileResponse, error)
+	// GetUsersWithContact returns those of the recipients who have the sender in their contacts
+	GetUsersWithContact(ctx context.Context, in *UsersRequest, opts ...grpc.CallOption) (*UserIDsResponse, error)
 }
 
 type profileServiceClient struct {
@@ -71,6 +74,16 @@ func (c *profileServiceClient) GetUsersProfile(ctx context.Context, in *UsersReq
 	return out, nil
 }
 
+func (c *profileServiceClient) GetUsersWithContact(ctx context.Context, in *UsersRequest, opts ...grpc.CallOption) (*UserIDsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UserIDsResponse)
+	err := c.cc.Invoke(ctx, ProfileService_GetUsersWithContact_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ProfileServiceServer is the server API for ProfileService service.
 // All implementations must embed UnimplementedProfileServiceServer
 // for forward compatibility.
@@ -78,6 +91,8 @@ type ProfileServiceServer interface {
 	GetUserBriefInfo(context.Context, *UserRequest) (*UserBriefInfoResponse, error)
 	GetUsersBriefInfo(context.Context, *UsersRequest) (*UsersBriefInfoResponse, error)
 	GetUsersProfile(context.Context, *UsersRequest) (*UsersProfileResponse, error)
+	// GetUsersWithContact returns those of the recipients who have the sender in their contacts
+	GetUsersWithContact(context.Context, *UsersRequest) (*UserIDsResponse, error)
 	mustEmbedUnimplementedProfileServiceServer()
 }
 
@@ -97,6 +112,9 @@ func (UnimplementedProfileServiceServer) GetUsersBriefInfo(context.Context, *Use
 func (UnimplementedProfileServiceServer) GetUsersProfile(context.Context, *UsersRequest) (*UsersProfileResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetUsersProfile not implemented")
 }
+func (UnimplementedProfileServiceServer) GetUsersWithContact(context.Context, *UsersRequest) (*UserIDsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUsersWithContact not implemented")
+}
 func (UnimplementedProfileServiceServer) mustEmbedUnimplementedProfileServiceServer() {}
 func (UnimplementedProfileServiceServer) testEmbeddedByValue()                        {}
 
@@ -172,6 +190,24 @@ func _ProfileService_GetUsersProfile_Handler(srv interface{}, ctx context.Contex
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ProfileService_GetUsersWithContact_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UsersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProfileServiceServer).GetUsersWithContact(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProfileService_GetUsersWithContact_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProfileServiceServer).GetUsersWithContact(ctx, req.(*UsersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ProfileService_ServiceDesc is the grpc.ServiceDesc for ProfileService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -191,6 +227,10 @@ var ProfileService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetUsersProfile",
 			Handler:    _ProfileService_GetUsersProfile_Handler,
 		},
+		{
+			MethodName: "GetUsersWithContact",
+			Handler:    _ProfileService_GetUsersWithContact_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "internal/server/grpc/proto/profile.proto",
@@ -32,3 +32,10 @@ func (s *ContactsService) UpdateAlias(ctx context.Context, request model.Contact
 func (s *ContactsService) DeleteAlias(ctx context.Context, request model.UserRequest) error {
 	return s.repo.DeleteAlias(ctx, request)
 }
+
+func (s *ContactsService) GetUsersWithContact(ctx context.Context, contactID string, userIDs []string) ([]string, error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+	return s.repo.GetUsersWithContact(ctx, contactID, userIDs)
+}
@@ -51,6 +51,7 @@ type Contacts interface {
 	GetAlias(ctx context.Context, request model.UserRequest) (string, error)
 	UpdateAlias(ctx context.Context, request model.Contact) error
 	DeleteAlias(ctx context.Context, request model.UserRequest) error
+	GetUsersWithContact(ctx context.Context, contactID string, userIDs []string) ([]string, error)
 }
 
 type Auth interface {
@@ -145,3 +145,28 @@ func (h *ProfileHandler) GetUsersProfile(ctx context.Context, req *proto.UsersRe
 		},
 	}, nil
 }
+
+func (h *ProfileHandler) GetUsersWithContact(ctx context.Context, req *proto.UsersRequest) (*proto.UserIDsResponse, error) {
+	usersIDs, err := h.services.Contacts.GetUsersWithContact(ctx, req.SenderID, req.RecipientIDs)
+	if err != nil {
+		logger.Error(
+			zap.String("handler", "grpc"),
+			zap.String("action", "GetUsersWithContact()"),
+			zap.Error(err),
+		)
+		return &proto.UserIDsResponse{
+			Response: &proto.Response{
+				Success: false,
+				Message: "Failed to get users with contact",
+			},
+		}, err
+	}
+
+	return &proto.UserIDsResponse{
+		UserIDs: usersIDs,
+		Response: &proto.Response{
+			Success: true,
+			Message: "Successfully retrieved users with contact",
+		},
+	}, nil
+}